@@ -0,0 +1,214 @@
+// Package alert posts operational alerts to a Telegram chat through a bot.
+//
+// Every alert has a key; an alert whose key fired within the cooldown is
+// suppressed and counted, and the next one sent for that key says how many
+// were swallowed. A gateway outage therefore produces one message per
+// cooldown instead of one per failed request. A nil or unconfigured *Alerter
+// only logs, so handlers and tests can use it unconditionally.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"project/utils"
+)
+
+// DefaultBaseURL is the Telegram Bot API root.
+const DefaultBaseURL = "https://api.telegram.org"
+
+// DefaultCooldown is used when ALERT_COOLDOWN_MINUTES is not set.
+const DefaultCooldown = 15 * time.Minute
+
+// sendTimeout bounds one Telegram call made by Notify.
+const sendTimeout = 10 * time.Second
+
+// Alert keys, one per kind of anomaly. Callers may append a suffix (for
+// example the bank code) to dedupe per instance instead of per kind.
+const (
+	KeyPayoutFailed       = "payout_failed"
+	KeyWebhookRejected    = "webhook_rejected"
+	KeyCronFailed         = "cron_failed"
+	KeyPendingWithdrawals = "pending_withdrawals"
+	KeyGatewayErrors      = "gateway_errors"
+	KeyChargeback         = "chargeback"
+	KeyBalanceDrift       = "balance_drift"
+	KeyAmountMismatch     = "amount_mismatch"
+	KeyOutboxFailed       = "outbox_failed"
+	// KeyMonitor is suffixed with the monitor check, e.g. "monitor:outbox_failed"
+	KeyMonitor = "monitor"
+)
+
+// Alerter sends alerts to one Telegram chat.
+type Alerter struct {
+	BaseURL  string
+	BotToken string
+	ChatID   string
+	Cooldown time.Duration
+	HTTP     *http.Client
+
+	// now is replaced in tests
+	now func() time.Time
+
+	mu         sync.Mutex
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+}
+
+// NewFromEnv builds an Alerter from TELEGRAM_BOT_TOKEN, TELEGRAM_CHAT_ID and
+// ALERT_COOLDOWN_MINUTES. Without a token or chat it only logs.
+func NewFromEnv() *Alerter {
+	cooldown := DefaultCooldown
+	if v, err := strconv.Atoi(os.Getenv("ALERT_COOLDOWN_MINUTES")); err == nil && v > 0 {
+		cooldown = time.Duration(v) * time.Minute
+	}
+	return &Alerter{
+		BaseURL:  DefaultBaseURL,
+		BotToken: strings.TrimSpace(os.Getenv("TELEGRAM_BOT_TOKEN")),
+		ChatID:   strings.TrimSpace(os.Getenv("TELEGRAM_CHAT_ID")),
+		Cooldown: cooldown,
+		HTTP:     &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Configured reports whether alerts reach Telegram.
+func (a *Alerter) Configured() bool {
+	return a != nil && a.BotToken != "" && a.ChatID != ""
+}
+
+// Notify sends an alert in the background; it never blocks the caller. The
+// alert is always logged, whether or not it is sent.
+func (a *Alerter) Notify(key, format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	utils.Logger.Warn("alert", "key", key, "text", text)
+	if !a.Configured() {
+		return
+	}
+	// Claim before spawning so a burst during the cooldown costs no goroutines
+	suppressed, ok := a.claim(key)
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		if err := a.deliver(ctx, key, text, suppressed); err != nil {
+			utils.Logger.Error("alert delivery failed", "key", key, "error", err.Error())
+		}
+	}()
+}
+
+// Send posts text unless key is cooling down. It reports whether a message
+// was sent.
+func (a *Alerter) Send(ctx context.Context, key, text string) (bool, error) {
+	if !a.Configured() {
+		return false, nil
+	}
+	suppressed, ok := a.claim(key)
+	if !ok {
+		return false, nil
+	}
+	if err := a.deliver(ctx, key, text, suppressed); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deliver posts a claimed alert. On failure the claim is undone so the next
+// occurrence tries again instead of waiting out the cooldown.
+func (a *Alerter) deliver(ctx context.Context, key, text string, suppressed int) error {
+	if suppressed > 0 {
+		text += fmt.Sprintf("\n(%d alert serupa ditahan sejak pesan terakhir)", suppressed)
+	}
+	if err := a.post(ctx, "["+key+"] "+text); err != nil {
+		a.release(key, suppressed)
+		return err
+	}
+	return nil
+}
+
+// claim starts a cooldown for key, returning how many alerts were suppressed
+// since the last one sent, or false when key is still cooling down.
+func (a *Alerter) claim(key string) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.lastSent == nil {
+		a.lastSent = map[string]time.Time{}
+		a.suppressed = map[string]int{}
+	}
+	now := a.clock()
+	if last, ok := a.lastSent[key]; ok && now.Sub(last) < a.cooldown() {
+		a.suppressed[key]++
+		return 0, false
+	}
+	n := a.suppressed[key]
+	a.lastSent[key] = now
+	delete(a.suppressed, key)
+	return n, true
+}
+
+func (a *Alerter) release(key string, suppressed int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.lastSent, key)
+	a.suppressed[key] += suppressed
+}
+
+func (a *Alerter) post(ctx context.Context, text string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chat_id":                  a.ChatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	base := a.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(base, "/")+"/bot"+a.BotToken+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := a.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The request URL carries the bot token; keep it out of the logs
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("alert: telegram request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alert: telegram status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (a *Alerter) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+func (a *Alerter) cooldown() time.Duration {
+	if a.Cooldown > 0 {
+		return a.Cooldown
+	}
+	return DefaultCooldown
+}
@@ -0,0 +1,114 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/kyta"
+)
+
+// stubTelegram records the text of every sendMessage call.
+type stubTelegram struct {
+	texts []string
+	fail  bool
+}
+
+func (s *stubTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/botTOKEN/sendMessage" || s.fail {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	var body struct {
+		ChatID string `json:"chat_id"`
+		Text   string `json:"text"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	s.texts = append(s.texts, body.Text)
+	_, _ = w.Write([]byte(`{"ok":true}`))
+}
+
+func newTestAlerter(t *testing.T, s *stubTelegram, now *time.Time) *Alerter {
+	t.Helper()
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return &Alerter{BaseURL: srv.URL, BotToken: "TOKEN", ChatID: "-100", Cooldown: 15 * time.Minute, HTTP: srv.Client(), now: func() time.Time { return *now }}
+}
+
+func TestSendDedupesWithinCooldown(t *testing.T) {
+	s := &stubTelegram{}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := newTestAlerter(t, s, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := a.Send(ctx, KeyPayoutFailed, "payout gagal"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent, _ := a.Send(ctx, KeyCronFailed, "cron gagal"); !sent {
+		t.Fatal("a different key should not be held back")
+	}
+	if len(s.texts) != 2 {
+		t.Fatalf("expected 2 messages, got %d: %v", len(s.texts), s.texts)
+	}
+
+	now = now.Add(16 * time.Minute)
+	if sent, _ := a.Send(ctx, KeyPayoutFailed, "payout gagal"); !sent {
+		t.Fatal("expected a send after the cooldown")
+	}
+	if last := s.texts[len(s.texts)-1]; !strings.Contains(last, "4 alert serupa") {
+		t.Fatalf("expected the suppressed count in %q", last)
+	}
+}
+
+func TestFailedSendIsRetried(t *testing.T) {
+	s := &stubTelegram{fail: true}
+	now := time.Now()
+	a := newTestAlerter(t, s, &now)
+
+	if _, err := a.Send(context.Background(), KeyPayoutFailed, "x"); err == nil || strings.Contains(err.Error(), "TOKEN") {
+		t.Fatalf("expected an error without the bot token, got %v", err)
+	}
+	s.fail = false
+	if sent, err := a.Send(context.Background(), KeyPayoutFailed, "x"); !sent || err != nil {
+		t.Fatalf("expected the retry to be sent, got %v %v", sent, err)
+	}
+}
+
+func TestNilAlerterOnlyLogs(t *testing.T) {
+	var a *Alerter
+	a.Notify(KeyCronFailed, "cron gagal %d", 1)
+	if sent, err := a.Send(context.Background(), KeyCronFailed, "x"); sent || err != nil {
+		t.Fatalf("expected a no-op, got %v %v", sent, err)
+	}
+}
+
+// failingGateway fails every call.
+type failingGateway struct{ kyta.Client }
+
+func (failingGateway) CreateQRIS(context.Context, kyta.PaymentRequest) (*kyta.PaymentResponse, error) {
+	return nil, errors.New("gateway down")
+}
+
+func TestGatewayMonitorThreshold(t *testing.T) {
+	m := &GatewayMonitor{Client: failingGateway{}, Window: time.Minute, MaxErrorRate: 0.5, MinCalls: 3}
+	for i := 0; i < 2; i++ {
+		_, _ = m.CreateQRIS(context.Background(), kyta.PaymentRequest{})
+	}
+	if m.Check() {
+		t.Fatal("below MinCalls should not alert")
+	}
+	_, _ = m.CreateQRIS(context.Background(), kyta.PaymentRequest{})
+	if calls, failed := m.Stats(); calls != 3 || failed != 3 {
+		t.Fatalf("expected 3 failed calls, got %d/%d", failed, calls)
+	}
+	if !m.Check() {
+		t.Fatal("expected an alert at 100% errors")
+	}
+}
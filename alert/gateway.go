@@ -0,0 +1,145 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"project/kyta"
+)
+
+// Gateway error-rate defaults, overridable with ALERT_GATEWAY_ERROR_RATE
+// (0-1) and ALERT_GATEWAY_MIN_CALLS.
+const (
+	DefaultGatewayWindow    = 10 * time.Minute
+	DefaultGatewayErrorRate = 0.5
+	DefaultGatewayMinCalls  = 5
+)
+
+// GatewayMonitor is a kyta.Client that counts gateway calls and failures over
+// a sliding window and alerts when the failure rate crosses the threshold.
+type GatewayMonitor struct {
+	Client       kyta.Client
+	Alerts       *Alerter
+	Window       time.Duration
+	MaxErrorRate float64
+	MinCalls     int
+
+	mu    sync.Mutex
+	calls []gatewayCall
+}
+
+type gatewayCall struct {
+	at     time.Time
+	failed bool
+}
+
+// MonitorGateway wraps c, reading the thresholds from the environment.
+func MonitorGateway(c kyta.Client, a *Alerter) *GatewayMonitor {
+	m := &GatewayMonitor{Client: c, Alerts: a, Window: DefaultGatewayWindow, MaxErrorRate: DefaultGatewayErrorRate, MinCalls: DefaultGatewayMinCalls}
+	if v, err := strconv.ParseFloat(os.Getenv("ALERT_GATEWAY_ERROR_RATE"), 64); err == nil && v > 0 && v <= 1 {
+		m.MaxErrorRate = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ALERT_GATEWAY_MIN_CALLS")); err == nil && v > 0 {
+		m.MinCalls = v
+	}
+	return m
+}
+
+func (m *GatewayMonitor) CreateQRIS(ctx context.Context, p kyta.PaymentRequest) (*kyta.PaymentResponse, error) {
+	resp, err := m.Client.CreateQRIS(ctx, p)
+	m.record(err)
+	return resp, err
+}
+
+func (m *GatewayMonitor) CreateVA(ctx context.Context, p kyta.PaymentRequest) (*kyta.PaymentResponse, error) {
+	resp, err := m.Client.CreateVA(ctx, p)
+	m.record(err)
+	return resp, err
+}
+
+func (m *GatewayMonitor) CreatePayout(ctx context.Context, p kyta.PayoutRequest) (*kyta.PayoutResponse, error) {
+	resp, err := m.Client.CreatePayout(ctx, p)
+	m.record(err)
+	return resp, err
+}
+
+// InquireAccount passes the inquiry on when the wrapped client supports it
+// and answers kyta.ErrInquiryUnsupported otherwise.
+func (m *GatewayMonitor) InquireAccount(ctx context.Context, bankCode, accountNumber string) (*kyta.AccountInquiryResponse, error) {
+	inq, ok := m.Client.(kyta.AccountInquirer)
+	if !ok {
+		return nil, kyta.ErrInquiryUnsupported
+	}
+	resp, err := inq.InquireAccount(ctx, bankCode, accountNumber)
+	m.record(err)
+	return resp, err
+}
+
+// CheckPayment passes the lookup on when the wrapped client supports it and
+// answers kyta.ErrCheckUnsupported otherwise.
+func (m *GatewayMonitor) CheckPayment(ctx context.Context, referenceID string) (*kyta.PaymentStatusResponse, error) {
+	pc, ok := m.Client.(kyta.PaymentChecker)
+	if !ok {
+		return nil, kyta.ErrCheckUnsupported
+	}
+	resp, err := pc.CheckPayment(ctx, referenceID)
+	m.record(err)
+	return resp, err
+}
+
+// Stats returns the calls and failures within the window.
+func (m *GatewayMonitor) Stats() (calls, failed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+	for _, c := range m.calls {
+		if c.failed {
+			failed++
+		}
+	}
+	return len(m.calls), failed
+}
+
+// Check alerts when the failure rate in the window is over the threshold and
+// reports whether it was.
+func (m *GatewayMonitor) Check() bool {
+	calls, failed := m.Stats()
+	if calls < m.MinCalls || float64(failed)/float64(calls) < m.MaxErrorRate {
+		return false
+	}
+	m.Alerts.Notify(KeyGatewayErrors, "KytaPay error rate tinggi: %d dari %d panggilan gagal dalam %s terakhir", failed, calls, m.Window)
+	return true
+}
+
+// record counts one call. A missing configuration, or a lookup the client
+// cannot make, is not the gateway's fault and is left out of the rate.
+func (m *GatewayMonitor) record(err error) {
+	if errors.Is(err, kyta.ErrNotConfigured) || errors.Is(err, kyta.ErrInquiryUnsupported) || errors.Is(err, kyta.ErrCheckUnsupported) {
+		return
+	}
+	m.mu.Lock()
+	now := time.Now()
+	m.prune(now)
+	m.calls = append(m.calls, gatewayCall{at: now, failed: err != nil})
+	m.mu.Unlock()
+	if err != nil {
+		m.Check()
+	}
+}
+
+// prune drops calls older than the window. m.mu must be held.
+func (m *GatewayMonitor) prune(now time.Time) {
+	window := m.Window
+	if window <= 0 {
+		window = DefaultGatewayWindow
+	}
+	cut := 0
+	for cut < len(m.calls) && now.Sub(m.calls[cut].at) > window {
+		cut++
+	}
+	m.calls = m.calls[cut:]
+}
@@ -0,0 +1,225 @@
+// Package cache keeps hot, shared read data in memory for a short TTL.
+//
+// Each Cache is named and registered so the admin metrics endpoint can report
+// its hits and misses. Entries expire after the TTL, and the admin endpoints
+// that change the underlying rows call Invalidate, so this instance serves the
+// change at once and other instances within one TTL. Only data that is the
+// same for every caller belongs in a cache; per-user flags are computed on top
+// of it on each request. A request carrying the bypass flag (see WithBypass)
+// reads afresh and refreshes the entry, for debugging staleness complaints.
+package cache
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTTL is used when READ_CACHE_TTL is unset or invalid.
+const DefaultTTL = 30 * time.Second
+
+// EnvTTL, passed to New, makes the cache read READ_CACHE_TTL when first used
+// rather than when created, so package-level caches see the .env main loads.
+const EnvTTL time.Duration = -1
+
+// Outcome is how a Load was served, as sent in the X-Cache response header.
+type Outcome string
+
+const (
+	Hit    Outcome = "HIT"
+	Miss   Outcome = "MISS"
+	Bypass Outcome = "BYPASS"
+)
+
+// TTL reads READ_CACHE_TTL as a Go duration ("30s", "2m"); "0" turns
+// caching off.
+func TTL() time.Duration {
+	v := strings.TrimSpace(os.Getenv("READ_CACHE_TTL"))
+	if v == "0" {
+		return 0
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultTTL
+}
+
+type entry[V any] struct {
+	value    V
+	loadedAt time.Time
+}
+
+// Cache maps keys to values loaded on a miss. The zero TTL caches nothing.
+type Cache[V any] struct {
+	name    string
+	ttl     time.Duration
+	ttlOnce sync.Once
+
+	mu      sync.RWMutex
+	entries map[string]entry[V]
+	// gen is bumped by Invalidate so a load that started before it is not
+	// stored over the invalidation
+	gen uint64
+
+	hits, misses, bypasses, invalidations atomic.Int64
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New returns a registered cache whose entries live for ttl, or for TTL()
+// when ttl is EnvTTL.
+func New[V any](name string, ttl time.Duration) *Cache[V] {
+	c := &Cache[V]{name: name, ttl: ttl, entries: map[string]entry[V]{}, now: time.Now}
+	register(c)
+	return c
+}
+
+// Load returns the value cached under key, calling load on a miss, an expired
+// entry or a bypassed request. Errors are not cached. Concurrent misses each
+// call load; the last to finish is kept.
+func (c *Cache[V]) Load(ctx context.Context, key string, load func() (V, error)) (V, Outcome, error) {
+	outcome := Miss
+	if Bypassed(ctx) {
+		outcome = Bypass
+	} else {
+		c.mu.RLock()
+		e, ok := c.entries[key]
+		c.mu.RUnlock()
+		if ok && c.now().Sub(e.loadedAt) < c.lifetime() {
+			c.hits.Add(1)
+			return e.value, Hit, nil
+		}
+	}
+	if outcome == Bypass {
+		c.bypasses.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	c.mu.RLock()
+	gen := c.gen
+	c.mu.RUnlock()
+	v, err := load()
+	if err != nil || c.lifetime() <= 0 {
+		return v, outcome, err
+	}
+	c.mu.Lock()
+	if c.gen == gen {
+		c.entries[key] = entry[V]{value: v, loadedAt: c.now()}
+	}
+	c.mu.Unlock()
+	return v, outcome, nil
+}
+
+// lifetime is how long entries live, resolving EnvTTL on first use.
+func (c *Cache[V]) lifetime() time.Duration {
+	c.ttlOnce.Do(func() {
+		if c.ttl == EnvTTL {
+			c.ttl = TTL()
+		}
+	})
+	return c.ttl
+}
+
+// Invalidate drops every entry, so the next Load of any key reads afresh.
+func (c *Cache[V]) Invalidate() {
+	c.mu.Lock()
+	c.entries = map[string]entry[V]{}
+	c.gen++
+	c.mu.Unlock()
+	c.invalidations.Add(1)
+}
+
+// Stats is one cache's counters since the process started.
+type Stats struct {
+	Name          string  `json:"name"`
+	TTLSeconds    float64 `json:"ttl_seconds"`
+	Entries       int     `json:"entries"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	Bypasses      int64   `json:"bypasses"`
+	Invalidations int64   `json:"invalidations"`
+	// HitRatio is hits over hits and misses, 0 before any Load
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// Stats reports c's counters.
+func (c *Cache[V]) Stats() Stats {
+	c.mu.RLock()
+	n := len(c.entries)
+	c.mu.RUnlock()
+	s := Stats{
+		Name:          c.name,
+		TTLSeconds:    c.lifetime().Seconds(),
+		Entries:       n,
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Bypasses:      c.bypasses.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+type registered interface {
+	Stats() Stats
+	Invalidate()
+}
+
+var registry struct {
+	mu     sync.Mutex
+	caches []registered
+}
+
+func register(c registered) {
+	registry.mu.Lock()
+	registry.caches = append(registry.caches, c)
+	registry.mu.Unlock()
+}
+
+func all() []registered {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	return append([]registered(nil), registry.caches...)
+}
+
+// Snapshot returns the stats of every cache, sorted by name.
+func Snapshot() []Stats {
+	caches := all()
+	out := make([]Stats, 0, len(caches))
+	for _, c := range caches {
+		out = append(out, c.Stats())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// InvalidateAll empties every cache. Tests call it between cases.
+func InvalidateAll() {
+	for _, c := range all() {
+		c.Invalidate()
+	}
+}
+
+type bypassKey struct{}
+
+// WithBypass marks ctx so every Load made with it reads afresh.
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed reports whether ctx was marked by WithBypass.
+func Bypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	b, _ := ctx.Value(bypassKey{}).(bool)
+	return b
+}
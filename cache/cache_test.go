@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	c := New[int]("test_load", time.Minute)
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+	calls := 0
+	load := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+	ctx := context.Background()
+
+	for i, want := range []struct {
+		value   int
+		outcome Outcome
+	}{{1, Miss}, {1, Hit}} {
+		v, outcome, err := c.Load(ctx, "k", load)
+		if err != nil || v != want.value || outcome != want.outcome {
+			t.Fatalf("load %d = %d, %s, %v; want %d, %s", i+1, v, outcome, err, want.value, want.outcome)
+		}
+	}
+	// Keys are cached apart
+	if v, outcome, _ := c.Load(ctx, "other", load); v != 2 || outcome != Miss {
+		t.Fatalf("other key = %d, %s", v, outcome)
+	}
+
+	// Expiry, invalidation and bypass all read afresh
+	now = now.Add(time.Minute)
+	if v, outcome, _ := c.Load(ctx, "k", load); v != 3 || outcome != Miss {
+		t.Fatalf("after the ttl = %d, %s", v, outcome)
+	}
+	c.Invalidate()
+	if v, outcome, _ := c.Load(ctx, "k", load); v != 4 || outcome != Miss {
+		t.Fatalf("after invalidation = %d, %s", v, outcome)
+	}
+	if v, outcome, _ := c.Load(WithBypass(ctx), "k", load); v != 5 || outcome != Bypass {
+		t.Fatalf("bypassed = %d, %s", v, outcome)
+	}
+	// A bypass refreshes the entry for everyone
+	if v, outcome, _ := c.Load(ctx, "k", load); v != 5 || outcome != Hit {
+		t.Fatalf("after bypass = %d, %s", v, outcome)
+	}
+
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 4 || s.Bypasses != 1 || s.Invalidations != 1 || s.Entries != 1 || s.HitRatio != 2.0/6 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestLoadErrorsAndInvalidationDuringLoad(t *testing.T) {
+	c := New[string]("test_errors", time.Minute)
+	ctx := context.Background()
+
+	if _, _, err := c.Load(ctx, "k", func() (string, error) { return "", errors.New("down") }); err == nil {
+		t.Fatal("expected the load error")
+	}
+	if _, outcome, _ := c.Load(ctx, "k", func() (string, error) { return "up", nil }); outcome != Miss {
+		t.Fatalf("an error was cached: %s", outcome)
+	}
+
+	// A value read before an invalidation is not stored over it
+	c.Invalidate()
+	if v, _, _ := c.Load(ctx, "k", func() (string, error) {
+		c.Invalidate()
+		return "stale", nil
+	}); v != "stale" {
+		t.Fatalf("load returned %q", v)
+	}
+	if v, outcome, _ := c.Load(ctx, "k", func() (string, error) { return "fresh", nil }); v != "fresh" || outcome != Miss {
+		t.Fatalf("stale value stored: %q, %s", v, outcome)
+	}
+}
+
+func TestZeroTTLCachesNothing(t *testing.T) {
+	c := New[int]("test_off", 0)
+	for i := 0; i < 2; i++ {
+		if _, outcome, _ := c.Load(context.Background(), "k", func() (int, error) { return 1, nil }); outcome != Miss {
+			t.Fatalf("load %d = %s, want MISS", i+1, outcome)
+		}
+	}
+}
+
+func TestEnvTTL(t *testing.T) {
+	c := New[int]("test_env", EnvTTL)
+	t.Setenv("READ_CACHE_TTL", "2m")
+	if s := c.Stats(); s.TTLSeconds != 120 {
+		t.Fatalf("ttl = %vs, want READ_CACHE_TTL read on first use", s.TTLSeconds)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	for v, want := range map[string]time.Duration{"": DefaultTTL, "0": 0, "2m": 2 * time.Minute, "soon": DefaultTTL, "-1s": DefaultTTL} {
+		t.Setenv("READ_CACHE_TTL", v)
+		if got := TTL(); got != want {
+			t.Errorf("TTL() with %q = %s, want %s", v, got, want)
+		}
+	}
+}
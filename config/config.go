@@ -0,0 +1,197 @@
+// Package config loads the environment configuration into typed fields.
+//
+// main loads it once at startup and refuses to start while a required
+// integration is missing a variable, listing every one missing, so a
+// deployment cannot, for example, create KytaPay payments with empty callback
+// URLs whose webhooks never arrive. Handlers read it through Get. Check
+// reports per integration which variables are missing, never their values.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults used when the variable is unset or invalid.
+const (
+	DefaultWithdrawalSLAHours = 24
+	DefaultExpressSLAMinutes  = 15
+)
+
+// Kyta is the KytaPay gateway: credentials and the callback URLs sent with
+// every payment and payout.
+type Kyta struct {
+	// BaseURL is empty for kyta.DefaultBaseURL
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	// NotifyURL receives payment webhooks; SuccessURL and FailedURL are
+	// where the payment page sends the user back
+	NotifyURL  string
+	SuccessURL string
+	FailedURL  string
+	// PayoutNotifyURL receives payout webhooks (CALLBACK_WITHDRAW)
+	PayoutNotifyURL string
+}
+
+// Withdrawal holds the payout times users are quoted. The fee is the
+// settings' withdraw_charge.
+type Withdrawal struct {
+	SLAHours          int
+	ExpressSLAMinutes int
+}
+
+// Config is the environment configuration.
+type Config struct {
+	Env        string
+	Kyta       Kyta
+	Withdrawal Withdrawal
+}
+
+// Integration is one external dependency and the variables it needs.
+type Integration struct {
+	Name string
+	// Required integrations must be complete for the server to start;
+	// optional ones are off until all their variables are set
+	Required bool
+	Vars     []string
+	// URLs are the Vars that must be absolute http(s) URLs
+	URLs []string
+}
+
+// Integrations lists what Check reports and Validate enforces.
+var Integrations = []Integration{
+	{Name: "database", Required: true, Vars: []string{"DB_HOST", "DB_USER", "DB_PASS", "DB_NAME"}},
+	{Name: "jwt", Required: true, Vars: []string{"JWT_SECRET"}},
+	{Name: "kytapay", Required: true,
+		Vars: []string{"KYTAPAY_CLIENT_ID", "KYTAPAY_CLIENT_SECRET", "NOTIFY_URL", "SUCCESS_URL", "FAILED_URL", "CALLBACK_WITHDRAW"},
+		URLs: []string{"NOTIFY_URL", "SUCCESS_URL", "FAILED_URL", "CALLBACK_WITHDRAW"}},
+	{Name: "cron", Vars: []string{"CRON_KEY"}},
+	{Name: "redis", Vars: []string{"REDIS_ADDR"}},
+	{Name: "s3", Vars: []string{"S3_REGION", "S3_ACCESS_KEY", "S3_SECRET_KEY", "S3_BUCKET"}},
+	{Name: "sfxcr", Vars: []string{"SFXCR_API_KEY"}},
+	{Name: "fcm", Vars: []string{"FCM_SERVICE_ACCOUNT_FILE"}},
+	{Name: "telegram", Vars: []string{"TELEGRAM_BOT_TOKEN", "TELEGRAM_CHAT_ID"}},
+	{Name: "read_replica", Vars: []string{"DB_REPLICA_DSN"}},
+	{Name: "geoip", Vars: []string{"GEOIP_DB_PATH"}},
+	{Name: "settlement_export", Vars: []string{"SETTLEMENT_S3_BUCKET", "S3_ACCESS_KEY", "S3_SECRET_KEY"}},
+}
+
+// IntegrationStatus is Check's report on one integration. It names the
+// variables at fault but never carries their values.
+type IntegrationStatus struct {
+	Name       string   `json:"name"`
+	Required   bool     `json:"required"`
+	Configured bool     `json:"configured"`
+	Missing    []string `json:"missing,omitempty"`
+	Invalid    []string `json:"invalid,omitempty"`
+}
+
+// Check reports every integration against the current environment.
+func Check() []IntegrationStatus {
+	out := make([]IntegrationStatus, 0, len(Integrations))
+	for _, in := range Integrations {
+		st := IntegrationStatus{Name: in.Name, Required: in.Required}
+		for _, v := range in.Vars {
+			if env(v) == "" {
+				st.Missing = append(st.Missing, v)
+			}
+		}
+		for _, v := range in.URLs {
+			if s := env(v); s != "" && !absoluteURL(s) {
+				st.Invalid = append(st.Invalid, v)
+			}
+		}
+		st.Configured = len(st.Missing) == 0 && len(st.Invalid) == 0
+		out = append(out, st)
+	}
+	return out
+}
+
+// Validate returns an error listing every variable a required integration is
+// missing or has invalid.
+func Validate() error {
+	var missing, invalid []string
+	for _, st := range Check() {
+		if st.Required {
+			missing = append(missing, st.Missing...)
+			invalid = append(invalid, st.Invalid...)
+		}
+	}
+	var parts []string
+	if len(missing) > 0 {
+		parts = append(parts, "missing required environment variables: "+strings.Join(missing, ", "))
+	}
+	if len(invalid) > 0 {
+		parts = append(parts, "not absolute http(s) URLs: "+strings.Join(invalid, ", "))
+	}
+	if len(parts) > 0 {
+		return fmt.Errorf("%s", strings.Join(parts, "; "))
+	}
+	return nil
+}
+
+// FromEnv reads the configuration from the environment, with defaults for
+// what is unset or invalid. It does not validate.
+func FromEnv() *Config {
+	c := &Config{
+		Env: strings.ToLower(env("ENV")),
+		Kyta: Kyta{
+			BaseURL:         env("KYTAPAY_BASE_URL"),
+			ClientID:        env("KYTAPAY_CLIENT_ID"),
+			ClientSecret:    env("KYTAPAY_CLIENT_SECRET"),
+			NotifyURL:       env("NOTIFY_URL"),
+			SuccessURL:      env("SUCCESS_URL"),
+			FailedURL:       env("FAILED_URL"),
+			PayoutNotifyURL: env("CALLBACK_WITHDRAW"),
+		},
+		Withdrawal: Withdrawal{
+			SLAHours:          DefaultWithdrawalSLAHours,
+			ExpressSLAMinutes: DefaultExpressSLAMinutes,
+		},
+	}
+	if v, err := strconv.Atoi(env("WITHDRAWAL_SLA_HOURS")); err == nil && v > 0 {
+		c.Withdrawal.SLAHours = v
+	}
+	if v, err := strconv.Atoi(env("EXPRESS_WITHDRAWAL_SLA_MINUTES")); err == nil && v > 0 {
+		c.Withdrawal.ExpressSLAMinutes = v
+	}
+	return c
+}
+
+var current struct {
+	sync.RWMutex
+	cfg *Config
+}
+
+// Set makes c the configuration Get returns. main calls it once validated.
+func Set(c *Config) {
+	current.Lock()
+	current.cfg = c
+	current.Unlock()
+}
+
+// Get returns the configuration set at startup. Until Set is called, as in
+// tests, it reads the environment afresh on every call.
+func Get() *Config {
+	current.RLock()
+	c := current.cfg
+	current.RUnlock()
+	if c == nil {
+		return FromEnv()
+	}
+	return c
+}
+
+func env(name string) string {
+	return strings.TrimSpace(os.Getenv(name))
+}
+
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// A deployment missing KytaPay's callback URLs must not start, and the error
+// names every missing variable.
+func TestValidateListsEveryMissingVariable(t *testing.T) {
+	for _, v := range []string{"DB_HOST", "DB_USER", "DB_PASS", "DB_NAME", "JWT_SECRET", "KYTAPAY_CLIENT_ID", "KYTAPAY_CLIENT_SECRET", "CALLBACK_WITHDRAW"} {
+		t.Setenv(v, "set")
+	}
+	t.Setenv("CALLBACK_WITHDRAW", "https://api.example.test/v3/callback/payment")
+	t.Setenv("NOTIFY_URL", "")
+	t.Setenv("SUCCESS_URL", "")
+	t.Setenv("FAILED_URL", "/relative")
+
+	err := Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	msg := err.Error()
+	for _, want := range []string{"NOTIFY_URL", "SUCCESS_URL", "FAILED_URL"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not name %s", msg, want)
+		}
+	}
+	if strings.Contains(msg, "KYTAPAY_CLIENT_ID") {
+		t.Errorf("error %q names a variable that is set", msg)
+	}
+
+	t.Setenv("NOTIFY_URL", "https://api.example.test/v3/payments/kyta/webhook")
+	t.Setenv("SUCCESS_URL", "https://example.test")
+	t.Setenv("FAILED_URL", "https://example.test")
+	if err := Validate(); err != nil {
+		t.Fatalf("complete configuration rejected: %v", err)
+	}
+}
+
+func TestCheckNeverShowsValues(t *testing.T) {
+	t.Setenv("KYTAPAY_CLIENT_SECRET", "super-secret-value")
+	t.Setenv("TELEGRAM_BOT_TOKEN", "bot-token-value")
+	t.Setenv("TELEGRAM_CHAT_ID", "")
+	b, err := json.Marshal(Check())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "super-secret-value") || strings.Contains(string(b), "bot-token-value") {
+		t.Fatalf("check leaks a value: %s", b)
+	}
+	for _, st := range Check() {
+		if st.Name == "telegram" && (st.Configured || len(st.Missing) != 1 || st.Missing[0] != "TELEGRAM_CHAT_ID") {
+			t.Fatalf("telegram: got %+v", st)
+		}
+	}
+}
+
+func TestFromEnvDefaults(t *testing.T) {
+	t.Setenv("WITHDRAWAL_SLA_HOURS", "6")
+	t.Setenv("EXPRESS_WITHDRAWAL_SLA_MINUTES", "abc")
+	c := FromEnv()
+	if c.Withdrawal.SLAHours != 6 || c.Withdrawal.ExpressSLAMinutes != DefaultExpressSLAMinutes {
+		t.Fatalf("got %+v", c.Withdrawal)
+	}
+}
@@ -1,7 +1,6 @@
 package admins
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -122,11 +121,7 @@ func UpdateAdminProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req updateAdminProfileRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -219,11 +214,7 @@ func UpdateAdminPassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req updateAdminPasswordRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -257,6 +248,7 @@ func UpdateAdminPassword(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
 			Success: false,
 			Message: "Password saat ini salah",
+			Code:    utils.CodeWrongPassword,
 		})
 		return
 	}
@@ -264,6 +256,7 @@ func UpdateAdminPassword(w http.ResponseWriter, r *http.Request) {
 	// Set new password and hash
 	admin.Password = req.NewPassword
 	if err := admin.HashPassword(); err != nil {
+		utils.LogError(r, "UpdateAdminPassword", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengatur ulang password",
@@ -271,6 +264,7 @@ func UpdateAdminPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := database.DB.Model(&admin).Update("password", admin.Password).Error; err != nil {
+		utils.LogError(r, "UpdateAdminPassword", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal menyimpan password baru",
@@ -0,0 +1,83 @@
+package admins
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"project/alert"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// defaultPendingWithdrawalHours is how old a Pending withdrawal may get before
+// the alert check reports it, when ALERT_PENDING_WITHDRAWAL_HOURS is not set.
+const defaultPendingWithdrawalHours = 6
+
+// pendingWithdrawalHours is ALERT_PENDING_WITHDRAWAL_HOURS or its default.
+func pendingWithdrawalHours() int {
+	if v, err := strconv.Atoi(os.Getenv("ALERT_PENDING_WITHDRAWAL_HOURS")); err == nil && v > 0 {
+		return v
+	}
+	return defaultPendingWithdrawalHours
+}
+
+// AlertCheckHandler evaluates the ops alert thresholds that no single request
+// can see: withdrawals waiting too long and the gateway error rate.
+type AlertCheckHandler struct {
+	DB      *gorm.DB
+	Alerts  *alert.Alerter
+	Gateway *alert.GatewayMonitor
+}
+
+func NewAlertCheckHandler(db *gorm.DB, a *alert.Alerter, gw *alert.GatewayMonitor) *AlertCheckHandler {
+	return &AlertCheckHandler{DB: db, Alerts: a, Gateway: gw}
+}
+
+// POST /api/cron/alert-check
+// Meant to run every few minutes; repeated findings are deduplicated by the
+// alerter's cooldown.
+func (h *AlertCheckHandler) Run(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	hours := pendingWithdrawalHours()
+	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	var stale struct {
+		Count  int64
+		Amount int64
+		Oldest *time.Time
+	}
+	if err := h.DB.Model(&models.Withdrawal{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount, MIN(created_at) AS oldest").
+		Where("status = ? AND created_at < ?", "Pending", cutoff).
+		Scan(&stale).Error; err != nil {
+		utils.LogError(r, "alert check: pending withdrawals", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	if stale.Count > 0 && stale.Oldest != nil {
+		h.Alerts.Notify(alert.KeyPendingWithdrawals, "%d penarikan (Rp%d) Pending lebih dari %d jam, tertua sejak %s", stale.Count, stale.Amount, hours, utils.FormatTime(*stale.Oldest))
+	}
+
+	gatewayAlert := false
+	calls, failed := 0, 0
+	if h.Gateway != nil {
+		calls, failed = h.Gateway.Stats()
+		gatewayAlert = h.Gateway.Check()
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{
+		"stale_withdrawals": stale.Count,
+		"gateway_calls":     calls,
+		"gateway_failures":  failed,
+		"gateway_alert":     gatewayAlert,
+	}})
+}
@@ -0,0 +1,270 @@
+package admins
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// announcementBatchSize is the number of user ids covered by one notification insert.
+const announcementBatchSize = 1000
+
+type announcementRequest struct {
+	Title     *string    `json:"title"`
+	Body      *string    `json:"body"`
+	MinLevel  *uint      `json:"min_level"`
+	MaxLevel  *uint      `json:"max_level"`
+	PublishAt *time.Time `json:"publish_at"`
+	ExpireAt  *time.Time `json:"expire_at"`
+	Pinned    *bool      `json:"pinned"`
+	Status    string     `json:"status"`
+}
+
+// GET /api/admin/announcements
+func ListAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	query := db.Model(&models.Announcement{})
+	if status := r.URL.Query().Get("status"); status == "Active" || status == "Inactive" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListAnnouncementsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var announcements []models.Announcement
+	if err := query.Order("pinned DESC, publish_at DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&announcements).Error; err != nil {
+		utils.LogError(r, "ListAnnouncementsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(announcements, pg, totalRows),
+	})
+}
+
+// POST /api/admin/announcements
+// Notification rows for the targeted users are created in the background.
+func CreateAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	var req announcementRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	ann := models.Announcement{
+		PublishAt: time.Now(),
+		Status:    "Active",
+	}
+	if msg := applyAnnouncementRequest(&ann, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	db := database.DB
+	if err := db.Create(&ann).Error; err != nil {
+		utils.LogError(r, "CreateAnnouncementHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat pengumuman"})
+		return
+	}
+
+	go deliverAnnouncement(db, ann)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Pengumuman berhasil dibuat",
+		Data:    ann,
+	})
+}
+
+// PUT /api/admin/announcements/{id}
+func UpdateAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req announcementRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	var ann models.Announcement
+	if err := db.First(&ann, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengumuman tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "UpdateAnnouncementHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	prevMin, prevMax := ann.MinLevel, ann.MaxLevel
+	if msg := applyAnnouncementRequest(&ann, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if err := db.Save(&ann).Error; err != nil {
+		utils.LogError(r, "UpdateAnnouncementHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate pengumuman"})
+		return
+	}
+
+	// A widened target needs notifications for the newly included users
+	if !sameLevelBound(prevMin, ann.MinLevel) || !sameLevelBound(prevMax, ann.MaxLevel) {
+		go deliverAnnouncement(db, ann)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Pengumuman berhasil diupdate",
+		Data:    ann,
+	})
+}
+
+// DELETE /api/admin/announcements/{id}
+// Deactivates the announcement; notification rows are kept for read history.
+func DeleteAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	db := database.DB
+	res := db.Model(&models.Announcement{}).Where("id = ?", id).Update("status", "Inactive")
+	if res.Error != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus pengumuman"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengumuman tidak ditemukan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Pengumuman berhasil dihapus",
+	})
+}
+
+// applyAnnouncementRequest copies the provided fields onto ann and validates
+// the result, returning a user-facing message when invalid.
+func applyAnnouncementRequest(ann *models.Announcement, req *announcementRequest) string {
+	if req.Title != nil {
+		ann.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Body != nil {
+		ann.Body = strings.TrimSpace(*req.Body)
+	}
+	if req.MinLevel != nil {
+		ann.MinLevel = req.MinLevel
+	}
+	if req.MaxLevel != nil {
+		ann.MaxLevel = req.MaxLevel
+	}
+	if req.PublishAt != nil {
+		ann.PublishAt = *req.PublishAt
+	}
+	if req.ExpireAt != nil {
+		ann.ExpireAt = req.ExpireAt
+	}
+	if req.Pinned != nil {
+		ann.Pinned = *req.Pinned
+	}
+	if req.Status == "Active" || req.Status == "Inactive" {
+		ann.Status = req.Status
+	}
+
+	if ann.Title == "" {
+		return "Judul wajib diisi"
+	}
+	if ann.Body == "" {
+		return "Isi pengumuman wajib diisi"
+	}
+	if ann.MinLevel != nil && *ann.MinLevel > models.MaxVIPLevel {
+		return "Level minimum tidak valid"
+	}
+	if ann.MaxLevel != nil && *ann.MaxLevel > models.MaxVIPLevel {
+		return "Level maksimum tidak valid"
+	}
+	if ann.MinLevel != nil && ann.MaxLevel != nil && *ann.MinLevel > *ann.MaxLevel {
+		return "Level minimum tidak boleh lebih besar dari level maksimum"
+	}
+	if ann.ExpireAt != nil && !ann.ExpireAt.After(ann.PublishAt) {
+		return "Waktu berakhir harus setelah waktu publikasi"
+	}
+	return ""
+}
+
+func sameLevelBound(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// deliverAnnouncement creates a notification row for every targeted active user.
+// Users are walked in id ranges so each insert touches a bounded number of rows,
+// and INSERT IGNORE on (announcement_id, user_id) makes re-runs harmless.
+func deliverAnnouncement(db *gorm.DB, ann models.Announcement) {
+	var maxID uint
+	if err := db.Model(&models.User{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		log.Printf("[announcement] delivery %d: load max user id: %v", ann.ID, err)
+		return
+	}
+
+	levelFilter := ""
+	args := []interface{}{}
+	if ann.MinLevel != nil {
+		levelFilter += " AND COALESCE(level, 0) >= ?"
+		args = append(args, *ann.MinLevel)
+	}
+	if ann.MaxLevel != nil {
+		levelFilter += " AND COALESCE(level, 0) <= ?"
+		args = append(args, *ann.MaxLevel)
+	}
+
+	stmt := "INSERT IGNORE INTO notifications (user_id, announcement_id, type, title, body, created_at) " +
+		"SELECT id, ?, 'announcement', ?, ?, ? FROM users WHERE id > ? AND id <= ? AND status = 'Active'" + levelFilter
+
+	var delivered int64
+	now := time.Now()
+	for start := uint(0); start < maxID; start += announcementBatchSize {
+		batchArgs := append([]interface{}{ann.ID, ann.Title, ann.Body, now, start, start + announcementBatchSize}, args...)
+		res := db.Exec(stmt, batchArgs...)
+		if res.Error != nil {
+			log.Printf("[announcement] delivery %d: batch after user %d: %v", ann.ID, start, res.Error)
+			return
+		}
+		delivered += res.RowsAffected
+		// Give replication and concurrent writers room between batches
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	db.Model(&models.Announcement{}).Where("id = ?", ann.ID).Update("delivered_at", time.Now())
+	log.Printf("[announcement] delivery %d: %d notifications created", ann.ID, delivered)
+}
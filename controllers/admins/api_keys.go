@@ -0,0 +1,182 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAPIKeyRateLimit is the requests per minute when none is given.
+	defaultAPIKeyRateLimit = 60
+	// maxAPIKeyRateLimit bounds the requests per minute a key may be given.
+	maxAPIKeyRateLimit = 600
+	// maxAPIKeyDays bounds how far ahead a key may expire.
+	maxAPIKeyDays = 365
+)
+
+type apiKeyRequest struct {
+	Name          string     `json:"name"`
+	Scopes        []string   `json:"scopes"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	ExpiresInDays *int       `json:"expires_in_days"`
+	RateLimit     *int       `json:"rate_limit"`
+}
+
+// apiKeyWithSecret shows the key itself, which is only returned when it is
+// created.
+type apiKeyWithSecret struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// GET /api/admin/api-keys
+// Every API key, newest first, with its hint and last use but never the key.
+func ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys := []models.APIKey{}
+	if err := database.DB.Order("id DESC").Find(&keys).Error; err != nil {
+		utils.LogError(r, "ListAPIKeys", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"keys":   keys,
+		"scopes": models.APIKeyScopes,
+	}})
+}
+
+// POST /api/admin/api-keys
+// Creates a key acting as the calling admin. Exactly one of expires_at and
+// expires_in_days is required. The response carries the key; it is not
+// shown again.
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := utils.GetAdminID(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	var req apiKeyRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	key := models.APIKey{AdminID: adminID}
+	if msg := applyAPIKeyRequest(&key, &req, time.Now()); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	secret, hash, err := models.NewAPIKeySecret()
+	if err != nil {
+		utils.LogError(r, "CreateAPIKey: secret", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	key.KeyHash = hash
+	key.Hint = secret[:len(models.APIKeyPrefix)+6]
+
+	if err := database.DB.Create(&key).Error; err != nil {
+		utils.LogError(r, "CreateAPIKey", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat API key"})
+		return
+	}
+	auditLogTarget(r, "api_key.create", "api_key", key.ID, nil, key)
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "API key berhasil dibuat", Data: apiKeyWithSecret{APIKey: key, Key: secret}})
+}
+
+// DELETE /api/admin/api-keys/{id}
+// Revokes the key at once. The row stays, for the audit trail.
+func RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var key models.APIKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "API key tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "RevokeAPIKey", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	if key.RevokedAt != nil {
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "API key sudah dicabut", Data: key})
+		return
+	}
+	before := key
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := database.DB.Model(&key).Update("revoked_at", now).Error; err != nil {
+		utils.LogError(r, "RevokeAPIKey", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mencabut API key"})
+		return
+	}
+	auditLogTarget(r, "api_key.revoke", "api_key", key.ID, before, key)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "API key berhasil dicabut", Data: key})
+}
+
+// applyAPIKeyRequest validates req and copies it onto key, returning a
+// user-facing message when invalid.
+func applyAPIKeyRequest(key *models.APIKey, req *apiKeyRequest, now time.Time) string {
+	key.Name = strings.TrimSpace(req.Name)
+	if key.Name == "" || len(key.Name) > 100 {
+		return "Nama API key wajib diisi (maksimal 100 karakter)"
+	}
+
+	valid := map[string]bool{}
+	for _, s := range models.APIKeyScopes {
+		valid[s] = true
+	}
+	seen := map[string]bool{}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		s = strings.TrimSpace(s)
+		if !valid[s] {
+			return "Scope tidak dikenal: " + s
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return "Pilih minimal satu scope"
+	}
+	key.Scopes = strings.Join(scopes, ",")
+
+	switch {
+	case req.ExpiresAt != nil && req.ExpiresInDays != nil:
+		return "Isi salah satu dari expires_at atau expires_in_days"
+	case req.ExpiresAt != nil:
+		exp := *req.ExpiresAt
+		key.ExpiresAt = &exp
+	case req.ExpiresInDays != nil:
+		if *req.ExpiresInDays < 1 {
+			return "expires_in_days minimal 1"
+		}
+		exp := now.AddDate(0, 0, *req.ExpiresInDays)
+		key.ExpiresAt = &exp
+	default:
+		return "Masa berlaku API key wajib diisi"
+	}
+	if !key.ExpiresAt.After(now) || key.ExpiresAt.After(now.AddDate(0, 0, maxAPIKeyDays)) {
+		return "Masa berlaku API key harus di masa depan dan maksimal 365 hari"
+	}
+
+	key.RateLimit = defaultAPIKeyRateLimit
+	if req.RateLimit != nil {
+		if *req.RateLimit < 1 || *req.RateLimit > maxAPIKeyRateLimit {
+			return "rate_limit harus antara 1 dan 600 per menit"
+		}
+		key.RateLimit = *req.RateLimit
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package admins
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestApplyAPIKeyRequest(t *testing.T) {
+	now := time.Now()
+	days, limit := 30, 120
+	key := models.APIKey{}
+	req := apiKeyRequest{Name: " Recon ", Scopes: []string{models.ScopeReports, models.ScopeTransactions, models.ScopeReports}, ExpiresInDays: &days, RateLimit: &limit}
+	if msg := applyAPIKeyRequest(&key, &req, now); msg != "" {
+		t.Fatalf("valid request rejected: %s", msg)
+	}
+	if key.Name != "Recon" || key.Scopes != "reports:read,transactions:read" || key.RateLimit != 120 {
+		t.Fatalf("got %+v", key)
+	}
+	if !key.ExpiresAt.Equal(now.AddDate(0, 0, 30)) || !key.HasScope(models.ScopeTransactions) || key.HasScope(models.ScopeWithdrawals) {
+		t.Fatalf("got expiry %v scopes %q", key.ExpiresAt, key.Scopes)
+	}
+
+	past, far, zero, tooFast := now.Add(-time.Hour), now.AddDate(2, 0, 0), 0, 1000
+	for label, r := range map[string]apiKeyRequest{
+		"no name":         {Scopes: []string{models.ScopeReports}, ExpiresInDays: &days},
+		"unknown scope":   {Name: "x", Scopes: []string{"settings:write"}, ExpiresInDays: &days},
+		"no scope":        {Name: "x", ExpiresInDays: &days},
+		"no expiry":       {Name: "x", Scopes: []string{models.ScopeReports}},
+		"both expiries":   {Name: "x", Scopes: []string{models.ScopeReports}, ExpiresInDays: &days, ExpiresAt: &far},
+		"expired":         {Name: "x", Scopes: []string{models.ScopeReports}, ExpiresAt: &past},
+		"too far":         {Name: "x", Scopes: []string{models.ScopeReports}, ExpiresAt: &far},
+		"zero days":       {Name: "x", Scopes: []string{models.ScopeReports}, ExpiresInDays: &zero},
+		"rate over bound": {Name: "x", Scopes: []string{models.ScopeReports}, ExpiresInDays: &days, RateLimit: &tooFast},
+	} {
+		k := models.APIKey{}
+		if msg := applyAPIKeyRequest(&k, &r, now); msg == "" {
+			t.Errorf("%s: expected a validation message", label)
+		}
+	}
+
+	// A revoked or expired key no longer authenticates
+	revoked := now
+	if (&models.APIKey{RevokedAt: &revoked}).Usable(now) || (&models.APIKey{ExpiresAt: &past}).Usable(now) {
+		t.Fatal("revoked or expired key still usable")
+	}
+}
@@ -0,0 +1,93 @@
+package admins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAdminAuditLog(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Diaudit"})
+	asAdmin := func(r *http.Request, id int64) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), utils.AdminIDKey, id))
+	}
+
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/v3/admin/users/balance/%d", user.ID), strings.NewReader(`{"amount":50000,"type":"add"}`))
+	req = mux.SetURLVars(asAdmin(req, 7), map[string]string{"id": fmt.Sprint(user.ID)})
+	rec := httptest.NewRecorder()
+	UpdateUserBalance(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("balance: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	type auditEntry struct {
+		ID       uint            `json:"id"`
+		AdminID  uint            `json:"admin_id"`
+		Action   string          `json:"action"`
+		TargetID *uint           `json:"target_id"`
+		Before   json.RawMessage `json:"before"`
+		After    json.RawMessage `json:"after"`
+	}
+	list := func(query string) []auditEntry {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ListAuditLogs(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/audit-logs?"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list %q: expected 200, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data struct {
+				Data []auditEntry `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data.Data
+	}
+
+	logs := list(fmt.Sprintf("action=user.&target_type=user&target_id=%d", user.ID))
+	if len(logs) != 1 || logs[0].Action != "user.balance" || logs[0].AdminID != 7 || logs[0].TargetID == nil || *logs[0].TargetID != user.ID {
+		t.Fatalf("expected one user.balance log by admin 7, got %+v", logs)
+	}
+	var before, after struct {
+		Balance int64 `json:"balance"`
+	}
+	if err := json.Unmarshal(logs[0].Before, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(logs[0].After, &after); err != nil {
+		t.Fatal(err)
+	}
+	if before.Balance != 0 || after.Balance != 50000 {
+		t.Fatalf("expected balance 0 -> 50000, got %d -> %d", before.Balance, after.Balance)
+	}
+	if logs := list(fmt.Sprintf("admin_id=8&target_id=%d", user.ID)); len(logs) != 0 {
+		t.Fatalf("expected no logs by admin 8, got %+v", logs)
+	}
+
+	// Append-only
+	if err := tx.Delete(&models.AdminAuditLog{ID: logs[0].ID}).Error; !errors.Is(err, models.ErrAuditLogAppendOnly) {
+		t.Fatalf("expected delete refused, got %v", err)
+	}
+	if err := tx.Model(&models.AdminAuditLog{ID: logs[0].ID}).Update("action", "user.nothing").Error; !errors.Is(err, models.ErrAuditLogAppendOnly) {
+		t.Fatalf("expected update refused, got %v", err)
+	}
+}
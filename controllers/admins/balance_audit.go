@@ -0,0 +1,323 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"project/alert"
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// balanceAuditBatchSize is how many users one ledger query checks.
+const balanceAuditBatchSize = 500
+
+// ledgerCondition selects the transactions that moved a balance: Success
+// rows, plus Pending withdrawals, whose amount leaves the balance when they
+// are requested. Investments and TUP- top-ups are paid through the gateway,
+// and refund_payout and RPO- refunds go to the bank, so none ever touched a
+// balance.
+const ledgerCondition = "t.transaction_type NOT IN ('investment', 'refund_payout') AND " +
+	"NOT (t.transaction_type = 'investment_topup' AND t.order_id LIKE '" + utils.TopupOrderPrefix + "%') AND " +
+	"NOT (t.transaction_type = 'refund' AND t.order_id LIKE '" + utils.RefundPayoutOrderPrefix + "%') AND " +
+	"(t.status = 'Success' OR (t.status = 'Pending' AND t.transaction_type = 'withdrawal'))"
+
+// ledgerSum is the balance a user's counted transactions add up to: debits
+// flow into the balance, credits out of it.
+const ledgerSum = "COALESCE(SUM(CASE WHEN t.transaction_flow = 'debit' THEN t.amount ELSE -t.amount END), 0)"
+
+var errRepairNotConfirmed = errors.New("repair not confirmed")
+
+// BalanceAuditHandler compares every balance with the transaction ledger.
+type BalanceAuditHandler struct {
+	DB     *gorm.DB
+	Alerts *alert.Alerter
+}
+
+func NewBalanceAuditHandler(db *gorm.DB, a *alert.Alerter) *BalanceAuditHandler {
+	return &BalanceAuditHandler{DB: db, Alerts: a}
+}
+
+type ledgerCheck struct {
+	UserID        uint
+	Balance       int64
+	LedgerBalance int64
+}
+
+// POST /api/cron/balance-audit
+// Recomputes every user's balance from the ledger in batches and stores the
+// mismatches under one run_id. Alerts when more than
+// BALANCE_AUDIT_ALERT_COUNT users drift, or the absolute drift sums to more
+// than BALANCE_AUDIT_ALERT_AMOUNT rupiah (both default 0: any drift alerts).
+func (h *BalanceAuditHandler) Cron(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	runID := "BA-" + time.Now().In(utils.AppLocation()).Format("20060102-150405")
+	var checked, mismatched, drift int64
+	var lastID uint
+	for {
+		if utils.ShuttingDown(r) {
+			break
+		}
+		batch, err := checkLedgerBatch(h.DB, lastID)
+		if err != nil {
+			utils.LogError(r, "balance audit cron: ledger batch", err, "after_user_id", lastID)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].UserID
+		checked += int64(len(batch))
+
+		var audits []models.BalanceAudit
+		for _, c := range batch {
+			if c.Balance == c.LedgerBalance {
+				continue
+			}
+			d := c.Balance - c.LedgerBalance
+			audits = append(audits, models.BalanceAudit{RunID: runID, UserID: c.UserID, Balance: c.Balance, LedgerBalance: c.LedgerBalance, Drift: d})
+			mismatched++
+			drift += max(d, -d)
+		}
+		if len(audits) > 0 {
+			if err := h.DB.Create(&audits).Error; err != nil {
+				utils.LogError(r, "balance audit cron: save mismatches", err, "run_id", runID)
+				utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+				return
+			}
+		}
+	}
+
+	countLimit, _ := strconv.ParseInt(os.Getenv("BALANCE_AUDIT_ALERT_COUNT"), 10, 64)
+	amountLimit, _ := strconv.ParseInt(os.Getenv("BALANCE_AUDIT_ALERT_AMOUNT"), 10, 64)
+	alerted := mismatched > 0 && (mismatched > countLimit || drift > amountLimit)
+	if alerted {
+		h.Alerts.Notify(alert.KeyBalanceDrift, "Audit saldo %s: %d pengguna tidak cocok dengan ledger, total selisih Rp%d", runID, mismatched, drift)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{
+		"run_id":     runID,
+		"checked":    checked,
+		"mismatched": mismatched,
+		"drift":      drift,
+		"alerted":    alerted,
+	}})
+}
+
+// checkLedgerBatch reads the balance and ledger of the next
+// balanceAuditBatchSize users after afterID. One statement reads both, so a
+// balance change committing mid-batch cannot show up as drift.
+func checkLedgerBatch(db *gorm.DB, afterID uint) ([]ledgerCheck, error) {
+	var batch []ledgerCheck
+	err := db.Raw("SELECT u.id AS user_id, u.balance, "+
+		"(SELECT "+ledgerSum+" FROM transactions t WHERE t.user_id = u.id AND "+ledgerCondition+") AS ledger_balance "+
+		"FROM users u WHERE u.id > ? ORDER BY u.id ASC LIMIT ?", afterID, balanceAuditBatchSize).
+		Scan(&batch).Error
+	return batch, err
+}
+
+// ledgerBalance sums userID's ledger.
+func ledgerBalance(db *gorm.DB, userID uint) (int64, error) {
+	var sum int64
+	err := db.Table("transactions AS t").Select(ledgerSum).Where("t.user_id = ? AND "+ledgerCondition, userID).Scan(&sum).Error
+	return sum, err
+}
+
+// BalanceAuditResponse is a mismatch with the user's name and phone.
+type BalanceAuditResponse struct {
+	models.BalanceAudit
+	UserName string `json:"user_name"`
+	Phone    string `json:"phone"`
+}
+
+// GET /api/admin/balance-audits
+// Lists mismatches, newest first. Filters: run_id, user_id, and
+// unrepaired=true for those not repaired yet.
+func ListBalanceAudits(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	q := r.URL.Query()
+	query := database.DB.Table("balance_audits AS a").Joins("LEFT JOIN users u ON u.id = a.user_id")
+	if v := q.Get("run_id"); v != "" {
+		query = query.Where("a.run_id = ?", v)
+	}
+	if v := q.Get("user_id"); v != "" {
+		query = query.Where("a.user_id = ?", v)
+	}
+	if unrepaired, _ := strconv.ParseBool(q.Get("unrepaired")); unrepaired {
+		query = query.Where("a.repaired_at IS NULL")
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListBalanceAudits", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	rows := make([]BalanceAuditResponse, 0, pg.Limit)
+	if err := query.Select("a.*, u.name AS user_name, u.number AS phone").
+		Order("a.id DESC").Offset(pg.Offset).Limit(pg.Limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "ListBalanceAudits", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(rows, pg, totalRows)})
+}
+
+// LedgerEntry is one of the user's transactions; Counted tells whether it is
+// part of the ledger balance.
+type LedgerEntry struct {
+	TransactionResponse
+	Counted bool `json:"counted"`
+}
+
+// GET /api/admin/balance-audits/{id}
+// One mismatch with the user's balance and ledger as they are now, and a page
+// of the user's transactions, newest first.
+func GetBalanceAudit(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID audit tidak valid"})
+		return
+	}
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	var audit models.BalanceAudit
+	if err := db.First(&audit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Audit saldo tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "GetBalanceAudit", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var user models.User
+	var ledger, totalRows int64
+	var rows []struct {
+		transactionRow
+		Counted bool
+	}
+	err = db.Select("id, name, number, balance").First(&user, audit.UserID).Error
+	if err == nil {
+		ledger, err = ledgerBalance(db, audit.UserID)
+	}
+	if err == nil {
+		err = db.Model(&models.Transaction{}).Where("user_id = ?", audit.UserID).Count(&totalRows).Error
+	}
+	if err == nil {
+		err = db.Table("transactions AS t").
+			Select("t.*, ("+ledgerCondition+") AS counted").
+			Where("t.user_id = ?", audit.UserID).
+			Order("t.id DESC").Offset(pg.Offset).Limit(pg.Limit).
+			Scan(&rows).Error
+	}
+	if err != nil {
+		utils.LogError(r, "GetBalanceAudit", err, "audit_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	entries := make([]LedgerEntry, len(rows))
+	for i, t := range rows {
+		t.UserName, t.Phone = user.Name, user.Number
+		entries[i] = LedgerEntry{TransactionResponse: t.response(), Counted: t.Counted}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"audit":                  audit,
+		"user_name":              user.Name,
+		"phone":                  user.Number,
+		"current_balance":        user.Balance,
+		"current_ledger_balance": ledger,
+		"transactions":           utils.NewPaginated(entries, pg, totalRows),
+	}})
+}
+
+type repairBalanceRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
+// POST /api/admin/balance-audits/{id}/repair
+// Sets the user's balance to their ledger balance, recomputed now under the
+// user's row lock rather than taken from the audit. Requires
+// {"confirm": true}; every repair is audit-logged.
+func RepairBalanceAudit(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID audit tidak valid"})
+		return
+	}
+	var req repairBalanceRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	adminID, _ := utils.GetAdminID(r)
+
+	var audit models.BalanceAudit
+	var before, after int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&audit, id).Error; err != nil {
+			return err
+		}
+		if !req.Confirm {
+			return errRepairNotConfirmed
+		}
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, balance").First(&user, audit.UserID).Error; err != nil {
+			return err
+		}
+		ledger, err := ledgerBalance(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		before, after = user.Balance, ledger
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("balance", ledger).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		audit.RepairedAt, audit.RepairedBy = &now, &adminID
+		return tx.Model(&audit).Updates(map[string]interface{}{"repaired_at": now, "repaired_by": adminID}).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Audit saldo tidak ditemukan"})
+		return
+	case errors.Is(err, errRepairNotConfirmed):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Perbaikan saldo harus dikonfirmasi dengan confirm: true"})
+		return
+	case err != nil:
+		utils.LogError(r, "RepairBalanceAudit", err, "audit_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbaiki saldo"})
+		return
+	}
+
+	auditLog(r, "balance_audit.repair", map[string]interface{}{"audit_id": audit.ID, "user_id": audit.UserID, "balance": before},
+		map[string]interface{}{"audit_id": audit.ID, "user_id": audit.UserID, "balance": after})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Saldo disamakan dengan ledger",
+		Data:    map[string]interface{}{"audit": audit, "previous_balance": before, "balance": after},
+	})
+}
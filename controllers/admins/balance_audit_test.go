@@ -0,0 +1,130 @@
+package admins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestBalanceAuditFindsAndRepairsDrift(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	t.Setenv("CRON_KEY", "cron-test")
+
+	// Ledger: 700000 deposit + 5000 return - 200000 pending withdrawal = 505000;
+	// the gateway-paid investment does not count
+	suffix := time.Now().UnixNano() % 1000000000
+	user := testutil.NewUser(t, tx, models.User{Name: "Audit", Balance: 555000})
+	for i, row := range []struct {
+		amount int64
+		flow   string
+		typ    string
+		status string
+	}{
+		{700000, "debit", "deposit", "Success"},
+		{100000, "credit", "investment", "Success"},
+		{5000, "debit", "return", "Success"},
+		{200000, "credit", "withdrawal", "Pending"},
+		{90000, "debit", "bonus", "Failed"},
+	} {
+		trx := models.Transaction{UserID: user.ID, Amount: row.amount, OrderID: fmt.Sprintf("BA-%d-%d", suffix, i), TransactionFlow: row.flow, TransactionType: row.typ, Status: row.status}
+		if err := tx.Create(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cron := httptest.NewRequest(http.MethodPost, "/v3/cron/balance-audit", nil)
+	cron.Header.Set("X-CRON-KEY", "cron-test")
+	rec := httptest.NewRecorder()
+	NewBalanceAuditHandler(tx, nil).Cron(rec, cron)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cron: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var audit models.BalanceAudit
+	if err := tx.Where("user_id = ?", user.ID).First(&audit).Error; err != nil {
+		t.Fatal(err)
+	}
+	if audit.LedgerBalance != 505000 || audit.Drift != 50000 {
+		t.Fatalf("expected ledger 505000 and drift 50000, got %+v", audit)
+	}
+
+	repair := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/balance-audits/x/repair", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		req = mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(audit.ID)})
+		rec := httptest.NewRecorder()
+		RepairBalanceAudit(rec, req)
+		return rec
+	}
+	if rec := repair(`{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unconfirmed repair: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := repair(`{"confirm":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("repair: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var got models.User
+	tx.First(&got, user.ID)
+	if got.Balance != 505000 {
+		t.Fatalf("expected balance repaired to 505000, got %d", got.Balance)
+	}
+
+	// Admin adjustments either way are in the ledger: the next run finds no
+	// drift and a repair would change nothing
+	for _, body := range []string{`{"amount":30000,"type":"add"}`, `{"amount":80000,"type":"less"}`} {
+		req := httptest.NewRequest(http.MethodPut, "/v3/admin/users/balance/x", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		rec := httptest.NewRecorder()
+		UpdateUserBalance(rec, mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(user.ID)}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("adjust %s: expected 200, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	rec = httptest.NewRecorder()
+	NewBalanceAuditHandler(tx, nil).Cron(rec, cron)
+	var audits int64
+	tx.Model(&models.BalanceAudit{}).Where("user_id = ?", user.ID).Count(&audits)
+	if ledger, err := ledgerBalance(tx, user.ID); err != nil || ledger != 455000 || audits != 1 {
+		t.Fatalf("expected ledger 455000 and no new drift after adjustments, got %d (%v) and %d audits", ledger, err, audits)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v3/admin/balance-audits/x", nil), map[string]string{"id": fmt.Sprint(audit.ID)})
+	rec = httptest.NewRecorder()
+	GetBalanceAudit(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("detail: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var detail struct {
+		Data struct {
+			CurrentLedgerBalance int64 `json:"current_ledger_balance"`
+			Transactions         struct {
+				Data []LedgerEntry `json:"data"`
+			} `json:"transactions"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatal(err)
+	}
+	counted := 0
+	for _, e := range detail.Data.Transactions.Data {
+		if e.Counted {
+			counted++
+		}
+	}
+	if detail.Data.CurrentLedgerBalance != 455000 || len(detail.Data.Transactions.Data) != 7 || counted != 5 {
+		t.Fatalf("expected ledger 455000 and 5 of 7 transactions counted, got %+v", detail.Data)
+	}
+}
@@ -2,11 +2,12 @@ package admins
 
 import (
 	"net/http"
-	"strconv"
 
 	"project/database"
 	"project/models"
 	"project/utils"
+
+	"gorm.io/gorm"
 )
 
 type BankAccountResponse struct {
@@ -22,21 +23,15 @@ type BankAccountResponse struct {
 
 func GetBankAccounts(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
 	userId := r.URL.Query().Get("userId")
 	bankId := r.URL.Query().Get("bankId")
 	search := r.URL.Query().Get("search")
 
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
-	}
-
-	offset := (page - 1) * limit
-
 	// Start query
 	db := database.DB
 	query := db.Model(&models.BankAccount{}).
@@ -63,10 +58,17 @@ func GetBankAccounts(w http.ResponseWriter, r *http.Request) {
 		BankName string
 	}
 
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "GetBankAccounts", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
 	var bankAccounts []BankAccountWithDetails
 	query.Select("bank_accounts.*, users.name as user_name, users.number as phone, banks.name as bank_name").
-		Offset(offset).
-		Limit(limit).
+		Offset(pg.Offset).
+		Limit(pg.Limit).
 		Find(&bankAccounts)
 
 	// Transform to response format
@@ -87,6 +89,6 @@ func GetBankAccounts(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data:    utils.NewPaginated(response, pg, totalRows),
 	})
 }
@@ -1,9 +1,10 @@
 package admins
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"project/database"
 	"project/models"
@@ -14,21 +15,66 @@ import (
 )
 
 type BankResponse struct {
-	ID     uint   `json:"id"`
-	Name   string `json:"name"`
-	Code   string `json:"code"`
-	Status string `json:"status"`
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Code        string `json:"code"`
+	GatewayCode string `json:"gateway_code"`
+	Logo        string `json:"logo"`
+	LogoURL     string `json:"logo_url"`
+	Status      string `json:"status"`
 }
 
+func newBankResponse(bank models.Bank) BankResponse {
+	logoURL, _ := utils.StoredImageURL(bank.Logo, utils.ImageURLExpiry)
+	return BankResponse{
+		ID:          bank.ID,
+		Name:        bank.Name,
+		Code:        bank.Code,
+		GatewayCode: bank.GatewayCode,
+		Logo:        bank.Logo,
+		LogoURL:     logoURL,
+		Status:      bank.Status,
+	}
+}
+
+// CreateBankRequest is also the body of UpdateBank. gateway_code is the
+// bank's code at KytaPay; leave it empty when it equals code. logo is an
+// object key in the upload bucket or an absolute URL. status defaults to
+// Active on create and is left as is on update.
 type CreateBankRequest struct {
-	Name   string `json:"name"`
-	Code   string `json:"code"`
-	Status string `json:"status"`
+	Name        string `json:"name"`
+	Code        string `json:"code"`
+	GatewayCode string `json:"gateway_code"`
+	Logo        string `json:"logo"`
+	Status      string `json:"status"`
+}
+
+// validateBankRequest trims req and returns a user-facing message, or an
+// empty string when it is valid.
+func validateBankRequest(req *CreateBankRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Code = strings.TrimSpace(req.Code)
+	req.GatewayCode = strings.TrimSpace(req.GatewayCode)
+	req.Logo = strings.TrimSpace(req.Logo)
+	switch {
+	case req.Name == "" || len(req.Name) > 100:
+		return "Nama bank wajib diisi, maksimal 100 karakter"
+	case req.Code == "" || len(req.Code) > 20:
+		return "Kode bank wajib diisi, maksimal 20 karakter"
+	case len(req.GatewayCode) > 20:
+		return "Kode gateway maksimal 20 karakter"
+	case len(req.Logo) > 255:
+		return "Logo maksimal 255 karakter"
+	case req.Status != "" && req.Status != "Active" && req.Status != "Inactive":
+		return "Status harus Active atau Inactive"
+	}
+	return ""
 }
 
 func GetBanks(w http.ResponseWriter, r *http.Request) {
 	var banks []models.Bank
 	if err := database.DB.Find(&banks).Error; err != nil {
+		utils.LogError(r, "GetBanks", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data bank",
@@ -36,14 +82,9 @@ func GetBanks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var response []BankResponse
+	response := make([]BankResponse, 0, len(banks))
 	for _, bank := range banks {
-		response = append(response, BankResponse{
-			ID:     bank.ID,
-			Name:   bank.Name,
-			Code:   bank.Code,
-			Status: bank.Status,
-		})
+		response = append(response, newBankResponse(bank))
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
@@ -55,13 +96,16 @@ func GetBanks(w http.ResponseWriter, r *http.Request) {
 
 func CreateBank(w http.ResponseWriter, r *http.Request) {
 	var req CreateBankRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
+	if msg := validateBankRequest(&req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	if req.Status == "" {
+		req.Status = "Active"
+	}
 
 	// Check for duplicate bank code
 	var existingBank models.Bank
@@ -74,12 +118,15 @@ func CreateBank(w http.ResponseWriter, r *http.Request) {
 	}
 
 	bank := models.Bank{
-		Name:   req.Name,
-		Code:   req.Code,
-		Status: req.Status,
+		Name:        req.Name,
+		Code:        req.Code,
+		GatewayCode: req.GatewayCode,
+		Logo:        req.Logo,
+		Status:      req.Status,
 	}
 
 	if err := database.DB.Create(&bank).Error; err != nil {
+		utils.LogError(r, "CreateBank", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal menambahkan bank",
@@ -87,15 +134,11 @@ func CreateBank(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditLogTarget(r, "bank.create", "bank", bank.ID, nil, bank)
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Bank berhasil ditambahkan",
-		Data: BankResponse{
-			ID:     bank.ID,
-			Name:   bank.Name,
-			Code:   bank.Code,
-			Status: bank.Status,
-		},
+		Data:    newBankResponse(bank),
 	})
 }
 
@@ -111,11 +154,11 @@ func UpdateBank(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CreateBankRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if msg := validateBankRequest(&req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
 		return
 	}
 
@@ -128,6 +171,7 @@ func UpdateBank(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		utils.LogError(r, "UpdateBank", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data bank",
@@ -145,11 +189,17 @@ func UpdateBank(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := bank
 	bank.Name = req.Name
 	bank.Code = req.Code
-	bank.Status = req.Status
+	bank.GatewayCode = req.GatewayCode
+	bank.Logo = req.Logo
+	if req.Status != "" {
+		bank.Status = req.Status
+	}
 
 	if err := database.DB.Save(&bank).Error; err != nil {
+		utils.LogError(r, "UpdateBank", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal memperbarui bank",
@@ -157,14 +207,56 @@ func UpdateBank(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditLog(r, "bank.update", before, bank)
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Bank berhasil diperbarui",
-		Data: map[string]interface{}{
-			"id":     bank.ID,
-			"name":   bank.Name,
-			"code":   bank.Code,
-			"status": bank.Status,
-		},
+		Data:    newBankResponse(bank),
+	})
+}
+
+// PUT /api/admin/banks/{id}/status
+// Enables (Active) or disables (Inactive) a bank. A disabled bank drops out
+// of the app's bank list and new accounts at it are refused with
+// BANK_DISABLED; accounts already registered at it are left alone.
+func UpdateBankStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid bank ID"})
+		return
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if req.Status != "Active" && req.Status != "Inactive" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Status harus Active atau Inactive"})
+		return
+	}
+
+	var bank models.Bank
+	if err := database.DB.First(&bank, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Bank tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "UpdateBankStatus", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data bank"})
+		return
+	}
+	before := bank
+	if err := database.DB.Model(&bank).Update("status", req.Status).Error; err != nil {
+		utils.LogError(r, "UpdateBankStatus", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui bank"})
+		return
+	}
+
+	auditLog(r, "bank.status", before, bank)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Status bank berhasil diperbarui",
+		Data:    newBankResponse(bank),
 	})
 }
@@ -0,0 +1,302 @@
+package admins
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// bannerImageMaxBytes bounds uploaded banner images.
+const bannerImageMaxBytes = 2 << 20
+
+type bannerRequest struct {
+	Title     *string    `json:"title"`
+	Image     *string    `json:"image"`
+	Link      *string    `json:"link"`
+	SortOrder *int       `json:"sort_order"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	MinLevel  *uint      `json:"min_level"`
+	MaxLevel  *uint      `json:"max_level"`
+	Status    string     `json:"status"`
+}
+
+// GET /api/admin/banners?status=Active|Inactive
+func ListBannersHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	query := db.Model(&models.Banner{})
+	if status := r.URL.Query().Get("status"); status == "Active" || status == "Inactive" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListBannersHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var banners []models.Banner
+	if err := query.Order("sort_order ASC, starts_at DESC, id ASC").Offset(pg.Offset).Limit(pg.Limit).Find(&banners).Error; err != nil {
+		utils.LogError(r, "ListBannersHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(banners, pg, totalRows),
+	})
+}
+
+// POST /api/admin/banners
+// The image can be an absolute URL here or uploaded afterwards with
+// POST /api/admin/banners/{id}/image; banners without one are never shown.
+func CreateBannerHandler(w http.ResponseWriter, r *http.Request) {
+	var req bannerRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	banner := models.Banner{StartsAt: time.Now(), Status: "Active"}
+	if msg := applyBannerRequest(&banner, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if err := database.DB.Create(&banner).Error; err != nil {
+		utils.LogError(r, "CreateBannerHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat banner"})
+		return
+	}
+	models.InvalidateBanners()
+	auditLogTarget(r, "banner.create", "banner", banner.ID, nil, banner)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Banner berhasil dibuat",
+		Data:    banner,
+	})
+}
+
+// PUT /api/admin/banners/{id}
+func UpdateBannerHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req bannerRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	banner, ok := loadBanner(w, r, db, id, "UpdateBannerHandler")
+	if !ok {
+		return
+	}
+	before := banner
+
+	if msg := applyBannerRequest(&banner, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if err := db.Save(&banner).Error; err != nil {
+		utils.LogError(r, "UpdateBannerHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate banner"})
+		return
+	}
+	models.InvalidateBanners()
+	auditLog(r, "banner.update", before, banner)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Banner berhasil diupdate",
+		Data:    banner,
+	})
+}
+
+// POST /api/admin/banners/{id}/image (multipart, field "image")
+// Re-encodes the JPG/PNG and stores it in the upload bucket under banners/.
+func UploadBannerImageHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	db := database.DB
+	banner, ok := loadBanner(w, r, db, id, "UploadBannerImageHandler")
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(bannerImageMaxBytes); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar diperlukan", Code: utils.CodeInvalidImage})
+		return
+	}
+	defer file.Close()
+
+	imageBytes, ext, err := utils.SanitizeImage(file, header.Filename, header.Size, bannerImageMaxBytes)
+	var imgErr *utils.ImageError
+	if errors.As(err, &imgErr) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: imgErr.Message, Code: utils.CodeInvalidImage})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "UploadBannerImageHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses gambar"})
+		return
+	}
+
+	objectName := "banners/" + strconv.FormatUint(uint64(banner.ID), 10) + "_" + strconv.FormatInt(time.Now().UnixNano(), 10) + ext
+	if err := utils.UploadToS3(objectName, bytes.NewReader(imageBytes), int64(len(imageBytes))); err != nil {
+		utils.LogError(r, "UploadBannerImageHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengunggah gambar"})
+		return
+	}
+
+	before := banner
+	if err := db.Model(&banner).Update("image", objectName).Error; err != nil {
+		utils.LogError(r, "UploadBannerImageHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate banner"})
+		return
+	}
+	models.InvalidateBanners()
+	auditLog(r, "banner.image", before, banner)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Gambar banner berhasil diunggah",
+		Data:    banner,
+	})
+}
+
+// DELETE /api/admin/banners/{id}
+// Deactivates the banner; it disappears from the app immediately.
+func DeleteBannerHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	res := database.DB.Model(&models.Banner{}).Where("id = ?", id).Update("status", "Inactive")
+	if res.Error != nil {
+		utils.LogError(r, "DeleteBannerHandler", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus banner"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Banner tidak ditemukan"})
+		return
+	}
+	models.InvalidateBanners()
+	auditLog(r, "banner.deactivate", map[string]interface{}{"id": id}, nil)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Banner berhasil dinonaktifkan",
+	})
+}
+
+// loadBanner fetches banner id, writing the 404/500 response when it cannot.
+func loadBanner(w http.ResponseWriter, r *http.Request, db *gorm.DB, id uint, op string) (models.Banner, bool) {
+	var banner models.Banner
+	if err := db.First(&banner, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Banner tidak ditemukan"})
+			return banner, false
+		}
+		utils.LogError(r, op, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return banner, false
+	}
+	return banner, true
+}
+
+// applyBannerRequest copies the provided fields onto b and validates the
+// result, returning a user-facing message when invalid.
+func applyBannerRequest(b *models.Banner, req *bannerRequest) string {
+	if req.Title != nil {
+		b.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Image != nil {
+		b.Image = strings.TrimSpace(*req.Image)
+	}
+	if req.Link != nil {
+		link := strings.TrimSpace(*req.Link)
+		b.Link = &link
+		if link == "" {
+			b.Link = nil
+		}
+	}
+	if req.SortOrder != nil {
+		b.SortOrder = *req.SortOrder
+	}
+	if req.StartsAt != nil {
+		b.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		b.EndsAt = req.EndsAt
+	}
+	if req.MinLevel != nil {
+		b.MinLevel = req.MinLevel
+	}
+	if req.MaxLevel != nil {
+		b.MaxLevel = req.MaxLevel
+	}
+	if req.Status == "Active" || req.Status == "Inactive" {
+		b.Status = req.Status
+	}
+
+	if b.Title == "" || len(b.Title) > 150 {
+		return "Judul banner wajib diisi (maksimal 150 karakter)"
+	}
+	if len(b.Image) > 255 {
+		return "URL gambar terlalu panjang"
+	}
+	if b.Image != "" && !strings.HasPrefix(b.Image, "https://") && !strings.HasPrefix(b.Image, "banners/") {
+		return "Gambar harus URL https atau diunggah melalui endpoint gambar banner"
+	}
+	if b.Link != nil && len(*b.Link) > 500 {
+		return "Link terlalu panjang"
+	}
+	if b.MinLevel != nil && *b.MinLevel > models.MaxVIPLevel {
+		return "Level minimum tidak valid"
+	}
+	if b.MaxLevel != nil && *b.MaxLevel > models.MaxVIPLevel {
+		return "Level maksimum tidak valid"
+	}
+	if b.MinLevel != nil && b.MaxLevel != nil && *b.MinLevel > *b.MaxLevel {
+		return "Level minimum tidak boleh lebih besar dari level maksimum"
+	}
+	if b.EndsAt != nil && !b.EndsAt.After(b.StartsAt) {
+		return "Waktu berakhir harus setelah waktu mulai"
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package admins
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestApplyBannerRequestValidation(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	str := func(v string) *string { return &v }
+	lvl := func(v uint) *uint { return &v }
+	title := "Promo Juni"
+
+	valid := bannerRequest{Title: &title, Image: str("https://cdn.example.com/juni.png"), Link: str("app://products"), StartsAt: &start, EndsAt: &end, MinLevel: lvl(1), MaxLevel: lvl(3)}
+	var b models.Banner
+	if msg := applyBannerRequest(&b, &valid); msg != "" {
+		t.Fatalf("valid banner rejected: %s", msg)
+	}
+
+	cases := map[string]bannerRequest{
+		"no title":       {Image: str("https://cdn.example.com/a.png"), StartsAt: &start},
+		"http image":     {Title: &title, Image: str("http://cdn.example.com/a.png"), StartsAt: &start},
+		"foreign key":    {Title: &title, Image: str("forum/1_2.png"), StartsAt: &start},
+		"ends before":    {Title: &title, StartsAt: &end, EndsAt: &start},
+		"level too high": {Title: &title, StartsAt: &start, MinLevel: lvl(models.MaxVIPLevel + 1)},
+		"min above max":  {Title: &title, StartsAt: &start, MinLevel: lvl(4), MaxLevel: lvl(2)},
+	}
+	for label, req := range cases {
+		var b models.Banner
+		if msg := applyBannerRequest(&b, &req); msg == "" {
+			t.Errorf("%s: expected a validation message", label)
+		}
+	}
+
+	// An empty link clears it
+	if msg := applyBannerRequest(&b, &bannerRequest{Link: str("")}); msg != "" || b.Link != nil {
+		t.Fatalf("expected link cleared, got %v (%s)", b.Link, msg)
+	}
+}
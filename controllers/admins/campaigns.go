@@ -0,0 +1,317 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// maxCampaignItems bounds the banners, and the products, on one campaign.
+const maxCampaignItems = 20
+
+var campaignSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type campaignRequest struct {
+	Slug        *string `json:"slug"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	// DepositCampaignID links a deposit campaign; 0 unlinks it
+	DepositCampaignID *uint      `json:"deposit_campaign_id"`
+	StartsAt          *time.Time `json:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at"`
+	Status            string     `json:"status"`
+	// BannerIDs and ProductIDs replace the campaign's items, in display
+	// order, when given
+	BannerIDs  []uint `json:"banner_ids"`
+	ProductIDs []uint `json:"product_ids"`
+}
+
+// GET /api/admin/campaigns?status=Active|Inactive
+func ListCampaignsHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	query := db.Model(&models.Campaign{})
+	if status := r.URL.Query().Get("status"); status == "Active" || status == "Inactive" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListCampaignsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	campaigns := []models.Campaign{}
+	if err := query.Order("starts_at DESC, id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&campaigns).Error; err != nil {
+		utils.LogError(r, "ListCampaignsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if err := models.LoadCampaignItems(db, campaigns); err != nil {
+		utils.LogError(r, "ListCampaignsHandler: items", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(campaigns, pg, totalRows),
+	})
+}
+
+// POST /api/admin/campaigns
+func CreateCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	var req campaignRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	campaign := models.Campaign{Status: "Active", BannerIDs: []uint{}, ProductIDs: []uint{}}
+	if msg := applyCampaignRequest(&campaign, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	db := database.DB
+	if status, msg := checkCampaignLinks(r, db, &campaign); msg != "" {
+		utils.WriteJSON(w, status, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&campaign).Error; err != nil {
+			return err
+		}
+		return models.ReplaceCampaignItems(tx, campaign.ID, campaign.BannerIDs, campaign.ProductIDs)
+	})
+	if err != nil {
+		utils.LogError(r, "CreateCampaignHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat campaign"})
+		return
+	}
+	auditLogTarget(r, "campaign.create", "campaign", campaign.ID, nil, campaign)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Campaign berhasil dibuat",
+		Data:    campaign,
+	})
+}
+
+// PUT /api/admin/campaigns/{id}
+func UpdateCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req campaignRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	var campaign models.Campaign
+	if err := db.First(&campaign, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Campaign tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "UpdateCampaignHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	loaded := []models.Campaign{campaign}
+	if err := models.LoadCampaignItems(db, loaded); err != nil {
+		utils.LogError(r, "UpdateCampaignHandler: items", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	campaign = loaded[0]
+	before := campaign
+
+	if msg := applyCampaignRequest(&campaign, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	if status, msg := checkCampaignLinks(r, db, &campaign); msg != "" {
+		utils.WriteJSON(w, status, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&campaign).
+			Select("slug", "title", "description", "deposit_campaign_id", "starts_at", "ends_at", "status").
+			Updates(&campaign).Error; err != nil {
+			return err
+		}
+		return models.ReplaceCampaignItems(tx, campaign.ID, req.BannerIDs, req.ProductIDs)
+	})
+	if err != nil {
+		utils.LogError(r, "UpdateCampaignHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate campaign"})
+		return
+	}
+	auditLog(r, "campaign.update", before, campaign)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Campaign berhasil diupdate",
+		Data:    campaign,
+	})
+}
+
+// DELETE /api/admin/campaigns/{id}
+// Deactivates the campaign; its page answers 404 from then on.
+func DeleteCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	res := database.DB.Model(&models.Campaign{}).Where("id = ?", id).Update("status", "Inactive")
+	if res.Error != nil {
+		utils.LogError(r, "DeleteCampaignHandler", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus campaign"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Campaign tidak ditemukan"})
+		return
+	}
+	auditLog(r, "campaign.deactivate", map[string]interface{}{"id": id}, nil)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Campaign berhasil dinonaktifkan",
+	})
+}
+
+// applyCampaignRequest copies the provided fields onto c and validates the
+// result, returning a user-facing message when invalid.
+func applyCampaignRequest(c *models.Campaign, req *campaignRequest) string {
+	if req.Slug != nil {
+		c.Slug = strings.ToLower(strings.TrimSpace(*req.Slug))
+	}
+	if req.Title != nil {
+		c.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		if d := strings.TrimSpace(*req.Description); d != "" {
+			c.Description = &d
+		} else {
+			c.Description = nil
+		}
+	}
+	if req.DepositCampaignID != nil {
+		if *req.DepositCampaignID == 0 {
+			c.DepositCampaignID = nil
+		} else {
+			id := *req.DepositCampaignID
+			c.DepositCampaignID = &id
+		}
+	}
+	if req.StartsAt != nil {
+		c.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		c.EndsAt = *req.EndsAt
+	}
+	if req.Status == "Active" || req.Status == "Inactive" {
+		c.Status = req.Status
+	}
+	if req.BannerIDs != nil {
+		c.BannerIDs = uniqueIDs(req.BannerIDs)
+		req.BannerIDs = c.BannerIDs
+	}
+	if req.ProductIDs != nil {
+		c.ProductIDs = uniqueIDs(req.ProductIDs)
+		req.ProductIDs = c.ProductIDs
+	}
+
+	if len(c.Slug) > 64 || !campaignSlugPattern.MatchString(c.Slug) {
+		return "Slug wajib diisi: huruf kecil, angka dan tanda hubung (maksimal 64 karakter)"
+	}
+	if c.Title == "" || len(c.Title) > 150 {
+		return "Judul campaign wajib diisi (maksimal 150 karakter)"
+	}
+	if c.StartsAt.IsZero() || c.EndsAt.IsZero() {
+		return "Waktu mulai dan berakhir wajib diisi"
+	}
+	if !c.EndsAt.After(c.StartsAt) {
+		return "Waktu berakhir harus setelah waktu mulai"
+	}
+	if len(c.BannerIDs) > maxCampaignItems || len(c.ProductIDs) > maxCampaignItems {
+		return "Maksimal 20 banner dan 20 produk per campaign"
+	}
+	return ""
+}
+
+// checkCampaignLinks verifies the slug is free and that the linked banners,
+// products and deposit campaign exist, returning the status and message to
+// answer with when not.
+func checkCampaignLinks(r *http.Request, db *gorm.DB, c *models.Campaign) (int, string) {
+	var taken int64
+	if err := db.Model(&models.Campaign{}).Where("slug = ? AND id <> ?", c.Slug, c.ID).Count(&taken).Error; err != nil {
+		utils.LogError(r, "checkCampaignLinks: slug", err)
+		return http.StatusInternalServerError, "Terjadi kesalahan sistem, silakan coba lagi"
+	}
+	if taken > 0 {
+		return http.StatusConflict, "Slug sudah dipakai campaign lain"
+	}
+	type link struct {
+		model interface{}
+		ids   []uint
+		msg   string
+	}
+	links := []link{
+		{&models.Banner{}, c.BannerIDs, "Banner tidak ditemukan"},
+		{&models.Product{}, c.ProductIDs, "Produk tidak ditemukan"},
+	}
+	if c.DepositCampaignID != nil {
+		links = append(links, link{&models.DepositCampaign{}, []uint{*c.DepositCampaignID}, "Deposit campaign tidak ditemukan"})
+	}
+	for _, l := range links {
+		if len(l.ids) == 0 {
+			continue
+		}
+		var found int64
+		if err := db.Model(l.model).Where("id IN ?", l.ids).Count(&found).Error; err != nil {
+			utils.LogError(r, "checkCampaignLinks", err)
+			return http.StatusInternalServerError, "Terjadi kesalahan sistem, silakan coba lagi"
+		}
+		if found != int64(len(l.ids)) {
+			return http.StatusBadRequest, l.msg
+		}
+	}
+	return 0, ""
+}
+
+// uniqueIDs drops zero and repeated ids, keeping the first position of each.
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
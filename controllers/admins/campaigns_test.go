@@ -0,0 +1,54 @@
+package admins
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestApplyCampaignRequestValidation(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	str := func(v string) *string { return &v }
+
+	valid := campaignRequest{Slug: str(" Gajian-Juni "), Title: str("Promo Gajian"), StartsAt: &start, EndsAt: &end, ProductIDs: []uint{3, 1, 3, 0, 2}}
+	var c models.Campaign
+	if msg := applyCampaignRequest(&c, &valid); msg != "" {
+		t.Fatalf("valid campaign rejected: %s", msg)
+	}
+	if c.Slug != "gajian-juni" {
+		t.Errorf("slug not normalized: %q", c.Slug)
+	}
+	if !slices.Equal(c.ProductIDs, []uint{3, 1, 2}) {
+		t.Errorf("expected products deduplicated in order, got %v", c.ProductIDs)
+	}
+
+	// A zero deposit campaign id unlinks it
+	linked := uint(9)
+	c.DepositCampaignID = &linked
+	zero := uint(0)
+	if msg := applyCampaignRequest(&c, &campaignRequest{DepositCampaignID: &zero}); msg != "" || c.DepositCampaignID != nil {
+		t.Errorf("expected the deposit campaign unlinked, got %v (%s)", c.DepositCampaignID, msg)
+	}
+
+	many := make([]uint, maxCampaignItems+1)
+	for i := range many {
+		many[i] = uint(i + 1)
+	}
+	cases := map[string]campaignRequest{
+		"no slug":        {Title: str("Promo"), StartsAt: &start, EndsAt: &end},
+		"bad slug":       {Slug: str("promo juni!"), Title: str("Promo"), StartsAt: &start, EndsAt: &end},
+		"no title":       {Slug: str("promo"), StartsAt: &start, EndsAt: &end},
+		"ends before":    {Slug: str("promo"), Title: str("Promo"), StartsAt: &end, EndsAt: &start},
+		"missing window": {Slug: str("promo"), Title: str("Promo")},
+		"too many":       {Slug: str("promo"), Title: str("Promo"), StartsAt: &start, EndsAt: &end, BannerIDs: many},
+	}
+	for label, req := range cases {
+		var c models.Campaign
+		if msg := applyCampaignRequest(&c, &req); msg == "" {
+			t.Errorf("%s: expected a validation message", label)
+		}
+	}
+}
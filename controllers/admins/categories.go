@@ -1,10 +1,8 @@
 package admins
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"project/database"
@@ -18,7 +16,8 @@ import (
 func ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
 	var categories []models.Category
-	if err := db.Order("id ASC").Find(&categories).Error; err != nil {
+	if err := db.Order("sort_priority ASC, id ASC").Find(&categories).Error; err != nil {
+		utils.LogError(r, "ListCategoriesHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data kategori"})
 		return
 	}
@@ -34,24 +33,20 @@ func ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 
 // GET /api/admin/categories/{id}
 func GetCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var idStr string
-	if len(parts) >= 4 {
-		idStr = parts[3]
-	}
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil || id64 == 0 {
+	id, ok := idFromRequest(r)
+	if !ok {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
 		return
 	}
 
 	db := database.DB
 	var category models.Category
-	if err := db.First(&category, uint(id64)).Error; err != nil {
+	if err := db.First(&category, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan", Code: utils.CodeCategoryNotFound})
 			return
 		}
+		utils.LogError(r, "GetCategoryHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
@@ -63,45 +58,60 @@ func GetCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// POST /api/admin/categories
-func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		ProfitType  string `json:"profit_type"`
-		Status      string `json:"status"`
-	}
+type CreateCategoryRequest struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Description  string `json:"description"`
+	ProfitType   string `json:"profit_type" validate:"required,oneof=locked unlocked"`
+	Status       string `json:"status" validate:"omitempty,oneof=Active Inactive"`
+	SortPriority *int   `json:"sort_priority"`
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
-		return
-	}
+func (req *CreateCategoryRequest) Normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+}
 
-	if strings.TrimSpace(req.Name) == "" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Nama kategori wajib diisi"})
-		return
-	}
+// UpdateCategoryRequest leaves empty fields unchanged.
+type UpdateCategoryRequest struct {
+	Name         string `json:"name" validate:"max=100"`
+	Description  string `json:"description"`
+	ProfitType   string `json:"profit_type" validate:"omitempty,oneof=locked unlocked"`
+	Status       string `json:"status" validate:"omitempty,oneof=Active Inactive"`
+	SortPriority *int   `json:"sort_priority"`
+}
 
-	if req.ProfitType != "locked" && req.ProfitType != "unlocked" {
-		req.ProfitType = "unlocked"
+func (req *UpdateCategoryRequest) Normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+}
+
+// POST /api/admin/categories
+func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateCategoryRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
 	}
 
-	if req.Status != "Active" && req.Status != "Inactive" {
+	if req.Status == "" {
 		req.Status = "Active"
 	}
 
 	category := models.Category{
-		Name:        req.Name,
-		Description: req.Description,
-		ProfitType:  req.ProfitType,
-		Status:      req.Status,
+		Name:         req.Name,
+		Description:  req.Description,
+		ProfitType:   req.ProfitType,
+		Status:       req.Status,
+		SortPriority: models.DefaultCategorySortPriority,
+	}
+	if req.SortPriority != nil {
+		category.SortPriority = *req.SortPriority
 	}
 
 	db := database.DB
 	if err := db.Create(&category).Error; err != nil {
+		utils.LogError(r, "CreateCategoryHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat kategori"})
 		return
 	}
+	models.InvalidateProductCatalog()
 
 	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
 		Success: true,
@@ -111,41 +121,51 @@ func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // PUT /api/admin/categories/{id}
+// The webhook and daily cron branch on profit_type, so it cannot change and the
+// category cannot be deactivated while investments in it are still Running.
 func UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var idStr string
-	if len(parts) >= 4 {
-		idStr = parts[3]
-	}
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil || id64 == 0 {
+	id, ok := idFromRequest(r)
+	if !ok {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
 		return
 	}
 
-	var req struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		ProfitType  string `json:"profit_type"`
-		Status      string `json:"status"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
+	var req UpdateCategoryRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
 	db := database.DB
 	var category models.Category
-	if err := db.First(&category, uint(id64)).Error; err != nil {
+	if err := db.First(&category, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan", Code: utils.CodeCategoryNotFound})
 			return
 		}
+		utils.LogError(r, "UpdateCategoryHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
 
+	profitTypeChanged := req.ProfitType != "" && req.ProfitType != category.ProfitType
+	deactivating := req.Status == "Inactive" && category.Status != "Inactive"
+	if profitTypeChanged || deactivating {
+		var running int64
+		if err := db.Model(&models.Investment{}).Where("category_id = ? AND status = ?", category.ID, "Running").Count(&running).Error; err != nil {
+			utils.LogError(r, "UpdateCategoryHandler", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		if running > 0 {
+			msg := "Tidak dapat menonaktifkan kategori yang masih memiliki investasi berjalan"
+			if profitTypeChanged {
+				msg = "Tidak dapat mengubah profit type kategori yang masih memiliki investasi berjalan"
+			}
+			utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: msg, Code: utils.CodeCategoryInUse})
+			return
+		}
+	}
+
 	updates := map[string]interface{}{}
 	if req.Name != "" {
 		updates["name"] = req.Name
@@ -153,22 +173,27 @@ func UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	if req.Description != "" {
 		updates["description"] = req.Description
 	}
-	if req.ProfitType == "locked" || req.ProfitType == "unlocked" {
+	if profitTypeChanged {
 		updates["profit_type"] = req.ProfitType
 	}
-	if req.Status == "Active" || req.Status == "Inactive" {
+	if req.Status != "" {
 		updates["status"] = req.Status
 	}
+	if req.SortPriority != nil {
+		updates["sort_priority"] = *req.SortPriority
+	}
 
 	if len(updates) > 0 {
 		if err := db.Model(&category).Updates(updates).Error; err != nil {
+			utils.LogError(r, "UpdateCategoryHandler", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate kategori"})
 			return
 		}
+		models.InvalidateProductCatalog()
 	}
 
 	// Reload to get updated data
-	db.First(&category, uint(id64))
+	db.First(&category, id)
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
@@ -179,43 +204,40 @@ func UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 
 // DELETE /api/admin/categories/{id}
 func DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var idStr string
-	if len(parts) >= 4 {
-		idStr = parts[3]
-	}
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil || id64 == 0 {
+	id, ok := idFromRequest(r)
+	if !ok {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
 		return
 	}
 
 	db := database.DB
 	var category models.Category
-	if err := db.First(&category, uint(id64)).Error; err != nil {
+	if err := db.First(&category, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan", Code: utils.CodeCategoryNotFound})
 			return
 		}
+		utils.LogError(r, "DeleteCategoryHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
 
 	// Check if any products use this category
 	var count int64
-	if err := db.Model(&models.Product{}).Where("category_id = ?", uint(id64)).Count(&count).Error; err == nil && count > 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak dapat menghapus kategori yang masih digunakan oleh produk"})
+	if err := db.Model(&models.Product{}).Where("category_id = ?", id).Count(&count).Error; err == nil && count > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak dapat menghapus kategori yang masih digunakan oleh produk", Code: utils.CodeCategoryInUse})
 		return
 	}
 
 	if err := db.Delete(&category).Error; err != nil {
+		utils.LogError(r, "DeleteCategoryHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus kategori"})
 		return
 	}
+	models.InvalidateProductCatalog()
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Kategori berhasil dihapus",
 	})
 }
-
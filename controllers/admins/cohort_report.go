@@ -0,0 +1,170 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/utils"
+)
+
+const (
+	// cohortDefaultPeriods and cohortMaxPeriods bound how many periods, up to
+	// and including the current one, the cohort report covers.
+	cohortDefaultPeriods = 12
+	cohortMaxPeriods     = 26
+)
+
+// CohortCell is one cohort's repeat investing in the period offset periods
+// after its first investment. Offset 0 is the cohort's own period; the first
+// investment itself never counts as a repeat.
+type CohortCell struct {
+	Offset        int     `json:"offset"`
+	Users         int64   `json:"users"`
+	RetentionRate float64 `json:"retention_rate"`
+	RepeatVolume  int64   `json:"repeat_volume"`
+}
+
+// CohortRow is the users whose first Success investment fell in one period.
+// Cells run from offset 0 to the current period.
+type CohortRow struct {
+	Period string       `json:"period"`
+	Start  string       `json:"start"`
+	Users  int64        `json:"users"`
+	Cells  []CohortCell `json:"cells"`
+}
+
+// GET /api/admin/reports/cohorts?granularity=week|month&periods=
+// Groups users by the period (ISO week starting Monday, or calendar month, in
+// APP_TIMEZONE) of their first Success investment transaction and reports how
+// many invested again in each later period and with how much. periods
+// (default 12, max 26) counts back from the current period.
+func (h *ReportHandler) GetCohortReportHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	granularity := q.Get("granularity")
+	if granularity == "" {
+		granularity = "week"
+	}
+	if granularity != "week" && granularity != "month" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "granularity harus week atau month"})
+		return
+	}
+	periods := cohortDefaultPeriods
+	if v := q.Get("periods"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > cohortMaxPeriods {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: fmt.Sprintf("periods harus antara 1 dan %d", cohortMaxPeriods)})
+			return
+		}
+		periods = n
+	}
+
+	// Timestamps are stored in UTC; shifting them by the app timezone's offset
+	// lets MySQL bucket them by local date
+	appLoc := utils.AppLocation()
+	now := time.Now().In(appLoc)
+	_, offset := now.Zone()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, appLoc)
+	var first time.Time
+	var bucket string
+	args := map[string]interface{}{"offset": offset, "periods": periods}
+	if granularity == "week" {
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		first = monday.AddDate(0, 0, -7*(periods-1))
+		args["anchor"] = first.Format("2006-01-02")
+		bucket = "FLOOR(DATEDIFF(updated_at + INTERVAL @offset SECOND, @anchor) / 7)"
+	} else {
+		first = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, appLoc).AddDate(0, -(periods - 1), 0)
+		args["anchor"] = first.Format("200601")
+		bucket = "PERIOD_DIFF(DATE_FORMAT(updated_at + INTERVAL @offset SECOND, '%Y%m'), @anchor)"
+	}
+
+	// Each user's investments ranked over their whole history, so someone who
+	// first invested before the window is in no cohort
+	cohorts := `WITH ranked AS (
+			SELECT user_id, amount, ` + bucket + ` AS bucket,
+				ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY updated_at, id) AS rn
+			FROM transactions
+			WHERE transaction_type = 'investment' AND status = 'Success'
+		), cohorts AS (
+			SELECT user_id, bucket AS cohort FROM ranked WHERE rn = 1 AND bucket >= 0 AND bucket < @periods
+		) `
+
+	type sizeRow struct {
+		Cohort int
+		Users  int64
+	}
+	db := h.db()
+	var sizes []sizeRow
+	if err := db.Raw(cohorts+`SELECT cohort, COUNT(*) AS users FROM cohorts GROUP BY cohort`, args).
+		Scan(&sizes).Error; err != nil {
+		utils.LogError(r, "cohort report: cohort sizes", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	type repeatRow struct {
+		Cohort       int
+		PeriodOffset int
+		Users        int64
+		Volume       int64
+	}
+	var repeats []repeatRow
+	if err := db.Raw(cohorts+`SELECT c.cohort, r.bucket - c.cohort AS period_offset,
+			COUNT(DISTINCT r.user_id) AS users, COALESCE(SUM(r.amount), 0) AS volume
+		FROM cohorts c
+		JOIN ranked r ON r.user_id = c.user_id AND r.rn > 1 AND r.bucket < @periods
+		GROUP BY c.cohort, period_offset`, args).
+		Scan(&repeats).Error; err != nil {
+		utils.LogError(r, "cohort report: repeats", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	rows := make([]CohortRow, periods)
+	for i := range rows {
+		start := first.AddDate(0, 0, 7*i)
+		label := ""
+		if granularity == "week" {
+			year, week := start.ISOWeek()
+			label = fmt.Sprintf("%d-W%02d", year, week)
+		} else {
+			start = first.AddDate(0, i, 0)
+			label = start.Format("2006-01")
+		}
+		rows[i] = CohortRow{Period: label, Start: start.Format("2006-01-02"), Cells: make([]CohortCell, periods-i)}
+		for k := range rows[i].Cells {
+			rows[i].Cells[k].Offset = k
+		}
+	}
+	for _, s := range sizes {
+		if s.Cohort >= 0 && s.Cohort < periods {
+			rows[s.Cohort].Users = s.Users
+		}
+	}
+	for _, rep := range repeats {
+		if rep.Cohort < 0 || rep.Cohort >= periods || rep.PeriodOffset < 0 || rep.PeriodOffset >= len(rows[rep.Cohort].Cells) {
+			continue
+		}
+		cell := &rows[rep.Cohort].Cells[rep.PeriodOffset]
+		cell.Users, cell.RepeatVolume = rep.Users, rep.Volume
+	}
+	for i := range rows {
+		if rows[i].Users == 0 {
+			continue
+		}
+		for k := range rows[i].Cells {
+			rows[i].Cells[k].RetentionRate = float64(rows[i].Cells[k].Users) / float64(rows[i].Users)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"granularity": granularity,
+			"periods":     periods,
+			"cohorts":     rows,
+		},
+	})
+}
@@ -0,0 +1,86 @@
+package admins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestAdminCohortReport(t *testing.T) {
+	tx := testutil.Tx(t)
+	reports := NewReportHandler(database.NewReadReplica(tx, nil))
+	suffix := time.Now().UnixNano() % 1000000000
+
+	now := time.Now().In(utils.AppLocation())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	// Wednesday noon, weeks before the current week
+	week := func(ago int) time.Time { return monday.AddDate(0, 0, -7*ago+2).Add(12 * time.Hour) }
+
+	// repeat and single first invested four weeks ago; veteran long before
+	// the window, so its investment four weeks ago is in no cohort
+	invest := map[string][]struct {
+		at     time.Time
+		amount int64
+	}{
+		"repeat":  {{week(4), 100000}, {week(4).Add(time.Hour), 20000}, {week(2), 30000}},
+		"single":  {{week(4), 100000}},
+		"veteran": {{week(10), 100000}, {week(4), 40000}},
+	}
+	n := 0
+	for name, rows := range invest {
+		user := testutil.NewUser(t, tx, models.User{Name: name})
+		for _, row := range rows {
+			trx := models.Transaction{UserID: user.ID, Amount: row.amount, OrderID: fmt.Sprintf("CH-%d-%d", suffix, n), TransactionFlow: "credit", TransactionType: "investment", Status: "Success", CreatedAt: row.at, UpdatedAt: row.at}
+			if err := tx.Create(&trx).Error; err != nil {
+				t.Fatal(err)
+			}
+			n++
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	reports.GetCohortReportHandler(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/reports/cohorts?granularity=week&periods=6", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("report: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Cohorts []CohortRow `json:"cohorts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Cohorts) != 6 || len(resp.Data.Cohorts[0].Cells) != 6 || len(resp.Data.Cohorts[5].Cells) != 1 {
+		t.Fatalf("expected a 6-period triangle, got %+v", resp.Data.Cohorts)
+	}
+	cohort := resp.Data.Cohorts[1]
+	if cohort.Start != monday.AddDate(0, 0, -28).Format("2006-01-02") || cohort.Users != 2 {
+		t.Fatalf("expected the cohort four weeks ago with 2 users, got %+v", cohort)
+	}
+	want := []CohortCell{
+		{Offset: 0, Users: 1, RetentionRate: 0.5, RepeatVolume: 20000},
+		{Offset: 1},
+		{Offset: 2, Users: 1, RetentionRate: 0.5, RepeatVolume: 30000},
+		{Offset: 3},
+		{Offset: 4},
+	}
+	if fmt.Sprint(cohort.Cells) != fmt.Sprint(want) {
+		t.Fatalf("expected cells %+v, got %+v", want, cohort.Cells)
+	}
+
+	rec = httptest.NewRecorder()
+	reports.GetCohortReportHandler(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/reports/cohorts?periods=27", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("too many periods: expected 400, got %d", rec.Code)
+	}
+}
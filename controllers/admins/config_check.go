@@ -0,0 +1,28 @@
+package admins
+
+import (
+	"net/http"
+
+	"project/config"
+	"project/utils"
+)
+
+// GET /api/admin/config/check
+// Which integrations are fully configured, and the variables missing or
+// invalid for the others. Values are never shown. ready is false when a
+// required integration is incomplete, which only happens if the environment
+// changed after startup validated it.
+func GetConfigCheck(w http.ResponseWriter, r *http.Request) {
+	integrations := config.Check()
+	ready := true
+	for _, in := range integrations {
+		if in.Required && !in.Configured {
+			ready = false
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"env":          config.Get().Env,
+		"ready":        ready,
+		"integrations": integrations,
+	}})
+}
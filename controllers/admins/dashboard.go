@@ -2,7 +2,6 @@ package admins
 
 import (
 	"net/http"
-	"project/database"
 	"project/models"
 	"project/utils"
 	"strings"
@@ -47,13 +46,14 @@ type DashboardStats struct {
 	TotalBalance        float64             `json:"total_balance"`
 	TotalForums         int64               `json:"total_forums"`
 	PendingForums       int64               `json:"pending_forums"`
+	OpenTickets         int64               `json:"open_tickets"`
 	TypeTransactions    TypeTransactions    `json:"type_transactions"`
 	LastTransactions    []TransactionDetail `json:"last_transactions"`
 }
 
-func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
+func (h *ReportHandler) GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 	var stats DashboardStats
-	db := database.DB
+	db := h.db()
 
 	// initialize slices to ensure empty arrays are returned (not null)
 	stats.GrowthUsers = make([]DailyGrowth, 0)
@@ -166,6 +166,11 @@ func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 		Where("status = ?", "Pending").
 		Count(&stats.PendingForums)
 
+	// Get support tickets waiting for a support reply
+	db.Model(&models.SupportTicket{}).
+		Where("status = ?", "Open").
+		Count(&stats.OpenTickets)
+
 	// Type transactions counts (set to null when zero)
 	var cnt int64
 
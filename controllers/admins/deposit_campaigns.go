@@ -0,0 +1,222 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+type depositCampaignRequest struct {
+	Name             *string    `json:"name"`
+	MinAmount        *int64     `json:"min_amount"`
+	BonusPercent     *float64   `json:"bonus_percent"`
+	BonusFlat        *int64     `json:"bonus_flat"`
+	FirstDepositOnly *bool      `json:"first_deposit_only"`
+	StartsAt         *time.Time `json:"starts_at"`
+	EndsAt           *time.Time `json:"ends_at"`
+	Budget           *int64     `json:"budget"`
+	Status           string     `json:"status"`
+}
+
+// GET /api/admin/deposit-campaigns?status=Active|Inactive
+func ListDepositCampaignsHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	query := db.Model(&models.DepositCampaign{})
+	if status := r.URL.Query().Get("status"); status == "Active" || status == "Inactive" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListDepositCampaignsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var campaigns []models.DepositCampaign
+	if err := query.Order("starts_at DESC, id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&campaigns).Error; err != nil {
+		utils.LogError(r, "ListDepositCampaignsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(campaigns, pg, totalRows),
+	})
+}
+
+// POST /api/admin/deposit-campaigns
+func CreateDepositCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	var req depositCampaignRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	campaign := models.DepositCampaign{Status: "Active"}
+	if msg := applyDepositCampaignRequest(&campaign, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if err := database.DB.Create(&campaign).Error; err != nil {
+		utils.LogError(r, "CreateDepositCampaignHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat campaign"})
+		return
+	}
+	auditLogTarget(r, "deposit_campaign.create", "deposit_campaign", campaign.ID, nil, campaign)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Campaign berhasil dibuat",
+		Data:    campaign,
+	})
+}
+
+// PUT /api/admin/deposit-campaigns/{id}
+// budget_used is never edited here; it only grows as bonuses are paid.
+func UpdateDepositCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req depositCampaignRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	var campaign models.DepositCampaign
+	if err := db.First(&campaign, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Campaign tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "UpdateDepositCampaignHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	before := campaign
+
+	if msg := applyDepositCampaignRequest(&campaign, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	// Select skips budget_used so a bonus paid meanwhile is not overwritten
+	if err := db.Model(&campaign).
+		Select("name", "min_amount", "bonus_percent", "bonus_flat", "first_deposit_only", "starts_at", "ends_at", "budget", "status").
+		Updates(&campaign).Error; err != nil {
+		utils.LogError(r, "UpdateDepositCampaignHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate campaign"})
+		return
+	}
+	auditLog(r, "deposit_campaign.update", before, campaign)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Campaign berhasil diupdate",
+		Data:    campaign,
+	})
+}
+
+// DELETE /api/admin/deposit-campaigns/{id}
+// Deactivates the campaign; it stops applying to payments settled afterwards.
+func DeleteDepositCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	res := database.DB.Model(&models.DepositCampaign{}).Where("id = ?", id).Update("status", "Inactive")
+	if res.Error != nil {
+		utils.LogError(r, "DeleteDepositCampaignHandler", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus campaign"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Campaign tidak ditemukan"})
+		return
+	}
+	auditLog(r, "deposit_campaign.deactivate", map[string]interface{}{"id": id}, nil)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Campaign berhasil dinonaktifkan",
+	})
+}
+
+// applyDepositCampaignRequest copies the provided fields onto c and validates
+// the result, returning a user-facing message when invalid.
+func applyDepositCampaignRequest(c *models.DepositCampaign, req *depositCampaignRequest) string {
+	if req.Name != nil {
+		c.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.MinAmount != nil {
+		c.MinAmount = *req.MinAmount
+	}
+	if req.BonusPercent != nil {
+		c.BonusPercent = *req.BonusPercent
+	}
+	if req.BonusFlat != nil {
+		c.BonusFlat = *req.BonusFlat
+	}
+	if req.FirstDepositOnly != nil {
+		c.FirstDepositOnly = *req.FirstDepositOnly
+	}
+	if req.StartsAt != nil {
+		c.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		c.EndsAt = *req.EndsAt
+	}
+	if req.Budget != nil {
+		c.Budget = *req.Budget
+	}
+	if req.Status == "Active" || req.Status == "Inactive" {
+		c.Status = req.Status
+	}
+
+	if c.Name == "" || len(c.Name) > 100 {
+		return "Nama campaign wajib diisi (maksimal 100 karakter)"
+	}
+	if c.MinAmount < 0 {
+		return "Minimal deposit tidak boleh negatif"
+	}
+	if c.BonusPercent < 0 || c.BonusPercent > 100 || c.BonusFlat < 0 {
+		return "Bonus tidak valid"
+	}
+	if (c.BonusPercent > 0) == (c.BonusFlat > 0) {
+		return "Isi salah satu: bonus_percent atau bonus_flat"
+	}
+	if c.StartsAt.IsZero() || c.EndsAt.IsZero() {
+		return "Waktu mulai dan berakhir wajib diisi"
+	}
+	if !c.EndsAt.After(c.StartsAt) {
+		return "Waktu berakhir harus setelah waktu mulai"
+	}
+	if c.Budget < 0 {
+		return "Budget tidak boleh negatif"
+	}
+	if c.Budget > 0 && c.Budget < c.BudgetUsed {
+		return "Budget tidak boleh kurang dari bonus yang sudah dibayarkan"
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package admins
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestApplyDepositCampaignRequestValidation(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	ptr := func(v float64) *float64 { return &v }
+	i64 := func(v int64) *int64 { return &v }
+	name := "Promo 500k"
+
+	valid := depositCampaignRequest{Name: &name, MinAmount: i64(500000), BonusPercent: ptr(5), StartsAt: &start, EndsAt: &end}
+	var c models.DepositCampaign
+	if msg := applyDepositCampaignRequest(&c, &valid); msg != "" {
+		t.Fatalf("valid campaign rejected: %s", msg)
+	}
+	if got := c.BonusFor(600000); got != 30000 {
+		t.Fatalf("expected 5%% of 600000 = 30000, got %d", got)
+	}
+
+	cases := map[string]depositCampaignRequest{
+		"no bonus":        {Name: &name, StartsAt: &start, EndsAt: &end},
+		"both bonuses":    {Name: &name, BonusPercent: ptr(5), BonusFlat: i64(10000), StartsAt: &start, EndsAt: &end},
+		"ends before":     {Name: &name, BonusFlat: i64(10000), StartsAt: &end, EndsAt: &start},
+		"missing window":  {Name: &name, BonusFlat: i64(10000)},
+		"negative budget": {Name: &name, BonusFlat: i64(10000), StartsAt: &start, EndsAt: &end, Budget: i64(-1)},
+	}
+	for label, req := range cases {
+		var c models.DepositCampaign
+		if msg := applyDepositCampaignRequest(&c, &req); msg == "" {
+			t.Errorf("%s: expected a validation message", label)
+		}
+	}
+
+	// The budget cannot be lowered below what was already paid
+	spent := models.DepositCampaign{Name: name, BonusFlat: 10000, StartsAt: start, EndsAt: end, Budget: 100000, BudgetUsed: 50000}
+	if msg := applyDepositCampaignRequest(&spent, &depositCampaignRequest{Budget: i64(40000)}); msg == "" {
+		t.Error("expected budget below budget_used to be rejected")
+	}
+}
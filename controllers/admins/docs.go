@@ -0,0 +1,29 @@
+package admins
+
+import (
+	"net/http"
+)
+
+// swaggerUIPage renders /v3/docs/openapi.json with swagger-ui from the CDN.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Xinxun API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: "/v3/docs/openapi.json", dom_id: "#swagger-ui", persistAuthorization: true});
+</script>
+</body>
+</html>
+`
+
+// GET /api/admin/docs
+func SwaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(swaggerUIPage))
+}
@@ -0,0 +1,136 @@
+package admins
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"project/alert"
+	"project/kyta"
+	"project/models"
+	"project/notify"
+	"project/utils"
+	"project/webhook"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultExpressRunBudget bounds the rupiah one express run pays out
+	// when EXPRESS_WITHDRAWAL_RUN_BUDGET is unset
+	defaultExpressRunBudget int64 = 10000000
+	expressBatchSize              = 100
+)
+
+// errExpressSkipped marks a withdrawal an overlapping approval, rejection or
+// run already took out of Pending.
+var errExpressSkipped = errors.New("express withdrawal no longer pending")
+
+// POST /api/cron/express-withdrawals
+// Pays out Pending express withdrawals through Kyta, oldest first, without an
+// admin approving them. Withdrawals a risk rule flagged or held wait for an
+// admin. A run stops before the final amounts paid would exceed
+// EXPRESS_WITHDRAWAL_RUN_BUDGET rupiah; the rest wait for the next run.
+func (h *WithdrawalHandler) CronExpress(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	budget := defaultExpressRunBudget
+	if v, err := strconv.ParseInt(os.Getenv("EXPRESS_WITHDRAWAL_RUN_BUDGET"), 10, 64); err == nil && v >= 0 {
+		budget = v
+	}
+
+	var queue []models.Withdrawal
+	if err := h.DB.Where("express = ? AND status = ? AND risk_flags IS NULL", true, "Pending").
+		Order("id ASC").Limit(expressBatchSize).
+		Find(&queue).Error; err != nil {
+		utils.LogError(r, "express withdrawal cron: load withdrawals", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	paid, failed := 0, 0
+	var spent int64
+	for i := range queue {
+		if utils.ShuttingDown(r) {
+			break
+		}
+		wd := &queue[i]
+		if spent+wd.FinalAmount > budget {
+			break
+		}
+		err := h.payExpress(r, wd)
+		switch {
+		case err == nil:
+			paid++
+			spent += wd.FinalAmount
+			h.Notifier.Enqueue(notify.WithdrawalStatus(wd.UserID, wd.OrderID, wd.Status, wd.FinalAmount))
+		case errors.Is(err, errExpressSkipped):
+		case errors.Is(err, kyta.ErrNotConfigured):
+			h.Alerts.Notify(alert.KeyPayoutFailed, "Penarikan ekspres tertunda: KytaPay belum dikonfigurasi")
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Konfigurasi payment gateway tidak lengkap"})
+			return
+		default:
+			utils.LogError(r, "express withdrawal cron: payout", err, "order_id", wd.OrderID)
+			h.Alerts.Notify(alert.KeyPayoutFailed, "Payout ekspres %s (Rp%d) gagal: %v", wd.OrderID, wd.FinalAmount, err)
+			failed++
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Cron executed",
+		Data: map[string]interface{}{
+			"queued": len(queue),
+			"paid":   paid,
+			"amount": spent,
+			"failed": failed,
+			"budget": budget,
+		},
+	})
+}
+
+// payExpress pays wd out and marks it and its transaction Success. The row
+// stays locked during the payout so an admin cannot process it meanwhile; a
+// failed payout leaves it Pending for review.
+func (h *WithdrawalHandler) payExpress(r *http.Request, wd *models.Withdrawal) error {
+	sent := false
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(wd, wd.ID).Error; err != nil {
+			return err
+		}
+		if wd.Status != "Pending" || wd.RiskFlags != nil {
+			return errExpressSkipped
+		}
+		var ba models.BankAccount
+		if err := tx.Preload("Bank").First(&ba, wd.BankAccountID).Error; err != nil {
+			return err
+		}
+		payout, err := h.Kyta.CreatePayout(r.Context(), payoutRequest(wd, &ba))
+		if err != nil {
+			return err
+		}
+		sent = true
+
+		wd.Status = "Success"
+		wd.GatewayPayoutID = payout.PayoutID()
+		markProcessed(r, wd)
+		if err := tx.Save(wd).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", wd.OrderID).Update("status", "Success").Error; err != nil {
+			return err
+		}
+		return recordWithdrawalEvent(tx, webhook.EventWithdrawalSettled, wd, "kytapay")
+	})
+	if err != nil && sent {
+		return fmt.Errorf("payout %s sent but status not saved: %w", utils.GetStringValue(wd.GatewayPayoutID), err)
+	}
+	return err
+}
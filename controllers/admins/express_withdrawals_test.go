@@ -0,0 +1,75 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/controllers/users"
+	"project/models"
+	"project/testutil"
+)
+
+// The express cron pays pending express withdrawals out through the gateway
+// without an admin.
+func TestCronExpressWithdrawals(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	t.Setenv("EXPRESS_WITHDRAWAL_RUN_BUDGET", "1000000")
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, WithdrawCharge: 10, ExpressWithdraw: true, ExpressWithdrawCharge: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	suffix := time.Now().UnixNano() % 1000000000
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Express", Balance: 200000})
+	bank := models.Bank{Name: "Bank Ekspres", Code: fmt.Sprintf("EX%d", suffix), GatewayCode: "EXGW", Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	acc := models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: "Express", AccountNumber: fmt.Sprintf("%09d", suffix)}
+	if err := tx.Create(&acc).Error; err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"amount":100000,"bank_account_id":%d,"express":true}`, acc.ID)
+	rec := httptest.NewRecorder()
+	users.NewWithdrawalHandler(tx).Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/withdrawal", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var wd models.Withdrawal
+	if err := tx.Where("user_id = ?", user.ID).First(&wd).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	gateway := &testutil.Kyta{}
+	admin := NewWithdrawalHandler(tx, gateway)
+	req := httptest.NewRequest(http.MethodPost, "/v3/cron/express-withdrawals", nil)
+	req.Header.Set("X-CRON-KEY", "cron-test")
+	rec = httptest.NewRecorder()
+	admin.CronExpress(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cron: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	tx.First(&wd, wd.ID)
+	if wd.Status != "Success" || wd.ProcessedAt == nil {
+		t.Fatalf("expected the express withdrawal paid, got %+v", wd)
+	}
+	if len(gateway.Payouts) != 1 || gateway.Payouts[0].BankCode != "EXGW" {
+		t.Fatalf("expected one payout to the gateway bank code, got %+v", gateway.Payouts)
+	}
+	var trx models.Transaction
+	if err := tx.Where("order_id = ?", wd.OrderID).First(&trx).Error; err != nil {
+		t.Fatal(err)
+	}
+	if trx.Status != "Success" || trx.Charge != 15000 {
+		t.Fatalf("unexpected withdrawal transaction %+v", trx)
+	}
+}
@@ -1,7 +1,6 @@
 package admins
 
 import (
-	"encoding/json"
 	"net/http"
 	"project/database"
 	"project/models"
@@ -34,13 +33,15 @@ func GetForumsHandler(w http.ResponseWriter, r *http.Request) {
 	IDStr := r.URL.Query().Get("id")
 	startDate := r.URL.Query().Get("start_date")
 	endDate := r.URL.Query().Get("end_date")
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
 	search := r.URL.Query().Get("search")
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
 
 	// Build query
 	query := db.Table("forums").
-		Select("forums.*, users.name as user_name, users.number as phone").
 		Joins("LEFT JOIN users ON forums.user_id = users.id")
 
 	// Apply filters
@@ -64,20 +65,15 @@ func GetForumsHandler(w http.ResponseWriter, r *http.Request) {
 		query = query.Where("users.name LIKE ? OR users.number LIKE ?", like, like)
 	}
 
-	// Parse pagination parameters
-	page := 1
-	limit := 20
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
-	}
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "GetForumsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: "Terjadi kesalahan sistem, silakan coba lagi",
+		})
+		return
 	}
-	offset := (page - 1) * limit
 
 	// Execute query
 	type ForumWithUserName struct {
@@ -86,7 +82,9 @@ func GetForumsHandler(w http.ResponseWriter, r *http.Request) {
 		Phone    string `gorm:"column:phone"`
 	}
 	var forums []ForumWithUserName
-	if err := query.Order("forums.created_at DESC").Offset(offset).Limit(limit).Find(&forums).Error; err != nil {
+	if err := query.Select("forums.*, users.name as user_name, users.number as phone").
+		Order("forums.created_at DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&forums).Error; err != nil {
+		utils.LogError(r, "GetForumsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -113,12 +111,12 @@ func GetForumsHandler(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data:    utils.NewPaginated(response, pg, totalRows),
 	})
 }
 
 type ApproveForumRequest struct {
-	Reward float64 `json:"reward"`
+	Reward int64 `json:"reward"` // whole rupiah
 }
 
 // PUT /api/admin/forums/:id/approve
@@ -127,11 +125,7 @@ func ApproveForumHandler(w http.ResponseWriter, r *http.Request) {
 	forumID := vars["id"]
 
 	var req ApproveForumRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -154,7 +148,7 @@ func ApproveForumHandler(w http.ResponseWriter, r *http.Request) {
 
 		// Update forum status and reward
 		forum.Status = "Accepted"
-		forum.Reward = req.Reward
+		forum.Reward = float64(req.Reward)
 		if err := tx.Save(&forum).Error; err != nil {
 			return err
 		}
@@ -172,7 +166,7 @@ func ApproveForumHandler(w http.ResponseWriter, r *http.Request) {
 			UserID:          forum.UserID,
 			Amount:          req.Reward,
 			Charge:          0,
-			OrderID:         utils.GenerateOrderID(forum.UserID),
+			OrderID:         utils.GenerateOrderID(utils.OrderBonus, forum.UserID),
 			TransactionFlow: "debit",
 			TransactionType: "bonus",
 			Message:         &msg,
@@ -186,6 +180,7 @@ func ApproveForumHandler(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
+		utils.LogError(r, "ApproveForumHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -230,6 +225,7 @@ func RejectForumHandler(w http.ResponseWriter, r *http.Request) {
 	// Update forum status
 	forum.Status = "Rejected"
 	if err := db.Save(&forum).Error; err != nil {
+		utils.LogError(r, "RejectForumHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
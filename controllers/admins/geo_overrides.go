@@ -0,0 +1,166 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/geoip"
+	"project/middleware"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+type geoOverrideRequest struct {
+	UserID uint   `json:"user_id"`
+	Reason string `json:"reason"`
+	// ExpiresAt ends the override by itself; omitted keeps it until removed
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GET /api/admin/geo-overrides?user_id=
+// Users let past geoblocking, newest first.
+func ListGeoOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	query := database.DB.Model(&models.GeoOverride{})
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		uid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "user_id tidak valid"})
+			return
+		}
+		query = query.Where("user_id = ?", uid)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListGeoOverridesHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	overrides := []models.GeoOverride{}
+	if err := query.Order("id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&overrides).Error; err != nil {
+		utils.LogError(r, "ListGeoOverridesHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(overrides, pg, totalRows),
+	})
+}
+
+// POST /api/admin/geo-overrides
+// Lets a user register, buy and withdraw from any country. A user who
+// already has an override gets its reason and expiry replaced.
+func CreateGeoOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	var req geoOverrideRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.UserID == 0 || req.Reason == "" || len(req.Reason) > 255 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "user_id dan alasan (maksimal 255 karakter) wajib diisi"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "expires_at harus di masa depan"})
+		return
+	}
+	adminID, _ := utils.GetAdminID(r)
+
+	db := database.DB
+	if err := db.Select("id").First(&models.User{}, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeUserNotFound)
+			return
+		}
+		utils.LogError(r, "CreateGeoOverrideHandler: user", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var override models.GeoOverride
+	err := db.Where("user_id = ?", req.UserID).First(&override).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogError(r, "CreateGeoOverrideHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	var before interface{}
+	status := http.StatusCreated
+	if err == nil {
+		before = override
+		status = http.StatusOK
+	}
+	override.UserID = req.UserID
+	override.Reason = req.Reason
+	override.AdminID = adminID
+	override.ExpiresAt = req.ExpiresAt
+	if err := db.Save(&override).Error; err != nil {
+		utils.LogError(r, "CreateGeoOverrideHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan pengecualian wilayah"})
+		return
+	}
+	auditLogTarget(r, "geo_override.save", "geo_override", override.ID, before, override)
+	utils.WriteJSON(w, status, utils.APIResponse{Success: true, Message: "Pengecualian wilayah berhasil disimpan", Data: override})
+}
+
+// DELETE /api/admin/geo-overrides/{id}
+func DeleteGeoOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	db := database.DB
+	var override models.GeoOverride
+	if err := db.First(&override, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengecualian wilayah tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "DeleteGeoOverrideHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	if err := db.Delete(&override).Error; err != nil {
+		utils.LogError(r, "DeleteGeoOverrideHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus pengecualian wilayah"})
+		return
+	}
+	auditLog(r, "geo_override.delete", override, nil)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Pengecualian wilayah berhasil dihapus"})
+}
+
+// GET /api/admin/geoip?ip=
+// The country an IP resolves to, to explain a REGION_BLOCKED to support.
+// Without ip it looks up the caller's own address.
+func GeoIPLookupHandler(w http.ResponseWriter, r *http.Request) {
+	ip := strings.TrimSpace(r.URL.Query().Get("ip"))
+	if ip == "" {
+		ip = middleware.ClientIP(r)
+	}
+	country := geoip.Lookup(ip)
+	blocked := false
+	if setting, err := models.GetCachedSetting(database.DB); err == nil && geoip.Loaded() {
+		blocked = setting.GeoBlocked(country)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"ip":      ip,
+		"country": country,
+		"blocked": blocked,
+	}})
+}
@@ -0,0 +1,63 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+)
+
+// The geo lists are validated and normalised when saved, and support's
+// override is in force from the moment it is created.
+func TestGeoSettingsAndOverrides(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, MinDeposit: 10000, MaxDeposit: 10000000, WithdrawStartHour: 9, WithdrawEndHour: 17}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	traveller := testutil.NewUser(t, tx, models.User{Name: "Pelancong"})
+
+	setLists := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		UpdateSettingsHandler(rec, testutil.AsAdmin(httptest.NewRequest(http.MethodPut, "/v3/admin/settings", strings.NewReader(body)), 7))
+		return rec
+	}
+	if rec := setLists(`{"geo_deny_countries":["sg","Singapore"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid code: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := setLists(`{"geo_deny_countries":[" sg","SG"]}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"geo_deny_countries":["SG"]`) {
+		t.Fatalf("deny list: expected SG stored once, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := setLists(`{"geo_allow_countries":["ID"],"geo_deny_countries":[]}`); rec.Code != http.StatusOK {
+		t.Fatalf("allow list: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stored models.Setting
+	if err := tx.First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.GeoAllowCountries != "ID" || stored.GeoDenyCountries != "" {
+		t.Fatalf("expected allow ID and no deny list, got %q and %q", stored.GeoAllowCountries, stored.GeoDenyCountries)
+	}
+
+	rec := httptest.NewRecorder()
+	CreateGeoOverrideHandler(rec, testutil.AsAdmin(httptest.NewRequest(http.MethodPost, "/v3/admin/geo-overrides", strings.NewReader(fmt.Sprintf(`{"user_id":%d,"reason":"Dinas ke Singapura"}`, traveller.ID))), 7))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("override: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ok, err := models.HasGeoOverride(tx, traveller.ID, time.Now()); err != nil || !ok {
+		t.Fatalf("expected the override in force, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,452 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// grantBatchSize is how many users one grant transaction credits.
+const grantBatchSize = 500
+
+// Per-user caps, so a mistyped amount cannot credit millions of users with a
+// fortune.
+const (
+	maxGrantTickets = 100
+	maxGrantBalance = 10000000
+	maxGrantUserIDs = 10000
+)
+
+var grantBatchKeyPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type grantRequest struct {
+	// BatchID names the job; submitting it again resumes it
+	BatchID  string `json:"batch_id"`
+	Target   string `json:"target"`
+	MinLevel *uint  `json:"min_level"`
+	UserIDs  []uint `json:"user_ids"`
+	// InvestedFrom and InvestedTo are YYYY-MM-DD in APP_TIMEZONE, both inclusive
+	InvestedFrom string `json:"invested_from"`
+	InvestedTo   string `json:"invested_to"`
+	GrantType    string `json:"grant_type"`
+	Amount       int64  `json:"amount"`
+	Message      string `json:"message"`
+}
+
+// GrantBatchResponse is a batch with its progress.
+type GrantBatchResponse struct {
+	models.GrantBatch
+	// Matched is how many users the target matches now; Remaining how many of
+	// them the batch has not reached yet
+	Matched   int64 `json:"matched"`
+	Remaining int64 `json:"remaining"`
+}
+
+// GrantItemResponse is one user's credit from a batch.
+type GrantItemResponse struct {
+	UserID    uint    `json:"user_id"`
+	Name      string  `json:"name"`
+	Number    string  `json:"number"`
+	Amount    int64   `json:"amount"`
+	OrderID   *string `json:"order_id"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// POST /api/admin/grants
+// Credits spin tickets or balance to every active user matching the target:
+// all users, VIP level min_level and above, a user_ids list, or users who
+// bought an investment between invested_from and invested_to. Users are
+// credited in id order, 500 per transaction, each recorded in
+// grant_batch_items. Submitting the same batch_id again after a crash or a
+// timeout resumes the batch where it stopped; nobody is credited twice.
+func CreateGrantHandler(w http.ResponseWriter, r *http.Request) {
+	var req grantRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	batch, msg := grantBatchFromRequest(&req)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	var existing models.GrantBatch
+	err := db.Where("batch_key = ?", batch.BatchKey).First(&existing).Error
+	switch {
+	case err == nil:
+		if !sameGrant(&existing, batch) {
+			utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "batch_id sudah dipakai untuk grant dengan parameter lain"})
+			return
+		}
+		batch = &existing
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		adminID, _ := utils.GetAdminID(r)
+		batch.AdminID = uint(adminID)
+		if err := db.Model(&models.User{}).Select("COALESCE(MAX(id), 0)").Scan(&batch.MaxUserID).Error; err != nil {
+			utils.LogError(r, "CreateGrantHandler", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+			return
+		}
+		if err := db.Create(batch).Error; err != nil {
+			utils.LogError(r, "CreateGrantHandler", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat grant"})
+			return
+		}
+		auditLogTarget(r, "grant.create", "grant_batch", batch.ID, nil, batch)
+	default:
+		utils.LogError(r, "CreateGrantHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	if err := runGrantBatch(r, db, batch); err != nil {
+		utils.LogError(r, "CreateGrantHandler: run", err, "batch_id", batch.ID, "last_user_id", batch.LastUserID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Grant terhenti; kirim ulang batch_id yang sama untuk melanjutkan"})
+		return
+	}
+	resp, err := grantBatchProgress(db, batch)
+	if err != nil {
+		utils.LogError(r, "CreateGrantHandler: progress", err, "batch_id", batch.ID)
+	}
+	// Stopped by a shutdown: accepted, to be resumed
+	status, message := http.StatusOK, "Grant selesai"
+	if batch.Status != models.GrantBatchCompleted {
+		status, message = http.StatusAccepted, "Grant belum selesai; kirim ulang batch_id yang sama untuk melanjutkan"
+	}
+	utils.WriteJSON(w, status, utils.APIResponse{Success: true, Message: message, Data: resp})
+}
+
+// GET /api/admin/grants?status=Running|Completed
+func ListGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	query := database.DB.Model(&models.GrantBatch{})
+	if status := r.URL.Query().Get("status"); status == models.GrantBatchRunning || status == models.GrantBatchCompleted {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListGrantsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var batches []models.GrantBatch
+	if err := query.Order("id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&batches).Error; err != nil {
+		utils.LogError(r, "ListGrantsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(batches, pg, totalRows),
+	})
+}
+
+// GET /api/admin/grants/{id}
+// The batch with how many users its target matches and how many are left.
+func GetGrantHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	db := database.DB
+	var batch models.GrantBatch
+	if err := db.First(&batch, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Grant tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "GetGrantHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	resp, err := grantBatchProgress(db, &batch)
+	if err != nil {
+		utils.LogError(r, "GetGrantHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: resp})
+}
+
+// GET /api/admin/grants/{id}/items
+// The users a batch credited, in the order it credited them.
+func ListGrantItemsHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	query := database.DB.Table("grant_batch_items").
+		Joins("JOIN users ON users.id = grant_batch_items.user_id").
+		Where("grant_batch_items.batch_id = ?", id)
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListGrantItemsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	type itemRow struct {
+		UserID    uint
+		Name      string
+		Number    string
+		Amount    int64
+		OrderID   *string
+		CreatedAt time.Time
+	}
+	var rows []itemRow
+	if err := query.Select("grant_batch_items.user_id, users.name, users.number, grant_batch_items.amount, grant_batch_items.order_id, grant_batch_items.created_at").
+		Order("grant_batch_items.user_id ASC").Offset(pg.Offset).Limit(pg.Limit).Scan(&rows).Error; err != nil {
+		utils.LogError(r, "ListGrantItemsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	items := make([]GrantItemResponse, len(rows))
+	for i, row := range rows {
+		items[i] = GrantItemResponse{UserID: row.UserID, Name: row.Name, Number: row.Number, Amount: row.Amount, OrderID: row.OrderID, CreatedAt: utils.FormatTime(row.CreatedAt)}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(items, pg, totalRows),
+	})
+}
+
+// grantBatchFromRequest validates req into a new batch, returning a
+// user-facing message when invalid.
+func grantBatchFromRequest(req *grantRequest) (*models.GrantBatch, string) {
+	b := &models.GrantBatch{
+		BatchKey:  strings.TrimSpace(req.BatchID),
+		Target:    req.Target,
+		GrantType: req.GrantType,
+		Amount:    req.Amount,
+		Message:   strings.TrimSpace(req.Message),
+		Status:    models.GrantBatchRunning,
+	}
+	if !grantBatchKeyPattern.MatchString(b.BatchKey) {
+		return nil, "batch_id wajib diisi (huruf, angka, titik, garis bawah atau strip; maksimal 64 karakter)"
+	}
+
+	switch b.Target {
+	case models.GrantTargetAll:
+	case models.GrantTargetVIP:
+		if req.MinLevel == nil || *req.MinLevel < 1 || *req.MinLevel > models.MaxVIPLevel {
+			return nil, fmt.Sprintf("min_level harus antara 1 dan %d", models.MaxVIPLevel)
+		}
+		b.MinLevel = req.MinLevel
+	case models.GrantTargetUsers:
+		if len(req.UserIDs) == 0 || len(req.UserIDs) > maxGrantUserIDs {
+			return nil, fmt.Sprintf("user_ids wajib diisi (maksimal %d)", maxGrantUserIDs)
+		}
+		raw, _ := json.Marshal(req.UserIDs)
+		ids := string(raw)
+		b.UserIDs = &ids
+	case models.GrantTargetInvested:
+		appLoc := utils.AppLocation()
+		from, errFrom := time.ParseInLocation("2006-01-02", req.InvestedFrom, appLoc)
+		to, errTo := time.ParseInLocation("2006-01-02", req.InvestedTo, appLoc)
+		if errFrom != nil || errTo != nil || to.Before(from) {
+			return nil, "invested_from dan invested_to wajib diisi (YYYY-MM-DD), invested_to tidak sebelum invested_from"
+		}
+		to = to.AddDate(0, 0, 1)
+		b.InvestedFrom, b.InvestedTo = &from, &to
+	default:
+		return nil, "target harus all, vip, users atau invested"
+	}
+
+	switch b.GrantType {
+	case models.GrantTypeSpinTicket:
+		if b.Amount < 1 || b.Amount > maxGrantTickets {
+			return nil, fmt.Sprintf("Jumlah tiket harus antara 1 dan %d", maxGrantTickets)
+		}
+		if b.Message == "" {
+			b.Message = "Tiket spin promo dari admin"
+		}
+	case models.GrantTypeBalance:
+		if b.Amount < 1 || b.Amount > maxGrantBalance {
+			return nil, fmt.Sprintf("Jumlah saldo harus antara 1 dan %d", maxGrantBalance)
+		}
+		if b.Message == "" {
+			b.Message = "Bonus promo dari admin"
+		}
+	default:
+		return nil, "grant_type harus spin_ticket atau balance"
+	}
+	if len(b.Message) > 255 {
+		return nil, "Pesan maksimal 255 karakter"
+	}
+	return b, ""
+}
+
+// sameGrant reports whether a resubmitted batch asks for the same grant as
+// the stored one.
+func sameGrant(stored, req *models.GrantBatch) bool {
+	sameUint := func(a, b *uint) bool { return (a == nil) == (b == nil) && (a == nil || *a == *b) }
+	sameString := func(a, b *string) bool { return (a == nil) == (b == nil) && (a == nil || *a == *b) }
+	sameTime := func(a, b *time.Time) bool { return (a == nil) == (b == nil) && (a == nil || a.Equal(*b)) }
+	return stored.Target == req.Target && stored.GrantType == req.GrantType && stored.Amount == req.Amount &&
+		sameUint(stored.MinLevel, req.MinLevel) && sameString(stored.UserIDs, req.UserIDs) &&
+		sameTime(stored.InvestedFrom, req.InvestedFrom) && sameTime(stored.InvestedTo, req.InvestedTo)
+}
+
+// grantTargetQuery selects the active users b targets, up to its MaxUserID.
+func grantTargetQuery(db *gorm.DB, b *models.GrantBatch) (*gorm.DB, error) {
+	q := db.Model(&models.User{}).Where("users.status = ? AND users.id <= ?", "Active", b.MaxUserID)
+	switch b.Target {
+	case models.GrantTargetVIP:
+		q = q.Where("users.level >= ?", *b.MinLevel)
+	case models.GrantTargetUsers:
+		var ids []uint
+		if err := json.Unmarshal([]byte(*b.UserIDs), &ids); err != nil {
+			return nil, err
+		}
+		q = q.Where("users.id IN ?", ids)
+	case models.GrantTargetInvested:
+		q = q.Where(`EXISTS (SELECT 1 FROM investments WHERE investments.user_id = users.id AND investments.deleted_at IS NULL
+			AND investments.status IN ? AND investments.created_at >= ? AND investments.created_at < ?)`,
+			[]string{"Running", "Completed"}, *b.InvestedFrom, *b.InvestedTo)
+	}
+	return q, nil
+}
+
+// runGrantBatch credits b's remaining users, grantBatchSize per transaction.
+// Each transaction locks the batch and moves its cursor with the credits, so
+// a crash loses no progress and two runs of one batch take turns. It returns
+// early, with b still Running, when the server shuts down.
+func runGrantBatch(r *http.Request, db *gorm.DB, b *models.GrantBatch) error {
+	for b.Status == models.GrantBatchRunning {
+		if utils.ShuttingDown(r) {
+			return nil
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(b, b.ID).Error; err != nil {
+				return err
+			}
+			if b.Status != models.GrantBatchRunning {
+				return nil
+			}
+			q, err := grantTargetQuery(tx, b)
+			if err != nil {
+				return err
+			}
+			var ids []uint
+			if err := q.Where("users.id > ?", b.LastUserID).Order("users.id ASC").Limit(grantBatchSize).Pluck("users.id", &ids).Error; err != nil {
+				return err
+			}
+			granted := 0
+			for _, uid := range ids {
+				ok, err := grantOne(tx, b, uid)
+				if err != nil {
+					return fmt.Errorf("user %d: %w", uid, err)
+				}
+				if ok {
+					granted++
+				}
+			}
+			updates := map[string]interface{}{"granted": b.Granted + granted}
+			if len(ids) > 0 {
+				updates["last_user_id"] = ids[len(ids)-1]
+			}
+			if len(ids) < grantBatchSize {
+				updates["status"] = models.GrantBatchCompleted
+				updates["completed_at"] = time.Now()
+			}
+			return tx.Model(b).Updates(updates).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantOne credits uid once per batch, recording the grant_batch_items row
+// first; false when uid was credited before.
+func grantOne(tx *gorm.DB, b *models.GrantBatch, uid uint) (bool, error) {
+	item := models.GrantBatchItem{BatchID: b.ID, UserID: uid, Amount: b.Amount}
+	var orderID string
+	if b.GrantType == models.GrantTypeBalance {
+		orderID = utils.GenerateOrderID(utils.OrderBonus, uid)
+		item.OrderID = &orderID
+	}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&item)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+
+	if b.GrantType == models.GrantTypeSpinTicket {
+		_, err := models.GrantSpinTickets(tx, uid, models.TicketSourceAdmin, fmt.Sprintf("grant-%d-%d", b.ID, uid), uint(b.Amount))
+		return err == nil, err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", uid).UpdateColumn("balance", gorm.Expr("balance + ?", b.Amount)).Error; err != nil {
+		return false, err
+	}
+	msg := b.Message
+	if err := tx.Create(&models.Transaction{
+		UserID:          uid,
+		Amount:          b.Amount,
+		OrderID:         orderID,
+		TransactionFlow: "debit",
+		TransactionType: "bonus",
+		Message:         &msg,
+		Status:          "Success",
+	}).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// grantBatchProgress counts the users b's target matches and, while it runs,
+// those it has not reached.
+func grantBatchProgress(db *gorm.DB, b *models.GrantBatch) (GrantBatchResponse, error) {
+	resp := GrantBatchResponse{GrantBatch: *b}
+	q, err := grantTargetQuery(db, b)
+	if err != nil {
+		return resp, err
+	}
+	if err := q.Count(&resp.Matched).Error; err != nil {
+		return resp, err
+	}
+	if b.Status == models.GrantBatchRunning {
+		if q, err = grantTargetQuery(db, b); err != nil {
+			return resp, err
+		}
+		if err := q.Where("users.id > ?", b.LastUserID).Count(&resp.Remaining).Error; err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
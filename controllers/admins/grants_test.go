@@ -0,0 +1,123 @@
+package admins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// A grant credits each matching active user once per batch_id: submitting the
+// batch again, even after its cursor was lost, credits nobody twice.
+func TestAdminGrantBatches(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	suffix := time.Now().UnixNano() % 1000000000
+
+	level := func(l uint) *uint { return &l }
+	vip3 := testutil.NewUser(t, tx, models.User{Name: "VIP3", Level: level(3)})
+	vip1 := testutil.NewUser(t, tx, models.User{Name: "VIP1", Level: level(1)})
+	suspended := testutil.NewUser(t, tx, models.User{Name: "Suspend", Level: level(4), Status: "Suspend"})
+
+	post := func(body string) (*httptest.ResponseRecorder, GrantBatchResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/grants", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		rec := httptest.NewRecorder()
+		CreateGrantHandler(rec, req)
+		var resp struct {
+			Data GrantBatchResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return rec, resp.Data
+	}
+	balances := func() (int64, int64, int64) {
+		var got [3]models.User
+		for i, u := range []models.User{vip3, vip1, suspended} {
+			if err := tx.Select("balance").First(&got[i], u.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		return got[0].Balance, got[1].Balance, got[2].Balance
+	}
+
+	key := fmt.Sprintf("promo-%d", suffix)
+	body := fmt.Sprintf(`{"batch_id":%q,"target":"users","user_ids":[%d,%d,%d],"grant_type":"balance","amount":25000}`, key, vip3.ID, vip1.ID, suspended.ID)
+	rec, batch := post(body)
+	if rec.Code != http.StatusOK || batch.Status != models.GrantBatchCompleted || batch.Granted != 2 || batch.Matched != 2 {
+		t.Fatalf("balance grant: expected 200 Completed with 2 granted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if a, b, c := balances(); a != 25000 || b != 25000 || c != 0 {
+		t.Fatalf("unexpected balances %d %d %d", a, b, c)
+	}
+
+	// Resubmitted, and again as if the crash had lost the cursor
+	if rec, _ := post(body); rec.Code != http.StatusOK {
+		t.Fatalf("resubmit: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := tx.Model(&models.GrantBatch{}).Where("id = ?", batch.ID).Updates(map[string]interface{}{"status": models.GrantBatchRunning, "last_user_id": 0}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rec, resumed := post(body); rec.Code != http.StatusOK || resumed.Status != models.GrantBatchCompleted || resumed.Granted != 2 {
+		t.Fatalf("resume: expected Completed with 2 granted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if a, b, _ := balances(); a != 25000 || b != 25000 {
+		t.Fatalf("credited twice: balances %d %d", a, b)
+	}
+	var bonuses int64
+	tx.Model(&models.Transaction{}).Where("user_id IN ? AND transaction_type = ?", []uint{vip3.ID, vip1.ID}, "bonus").Count(&bonuses)
+	if bonuses != 2 {
+		t.Fatalf("expected 2 bonus transactions, got %d", bonuses)
+	}
+	if rec, _ := post(strings.Replace(body, "25000", "30000", 1)); rec.Code != http.StatusConflict {
+		t.Fatalf("batch_id reused for another grant: expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Per-user results
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/admin/grants/%d/items", batch.ID), nil), map[string]string{"id": fmt.Sprint(batch.ID)})
+	rec = httptest.NewRecorder()
+	ListGrantItemsHandler(rec, req)
+	var items struct {
+		Data struct {
+			Data []GrantItemResponse `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items.Data.Data) != 2 || items.Data.Data[0].UserID != vip3.ID || items.Data.Data[0].OrderID == nil {
+		t.Fatalf("unexpected items %s", rec.Body.String())
+	}
+
+	// VIP3 and up, active only
+	if rec, _ := post(fmt.Sprintf(`{"batch_id":"tiket-%d","target":"vip","min_level":3,"grant_type":"spin_ticket","amount":2}`, suffix)); rec.Code != http.StatusOK {
+		t.Fatalf("ticket grant: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	tickets := func(u models.User) uint {
+		var got models.User
+		if err := tx.Select("spin_ticket").First(&got, u.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.SpinTicket == nil {
+			return 0
+		}
+		return *got.SpinTicket
+	}
+	if tickets(vip3) != 2 || tickets(vip1) != 0 || tickets(suspended) != 0 {
+		t.Fatalf("unexpected tickets %d %d %d", tickets(vip3), tickets(vip1), tickets(suspended))
+	}
+}
@@ -1,17 +1,26 @@
 package admins
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"project/alert"
 	"project/database"
+	"project/kyta"
 	"project/models"
+	"project/notify"
+	"project/referral"
 	"project/utils"
+	"project/vip"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InvestmentResponse struct {
@@ -23,94 +32,120 @@ type InvestmentResponse struct {
 	ProductName   string  `json:"product_name"`
 	CategoryID    uint    `json:"category_id"`
 	CategoryName  string  `json:"category_name"`
-	Amount        float64 `json:"amount"`
+	Amount        int64   `json:"amount"`
 	Duration      int     `json:"duration"`
-	DailyProfit   float64 `json:"daily_profit"`
+	DailyProfit   int64   `json:"daily_profit"`
 	TotalPaid     int     `json:"total_paid"`
-	TotalReturned float64 `json:"total_returned"`
+	TotalReturned int64   `json:"total_returned"`
 	LastReturnAt  string  `json:"last_return_at,omitempty"`
 	NextReturnAt  string  `json:"next_return_at,omitempty"`
 	OrderID       string  `json:"order_id"`
+	CertificateNo *string `json:"certificate_no,omitempty"`
 	Status        string  `json:"status"`
+	CreatedBy     *int64  `json:"created_by,omitempty"`
 	CreatedAt     string  `json:"created_at"`
 }
 
-func GetInvestments(w http.ResponseWriter, r *http.Request) {
+// GET /api/admin/investments
+// Filters: user_id, product_id, category_id, status, search (order_id),
+// start_date/end_date (creation date, APP_TIMEZONE), include_archived.
+func (h *ReportHandler) GetInvestments(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	productID := r.URL.Query().Get("product_id")
-	status := r.URL.Query().Get("status")
-	orderID := r.URL.Query().Get("search")
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
+	q := r.URL.Query()
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
-
-	offset := (page - 1) * limit
+	userID := q.Get("user_id")
+	productID := q.Get("product_id")
+	categoryID := q.Get("category_id")
+	status := q.Get("status")
+	orderID := q.Get("search")
+	startDate := q.Get("start_date")
+	endDate := q.Get("end_date")
 
 	// Start query
-	db := database.DB
-	query := db.Model(&models.Investment{}).
-		Joins("JOIN products ON investments.product_id = products.id").
-		Joins("JOIN categories ON investments.category_id = categories.id")
+	db := h.db()
+	if includeArchived, _ := strconv.ParseBool(q.Get("include_archived")); includeArchived {
+		db = db.Unscoped()
+	}
+	query := db.Model(&models.Investment{})
 
 	// Apply filters
+	if userID != "" {
+		query = query.Where("investments.user_id = ?", userID)
+	}
 	if productID != "" {
 		query = query.Where("investments.product_id = ?", productID)
 	}
+	if categoryID != "" {
+		query = query.Where("investments.category_id = ?", categoryID)
+	}
 	if status != "" {
 		query = query.Where("investments.status = ?", status)
 	}
 	if orderID != "" {
-		query = query.Where("investments.order_id LIKE ?", "%"+orderID+"%")
+		query = query.Where("investments.order_id LIKE ?", utils.PrefixLike(orderID))
 	}
 
-	// Get investments with product and category details
+	appLoc := utils.AppLocation()
+	if startDate != "" {
+		if startTime, err := time.ParseInLocation("2006-01-02", startDate, appLoc); err == nil {
+			query = query.Where("investments.created_at >= ?", startTime)
+		}
+	}
+	if endDate != "" {
+		if endTime, err := time.ParseInLocation("2006-01-02", endDate, appLoc); err == nil {
+			query = query.Where("investments.created_at < ?", endTime.AddDate(0, 0, 1))
+		}
+	}
+
+	// Totals for the whole filtered set, not just the current page
+	var summary struct {
+		TotalRows           int64
+		TotalAmount         int64
+		ExpectedTotalProfit int64
+		TotalReturned       int64
+	}
+	if err := query.Session(&gorm.Session{}).
+		Select("COUNT(*) AS total_rows, COALESCE(SUM(investments.amount), 0) AS total_amount, COALESCE(SUM(investments.total_returned + investments.daily_profit * GREATEST(investments.duration - investments.total_paid, 0)), 0) AS expected_total_profit, COALESCE(SUM(investments.total_returned), 0) AS total_returned").
+		Scan(&summary).Error; err != nil {
+		utils.LogError(r, "GetInvestments", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	// Get investments with user and category details
+	// Product name comes from the snapshot on the investment row
 	type InvestmentWithProduct struct {
 		models.Investment
-		ProductName  string
 		CategoryName string
+		UserName     string
+		UserNumber   string
 	}
 
 	var investments []InvestmentWithProduct
-	query.Select("investments.*, products.name as product_name, categories.name as category_name").
-		Offset(offset).
-		Limit(limit).
+	if err := query.Joins("LEFT JOIN categories ON investments.category_id = categories.id").
+		Joins("LEFT JOIN users ON investments.user_id = users.id").
+		Select("investments.*, categories.name as category_name, users.name as user_name, users.number as user_number").
+		Offset(pg.Offset).
+		Limit(pg.Limit).
 		Order("investments.created_at DESC").
-		Find(&investments)
-
-	// Prepare user IDs to fetch names in batch
-	userIDsSet := make(map[uint]struct{})
-	for _, inv := range investments {
-		userIDsSet[inv.UserID] = struct{}{}
-	}
-	var userIDs []uint
-	for id := range userIDsSet {
-		userIDs = append(userIDs, id)
-	}
-
-	// Fetch users and build a map[id]user
-	usersByID := make(map[uint]models.User, len(userIDs))
-	if len(userIDs) > 0 {
-		var users []models.User
-		db.Select("id, name, number").Where("id IN ?", userIDs).Find(&users)
-		for _, u := range users {
-			usersByID[u.ID] = u
-		}
+		Find(&investments).Error; err != nil {
+		utils.LogError(r, "GetInvestments", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
 	}
 
 	// Transform to response format
-	var response []InvestmentResponse
+	response := make([]InvestmentResponse, 0, len(investments))
 	for _, inv := range investments {
 		response = append(response, InvestmentResponse{
 			ID:            inv.ID,
 			UserID:        inv.UserID,
-			UserName:      usersByID[inv.UserID].Name,
-			Phone:         usersByID[inv.UserID].Number,
+			UserName:      inv.UserName,
+			Phone:         inv.UserNumber,
 			ProductID:     inv.ProductID,
 			ProductName:   inv.ProductName,
 			CategoryID:    inv.CategoryID,
@@ -123,18 +158,30 @@ func GetInvestments(w http.ResponseWriter, r *http.Request) {
 			LastReturnAt:  formatTimePtr(inv.LastReturnAt),
 			NextReturnAt:  formatTimePtr(inv.NextReturnAt),
 			OrderID:       inv.OrderID,
+			CertificateNo: inv.CertificateNo,
 			Status:        inv.Status,
-			CreatedAt:     inv.CreatedAt.Format(time.RFC3339),
+			CreatedBy:     inv.CreatedBy,
+			CreatedAt:     utils.FormatTime(inv.CreatedAt),
 		})
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data: map[string]interface{}{
+			"data":       response,
+			"pagination": pg.Meta(summary.TotalRows),
+			"summary": map[string]interface{}{
+				"total_amount":          summary.TotalAmount,
+				"expected_total_profit": summary.ExpectedTotalProfit,
+				"total_returned":        summary.TotalReturned,
+			},
+		},
 	})
 }
 
+// GET /api/admin/investments/{id}
+// Includes the payment row, every related transaction and the return history.
 func GetInvestmentDetail(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseUint(vars["id"], 10, 32)
@@ -146,18 +193,18 @@ func GetInvestmentDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get investment with product and category details
+	// Get investment with category details
+	// Product name comes from the snapshot on the investment row
 	type InvestmentWithProduct struct {
 		models.Investment
-		ProductName  string
 		CategoryName string
 	}
 
+	db := database.DB.Unscoped()
 	var investment InvestmentWithProduct
-	err = database.DB.Model(&models.Investment{}).
-		Joins("JOIN products ON investments.product_id = products.id").
-		Joins("JOIN categories ON investments.category_id = categories.id").
-		Select("investments.*, products.name as product_name, categories.name as category_name").
+	err = db.Model(&models.Investment{}).
+		Joins("LEFT JOIN categories ON investments.category_id = categories.id").
+		Select("investments.*, categories.name as category_name").
 		Where("investments.id = ?", id).
 		First(&investment).Error
 
@@ -169,6 +216,7 @@ func GetInvestmentDetail(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		utils.LogError(r, "GetInvestmentDetail", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -178,7 +226,46 @@ func GetInvestmentDetail(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch user name
 	var user models.User
-	_ = database.DB.Select("id, name, number").First(&user, investment.UserID).Error
+	_ = db.Select("id, name, number").First(&user, investment.UserID).Error
+
+	var payment *models.Payment
+	var p models.Payment
+	if err := db.Where("investment_id = ?", investment.ID).Order("id DESC").First(&p).Error; err == nil {
+		payment = &p
+	}
+
+	// Older transactions predate investment_id and are matched by the purchase order_id
+	var transactions []models.Transaction
+	if err := db.Where("investment_id = ? OR order_id = ?", investment.ID, investment.OrderID).
+		Order("id ASC").
+		Find(&transactions).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: "Terjadi kesalahan sistem, silakan coba lagi",
+		})
+		return
+	}
+
+	related := make([]TransactionResponse, 0, len(transactions))
+	returns := make([]TransactionResponse, 0)
+	for _, t := range transactions {
+		item := TransactionResponse{
+			ID:              t.ID,
+			UserID:          t.UserID,
+			Amount:          t.Amount,
+			Charge:          t.Charge,
+			OrderID:         t.OrderID,
+			TransactionFlow: t.TransactionFlow,
+			TransactionType: t.TransactionType,
+			Message:         utils.GetStringValue(t.Message),
+			Status:          t.Status,
+			CreatedAt:       utils.FormatTime(t.CreatedAt),
+		}
+		related = append(related, item)
+		if t.TransactionType == "return" && t.UserID == investment.UserID {
+			returns = append(returns, item)
+		}
+	}
 
 	response := InvestmentResponse{
 		ID:            investment.ID,
@@ -197,14 +284,21 @@ func GetInvestmentDetail(w http.ResponseWriter, r *http.Request) {
 		LastReturnAt:  formatTimePtr(investment.LastReturnAt),
 		NextReturnAt:  formatTimePtr(investment.NextReturnAt),
 		OrderID:       investment.OrderID,
+		CertificateNo: investment.CertificateNo,
 		Status:        investment.Status,
-		CreatedAt:     investment.CreatedAt.Format(time.RFC3339),
+		CreatedBy:     investment.CreatedBy,
+		CreatedAt:     utils.FormatTime(investment.CreatedAt),
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data: map[string]interface{}{
+			"investment":     response,
+			"payment":        payment,
+			"transactions":   related,
+			"return_history": returns,
+		},
 	})
 }
 
@@ -224,11 +318,7 @@ func UpdateInvestmentStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateInvestmentStatusRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Format data tidak valid",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -254,9 +344,11 @@ func UpdateInvestmentStatus(w http.ResponseWriter, r *http.Request) {
 			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
 				Success: false,
 				Message: "Investasi tidak ditemukan",
+				Code:    utils.CodeInvestmentNotFound,
 			})
 			return
 		}
+		utils.LogError(r, "UpdateInvestmentStatus", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data investasi",
@@ -274,6 +366,7 @@ func UpdateInvestmentStatus(w http.ResponseWriter, r *http.Request) {
 	investment.Status = req.Status
 
 	if err := database.DB.Save(&investment).Error; err != nil {
+		utils.LogError(r, "UpdateInvestmentStatus", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal memperbarui status investasi",
@@ -291,9 +384,232 @@ func UpdateInvestmentStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+type cancelInvestmentRequest struct {
+	RefundMode string `json:"refund_mode" validate:"required,oneof=balance payout none"`
+	// BankAccountID picks the verified account a payout refund goes to;
+	// omitted uses the one the user last withdrew to
+	BankAccountID    uint   `json:"bank_account_id"`
+	ClawbackReferral bool   `json:"clawback_referral"`
+	Reason           string `json:"reason" validate:"required,max=255"`
+}
+
+var (
+	errInvestmentCompleted      = errors.New("investment completed")
+	errInvestmentNotCancellable = errors.New("investment not cancellable")
+	errRefundAccountUnverified  = errors.New("refund bank account not verified")
+)
+
+// POST /api/admin/investments/{id}/cancel
+// Unwinds a Running or Suspended investment, e.g. after a mis-priced product.
+// refund_mode "balance" credits the principal to the user's balance, "none"
+// refunds nothing and "payout" sends it through KytaPay to the user's
+// verified bank account: bank_account_id, or by default the account last
+// withdrawn to. The payout is a Pending "refund" that the payout callback
+// confirms; a user without a verified account is refunded to the balance and
+// asked to withdraw it. Profit already paid is kept. total_invest and
+// total_invest_vip are rolled back and the VIP level recalculated following
+// VIP_DOWNGRADE_POLICY, and clawback_referral takes the referral bonus back
+// from the referrer following REFERRAL_CLAWBACK_POLICY. As in Approve, the
+// payout is sent last inside the transaction, so a gateway error cancels
+// nothing.
+func (h *WithdrawalHandler) CancelInvestment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID investasi tidak valid"})
+		return
+	}
+	var req cancelInvestmentRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	adminID, _ := utils.GetAdminID(r)
+
+	ctx := r.Context()
+	var opts utils.TxOptions
+	if req.RefundMode == "payout" {
+		// Once the payout is sent the cancellation must be saved, and a
+		// retry would pay twice
+		ctx = context.WithoutCancel(ctx)
+		opts = utils.TxOptions{Timeout: -1}
+	}
+
+	var inv, before models.Investment
+	var refunded, clawedBack int64
+	var refundPayout *models.RefundPayout
+	refundMode := req.RefundMode
+	var payoutErr error
+	sent := false
+	err = utils.WithTxOptions(ctx, h.DB, opts, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, id).Error; err != nil {
+			return err
+		}
+		before = inv
+		switch inv.Status {
+		case "Completed":
+			return errInvestmentCompleted
+		case "Running", "Suspended":
+		default:
+			return errInvestmentNotCancellable
+		}
+		if err := tx.Model(&inv).Updates(map[string]interface{}{"status": "Cancelled", "next_return_at": nil}).Error; err != nil {
+			return err
+		}
+
+		// Undo what activation added; the VIP level only follows locked categories
+		var category models.Category
+		locked := tx.Where("id = ?", inv.CategoryID).First(&category).Error == nil && category.ProfitType == "locked"
+		userUpdates := map[string]interface{}{"total_invest": gorm.Expr("GREATEST(total_invest - ?, 0)", inv.Amount)}
+		if locked {
+			userUpdates["total_invest_vip"] = gorm.Expr("GREATEST(total_invest_vip - ?, 0)", inv.Amount)
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Updates(userUpdates).Error; err != nil {
+			return err
+		}
+		if locked {
+			if _, err := vip.Recalculate(tx, inv.UserID, nil, vip.SourceCancel, vip.Policy()); err != nil {
+				return err
+			}
+		}
+
+		var account *models.BankAccount
+		if refundMode == "payout" {
+			var err error
+			if account, err = models.VerifiedBankAccount(tx, inv.UserID, req.BankAccountID); err != nil {
+				return err
+			}
+			if account == nil {
+				if req.BankAccountID != 0 {
+					return errRefundAccountUnverified
+				}
+				refundMode = "balance"
+			}
+		}
+		msg := fmt.Sprintf("Refund pembatalan investasi %s: %s", inv.ProductName, reason)
+		switch refundMode {
+		case "balance":
+			if err := creditRefund(tx, &inv, msg); err != nil {
+				return err
+			}
+			refunded = inv.Amount
+		case "payout":
+			refundPayout = &models.RefundPayout{
+				InvestmentID:  inv.ID,
+				UserID:        inv.UserID,
+				BankAccountID: account.ID,
+				Amount:        inv.Amount,
+				OrderID:       utils.GenerateOrderID(utils.OrderRefundPayout, inv.UserID),
+				Status:        "Pending",
+				AdminID:       adminID,
+			}
+			if err := tx.Create(refundPayout).Error; err != nil {
+				return err
+			}
+			// Never counted in the balance ledger: it goes to the bank
+			if err := tx.Create(&models.Transaction{
+				UserID:          inv.UserID,
+				InvestmentID:    &inv.ID,
+				Amount:          inv.Amount,
+				OrderID:         refundPayout.OrderID,
+				TransactionFlow: "debit",
+				TransactionType: "refund",
+				Message:         &msg,
+				Status:          "Pending",
+			}).Error; err != nil {
+				return err
+			}
+			refunded = inv.Amount
+		}
+
+		if req.ClawbackReferral {
+			res, err := referral.Clawback(tx, inv.OrderID, referral.Policy(), "investasi dibatalkan: "+reason)
+			if err != nil {
+				return err
+			}
+			clawedBack = res.Recovered
+		}
+
+		if refundPayout != nil {
+			resp, err := h.Kyta.CreatePayout(r.Context(), refundPayoutRequest(refundPayout, account))
+			if err != nil {
+				payoutErr = err
+				return err
+			}
+			sent = true
+			refundPayout.GatewayPayoutID = resp.PayoutID()
+			if refundPayout.GatewayPayoutID != nil {
+				return tx.Model(refundPayout).Update("gateway_payout_id", refundPayout.GatewayPayoutID).Error
+			}
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Investasi tidak ditemukan", Code: utils.CodeInvestmentNotFound})
+		return
+	case errors.Is(err, errInvestmentCompleted):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Investasi yang sudah selesai tidak dapat dibatalkan"})
+		return
+	case errors.Is(err, errInvestmentNotCancellable):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hanya investasi Running atau Suspended yang dapat dibatalkan"})
+		return
+	case errors.Is(err, errRefundAccountUnverified):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Rekening tujuan refund tidak ditemukan atau belum terverifikasi"})
+		return
+	case errors.Is(payoutErr, kyta.ErrNotConfigured):
+		h.Alerts.Notify(alert.KeyPayoutFailed, "Refund payout investasi %d tidak terkirim: KytaPay belum dikonfigurasi", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Konfigurasi payment gateway tidak lengkap"})
+		return
+	case payoutErr != nil:
+		utils.LogError(r, "CancelInvestment: refund payout", err, "investment_id", id)
+		message := "Gagal memproses payout"
+		var kerr *kyta.Error
+		if errors.As(err, &kerr) && kerr.Message != "" {
+			message = kerr.Message
+		}
+		utils.WriteJSON(w, http.StatusBadGateway, utils.APIResponse{Success: false, Message: message, Code: utils.CodePaymentGatewayError})
+		return
+	case sent:
+		utils.LogError(r, "CancelInvestment: refund payout sent", err, "order_id", refundPayout.OrderID, "gateway_payout_id", utils.GetStringValue(refundPayout.GatewayPayoutID))
+		h.Alerts.Notify(alert.KeyPayoutFailed, "Refund payout %s (KytaPay %s) sudah dikirim tetapi pembatalan investasi %d gagal disimpan: %v", refundPayout.OrderID, utils.GetStringValue(refundPayout.GatewayPayoutID), id, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membatalkan investasi"})
+		return
+	default:
+		utils.LogError(r, "CancelInvestment", err, "investment_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membatalkan investasi"})
+		return
+	}
+
+	result := map[string]interface{}{
+		"id":     inv.ID,
+		"status": inv.Status,
+		// refund_mode is what was done: "balance" when a payout was asked
+		// for a user without a verified account
+		"refund_mode":          refundMode,
+		"refunded":             refunded,
+		"refund_payout":        refundPayout,
+		"referral_clawed_back": clawedBack,
+		"reason":               reason,
+	}
+	auditLog(r, "investment.cancel", before, result)
+	switch {
+	case refundPayout != nil:
+		h.Notifier.Enqueue(notify.InvestmentRefund(inv.UserID, refundPayout.OrderID, inv.ProductName, notify.RefundSent, refundPayout.Amount))
+	case refundMode != req.RefundMode:
+		h.Notifier.Enqueue(notify.InvestmentRefund(inv.UserID, inv.OrderID, inv.ProductName, notify.RefundBalance, refunded))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Investasi berhasil dibatalkan",
+		Data:    result,
+	})
+}
+
 func formatTimePtr(t *time.Time) string {
 	if t == nil {
 		return ""
 	}
-	return t.Format(time.RFC3339)
+	return utils.FormatTime(*t)
 }
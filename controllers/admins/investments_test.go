@@ -0,0 +1,230 @@
+package admins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/controllers/users"
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAdminCancelInvestment(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Batal", ReffBy: &referrer.ID})
+	category := testutil.NewCategory(t, tx, "locked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Monitor 1", Amount: 1500000, DailyProfit: 10000, Duration: 30})
+
+	asAdmin := func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), utils.AdminIDKey, int64(1)))
+	}
+	rec := httptest.NewRecorder()
+	users.NewInvestmentHandler(tx, &testutil.Kyta{}).AdminCreate(rec, asAdmin(httptest.NewRequest(http.MethodPost, "/v3/admin/investments",
+		strings.NewReader(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true}`, user.ID, product.ID)))))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	var activated, refBefore models.User
+	tx.First(&activated, user.ID)
+	tx.First(&refBefore, referrer.ID)
+	if activated.Level == nil || *activated.Level != 2 || refBefore.Balance <= 0 {
+		t.Fatalf("expected VIP 2 and a referral bonus after activation, got level %v referrer balance %d", activated.Level, refBefore.Balance)
+	}
+
+	cancel := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/investments/x/cancel", strings.NewReader(body))
+		req = mux.SetURLVars(asAdmin(req), map[string]string{"id": fmt.Sprint(inv.ID)})
+		rec := httptest.NewRecorder()
+		NewWithdrawalHandler(tx, &testutil.Kyta{}).CancelInvestment(rec, req)
+		return rec
+	}
+	if rec := cancel(`{"refund_mode":"cash","reason":"Salah harga"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown refund mode: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := cancel(`{"refund_mode":"balance","clawback_referral":true,"reason":"Salah harga"}`); rec.Code != http.StatusOK {
+		t.Fatalf("cancel: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got, ref models.User
+	tx.First(&got, user.ID)
+	tx.First(&ref, referrer.ID)
+	if got.TotalInvest != 0 || got.TotalInvestVIP != 0 || got.Level == nil || *got.Level != 0 {
+		t.Fatalf("expected totals and VIP level rolled back, got %+v", got)
+	}
+	if got.Balance != product.Amount {
+		t.Fatalf("expected principal %d refunded to balance, got %d", product.Amount, got.Balance)
+	}
+	if ref.Balance != 0 {
+		t.Fatalf("expected referral bonus clawed back, referrer balance %d", ref.Balance)
+	}
+	if err := tx.First(&inv, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Cancelled" {
+		t.Fatalf("expected Cancelled, got %s", inv.Status)
+	}
+
+	// A cancelled or completed investment cannot be cancelled again
+	if rec := cancel(`{"refund_mode":"none","reason":"lagi"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("second cancel: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	tx.Model(&inv).Update("status", "Completed")
+	if rec := cancel(`{"refund_mode":"none","reason":"selesai"}`); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "selesai") {
+		t.Fatalf("completed: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// A payout refund goes to the user's verified bank account and is settled by
+// the payout callback; a failed payout, or a user without a verified account,
+// is refunded to the balance instead.
+func TestAdminCancelInvestmentPayoutRefund(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	suffix := time.Now().UnixNano() % 1000000000
+	bank := models.Bank{Name: "Bank Refund", Code: fmt.Sprintf("RP%d", suffix), GatewayCode: "RPGW", Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	verified := testutil.NewUser(t, tx, models.User{Name: "Refund Bank"})
+	unverified := testutil.NewUser(t, tx, models.User{Name: "Refund Saldo"})
+	paidTo := models.BankAccount{UserID: verified.ID, BankID: bank.ID, AccountName: "Refund Bank", AccountNumber: fmt.Sprintf("1%09d", suffix)}
+	fresh := models.BankAccount{UserID: verified.ID, BankID: bank.ID, AccountName: "Refund Bank", AccountNumber: fmt.Sprintf("2%09d", suffix)}
+	for _, a := range []*models.BankAccount{&paidTo, &fresh} {
+		if err := tx.Create(a).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Create(&models.Withdrawal{UserID: verified.ID, BankAccountID: paidTo.ID, Amount: 100000, FinalAmount: 90000, OrderID: fmt.Sprintf("WD-RP%d", suffix), Status: "Success"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	category := testutil.NewCategory(t, tx, "unlocked")
+	newInvestment := func(u models.User, n int) models.Investment {
+		t.Helper()
+		inv := models.Investment{UserID: u.ID, CategoryID: category.ID, ProductName: "Refund 1", Amount: 500000, DailyProfit: 5000, Duration: 30, OrderID: fmt.Sprintf("INV-RP%d-%d", suffix, n), Status: "Running"}
+		if err := tx.Create(&inv).Error; err != nil {
+			t.Fatal(err)
+		}
+		return inv
+	}
+
+	kc := &testutil.Kyta{}
+	admin := NewWithdrawalHandler(tx, kc)
+	cancel := func(inv models.Investment, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/investments/x/cancel", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		req = mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(inv.ID)})
+		rec := httptest.NewRecorder()
+		admin.CancelInvestment(rec, req)
+		return rec
+	}
+	callback := func(orderID, status string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"po-%s","reference_id":%q,"amount":500000,"status":%q}}`, orderID, orderID, status)
+		rec := httptest.NewRecorder()
+		admin.KytaPayoutCallback(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/withdrawals", strings.NewReader(body)))
+		return rec
+	}
+	balance := func(u models.User) int64 {
+		t.Helper()
+		var got models.User
+		if err := tx.First(&got, u.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return got.Balance
+	}
+
+	// Without a verified account the principal goes to the balance
+	inv := newInvestment(unverified, 1)
+	if rec := cancel(inv, `{"refund_mode":"payout","reason":"Salah harga"}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"refund_mode":"balance"`) {
+		t.Fatalf("no account: expected 200 refunded to balance, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := balance(unverified); got != inv.Amount || len(kc.Payouts) != 0 {
+		t.Fatalf("no account: expected balance %d and no payout, got %d and %d payouts", inv.Amount, got, len(kc.Payouts))
+	}
+
+	// An account never withdrawn to cannot be picked
+	inv = newInvestment(verified, 2)
+	if rec := cancel(inv, fmt.Sprintf(`{"refund_mode":"payout","bank_account_id":%d,"reason":"Salah harga"}`, fresh.ID)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unverified account: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := tx.First(&inv, inv.ID).Error; err != nil || inv.Status != "Running" {
+		t.Fatalf("unverified account: expected the investment still Running, got %s (%v)", inv.Status, err)
+	}
+
+	// The default account is paid and the callback confirms it
+	if rec := cancel(inv, `{"refund_mode":"payout","reason":"Salah harga"}`); rec.Code != http.StatusOK {
+		t.Fatalf("payout: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rp models.RefundPayout
+	if err := tx.Where("investment_id = ?", inv.ID).First(&rp).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(kc.Payouts) != 1 || kc.Payouts[0].ReferenceID != rp.OrderID || kc.Payouts[0].AccountNumber != paidTo.AccountNumber || !strings.HasPrefix(rp.OrderID, utils.RefundPayoutOrderPrefix) {
+		t.Fatalf("payout: expected %s sent to %s, got %+v", rp.OrderID, paidTo.AccountNumber, kc.Payouts)
+	}
+	if got := balance(verified); got != 0 || rp.Status != "Pending" {
+		t.Fatalf("payout: expected a Pending payout and the balance untouched, got %s and %d", rp.Status, got)
+	}
+	if rec := callback(rp.OrderID, "Success"); rec.Code != http.StatusOK {
+		t.Fatalf("success callback: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var trx models.Transaction
+	if err := tx.First(&rp, rp.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Where("order_id = ?", rp.OrderID).First(&trx).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rp.Status != "Success" || trx.Status != "Success" || trx.TransactionType != "refund" {
+		t.Fatalf("success callback: expected the payout and its refund Success, got %s and %s %s", rp.Status, trx.TransactionType, trx.Status)
+	}
+
+	// A failed payout is credited to the balance, once
+	inv = newInvestment(verified, 3)
+	if rec := cancel(inv, fmt.Sprintf(`{"refund_mode":"payout","bank_account_id":%d,"reason":"Salah harga"}`, paidTo.ID)); rec.Code != http.StatusOK {
+		t.Fatalf("second payout: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rp = models.RefundPayout{}
+	if err := tx.Where("investment_id = ?", inv.ID).First(&rp).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if rec := callback(rp.OrderID, "Failed"); rec.Code != http.StatusOK {
+			t.Fatalf("failed callback: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if rec := callback(rp.OrderID, "Success"); rec.Code != http.StatusOK {
+		t.Fatalf("late success: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := tx.First(&rp, rp.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(verified); got != inv.Amount || rp.Status != "Failed" {
+		t.Fatalf("failed callback: expected %d on the balance and the payout Failed, got %d and %s", inv.Amount, got, rp.Status)
+	}
+	if rec := callback("RPO-missing", "Success"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown refund: expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,265 @@
+package admins
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errLeaderboardClosed = errors.New("leaderboard period closed")
+
+// leaderboardScope reads LEADERBOARD_SCOPE: "level1" ranks by direct
+// referrals only, anything else by the whole downline.
+func leaderboardScope() string {
+	if strings.TrimSpace(os.Getenv("LEADERBOARD_SCOPE")) == models.LeaderboardScopeLevel1 {
+		return models.LeaderboardScopeLevel1
+	}
+	return models.LeaderboardScopeTree
+}
+
+// leaderboardPrizes reads LEADERBOARD_PRIZES, the rupiah prize per rank as a
+// comma-separated list starting at rank 1 (e.g. "1000000,500000,250000").
+func leaderboardPrizes() ([]int64, error) {
+	s := strings.TrimSpace(os.Getenv("LEADERBOARD_PRIZES"))
+	if s == "" {
+		return nil, nil
+	}
+	var prizes []int64
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid LEADERBOARD_PRIZES entry %q", part)
+		}
+		prizes = append(prizes, v)
+	}
+	return prizes, nil
+}
+
+// lockLeaderboardPeriod loads period FOR UPDATE, returning a new unsaved Open
+// row when it does not exist yet. It fails with errLeaderboardClosed once the
+// period was closed.
+func lockLeaderboardPeriod(tx *gorm.DB, period string) (*models.LeaderboardPeriod, error) {
+	p := models.LeaderboardPeriod{Period: period, Status: "Open"}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("period = ?", period).First(&p).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if p.Status == "Closed" {
+		return nil, errLeaderboardClosed
+	}
+	p.Scope = leaderboardScope()
+	return &p, nil
+}
+
+// POST /api/cron/leaderboard-snapshot?period=YYYY-MM
+// Rebuilds the ranking of the period (default: the current month) from the
+// investments settled in it so far. Closed periods are left untouched.
+func CronLeaderboardSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	loc := utils.AppLocation()
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = time.Now().In(loc).Format("2006-01")
+	}
+	start, end, err := models.LeaderboardPeriodRange(period, loc)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format periode harus YYYY-MM"})
+		return
+	}
+
+	var saved *models.LeaderboardPeriod
+	var ranked int
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		p, err := lockLeaderboardPeriod(tx, period)
+		if err != nil {
+			return err
+		}
+		snapshot, err := models.BuildLeaderboardSnapshot(tx, period, start, end, p.Scope)
+		if err != nil {
+			return err
+		}
+		saved, ranked = p, len(snapshot)
+		return models.SaveLeaderboardSnapshot(tx, p, snapshot)
+	})
+	if errors.Is(err, errLeaderboardClosed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Periode leaderboard sudah ditutup"})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "leaderboard cron: build snapshot", err, "period", period)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Cron executed",
+		Data:    map[string]interface{}{"period": saved.Period, "scope": saved.Scope, "snapshot_at": saved.SnapshotAt, "ranked": ranked},
+	})
+}
+
+// GET /api/admin/leaderboard/{period}
+func GetLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	period := mux.Vars(r)["period"]
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	var p models.LeaderboardPeriod
+	if err := db.Where("period = ?", period).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Periode leaderboard tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "GetLeaderboardHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var totalRows int64
+	if err := db.Model(&models.LeaderboardSnapshot{}).Where("period = ?", period).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "GetLeaderboardHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	type row struct {
+		Rank       int    `json:"rank"`
+		UserID     uint   `json:"user_id"`
+		Name       string `json:"name"`
+		Number     string `json:"number"`
+		TeamVolume int64  `json:"team_volume"`
+		Prize      int64  `json:"prize"`
+	}
+	rows := []row{}
+	if err := db.Table("leaderboard_snapshots AS s").
+		Select("s.`rank`, s.user_id, users.name, users.number, s.team_volume, s.prize").
+		Joins("JOIN users ON users.id = s.user_id").
+		Where("s.period = ?", period).
+		Order("s.`rank` ASC").Offset(pg.Offset).Limit(pg.Limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "GetLeaderboardHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"period":  p,
+			"ranking": utils.NewPaginated(rows, pg, totalRows),
+		},
+	})
+}
+
+// POST /api/admin/leaderboard/{period}/close
+// Takes the final snapshot of an ended period, credits LEADERBOARD_PRIZES to
+// the top ranks and freezes the period. Closing twice is refused.
+func CloseLeaderboardPeriodHandler(w http.ResponseWriter, r *http.Request) {
+	period := mux.Vars(r)["period"]
+	start, end, err := models.LeaderboardPeriodRange(period, utils.AppLocation())
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format periode harus YYYY-MM"})
+		return
+	}
+	if time.Now().Before(end) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Periode leaderboard belum berakhir"})
+		return
+	}
+	prizes, err := leaderboardPrizes()
+	if err != nil {
+		utils.LogError(r, "CloseLeaderboardPeriodHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Konfigurasi hadiah leaderboard tidak valid"})
+		return
+	}
+	adminID, _ := utils.GetAdminID(r)
+
+	var closed *models.LeaderboardPeriod
+	winners := []models.LeaderboardSnapshot{}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		p, err := lockLeaderboardPeriod(tx, period)
+		if err != nil {
+			return err
+		}
+		snapshot, err := models.BuildLeaderboardSnapshot(tx, period, start, end, p.Scope)
+		if err != nil {
+			return err
+		}
+		for i := range snapshot {
+			if i < len(prizes) {
+				snapshot[i].Prize = prizes[i]
+			}
+		}
+
+		now := time.Now()
+		p.Status = "Closed"
+		p.ClosedAt = &now
+		p.ClosedBy = &adminID
+		if err := models.SaveLeaderboardSnapshot(tx, p, snapshot); err != nil {
+			return err
+		}
+
+		for _, s := range snapshot {
+			if s.Prize <= 0 {
+				continue
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", s.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", s.Prize)).Error; err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("Hadiah leaderboard %s peringkat %d", period, s.Rank)
+			trx := models.Transaction{
+				UserID:          s.UserID,
+				Amount:          s.Prize,
+				Charge:          0,
+				OrderID:         utils.GenerateOrderID(utils.OrderBonus, s.UserID),
+				TransactionFlow: "debit",
+				TransactionType: "leaderboard",
+				Message:         &msg,
+				Status:          "Success",
+			}
+			if err := tx.Create(&trx).Error; err != nil {
+				return err
+			}
+			winners = append(winners, s)
+		}
+		closed = p
+		return nil
+	})
+	if errors.Is(err, errLeaderboardClosed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Periode leaderboard sudah ditutup"})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "CloseLeaderboardPeriodHandler", err, "period", period)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menutup periode leaderboard"})
+		return
+	}
+	auditLog(r, "leaderboard.close", nil, map[string]interface{}{"period": closed, "winners": winners})
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Periode leaderboard berhasil ditutup",
+		Data:    map[string]interface{}{"period": closed, "winners": winners},
+	})
+}
@@ -1,7 +1,6 @@
 package admins
 
 import (
-	"encoding/json"
 	"net/http"
 	"project/models"
 	"project/utils"
@@ -14,11 +13,7 @@ type LoginRequest struct {
 
 func Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid JSON body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -28,6 +23,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
 			Success: false,
 			Message: "Username atau password salah",
+			Code:    utils.CodeInvalidCredentials,
 		})
 		return
 	}
@@ -37,6 +33,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
 			Success: false,
 			Message: "Username atau password salah",
+			Code:    utils.CodeInvalidCredentials,
 		})
 		return
 	}
@@ -44,6 +41,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	// Generate JWT token
 	token, err := utils.GenerateJWT(admin.ID, admin.Username, "admin")
 	if err != nil {
+		utils.LogError(r, "Login", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal membuat token",
@@ -0,0 +1,26 @@
+package admins
+
+import (
+	"net/http"
+
+	"project/cache"
+	"project/middleware"
+	"project/utils"
+)
+
+// GET /api/admin/metrics
+// In-process counters for this instance: rate limiter map sizes, recent
+// response times per route, recovered panics and read cache hits and misses. Each replica reports only its own state.
+func GetMetrics(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"rate_limiters":  middleware.RateLimiterSnapshot(),
+			"routes":         middleware.RouteTimingSnapshot(),
+			"suspicious_ips": middleware.SuspiciousIPCount(),
+			"panics":         middleware.PanicCount(),
+			"caches":         cache.Snapshot(),
+		},
+	})
+}
@@ -0,0 +1,224 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+type missionRequest struct {
+	Name         *string    `json:"name"`
+	Description  *string    `json:"description"`
+	Type         *string    `json:"type"`
+	TargetCount  *int64     `json:"target_count"`
+	RewardType   *string    `json:"reward_type"`
+	RewardAmount *int64     `json:"reward_amount"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Status       string     `json:"status"`
+}
+
+// GET /api/admin/missions?status=Active|Inactive&type=
+func ListMissionsHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	query := db.Model(&models.Mission{})
+	if status := r.URL.Query().Get("status"); status == "Active" || status == "Inactive" {
+		query = query.Where("status = ?", status)
+	}
+	if t := r.URL.Query().Get("type"); slices.Contains(models.MissionTypes, t) {
+		query = query.Where("type = ?", t)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListMissionsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var missions []models.Mission
+	if err := query.Order("starts_at DESC, id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&missions).Error; err != nil {
+		utils.LogError(r, "ListMissionsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(missions, pg, totalRows),
+	})
+}
+
+// POST /api/admin/missions
+func CreateMissionHandler(w http.ResponseWriter, r *http.Request) {
+	var req missionRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	mission := models.Mission{Status: "Active", TargetCount: 1, StartsAt: time.Now()}
+	if msg := applyMissionRequest(&mission, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if err := database.DB.Create(&mission).Error; err != nil {
+		utils.LogError(r, "CreateMissionHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat misi"})
+		return
+	}
+	auditLogTarget(r, "mission.create", "mission", mission.ID, nil, mission)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Misi berhasil dibuat",
+		Data:    mission,
+	})
+}
+
+// PUT /api/admin/missions/{id}
+// Existing progress is kept; a lowered target applies from the user's next
+// qualifying action.
+func UpdateMissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req missionRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	var mission models.Mission
+	if err := db.First(&mission, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Misi tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "UpdateMissionHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	before := mission
+
+	if msg := applyMissionRequest(&mission, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if err := db.Model(&mission).
+		Select("name", "description", "type", "target_count", "reward_type", "reward_amount", "starts_at", "ends_at", "status").
+		Updates(&mission).Error; err != nil {
+		utils.LogError(r, "UpdateMissionHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate misi"})
+		return
+	}
+	auditLog(r, "mission.update", before, mission)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Misi berhasil diupdate",
+		Data:    mission,
+	})
+}
+
+// DELETE /api/admin/missions/{id}
+// Deactivates the mission; progress stops and unclaimed rewards can no longer
+// be claimed.
+func DeleteMissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	res := database.DB.Model(&models.Mission{}).Where("id = ?", id).Update("status", "Inactive")
+	if res.Error != nil {
+		utils.LogError(r, "DeleteMissionHandler", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus misi"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Misi tidak ditemukan"})
+		return
+	}
+	auditLog(r, "mission.deactivate", map[string]interface{}{"id": id}, nil)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Misi berhasil dinonaktifkan",
+	})
+}
+
+// applyMissionRequest copies the provided fields onto m and validates the
+// result, returning a user-facing message when invalid.
+func applyMissionRequest(m *models.Mission, req *missionRequest) string {
+	if req.Name != nil {
+		m.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		m.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Type != nil {
+		m.Type = *req.Type
+	}
+	if req.TargetCount != nil {
+		m.TargetCount = *req.TargetCount
+	}
+	if req.RewardType != nil {
+		m.RewardType = *req.RewardType
+	}
+	if req.RewardAmount != nil {
+		m.RewardAmount = *req.RewardAmount
+	}
+	if req.StartsAt != nil {
+		m.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		m.EndsAt = req.EndsAt
+	}
+	if req.Status == "Active" || req.Status == "Inactive" {
+		m.Status = req.Status
+	}
+
+	if m.Name == "" || len(m.Name) > 100 {
+		return "Nama misi wajib diisi (maksimal 100 karakter)"
+	}
+	if !slices.Contains(models.MissionTypes, m.Type) {
+		return "Tipe misi harus salah satu dari: " + strings.Join(models.MissionTypes, ", ")
+	}
+	if m.TargetCount < 1 {
+		return "Target minimal 1"
+	}
+	if m.RewardType != "balance" && m.RewardType != "spin_ticket" {
+		return "Tipe hadiah harus balance atau spin_ticket"
+	}
+	if m.RewardAmount <= 0 {
+		return "Jumlah hadiah harus lebih dari 0"
+	}
+	if m.StartsAt.IsZero() {
+		return "Waktu mulai wajib diisi"
+	}
+	if m.EndsAt != nil && !m.EndsAt.After(m.StartsAt) {
+		return "Waktu berakhir harus setelah waktu mulai"
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package admins
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestApplyMissionRequestValidation(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	str := func(v string) *string { return &v }
+	i64 := func(v int64) *int64 { return &v }
+
+	valid := missionRequest{Name: str("Undang 3 investor"), Type: str(models.MissionInviteInvestor), TargetCount: i64(3), RewardType: str("balance"), RewardAmount: i64(25000), StartsAt: &start, EndsAt: &end}
+	m := models.Mission{Status: "Active", TargetCount: 1}
+	if msg := applyMissionRequest(&m, &valid); msg != "" {
+		t.Fatalf("valid mission rejected: %s", msg)
+	}
+	if !m.RunningAt(start.Add(time.Hour)) || m.RunningAt(end) {
+		t.Fatalf("expected mission to run only inside its window: %+v", m)
+	}
+
+	cases := map[string]missionRequest{
+		"unknown type":   {Name: str("x"), Type: str("deposit"), RewardType: str("balance"), RewardAmount: i64(1), StartsAt: &start},
+		"zero target":    {Name: str("x"), Type: str(models.MissionInvestment), TargetCount: i64(0), RewardType: str("balance"), RewardAmount: i64(1), StartsAt: &start},
+		"unknown reward": {Name: str("x"), Type: str(models.MissionInvestment), RewardType: str("voucher"), RewardAmount: i64(1), StartsAt: &start},
+		"no reward":      {Name: str("x"), Type: str(models.MissionInvestment), RewardType: str("spin_ticket"), StartsAt: &start},
+		"ends before":    {Name: str("x"), Type: str(models.MissionInvestment), RewardType: str("balance"), RewardAmount: i64(1), StartsAt: &end, EndsAt: &start},
+	}
+	for label, req := range cases {
+		m := models.Mission{TargetCount: 1}
+		if msg := applyMissionRequest(&m, &req); msg == "" {
+			t.Errorf("%s: expected a validation message", label)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/alert"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// monitorSampleSize is how many ids of each violation the monitor returns.
+const monitorSampleSize = 10
+
+// Grace periods before an entity counts as stuck: the outbox retries a
+// failed activation for a while, and the daily returns cron runs late.
+const (
+	monitorActivationGrace = 15 * time.Minute
+	monitorReturnGrace     = 2 * time.Hour
+)
+
+// monitorCheck is one invariant the monitor counts violations of. query
+// selects the violating rows of table, oldest first.
+type monitorCheck struct {
+	Key         string
+	Description string
+	Remediation string
+	table       string
+	query       func(db *gorm.DB, now time.Time) *gorm.DB
+}
+
+// monitorChecks are evaluated in this order.
+var monitorChecks = []monitorCheck{
+	{
+		Key:         "investment_paid_not_active",
+		Description: "Investasi Pending padahal pembayarannya Success",
+		Remediation: `PUT /api/admin/investments/{id}/status {"status":"Running"}`,
+		table:       "investments",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("investments").
+				Joins("JOIN payments ON payments.investment_id = investments.id AND payments.deleted_at IS NULL").
+				Where("investments.deleted_at IS NULL AND investments.status = ? AND payments.status = ? AND payments.updated_at < ?", "Pending", "Success", now.Add(-monitorActivationGrace)).
+				Order("payments.updated_at ASC")
+		},
+	},
+	{
+		Key:         "withdrawal_pending_stale",
+		Description: "Penarikan Pending melewati ALERT_PENDING_WITHDRAWAL_HOURS",
+		Remediation: "PUT /api/admin/withdrawals/{id}/approve or PUT /api/admin/withdrawals/{id}/reject",
+		table:       "withdrawals",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("withdrawals").
+				Where("status = ? AND created_at < ?", "Pending", now.Add(-time.Duration(pendingWithdrawalHours())*time.Hour)).
+				Order("created_at ASC")
+		},
+	},
+	{
+		Key:         "payout_failed_unresolved",
+		Description: "Payout KytaPay gagal; penarikan kembali Pending menunggu persetujuan ulang",
+		Remediation: "PUT /api/admin/withdrawals/{id}/approve (retry payout) or PUT /api/admin/withdrawals/{id}/reject",
+		table:       "withdrawals",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("withdrawals").
+				Where("status = ? AND gateway_payout_id IS NOT NULL", "Pending").
+				Order("updated_at ASC")
+		},
+	},
+	{
+		Key:         "investment_return_overdue",
+		Description: "Investasi Running yang imbal hasil hariannya terlambat",
+		Remediation: "POST /api/cron/daily-returns (X-CRON-KEY)",
+		table:       "investments",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("investments").
+				Where("deleted_at IS NULL AND status = ? AND total_paid < duration AND next_return_at < ?", "Running", now.Add(-monitorReturnGrace)).
+				Order("next_return_at ASC")
+		},
+	},
+	{
+		Key:         "investment_past_duration",
+		Description: "Investasi masih Running padahal semua hari sudah dibayar",
+		Remediation: `PUT /api/admin/investments/{id}/status {"status":"Completed"} after checking the capital was returned`,
+		table:       "investments",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("investments").
+				Where("deleted_at IS NULL AND status = ? AND total_paid >= duration", "Running").
+				Order("updated_at ASC")
+		},
+	},
+	{
+		Key:         "user_balance_negative",
+		Description: "Saldo pengguna negatif; hanya clawback bonus rekomendasi dengan REFERRAL_CLAWBACK_POLICY=negative yang boleh membuatnya",
+		Remediation: "Check the user's transactions and GET /api/admin/balance-audits, then PUT /api/admin/users/balance/{id}",
+		table:       "users",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("users").
+				Where("balance < ?", 0).
+				Order("updated_at ASC")
+		},
+	},
+	{
+		Key:         "outbox_failed",
+		Description: "Event outbox gagal setelah semua percobaan",
+		Remediation: "POST /api/admin/outbox-events/{id}/retry",
+		table:       "outbox_events",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("outbox_events").
+				Where("status = ?", models.OutboxFailed).
+				Order("updated_at ASC")
+		},
+	},
+	{
+		Key:         "webhook_delivery_failed",
+		Description: "Pengiriman webhook gagal setelah semua percobaan",
+		Remediation: "POST /api/admin/webhook-deliveries/{id}/redeliver",
+		table:       "webhook_deliveries",
+		query: func(db *gorm.DB, now time.Time) *gorm.DB {
+			return db.Table("webhook_deliveries").
+				Where("status = ?", models.WebhookDeliveryFailed).
+				Order("updated_at ASC")
+		},
+	},
+}
+
+// MonitorResult is the outcome of one check.
+type MonitorResult struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	Count       int64  `json:"count"`
+	SampleIDs   []uint `json:"sample_ids"`
+	Remediation string `json:"remediation"`
+	// Threshold is the count the cron tolerates; above it, it alerts
+	Threshold int64 `json:"threshold"`
+	Exceeded  bool  `json:"exceeded"`
+}
+
+// MonitorHandler reports entities stuck between states.
+type MonitorHandler struct {
+	DB     *gorm.DB
+	Alerts *alert.Alerter
+}
+
+func NewMonitorHandler(db *gorm.DB, a *alert.Alerter) *MonitorHandler {
+	return &MonitorHandler{DB: db, Alerts: a}
+}
+
+// monitorThresholds reads MONITOR_THRESHOLDS, e.g.
+// "withdrawal_pending_stale=5,outbox_failed=0". Unlisted checks tolerate 0.
+func monitorThresholds() map[string]int64 {
+	thresholds := map[string]int64{}
+	for _, part := range strings.Split(os.Getenv("MONITOR_THRESHOLDS"), ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil && n >= 0 {
+			thresholds[strings.TrimSpace(key)] = n
+		}
+	}
+	return thresholds
+}
+
+// run evaluates every check at now.
+func (h *MonitorHandler) run(now time.Time) ([]MonitorResult, error) {
+	thresholds := monitorThresholds()
+	results := make([]MonitorResult, 0, len(monitorChecks))
+	for _, c := range monitorChecks {
+		res := MonitorResult{Key: c.Key, Description: c.Description, Remediation: c.Remediation, Threshold: thresholds[c.Key], SampleIDs: []uint{}}
+		if err := c.query(h.DB, now).Count(&res.Count).Error; err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Key, err)
+		}
+		if res.Count > 0 {
+			if err := c.query(h.DB, now).Limit(monitorSampleSize).Pluck(c.table+".id", &res.SampleIDs).Error; err != nil {
+				return nil, fmt.Errorf("%s: %w", c.Key, err)
+			}
+		}
+		res.Exceeded = res.Count > res.Threshold
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// GET /api/admin/monitor
+// Counts each kind of stuck entity with up to 10 sample ids and the endpoint
+// that fixes one, so on-call can work from one screen.
+func (h *MonitorHandler) Get(w http.ResponseWriter, r *http.Request) {
+	results, err := h.run(time.Now())
+	if err != nil {
+		utils.LogError(r, "Monitor", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: results})
+}
+
+// POST /api/cron/monitor
+// Runs the monitor checks and alerts once per check whose count exceeds its
+// MONITOR_THRESHOLDS entry. Every 5 to 15 minutes; the alerter's cooldown
+// holds back repeats.
+func (h *MonitorHandler) Cron(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	results, err := h.run(time.Now())
+	if err != nil {
+		utils.LogError(r, "Monitor cron", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	alerted := 0
+	for _, res := range results {
+		if !res.Exceeded {
+			continue
+		}
+		alerted++
+		h.Alerts.Notify(alert.KeyMonitor+":"+res.Key, "Monitor %s: %d (batas %d). %s. Contoh id %v. Perbaikan: %s",
+			res.Key, res.Count, res.Threshold, res.Description, res.SampleIDs, res.Remediation)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{
+		"checks":  results,
+		"alerted": alerted,
+	}})
+}
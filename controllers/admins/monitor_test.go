@@ -0,0 +1,27 @@
+package admins
+
+import "testing"
+
+func TestMonitorThresholds(t *testing.T) {
+	t.Setenv("MONITOR_THRESHOLDS", " withdrawal_pending_stale=5, outbox_failed = 0,bad=-1,junk,investment_return_overdue=x")
+	got := monitorThresholds()
+	if len(got) != 2 || got["withdrawal_pending_stale"] != 5 || got["outbox_failed"] != 0 {
+		t.Fatalf("unexpected thresholds %v", got)
+	}
+	if _, ok := got["bad"]; ok {
+		t.Error("negative threshold accepted")
+	}
+}
+
+func TestMonitorChecksAreComplete(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range monitorChecks {
+		if c.Key == "" || c.Description == "" || c.Remediation == "" || c.table == "" || c.query == nil {
+			t.Errorf("check %q is incomplete", c.Key)
+		}
+		if seen[c.Key] {
+			t.Errorf("duplicate check %q", c.Key)
+		}
+		seen[c.Key] = true
+	}
+}
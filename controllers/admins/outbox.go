@@ -0,0 +1,123 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/outbox"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// outboxCronBatchSize caps the events one cron run processes.
+const outboxCronBatchSize = 500
+
+// OutboxHandler runs the outbox cron over a dispatcher built by routes.
+type OutboxHandler struct {
+	Dispatcher *outbox.Dispatcher
+}
+
+func NewOutboxHandler(d *outbox.Dispatcher) *OutboxHandler {
+	return &OutboxHandler{Dispatcher: d}
+}
+
+// POST /api/cron/outbox
+// Retries the outbox events that are due, including any left behind when the
+// dispatch right after their commit failed or the process stopped.
+func (h *OutboxHandler) Cron(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	res, err := h.Dispatcher.Run(outboxCronBatchSize, func() bool { return utils.ShuttingDown(r) })
+	if err != nil {
+		utils.LogError(r, "outbox cron: load due events", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: res})
+}
+
+// GET /api/admin/outbox-events?status=&kind=
+func ListOutboxEvents(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	query := database.DB.Model(&models.OutboxEvent{})
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case models.OutboxPending, models.OutboxDone, models.OutboxFailed:
+		query = query.Where("status = ?", status)
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Status tidak valid"})
+		return
+	}
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError(r, "ListOutboxEvents: count", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	events := []models.OutboxEvent{}
+	if err := query.Order("id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&events).Error; err != nil {
+		utils.LogError(r, "ListOutboxEvents", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(events, pg, total)})
+}
+
+// POST /api/admin/outbox-events/{id}/retry
+// Puts a Failed event back to Pending with fresh attempts for the next cron run.
+func RetryOutboxEvent(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Event tidak valid"})
+		return
+	}
+	var event models.OutboxEvent
+	if err := database.DB.First(&event, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Event tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "RetryOutboxEvent", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if event.Status != models.OutboxFailed {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Hanya event Failed yang dapat diulang"})
+		return
+	}
+
+	before := event
+	res := database.DB.Model(&models.OutboxEvent{}).Where("id = ? AND status = ?", id, models.OutboxFailed).
+		Updates(map[string]interface{}{"status": models.OutboxPending, "attempts": 0, "next_attempt_at": time.Now()})
+	if res.Error != nil {
+		utils.LogError(r, "RetryOutboxEvent: update", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Hanya event Failed yang dapat diulang"})
+		return
+	}
+	if err := database.DB.First(&event, id).Error; err != nil {
+		utils.LogError(r, "RetryOutboxEvent: reload", err)
+	}
+	auditLog(r, "outbox_event.retry", before, event)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Event dijadwalkan ulang", Data: event})
+}
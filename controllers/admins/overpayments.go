@@ -0,0 +1,145 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errOverpaymentNotHeld = errors.New("overpayment not held")
+
+// HeldOverpaymentResponse is the excess of an overpaid investment payment
+// waiting for review.
+type HeldOverpaymentResponse struct {
+	ID        uint   `json:"id"`
+	OrderID   string `json:"order_id"`
+	UserID    uint   `json:"user_id"`
+	UserName  string `json:"user_name"`
+	Payment   string `json:"payment_order_id"`
+	Amount    int64  `json:"amount"`
+	Received  int64  `json:"amount_received"`
+	CreatedAt string `json:"created_at"`
+}
+
+// GET /api/admin/overpayments/held
+// Lists overpayments held because their amount came from the payment
+// webhook, oldest first, with the amount the webhook reported so it can be
+// checked against the gateway dashboard.
+func ListHeldOverpayments(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	query := database.DB.Table("transactions AS t").
+		Joins("JOIN users u ON u.id = t.user_id").
+		Joins("LEFT JOIN payments p ON p.investment_id = t.investment_id").
+		Where("t.transaction_type = ? AND t.status = ?", "overpayment", "Held")
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListHeldOverpayments", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	type row struct {
+		models.Transaction
+		UserName       string
+		PaymentOrderID *string
+		AmountReceived *int64
+	}
+	var rows []row
+	if err := query.Select("t.*, u.name AS user_name, p.order_id AS payment_order_id, p.amount_received").
+		Order("t.id ASC").Offset(pg.Offset).Limit(pg.Limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "ListHeldOverpayments", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	resp := make([]HeldOverpaymentResponse, 0, len(rows))
+	for _, t := range rows {
+		item := HeldOverpaymentResponse{
+			ID:        t.ID,
+			OrderID:   t.OrderID,
+			UserID:    t.UserID,
+			UserName:  t.UserName,
+			Amount:    t.Amount,
+			CreatedAt: t.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		}
+		if t.PaymentOrderID != nil {
+			item.Payment = *t.PaymentOrderID
+		}
+		if t.AmountReceived != nil {
+			item.Received = *t.AmountReceived
+		}
+		resp = append(resp, item)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(resp, pg, totalRows)})
+}
+
+// POST /api/admin/overpayments/{id}/release
+// Credits a held overpayment to the investor's balance once the gateway
+// confirms it was received.
+func ReleaseOverpayment(w http.ResponseWriter, r *http.Request) {
+	reviewHeldOverpayment(w, r, true)
+}
+
+// POST /api/admin/overpayments/{id}/reject
+// Drops a held overpayment; nothing was credited, so nothing is debited.
+func RejectOverpayment(w http.ResponseWriter, r *http.Request) {
+	reviewHeldOverpayment(w, r, false)
+}
+
+func reviewHeldOverpayment(w http.ResponseWriter, r *http.Request, release bool) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID kelebihan pembayaran tidak valid"})
+		return
+	}
+
+	var trx models.Transaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND transaction_type = ?", id, "overpayment").First(&trx).Error; err != nil {
+			return err
+		}
+		if trx.Status != "Held" {
+			return errOverpaymentNotHeld
+		}
+		if !release {
+			return tx.Model(&trx).Update("status", "Failed").Error
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", trx.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", trx.Amount)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&trx).Update("status", "Success").Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kelebihan pembayaran tidak ditemukan"})
+		return
+	case errors.Is(err, errOverpaymentNotHeld):
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Kelebihan pembayaran tidak sedang ditahan"})
+		return
+	case err != nil:
+		utils.LogError(r, "reviewHeldOverpayment", err, "transaction_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses kelebihan pembayaran"})
+		return
+	}
+
+	action, message := "overpayment.reject", "Kelebihan pembayaran ditolak"
+	if release {
+		action, message = "overpayment.release", "Kelebihan pembayaran dikreditkan ke saldo"
+	}
+	auditLog(r, action, map[string]interface{}{"id": trx.ID, "status": "Held"}, map[string]interface{}{"id": trx.ID, "user_id": trx.UserID, "amount": trx.Amount, "status": trx.Status})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message, Data: trx})
+}
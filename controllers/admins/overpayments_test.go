@@ -0,0 +1,63 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+
+	"github.com/gorilla/mux"
+)
+
+// A held overpayment reaches the balance only when an admin releases it, and
+// is reviewed once.
+func TestReviewHeldOverpayment(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	suffix := time.Now().UnixNano() % 1000000000
+	user := testutil.NewUser(t, tx, models.User{Name: "Lebih"})
+	held := func(n int) models.Transaction {
+		trx := models.Transaction{UserID: user.ID, Amount: 30000, OrderID: fmt.Sprintf("RFD-%d-%d", suffix, n), TransactionFlow: "debit", TransactionType: "overpayment", Status: "Held"}
+		if err := tx.Create(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+		return trx
+	}
+	review := func(handle http.HandlerFunc, trx models.Transaction) int {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/overpayments/x", nil)
+		req = mux.SetURLVars(testutil.AsAdmin(req, 1), map[string]string{"id": fmt.Sprint(trx.ID)})
+		rec := httptest.NewRecorder()
+		handle(rec, req)
+		return rec.Code
+	}
+	balance := func() int64 {
+		var u models.User
+		if err := tx.First(&u, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return u.Balance
+	}
+
+	rejected := held(1)
+	if code := review(RejectOverpayment, rejected); code != http.StatusOK || balance() != 0 {
+		t.Fatalf("reject: expected 200 and nothing credited, got %d and balance %d", code, balance())
+	}
+	released := held(2)
+	if code := review(ReleaseOverpayment, released); code != http.StatusOK || balance() != 30000 {
+		t.Fatalf("release: expected 200 and 30000 credited, got %d and balance %d", code, balance())
+	}
+	if code := review(ReleaseOverpayment, released); code != http.StatusConflict || balance() != 30000 {
+		t.Fatalf("second release: expected 409 and no second credit, got %d and balance %d", code, balance())
+	}
+	if code := review(ReleaseOverpayment, rejected); code != http.StatusConflict {
+		t.Fatalf("release after reject: expected 409, got %d", code)
+	}
+}
@@ -0,0 +1,95 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GET /api/admin/payment-channels
+func ListPaymentChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	channels := []models.PaymentChannel{}
+	if err := database.DB.Order("method ASC, code ASC").Find(&channels).Error; err != nil {
+		utils.LogError(r, "ListPaymentChannelsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: channels})
+}
+
+// PUT /api/admin/payment-channels
+// Creates or updates the fee of one method and channel. QRIS always uses the
+// code QRIS. disabled switches the channel off for purchases; omitted keeps
+// it as it was.
+func UpdatePaymentChannelHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method     string  `json:"method"`
+		Code       string  `json:"code"`
+		FeeFlat    int64   `json:"fee_flat"`
+		FeePercent float64 `json:"fee_percent"`
+		PassFee    bool    `json:"pass_fee"`
+		Disabled   *bool   `json:"disabled"`
+	}
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	if req.Method == "QRIS" {
+		req.Code = "QRIS"
+	}
+	switch {
+	case req.Method != "QRIS" && req.Method != "BANK":
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Metode harus QRIS atau BANK"})
+		return
+	case req.Code == "" || len(req.Code) > 16:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode channel tidak valid"})
+		return
+	case req.FeeFlat < 0:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Biaya flat tidak boleh negatif"})
+		return
+	case req.FeePercent < 0 || req.FeePercent > 100:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Persentase biaya harus antara 0 dan 100"})
+		return
+	}
+
+	var before *models.PaymentChannel
+	var after models.PaymentChannel
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.PaymentChannel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("method = ? AND code = ?", req.Method, req.Code).First(&existing).Error
+		switch {
+		case err == nil:
+			snapshot := existing
+			before = &snapshot
+			after = existing
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			after = models.PaymentChannel{Method: req.Method, Code: req.Code}
+		default:
+			return err
+		}
+		after.FeeFlat = req.FeeFlat
+		after.FeePercent = req.FeePercent
+		after.PassFee = req.PassFee
+		if req.Disabled != nil {
+			after.Disabled = *req.Disabled
+		}
+		return tx.Save(&after).Error
+	})
+	if err != nil {
+		utils.LogError(r, "UpdatePaymentChannelHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	auditLog(r, "payment_channels.update", before, after)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Biaya channel berhasil disimpan", Data: after})
+}
@@ -0,0 +1,275 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GET /api/admin/payment-settings/wishlist
+func ListWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	db := database.DB
+	var ps models.PaymentSettings
+	if err := db.First(&ps).Error; err != nil {
+		writePaymentSettingsError(w, err)
+		return
+	}
+
+	ids := ps.WishlistIDs()
+	users := []models.User{}
+	if len(ids) > 0 {
+		if err := db.Select("id, name, number").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			utils.LogError(r, "ListWishlistHandler", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+			return
+		}
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	resp := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		u, ok := byID[id]
+		resp = append(resp, map[string]interface{}{
+			"user_id": id,
+			"name":    u.Name,
+			"number":  u.Number,
+			"exists":  ok,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    resp,
+	})
+}
+
+// POST /api/admin/payment-settings/wishlist
+func AddWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID uint `json:"user_id"`
+	}
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	if req.UserID == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "User ID tidak valid"})
+		return
+	}
+
+	db := database.DB
+	var user models.User
+	if err := db.Select("id").First(&user, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User tidak ditemukan", Code: utils.CodeUserNotFound})
+			return
+		}
+		utils.LogError(r, "AddWishlistHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var before, after []uint
+	err := updateWishlist(db, func(ids []uint) []uint {
+		before = ids
+		for _, id := range ids {
+			if id == req.UserID {
+				return ids
+			}
+		}
+		return append(append([]uint{}, ids...), req.UserID)
+	}, &after)
+	if err != nil {
+		writePaymentSettingsError(w, err)
+		return
+	}
+
+	auditLog(r, "payment_settings.wishlist.add", before, after)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "User berhasil ditambahkan ke wishlist",
+		Data:    map[string]interface{}{"wishlist": after},
+	})
+}
+
+// DELETE /api/admin/payment-settings/wishlist/{id}
+func RemoveWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "User ID tidak valid"})
+		return
+	}
+
+	var before, after []uint
+	err := updateWishlist(database.DB, func(ids []uint) []uint {
+		before = ids
+		kept := make([]uint, 0, len(ids))
+		for _, id := range ids {
+			if id != userID {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	}, &after)
+	if err != nil {
+		writePaymentSettingsError(w, err)
+		return
+	}
+	if len(before) == len(after) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User tidak ada di wishlist"})
+		return
+	}
+
+	auditLog(r, "payment_settings.wishlist.remove", before, after)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "User berhasil dihapus dari wishlist",
+		Data:    map[string]interface{}{"wishlist": after},
+	})
+}
+
+// GET /api/admin/payment-settings/masking
+func GetMaskingConfigHandler(w http.ResponseWriter, r *http.Request) {
+	var ps models.PaymentSettings
+	if err := database.DB.First(&ps).Error; err != nil {
+		writePaymentSettingsError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    maskingConfigResponse(&ps),
+	})
+}
+
+// PUT /api/admin/payment-settings/masking
+func UpdateMaskingConfigHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WithdrawAmount *float64 `json:"withdraw_amount"`
+		BankName       *string  `json:"bank_name"`
+		BankCode       *string  `json:"bank_code"`
+		AccountNumber  *string  `json:"account_number"`
+		AccountName    *string  `json:"account_name"`
+	}
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	var before, after map[string]interface{}
+	var msg string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var ps models.PaymentSettings
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ps).Error; err != nil {
+			return err
+		}
+		before = maskingConfigResponse(&ps)
+
+		if req.WithdrawAmount != nil {
+			ps.WithdrawAmount = *req.WithdrawAmount
+		}
+		if req.BankName != nil {
+			ps.BankName = strings.TrimSpace(*req.BankName)
+		}
+		if req.BankCode != nil {
+			ps.BankCode = strings.ToUpper(strings.TrimSpace(*req.BankCode))
+		}
+		if req.AccountNumber != nil {
+			ps.AccountNumber = strings.TrimSpace(*req.AccountNumber)
+		}
+		if req.AccountName != nil {
+			ps.AccountName = strings.TrimSpace(*req.AccountName)
+		}
+
+		if msg = validateMaskingConfig(&ps); msg != "" {
+			return nil
+		}
+		after = maskingConfigResponse(&ps)
+		return tx.Model(&ps).Updates(map[string]interface{}{
+			"withdraw_amount": ps.WithdrawAmount,
+			"bank_name":       ps.BankName,
+			"bank_code":       ps.BankCode,
+			"account_number":  ps.AccountNumber,
+			"account_name":    ps.AccountName,
+		}).Error
+	})
+	if err != nil {
+		writePaymentSettingsError(w, err)
+		return
+	}
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	auditLog(r, "payment_settings.masking.update", before, after)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Pengaturan berhasil diperbarui",
+		Data:    after,
+	})
+}
+
+// updateWishlist applies fn to the wishlist under a row lock so concurrent
+// admin edits don't overwrite each other.
+func updateWishlist(db *gorm.DB, fn func(ids []uint) []uint, result *[]uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var ps models.PaymentSettings
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ps).Error; err != nil {
+			return err
+		}
+		ids := fn(ps.WishlistIDs())
+		ps.SetWishlistIDs(ids)
+		*result = ids
+		return tx.Model(&ps).Update("wishlist_id", ps.WishlistID).Error
+	})
+}
+
+func validateMaskingConfig(ps *models.PaymentSettings) string {
+	if ps.WithdrawAmount < 0 {
+		return "Withdraw amount tidak boleh negatif"
+	}
+	if ps.BankName == "" || ps.BankCode == "" || ps.AccountName == "" {
+		return "Nama bank, kode bank, dan nama rekening wajib diisi"
+	}
+	if ps.AccountNumber == "" {
+		return "Nomor rekening wajib diisi"
+	}
+	for _, c := range ps.AccountNumber {
+		if c < '0' || c > '9' {
+			return "Nomor rekening hanya boleh berisi angka"
+		}
+	}
+	return ""
+}
+
+func maskingConfigResponse(ps *models.PaymentSettings) map[string]interface{} {
+	return map[string]interface{}{
+		"withdraw_amount": ps.WithdrawAmount,
+		"bank_name":       ps.BankName,
+		"bank_code":       ps.BankCode,
+		"account_number":  ps.AccountNumber,
+		"account_name":    ps.AccountName,
+	}
+}
+
+func writePaymentSettingsError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "payment_settings not found"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+}
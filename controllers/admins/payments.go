@@ -2,12 +2,12 @@ package admins
 
 import (
 	"net/http"
-	"strconv"
 	"time"
 
-	"project/database"
 	"project/models"
 	"project/utils"
+
+	"gorm.io/gorm"
 )
 
 type PaymentResponse struct {
@@ -21,29 +21,25 @@ type PaymentResponse struct {
 	Status         string `json:"status"`
 	ExpiredAt      string `json:"expired_at"`
 	CreatedAt      string `json:"created_at"`
+
+	GatewayPaymentID *string `json:"gateway_payment_id"`
 }
 
-func GetPayments(w http.ResponseWriter, r *http.Request) {
+func (h *ReportHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
 	// Get query parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
 	investmentId := r.URL.Query().Get("investmentId")
 	userId := r.URL.Query().Get("userId")
 	status := r.URL.Query().Get("status")
 	startDate := r.URL.Query().Get("startDate")
 	endDate := r.URL.Query().Get("endDate")
 
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
-	}
-
-	offset := (page - 1) * limit
-
 	// Start query
-	db := database.DB
+	db := h.db()
 	query := db.Model(&models.Payment{})
 
 	// Apply filters
@@ -60,25 +56,32 @@ func GetPayments(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Apply date filters if provided
-	jakartaLoc, _ := time.LoadLocation("Asia/Jakarta")
+	appLoc := utils.AppLocation()
 	if startDate != "" {
-		startTime, err := time.ParseInLocation("2006-01-02", startDate, jakartaLoc)
+		startTime, err := time.ParseInLocation("2006-01-02", startDate, appLoc)
 		if err == nil {
 			query = query.Where("payments.created_at >= ?", startTime)
 		}
 	}
 	if endDate != "" {
-		endTime, err := time.ParseInLocation("2006-01-02", endDate, jakartaLoc)
+		endTime, err := time.ParseInLocation("2006-01-02", endDate, appLoc)
 		if err == nil {
-			// Add one day to get to the start of the next day in Jakarta time
+			// Add one day to get to the start of the next day in the app timezone
 			endTime = endTime.AddDate(0, 0, 1)
 			query = query.Where("payments.created_at < ?", endTime)
 		}
 	}
 
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "GetPayments", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
 	var payments []models.Payment
-	query.Offset(offset).
-		Limit(limit).
+	query.Offset(pg.Offset).
+		Limit(pg.Limit).
 		Order("payments.created_at DESC").
 		Find(&payments)
 
@@ -94,14 +97,16 @@ func GetPayments(w http.ResponseWriter, r *http.Request) {
 			PaymentChannel: utils.GetStringValue(p.PaymentChannel),
 			PaymentCode:    utils.GetStringValue(p.PaymentCode),
 			Status:         p.Status,
-			ExpiredAt:      p.ExpiredAt.Format(time.RFC3339),
-			CreatedAt:      p.CreatedAt.Format(time.RFC3339),
+			ExpiredAt:      utils.GetStringValue(utils.FormatTimePtr(p.ExpiredAt)),
+			CreatedAt:      utils.FormatTime(p.CreatedAt),
+
+			GatewayPaymentID: p.GatewayPaymentID,
 		})
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data:    utils.NewPaginated(response, pg, totalRows),
 	})
 }
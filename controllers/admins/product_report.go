@@ -0,0 +1,292 @@
+package admins
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// ProductPerformance is one product's row in the product report. The sales
+// figures (units sold, gross volume, completion, buyers) cover investments
+// confirmed in the range; active principal and profit liability are as of now.
+type ProductPerformance struct {
+	ProductID    uint   `json:"product_id,omitempty"`
+	ProductName  string `json:"product_name,omitempty"`
+	CategoryID   uint   `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	ProfitType   string `json:"profit_type"`
+	productMetrics
+}
+
+// productMetrics are the figures shared by product rows and category rollups.
+type productMetrics struct {
+	UnitsSold       int64   `json:"units_sold"`
+	GrossVolume     int64   `json:"gross_volume"`
+	ActivePrincipal int64   `json:"active_principal"`
+	ProfitLiability int64   `json:"profit_liability"`
+	Completed       int64   `json:"completed"`
+	CompletionRate  float64 `json:"completion_rate"`
+	Buyers          int64   `json:"buyers"`
+	AvgBuyerLevel   float64 `json:"avg_buyer_level"`
+	// levelSum is the buyers' summed VIP level, kept to average rollups
+	levelSum int64
+}
+
+func (m *productMetrics) add(o productMetrics) {
+	m.UnitsSold += o.UnitsSold
+	m.GrossVolume += o.GrossVolume
+	m.ActivePrincipal += o.ActivePrincipal
+	m.ProfitLiability += o.ProfitLiability
+	m.Completed += o.Completed
+	m.Buyers += o.Buyers
+	m.levelSum += o.levelSum
+}
+
+func (m *productMetrics) finish() {
+	m.CompletionRate, m.AvgBuyerLevel = 0, 0
+	if m.UnitsSold > 0 {
+		m.CompletionRate = float64(m.Completed) / float64(m.UnitsSold)
+	}
+	if m.Buyers > 0 {
+		m.AvgBuyerLevel = float64(m.levelSum) / float64(m.Buyers)
+	}
+}
+
+// productReportSorts maps the sort parameter to the metric it orders by.
+var productReportSorts = map[string]func(m productMetrics) float64{
+	"units_sold":       func(m productMetrics) float64 { return float64(m.UnitsSold) },
+	"gross_volume":     func(m productMetrics) float64 { return float64(m.GrossVolume) },
+	"active_principal": func(m productMetrics) float64 { return float64(m.ActivePrincipal) },
+	"profit_liability": func(m productMetrics) float64 { return float64(m.ProfitLiability) },
+	"completion_rate":  func(m productMetrics) float64 { return m.CompletionRate },
+	"avg_buyer_level":  func(m productMetrics) float64 { return m.AvgBuyerLevel },
+}
+
+type productReport struct {
+	From       string               `json:"from"`
+	To         string               `json:"to"`
+	Products   []ProductPerformance `json:"products"`
+	Categories []ProductPerformance `json:"categories"`
+	Total      productMetrics       `json:"total"`
+}
+
+// GET /api/admin/reports/products?from=&to=&category_id=&sort=&order=
+// Per-product sales and obligations with category rollups. sort is any
+// metric (default units_sold), order asc or desc (default).
+func (h *ReportHandler) GetProductReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, msg := buildProductReport(h.db(), r)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	if report == nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: report})
+}
+
+// GET /api/admin/reports/products/export
+// The product report as CSV: product rows in the requested order, then the
+// category rollups.
+func (h *ReportHandler) ExportProductReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, msg := buildProductReport(h.db(), r)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	if report == nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	amount := func(v int64) string { return strconv.FormatInt(v, 10) }
+	ratio := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=product-report-%s-%s.csv", report.From, report.To))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"row", "product_id", "product_name", "category_id", "category_name", "profit_type", "units_sold", "gross_volume", "active_principal", "profit_liability", "completed", "completion_rate", "buyers", "avg_buyer_level"})
+	write := func(kind string, p ProductPerformance) {
+		productID := ""
+		if p.ProductID != 0 {
+			productID = strconv.FormatUint(uint64(p.ProductID), 10)
+		}
+		_ = cw.Write([]string{
+			kind, productID, p.ProductName,
+			strconv.FormatUint(uint64(p.CategoryID), 10), p.CategoryName, p.ProfitType,
+			amount(p.UnitsSold), amount(p.GrossVolume), amount(p.ActivePrincipal), amount(p.ProfitLiability),
+			amount(p.Completed), ratio(p.CompletionRate), amount(p.Buyers), ratio(p.AvgBuyerLevel),
+		})
+	}
+	for _, p := range report.Products {
+		write("product", p)
+	}
+	for _, c := range report.Categories {
+		write("category", c)
+	}
+	cw.Flush()
+}
+
+// buildProductReport computes the report for the request's filters. It
+// returns a user-facing message for bad input and a nil report on a database
+// error.
+func buildProductReport(db *gorm.DB, r *http.Request) (*productReport, string) {
+	from, to, msg := parseReportRange(r)
+	if msg != "" {
+		return nil, msg
+	}
+	q := r.URL.Query()
+	sortBy := q.Get("sort")
+	if sortBy == "" {
+		sortBy = "units_sold"
+	}
+	metric, ok := productReportSorts[sortBy]
+	if !ok {
+		return nil, "sort tidak dikenal"
+	}
+	desc := q.Get("order") != "asc"
+	var categoryID uint64
+	if v := q.Get("category_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, "category_id tidak valid"
+		}
+		categoryID = id
+	}
+
+	products := []models.Product{}
+	query := db.Preload("Category").Order("category_id ASC, id ASC")
+	if categoryID != 0 {
+		query = query.Where("category_id = ?", categoryID)
+	}
+	if err := query.Find(&products).Error; err != nil {
+		utils.LogError(r, "product report: load products", err)
+		return nil, ""
+	}
+
+	// Sales: investments whose purchase transaction settled in the range
+	type salesRow struct {
+		ProductID   uint
+		UnitsSold   int64
+		GrossVolume int64
+		Completed   int64
+	}
+	var sales []salesRow
+	if err := db.Table("transactions AS t").
+		Joins("JOIN investments i ON i.order_id = t.order_id").
+		Select("i.product_id, COUNT(*) AS units_sold, COALESCE(SUM(t.amount), 0) AS gross_volume, COALESCE(SUM(i.status = 'Completed'), 0) AS completed").
+		Where("t.transaction_type = ? AND t.status = ? AND t.updated_at >= ? AND t.updated_at < ?", "investment", "Success", from, to.AddDate(0, 0, 1)).
+		Group("i.product_id").
+		Scan(&sales).Error; err != nil {
+		utils.LogError(r, "product report: sales", err)
+		return nil, ""
+	}
+	type buyerRow struct {
+		ProductID uint
+		Buyers    int64
+		LevelSum  int64
+	}
+	var buyers []buyerRow
+	if err := db.Raw(`SELECT b.product_id, COUNT(*) AS buyers, COALESCE(SUM(b.level), 0) AS level_sum
+		FROM (SELECT DISTINCT i.product_id, i.user_id, COALESCE(u.level, 0) AS level
+			FROM transactions t
+			JOIN investments i ON i.order_id = t.order_id
+			JOIN users u ON u.id = i.user_id
+			WHERE t.transaction_type = ? AND t.status = ? AND t.updated_at >= ? AND t.updated_at < ?) b
+		GROUP BY b.product_id`, "investment", "Success", from, to.AddDate(0, 0, 1)).
+		Scan(&buyers).Error; err != nil {
+		utils.LogError(r, "product report: buyers", err)
+		return nil, ""
+	}
+
+	// Obligations now, from Running and Suspended investments. Unlocked
+	// categories pay profit daily, so only the unpaid days remain; locked
+	// categories pay all profit at completion, so what accrued so far plus the
+	// remaining days remain (the daily profit changes on a top-up).
+	type obligationRow struct {
+		ProductID       uint
+		ActivePrincipal int64
+		ProfitLiability int64
+	}
+	var obligations []obligationRow
+	if err := db.Table("investments AS i").
+		Joins("JOIN categories c ON c.id = i.category_id").
+		Select("i.product_id, COALESCE(SUM(i.amount), 0) AS active_principal, "+
+			"COALESCE(SUM(CASE WHEN c.profit_type = ? THEN i.total_returned + i.daily_profit * GREATEST(i.duration - i.total_paid, 0) "+
+			"ELSE i.daily_profit * GREATEST(i.duration - i.total_paid, 0) END), 0) AS profit_liability", models.ProfitTypeLocked).
+		Where("i.status IN ? AND i.deleted_at IS NULL", []string{"Running", "Suspended"}).
+		Group("i.product_id").
+		Scan(&obligations).Error; err != nil {
+		utils.LogError(r, "product report: obligations", err)
+		return nil, ""
+	}
+
+	metrics := make(map[uint]*productMetrics, len(products))
+	get := func(id uint) *productMetrics {
+		if metrics[id] == nil {
+			metrics[id] = &productMetrics{}
+		}
+		return metrics[id]
+	}
+	for _, s := range sales {
+		m := get(s.ProductID)
+		m.UnitsSold, m.GrossVolume, m.Completed = s.UnitsSold, s.GrossVolume, s.Completed
+	}
+	for _, b := range buyers {
+		m := get(b.ProductID)
+		m.Buyers, m.levelSum = b.Buyers, b.LevelSum
+	}
+	for _, o := range obligations {
+		m := get(o.ProductID)
+		m.ActivePrincipal, m.ProfitLiability = o.ActivePrincipal, o.ProfitLiability
+	}
+
+	report := &productReport{
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		Products:   make([]ProductPerformance, 0, len(products)),
+		Categories: []ProductPerformance{},
+	}
+	categories := map[uint]int{}
+	for _, p := range products {
+		row := ProductPerformance{ProductID: p.ID, ProductName: p.Name, CategoryID: p.CategoryID}
+		if p.Category != nil {
+			row.CategoryName, row.ProfitType = p.Category.Name, p.Category.ProfitType
+		}
+		if m := metrics[p.ID]; m != nil {
+			row.productMetrics = *m
+		}
+		row.finish()
+		report.Products = append(report.Products, row)
+
+		i, ok := categories[p.CategoryID]
+		if !ok {
+			i = len(report.Categories)
+			categories[p.CategoryID] = i
+			report.Categories = append(report.Categories, ProductPerformance{CategoryID: p.CategoryID, CategoryName: row.CategoryName, ProfitType: row.ProfitType})
+		}
+		report.Categories[i].add(row.productMetrics)
+		report.Total.add(row.productMetrics)
+	}
+	for i := range report.Categories {
+		report.Categories[i].finish()
+	}
+	report.Total.finish()
+
+	less := func(a, b ProductPerformance) bool {
+		if desc {
+			return metric(a.productMetrics) > metric(b.productMetrics)
+		}
+		return metric(a.productMetrics) < metric(b.productMetrics)
+	}
+	sort.SliceStable(report.Products, func(i, j int) bool { return less(report.Products[i], report.Products[j]) })
+	sort.SliceStable(report.Categories, func(i, j int) bool { return less(report.Categories[i], report.Categories[j]) })
+	return report, ""
+}
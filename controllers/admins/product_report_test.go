@@ -0,0 +1,105 @@
+package admins
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/controllers/users"
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestAdminProductReport(t *testing.T) {
+	tx := testutil.Tx(t)
+	reports := NewReportHandler(database.NewReadReplica(tx, nil))
+
+	level := uint(3)
+	user := testutil.NewUser(t, tx, models.User{Name: "Laporan", Level: &level})
+	newProduct := func(profitType string, dailyProfit int64, duration int) models.Product {
+		category := testutil.NewCategory(t, tx, profitType)
+		product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Report " + profitType, Amount: 1000000, DailyProfit: dailyProfit, Duration: duration})
+		return product
+	}
+	unlocked := newProduct(models.ProfitTypeUnlocked, 50000, 2)
+	locked := newProduct(models.ProfitTypeLocked, 30000, 3)
+
+	h := users.NewInvestmentHandler(tx, &testutil.Kyta{})
+	for _, p := range []models.Product{unlocked, unlocked, locked} {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/investments", strings.NewReader(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true}`, user.ID, p.ID)))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		rec := httptest.NewRecorder()
+		h.AdminCreate(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	// One unlocked investment has been paid a day, the other has completed
+	var invs []models.Investment
+	if err := tx.Where("product_id = ?", unlocked.ID).Order("id ASC").Find(&invs).Error; err != nil {
+		t.Fatal(err)
+	}
+	tx.Model(&invs[0]).Update("total_paid", 1)
+	tx.Model(&invs[1]).Updates(map[string]interface{}{"total_paid": 2, "status": "Completed"})
+
+	rec := httptest.NewRecorder()
+	reports.GetProductReportHandler(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/reports/products?sort=profit_liability", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("report: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Products   []ProductPerformance `json:"products"`
+			Categories []ProductPerformance `json:"categories"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	rows := map[uint]ProductPerformance{}
+	for _, p := range resp.Data.Products {
+		rows[p.ProductID] = p
+	}
+	u, l := rows[unlocked.ID], rows[locked.ID]
+	if u.UnitsSold != 2 || u.Completed != 1 || u.CompletionRate != 0.5 || u.Buyers != 1 || u.AvgBuyerLevel != 3 {
+		t.Fatalf("unexpected unlocked sales figures: %+v", u)
+	}
+	// Unlocked: one unpaid day left; locked: the whole profit is still owed
+	if u.ActivePrincipal != 1000000 || u.ProfitLiability != 50000 {
+		t.Fatalf("expected unlocked principal 1000000 and liability 50000, got %+v", u)
+	}
+	if l.UnitsSold != 1 || l.ActivePrincipal != 1000000 || l.ProfitLiability != 90000 {
+		t.Fatalf("expected locked principal 1000000 and liability 90000, got %+v", l)
+	}
+	for i := 1; i < len(resp.Data.Products); i++ {
+		if resp.Data.Products[i].ProfitLiability > resp.Data.Products[i-1].ProfitLiability {
+			t.Fatalf("expected products sorted by profit_liability desc, got %+v", resp.Data.Products)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	reports.GetProductReportHandler(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/reports/products?sort=name", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad sort: expected 400, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	reports.ExportProductReportHandler(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/admin/reports/products/export?category_id=%d", locked.CategoryID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	records, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1][0] != "product" || records[2][0] != "category" || records[2][9] != "90000" {
+		t.Fatalf("expected a header, the locked product and its category, got %v", records)
+	}
+}
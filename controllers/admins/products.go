@@ -1,27 +1,74 @@
 package admins
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
+	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"project/database"
 	"project/models"
 	"project/utils"
 
+	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
+const (
+	// productImageMaxBytes bounds uploaded product images.
+	productImageMaxBytes = 2 << 20
+
+	// Limits of the product texts shown in the app, in characters
+	productDescriptionMaxChars = 2000
+	productMaxHighlights       = 6
+	productHighlightMaxChars   = 120
+	productBadgeMaxChars       = 32
+)
+
+// productImagePrefix is the folder of uploaded product images in the bucket,
+// PRODUCT_IMAGE_PREFIX (default "products").
+func productImagePrefix() string {
+	if p := strings.Trim(strings.TrimSpace(os.Getenv("PRODUCT_IMAGE_PREFIX")), "/"); p != "" {
+		return p
+	}
+	return "products"
+}
+
+// withProductImageURL resolves p.ImageURL for the response. A URL that
+// cannot be made is logged and left empty.
+func withProductImageURL(r *http.Request, p *models.Product) {
+	url, err := utils.StoredImageURL(p.Image, utils.ImageURLExpiry)
+	if err != nil {
+		utils.LogError(r, "product image url", err, "product_id", p.ID)
+	}
+	p.ImageURL = url
+}
+
 // GET /api/admin/products
 func ListProductsHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
+	query := db.Preload("Category")
+	if status := r.URL.Query().Get("status"); status == "Active" || status == "Inactive" {
+		query = query.Where("status = ?", status)
+	}
+	if categoryID, err := strconv.ParseUint(r.URL.Query().Get("category_id"), 10, 64); err == nil && categoryID > 0 {
+		query = query.Where("category_id = ?", uint(categoryID))
+	}
+
 	var products []models.Product
-	if err := db.Preload("Category").Order("category_id ASC, id ASC").Find(&products).Error; err != nil {
+	if err := query.Order("category_id ASC, id ASC").Find(&products).Error; err != nil {
+		utils.LogError(r, "ListProductsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data produk"})
 		return
 	}
+	for i := range products {
+		withProductImageURL(r, &products[i])
+	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
@@ -34,27 +81,24 @@ func ListProductsHandler(w http.ResponseWriter, r *http.Request) {
 
 // GET /api/admin/products/{id}
 func GetProductHandler(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var idStr string
-	if len(parts) >= 4 {
-		idStr = parts[3]
-	}
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil || id64 == 0 {
+	id, ok := idFromRequest(r)
+	if !ok {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
 		return
 	}
 
 	db := database.DB
 	var product models.Product
-	if err := db.Preload("Category").First(&product, uint(id64)).Error; err != nil {
+	if err := db.Preload("Category").First(&product, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan", Code: utils.CodeProductNotFound})
 			return
 		}
+		utils.LogError(r, "GetProductHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
+	withProductImageURL(r, &product)
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
@@ -63,84 +107,106 @@ func GetProductHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// POST /api/admin/products
-func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		CategoryID    uint    `json:"category_id"`
-		Name          string  `json:"name"`
-		Amount        float64 `json:"amount"`
-		DailyProfit   float64 `json:"daily_profit"`
-		Duration      int     `json:"duration"`
-		RequiredVIP   int     `json:"required_vip"`
-		PurchaseLimit int     `json:"purchase_limit"`
-		Status        string  `json:"status"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
-		return
-	}
+type CreateProductRequest struct {
+	CategoryID    uint   `json:"category_id" validate:"required"`
+	Name          string `json:"name" validate:"required,max=100"`
+	Amount        int64  `json:"amount" validate:"gt=0"`
+	DailyProfit   int64  `json:"daily_profit" validate:"gte=0"`
+	Duration      int    `json:"duration" validate:"gte=1"`
+	RequiredVIP   int    `json:"required_vip" validate:"gte=0,lte=5"`
+	PurchaseLimit int    `json:"purchase_limit" validate:"gte=0"`
+	TopupMin      int64  `json:"topup_min" validate:"gte=0"`
+	TopupMax      int64  `json:"topup_max" validate:"gte=0"`
+	Status        string `json:"status" validate:"omitempty,oneof=Active Inactive"`
+	// PurchaseCooldownHours spaces one user's purchases of the product; 0 = none
+	PurchaseCooldownHours int `json:"purchase_cooldown_hours" validate:"gte=0,lte=720"`
+	// Image is an absolute URL, or left empty and uploaded afterwards with
+	// POST /api/admin/products/{id}/image
+	Image       string   `json:"image" validate:"max=255"`
+	Description *string  `json:"description"`
+	Highlights  []string `json:"highlights"`
+	Badge       string   `json:"badge"`
+}
 
-	if strings.TrimSpace(req.Name) == "" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Nama produk wajib diisi"})
-		return
-	}
+func (req *CreateProductRequest) Normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Image = strings.TrimSpace(req.Image)
+	req.Badge = strings.TrimSpace(req.Badge)
+}
 
-	if req.CategoryID == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kategori wajib dipilih"})
-		return
-	}
+// UpdateProductRequest only changes the fields that are present.
+type UpdateProductRequest struct {
+	CategoryID    *uint   `json:"category_id" validate:"omitempty,gt=0"`
+	Name          *string `json:"name" validate:"omitempty,max=100"`
+	Amount        *int64  `json:"amount" validate:"omitempty,gt=0"`
+	DailyProfit   *int64  `json:"daily_profit" validate:"omitempty,gte=0"`
+	Duration      *int    `json:"duration" validate:"omitempty,gte=1"`
+	RequiredVIP   *int    `json:"required_vip" validate:"omitempty,gte=0,lte=5"`
+	PurchaseLimit *int    `json:"purchase_limit" validate:"omitempty,gte=0"`
+	TopupMin      *int64  `json:"topup_min" validate:"omitempty,gte=0"`
+	TopupMax      *int64  `json:"topup_max" validate:"omitempty,gte=0"`
+	Status        string  `json:"status" validate:"omitempty,oneof=Active Inactive"`
+	Image         *string `json:"image" validate:"omitempty,max=255"`
+	// PurchaseCooldownHours spaces one user's purchases of the product; 0 = none
+	PurchaseCooldownHours *int `json:"purchase_cooldown_hours" validate:"omitempty,gte=0,lte=720"`
+	// An empty description clears it, as an empty list clears the highlights
+	Description *string   `json:"description"`
+	Highlights  *[]string `json:"highlights"`
+	Badge       *string   `json:"badge"`
+}
 
-	if req.Amount <= 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Amount harus lebih dari 0"})
+// POST /api/admin/products
+func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateProductRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
-	if req.DailyProfit <= 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Daily profit harus lebih dari 0"})
-		return
+	if req.Status == "" {
+		req.Status = "Active"
 	}
 
-	if req.Duration <= 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Duration harus lebih dari 0"})
+	product := models.Product{
+		CategoryID:            req.CategoryID,
+		Name:                  req.Name,
+		Amount:                req.Amount,
+		DailyProfit:           req.DailyProfit,
+		Duration:              req.Duration,
+		RequiredVIP:           req.RequiredVIP,
+		PurchaseLimit:         req.PurchaseLimit,
+		TopupMin:              req.TopupMin,
+		TopupMax:              req.TopupMax,
+		Status:                req.Status,
+		Image:                 req.Image,
+		Description:           productDescription(req.Description),
+		Highlights:            productHighlights(req.Highlights),
+		Badge:                 req.Badge,
+		PurchaseCooldownHours: req.PurchaseCooldownHours,
+	}
+
+	if msg := validateProduct(&product); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
 		return
 	}
 
-	if req.Status != "Active" && req.Status != "Inactive" {
-		req.Status = "Active"
-	}
-
 	db := database.DB
-
-	// Check if category exists
-	var category models.Category
-	if err := db.First(&category, req.CategoryID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan"})
-			return
-		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+	if status, msg := checkProductCategory(db, product.CategoryID); status != 0 {
+		utils.WriteJSON(w, status, utils.APIResponse{Success: false, Message: msg})
 		return
 	}
 
-	product := models.Product{
-		CategoryID:    req.CategoryID,
-		Name:          req.Name,
-		Amount:        req.Amount,
-		DailyProfit:   req.DailyProfit,
-		Duration:      req.Duration,
-		RequiredVIP:   req.RequiredVIP,
-		PurchaseLimit: req.PurchaseLimit,
-		Status:        req.Status,
-	}
-
 	if err := db.Create(&product).Error; err != nil {
+		utils.LogError(r, "CreateProductHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat produk"})
 		return
 	}
 
+	models.InvalidateProductCatalog()
+
 	// Reload with category
 	db.Preload("Category").First(&product, product.ID)
+	auditLogTarget(r, "product.create", "product", product.ID, nil, product)
+	withProductImageURL(r, &product)
 
 	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
 		Success: true,
@@ -150,90 +216,123 @@ func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // PUT /api/admin/products/{id}
+// Amount, daily profit and duration are snapshotted onto each investment at
+// purchase time, so editing them only affects new purchases; top-ups keep the
+// rate each investment was bought at. topup_max 0 turns top-ups off.
 func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var idStr string
-	if len(parts) >= 4 {
-		idStr = parts[3]
-	}
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil || id64 == 0 {
+	id, ok := idFromRequest(r)
+	if !ok {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
 		return
 	}
 
-	var req struct {
-		CategoryID    *uint    `json:"category_id"`
-		Name          string   `json:"name"`
-		Amount        *float64 `json:"amount"`
-		DailyProfit   *float64 `json:"daily_profit"`
-		Duration      *int     `json:"duration"`
-		RequiredVIP   *int     `json:"required_vip"`
-		PurchaseLimit *int     `json:"purchase_limit"`
-		Status        string   `json:"status"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
+	var req UpdateProductRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
 	db := database.DB
 	var product models.Product
-	if err := db.First(&product, uint(id64)).Error; err != nil {
+	if err := db.First(&product, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan", Code: utils.CodeProductNotFound})
 			return
 		}
+		utils.LogError(r, "UpdateProductHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
 
-	updates := map[string]interface{}{}
-	
-	if req.CategoryID != nil && *req.CategoryID > 0 {
-		// Check if category exists
-		var category models.Category
-		if err := db.First(&category, *req.CategoryID).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kategori tidak ditemukan"})
-				return
-			}
-		}
-		updates["category_id"] = *req.CategoryID
+	// Apply the changes to a copy first so the merged result can be validated as a whole
+	updated := product
+	if req.CategoryID != nil {
+		updated.CategoryID = *req.CategoryID
 	}
-	
-	if req.Name != "" {
-		updates["name"] = req.Name
+	if req.Name != nil {
+		updated.Name = strings.TrimSpace(*req.Name)
 	}
-	if req.Amount != nil && *req.Amount > 0 {
-		updates["amount"] = *req.Amount
+	if req.Amount != nil {
+		updated.Amount = *req.Amount
 	}
-	if req.DailyProfit != nil && *req.DailyProfit > 0 {
-		updates["daily_profit"] = *req.DailyProfit
+	if req.DailyProfit != nil {
+		updated.DailyProfit = *req.DailyProfit
 	}
-	if req.Duration != nil && *req.Duration > 0 {
-		updates["duration"] = *req.Duration
+	if req.Duration != nil {
+		updated.Duration = *req.Duration
 	}
 	if req.RequiredVIP != nil {
-		updates["required_vip"] = *req.RequiredVIP
+		updated.RequiredVIP = *req.RequiredVIP
 	}
 	if req.PurchaseLimit != nil {
-		updates["purchase_limit"] = *req.PurchaseLimit
+		updated.PurchaseLimit = *req.PurchaseLimit
+	}
+	if req.TopupMin != nil {
+		updated.TopupMin = *req.TopupMin
+	}
+	if req.TopupMax != nil {
+		updated.TopupMax = *req.TopupMax
 	}
-	if req.Status == "Active" || req.Status == "Inactive" {
-		updates["status"] = req.Status
+	if req.PurchaseCooldownHours != nil {
+		updated.PurchaseCooldownHours = *req.PurchaseCooldownHours
+	}
+	if req.Status != "" {
+		updated.Status = req.Status
+	}
+	if req.Image != nil {
+		updated.Image = strings.TrimSpace(*req.Image)
+	}
+	if req.Description != nil {
+		updated.Description = productDescription(req.Description)
+	}
+	if req.Highlights != nil {
+		updated.Highlights = productHighlights(*req.Highlights)
+	}
+	if req.Badge != nil {
+		updated.Badge = strings.TrimSpace(*req.Badge)
 	}
 
-	if len(updates) > 0 {
-		if err := db.Model(&product).Updates(updates).Error; err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate produk"})
+	if msg := validateProduct(&updated); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if updated.CategoryID != product.CategoryID {
+		if status, msg := checkProductCategory(db, updated.CategoryID); status != 0 {
+			utils.WriteJSON(w, status, utils.APIResponse{Success: false, Message: msg})
 			return
 		}
 	}
 
+	updates := map[string]interface{}{
+		"category_id":             updated.CategoryID,
+		"name":                    updated.Name,
+		"amount":                  updated.Amount,
+		"daily_profit":            updated.DailyProfit,
+		"duration":                updated.Duration,
+		"required_vip":            updated.RequiredVIP,
+		"purchase_limit":          updated.PurchaseLimit,
+		"topup_min":               updated.TopupMin,
+		"topup_max":               updated.TopupMax,
+		"status":                  updated.Status,
+		"image":                   updated.Image,
+		"description":             updated.Description,
+		"highlights":              updated.Highlights,
+		"badge":                   updated.Badge,
+		"purchase_cooldown_hours": updated.PurchaseCooldownHours,
+	}
+	before := product
+	if err := db.Model(&product).Updates(updates).Error; err != nil {
+		utils.LogError(r, "UpdateProductHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate produk"})
+		return
+	}
+
+	models.InvalidateProductCatalog()
+
 	// Reload to get updated data
-	db.Preload("Category").First(&product, uint(id64))
+	db.Preload("Category").First(&product, id)
+	auditLog(r, "product.update", before, product)
+	withProductImageURL(r, &product)
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
@@ -242,44 +341,202 @@ func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// DELETE /api/admin/products/{id}
-func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var idStr string
-	if len(parts) >= 4 {
-		idStr = parts[3]
-	}
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil || id64 == 0 {
+// POST /api/admin/products/{id}/image (multipart, field "image")
+// Replaced images stay in the bucket, so nothing that still points at one
+// breaks.
+func UploadProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
 		return
 	}
 
 	db := database.DB
 	var product models.Product
-	if err := db.First(&product, uint(id64)).Error; err != nil {
+	if err := db.First(&product, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan", Code: utils.CodeProductNotFound})
 			return
 		}
+		utils.LogError(r, "UploadProductImageHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
 
-	// Check if any investments use this product
-	var count int64
-	if err := db.Model(&models.Investment{}).Where("product_id = ?", uint(id64)).Count(&count).Error; err == nil && count > 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak dapat menghapus produk yang masih digunakan oleh investasi"})
+	if err := r.ParseMultipartForm(productImageMaxBytes); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar diperlukan", Code: utils.CodeInvalidImage})
 		return
 	}
+	defer file.Close()
 
-	if err := db.Delete(&product).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus produk"})
+	imageBytes, ext, err := utils.SanitizeImage(file, header.Filename, header.Size, productImageMaxBytes)
+	var imgErr *utils.ImageError
+	if errors.As(err, &imgErr) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: imgErr.Message, Code: utils.CodeInvalidImage})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "UploadProductImageHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses gambar"})
+		return
+	}
+
+	objectName := productImagePrefix() + "/" + strconv.FormatUint(uint64(product.ID), 10) + "_" + strconv.FormatInt(time.Now().UnixNano(), 10) + ext
+	if err := utils.UploadToS3(objectName, bytes.NewReader(imageBytes), int64(len(imageBytes))); err != nil {
+		utils.LogError(r, "UploadProductImageHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengunggah gambar"})
+		return
+	}
+
+	before := product
+	if err := db.Model(&product).Update("image", objectName).Error; err != nil {
+		utils.LogError(r, "UploadProductImageHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate produk"})
+		return
+	}
+	models.InvalidateProductCatalog()
+	auditLog(r, "product.image", map[string]interface{}{"image": before.Image}, map[string]interface{}{"image": product.Image})
+	withProductImageURL(r, &product)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Gambar produk berhasil diunggah",
+		Data:    product,
+	})
+}
+
+// DELETE /api/admin/products/{id}
+// Products are archived (set Inactive) rather than deleted so existing
+// investments keep a valid product_id.
+func ArchiveProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
 		return
 	}
 
+	db := database.DB
+	var product models.Product
+	if err := db.First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan", Code: utils.CodeProductNotFound})
+			return
+		}
+		utils.LogError(r, "ArchiveProductHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	if product.Status != "Inactive" {
+		before := map[string]interface{}{"id": product.ID, "status": product.Status}
+		if err := db.Model(&product).Update("status", "Inactive").Error; err != nil {
+			utils.LogError(r, "ArchiveProductHandler", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengarsipkan produk"})
+			return
+		}
+		models.InvalidateProductCatalog()
+		auditLog(r, "product.archive", before, map[string]interface{}{"id": product.ID, "status": "Inactive"})
+	}
+
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
-		Message: "Produk berhasil dihapus",
+		Message: "Produk berhasil diarsipkan",
+		Data:    product,
 	})
 }
+
+// idFromRequest parses the {id} route variable as a positive integer.
+func idFromRequest(r *http.Request) (uint, bool) {
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id64 == 0 {
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// validateProduct checks the product fields and returns a user-facing
+// message, or an empty string when the product is valid.
+func validateProduct(p *models.Product) string {
+	if p.Name == "" {
+		return "Nama produk wajib diisi"
+	}
+	if p.CategoryID == 0 {
+		return "Kategori wajib dipilih"
+	}
+	if p.Amount <= 0 {
+		return "Amount harus lebih dari 0"
+	}
+	if p.DailyProfit < 0 {
+		return "Daily profit tidak boleh negatif"
+	}
+	if p.Duration < 1 {
+		return "Duration minimal 1 hari"
+	}
+	if p.RequiredVIP < 0 || p.RequiredVIP > models.MaxVIPLevel {
+		return fmt.Sprintf("Required VIP harus antara 0 dan %d", models.MaxVIPLevel)
+	}
+	if p.PurchaseLimit < 0 {
+		return "Purchase limit tidak boleh negatif"
+	}
+	if p.TopupMin < 0 || p.TopupMax < 0 {
+		return "Batas top-up tidak boleh negatif"
+	}
+	if p.TopupMax > 0 && p.TopupMin > p.TopupMax {
+		return "Top-up minimal tidak boleh melebihi top-up maksimal"
+	}
+	if p.Description != nil && utf8.RuneCountInString(*p.Description) > productDescriptionMaxChars {
+		return fmt.Sprintf("Deskripsi maksimal %d karakter", productDescriptionMaxChars)
+	}
+	if len(p.Highlights) > productMaxHighlights {
+		return fmt.Sprintf("Highlight maksimal %d poin", productMaxHighlights)
+	}
+	for _, h := range p.Highlights {
+		if utf8.RuneCountInString(h) > productHighlightMaxChars {
+			return fmt.Sprintf("Setiap highlight maksimal %d karakter", productHighlightMaxChars)
+		}
+	}
+	if utf8.RuneCountInString(p.Badge) > productBadgeMaxChars {
+		return fmt.Sprintf("Badge maksimal %d karakter", productBadgeMaxChars)
+	}
+	return ""
+}
+
+// productDescription trims a description; a blank one is none.
+func productDescription(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	if t := strings.TrimSpace(*s); t != "" {
+		return &t
+	}
+	return nil
+}
+
+// productHighlights trims the bullet points and drops blank ones.
+func productHighlights(list []string) models.ProductHighlights {
+	var out models.ProductHighlights
+	for _, h := range list {
+		if h = strings.TrimSpace(h); h != "" {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// checkProductCategory returns a non-zero status with a message when the
+// category does not exist.
+func checkProductCategory(db *gorm.DB, categoryID uint) (int, string) {
+	var category models.Category
+	if err := db.Select("id").First(&category, categoryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusBadRequest, "Kategori tidak ditemukan"
+		}
+		return http.StatusInternalServerError, "Terjadi kesalahan"
+	}
+	return 0, ""
+}
@@ -0,0 +1,243 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+type profitBoostRequest struct {
+	Name         *string    `json:"name"`
+	CategoryID   *uint      `json:"category_id"`
+	ProductID    *uint      `json:"product_id"`
+	ExtraPercent *float64   `json:"extra_percent"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Status       string     `json:"status"`
+}
+
+// GET /api/admin/profit-boosts?status=Active|Inactive
+func ListProfitBoostsHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	db := database.DB
+	query := db.Model(&models.ProfitBoost{})
+	if status := r.URL.Query().Get("status"); status == "Active" || status == "Inactive" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListProfitBoostsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var boosts []models.ProfitBoost
+	if err := query.Order("starts_at DESC, id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&boosts).Error; err != nil {
+		utils.LogError(r, "ListProfitBoostsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(boosts, pg, totalRows),
+	})
+}
+
+// POST /api/admin/profit-boosts
+func CreateProfitBoostHandler(w http.ResponseWriter, r *http.Request) {
+	var req profitBoostRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	boost := models.ProfitBoost{Status: "Active"}
+	msg, err := applyProfitBoostRequest(db, &boost, &req)
+	if err != nil {
+		utils.LogError(r, "CreateProfitBoostHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	if err := db.Create(&boost).Error; err != nil {
+		utils.LogError(r, "CreateProfitBoostHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat boost"})
+		return
+	}
+	models.InvalidateProductCatalog()
+	auditLogTarget(r, "profit_boost.create", "profit_boost", boost.ID, nil, boost)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Boost berhasil dibuat",
+		Data:    boost,
+	})
+}
+
+// PUT /api/admin/profit-boosts/{id}
+// Payouts already credited keep their boost; the change applies to payouts
+// scheduled from the next cron run on.
+func UpdateProfitBoostHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req profitBoostRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	db := database.DB
+	var boost models.ProfitBoost
+	if err := db.First(&boost, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Boost tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "UpdateProfitBoostHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	before := boost
+
+	msg, err := applyProfitBoostRequest(db, &boost, &req)
+	if err != nil {
+		utils.LogError(r, "UpdateProfitBoostHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	// Select so a scope switched to the other kind clears the old column
+	if err := db.Model(&boost).
+		Select("name", "category_id", "product_id", "extra_percent", "starts_at", "ends_at", "status").
+		Updates(&boost).Error; err != nil {
+		utils.LogError(r, "UpdateProfitBoostHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate boost"})
+		return
+	}
+	models.InvalidateProductCatalog()
+	auditLog(r, "profit_boost.update", before, boost)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Boost berhasil diupdate",
+		Data:    boost,
+	})
+}
+
+// DELETE /api/admin/profit-boosts/{id}
+// Deactivates the boost; payouts the cron credits afterwards no longer get it.
+func DeleteProfitBoostHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	res := database.DB.Model(&models.ProfitBoost{}).Where("id = ?", id).Update("status", "Inactive")
+	if res.Error != nil {
+		utils.LogError(r, "DeleteProfitBoostHandler", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus boost"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Boost tidak ditemukan"})
+		return
+	}
+	models.InvalidateProductCatalog()
+	auditLog(r, "profit_boost.deactivate", map[string]interface{}{"id": id}, nil)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Boost berhasil dinonaktifkan",
+	})
+}
+
+// applyProfitBoostRequest copies the provided fields onto b and validates the
+// result, returning a user-facing message when invalid. Sending either
+// category_id or product_id replaces the scope; exactly one must be set.
+func applyProfitBoostRequest(db *gorm.DB, b *models.ProfitBoost, req *profitBoostRequest) (string, error) {
+	if req.Name != nil {
+		b.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.CategoryID != nil || req.ProductID != nil {
+		b.CategoryID, b.ProductID = nil, nil
+		if req.CategoryID != nil && *req.CategoryID != 0 {
+			b.CategoryID = req.CategoryID
+		}
+		if req.ProductID != nil && *req.ProductID != 0 {
+			b.ProductID = req.ProductID
+		}
+	}
+	if req.ExtraPercent != nil {
+		b.ExtraPercent = *req.ExtraPercent
+	}
+	if req.StartsAt != nil {
+		b.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		b.EndsAt = *req.EndsAt
+	}
+	if req.Status == "Active" || req.Status == "Inactive" {
+		b.Status = req.Status
+	}
+
+	if b.Name == "" || len(b.Name) > 100 {
+		return "Nama boost wajib diisi (maksimal 100 karakter)", nil
+	}
+	if (b.CategoryID != nil) == (b.ProductID != nil) {
+		return "Isi salah satu: category_id atau product_id", nil
+	}
+	if b.ExtraPercent <= 0 || b.ExtraPercent > 100 {
+		return "Persentase boost harus lebih dari 0 dan maksimal 100", nil
+	}
+	if b.StartsAt.IsZero() || b.EndsAt.IsZero() {
+		return "Waktu mulai dan berakhir wajib diisi", nil
+	}
+	if !b.EndsAt.After(b.StartsAt) {
+		return "Waktu berakhir harus setelah waktu mulai", nil
+	}
+
+	var count int64
+	if b.CategoryID != nil {
+		if err := db.Model(&models.Category{}).Where("id = ?", *b.CategoryID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return "Kategori tidak ditemukan", nil
+		}
+	} else {
+		if err := db.Model(&models.Product{}).Where("id = ?", *b.ProductID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return "Produk tidak ditemukan", nil
+		}
+	}
+	return "", nil
+}
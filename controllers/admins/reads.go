@@ -0,0 +1,25 @@
+package admins
+
+import (
+	"project/database"
+
+	"gorm.io/gorm"
+)
+
+// ReportHandler serves the admin lists and reports. They only read and can
+// live with replication lag, so their queries go through a ReadReplica and
+// stop competing with the webhook and crons for the primary. Handlers that
+// change data, money above all, take the primary and never a ReportHandler.
+type ReportHandler struct {
+	Reads *database.ReadReplica
+}
+
+// NewReportHandler reads from reads; nil reads from the primary.
+func NewReportHandler(reads *database.ReadReplica) *ReportHandler {
+	return &ReportHandler{Reads: reads}
+}
+
+// db is the connection for this request's queries.
+func (h *ReportHandler) db() *gorm.DB {
+	return h.Reads.DB()
+}
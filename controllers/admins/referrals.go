@@ -0,0 +1,207 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+
+	"project/database"
+	"project/models"
+	"project/referral"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errBonusNotHeld = errors.New("referral bonus not held")
+
+// HeldBonusResponse is a referral bonus waiting for fraud review, with both
+// sides of the referral.
+type HeldBonusResponse struct {
+	ID           uint   `json:"id"`
+	OrderID      string `json:"order_id"`
+	ReferrerID   uint   `json:"referrer_id"`
+	ReferrerName string `json:"referrer_name"`
+	RefereeID    *uint  `json:"referee_id"`
+	RefereeName  string `json:"referee_name"`
+	SourceOrder  string `json:"source_order_id"`
+	Amount       int64  `json:"amount"`
+	Message      string `json:"message"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// GET /api/admin/referral-bonuses/held
+// Lists referral bonuses held because the referrer and the investor share a
+// device, a bank account or an IP, oldest first.
+func ListHeldReferralBonuses(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	query := database.DB.Table("transactions AS t").
+		Joins("JOIN users ref ON ref.id = t.user_id").
+		Joins("LEFT JOIN investments i ON i.id = t.investment_id").
+		Joins("LEFT JOIN users inv ON inv.id = i.user_id").
+		Where("t.transaction_type = ? AND t.status = ?", "team", "Held")
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListHeldReferralBonuses", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	type row struct {
+		models.Transaction
+		ReferrerName string
+		RefereeID    *uint
+		RefereeName  *string
+	}
+	var rows []row
+	if err := query.Select("t.*, ref.name AS referrer_name, inv.id AS referee_id, inv.name AS referee_name").
+		Order("t.id ASC").Offset(pg.Offset).Limit(pg.Limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "ListHeldReferralBonuses", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	resp := make([]HeldBonusResponse, 0, len(rows))
+	for _, t := range rows {
+		item := HeldBonusResponse{
+			ID:           t.ID,
+			OrderID:      t.OrderID,
+			ReferrerID:   t.UserID,
+			ReferrerName: t.ReferrerName,
+			RefereeID:    t.RefereeID,
+			Amount:       t.Amount,
+			CreatedAt:    t.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		}
+		if t.RefereeName != nil {
+			item.RefereeName = *t.RefereeName
+		}
+		if t.SourceOrderID != nil {
+			item.SourceOrder = *t.SourceOrderID
+		}
+		if t.Message != nil {
+			item.Message = *t.Message
+		}
+		resp = append(resp, item)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(resp, pg, totalRows)})
+}
+
+// POST /api/admin/referral-bonuses/{id}/release
+// Pays a held bonus to the referrer after review.
+func ReleaseReferralBonus(w http.ResponseWriter, r *http.Request) {
+	reviewHeldBonus(w, r, true)
+}
+
+// POST /api/admin/referral-bonuses/{id}/reject
+// Drops a held bonus; nothing was credited, so nothing is debited.
+func RejectReferralBonus(w http.ResponseWriter, r *http.Request) {
+	reviewHeldBonus(w, r, false)
+}
+
+func reviewHeldBonus(w http.ResponseWriter, r *http.Request, release bool) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID bonus tidak valid"})
+		return
+	}
+
+	var trx models.Transaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND transaction_type = ?", id, "team").First(&trx).Error; err != nil {
+			return err
+		}
+		if trx.Status != "Held" {
+			return errBonusNotHeld
+		}
+		if !release {
+			return tx.Model(&trx).Update("status", "Failed").Error
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", trx.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", trx.Amount)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&trx).Update("status", "Success").Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Bonus rekomendasi tidak ditemukan"})
+		return
+	case errors.Is(err, errBonusNotHeld):
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Bonus rekomendasi tidak sedang ditahan"})
+		return
+	case err != nil:
+		utils.LogError(r, "reviewHeldBonus", err, "transaction_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses bonus rekomendasi"})
+		return
+	}
+
+	action, message := "referral_bonus.reject", "Bonus rekomendasi ditolak"
+	if release {
+		action, message = "referral_bonus.release", "Bonus rekomendasi dibayarkan"
+	}
+	auditLog(r, action, map[string]interface{}{"id": trx.ID, "status": "Held"}, map[string]interface{}{"id": trx.ID, "user_id": trx.UserID, "amount": trx.Amount, "status": trx.Status})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message, Data: trx})
+}
+
+type setReferrerRequest struct {
+	ReferrerID *uint `json:"referrer_id"`
+}
+
+// PUT /api/admin/users/{id}/referrer
+// Moves a user under another referrer, or detaches them with a null
+// referrer_id. A user cannot refer themselves or one of their descendants.
+func SetUserReferrer(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID pengguna tidak valid"})
+		return
+	}
+	var req setReferrerRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	var user models.User
+	var before *uint
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return err
+		}
+		before = user.ReffBy
+		if req.ReferrerID != nil {
+			if err := referral.ValidateReferrer(tx, user.ID, *req.ReferrerID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&user).Update("reff_by", req.ReferrerID).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengguna atau perekrut tidak ditemukan", Code: utils.CodeUserNotFound})
+		return
+	case errors.Is(err, referral.ErrSelfReferral):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Pengguna tidak dapat merekrut dirinya sendiri"})
+		return
+	case errors.Is(err, referral.ErrReferralCycle):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Perekrut adalah anggota tim pengguna ini, referral akan berputar"})
+		return
+	case err != nil:
+		utils.LogError(r, "SetUserReferrer", err, "user_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui perekrut"})
+		return
+	}
+
+	auditLog(r, "user.referrer", map[string]interface{}{"id": user.ID, "reff_by": before}, map[string]interface{}{"id": user.ID, "reff_by": req.ReferrerID})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Perekrut berhasil diperbarui",
+		Data:    map[string]interface{}{"id": user.ID, "reff_by": req.ReferrerID},
+	})
+}
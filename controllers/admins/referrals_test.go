@@ -0,0 +1,55 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSetUserReferrerRejectsCycles(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	// a refers b, b refers c
+	var chain []models.User
+	for i := 0; i < 3; i++ {
+		u := models.User{Name: fmt.Sprintf("Chain %d", i)}
+		if i > 0 {
+			u.ReffBy = &chain[i-1].ID
+		}
+		chain = append(chain, testutil.NewUser(t, tx, u))
+	}
+
+	set := func(user models.User, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/v3/admin/users/x/referrer", strings.NewReader(body))
+		req = testutil.AsAdmin(req, 1)
+		req = mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(user.ID)})
+		rec := httptest.NewRecorder()
+		SetUserReferrer(rec, req)
+		return rec
+	}
+	if rec := set(chain[0], fmt.Sprintf(`{"referrer_id":%d}`, chain[0].ID)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("self referral: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := set(chain[0], fmt.Sprintf(`{"referrer_id":%d}`, chain[2].ID)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("descendant as referrer: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := set(chain[2], fmt.Sprintf(`{"referrer_id":%d}`, chain[0].ID)); rec.Code != http.StatusOK {
+		t.Fatalf("ancestor as referrer: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var got models.User
+	tx.First(&got, chain[2].ID)
+	if got.ReffBy == nil || *got.ReffBy != chain[0].ID {
+		t.Fatalf("expected reff_by %d, got %v", chain[0].ID, got.ReffBy)
+	}
+}
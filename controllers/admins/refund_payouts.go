@@ -0,0 +1,137 @@
+package admins
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"project/alert"
+	"project/kyta"
+	"project/models"
+	"project/notify"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// creditRefund credits inv's principal to the user's balance with a Success
+// refund transaction. It must run inside the transaction cancelling inv.
+func creditRefund(tx *gorm.DB, inv *models.Investment, msg string) error {
+	res := tx.Model(&models.User{}).Where("id = ?", inv.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", inv.Amount))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("refund investment %d: user %d missing", inv.ID, inv.UserID)
+	}
+	return tx.Create(&models.Transaction{
+		UserID:          inv.UserID,
+		InvestmentID:    &inv.ID,
+		Amount:          inv.Amount,
+		OrderID:         utils.GenerateOrderID(utils.OrderRefund, inv.UserID),
+		TransactionFlow: "debit",
+		TransactionType: "refund",
+		Message:         &msg,
+		Status:          "Success",
+	}).Error
+}
+
+// refundPayoutRequest asks Kyta to pay rp to ba.
+func refundPayoutRequest(rp *models.RefundPayout, ba *models.BankAccount) kyta.PayoutRequest {
+	return kyta.PayoutRequest{
+		ReferenceID:   rp.OrderID,
+		Amount:        rp.Amount,
+		Description:   fmt.Sprintf("Refund # %s", rp.OrderID),
+		BankCode:      ba.Bank.PayoutCode(),
+		AccountNumber: ba.AccountNumber,
+		AccountName:   ba.AccountName,
+	}
+}
+
+// refundPayoutCallback settles the refund payout referenceID from a payout
+// callback. Success confirms the payout and its refund transaction. Failed
+// marks both Failed and credits the principal to the balance instead, asking
+// the user to withdraw it; that also covers a payout reversed after it was
+// confirmed. Callbacks for a payout already in that state change nothing.
+func (h *WithdrawalHandler) refundPayoutCallback(w http.ResponseWriter, r *http.Request, referenceID, status, gatewayID, message string) {
+	if status == "Pending" {
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Callback diterima"})
+		return
+	}
+
+	var rp models.RefundPayout
+	var inv models.Investment
+	changed, wasPaid := false, false
+	err := utils.WithTxOptions(r.Context(), h.DB, utils.TxOptions{Retries: withdrawalTxRetries}, func(tx *gorm.DB) error {
+		rp, inv, changed, wasPaid = models.RefundPayout{}, models.Investment{}, false, false
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", referenceID).First(&rp).Error; err != nil {
+			return err
+		}
+		if rp.Status == status || rp.Status == "Failed" {
+			return nil
+		}
+		changed, wasPaid = true, rp.Status == "Success"
+		if id := strings.TrimSpace(gatewayID); id != "" {
+			rp.GatewayPayoutID = &id
+		}
+		rp.Status = status
+		if status == "Failed" && message != "" {
+			reason := message
+			if len(reason) > 255 {
+				reason = reason[:255]
+			}
+			rp.FailureReason = &reason
+		}
+		if err := tx.Save(&rp).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", rp.OrderID).Update("status", status).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().First(&inv, rp.InvestmentID).Error; err != nil {
+			return err
+		}
+		if status == "Success" {
+			return nil
+		}
+		return creditRefund(tx, &inv, fmt.Sprintf("Refund pembatalan investasi %s: payout %s gagal", inv.ProductName, rp.OrderID))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Refund tidak ditemukan"})
+		return
+	}
+	if err != nil {
+		// A 5xx makes the gateway retry the callback
+		utils.LogError(r, "KytaPayoutCallbackHandler: refund", err, "reference_id", referenceID)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan perubahan"})
+		return
+	}
+
+	switch {
+	case !changed && status == "Success" && rp.Status == "Failed":
+		// Already credited to the balance: the user now has it twice
+		h.Alerts.Notify(alert.KeyPayoutFailed, "Refund payout %s (KytaPay %s) berhasil setelah dikembalikan ke saldo", referenceID, gatewayID)
+	case changed && status == "Success":
+		h.Notifier.Enqueue(notify.InvestmentRefund(rp.UserID, rp.OrderID, inv.ProductName, notify.RefundPaid, rp.Amount))
+	case changed:
+		if wasPaid {
+			h.Alerts.Notify(alert.KeyPayoutFailed, "Refund payout %s (KytaPay %s) dibatalkan setelah berhasil: %s", referenceID, gatewayID, message)
+		} else {
+			h.Alerts.Notify(alert.KeyPayoutFailed, "Refund payout %s (KytaPay %s) gagal di KytaPay: %s", referenceID, gatewayID, message)
+		}
+		h.Notifier.Enqueue(notify.InvestmentRefund(rp.UserID, rp.OrderID, inv.ProductName, notify.RefundBalance, rp.Amount))
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Callback diterima",
+		Data: map[string]interface{}{
+			"order_id": rp.OrderID,
+			"status":   rp.Status,
+		},
+	})
+}
@@ -0,0 +1,249 @@
+package admins
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dailyReportMaxDays bounds the range served by the report endpoints.
+const dailyReportMaxDays = 366
+
+// POST /api/cron/daily-report?date=YYYY-MM-DD
+// Builds the snapshot for the given app-timezone day (default: yesterday). Meant to
+// run shortly after the daily returns cron; re-running a day overwrites its row.
+func CronDailyReportHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	appLoc := utils.AppLocation()
+	now := time.Now().In(appLoc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, appLoc).AddDate(0, 0, -1)
+	if s := r.URL.Query().Get("date"); s != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", s, appLoc)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format tanggal harus YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+	if !day.Before(now) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tanggal laporan belum berakhir"})
+		return
+	}
+
+	report, err := buildDailyReport(database.DB, day)
+	if err != nil {
+		utils.LogError(r, "daily report cron: build snapshot", err, "date", day.Format("2006-01-02"))
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: report})
+}
+
+// GET /api/admin/reports/daily?from=&to=
+func (h *ReportHandler) GetDailyReportsHandler(w http.ResponseWriter, r *http.Request) {
+	reports, msg := loadDailyReports(h.db(), r)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	if reports == nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    map[string]interface{}{"reports": reports},
+	})
+}
+
+// GET /api/admin/reports/daily/export?from=&to=
+func (h *ReportHandler) ExportDailyReportsHandler(w http.ResponseWriter, r *http.Request) {
+	reports, msg := loadDailyReports(h.db(), r)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	if reports == nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	amount := func(v int64) string { return strconv.FormatInt(v, 10) }
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=daily-report-%s-%s.csv", r.URL.Query().Get("from"), r.URL.Query().Get("to")))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"date", "total_deposits", "profit_paid", "capital_returned", "referral_bonuses", "other_bonuses", "withdrawals_settled", "withdrawal_charges", "refunds_to_balance", "refunds_paid_out", "total_user_balance", "balance_delta", "generated_at"})
+	for _, rep := range reports {
+		_ = cw.Write([]string{
+			rep.ReportDate,
+			amount(rep.TotalDeposits),
+			amount(rep.ProfitPaid),
+			amount(rep.CapitalReturned),
+			amount(rep.ReferralBonuses),
+			amount(rep.OtherBonuses),
+			amount(rep.WithdrawalsSettled),
+			amount(rep.WithdrawalCharges),
+			amount(rep.RefundsToBalance),
+			amount(rep.RefundsPaidOut),
+			amount(rep.TotalUserBalance),
+			amount(rep.BalanceDelta),
+			rep.GeneratedAt.Format(time.RFC3339),
+		})
+	}
+	cw.Flush()
+}
+
+// loadDailyReports loads the reports in the request's from/to range. It
+// returns a user-facing message for bad input and nil reports on a database
+// error.
+func loadDailyReports(db *gorm.DB, r *http.Request) ([]models.DailyReport, string) {
+	from, to, msg := parseReportRange(r)
+	if msg != "" {
+		return nil, msg
+	}
+
+	reports := []models.DailyReport{}
+	if err := db.
+		Where("report_date >= ? AND report_date <= ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("report_date ASC").
+		Find(&reports).Error; err != nil {
+		utils.LogError(r, "daily report: load rows", err)
+		return nil, ""
+	}
+	return reports, ""
+}
+
+// parseReportRange reads the from/to query range: whole app-timezone days,
+// both inclusive, defaulting to the last 30 days. It returns a user-facing
+// message for bad input.
+func parseReportRange(r *http.Request) (from, to time.Time, msg string) {
+	appLoc := utils.AppLocation()
+	now := time.Now().In(appLoc)
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, appLoc)
+	from = to.AddDate(0, 0, -30)
+
+	if s := r.URL.Query().Get("from"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, appLoc)
+		if err != nil {
+			return from, to, "Format tanggal from harus YYYY-MM-DD"
+		}
+		from = t
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, appLoc)
+		if err != nil {
+			return from, to, "Format tanggal to harus YYYY-MM-DD"
+		}
+		to = t
+	}
+	if to.Before(from) {
+		return from, to, "Tanggal to tidak boleh sebelum from"
+	}
+	if to.Sub(from) > dailyReportMaxDays*24*time.Hour {
+		return from, to, fmt.Sprintf("Rentang tanggal maksimal %d hari", dailyReportMaxDays)
+	}
+	return from, to, ""
+}
+
+// buildDailyReport aggregates Success transactions by the time they settled
+// (updated_at: deposits and withdrawals turn Success later, everything else is
+// created as Success) and upserts the row for day.
+func buildDailyReport(db *gorm.DB, day time.Time) (*models.DailyReport, error) {
+	start := day
+	end := day.AddDate(0, 0, 1)
+
+	type typeTotal struct {
+		TransactionType string
+		Capital         bool
+		// PaidOut marks refunds sent to the user's bank (RPO- orders)
+		PaidOut bool
+		Amount  int64
+		Charge  int64
+	}
+	var totals []typeTotal
+	if err := db.Model(&models.Transaction{}).
+		Select("transaction_type, COALESCE(transaction_type = 'return' AND message LIKE 'Pengembalian modal%', 0) AS capital, COALESCE(order_id LIKE '"+utils.RefundPayoutOrderPrefix+"%', 0) AS paid_out, COALESCE(SUM(amount), 0) AS amount, COALESCE(SUM(charge), 0) AS charge").
+		Where("status = ? AND updated_at >= ? AND updated_at < ?", "Success", start, end).
+		Group("transaction_type, capital, paid_out").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	report := models.DailyReport{
+		ReportDate:  day.Format("2006-01-02"),
+		GeneratedAt: time.Now(),
+	}
+	for _, t := range totals {
+		switch t.TransactionType {
+		case "investment", "deposit":
+			report.TotalDeposits += t.Amount
+		case "return":
+			if t.Capital {
+				report.CapitalReturned += t.Amount
+			} else {
+				report.ProfitPaid += t.Amount
+			}
+		case "team":
+			report.ReferralBonuses += t.Amount
+		case "bonus", "campaign_bonus":
+			report.OtherBonuses += t.Amount
+		case "withdrawal":
+			report.WithdrawalsSettled += t.Amount
+			report.WithdrawalCharges += t.Charge
+		// Refunds are counted apart from deposits and withdrawals, by
+		// where the money went
+		case "refund", "partial_refund":
+			if t.PaidOut {
+				report.RefundsPaidOut += t.Amount
+			} else {
+				report.RefundsToBalance += t.Amount
+			}
+		case "refund_payout":
+			report.RefundsPaidOut += t.Amount
+		}
+	}
+
+	// Balances are only known "now", so the delta compares against the
+	// previous day's stored snapshot rather than replaying history
+	if err := db.Model(&models.User{}).Select("COALESCE(SUM(balance), 0)").Scan(&report.TotalUserBalance).Error; err != nil {
+		return nil, err
+	}
+	var prev models.DailyReport
+	err := db.Where("report_date = ?", day.AddDate(0, 0, -1).Format("2006-01-02")).Take(&prev).Error
+	if err == nil {
+		report.BalanceDelta = report.TotalUserBalance - prev.TotalUserBalance
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "report_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"total_deposits", "profit_paid", "capital_returned", "referral_bonuses", "other_bonuses",
+			"withdrawals_settled", "withdrawal_charges", "refunds_to_balance", "refunds_paid_out", "total_user_balance", "balance_delta",
+			"generated_at", "updated_at",
+		}),
+	}).Create(&report).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,222 @@
+package admins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultRetentionBatchSize is used when RETENTION_BATCH_SIZE is not set.
+	defaultRetentionBatchSize = 1000
+	// defaultRetentionPause is used when RETENTION_BATCH_PAUSE_MS is not set.
+	defaultRetentionPause = 200 * time.Millisecond
+	// retentionRunBudget bounds one run; what is left goes on the next run.
+	retentionRunBudget = 10 * time.Second
+	// RetentionCronName names the retention cron's cron_runs rows.
+	RetentionCronName = "retention"
+)
+
+// retentionTarget is a table the retention cron may purge: rows whose Column
+// is older than the table's retention days and that match Where.
+type retentionTarget struct {
+	Table  string
+	Column string
+	Where  string
+	Days   func(s *models.Setting) int
+}
+
+// retentionTargets is the hard-coded list of what the retention cron purges.
+// Adding a table here is the only way to have it purged.
+var retentionTargets = []retentionTarget{
+	{Table: "notifications", Column: "created_at", Days: func(s *models.Setting) int { return s.RetentionNotificationsDays }},
+	{Table: "user_signals", Column: "created_at", Days: func(s *models.Setting) int { return s.RetentionUserSignalsDays }},
+	// Pending deliveries are still being retried
+	{Table: "webhook_deliveries", Column: "created_at", Where: "status <> 'Pending'", Days: func(s *models.Setting) int { return s.RetentionWebhookDeliveriesDays }},
+	// Failed events wait for an admin's retry
+	{Table: "outbox_events", Column: "created_at", Where: "status = 'Done'", Days: func(s *models.Setting) int { return s.RetentionOutboxEventsDays }},
+	{Table: "cron_runs", Column: "started_at", Days: func(s *models.Setting) int { return s.RetentionCronRunsDays }},
+}
+
+// retentionProtected are tables holding money records or their audit trail.
+// purgeBatch refuses them even if a target names one by mistake.
+var retentionProtected = map[string]bool{
+	"users":                 true,
+	"transactions":          true,
+	"investments":           true,
+	"investment_topups":     true,
+	"payments":              true,
+	"deposits":              true,
+	"withdrawals":           true,
+	"ticket_grants":         true,
+	"grant_batches":         true,
+	"grant_batch_items":     true,
+	"sfxcr_callbacks":       true,
+	"balance_audits":        true,
+	"admin_audit_logs":      true,
+	"daily_reports":         true,
+	"bank_accounts":         true,
+	"user_spins":            true,
+	"vip_level_changes":     true,
+	"certificate_sequences": true,
+}
+
+// RetentionHandler purges old rows of log-like tables.
+type RetentionHandler struct {
+	DB *gorm.DB
+	// BatchSize rows are deleted per statement, with Pause between
+	// statements so replicas keep up
+	BatchSize int
+	Pause     time.Duration
+}
+
+func NewRetentionHandler(db *gorm.DB) *RetentionHandler {
+	h := &RetentionHandler{DB: db, BatchSize: defaultRetentionBatchSize, Pause: defaultRetentionPause}
+	if v, err := strconv.Atoi(os.Getenv("RETENTION_BATCH_SIZE")); err == nil && v > 0 {
+		h.BatchSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RETENTION_BATCH_PAUSE_MS")); err == nil && v >= 0 {
+		h.Pause = time.Duration(v) * time.Millisecond
+	}
+	return h
+}
+
+// RetentionResult is the retention cron's report, also stored in cron_runs.
+type RetentionResult struct {
+	// Deleted counts the rows deleted per table this run
+	Deleted map[string]int64 `json:"deleted"`
+	// Cutoffs are the times before which rows went, per table purged
+	Cutoffs map[string]string `json:"cutoffs"`
+	// Complete is false when the run stopped early; the next run goes on
+	Complete bool `json:"complete"`
+}
+
+// purgeBatch deletes up to limit rows of t older than cutoff.
+func purgeBatch(db *gorm.DB, t retentionTarget, cutoff time.Time, limit int) (int64, error) {
+	if retentionProtected[t.Table] {
+		return 0, fmt.Errorf("retention: %s holds money records and is never purged", t.Table)
+	}
+	where := t.Column + " < ?"
+	if t.Where != "" {
+		where += " AND " + t.Where
+	}
+	res := db.Exec("DELETE FROM "+t.Table+" WHERE "+where+" ORDER BY id LIMIT ?", cutoff, limit)
+	return res.RowsAffected, res.Error
+}
+
+// POST /api/cron/retention
+// Deletes, in batches of RETENTION_BATCH_SIZE (default 1000) with
+// RETENTION_BATCH_PAUSE_MS (default 200) between them, the rows of the log
+// tables older than their retention days in the settings. A run stops after
+// about 10 seconds, or when the server shuts down, and the next one carries
+// on. Each run is recorded in cron_runs with the rows deleted per table.
+// Money tables are never touched.
+func (h *RetentionHandler) Cron(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	started := time.Now()
+	setting, err := models.GetCachedSetting(h.DB)
+	if err != nil {
+		utils.LogError(r, "retention cron: load settings", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	res, err := h.run(&setting, started, func() bool {
+		return time.Since(started) > retentionRunBudget || utils.ShuttingDown(r)
+	})
+
+	run := models.CronRun{Name: RetentionCronName, Status: "Success", StartedAt: started, FinishedAt: time.Now()}
+	if !res.Complete {
+		run.Status = "Partial"
+	}
+	if err != nil {
+		run.Status = "Failed"
+		msg := err.Error()
+		run.Error = &msg
+	}
+	if b, jerr := json.Marshal(res); jerr == nil {
+		result := string(b)
+		run.Result = &result
+	}
+	if cerr := h.DB.Create(&run).Error; cerr != nil {
+		utils.LogError(r, "retention cron: record run", cerr)
+	}
+	if err != nil {
+		utils.LogError(r, "retention cron: purge", err, "deleted", res.Deleted)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: res})
+}
+
+// run purges every target with retention days set, until stop says so.
+func (h *RetentionHandler) run(setting *models.Setting, now time.Time, stop func() bool) (RetentionResult, error) {
+	res := RetentionResult{Deleted: map[string]int64{}, Cutoffs: map[string]string{}, Complete: true}
+	for _, t := range retentionTargets {
+		days := t.Days(setting)
+		if days <= 0 {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -days)
+		res.Cutoffs[t.Table] = utils.FormatTime(cutoff)
+		res.Deleted[t.Table] = 0
+		for {
+			n, err := purgeBatch(h.DB, t, cutoff, h.BatchSize)
+			res.Deleted[t.Table] += n
+			if err != nil {
+				res.Complete = false
+				return res, fmt.Errorf("%s: %w", t.Table, err)
+			}
+			if n < int64(h.BatchSize) {
+				break
+			}
+			if stop() {
+				res.Complete = false
+				return res, nil
+			}
+			time.Sleep(h.Pause)
+		}
+	}
+	return res, nil
+}
+
+// GET /api/admin/cron-runs?name=
+// Recorded cron runs, latest first, with their JSON result.
+func ListCronRuns(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	query := database.DB.Model(&models.CronRun{})
+	if name := r.URL.Query().Get("name"); name != "" {
+		query = query.Where("name = ?", name)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError(r, "ListCronRuns: count", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	runs := []models.CronRun{}
+	if err := query.Order("id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&runs).Error; err != nil {
+		utils.LogError(r, "ListCronRuns", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(runs, pg, total)})
+}
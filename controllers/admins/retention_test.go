@@ -0,0 +1,117 @@
+package admins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+func TestRetentionNeverTargetsMoneyTables(t *testing.T) {
+	seen := map[string]bool{}
+	for _, target := range retentionTargets {
+		if retentionProtected[target.Table] {
+			t.Errorf("retention targets protected table %s", target.Table)
+		}
+		if target.Column == "" || target.Days == nil || seen[target.Table] {
+			t.Errorf("target %q is incomplete or duplicated", target.Table)
+		}
+		seen[target.Table] = true
+	}
+	for _, table := range []string{"transactions", "investments", "withdrawals"} {
+		// Refused before any statement runs, so no database is needed
+		_, err := purgeBatch(nil, retentionTarget{Table: table, Column: "created_at"}, time.Now(), 10)
+		if err == nil || !strings.Contains(err.Error(), "never purged") {
+			t.Errorf("purge of %s not refused: %v", table, err)
+		}
+	}
+}
+
+// The retention cron deletes old log rows in batches, keeps recent ones and
+// outbox events still owed an admin's retry, and records what it deleted
+// per table in cron_runs.
+func TestRetentionCron(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, RetentionNotificationsDays: 30, RetentionOutboxEventsDays: 7}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	suffix := time.Now().UnixNano() % 1000000000
+	user := testutil.NewUser(t, tx, models.User{Name: "Simpan"})
+
+	old := time.Now().AddDate(0, 0, -40)
+	for i := 0; i < 5; i++ {
+		n := models.Notification{UserID: user.ID, Type: "info", Title: fmt.Sprintf("Lama %d", i), CreatedAt: old}
+		if err := tx.Create(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent := models.Notification{UserID: user.ID, Type: "info", Title: "Baru"}
+	if err := tx.Create(&recent).Error; err != nil {
+		t.Fatal(err)
+	}
+	done := models.OutboxEvent{Kind: "push", Key: fmt.Sprintf("rt-done-%d", suffix), Payload: "{}", Status: models.OutboxDone, NextAttemptAt: old, CreatedAt: old}
+	failed := models.OutboxEvent{Kind: "push", Key: fmt.Sprintf("rt-failed-%d", suffix), Payload: "{}", Status: models.OutboxFailed, NextAttemptAt: old, CreatedAt: old}
+	for _, e := range []*models.OutboxEvent{&done, &failed} {
+		if err := tx.Create(e).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewRetentionHandler(tx)
+	h.BatchSize, h.Pause = 2, 0
+	req := httptest.NewRequest(http.MethodPost, "/v3/cron/retention", nil)
+	req.Header.Set("X-CRON-KEY", "cron-test")
+	rec := httptest.NewRecorder()
+	h.Cron(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data RetentionResult `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Data.Complete || resp.Data.Deleted["notifications"] < 5 || resp.Data.Deleted["outbox_events"] < 1 {
+		t.Fatalf("unexpected report %+v", resp.Data)
+	}
+	if _, ok := resp.Data.Deleted["user_signals"]; !ok {
+		t.Fatalf("user_signals keeps its default retention, got %+v", resp.Data)
+	}
+
+	var left int64
+	tx.Model(&models.Notification{}).Where("user_id = ?", user.ID).Count(&left)
+	if left != 1 {
+		t.Fatalf("expected only the recent notification left, got %d", left)
+	}
+	if err := tx.First(&models.Notification{}, recent.ID).Error; err != nil {
+		t.Fatalf("recent notification deleted: %v", err)
+	}
+	if err := tx.First(&models.OutboxEvent{}, done.ID).Error; err == nil {
+		t.Fatal("old Done outbox event kept")
+	}
+	if err := tx.First(&models.OutboxEvent{}, failed.ID).Error; err != nil {
+		t.Fatalf("Failed outbox event deleted: %v", err)
+	}
+
+	var run models.CronRun
+	if err := tx.Where("name = ?", RetentionCronName).Order("id DESC").First(&run).Error; err != nil {
+		t.Fatal(err)
+	}
+	var stored RetentionResult
+	if run.Status != "Success" || run.Result == nil || json.Unmarshal([]byte(*run.Result), &stored) != nil || stored.Deleted["notifications"] != resp.Data.Deleted["notifications"] {
+		t.Fatalf("unexpected cron run %+v", run)
+	}
+}
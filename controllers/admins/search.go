@@ -0,0 +1,159 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/utils"
+)
+
+const (
+	// searchPrefixMinLength keeps prefix searches selective enough for the
+	// order_id indexes to do the work.
+	searchPrefixMinLength = 8
+	// searchMaxLength is the width of the order_id columns.
+	searchMaxLength = 191
+	// searchLimit bounds the results taken from each table.
+	searchLimit = 20
+)
+
+// searchSource is one table holding order ids. userID and amount are column
+// expressions; join brings in the table they come from when it is not the
+// source itself. gateway is the column holding KytaPay's id, if any.
+type searchSource struct {
+	kind    string
+	table   string
+	join    string
+	userID  string
+	amount  string
+	detail  string
+	gateway string
+}
+
+// searchSources are searched in this order, which is also the order of the
+// results: the purchase and its payment before the ledger rows they caused.
+var searchSources = []searchSource{
+	{kind: "investment", table: "investments", userID: "investments.user_id", amount: "investments.amount", detail: "investments.product_name"},
+	{kind: "payment", table: "payments", join: "JOIN investments ON investments.id = payments.investment_id", userID: "investments.user_id", amount: "investments.amount", detail: "payments.payment_method", gateway: "payments.gateway_payment_id"},
+	{kind: "investment_topup", table: "investment_topups", userID: "investment_topups.user_id", amount: "investment_topups.amount", detail: "investment_topups.payment_method", gateway: "investment_topups.gateway_payment_id"},
+	{kind: "deposit", table: "deposits", userID: "deposits.user_id", amount: "deposits.amount", detail: "deposits.payment_method", gateway: "deposits.gateway_payment_id"},
+	{kind: "withdrawal", table: "withdrawals", userID: "withdrawals.user_id", amount: "withdrawals.amount", detail: "''", gateway: "withdrawals.gateway_payout_id"},
+	{kind: "transaction", table: "transactions", userID: "transactions.user_id", amount: "transactions.amount", detail: "transactions.transaction_type"},
+}
+
+// SearchResult is one row whose order id matched. Type names the detail
+// screen to open; Detail is the product, payment method or transaction type.
+type SearchResult struct {
+	Type       string `json:"type"`
+	ID         uint   `json:"id"`
+	OrderID    string `json:"order_id"`
+	UserID     uint   `json:"user_id"`
+	UserName   string `json:"user_name"`
+	UserNumber string `json:"user_number"`
+	Amount     int64  `json:"amount"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+
+	GatewayID string `json:"gateway_id,omitempty"`
+}
+
+type searchRow struct {
+	ID         uint
+	OrderID    string
+	UserID     uint
+	UserName   string
+	UserNumber string
+	Amount     int64
+	Status     string
+	Detail     string
+	GatewayID  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// searchCondition builds the order_id condition for q: an exact match, or
+// with prefix a match on the start of the id. msg is set when q cannot be
+// searched.
+func searchCondition(q string, prefix bool) (op, arg, msg string) {
+	switch {
+	case q == "":
+		return "", "", "Parameter q wajib diisi"
+	case len(q) > searchMaxLength:
+		return "", "", "Parameter q terlalu panjang"
+	case !prefix:
+		return "=", q, ""
+	case len(q) < searchPrefixMinLength:
+		return "", "", fmt.Sprintf("Pencarian sebagian minimal %d karakter", searchPrefixMinLength)
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q)
+	return "LIKE", escaped + "%", ""
+}
+
+// GET /api/admin/search?q=ORDER_ID&match=exact|prefix
+// Looks an order id, or a KytaPay payment or payout id, up in every table
+// that holds one, so support can open the right detail screen without
+// knowing what the id belongs to. The match
+// is exact unless match=prefix, which needs at least searchPrefixMinLength
+// characters so it stays on the order_id and gateway id indexes.
+func (h *ReportHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	op, arg, msg := searchCondition(q, r.URL.Query().Get("match") == "prefix")
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+
+	db := h.db()
+	results := make([]SearchResult, 0)
+	for _, src := range searchSources {
+		gateway, where, args := "''", src.table+".order_id "+op+" ?", []interface{}{arg}
+		if src.gateway != "" {
+			gateway = src.gateway
+			where = "(" + where + " OR " + src.gateway + " " + op + " ?)"
+			args = append(args, arg)
+		}
+		query := db.Table(src.table).
+			Select(src.table + ".id, " + src.table + ".order_id, " + src.userID + " AS user_id, COALESCE(users.name, '') AS user_name, COALESCE(users.number, '') AS user_number, " +
+				src.amount + " AS amount, " + src.table + ".status, COALESCE(" + src.detail + ", '') AS detail, COALESCE(" + gateway + ", '') AS gateway_id, " + src.table + ".created_at, " + src.table + ".updated_at")
+		if src.join != "" {
+			query = query.Joins(src.join)
+		}
+		var rows []searchRow
+		if err := query.Joins("LEFT JOIN users ON users.id = "+src.userID).
+			Where(where, args...).
+			Order(src.table + ".id DESC").Limit(searchLimit).
+			Scan(&rows).Error; err != nil {
+			utils.LogError(r, "admin search", err, "table", src.table)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		for _, row := range rows {
+			results = append(results, SearchResult{
+				Type:       src.kind,
+				ID:         row.ID,
+				OrderID:    row.OrderID,
+				UserID:     row.UserID,
+				UserName:   row.UserName,
+				UserNumber: row.UserNumber,
+				Amount:     row.Amount,
+				Status:     row.Status,
+				Detail:     row.Detail,
+				CreatedAt:  utils.FormatTime(row.CreatedAt),
+				UpdatedAt:  utils.FormatTime(row.UpdatedAt),
+				GatewayID:  row.GatewayID,
+			})
+		}
+	}
+
+	data := map[string]interface{}{"query": q, "results": results}
+	// What the id says about itself, for ids no table knows (yet)
+	if o, ok := utils.ParseOrderID(q); ok {
+		data["order_type"] = o.Type
+		data["order_user_id"] = o.UserID
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: data})
+}
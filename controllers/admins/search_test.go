@@ -0,0 +1,22 @@
+package admins
+
+import "testing"
+
+func TestSearchCondition(t *testing.T) {
+	if op, arg, msg := searchCondition("INV-123456789", false); op != "=" || arg != "INV-123456789" || msg != "" {
+		t.Fatalf("exact: got %q %q %q", op, arg, msg)
+	}
+	// LIKE wildcards in the query match literally
+	if op, arg, msg := searchCondition("XIN-12_4%6", true); op != "LIKE" || arg != `XIN-12\_4\%6%` || msg != "" {
+		t.Fatalf("prefix: got %q %q %q", op, arg, msg)
+	}
+	for label, q := range map[string]string{"empty": "", "short prefix": "INV-12"} {
+		if _, _, msg := searchCondition(q, true); msg == "" {
+			t.Errorf("%s: expected a validation message", label)
+		}
+	}
+	// A short value is fine when matched exactly
+	if _, _, msg := searchCondition("INV-12", false); msg != "" {
+		t.Fatalf("short exact: unexpected message %q", msg)
+	}
+}
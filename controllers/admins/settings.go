@@ -1,26 +1,81 @@
 package admins
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
 	"project/database"
+	"project/geoip"
 	"project/models"
 	"project/utils"
 )
 
+const (
+	// maxRouteRateLimit bounds the per-minute route limits an admin can set.
+	maxRouteRateLimit = 1000
+	// maxRetentionDays bounds the retention days an admin can set.
+	maxRetentionDays = 3650
+)
+
+// SettingRequest holds the fields an admin may change; omitted fields are left as-is.
 type SettingRequest struct {
-	Name           string  `json:"name"`
-	Company        string  `json:"company"`
-	Logo           string  `json:"logo"`
-	MinWithdraw    float64 `json:"min_withdraw"`
-	MaxWithdraw    float64 `json:"max_withdraw"`
-	WithdrawCharge float64 `json:"withdraw_charge"`
-	AutoWithdraw   bool    `json:"auto_withdraw"`
-	Maintenance    bool    `json:"maintenance"`
-	ClosedRegister bool    `json:"closed_register"`
-	LinkCS         string  `json:"link_cs"`
-	LinkGroup      string  `json:"link_group"`
-	LinkApp        string  `json:"link_app"`
+	Name                 *string  `json:"name"`
+	Company              *string  `json:"company"`
+	Logo                 *string  `json:"logo"`
+	MinWithdraw          *float64 `json:"min_withdraw"`
+	MaxWithdraw          *float64 `json:"max_withdraw"`
+	MinDeposit           *float64 `json:"min_deposit"`
+	MaxDeposit           *float64 `json:"max_deposit"`
+	WithdrawCharge       *float64 `json:"withdraw_charge"`
+	WithdrawStartHour    *int     `json:"withdraw_start_hour"`
+	WithdrawEndHour      *int     `json:"withdraw_end_hour"`
+	ReferralBonusPercent *float64 `json:"referral_bonus_percent"`
+	// Repeat purchases pay referral_repeat_percent; referral_bonus_cap of 0 means no cap
+	ReferralRepeatPercent *float64 `json:"referral_repeat_percent"`
+	ReferralBonusCap      *int64   `json:"referral_bonus_cap"`
+	// Spin tickets for a direct referral's purchase; spin_ticket_daily_cap of 0 means no cap
+	SpinTicketMinAmount    *int64 `json:"spin_ticket_min_amount"`
+	SpinTicketsPerPurchase *uint  `json:"spin_tickets_per_purchase"`
+	SpinTicketDailyCap     *uint  `json:"spin_ticket_daily_cap"`
+	AutoWithdraw           *bool  `json:"auto_withdraw"`
+	Maintenance            *bool  `json:"maintenance"`
+	// Express withdrawals; express_withdraw_charge is added to withdraw_charge
+	ExpressWithdraw       *bool    `json:"express_withdraw"`
+	ExpressWithdrawCharge *float64 `json:"express_withdraw_charge"`
+	// Percent the account holder's name must match the user's; 0 turns it off
+	WithdrawNameMatchMin *int `json:"withdraw_name_match_min"`
+	// Per-feature maintenance; maintenance_until is an optional ETA shown to users
+	MaintenanceInvestment *bool      `json:"maintenance_investment"`
+	MaintenanceWithdrawal *bool      `json:"maintenance_withdrawal"`
+	MaintenanceMessage    *string    `json:"maintenance_message"`
+	MaintenanceUntil      *time.Time `json:"maintenance_until"`
+	ClearMaintenanceUntil bool       `json:"clear_maintenance_until"`
+	ClosedRegister        *bool      `json:"closed_register"`
+	LinkCS                *string    `json:"link_cs"`
+	LinkGroup             *string    `json:"link_group"`
+	LinkApp               *string    `json:"link_app"`
+	// Manual bank transfer, the purchase fallback while the gateway is down;
+	// turning it on needs the receiving account
+	ManualPayment       *bool   `json:"manual_payment"`
+	ManualBankName      *string `json:"manual_bank_name"`
+	ManualAccountNumber *string `json:"manual_account_number"`
+	ManualAccountName   *string `json:"manual_account_name"`
+	// ISO country codes that may register, buy and withdraw; an empty allow
+	// list admits every country the deny list does not name
+	GeoAllowCountries *[]string `json:"geo_allow_countries"`
+	GeoDenyCountries  *[]string `json:"geo_deny_countries"`
+	// Per-minute route limits per user; 0 turns one off
+	RateLimitPurchase *int `json:"rate_limit_purchase"`
+	RateLimitExport   *int `json:"rate_limit_export"`
+	RateLimitRead     *int `json:"rate_limit_read"`
+	// Days the retention cron keeps each log table; 0 keeps it forever
+	RetentionNotificationsDays     *int `json:"retention_notifications_days"`
+	RetentionUserSignalsDays       *int `json:"retention_user_signals_days"`
+	RetentionWebhookDeliveriesDays *int `json:"retention_webhook_deliveries_days"`
+	RetentionOutboxEventsDays      *int `json:"retention_outbox_events_days"`
+	RetentionCronRunsDays          *int `json:"retention_cron_runs_days"`
 }
 
 // GET /api/admin/settings
@@ -29,6 +84,7 @@ func GetSettingsHandler(w http.ResponseWriter, r *http.Request) {
 
 	var setting models.Setting
 	if err := db.First(&setting).Error; err != nil {
+		utils.LogError(r, "GetSettingsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -36,37 +92,18 @@ func GetSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Transform to response format
-	response := map[string]interface{}{
-		"name":            setting.Name,
-		"company":         setting.Company,
-		"logo":            setting.Logo,
-		"min_withdraw":    setting.MinWithdraw,
-		"max_withdraw":    setting.MaxWithdraw,
-		"withdraw_charge": setting.WithdrawCharge,
-		"auto_withdraw":   setting.AutoWithdraw,
-		"maintenance":     setting.Maintenance,
-		"closed_register": setting.ClosedRegister,
-		"link_cs":         setting.LinkCS,
-		"link_group":      setting.LinkGroup,
-		"link_app":        setting.LinkApp,
-	}
-
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data:    settingResponse(&setting),
 	})
 }
 
 // PUT /api/admin/settings
+// Changes take effect immediately: the cached settings copy used by hot paths is invalidated.
 func UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	var req SettingRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -75,54 +112,334 @@ func UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get current settings
 	var setting models.Setting
 	if err := db.First(&setting).Error; err != nil {
+		utils.LogError(r, "UpdateSettingsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
 		})
 		return
 	}
+	before := setting
 
 	// Update settings
-	setting.Name = req.Name
-	setting.Company = req.Company
-	setting.Logo = req.Logo
-	setting.MinWithdraw = req.MinWithdraw
-	setting.MaxWithdraw = req.MaxWithdraw
-	setting.WithdrawCharge = req.WithdrawCharge
-	setting.AutoWithdraw = req.AutoWithdraw
-	setting.Maintenance = req.Maintenance
-	setting.ClosedRegister = req.ClosedRegister
-	setting.LinkCS = req.LinkCS
-	setting.LinkGroup = req.LinkGroup
-	setting.LinkApp = req.LinkApp
+	if req.Name != nil {
+		setting.Name = *req.Name
+	}
+	if req.Company != nil {
+		setting.Company = *req.Company
+	}
+	if req.Logo != nil {
+		setting.Logo = *req.Logo
+	}
+	if req.MinWithdraw != nil {
+		setting.MinWithdraw = *req.MinWithdraw
+	}
+	if req.MaxWithdraw != nil {
+		setting.MaxWithdraw = *req.MaxWithdraw
+	}
+	if req.MinDeposit != nil {
+		setting.MinDeposit = *req.MinDeposit
+	}
+	if req.MaxDeposit != nil {
+		setting.MaxDeposit = *req.MaxDeposit
+	}
+	if req.WithdrawCharge != nil {
+		setting.WithdrawCharge = *req.WithdrawCharge
+	}
+	if req.WithdrawStartHour != nil {
+		setting.WithdrawStartHour = *req.WithdrawStartHour
+	}
+	if req.WithdrawEndHour != nil {
+		setting.WithdrawEndHour = *req.WithdrawEndHour
+	}
+	if req.ReferralBonusPercent != nil {
+		setting.ReferralBonusPercent = *req.ReferralBonusPercent
+	}
+	if req.ReferralRepeatPercent != nil {
+		setting.ReferralRepeatPercent = *req.ReferralRepeatPercent
+	}
+	if req.ReferralBonusCap != nil {
+		setting.ReferralBonusCap = *req.ReferralBonusCap
+	}
+	if req.SpinTicketMinAmount != nil {
+		setting.SpinTicketMinAmount = *req.SpinTicketMinAmount
+	}
+	if req.SpinTicketsPerPurchase != nil {
+		setting.SpinTicketsPerPurchase = *req.SpinTicketsPerPurchase
+	}
+	if req.SpinTicketDailyCap != nil {
+		setting.SpinTicketDailyCap = *req.SpinTicketDailyCap
+	}
+	if req.AutoWithdraw != nil {
+		setting.AutoWithdraw = *req.AutoWithdraw
+	}
+	if req.ExpressWithdraw != nil {
+		setting.ExpressWithdraw = *req.ExpressWithdraw
+	}
+	if req.ExpressWithdrawCharge != nil {
+		setting.ExpressWithdrawCharge = *req.ExpressWithdrawCharge
+	}
+	if req.WithdrawNameMatchMin != nil {
+		setting.WithdrawNameMatchMin = *req.WithdrawNameMatchMin
+	}
+	if req.Maintenance != nil {
+		setting.Maintenance = *req.Maintenance
+	}
+	if req.MaintenanceInvestment != nil {
+		setting.MaintenanceInvestment = *req.MaintenanceInvestment
+	}
+	if req.MaintenanceWithdrawal != nil {
+		setting.MaintenanceWithdrawal = *req.MaintenanceWithdrawal
+	}
+	if req.MaintenanceMessage != nil {
+		setting.MaintenanceMessage = *req.MaintenanceMessage
+	}
+	if req.MaintenanceUntil != nil {
+		setting.MaintenanceUntil = req.MaintenanceUntil
+	}
+	if req.ClearMaintenanceUntil {
+		setting.MaintenanceUntil = nil
+	}
+	if req.ClosedRegister != nil {
+		setting.ClosedRegister = *req.ClosedRegister
+	}
+	if req.LinkCS != nil {
+		setting.LinkCS = *req.LinkCS
+	}
+	if req.LinkGroup != nil {
+		setting.LinkGroup = *req.LinkGroup
+	}
+	if req.LinkApp != nil {
+		setting.LinkApp = *req.LinkApp
+	}
+	if req.ManualPayment != nil {
+		setting.ManualPayment = *req.ManualPayment
+	}
+	if req.ManualBankName != nil {
+		setting.ManualBankName = strings.TrimSpace(*req.ManualBankName)
+	}
+	if req.ManualAccountNumber != nil {
+		setting.ManualAccountNumber = strings.TrimSpace(*req.ManualAccountNumber)
+	}
+	if req.ManualAccountName != nil {
+		setting.ManualAccountName = strings.TrimSpace(*req.ManualAccountName)
+	}
+	if req.GeoAllowCountries != nil {
+		list, bad := countryList(*req.GeoAllowCountries)
+		if bad != "" {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+				Success: false,
+				Message: "Kode negara tidak valid: " + bad,
+			})
+			return
+		}
+		setting.GeoAllowCountries = list
+	}
+	if req.GeoDenyCountries != nil {
+		list, bad := countryList(*req.GeoDenyCountries)
+		if bad != "" {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+				Success: false,
+				Message: "Kode negara tidak valid: " + bad,
+			})
+			return
+		}
+		setting.GeoDenyCountries = list
+	}
+	if req.RateLimitPurchase != nil {
+		setting.RateLimitPurchase = *req.RateLimitPurchase
+	}
+	if req.RateLimitExport != nil {
+		setting.RateLimitExport = *req.RateLimitExport
+	}
+	if req.RateLimitRead != nil {
+		setting.RateLimitRead = *req.RateLimitRead
+	}
+	if req.RetentionNotificationsDays != nil {
+		setting.RetentionNotificationsDays = *req.RetentionNotificationsDays
+	}
+	if req.RetentionUserSignalsDays != nil {
+		setting.RetentionUserSignalsDays = *req.RetentionUserSignalsDays
+	}
+	if req.RetentionWebhookDeliveriesDays != nil {
+		setting.RetentionWebhookDeliveriesDays = *req.RetentionWebhookDeliveriesDays
+	}
+	if req.RetentionOutboxEventsDays != nil {
+		setting.RetentionOutboxEventsDays = *req.RetentionOutboxEventsDays
+	}
+	if req.RetentionCronRunsDays != nil {
+		setting.RetentionCronRunsDays = *req.RetentionCronRunsDays
+	}
+
+	if msg := validateSetting(&setting); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: msg,
+		})
+		return
+	}
 
 	if err := db.Save(&setting).Error; err != nil {
+		utils.LogError(r, "UpdateSettingsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
 		})
 		return
 	}
+	models.InvalidateSettingCache()
 
-	// Transform to response format
-	response := map[string]interface{}{
-		"name":            setting.Name,
-		"company":         setting.Company,
-		"logo":            setting.Logo,
-		"min_withdraw":    setting.MinWithdraw,
-		"max_withdraw":    setting.MaxWithdraw,
-		"withdraw_charge": setting.WithdrawCharge,
-		"auto_withdraw":   setting.AutoWithdraw,
-		"maintenance":     setting.Maintenance,
-		"closed_register": setting.ClosedRegister,
-		"link_cs":         setting.LinkCS,
-		"link_group":      setting.LinkGroup,
-		"link_app":        setting.LinkApp,
-	}
+	auditLog(r, "settings.update", settingResponse(&before), settingResponse(&setting))
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Pengaturan berhasil diperbarui",
-		Data:    response,
+		Data:    settingResponse(&setting),
 	})
 }
+
+// countryList joins codes as the settings store them, upper-cased and
+// deduplicated. It returns the first code that is not two letters as bad.
+func countryList(codes []string) (list, bad string) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(codes))
+	for _, c := range codes {
+		code := geoip.NormalizeCountry(c)
+		if code == geoip.Unknown {
+			return "", c
+		}
+		if !seen[code] {
+			seen[code] = true
+			out = append(out, code)
+		}
+	}
+	return strings.Join(out, ","), ""
+}
+
+// splitCountries is the inverse of countryList, for responses.
+func splitCountries(list string) []string {
+	if list == "" {
+		return []string{}
+	}
+	return strings.Split(list, ",")
+}
+
+// validateSetting returns a user-facing message when the merged settings are invalid.
+func validateSetting(s *models.Setting) string {
+	if s.MinWithdraw <= 0 {
+		return "Minimal penarikan harus lebih dari 0"
+	}
+	if s.MaxWithdraw < s.MinWithdraw {
+		return "Maksimal penarikan tidak boleh kurang dari minimal penarikan"
+	}
+	if s.MinDeposit <= 0 {
+		return "Minimal deposit harus lebih dari 0"
+	}
+	if s.MaxDeposit < s.MinDeposit {
+		return "Maksimal deposit tidak boleh kurang dari minimal deposit"
+	}
+	if s.WithdrawCharge < 0 || s.WithdrawCharge >= 100 {
+		return "Biaya penarikan harus antara 0 dan 100 persen"
+	}
+	if s.ExpressWithdrawCharge < 0 || s.WithdrawCharge+s.ExpressWithdrawCharge >= 100 {
+		return "Biaya penarikan ekspres harus 0 atau lebih dan bersama biaya penarikan di bawah 100 persen"
+	}
+	if s.WithdrawNameMatchMin < 0 || s.WithdrawNameMatchMin > 100 {
+		return "Batas kecocokan nama penarikan harus antara 0 dan 100 persen"
+	}
+	if s.WithdrawStartHour < 0 || s.WithdrawStartHour > 23 || s.WithdrawEndHour < 1 || s.WithdrawEndHour > 24 {
+		return "Jam penarikan tidak valid"
+	}
+	if s.WithdrawStartHour >= s.WithdrawEndHour {
+		return "Jam mulai penarikan harus sebelum jam selesai"
+	}
+	if s.ReferralBonusPercent < 0 || s.ReferralBonusPercent > 100 {
+		return "Bonus referral harus antara 0 dan 100 persen"
+	}
+	if s.ReferralRepeatPercent < 0 || s.ReferralRepeatPercent > 100 {
+		return "Bonus referral pembelian ulang harus antara 0 dan 100 persen"
+	}
+	if s.ReferralBonusCap < 0 {
+		return "Batas bonus referral tidak boleh negatif"
+	}
+	if s.SpinTicketMinAmount < 0 {
+		return "Minimal investasi untuk tiket spin tidak boleh negatif"
+	}
+	if len(s.MaintenanceMessage) > 255 {
+		return "Pesan pemeliharaan maksimal 255 karakter"
+	}
+	if s.ManualPayment && !s.ManualPaymentAvailable() {
+		return "Nama bank, nomor rekening, dan nama rekening wajib diisi untuk transfer manual"
+	}
+	if len(s.GeoAllowCountries) > 1000 || len(s.GeoDenyCountries) > 1000 {
+		return "Daftar negara terlalu panjang"
+	}
+	if len(s.ManualBankName) > 100 || len(s.ManualAccountName) > 100 || len(s.ManualAccountNumber) > 50 {
+		return "Data rekening transfer manual terlalu panjang"
+	}
+	for _, c := range s.ManualAccountNumber {
+		if c < '0' || c > '9' {
+			return "Nomor rekening transfer manual hanya boleh berisi angka"
+		}
+	}
+	for _, limit := range []int{s.RateLimitPurchase, s.RateLimitExport, s.RateLimitRead} {
+		if limit < 0 || limit > maxRouteRateLimit {
+			return fmt.Sprintf("Batas permintaan per menit harus antara 0 dan %d", maxRouteRateLimit)
+		}
+	}
+	for _, days := range []int{s.RetentionNotificationsDays, s.RetentionUserSignalsDays, s.RetentionWebhookDeliveriesDays, s.RetentionOutboxEventsDays, s.RetentionCronRunsDays} {
+		if days < 0 || days > maxRetentionDays {
+			return fmt.Sprintf("Masa simpan data harus antara 0 dan %d hari", maxRetentionDays)
+		}
+	}
+	return ""
+}
+
+func settingResponse(setting *models.Setting) map[string]interface{} {
+	return map[string]interface{}{
+		"name":                      setting.Name,
+		"company":                   setting.Company,
+		"logo":                      setting.Logo,
+		"min_withdraw":              setting.MinWithdraw,
+		"max_withdraw":              setting.MaxWithdraw,
+		"min_deposit":               setting.MinDeposit,
+		"max_deposit":               setting.MaxDeposit,
+		"withdraw_charge":           setting.WithdrawCharge,
+		"withdraw_start_hour":       setting.WithdrawStartHour,
+		"withdraw_end_hour":         setting.WithdrawEndHour,
+		"referral_bonus_percent":    setting.ReferralBonusPercent,
+		"referral_repeat_percent":   setting.ReferralRepeatPercent,
+		"referral_bonus_cap":        setting.ReferralBonusCap,
+		"spin_ticket_min_amount":    setting.SpinTicketMinAmount,
+		"spin_tickets_per_purchase": setting.SpinTicketsPerPurchase,
+		"spin_ticket_daily_cap":     setting.SpinTicketDailyCap,
+		"auto_withdraw":             setting.AutoWithdraw,
+		"express_withdraw":          setting.ExpressWithdraw,
+		"express_withdraw_charge":   setting.ExpressWithdrawCharge,
+		"withdraw_name_match_min":   setting.WithdrawNameMatchMin,
+		"maintenance":               setting.Maintenance,
+		"maintenance_investment":    setting.MaintenanceInvestment,
+		"maintenance_withdrawal":    setting.MaintenanceWithdrawal,
+		"maintenance_message":       setting.MaintenanceMessage,
+		"maintenance_until":         setting.MaintenanceUntil,
+		"closed_register":           setting.ClosedRegister,
+		"link_cs":                   setting.LinkCS,
+		"link_group":                setting.LinkGroup,
+		"link_app":                  setting.LinkApp,
+		"manual_payment":            setting.ManualPayment,
+		"manual_bank_name":          setting.ManualBankName,
+		"manual_account_number":     setting.ManualAccountNumber,
+		"manual_account_name":       setting.ManualAccountName,
+		"geo_allow_countries":       splitCountries(setting.GeoAllowCountries),
+		"geo_deny_countries":        splitCountries(setting.GeoDenyCountries),
+		"rate_limit_purchase":       setting.RateLimitPurchase,
+		"rate_limit_export":         setting.RateLimitExport,
+		"rate_limit_read":           setting.RateLimitRead,
+
+		"retention_notifications_days":      setting.RetentionNotificationsDays,
+		"retention_user_signals_days":       setting.RetentionUserSignalsDays,
+		"retention_webhook_deliveries_days": setting.RetentionWebhookDeliveriesDays,
+		"retention_outbox_events_days":      setting.RetentionOutboxEventsDays,
+		"retention_cron_runs_days":          setting.RetentionCronRunsDays,
+	}
+}
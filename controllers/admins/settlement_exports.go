@@ -0,0 +1,321 @@
+package admins
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/config"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// SettlementExportCronName names the settlement export cron's cron_runs rows.
+	SettlementExportCronName = "settlement-export"
+	// defaultSettlementPrefix is used when SETTLEMENT_EXPORT_PREFIX is not set.
+	defaultSettlementPrefix = "settlements"
+	// settlementURLExpiry is how long a download link stays valid.
+	settlementURLExpiry = 15 * time.Minute
+)
+
+// settlementFilters select each file's Success transactions: money the
+// gateway collected, money sent to banks, and profit credited to balances.
+// Capital returned at completion is a return too but no profit, while
+// promotion boosts are.
+var settlementFilters = map[string]string{
+	models.SettlementPayments: "transaction_type IN ('investment', 'deposit') OR (transaction_type = 'investment_topup' AND order_id LIKE '" + utils.TopupOrderPrefix + "%')",
+	models.SettlementPayouts:  "transaction_type = 'withdrawal' OR (transaction_type = 'refund' AND order_id LIKE '" + utils.RefundPayoutOrderPrefix + "%')",
+	models.SettlementProfits:  "transaction_type = 'profit_boost' OR (transaction_type = 'return' AND COALESCE(message, '') NOT LIKE 'Pengembalian modal%')",
+}
+
+// settlementHeader is the first row of every settlement file.
+var settlementHeader = []string{"transaction_id", "order_id", "user_id", "transaction_type", "flow", "amount", "charge", "settled_at", "message"}
+
+// SettlementStore keeps settlement files; S3-compatible storage in production.
+type SettlementStore interface {
+	// Put stores body under key, replacing what was there
+	Put(ctx context.Context, key string, body []byte) error
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+type s3SettlementStore struct {
+	bucket string
+}
+
+func (s s3SettlementStore) Put(ctx context.Context, key string, body []byte) error {
+	return utils.PutObject(ctx, s.bucket, key, bytes.NewReader(body), "text/csv; charset=utf-8")
+}
+
+func (s s3SettlementStore) SignedURL(key string, expiry time.Duration) (string, error) {
+	return utils.PresignGetObject(s.bucket, key, expiry)
+}
+
+// SettlementExportHandler writes the daily settlement files and serves their
+// download links.
+type SettlementExportHandler struct {
+	DB *gorm.DB
+	// Store overrides the storage configured by the environment
+	Store SettlementStore
+}
+
+func NewSettlementExportHandler(db *gorm.DB) *SettlementExportHandler {
+	return &SettlementExportHandler{DB: db}
+}
+
+// store returns the settlement storage, or an error naming every variable
+// missing to configure it.
+func (h *SettlementExportHandler) store() (SettlementStore, error) {
+	if h.Store != nil {
+		return h.Store, nil
+	}
+	for _, st := range config.Check() {
+		if st.Name == "settlement_export" && !st.Configured {
+			return nil, fmt.Errorf("settlement storage not configured: missing %s", strings.Join(st.Missing, ", "))
+		}
+	}
+	return s3SettlementStore{bucket: strings.TrimSpace(os.Getenv("SETTLEMENT_S3_BUCKET"))}, nil
+}
+
+// settlementObjectKey is where the kind file of date is stored. It depends on
+// nothing else, so a re-export overwrites the same object.
+func settlementObjectKey(date, kind string) string {
+	prefix := strings.Trim(os.Getenv("SETTLEMENT_EXPORT_PREFIX"), "/ ")
+	if prefix == "" {
+		prefix = defaultSettlementPrefix
+	}
+	return fmt.Sprintf("%s/%s/%s.csv", prefix, date, kind)
+}
+
+// SettlementExportResult is the settlement cron's report, also stored in
+// cron_runs.
+type SettlementExportResult struct {
+	Date  string                    `json:"date"`
+	Files []models.SettlementExport `json:"files"`
+}
+
+// POST /api/cron/settlement-export?date=YYYY-MM-DD
+// Writes the settlement files of an app-timezone day (default: yesterday) to
+// SETTLEMENT_S3_BUCKET: the Success payments, payouts and profit credits
+// that settled that day, one CSV each, ordered by transaction id. Re-running
+// a day overwrites its files and rows; unchanged transactions give the same
+// checksums. Each run is recorded in cron_runs, and a run without storage
+// configured fails there with the variables missing.
+func (h *SettlementExportHandler) Cron(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	appLoc := utils.AppLocation()
+	now := time.Now().In(appLoc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, appLoc).AddDate(0, 0, -1)
+	if s := r.URL.Query().Get("date"); s != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", s, appLoc)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format tanggal harus YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+	if day.AddDate(0, 0, 1).After(now) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tanggal settlement belum berakhir"})
+		return
+	}
+
+	started := time.Now()
+	res := SettlementExportResult{Date: day.Format("2006-01-02"), Files: []models.SettlementExport{}}
+	store, err := h.store()
+	if err == nil {
+		err = h.export(r.Context(), store, day, &res)
+	}
+
+	run := models.CronRun{Name: SettlementExportCronName, Status: "Success", StartedAt: started, FinishedAt: time.Now()}
+	if err != nil {
+		run.Status = "Failed"
+		msg := err.Error()
+		run.Error = &msg
+	}
+	if b, jerr := json.Marshal(res); jerr == nil {
+		result := string(b)
+		run.Result = &result
+	}
+	if cerr := h.DB.Create(&run).Error; cerr != nil {
+		utils.LogError(r, "settlement export cron: record run", cerr)
+	}
+	if err != nil {
+		utils.LogError(r, "settlement export cron", err, "date", res.Date)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Export settlement gagal: " + err.Error()})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: res})
+}
+
+// export writes every settlement file of day and records it, appending each
+// to res as it is stored. It stops at the first failure.
+func (h *SettlementExportHandler) export(ctx context.Context, store SettlementStore, day time.Time, res *SettlementExportResult) error {
+	for _, kind := range models.SettlementKinds {
+		file, body, err := buildSettlementFile(h.DB.WithContext(ctx), kind, day)
+		if err != nil {
+			return fmt.Errorf("%s: %w", kind, err)
+		}
+		if err := store.Put(ctx, file.ObjectKey, body); err != nil {
+			return fmt.Errorf("%s: upload: %w", kind, err)
+		}
+		if err := h.DB.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "export_date"}, {Name: "kind"}},
+			DoUpdates: clause.AssignmentColumns([]string{"object_key", "row_count", "total_amount", "size", "sha256", "updated_at"}),
+		}).Create(&file).Error; err != nil {
+			return fmt.Errorf("%s: record: %w", kind, err)
+		}
+		// The upsert leaves the created_at of an overwritten row unset, and
+		// its id may be the one an insert would have taken: read the row back
+		var stored models.SettlementExport
+		if err := h.DB.WithContext(ctx).Where("export_date = ? AND kind = ?", file.ExportDate, kind).Take(&stored).Error; err != nil {
+			return fmt.Errorf("%s: record: %w", kind, err)
+		}
+		res.Files = append(res.Files, stored)
+	}
+	return nil
+}
+
+// buildSettlementFile renders the kind file of day: the Success transactions
+// matching the kind that settled (settled_at) within the day, by id. Later
+// touches of a row do not move it to another day.
+func buildSettlementFile(db *gorm.DB, kind string, day time.Time) (models.SettlementExport, []byte, error) {
+	date := day.Format("2006-01-02")
+	file := models.SettlementExport{ExportDate: date, Kind: kind, ObjectKey: settlementObjectKey(date, kind)}
+
+	rows, err := db.Model(&models.Transaction{}).
+		Where("status = ? AND settled_at >= ? AND settled_at < ?", "Success", day, day.AddDate(0, 0, 1)).
+		Where(settlementFilters[kind]).
+		Order("id ASC").
+		Rows()
+	if err != nil {
+		return file, nil, err
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	_ = cw.Write(settlementHeader)
+	loc := day.Location()
+	for rows.Next() {
+		var t models.Transaction
+		if err := db.ScanRows(rows, &t); err != nil {
+			return file, nil, err
+		}
+		_ = cw.Write([]string{
+			strconv.FormatUint(uint64(t.ID), 10),
+			t.OrderID,
+			strconv.FormatUint(uint64(t.UserID), 10),
+			t.TransactionType,
+			t.TransactionFlow,
+			strconv.FormatInt(t.Amount, 10),
+			strconv.FormatInt(t.Charge, 10),
+			t.SettledAt.In(loc).Format(time.RFC3339),
+			utils.GetStringValue(t.Message),
+		})
+		file.RowCount++
+		file.TotalAmount += t.Amount
+	}
+	if err := rows.Err(); err != nil {
+		return file, nil, err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return file, nil, err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	file.SHA256 = hex.EncodeToString(sum[:])
+	file.Size = int64(buf.Len())
+	return file, buf.Bytes(), nil
+}
+
+// GET /api/admin/reports/settlements?from=&to=
+// The settlement files exported for the days in range, latest first.
+func (h *SettlementExportHandler) List(w http.ResponseWriter, r *http.Request) {
+	from, to, msg := parseReportRange(r)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	files := []models.SettlementExport{}
+	if err := h.DB.WithContext(r.Context()).
+		Where("export_date >= ? AND export_date <= ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("export_date DESC, id ASC").
+		Find(&files).Error; err != nil {
+		utils.LogError(r, "ListSettlementExports", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: files})
+}
+
+// SettlementDownload is a settlement file with a link to download it.
+type SettlementDownload struct {
+	models.SettlementExport
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// GET /api/admin/reports/settlements/{date}
+// The day's settlement files, each with a signed download URL valid for 15
+// minutes. Compare a download against its sha256.
+func (h *SettlementExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	date := mux.Vars(r)["date"]
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format tanggal harus YYYY-MM-DD"})
+		return
+	}
+	var files []models.SettlementExport
+	if err := h.DB.WithContext(r.Context()).Where("export_date = ?", date).Order("id ASC").Find(&files).Error; err != nil {
+		utils.LogError(r, "DownloadSettlementExport", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if len(files) == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Settlement tanggal ini belum diekspor"})
+		return
+	}
+
+	store, err := h.store()
+	if err != nil {
+		utils.LogError(r, "DownloadSettlementExport: storage", err)
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.APIResponse{Success: false, Message: "Penyimpanan settlement belum dikonfigurasi"})
+		return
+	}
+	expires := utils.FormatTime(time.Now().Add(settlementURLExpiry))
+	downloads := make([]SettlementDownload, 0, len(files))
+	for _, f := range files {
+		url, err := store.SignedURL(f.ObjectKey, settlementURLExpiry)
+		if err != nil {
+			utils.LogError(r, "DownloadSettlementExport: sign", err, "object_key", f.ObjectKey)
+			utils.WriteJSON(w, http.StatusBadGateway, utils.APIResponse{Success: false, Message: "Gagal membuat link unduhan"})
+			return
+		}
+		downloads = append(downloads, SettlementDownload{SettlementExport: f, URL: url, ExpiresAt: expires})
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: downloads})
+}
@@ -0,0 +1,159 @@
+package admins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// memSettlementStore keeps settlement files in memory.
+type memSettlementStore struct {
+	objects map[string][]byte
+}
+
+func (s *memSettlementStore) Put(_ context.Context, key string, body []byte) error {
+	s.objects[key] = append([]byte(nil), body...)
+	return nil
+}
+
+func (s *memSettlementStore) SignedURL(key string, _ time.Duration) (string, error) {
+	return "https://storage.test/" + key + "?signed", nil
+}
+
+// The settlement cron writes one file per kind with the day's Success
+// transactions, overwrites them identically when re-run, and fails in
+// cron_runs when storage is not configured.
+func TestSettlementExportCron(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	t.Setenv("SETTLEMENT_EXPORT_PREFIX", "")
+	suffix := time.Now().UnixNano() % 1000000000
+	user := testutil.NewUser(t, tx, models.User{Name: "Settle"})
+
+	day := time.Date(2020, 3, 1, 0, 0, 0, 0, utils.AppLocation())
+	settled := day.Add(10 * time.Hour)
+	nextDay := day.AddDate(0, 0, 1)
+	profit, capital, boost := "Profit investasi produk Settle", "Pengembalian modal investasi produk Settle", "Bonus promo Settle"
+	txs := []models.Transaction{
+		{UserID: user.ID, Amount: 150000, OrderID: fmt.Sprintf("DEP-S%d", suffix), TransactionFlow: "debit", TransactionType: "deposit", Status: "Success", SettledAt: &settled},
+		{UserID: user.ID, Amount: 80000, Charge: 8000, OrderID: fmt.Sprintf("WD-S%d", suffix), TransactionFlow: "credit", TransactionType: "withdrawal", Status: "Success", SettledAt: &settled},
+		{UserID: user.ID, Amount: 5000, OrderID: fmt.Sprintf("RTN-S%d", suffix), TransactionFlow: "debit", TransactionType: "return", Message: &profit, Status: "Success", SettledAt: &settled},
+		{UserID: user.ID, Amount: 500, OrderID: fmt.Sprintf("BST-S%d", suffix), TransactionFlow: "debit", TransactionType: "profit_boost", Message: &boost, Status: "Success", SettledAt: &settled},
+		// Capital is returned, not profit; the others did not settle that day
+		{UserID: user.ID, Amount: 100000, OrderID: fmt.Sprintf("RTN-C%d", suffix), TransactionFlow: "debit", TransactionType: "return", Message: &capital, Status: "Success", SettledAt: &settled},
+		{UserID: user.ID, Amount: 90000, OrderID: fmt.Sprintf("WD-P%d", suffix), TransactionFlow: "credit", TransactionType: "withdrawal", Status: "Pending"},
+		{UserID: user.ID, Amount: 7000, OrderID: fmt.Sprintf("RTN-N%d", suffix), TransactionFlow: "debit", TransactionType: "return", Message: &profit, Status: "Success", SettledAt: &nextDay},
+	}
+	for i := range txs {
+		if err := tx.Create(&txs[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Touching a settled row later keeps it in its day
+	if err := tx.Model(&txs[1]).Update("message", "Ditandai finance").Error; err != nil {
+		t.Fatal(err)
+	}
+	// Settling the Pending one now puts it in today's file, not this one
+	if err := tx.Model(&models.Transaction{}).Where("id = ?", txs[5].ID).Update("status", "Success").Error; err != nil {
+		t.Fatal(err)
+	}
+	var late models.Transaction
+	if err := tx.First(&late, txs[5].ID).Error; err != nil || late.SettledAt == nil || late.SettledAt.Before(nextDay) {
+		t.Fatalf("expected settled_at stamped on settlement, got %+v (%v)", late.SettledAt, err)
+	}
+
+	store := &memSettlementStore{objects: map[string][]byte{}}
+	h := NewSettlementExportHandler(tx)
+	h.Store = store
+	run := func() (int, SettlementExportResult) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v3/cron/settlement-export?date=2020-03-01", nil)
+		req.Header.Set("X-CRON-KEY", "cron-test")
+		rec := httptest.NewRecorder()
+		h.Cron(rec, req)
+		var resp struct {
+			Data SettlementExportResult `json:"data"`
+		}
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, resp.Data
+	}
+
+	code, first := run()
+	if code != http.StatusOK || len(first.Files) != 3 {
+		t.Fatalf("expected 200 with three files, got %d %+v", code, first)
+	}
+	want := map[string]struct {
+		rows  int
+		total int64
+		order string
+	}{
+		models.SettlementPayments: {1, 150000, txs[0].OrderID},
+		models.SettlementPayouts:  {1, 80000, txs[1].OrderID},
+		models.SettlementProfits:  {2, 5500, txs[2].OrderID},
+	}
+	for _, f := range first.Files {
+		w := want[f.Kind]
+		if f.ObjectKey != "settlements/2020-03-01/"+f.Kind+".csv" || f.RowCount != w.rows || f.TotalAmount != w.total || f.SHA256 == "" {
+			t.Fatalf("%s: unexpected file %+v", f.Kind, f)
+		}
+		body := string(store.objects[f.ObjectKey])
+		if !strings.HasPrefix(body, "transaction_id,order_id,") || !strings.Contains(body, w.order) || strings.Contains(body, txs[4].OrderID) || int64(len(body)) != f.Size {
+			t.Fatalf("%s: unexpected body %q", f.Kind, body)
+		}
+	}
+
+	// Re-running overwrites the same objects and rows with the same checksums
+	code, second := run()
+	if code != http.StatusOK || len(second.Files) != 3 {
+		t.Fatalf("re-run: expected 200 with three files, got %d %+v", code, second)
+	}
+	for i := range second.Files {
+		if second.Files[i].ID != first.Files[i].ID || second.Files[i].SHA256 != first.Files[i].SHA256 {
+			t.Fatalf("re-run: expected the same file, got %+v then %+v", first.Files[i], second.Files[i])
+		}
+	}
+	var files int64
+	tx.Model(&models.SettlementExport{}).Where("export_date = ?", "2020-03-01").Count(&files)
+	if files != 3 || len(store.objects) != 3 {
+		t.Fatalf("expected three rows and objects, got %d and %d", files, len(store.objects))
+	}
+
+	rec := httptest.NewRecorder()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v3/admin/reports/settlements/2020-03-01", nil), map[string]string{"date": "2020-03-01"})
+	h.Download(rec, req)
+	var downloads struct {
+		Data []SettlementDownload `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &downloads); err != nil || rec.Code != http.StatusOK || len(downloads.Data) != 3 || !strings.HasSuffix(downloads.Data[0].URL, "?signed") {
+		t.Fatalf("download: expected three signed links, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Without storage configured the run fails loudly
+	h.Store = nil
+	t.Setenv("SETTLEMENT_S3_BUCKET", "")
+	if code, _ := run(); code != http.StatusInternalServerError {
+		t.Fatalf("unconfigured: expected 500, got %d", code)
+	}
+	var failed models.CronRun
+	if err := tx.Where("name = ?", SettlementExportCronName).Order("id DESC").First(&failed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != "Failed" || failed.Error == nil || !strings.Contains(*failed.Error, "SETTLEMENT_S3_BUCKET") {
+		t.Fatalf("expected a Failed run naming the bucket, got %+v", failed)
+	}
+}
@@ -0,0 +1,129 @@
+package admins
+
+import (
+	"net/http"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// SharedBankAccount is one bank and normalized account number registered by
+// more than one user.
+type SharedBankAccount struct {
+	BankID         uint                  `json:"bank_id"`
+	BankName       string                `json:"bank_name"`
+	AccountNumber  string                `json:"account_number"` // normalized
+	Users          int64                 `json:"users"`
+	TotalWithdrawn int64                 `json:"total_withdrawn"`
+	Accounts       []SharedAccountHolder `json:"accounts"`
+}
+
+// SharedAccountHolder is one user's registration of a shared account with
+// what they withdrew to it.
+type SharedAccountHolder struct {
+	BankAccountID uint   `json:"bank_account_id"`
+	UserID        uint   `json:"user_id"`
+	UserName      string `json:"user_name"`
+	Phone         string `json:"phone"`
+	AccountName   string `json:"account_name"`
+	AccountNumber string `json:"account_number"`
+	// Withdrawn sums Success withdrawals, Pending the Pending and On Hold ones
+	Withdrawn int64 `json:"withdrawn"`
+	Pending   int64 `json:"pending"`
+}
+
+// GET /api/admin/bank-accounts/shared?page=&limit=
+// Accounts registered by several users, most users first.
+func ListSharedBankAccounts(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	db := database.DB
+
+	groups := db.Model(&models.BankAccount{}).
+		Select("bank_id, account_number_normalized, COUNT(DISTINCT user_id) AS users").
+		Where("account_number_normalized <> ''").
+		Group("bank_id, account_number_normalized").
+		Having("COUNT(DISTINCT user_id) > 1")
+	var total int64
+	if err := db.Table("(?) AS g", groups).Count(&total).Error; err != nil {
+		utils.LogError(r, "ListSharedBankAccounts: count", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	type groupRow struct {
+		BankID                  uint
+		AccountNumberNormalized string
+		Users                   int64
+	}
+	var rows []groupRow
+	if err := groups.Session(&gorm.Session{}).
+		Order("users DESC, bank_id ASC, account_number_normalized ASC").
+		Offset(pg.Offset).Limit(pg.Limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "ListSharedBankAccounts: groups", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	result := make([]SharedBankAccount, 0, len(rows))
+	if len(rows) > 0 {
+		pairs := make([][]interface{}, 0, len(rows))
+		for _, g := range rows {
+			pairs = append(pairs, []interface{}{g.BankID, g.AccountNumberNormalized})
+		}
+		type holderRow struct {
+			SharedAccountHolder
+			BankID                  uint
+			BankName                string
+			AccountNumberNormalized string
+		}
+		var holders []holderRow
+		if err := db.Table("bank_accounts AS a").
+			Joins("JOIN users u ON u.id = a.user_id").
+			Joins("JOIN banks b ON b.id = a.bank_id").
+			Joins("LEFT JOIN withdrawals wd ON wd.bank_account_id = a.id").
+			Select("a.id AS bank_account_id, a.user_id, u.name AS user_name, u.number AS phone, a.account_name, a.account_number, "+
+				"a.bank_id, b.name AS bank_name, a.account_number_normalized, "+
+				"COALESCE(SUM(CASE WHEN wd.status = 'Success' THEN wd.amount END), 0) AS withdrawn, "+
+				"COALESCE(SUM(CASE WHEN wd.status IN ('Pending', ?) THEN wd.amount END), 0) AS pending", models.WithdrawalOnHold).
+			Where("(a.bank_id, a.account_number_normalized) IN ?", pairs).
+			Group("a.id, a.user_id, u.name, u.number, a.account_name, a.account_number, a.bank_id, b.name, a.account_number_normalized").
+			Order("a.user_id ASC, a.id ASC").
+			Scan(&holders).Error; err != nil {
+			utils.LogError(r, "ListSharedBankAccounts: holders", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+			return
+		}
+
+		type key struct {
+			bankID uint
+			number string
+		}
+		index := make(map[key]int, len(rows))
+		for _, g := range rows {
+			index[key{g.BankID, g.AccountNumberNormalized}] = len(result)
+			result = append(result, SharedBankAccount{BankID: g.BankID, AccountNumber: g.AccountNumberNormalized, Users: g.Users, Accounts: []SharedAccountHolder{}})
+		}
+		for _, h := range holders {
+			i, ok := index[key{h.BankID, h.AccountNumberNormalized}]
+			if !ok {
+				continue
+			}
+			result[i].BankName = h.BankName
+			result[i].TotalWithdrawn += h.Withdrawn
+			result[i].Accounts = append(result[i].Accounts, h.SharedAccountHolder)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(result, pg, total),
+	})
+}
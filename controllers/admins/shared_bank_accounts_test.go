@@ -0,0 +1,117 @@
+package admins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/controllers/users"
+	"project/database"
+	"project/models"
+	"project/risk"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestNormalizeAccountNumber(t *testing.T) {
+	for _, c := range []struct{ code, in, want string }{
+		{"BCA", "0012 3456-78", "12345678"},
+		{"BCA", "12345678", "12345678"},
+		{"DANA", "+62 812-3456-789", "8123456789"},
+		{"dana", "08123456789", "8123456789"},
+		{"BRI", "6212345", "6212345"},
+	} {
+		if got := models.NormalizeAccountNumber(c.code, c.in); got != c.want {
+			t.Errorf("NormalizeAccountNumber(%q, %q) = %q, want %q", c.code, c.in, got, c.want)
+		}
+	}
+}
+
+func TestSharedBankAccountHoldsWithdrawal(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	suffix := time.Now().UnixNano() % 1000000000
+
+	bank := models.Bank{Name: "Bank Bersama", Code: fmt.Sprintf("SB%d", suffix), Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for i := 0; i < 2; i++ {
+		user := testutil.NewUser(t, tx, models.User{Name: fmt.Sprintf("Mule %d", i)})
+		ids = append(ids, user.ID)
+	}
+	number := fmt.Sprintf("%09d", suffix)
+	for i, n := range []string{number, "00" + number} {
+		body := fmt.Sprintf(`{"bank_id":%d,"account_name":"Mule Satu","account_number":%q}`, bank.ID, n)
+		rec := httptest.NewRecorder()
+		users.AddBankAccountHandler(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/bank", strings.NewReader(body)), ids[i]))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("add: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	var accs []models.BankAccount
+	if err := tx.Where("bank_id = ?", bank.ID).Order("id ASC").Find(&accs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 2 || !accs[0].Shared || !accs[1].Shared {
+		t.Fatalf("expected both registrations flagged shared, got %+v", accs)
+	}
+
+	flags, err := risk.WithdrawalFlags(tx, ids[1], accs[1].ID)
+	if err != nil || !risk.Holds(flags) {
+		t.Fatalf("expected a holding flag, got %v, %v", flags, err)
+	}
+	wd := models.Withdrawal{UserID: ids[0], BankAccountID: accs[0].ID, Amount: 70000, FinalAmount: 70000, OrderID: fmt.Sprintf("SB-%d", suffix), Status: "Success"}
+	held := models.Withdrawal{UserID: ids[1], BankAccountID: accs[1].ID, Amount: 50000, FinalAmount: 50000, OrderID: fmt.Sprintf("SB-%d-h", suffix), Status: models.WithdrawalOnHold}
+	for _, w := range []*models.Withdrawal{&wd, &held} {
+		if err := tx.Create(w).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	ListSharedBankAccounts(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/bank-accounts/shared?limit=100", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("report: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Data []SharedBankAccount `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var found *SharedBankAccount
+	for i := range resp.Data.Data {
+		if resp.Data.Data[i].BankID == bank.ID {
+			found = &resp.Data.Data[i]
+		}
+	}
+	if found == nil || found.Users != 2 || found.TotalWithdrawn != 70000 || len(found.Accounts) != 2 || found.Accounts[1].Pending != 50000 {
+		t.Fatalf("expected the shared account with both users and their withdrawals, got %+v", found)
+	}
+
+	h := NewWithdrawalHandler(tx, nil)
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPut, "/v3/admin/withdrawals/x/release", nil), map[string]string{"id": fmt.Sprint(held.ID)})
+	req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+	rec = httptest.NewRecorder()
+	h.Release(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("release: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	tx.First(&held, held.ID)
+	if held.Status != "Pending" {
+		t.Fatalf("expected the released withdrawal Pending, got %s", held.Status)
+	}
+}
@@ -1,7 +1,6 @@
 package admins
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -60,6 +59,7 @@ func calculateChances(prizes []models.SpinPrize) []SpinPrizeResponse {
 func GetSpinPrizes(w http.ResponseWriter, r *http.Request) {
 	var prizes []models.SpinPrize
 	if err := database.DB.Find(&prizes).Error; err != nil {
+		utils.LogError(r, "GetSpinPrizes", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data hadiah spin",
@@ -113,11 +113,7 @@ func UpdateSpinPrize(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateSpinPrizeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -130,6 +126,7 @@ func UpdateSpinPrize(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		utils.LogError(r, "UpdateSpinPrize", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data hadiah",
@@ -154,6 +151,7 @@ func UpdateSpinPrize(w http.ResponseWriter, r *http.Request) {
 	// Get all prizes to calculate new chances
 	var allPrizes []models.SpinPrize
 	if err := database.DB.Find(&allPrizes).Error; err != nil {
+		utils.LogError(r, "UpdateSpinPrize", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data hadiah",
@@ -0,0 +1,419 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/models"
+	"project/notify"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ticketImageURLExpiry is how long presigned attachment URLs stay valid.
+const ticketImageURLExpiry = 60 * 60
+
+// SupportHandler serves support ticket handling and canned responses for
+// admins. Answering or closing a ticket notifies its owner.
+type SupportHandler struct {
+	DB *gorm.DB
+	// Notifier receives push events after commits; nil disables them
+	Notifier *notify.Notifier
+}
+
+func NewSupportHandler(db *gorm.DB) *SupportHandler {
+	return &SupportHandler{DB: db}
+}
+
+type assignTicketRequest struct {
+	AdminID *int64 `json:"admin_id"`
+}
+
+type ticketReplyRequest struct {
+	Message          string `json:"message" validate:"max=2000"`
+	CannedResponseID *uint  `json:"canned_response_id"`
+	// Close closes the ticket with this reply instead of marking it Answered
+	Close bool `json:"close"`
+}
+
+type cannedResponseRequest struct {
+	Title string `json:"title" validate:"required,max=100"`
+	Body  string `json:"body" validate:"required,max=2000"`
+}
+
+type ticketMessageResponse struct {
+	models.TicketMessage
+	ImageURL *string `json:"image_url"`
+}
+
+// GET /api/admin/tickets?status=&category=&assigned_to=&user_id=&search=
+// assigned_to=0 lists unassigned tickets; search matches the subject prefix.
+// Waiting tickets come first, oldest activity first, so the queue reads top-down.
+func (h *SupportHandler) List(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	q := r.URL.Query()
+
+	query := h.DB.Model(&models.SupportTicket{}).
+		Joins("JOIN users ON support_tickets.user_id = users.id")
+	if status := q.Get("status"); status == "Open" || status == "Answered" || status == "Closed" {
+		query = query.Where("support_tickets.status = ?", status)
+	}
+	if category := q.Get("category"); slices.Contains(models.TicketCategories, category) {
+		query = query.Where("support_tickets.category = ?", category)
+	}
+	if assigned := q.Get("assigned_to"); assigned == "0" {
+		query = query.Where("support_tickets.assigned_to IS NULL")
+	} else if id, err := strconv.ParseInt(assigned, 10, 64); err == nil {
+		query = query.Where("support_tickets.assigned_to = ?", id)
+	}
+	if userID, err := strconv.ParseUint(q.Get("user_id"), 10, 64); err == nil {
+		query = query.Where("support_tickets.user_id = ?", userID)
+	}
+	if search := strings.TrimSpace(q.Get("search")); search != "" {
+		query = query.Where("support_tickets.subject LIKE ?", utils.PrefixLike(search))
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "SupportHandler.List", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	type ticketWithUser struct {
+		models.SupportTicket
+		UserName string `json:"user_name"`
+		Phone    string `json:"phone"`
+	}
+	tickets := make([]ticketWithUser, 0)
+	if err := query.Select("support_tickets.*, users.name as user_name, users.number as phone").
+		Order("FIELD(support_tickets.status, 'Open', 'Answered', 'Closed'), support_tickets.last_message_at ASC").
+		Offset(pg.Offset).
+		Limit(pg.Limit).
+		Find(&tickets).Error; err != nil {
+		utils.LogError(r, "SupportHandler.List", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    utils.NewPaginated(tickets, pg, totalRows),
+	})
+}
+
+// GET /api/admin/tickets/{id}
+func (h *SupportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := h.loadTicket(w, r)
+	if !ok {
+		return
+	}
+
+	var msgs []models.TicketMessage
+	if err := h.DB.Where("ticket_id = ?", ticket.ID).Order("id ASC").Find(&msgs).Error; err != nil {
+		utils.LogError(r, "SupportHandler.Get", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	resp := make([]ticketMessageResponse, 0, len(msgs))
+	for _, m := range msgs {
+		item := ticketMessageResponse{TicketMessage: m}
+		if m.Image != nil {
+			if url, err := utils.ObjectURL(*m.Image, ticketImageURLExpiry); err != nil {
+				utils.LogError(r, "SupportHandler.Get: image url", err, "message_id", m.ID)
+			} else {
+				item.ImageURL = &url
+			}
+		}
+		resp = append(resp, item)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    map[string]interface{}{"ticket": ticket, "messages": resp},
+	})
+}
+
+// PUT /api/admin/tickets/{id}/assign
+// {"admin_id": null} unassigns; omitting the body assigns the caller.
+func (h *SupportHandler) Assign(w http.ResponseWriter, r *http.Request) {
+	var req assignTicketRequest
+	if r.ContentLength != 0 {
+		if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+			return
+		}
+	} else if adminID, ok := utils.GetAdminID(r); ok {
+		req.AdminID = &adminID
+	}
+
+	ticket, ok := h.loadTicket(w, r)
+	if !ok {
+		return
+	}
+	if req.AdminID != nil {
+		var count int64
+		if err := h.DB.Model(&models.Admin{}).Where("id = ? AND is_active = ?", *req.AdminID, true).Count(&count).Error; err != nil {
+			utils.LogError(r, "SupportHandler.Assign", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		if count == 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Admin tidak ditemukan"})
+			return
+		}
+	}
+
+	before := ticket
+	ticket.AssignedTo = req.AdminID
+	if err := h.DB.Model(&ticket).Update("assigned_to", req.AdminID).Error; err != nil {
+		utils.LogError(r, "SupportHandler.Assign", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate tiket"})
+		return
+	}
+	auditLog(r, "ticket.assign", before, ticket)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Tiket berhasil ditugaskan", Data: ticket})
+}
+
+// POST /api/admin/tickets/{id}/messages
+// Sends message, or the canned response's body when message is empty, and
+// marks the ticket Answered (or Closed with "close": true). Unassigned tickets
+// are assigned to the replying admin.
+func (h *SupportHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	var req ticketReplyRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	body := strings.TrimSpace(req.Message)
+	if body == "" && req.CannedResponseID != nil {
+		var canned models.CannedResponse
+		if err := h.DB.First(&canned, *req.CannedResponseID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Template balasan tidak ditemukan"})
+				return
+			}
+			utils.LogError(r, "SupportHandler.Reply", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		body = canned.Body
+	}
+	if body == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Pesan atau template balasan wajib diisi", Code: utils.CodeValidationFailed, Errors: map[string]string{"message": "wajib diisi"}})
+		return
+	}
+
+	ticket, ok := h.loadTicket(w, r)
+	if !ok {
+		return
+	}
+	adminID, _ := utils.GetAdminID(r)
+	status := "Answered"
+	if req.Close {
+		status = "Closed"
+	}
+
+	msg := models.TicketMessage{TicketID: ticket.ID, SenderType: "admin", SenderID: adminID, Body: body}
+	before := ticket
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticket, ticket.ID).Error; err != nil {
+			return err
+		}
+		if ticket.Status == "Closed" {
+			return errTicketClosed
+		}
+		if err := tx.Create(&msg).Error; err != nil {
+			return err
+		}
+		ticket.Status = status
+		ticket.LastMessageAt = msg.CreatedAt
+		if ticket.AssignedTo == nil && adminID != 0 {
+			ticket.AssignedTo = &adminID
+		}
+		if status == "Closed" {
+			ticket.ClosedAt = &msg.CreatedAt
+		}
+		if err := tx.Save(&ticket).Error; err != nil {
+			return err
+		}
+		return createTicketNotification(tx, ticket)
+	})
+	if errors.Is(err, errTicketClosed) {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodeTicketClosed)
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "SupportHandler.Reply", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengirim balasan"})
+		return
+	}
+	h.Notifier.Enqueue(notify.TicketStatus(ticket.UserID, ticket.ID, ticket.Subject, ticket.Status))
+	auditLog(r, "ticket.reply", before, ticket)
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Balasan terkirim", Data: map[string]interface{}{"ticket": ticket, "message": msg}})
+}
+
+// PUT /api/admin/tickets/{id}/close
+func (h *SupportHandler) Close(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := h.loadTicket(w, r)
+	if !ok {
+		return
+	}
+
+	before := ticket
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticket, ticket.ID).Error; err != nil {
+			return err
+		}
+		if ticket.Status == "Closed" {
+			return errTicketClosed
+		}
+		now := time.Now()
+		ticket.Status = "Closed"
+		ticket.ClosedAt = &now
+		if err := tx.Save(&ticket).Error; err != nil {
+			return err
+		}
+		return createTicketNotification(tx, ticket)
+	})
+	if errors.Is(err, errTicketClosed) {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodeTicketClosed)
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "SupportHandler.Close", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menutup tiket"})
+		return
+	}
+	h.Notifier.Enqueue(notify.TicketStatus(ticket.UserID, ticket.ID, ticket.Subject, ticket.Status))
+	auditLog(r, "ticket.close", before, ticket)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Tiket berhasil ditutup", Data: ticket})
+}
+
+// GET /api/admin/canned-responses
+func (h *SupportHandler) ListCannedResponses(w http.ResponseWriter, r *http.Request) {
+	responses := make([]models.CannedResponse, 0)
+	if err := h.DB.Order("title ASC").Find(&responses).Error; err != nil {
+		utils.LogError(r, "SupportHandler.ListCannedResponses", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: responses})
+}
+
+// POST /api/admin/canned-responses
+func (h *SupportHandler) CreateCannedResponse(w http.ResponseWriter, r *http.Request) {
+	var req cannedResponseRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	canned := models.CannedResponse{Title: strings.TrimSpace(req.Title), Body: strings.TrimSpace(req.Body)}
+	if err := h.DB.Create(&canned).Error; err != nil {
+		utils.LogError(r, "SupportHandler.CreateCannedResponse", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat template balasan"})
+		return
+	}
+	auditLogTarget(r, "canned_response.create", "canned_response", canned.ID, nil, canned)
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Template balasan berhasil dibuat", Data: canned})
+}
+
+// PUT /api/admin/canned-responses/{id}
+func (h *SupportHandler) UpdateCannedResponse(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req cannedResponseRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	var canned models.CannedResponse
+	if err := h.DB.First(&canned, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Template balasan tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "SupportHandler.UpdateCannedResponse", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	before := canned
+	canned.Title = strings.TrimSpace(req.Title)
+	canned.Body = strings.TrimSpace(req.Body)
+	if err := h.DB.Save(&canned).Error; err != nil {
+		utils.LogError(r, "SupportHandler.UpdateCannedResponse", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate template balasan"})
+		return
+	}
+	auditLog(r, "canned_response.update", before, canned)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Template balasan berhasil diupdate", Data: canned})
+}
+
+// DELETE /api/admin/canned-responses/{id}
+func (h *SupportHandler) DeleteCannedResponse(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	res := h.DB.Delete(&models.CannedResponse{}, id)
+	if res.Error != nil {
+		utils.LogError(r, "SupportHandler.DeleteCannedResponse", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus template balasan"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Template balasan tidak ditemukan"})
+		return
+	}
+	auditLog(r, "canned_response.delete", map[string]interface{}{"id": id}, nil)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Template balasan berhasil dihapus"})
+}
+
+var errTicketClosed = errors.New("ticket closed")
+
+// loadTicket fetches the {id} ticket, writing the error response when it cannot.
+func (h *SupportHandler) loadTicket(w http.ResponseWriter, r *http.Request) (models.SupportTicket, bool) {
+	var ticket models.SupportTicket
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return ticket, false
+	}
+	if err := h.DB.First(&ticket, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Tiket tidak ditemukan", Code: utils.CodeTicketNotFound})
+			return ticket, false
+		}
+		utils.LogError(r, "SupportHandler.loadTicket", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return ticket, false
+	}
+	return ticket, true
+}
+
+// createTicketNotification adds the in-app notification for the ticket's
+// current status, in the owner's locale.
+func createTicketNotification(tx *gorm.DB, ticket models.SupportTicket) error {
+	locale, err := notify.UserLocale(tx, ticket.UserID)
+	if err != nil {
+		return err
+	}
+	n := notify.Inbox(notify.TicketStatus(ticket.UserID, ticket.ID, ticket.Subject, ticket.Status), locale, "support_ticket")
+	return tx.Create(&n).Error
+}
@@ -1,12 +1,10 @@
 package admins
 
 import (
-	"encoding/json"
 	"net/http"
 	"project/database"
 	"project/models"
 	"project/utils"
-	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -19,6 +17,7 @@ func TaskListHandler(w http.ResponseWriter, r *http.Request) {
 	// 1) Ambil semua tasks
 	var tasks []models.Task
 	if err := db.Find(&tasks).Error; err != nil {
+		utils.LogError(r, "TaskListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -29,6 +28,7 @@ func TaskListHandler(w http.ResponseWriter, r *http.Request) {
 	// 2) Hitung total_claimed global (jumlah seluruh user_tasks)
 	var totalClaimed int64
 	if err := db.Model(&models.UserTask{}).Count(&totalClaimed).Error; err != nil {
+		utils.LogError(r, "TaskListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -117,11 +117,7 @@ type TaskRequest struct {
 // POST /api/admin/tasks
 func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var req TaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -135,6 +131,7 @@ func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	db := database.DB
 	if err := db.Create(&task).Error; err != nil {
+		utils.LogError(r, "CreateTaskHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -155,11 +152,7 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	taskID := vars["id"]
 
 	var req TaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid request body",
-		})
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
 		return
 	}
 
@@ -181,6 +174,7 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	task.Status = req.Status
 
 	if err := db.Save(&task).Error; err != nil {
+		utils.LogError(r, "UpdateTaskHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -200,16 +194,12 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
 
 	// Pagination (optional)
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	search := r.URL.Query().Get("search")
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
-	offset := (page - 1) * limit
+	search := r.URL.Query().Get("search")
 
 	// Build base queries with joins
 	query := db.
@@ -232,6 +222,7 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 	// Total data (untuk pagination)
 	var total int64
 	if err := countQuery.Count(&total).Error; err != nil {
+		utils.LogError(r, "UserTasksHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal menghitung total data",
@@ -264,9 +255,10 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 			ut.claimed_at
 		`).
 		Order("ut.claimed_at DESC").
-		Offset(offset).
-		Limit(limit).
+		Offset(pg.Offset).
+		Limit(pg.Limit).
 		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "UserTasksHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -303,6 +295,6 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    items,
+		Data:    utils.NewPaginated(items, pg, total),
 	})
 }
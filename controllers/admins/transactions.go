@@ -1,134 +1,264 @@
 package admins
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"project/database"
 	"project/models"
 	"project/utils"
+
+	"gorm.io/gorm"
 )
 
+// transactionExportFlushRows is how many CSV rows are buffered before a flush.
+const transactionExportFlushRows = 500
+
 type TransactionResponse struct {
-	ID              uint    `json:"id"`
-	UserID          uint    `json:"user_id"`
-	UserName        string  `json:"username"`
-	Phone           string  `json:"phone"`
-	Amount          float64 `json:"amount"`
-	Charge          float64 `json:"charge"`
-	OrderID         string  `json:"order_id"`
-	TransactionFlow string  `json:"transaction_flow"`
-	TransactionType string  `json:"transaction_type"`
-	Message         string  `json:"message"`
-	Status          string  `json:"status"`
-	CreatedAt       string  `json:"created_at"`
+	ID              uint   `json:"id"`
+	UserID          uint   `json:"user_id"`
+	UserName        string `json:"username"`
+	Phone           string `json:"phone"`
+	Amount          int64  `json:"amount"`
+	Charge          int64  `json:"charge"`
+	OrderID         string `json:"order_id"`
+	TransactionFlow string `json:"transaction_flow"`
+	TransactionType string `json:"transaction_type"`
+	Message         string `json:"message"`
+	Status          string `json:"status"`
+	CreatedAt       string `json:"created_at"`
 }
 
-func GetTransactions(w http.ResponseWriter, r *http.Request) {
-	// Get query parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	userId := r.URL.Query().Get("userId")
-	transactionType := r.URL.Query().Get("type")
-	status := r.URL.Query().Get("status")
-	orderID := r.URL.Query().Get("search")
-	startDate := r.URL.Query().Get("start_date")
-	endDate := r.URL.Query().Get("end_date")
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
-	}
+// TransactionTotals sums the whole filtered set, not just the current page.
+// Debit is money into user balances, credit money out.
+type TransactionTotals struct {
+	Count        int64 `json:"count"`
+	Amount       int64 `json:"amount"`
+	Charge       int64 `json:"charge"`
+	DebitAmount  int64 `json:"debit_amount"`
+	CreditAmount int64 `json:"credit_amount"`
+}
 
-	offset := (page - 1) * limit
+type transactionPage struct {
+	utils.Paginated[TransactionResponse]
+	Totals TransactionTotals `json:"totals"`
+}
 
-	// Start query
-	db := database.DB
-	query := db.Model(&models.Transaction{})
+// transactionRow is a transaction joined with its owner's name and phone.
+type transactionRow struct {
+	models.Transaction
+	UserName string
+	Phone    string
+}
 
-	// Apply filters
-	if userId != "" {
-		query = query.Where("user_id = ?", userId)
+func (t transactionRow) response() TransactionResponse {
+	return TransactionResponse{
+		ID:              t.ID,
+		UserID:          t.UserID,
+		UserName:        t.UserName,
+		Phone:           t.Phone,
+		Amount:          t.Amount,
+		Charge:          t.Charge,
+		OrderID:         t.OrderID,
+		TransactionFlow: t.TransactionFlow,
+		TransactionType: t.TransactionType,
+		Message:         utils.GetStringValue(t.Message),
+		Status:          t.Status,
+		CreatedAt:       utils.FormatTime(t.CreatedAt),
 	}
-	if transactionType != "" {
-		query = query.Where("transaction_type = ?", transactionType)
+}
+
+// GET /api/admin/transactions
+// Filters: user_id, type, flow, status, order_id (prefix), min_amount,
+// max_amount, start_date/end_date (creation date, APP_TIMEZONE). The older
+// userId and search parameters are still accepted. Totals cover every match.
+func (h *ReportHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
-	if status != "" {
-		query = query.Where("status = ?", status)
+	query, msg := filterTransactions(h.db(), r)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
 	}
 
-	if orderID != "" {
-		query = query.Where("transactions.order_id LIKE ?", "%"+orderID+"%")
+	var totals TransactionTotals
+	if err := query.Session(&gorm.Session{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(t.amount), 0) AS amount, COALESCE(SUM(t.charge), 0) AS charge, " +
+			"COALESCE(SUM(CASE WHEN t.transaction_flow = 'debit' THEN t.amount END), 0) AS debit_amount, " +
+			"COALESCE(SUM(CASE WHEN t.transaction_flow = 'credit' THEN t.amount END), 0) AS credit_amount").
+		Scan(&totals).Error; err != nil {
+		utils.LogError(r, "GetTransactions", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
 	}
 
-	// Apply date filters if provided
-	jakartaLoc, _ := time.LoadLocation("Asia/Jakarta")
-	if startDate != "" {
-		startTime, err := time.ParseInLocation("2006-01-02", startDate, jakartaLoc)
-		if err == nil {
-			query = query.Where("created_at >= ?", startTime)
-		}
+	var rows []transactionRow
+	if err := query.Select("t.*, u.name AS user_name, u.number AS phone").
+		Order("t.created_at DESC, t.id DESC").
+		Offset(pg.Offset).Limit(pg.Limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "GetTransactions", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
 	}
-	if endDate != "" {
-		endTime, err := time.ParseInLocation("2006-01-02", endDate, jakartaLoc)
-		if err == nil {
-			// Add one day to get to the start of the next day in Jakarta time
-			endTime = endTime.AddDate(0, 0, 1)
-			query = query.Where("created_at < ?", endTime)
-		}
+
+	response := make([]TransactionResponse, len(rows))
+	for i, t := range rows {
+		response[i] = t.response()
 	}
 
-	var transactions []models.Transaction
-	query.Offset(offset).
-		Limit(limit).
-		Order("created_at DESC").
-		Find(&transactions)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    transactionPage{Paginated: utils.NewPaginated(response, pg, totals.Count), Totals: totals},
+	})
+}
 
-	// Prepare user IDs to fetch names and phones in batch
-	userIDsSet := make(map[uint]struct{})
-	for _, t := range transactions {
-		userIDsSet[t.UserID] = struct{}{}
+// GET /api/admin/transactions/export
+// Streams every transaction matching the GetTransactions filters as CSV,
+// oldest first, without loading the set into memory.
+func (h *ReportHandler) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	db := h.db()
+	query, msg := filterTransactions(db, r)
+	if msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
 	}
-	var userIDs []uint
-	for id := range userIDsSet {
-		userIDs = append(userIDs, id)
+	rows, err := query.Select("t.*, u.name AS user_name, u.number AS phone").
+		Order("t.created_at ASC, t.id ASC").
+		Rows()
+	if err != nil {
+		utils.LogError(r, "ExportTransactions", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
 	}
+	defer rows.Close()
 
-	// Fetch users and build a map[id]user
-	usersByID := make(map[uint]models.User, len(userIDs))
-	if len(userIDs) > 0 {
-		var users []models.User
-		db.Select("id, name, number").Where("id IN ?", userIDs).Find(&users)
-		for _, u := range users {
-			usersByID[u.ID] = u
+	amount := func(v int64) string { return strconv.FormatInt(v, 10) }
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=transactions-%s.csv", time.Now().In(utils.AppLocation()).Format("20060102-150405")))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"id", "created_at", "user_id", "username", "phone", "order_id", "transaction_type", "transaction_flow", "status", "amount", "charge", "message"})
+	n := 0
+	for rows.Next() {
+		var t transactionRow
+		if err := db.ScanRows(rows, &t); err != nil {
+			// Headers are gone; the truncated file is all we can signal
+			utils.LogError(r, "ExportTransactions", err, "rows", n)
+			break
 		}
-	}
-
-	// Transform to response format
-	var response []TransactionResponse
-	for _, t := range transactions {
-		response = append(response, TransactionResponse{
-			ID:              t.ID,
-			UserID:          t.UserID,
-			UserName:        usersByID[t.UserID].Name,
-			Phone:           usersByID[t.UserID].Number,
-			Amount:          t.Amount,
-			Charge:          t.Charge,
-			OrderID:         t.OrderID,
-			TransactionFlow: t.TransactionFlow,
-			TransactionType: t.TransactionType,
-			Message:         utils.GetStringValue(t.Message),
-			Status:          t.Status,
-			CreatedAt:       t.CreatedAt.Format(time.RFC3339),
+		_ = cw.Write([]string{
+			strconv.FormatUint(uint64(t.ID), 10),
+			utils.FormatTime(t.CreatedAt),
+			strconv.FormatUint(uint64(t.UserID), 10),
+			t.UserName,
+			t.Phone,
+			t.OrderID,
+			t.TransactionType,
+			t.TransactionFlow,
+			t.Status,
+			amount(t.Amount),
+			amount(t.Charge),
+			utils.GetStringValue(t.Message),
 		})
+		if n++; n%transactionExportFlushRows == 0 {
+			cw.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		utils.LogError(r, "ExportTransactions", err, "rows", n)
 	}
+	cw.Flush()
+}
 
+// POST /api/admin/transactions/fail-stale
+// One-off backfill: marks Failed every Pending investment transaction whose
+// payment expired, as the payment-expiry cron now does on each run, and
+// reports how many it fixed. Safe to run again; it then fixes none.
+func FailStaleTransactions(w http.ResponseWriter, r *http.Request) {
+	failed, err := models.FailExpiredInvestmentTransactions(database.DB, time.Now())
+	if err != nil {
+		utils.LogError(r, "FailStaleTransactions", err, "failed", failed)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if failed > 0 {
+		auditLog(r, "transaction.fail_stale", nil, map[string]interface{}{"fixed": failed})
+	}
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data:    map[string]interface{}{"fixed": failed},
 	})
 }
+
+// filterTransactions builds on db the transactions query (aliased t, joined with
+// users as u) from the request filters. It returns a user-facing message for
+// bad input.
+func filterTransactions(db *gorm.DB, r *http.Request) (*gorm.DB, string) {
+	q := r.URL.Query()
+	param := func(name, legacy string) string {
+		if v := strings.TrimSpace(q.Get(name)); v != "" {
+			return v
+		}
+		return strings.TrimSpace(q.Get(legacy))
+	}
+
+	query := db.Table("transactions AS t").Joins("LEFT JOIN users u ON u.id = t.user_id")
+	if v := param("user_id", "userId"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, "user_id tidak valid"
+		}
+		query = query.Where("t.user_id = ?", id)
+	}
+	if v := q.Get("type"); v != "" {
+		query = query.Where("t.transaction_type = ?", v)
+	}
+	if v := q.Get("flow"); v != "" {
+		if v != "debit" && v != "credit" {
+			return nil, "flow harus debit atau credit"
+		}
+		query = query.Where("t.transaction_flow = ?", v)
+	}
+	if v := q.Get("status"); v != "" {
+		query = query.Where("t.status = ?", v)
+	}
+	if v := param("order_id", "search"); v != "" {
+		query = query.Where("t.order_id LIKE ?", utils.PrefixLike(v))
+	}
+	for _, f := range []struct{ name, cond string }{{"min_amount", "t.amount >= ?"}, {"max_amount", "t.amount <= ?"}} {
+		if v := q.Get(f.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, f.name + " harus berupa angka"
+			}
+			query = query.Where(f.cond, n)
+		}
+	}
+
+	// Dates are whole days in the app timezone; end_date is inclusive
+	appLoc := utils.AppLocation()
+	if v := q.Get("start_date"); v != "" {
+		start, err := time.ParseInLocation("2006-01-02", v, appLoc)
+		if err != nil {
+			return nil, "Format start_date harus YYYY-MM-DD"
+		}
+		query = query.Where("t.created_at >= ?", start)
+	}
+	if v := q.Get("end_date"); v != "" {
+		end, err := time.ParseInLocation("2006-01-02", v, appLoc)
+		if err != nil {
+			return nil, "Format end_date harus YYYY-MM-DD"
+		}
+		query = query.Where("t.created_at < ?", end.AddDate(0, 0, 1))
+	}
+	return query, ""
+}
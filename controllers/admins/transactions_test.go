@@ -0,0 +1,75 @@
+package admins
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+)
+
+func TestAdminTransactionBrowser(t *testing.T) {
+	tx := testutil.Tx(t)
+	reports := NewReportHandler(database.NewReadReplica(tx, nil))
+
+	suffix := time.Now().UnixNano() % 1000000000
+	user := testutil.NewUser(t, tx, models.User{Name: "Keuangan"})
+	for i, row := range []struct {
+		amount int64
+		flow   string
+		typ    string
+	}{{100000, "credit", "investment"}, {5000, "debit", "return"}, {7000, "debit", "return"}, {50000, "credit", "withdrawal"}} {
+		trx := models.Transaction{UserID: user.ID, Amount: row.amount, OrderID: fmt.Sprintf("TB-%d-%d", suffix, i), TransactionFlow: row.flow, TransactionType: row.typ, Status: "Success"}
+		if err := tx.Create(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	reports.GetTransactions(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/admin/transactions?user_id=%d&min_amount=6000&limit=2", user.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Data   []TransactionResponse `json:"data"`
+			Totals TransactionTotals     `json:"totals"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := TransactionTotals{Count: 3, Amount: 157000, DebitAmount: 7000, CreditAmount: 150000}
+	if resp.Data.Totals != want {
+		t.Fatalf("expected totals %+v, got %+v", want, resp.Data.Totals)
+	}
+	if len(resp.Data.Data) != 2 || resp.Data.Data[0].Phone != user.Number {
+		t.Fatalf("expected a page of 2 rows with the user's phone, got %+v", resp.Data.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	reports.GetTransactions(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/transactions?flow=sideways", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad flow: expected 400, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	reports.ExportTransactions(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/admin/transactions/export?user_id=%d&type=return", user.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	records, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1][9] != "5000" || records[2][9] != "7000" {
+		t.Fatalf("expected a header and the two returns oldest first, got %v", records)
+	}
+}
@@ -0,0 +1,184 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// UserDeviceSeen is one fingerprint, user agent and IP a user registered or
+// logged in from, with when it was first and last seen.
+type UserDeviceSeen struct {
+	DeviceFingerprint string `json:"device_fingerprint"`
+	UserAgent         string `json:"user_agent"`
+	IP                string `json:"ip"`
+	Registered        bool   `json:"registered"`
+	Logins            int64  `json:"logins"`
+	FirstSeenAt       string `json:"first_seen_at"`
+	LastSeenAt        string `json:"last_seen_at"`
+	// SharedAccounts counts the other users seen on the fingerprint
+	SharedAccounts int64 `json:"shared_accounts"`
+}
+
+// LinkedAccount is another user sharing a device fingerprint or the
+// registration IP with the user under review.
+type LinkedAccount struct {
+	UserID    uint   `json:"user_id"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone"`
+	Status    string `json:"status"`
+	MatchedBy string `json:"matched_by"` // "device" or "registration_ip"
+	Value     string `json:"value"`
+	CreatedAt string `json:"created_at"`
+}
+
+// GET /api/admin/users/{id}/devices
+// The user's register and login signals grouped by fingerprint, user agent
+// and IP, most recently seen first.
+func GetUserDevices(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "User tidak valid"})
+		return
+	}
+	if !userExists(w, r, id) {
+		return
+	}
+
+	type deviceRow struct {
+		DeviceFingerprint *string
+		UserAgent         string
+		IP                string
+		Registered        bool
+		Logins            int64
+		FirstSeenAt       time.Time
+		LastSeenAt        time.Time
+	}
+	var rows []deviceRow
+	if err := database.DB.Model(&models.UserSignal{}).
+		Select("device_fingerprint, user_agent, ip, MAX(event = ?) AS registered, COALESCE(SUM(event = ?), 0) AS logins, MIN(created_at) AS first_seen_at, MAX(created_at) AS last_seen_at", models.UserSignalRegister, models.UserSignalLogin).
+		Where("user_id = ?", id).
+		Group("device_fingerprint, user_agent, ip").
+		Order("last_seen_at DESC").
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "GetUserDevices", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	type sharedRow struct {
+		DeviceFingerprint string
+		Accounts          int64
+	}
+	var shared []sharedRow
+	if err := database.DB.Table("user_signals AS a").
+		Joins("JOIN user_signals AS b ON b.device_fingerprint = a.device_fingerprint AND b.user_id <> a.user_id").
+		Select("a.device_fingerprint, COUNT(DISTINCT b.user_id) AS accounts").
+		Where("a.user_id = ? AND a.device_fingerprint IS NOT NULL", id).
+		Group("a.device_fingerprint").
+		Scan(&shared).Error; err != nil {
+		utils.LogError(r, "GetUserDevices: shared", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	sharedBy := make(map[string]int64, len(shared))
+	for _, s := range shared {
+		sharedBy[s.DeviceFingerprint] = s.Accounts
+	}
+
+	devices := make([]UserDeviceSeen, 0, len(rows))
+	for _, row := range rows {
+		fp := utils.GetStringValue(row.DeviceFingerprint)
+		devices = append(devices, UserDeviceSeen{
+			DeviceFingerprint: fp,
+			UserAgent:         row.UserAgent,
+			IP:                row.IP,
+			Registered:        row.Registered,
+			Logins:            row.Logins,
+			FirstSeenAt:       utils.FormatTime(row.FirstSeenAt),
+			LastSeenAt:        utils.FormatTime(row.LastSeenAt),
+			SharedAccounts:    sharedBy[fp],
+		})
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: devices})
+}
+
+// GET /api/admin/users/{id}/linked-accounts
+// Other users that registered or logged in from one of the user's device
+// fingerprints, or registered from the IP the user registered from.
+func GetLinkedAccounts(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "User tidak valid"})
+		return
+	}
+	if !userExists(w, r, id) {
+		return
+	}
+
+	type linkRow struct {
+		UserID    uint
+		Name      string
+		Phone     string
+		Status    string
+		MatchedBy string
+		Value     string
+		CreatedAt time.Time
+	}
+	var rows []linkRow
+	if err := database.DB.Raw(`SELECT DISTINCT u.id AS user_id, u.name, u.number AS phone, u.status, l.matched_by, l.value, u.created_at
+		FROM (
+			SELECT b.user_id, 'device' AS matched_by, a.device_fingerprint AS value
+			FROM user_signals a
+			JOIN user_signals b ON b.device_fingerprint = a.device_fingerprint
+			WHERE a.user_id = ? AND a.device_fingerprint IS NOT NULL
+			UNION
+			SELECT b.user_id, 'registration_ip', a.ip
+			FROM user_signals a
+			JOIN user_signals b ON b.ip = a.ip AND b.event = ?
+			WHERE a.user_id = ? AND a.event = ?
+		) l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.user_id <> ?
+		ORDER BY u.id ASC, l.matched_by ASC`, id, models.UserSignalRegister, id, models.UserSignalRegister, id).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "GetLinkedAccounts", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	linked := make([]LinkedAccount, 0, len(rows))
+	for _, row := range rows {
+		linked = append(linked, LinkedAccount{
+			UserID:    row.UserID,
+			Name:      row.Name,
+			Phone:     row.Phone,
+			Status:    row.Status,
+			MatchedBy: row.MatchedBy,
+			Value:     row.Value,
+			CreatedAt: utils.FormatTime(row.CreatedAt),
+		})
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: linked})
+}
+
+// userExists writes a 404 or 500 and returns false unless user id exists.
+func userExists(w http.ResponseWriter, r *http.Request, id uint) bool {
+	var user models.User
+	if err := database.DB.Select("id").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User tidak ditemukan", Code: utils.CodeUserNotFound})
+			return false
+		}
+		utils.LogError(r, "load user", err, "user_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package admins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/risk"
+	"project/testutil"
+
+	"github.com/gorilla/mux"
+)
+
+func TestDeviceHistoryAndSharedDeviceRisk(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	suffix := time.Now().UnixNano() % 1000000000
+
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		user := testutil.NewUser(t, tx, models.User{Name: fmt.Sprintf("Perangkat %d", i)})
+		ids = append(ids, user.ID)
+	}
+	fp := fmt.Sprintf("fp-%d", suffix)
+	regIP := fmt.Sprintf("10.9.%d.%d", suffix%250, suffix/250%250)
+	for _, s := range []struct {
+		user  uint
+		event string
+		ip    string
+		fp    string
+		agent string
+	}{
+		{ids[0], models.UserSignalRegister, regIP, fp, "app/1.0"},
+		{ids[0], models.UserSignalLogin, "10.8.0.1", fp, "app/1.0"},
+		{ids[0], models.UserSignalLogin, "10.8.0.1", fp, "app/1.0"},
+		{ids[1], models.UserSignalLogin, "10.8.0.2", fp, "app/1.1"},
+		{ids[2], models.UserSignalRegister, regIP, "", "browser"},
+	} {
+		if err := models.RecordUserSignal(tx, s.user, s.event, s.ip, s.fp, s.agent); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(handler http.HandlerFunc, id uint, out interface{}) {
+		t.Helper()
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v3/admin/users/x", nil), map[string]string{"id": fmt.Sprint(id)})
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &struct {
+			Data interface{} `json:"data"`
+		}{out}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var devices []UserDeviceSeen
+	get(GetUserDevices, ids[0], &devices)
+	if len(devices) != 2 {
+		t.Fatalf("expected the register and login IPs as two rows, got %+v", devices)
+	}
+	for _, d := range devices {
+		if d.DeviceFingerprint != fp || d.SharedAccounts != 1 {
+			t.Fatalf("expected fingerprint %s shared with 1 account, got %+v", fp, d)
+		}
+		if d.IP == "10.8.0.1" && (d.Logins != 2 || d.Registered) {
+			t.Fatalf("expected 2 logins from 10.8.0.1, got %+v", d)
+		}
+	}
+
+	var linked []LinkedAccount
+	get(GetLinkedAccounts, ids[0], &linked)
+	if len(linked) != 2 || linked[0].UserID != ids[1] || linked[0].MatchedBy != "device" || linked[1].UserID != ids[2] || linked[1].MatchedBy != "registration_ip" {
+		t.Fatalf("expected the device and registration IP matches, got %+v", linked)
+	}
+
+	t.Setenv("WITHDRAWAL_RISK_SHARED_DEVICE_ACCOUNTS", "2")
+	if flags, err := risk.WithdrawalFlags(tx, ids[0], 0); err != nil || len(flags) != 1 || flags[0] != risk.FlagSharedDevice {
+		t.Fatalf("expected shared_device, got %v, %v", flags, err)
+	}
+	if flags, err := risk.WithdrawalFlags(tx, ids[2], 0); err != nil || len(flags) != 0 {
+		t.Fatalf("expected no flags without a fingerprint, got %v, %v", flags, err)
+	}
+	t.Setenv("WITHDRAWAL_RISK_SHARED_DEVICE_ACCOUNTS", "3")
+	if flags, err := risk.WithdrawalFlags(tx, ids[0], 0); err != nil || len(flags) != 0 {
+		t.Fatalf("expected no flags below the threshold, got %v, %v", flags, err)
+	}
+}
@@ -2,7 +2,6 @@ package admins
 
 import (
 	"net/http"
-	"strconv"
 	"time"
 
 	"project/database"
@@ -15,16 +14,12 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
 
 	// Pagination
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	search := r.URL.Query().Get("search")
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
-	offset := (page - 1) * limit
+	search := r.URL.Query().Get("search")
 
 	// Base queries
 	query := db.
@@ -44,9 +39,20 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 		countQuery = countQuery.Where("u.name LIKE ? OR u.number LIKE ?", like, like)
 	}
 
+	var totalRows int64
+	if err := countQuery.Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "UserSpinsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: "Terjadi kesalahan sistem, silakan coba lagi",
+		})
+		return
+	}
+
 	// Aggregates (overall)
 	var totalWins int64
 	if err := db.Model(&models.UserSpin{}).Count(&totalWins).Error; err != nil {
+		utils.LogError(r, "UserSpinsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -58,6 +64,7 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	var agg paidAgg
 	if err := db.Table("user_spins").Select("COALESCE(SUM(amount), 0) as total_paid").Scan(&agg).Error; err != nil {
+		utils.LogError(r, "UserSpinsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -90,9 +97,10 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 			us.won_at
 		`).
 		Order("us.won_at DESC").
-		Offset(offset).
-		Limit(limit).
+		Offset(pg.Offset).
+		Limit(pg.Limit).
 		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "UserSpinsHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -127,14 +135,16 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Wrap data
 	type Data struct {
-		TotalWins int64               `json:"total_wins"`
-		TotalPaid float64             `json:"total_paid"`
-		Items     []UserSpinResponse  `json:"items"`
+		TotalWins  int64                `json:"total_wins"`
+		TotalPaid  float64              `json:"total_paid"`
+		Items      []UserSpinResponse   `json:"items"`
+		Pagination utils.PaginationMeta `json:"pagination"`
 	}
 	data := Data{
-		TotalWins: totalWins,
-		TotalPaid: agg.TotalPaid,
-		Items:     items,
+		TotalWins:  totalWins,
+		TotalPaid:  agg.TotalPaid,
+		Items:      items,
+		Pagination: pg.Meta(totalRows),
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
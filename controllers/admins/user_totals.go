@@ -0,0 +1,181 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"project/database"
+	"project/models"
+	"project/notify"
+	"project/utils"
+	"project/vip"
+
+	"gorm.io/gorm"
+)
+
+// totalsDriftSample bounds the drifted users listed in the cron summary.
+const totalsDriftSample = 50
+
+// POST /api/admin/users/{id}/recalculate?apply=true
+// Rebuilds the user's total_invest and total_invest_vip from their Success
+// investment and top-up transactions, skipping cancelled investments, and
+// reports them next to the stored values and the VIP level they give.
+// Nothing is written unless apply=true; then the totals are replaced and the
+// level recalculated, downgrades following VIP_DOWNGRADE_POLICY.
+func RecalculateUserTotals(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "User tidak valid"})
+		return
+	}
+	apply, _ := strconv.ParseBool(r.URL.Query().Get("apply"))
+
+	var drift *vip.Drift
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		drift, err = vip.Rebuild(tx, id, nil, apply, vip.SourceAdmin, vip.Policy())
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengguna tidak ditemukan", Code: utils.CodeUserNotFound})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "RecalculateUserTotals", err, "user_id", id, "apply", apply)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if drift.Applied {
+		auditLog(r, "user.recalculate_totals", drift.Before, drift.After)
+	}
+
+	msg := "Total investasi sudah sesuai"
+	switch {
+	case drift.Applied:
+		msg = "Total investasi dihitung ulang"
+	case drift.Drifted:
+		msg = "Total investasi berbeda, kirim apply=true untuk memperbaiki"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: msg, Data: drift})
+}
+
+// totalsDriftSummary adds up the drift the totals cron finds.
+type totalsDriftSummary struct {
+	Checked int `json:"checked"`
+	Drifted int `json:"drifted"`
+	Applied int `json:"applied"`
+	Failed  int `json:"failed"`
+	// Sums of the absolute differences, and the largest single one
+	TotalInvestDrift    int64        `json:"total_invest_drift"`
+	TotalInvestVIPDrift int64        `json:"total_invest_vip_drift"`
+	MaxDrift            int64        `json:"max_drift"`
+	LevelChanges        int          `json:"level_changes"`
+	Users               []*vip.Drift `json:"users"`
+}
+
+// add counts d when it drifted, keeping the first totalsDriftSample users.
+func (s *totalsDriftSummary) add(d *vip.Drift) {
+	if !d.Drifted {
+		return
+	}
+	s.Drifted++
+	invest := d.After.TotalInvest - d.Before.TotalInvest
+	invest = max(invest, -invest)
+	investVIP := d.After.TotalInvestVIP - d.Before.TotalInvestVIP
+	investVIP = max(investVIP, -investVIP)
+	s.TotalInvestDrift += invest
+	s.TotalInvestVIPDrift += investVIP
+	s.MaxDrift = max(s.MaxDrift, invest, investVIP)
+	if d.After.Level != d.Before.Level {
+		s.LevelChanges++
+	}
+	if len(s.Users) < totalsDriftSample {
+		s.Users = append(s.Users, d)
+	}
+}
+
+// POST /api/cron/user-totals?apply=true
+// RecalculateUserTotals for every user, in batches: each batch is compared
+// with one grouped ledger query and only drifted users are locked and
+// rebuilt. Without apply=true it only reports the drift.
+func (h *VIPLevelHandler) CronTotals(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	apply, _ := strconv.ParseBool(r.URL.Query().Get("apply"))
+
+	levels, err := vip.Levels(h.DB)
+	if err != nil {
+		utils.LogError(r, "user totals cron: load levels", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	policy := vip.Policy()
+
+	summary := totalsDriftSummary{Users: []*vip.Drift{}}
+	var lastID uint
+	for {
+		if utils.ShuttingDown(r) {
+			break
+		}
+		var batch []models.User
+		if err := h.DB.Select("id, total_invest, total_invest_vip").
+			Where("id > ?", lastID).Order("id ASC").Limit(vipRecalcBatchSize).
+			Find(&batch).Error; err != nil {
+			utils.LogError(r, "user totals cron: load users", err, "after_user_id", lastID)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+		summary.Checked += len(batch)
+
+		ids := make([]uint, len(batch))
+		for i, u := range batch {
+			ids[i] = u.ID
+		}
+		ledger, err := vip.LedgerTotals(h.DB, ids)
+		if err != nil {
+			utils.LogError(r, "user totals cron: ledger totals", err, "after_user_id", lastID)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+
+		for _, u := range batch {
+			if t := ledger[u.ID]; t.TotalInvest == u.TotalInvest && t.TotalInvestVIP == u.TotalInvestVIP {
+				continue
+			}
+			// Recheck under the user's lock: a payment may have landed since
+			var drift *vip.Drift
+			err := h.DB.Transaction(func(tx *gorm.DB) error {
+				var err error
+				drift, err = vip.Rebuild(tx, u.ID, levels, apply, vip.SourceCron, policy)
+				return err
+			})
+			if err != nil {
+				utils.LogError(r, "user totals cron: rebuild", err, "user_id", u.ID, "apply", apply)
+				summary.Failed++
+				continue
+			}
+			summary.add(drift)
+			if drift.Applied {
+				summary.Applied++
+			}
+			if c := drift.Change; c != nil && c.Applied {
+				h.Notifier.Enqueue(notify.VIPLevelChanged(c.UserID, c.FromLevel, c.ToLevel))
+			}
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{
+		"apply":   apply,
+		"policy":  policy,
+		"summary": summary,
+	}})
+}
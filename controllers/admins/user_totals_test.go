@@ -0,0 +1,29 @@
+package admins
+
+import (
+	"testing"
+
+	"project/vip"
+)
+
+func TestTotalsDriftSummary(t *testing.T) {
+	var s totalsDriftSummary
+	s.add(&vip.Drift{UserID: 1, Before: vip.Totals{TotalInvest: 100}, After: vip.Totals{TotalInvest: 100}})
+	s.add(&vip.Drift{UserID: 2, Drifted: true,
+		Before: vip.Totals{TotalInvest: 500, TotalInvestVIP: 300, Level: 2},
+		After:  vip.Totals{TotalInvest: 200, TotalInvestVIP: 200, Level: 1}})
+	s.add(&vip.Drift{UserID: 3, Drifted: true,
+		Before: vip.Totals{TotalInvest: 100},
+		After:  vip.Totals{TotalInvest: 150}})
+
+	if s.Drifted != 2 || len(s.Users) != 2 {
+		t.Fatalf("drifted %d with %d users, want 2 and 2", s.Drifted, len(s.Users))
+	}
+	// Drift in either direction adds up as an absolute amount
+	if s.TotalInvestDrift != 350 || s.TotalInvestVIPDrift != 100 || s.MaxDrift != 300 {
+		t.Fatalf("drift %d/%d max %d, want 350/100 max 300", s.TotalInvestDrift, s.TotalInvestVIPDrift, s.MaxDrift)
+	}
+	if s.LevelChanges != 1 {
+		t.Fatalf("level changes %d, want 1", s.LevelChanges)
+	}
+}
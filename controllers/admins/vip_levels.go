@@ -0,0 +1,263 @@
+package admins
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"project/database"
+	"project/models"
+	"project/notify"
+	"project/utils"
+	"project/vip"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GET /api/admin/vip-levels
+func ListVIPLevelsHandler(w http.ResponseWriter, r *http.Request) {
+	levels := []models.VIPLevel{}
+	if err := database.DB.Order("level ASC").Find(&levels).Error; err != nil {
+		utils.LogError(r, "ListVIPLevelsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: levels})
+}
+
+// PUT /api/admin/vip-levels
+// Creates or updates the threshold and caps of one VIP level. Zero removes a
+// cap; levels above 0 need a threshold. Users move to a new threshold on the
+// next VIP level cron.
+func UpdateVIPLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level                *uint `json:"level"`
+		MinTotalInvest       int64 `json:"min_total_invest"`
+		MaxActiveInvestments int   `json:"max_active_investments"`
+		MaxDailyWithdrawal   int64 `json:"max_daily_withdrawal"`
+		MaxSingleWithdrawal  int64 `json:"max_single_withdrawal"`
+	}
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	switch {
+	case req.Level == nil || *req.Level > models.MaxVIPLevel:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: fmt.Sprintf("Level harus antara 0 dan %d", models.MaxVIPLevel)})
+		return
+	case *req.Level > 0 && req.MinTotalInvest <= 0:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Minimal total investasi harus lebih dari 0"})
+		return
+	case *req.Level == 0 && req.MinTotalInvest != 0:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Level 0 tidak memiliki minimal total investasi"})
+		return
+	case req.MaxActiveInvestments < 0 || req.MaxDailyWithdrawal < 0 || req.MaxSingleWithdrawal < 0:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Batas tidak boleh negatif"})
+		return
+	case req.MaxDailyWithdrawal > 0 && req.MaxSingleWithdrawal > req.MaxDailyWithdrawal:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Batas sekali penarikan tidak boleh melebihi batas harian"})
+		return
+	}
+
+	var before *models.VIPLevel
+	var after models.VIPLevel
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.VIPLevel
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("level = ?", *req.Level).First(&existing).Error
+		switch {
+		case err == nil:
+			snapshot := existing
+			before = &snapshot
+			after = existing
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			after = models.VIPLevel{Level: *req.Level}
+		default:
+			return err
+		}
+		after.MinTotalInvest = req.MinTotalInvest
+		after.MaxActiveInvestments = req.MaxActiveInvestments
+		after.MaxDailyWithdrawal = req.MaxDailyWithdrawal
+		after.MaxSingleWithdrawal = req.MaxSingleWithdrawal
+		return tx.Save(&after).Error
+	})
+	if err != nil {
+		utils.LogError(r, "UpdateVIPLevelHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	models.InvalidateVIPLevels()
+	auditLog(r, "vip_levels.update", before, after)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Batas level VIP berhasil disimpan", Data: after})
+}
+
+// vipRecalcBatchSize is how many users one VIP level cron query reads.
+const vipRecalcBatchSize = 500
+
+// VIPLevelHandler runs the VIP level recalculation cron.
+type VIPLevelHandler struct {
+	DB *gorm.DB
+	// Notifier receives push events after commits; nil disables them
+	Notifier *notify.Notifier
+}
+
+func NewVIPLevelHandler(db *gorm.DB) *VIPLevelHandler {
+	return &VIPLevelHandler{DB: db}
+}
+
+// POST /api/cron/vip-levels
+// Recomputes every user's VIP level from total_invest_vip and the thresholds
+// in vip_levels, in batches. Downgrades follow VIP_DOWNGRADE_POLICY.
+func (h *VIPLevelHandler) Cron(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	levels, err := vip.Levels(h.DB)
+	if err != nil {
+		utils.LogError(r, "vip level cron: load levels", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	policy := vip.Policy()
+
+	var checked, upgraded, downgraded, flagged, failed int
+	var lastID uint
+	for {
+		if utils.ShuttingDown(r) {
+			break
+		}
+		var batch []models.User
+		if err := h.DB.Select("id, level, total_invest_vip").
+			Where("id > ?", lastID).Order("id ASC").Limit(vipRecalcBatchSize).
+			Find(&batch).Error; err != nil {
+			utils.LogError(r, "vip level cron: load users", err, "after_user_id", lastID)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+		checked += len(batch)
+
+		for _, u := range batch {
+			if vip.LevelFor(levels, u.TotalInvestVIP) == u.CurrentVIPLevel() {
+				continue
+			}
+			var change *models.VIPLevelChange
+			err := h.DB.Transaction(func(tx *gorm.DB) error {
+				var err error
+				change, err = vip.Recalculate(tx, u.ID, levels, vip.SourceCron, policy)
+				return err
+			})
+			switch {
+			case err != nil:
+				utils.LogError(r, "vip level cron: recalculate", err, "user_id", u.ID)
+				failed++
+			case change == nil:
+			case !change.Applied:
+				flagged++
+			default:
+				if change.ToLevel > change.FromLevel {
+					upgraded++
+				} else {
+					downgraded++
+				}
+				h.Notifier.Enqueue(notify.VIPLevelChanged(change.UserID, change.FromLevel, change.ToLevel))
+			}
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{
+		"checked":    checked,
+		"upgraded":   upgraded,
+		"downgraded": downgraded,
+		"flagged":    flagged,
+		"failed":     failed,
+		"policy":     policy,
+	}})
+}
+
+// POST /api/admin/users/{id}/vip-level/recalculate
+// Recalculates one user's VIP level now, e.g. after a support adjustment.
+// apply_downgrade lowers the level even under the flag policy.
+func RecalculateUserVIPLevel(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "User tidak valid"})
+		return
+	}
+	var req struct {
+		ApplyDowngrade bool `json:"apply_downgrade"`
+	}
+	if r.ContentLength > 0 {
+		if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+			return
+		}
+	}
+	policy := vip.Policy()
+	if req.ApplyDowngrade {
+		policy = vip.PolicyApply
+	}
+
+	var change *models.VIPLevelChange
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		change, err = vip.Recalculate(tx, uint(id), nil, vip.SourceAdmin, policy)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengguna tidak ditemukan", Code: utils.CodeUserNotFound})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "RecalculateUserVIPLevel", err, "user_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if change == nil {
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Level VIP sudah sesuai"})
+		return
+	}
+	auditLogTarget(r, "vip_level.recalculate", "user", id, map[string]interface{}{"user_id": id, "level": change.FromLevel}, change)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Level VIP dihitung ulang", Data: change})
+}
+
+// GET /api/admin/users/{id}/vip-history?page=&limit=
+// The user's VIP level changes, newest first, including downgrades only
+// flagged (applied false).
+func GetUserVIPHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "User tidak valid"})
+		return
+	}
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	if !userExists(w, r, id) {
+		return
+	}
+
+	query := database.DB.Model(&models.VIPLevelChange{}).Where("user_id = ?", id)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError(r, "GetUserVIPHistory: count", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	changes := []models.VIPLevelChange{}
+	if err := query.Order("id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&changes).Error; err != nil {
+		utils.LogError(r, "GetUserVIPHistory", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(changes, pg, total)})
+}
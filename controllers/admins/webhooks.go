@@ -0,0 +1,301 @@
+package admins
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"project/database"
+	"project/models"
+	"project/outbox"
+	"project/utils"
+	"project/webhook"
+
+	"gorm.io/gorm"
+)
+
+type webhookEndpointRequest struct {
+	Name         *string  `json:"name"`
+	URL          *string  `json:"url"`
+	Events       []string `json:"events"`
+	IncludePII   *bool    `json:"include_pii"`
+	Active       *bool    `json:"active"`
+	RotateSecret bool     `json:"rotate_secret"`
+}
+
+// webhookEndpointWithSecret shows the signing secret, which is only returned
+// when it is created or rotated.
+type webhookEndpointWithSecret struct {
+	models.WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+// GET /api/admin/webhooks
+// The endpoints without their secrets, and the event types they can subscribe to.
+func ListWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints := []models.WebhookEndpoint{}
+	if err := database.DB.Order("id ASC").Find(&endpoints).Error; err != nil {
+		utils.LogError(r, "ListWebhookEndpoints", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"endpoints": endpoints,
+		"events":    webhook.Events,
+	}})
+}
+
+// POST /api/admin/webhooks
+// The response carries the signing secret; it is not shown again.
+func CreateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req webhookEndpointRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+	ep := models.WebhookEndpoint{Active: true}
+	if msg := applyWebhookEndpointRequest(&ep, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		utils.LogError(r, "CreateWebhookEndpoint: secret", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	ep.Secret = secret
+
+	if err := database.DB.Create(&ep).Error; err != nil {
+		utils.LogError(r, "CreateWebhookEndpoint", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat webhook"})
+		return
+	}
+	auditLogTarget(r, "webhook.create", "webhook", ep.ID, nil, ep)
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Webhook berhasil dibuat", Data: webhookEndpointWithSecret{WebhookEndpoint: ep, Secret: ep.Secret}})
+}
+
+// PUT /api/admin/webhooks/{id}
+// Updates the given fields. rotate_secret issues a new secret, returned once;
+// deliveries still pending are signed with it.
+func UpdateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req webhookEndpointRequest
+	if err := utils.DecodeJSON(w, r, &req, utils.MaxJSONBodyBytes); err != nil {
+		return
+	}
+
+	var ep models.WebhookEndpoint
+	if err := database.DB.First(&ep, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Webhook tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "UpdateWebhookEndpoint", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	before := ep
+	if msg := applyWebhookEndpointRequest(&ep, &req); msg != "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		return
+	}
+	if req.RotateSecret {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			utils.LogError(r, "UpdateWebhookEndpoint: secret", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+			return
+		}
+		ep.Secret = secret
+	}
+
+	if err := database.DB.Model(&ep).Select("name", "url", "secret", "events", "include_pii", "active").Updates(&ep).Error; err != nil {
+		utils.LogError(r, "UpdateWebhookEndpoint", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate webhook"})
+		return
+	}
+	after := map[string]interface{}{"endpoint": ep, "secret_rotated": req.RotateSecret}
+	auditLog(r, "webhook.update", before, after)
+
+	var data interface{} = ep
+	if req.RotateSecret {
+		data = webhookEndpointWithSecret{WebhookEndpoint: ep, Secret: ep.Secret}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Webhook berhasil diupdate", Data: data})
+}
+
+// DELETE /api/admin/webhooks/{id}
+// Removes the endpoint. Its delivery log stays; deliveries still pending fail.
+func DeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var ep models.WebhookEndpoint
+	if err := database.DB.First(&ep, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Webhook tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "DeleteWebhookEndpoint", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	if err := database.DB.Delete(&ep).Error; err != nil {
+		utils.LogError(r, "DeleteWebhookEndpoint", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus webhook"})
+		return
+	}
+	auditLog(r, "webhook.delete", ep, nil)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Webhook berhasil dihapus"})
+}
+
+// applyWebhookEndpointRequest copies the provided fields onto ep and
+// validates the result, returning a user-facing message when invalid.
+func applyWebhookEndpointRequest(ep *models.WebhookEndpoint, req *webhookEndpointRequest) string {
+	if req.Name != nil {
+		ep.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		ep.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.Events != nil {
+		seen := map[string]bool{}
+		events := make([]string, 0, len(req.Events))
+		for _, e := range req.Events {
+			e = strings.TrimSpace(e)
+			if !webhook.Valid(e) {
+				return "Event tidak dikenal: " + e
+			}
+			if !seen[e] {
+				seen[e] = true
+				events = append(events, e)
+			}
+		}
+		ep.Events = strings.Join(events, ",")
+	}
+	if req.IncludePII != nil {
+		ep.IncludePII = *req.IncludePII
+	}
+	if req.Active != nil {
+		ep.Active = *req.Active
+	}
+
+	if ep.Name == "" || len(ep.Name) > 100 {
+		return "Nama webhook wajib diisi (maksimal 100 karakter)"
+	}
+	u, err := url.Parse(ep.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" || len(ep.URL) > 500 {
+		return "URL webhook harus https (maksimal 500 karakter)"
+	}
+	if ep.Events == "" {
+		return "Pilih minimal satu event"
+	}
+	return ""
+}
+
+// WebhookHandler redelivers webhook deliveries through the outbox dispatcher.
+type WebhookHandler struct {
+	Dispatcher *outbox.Dispatcher
+}
+
+func NewWebhookHandler(d *outbox.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{Dispatcher: d}
+}
+
+// GET /api/admin/webhook-deliveries?endpoint_id=&status=&event_type=&page=&limit=
+// The delivery log, newest first, with the body sent and the last attempt's outcome.
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	q := r.URL.Query()
+	query := database.DB.Model(&models.WebhookDelivery{})
+	if s := q.Get("endpoint_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "endpoint_id tidak valid"})
+			return
+		}
+		query = query.Where("endpoint_id = ?", id)
+	}
+	switch status := q.Get("status"); status {
+	case "":
+	case models.WebhookDeliveryPending, models.WebhookDeliverySuccess, models.WebhookDeliveryFailed:
+		query = query.Where("status = ?", status)
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Status tidak valid"})
+		return
+	}
+	if t := q.Get("event_type"); t != "" {
+		query = query.Where("event_type = ?", t)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.LogError(r, "ListWebhookDeliveries: count", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	deliveries := []models.WebhookDelivery{}
+	if err := query.Order("id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&deliveries).Error; err != nil {
+		utils.LogError(r, "ListWebhookDeliveries", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(deliveries, pg, total)})
+}
+
+// POST /api/admin/webhook-deliveries/{id}/redeliver
+// Sends a Failed delivery again now, with the same body and event id and
+// fresh attempts; if this try fails too, the outbox cron keeps retrying.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var before models.WebhookDelivery
+	if err := database.DB.First(&before, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengiriman tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "RedeliverWebhook", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	eventID, err := webhook.Redeliver(database.DB, id)
+	if errors.Is(err, webhook.ErrNotFailed) {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Hanya pengiriman Failed yang dapat dikirim ulang"})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "RedeliverWebhook", err, "delivery_id", id)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	h.Dispatcher.Dispatch(outbox.Batch{eventID})
+
+	var after models.WebhookDelivery
+	if err := database.DB.First(&after, id).Error; err != nil {
+		utils.LogError(r, "RedeliverWebhook: reload", err)
+	}
+	auditLog(r, "webhook_delivery.redeliver", map[string]interface{}{"id": id, "status": before.Status}, map[string]interface{}{"id": id, "status": after.Status})
+
+	msg := "Webhook berhasil dikirim ulang"
+	if after.Status != models.WebhookDeliverySuccess {
+		msg = "Pengiriman ulang gagal, akan dicoba lagi otomatis"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: msg, Data: after})
+}
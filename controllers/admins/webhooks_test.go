@@ -0,0 +1,36 @@
+package admins
+
+import (
+	"testing"
+
+	"project/models"
+)
+
+func TestApplyWebhookEndpointRequest(t *testing.T) {
+	name, u := " Accounting ", "https://example.com/hooks"
+	ep := models.WebhookEndpoint{Active: true}
+	req := webhookEndpointRequest{Name: &name, URL: &u, Events: []string{"deposit.confirmed", "withdrawal.settled", "deposit.confirmed"}}
+	if msg := applyWebhookEndpointRequest(&ep, &req); msg != "" {
+		t.Fatalf("valid request rejected: %s", msg)
+	}
+	if ep.Name != "Accounting" || ep.Events != "deposit.confirmed,withdrawal.settled" {
+		t.Fatalf("got name %q events %q", ep.Name, ep.Events)
+	}
+
+	// Fields left out keep their values
+	if msg := applyWebhookEndpointRequest(&ep, &webhookEndpointRequest{}); msg != "" || ep.URL != u {
+		t.Fatalf("partial update: %q, url %q", msg, ep.URL)
+	}
+
+	plain, unknown, none := "http://example.com/hooks", []string{"balance.changed"}, []string{}
+	for label, r := range map[string]webhookEndpointRequest{
+		"plain http":    {URL: &plain},
+		"unknown event": {Events: unknown},
+		"no events":     {Events: none},
+	} {
+		e := ep
+		if msg := applyWebhookEndpointRequest(&e, &r); msg == "" {
+			t.Errorf("%s: expected a validation message", label)
+		}
+	}
+}
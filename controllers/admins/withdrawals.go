@@ -1,76 +1,82 @@
 package admins
 
 import (
-	"bytes"
-	"encoding/base64"
-	"encoding/json"
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"project/alert"
 	"project/database"
+	"project/kyta"
 	"project/models"
+	"project/notify"
 	"project/utils"
+	"project/webhook"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WithdrawalResponse struct {
-	ID            uint    `json:"id"`
-	UserID        uint    `json:"user_id"`
-	UserName      string  `json:"user_name"`
-	Phone         string  `json:"phone"`
-	BankAccountID uint    `json:"bank_account_id"`
-	BankName      string  `json:"bank_name"`
-	AccountName   string  `json:"account_name"`
-	AccountNumber string  `json:"account_number"`
-	Amount        float64 `json:"amount"`
-	Charge        float64 `json:"charge"`
-	FinalAmount   float64 `json:"final_amount"`
-	OrderID       string  `json:"order_id"`
-	Status        string  `json:"status"`
-	CreatedAt     string  `json:"created_at"`
+	ID            uint   `json:"id"`
+	UserID        uint   `json:"user_id"`
+	UserName      string `json:"user_name"`
+	Phone         string `json:"phone"`
+	BankAccountID uint   `json:"bank_account_id"`
+	BankName      string `json:"bank_name"`
+	AccountName   string `json:"account_name"`
+	AccountNumber string `json:"account_number"`
+	Amount        int64  `json:"amount"`
+	Charge        int64  `json:"charge"`
+	FinalAmount   int64  `json:"final_amount"`
+	OrderID       string `json:"order_id"`
+	Status        string `json:"status"`
+	ProcessedBy   *int64 `json:"processed_by"`
+	RiskFlags     string `json:"risk_flags,omitempty"`
+	Express       bool   `json:"express"`
+	ExpressFee    int64  `json:"express_fee"`
+	CreatedAt     string `json:"created_at"`
+
+	GatewayPayoutID *string `json:"gateway_payout_id"`
+
+	// The account holder the bank reported when the withdrawal was made, to
+	// compare with user_name and account_name, and how well it matched
+	InquiryStatus  *string `json:"inquiry_status"`
+	InquiryName    *string `json:"inquiry_name"`
+	NameMatchScore *int    `json:"name_match_score"`
 }
 
-func GetWithdrawals(w http.ResponseWriter, r *http.Request) {
-	// Get query parameters
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	status := r.URL.Query().Get("status")
-	userID := r.URL.Query().Get("user_id")
-	orderID := r.URL.Query().Get("search")
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
-	}
-
-	offset := (page - 1) * limit
+// WithdrawalHandler serves withdrawal review for admins, investment
+// cancellations, which can refund through a payout, and the payout callback.
+// Approval pays out through Kyta when auto withdraw is enabled.
+type WithdrawalHandler struct {
+	DB   *gorm.DB
+	Kyta kyta.Client
+	// Notifier receives push events after commits; nil disables them
+	Notifier *notify.Notifier
+	// Alerts receives ops alerts; nil only logs them
+	Alerts *alert.Alerter
+}
 
-	// Start query
-	db := database.DB
-	query := db.Model(&models.Withdrawal{}).
-		Joins("JOIN users ON withdrawals.user_id = users.id").
-		Joins("JOIN bank_accounts ON withdrawals.bank_account_id = bank_accounts.id").
-		Joins("JOIN banks ON bank_accounts.bank_id = banks.id")
+func NewWithdrawalHandler(db *gorm.DB, kc kyta.Client) *WithdrawalHandler {
+	return &WithdrawalHandler{DB: db, Kyta: kc}
+}
 
-	// Apply filters
-	if status != "" {
-		query = query.Where("withdrawals.status = ?", status)
-	}
-	if userID != "" {
-		query = query.Where("withdrawals.user_id = ?", userID)
-	}
-	if orderID != "" {
-		query = query.Where("withdrawals.order_id LIKE ?", "%"+orderID+"%")
+// GET /api/admin/withdrawals?status=&user_id=&search=&flagged=true&express=true
+func (h *WithdrawalHandler) List(w http.ResponseWriter, r *http.Request) {
+	// Get query parameters
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
+	query := filterWithdrawals(h.DB, r)
 
 	// Get withdrawals with joined details
 	type WithdrawalWithDetails struct {
@@ -82,10 +88,17 @@ func GetWithdrawals(w http.ResponseWriter, r *http.Request) {
 		AccountNumber string
 	}
 
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "GetWithdrawals", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
 	var withdrawals []WithdrawalWithDetails
 	query.Select("withdrawals.*, users.name as user_name, users.number as phone, banks.name as bank_name, bank_accounts.account_name, bank_accounts.account_number").
-		Offset(offset).
-		Limit(limit).
+		Offset(pg.Offset).
+		Limit(pg.Limit).
 		Order("withdrawals.created_at DESC").
 		Find(&withdrawals)
 
@@ -109,22 +122,137 @@ func GetWithdrawals(w http.ResponseWriter, r *http.Request) {
 			FinalAmount:   w.FinalAmount,
 			OrderID:       w.OrderID,
 			Status:        w.Status,
-			CreatedAt:     w.CreatedAt.Format(time.RFC3339),
+			ProcessedBy:   w.ProcessedBy,
+			RiskFlags:     utils.GetStringValue(w.RiskFlags),
+			Express:       w.Express,
+			ExpressFee:    w.ExpressFee,
+			CreatedAt:     utils.FormatTime(w.CreatedAt),
+
+			GatewayPayoutID: w.GatewayPayoutID,
+			InquiryStatus:   w.InquiryStatus,
+			InquiryName:     w.InquiryName,
+			NameMatchScore:  w.NameMatchScore,
 		})
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    response,
+		Data:    utils.NewPaginated(response, pg, totalRows),
 	})
 }
 
-func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
-	client := &http.Client{Timeout: 30 * time.Second}
+// filterWithdrawals builds on db the withdrawals query, joined with the user
+// and bank account, from the status, user_id, search, flagged and express
+// filters.
+func filterWithdrawals(db *gorm.DB, r *http.Request) *gorm.DB {
+	q := r.URL.Query()
+	query := db.Model(&models.Withdrawal{}).
+		Joins("JOIN users ON withdrawals.user_id = users.id").
+		Joins("JOIN bank_accounts ON withdrawals.bank_account_id = bank_accounts.id").
+		Joins("JOIN banks ON bank_accounts.bank_id = banks.id")
+
+	if status := q.Get("status"); status != "" {
+		query = query.Where("withdrawals.status = ?", status)
+	}
+	if userID := q.Get("user_id"); userID != "" {
+		query = query.Where("withdrawals.user_id = ?", userID)
+	}
+	if orderID := q.Get("search"); orderID != "" {
+		query = query.Where("withdrawals.order_id LIKE ?", utils.PrefixLike(orderID))
+	}
+	if q.Get("flagged") == "true" {
+		query = query.Where("withdrawals.risk_flags IS NOT NULL")
+	}
+	if q.Get("express") == "true" {
+		query = query.Where("withdrawals.express = ?", true)
+	}
+	return query
+}
+
+// GET /api/admin/withdrawals/export?status=&user_id=&search=&flagged=true&express=true
+// The filtered withdrawals as CSV, oldest first, for reconciliation against
+// the bank's payout statement.
+func (h *WithdrawalHandler) Export(w http.ResponseWriter, r *http.Request) {
+	type withdrawalRow struct {
+		models.Withdrawal
+		UserName      string
+		Phone         string
+		BankName      string
+		AccountName   string
+		AccountNumber string
+	}
+	db := h.DB
+	rows, err := filterWithdrawals(db, r).
+		Select("withdrawals.*, users.name as user_name, users.number as phone, banks.name as bank_name, bank_accounts.account_name, bank_accounts.account_number").
+		Order("withdrawals.created_at ASC, withdrawals.id ASC").
+		Rows()
+	if err != nil {
+		utils.LogError(r, "ExportWithdrawals", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	defer rows.Close()
+
+	amount := func(v int64) string { return strconv.FormatInt(v, 10) }
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=withdrawals-%s.csv", time.Now().In(utils.AppLocation()).Format("20060102-150405")))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"id", "created_at", "user_id", "username", "phone", "order_id", "status", "bank_name", "account_name", "account_number", "amount", "charge", "express_fee", "final_amount", "gateway_payout_id"})
+	n := 0
+	for rows.Next() {
+		var wd withdrawalRow
+		if err := db.ScanRows(rows, &wd); err != nil {
+			// Headers are gone; the truncated file is all we can signal
+			utils.LogError(r, "ExportWithdrawals", err, "rows", n)
+			break
+		}
+		_ = cw.Write([]string{
+			strconv.FormatUint(uint64(wd.ID), 10),
+			utils.FormatTime(wd.CreatedAt),
+			strconv.FormatUint(uint64(wd.UserID), 10),
+			wd.UserName,
+			wd.Phone,
+			wd.OrderID,
+			wd.Status,
+			wd.BankName,
+			wd.AccountName,
+			wd.AccountNumber,
+			amount(wd.Amount),
+			amount(wd.Charge),
+			amount(wd.ExpressFee),
+			amount(wd.FinalAmount),
+			utils.GetStringValue(wd.GatewayPayoutID),
+		})
+		if n++; n%transactionExportFlushRows == 0 {
+			cw.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		utils.LogError(r, "ExportWithdrawals", err, "rows", n)
+	}
+	cw.Flush()
+}
+
+// errWithdrawalNotOpen refuses to approve or reject a withdrawal another
+// request has already processed.
+var errWithdrawalNotOpen = errors.New("withdrawal already processed")
+
+// withdrawalTxRetries is how often a deadlocked approval or rejection is
+// retried before the admin sees an error.
+const withdrawalTxRetries = 2
+
+// PUT /api/admin/withdrawals/{id}/approve
+// The withdrawal is locked, checked still Pending, paid out when
+// auto_withdraw is on, and marked Success with its transaction and the
+// settled webhook event, all in one transaction. The row stays locked
+// through the payout, as in the express cron, so two approvals cannot both
+// pay.
+func (h *WithdrawalHandler) Approve(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
+		utils.LogError(r, "ApproveWithdrawal", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "ID penarikan tidak valid",
@@ -132,15 +260,23 @@ func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	db, cancel := database.WithTimeout(r.Context(), h.DB)
+	defer cancel()
+
 	var withdrawal models.Withdrawal
-	if err := database.DB.First(&withdrawal, id).Error; err != nil {
+	if err := db.First(&withdrawal, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
 				Success: false,
 				Message: "Penarikan tidak ditemukan",
+				Code:    utils.CodeWithdrawalNotFound,
 			})
 			return
 		}
+		utils.LogError(r, "ApproveWithdrawal", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data penarikan",
@@ -156,8 +292,12 @@ func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var setting models.Setting
-	if err := database.DB.First(&setting).Error; err != nil {
+	setting, err := models.GetCachedSetting(db)
+	if err != nil {
+		utils.LogError(r, "ApproveWithdrawal", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil informasi aplikasi",
@@ -165,303 +305,104 @@ func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check auto_withdraw setting
-	if !setting.AutoWithdraw {
-		tx := database.DB.Begin()
-
-		withdrawal.Status = "Success"
-		if err := tx.Save(&withdrawal).Error; err != nil {
-			tx.Rollback()
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-				Success: false,
-				Message: "Gagal memperbarui status penarikan",
-			})
-			return
+	payout := "manual"
+	ctx := r.Context()
+	opts := utils.TxOptions{Retries: withdrawalTxRetries}
+	if setting.AutoWithdraw {
+		// Once the payout is sent the status must be saved even if MySQL is
+		// slow or the admin goes away, and a retry would pay twice
+		payout = "kytapay"
+		ctx = context.WithoutCancel(ctx)
+		opts = utils.TxOptions{Timeout: -1}
+	}
+	var payoutErr error
+	sent := false
+	err = utils.WithTxOptions(ctx, h.DB, opts, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&withdrawal, id).Error; err != nil {
+			return err
 		}
-
-		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", withdrawal.OrderID).Update("status", "Success").Error; err != nil {
-			tx.Rollback()
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui status transaksi"})
-			return
+		if withdrawal.Status != "Pending" {
+			return errWithdrawalNotOpen
 		}
-
-		if err := tx.Commit().Error; err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan perubahan"})
-			return
+		if setting.AutoWithdraw {
+			var ba models.BankAccount
+			if err := tx.Preload("Bank").First(&ba, withdrawal.BankAccountID).Error; err != nil {
+				return err
+			}
+			resp, err := h.Kyta.CreatePayout(r.Context(), payoutRequest(&withdrawal, &ba))
+			if err != nil {
+				payoutErr = err
+				return err
+			}
+			sent = true
+			withdrawal.GatewayPayoutID = resp.PayoutID()
 		}
 
-		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Penarikan berhasil disetujui (transfer manual)"})
-		return
-	}
-
-	// Auto withdrawal using KYTAPAY/KYTAPAY
-	var ba models.BankAccount
-	if err := database.DB.Preload("Bank").First(&ba, withdrawal.BankAccountID).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil rekening"})
-		return
-	}
-
-	bankCode := ba.Bank.Code
-	accountNumber := ba.AccountNumber
-	accountName := ba.AccountName
-	description := fmt.Sprintf("Penarikan # %s", withdrawal.OrderID)
-	notifyURL := os.Getenv("CALLBACK_WITHDRAW")
-
-	// Get payment gateway configuration
-	apiURL := os.Getenv("KYTAPAY_BASE_URL")
-	// Trim trailing slash untuk konsistensi
-	apiURL = strings.TrimRight(apiURL, "/")
-
-	clientID := os.Getenv("KYTAPAY_CLIENT_ID")
-	clientSecret := os.Getenv("KYTAPAY_CLIENT_SECRET")
-
-	if clientID == "" || clientSecret == "" {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Konfigurasi payment gateway tidak lengkap",
-		})
-		return
-	}
-
-	// 1) Get access token
-	basic := base64.StdEncoding.EncodeToString([]byte(clientID + ":" + clientSecret))
-	atkReqBody := map[string]string{"grant_type": "client_credentials"}
-	atkJSON, _ := json.Marshal(atkReqBody)
-
-	req, err := http.NewRequest(http.MethodPost, apiURL+"/access-token", bytes.NewReader(atkJSON))
-	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal membuat request token",
-		})
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Basic "+basic)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Koneksi ke payment gateway gagal: " + err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	// Read response body terlebih dahulu
-	tokenBodyBytes, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal membaca response token",
-		})
-		return
-	}
-
-	// Parse response
-	var atkResp struct {
-		ResponseCode    string `json:"response_code"`
-		ResponseMessage string `json:"response_message"`
-		ResponseData    struct {
-			AccessToken string `json:"access_token"`
-			TokenType   string `json:"token_type"`
-			ExpiresIn   int    `json:"expires_in"`
-		} `json:"response_data"`
-	}
-
-	parseErr := json.Unmarshal(tokenBodyBytes, &atkResp)
-
-	// Cek HTTP status code
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		errorMsg := "Gagal mendapatkan token pembayaran"
-		if parseErr == nil && atkResp.ResponseMessage != "" {
-			errorMsg = atkResp.ResponseMessage
-		} else if len(tokenBodyBytes) > 0 && len(tokenBodyBytes) < 500 {
-			errorMsg = string(tokenBodyBytes)
+		withdrawal.Status = "Success"
+		markProcessed(r, &withdrawal)
+		if err := tx.Save(&withdrawal).Error; err != nil {
+			return err
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: errorMsg,
-		})
-		return
-	}
-
-	// Cek parsing error
-	if parseErr != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal parsing response token: " + string(tokenBodyBytes),
-		})
-		return
-	}
-
-	// Check response code - lebih fleksibel untuk support kedua platform
-	if atkResp.ResponseCode != "" {
-		// Accept: 200, 2000100 (KYTAPAY), atau code lain yang diawali 200
-		isSuccess := atkResp.ResponseCode == "200" ||
-			atkResp.ResponseCode == "2000100" ||
-			strings.HasPrefix(atkResp.ResponseCode, "200")
-
-		if !isSuccess {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-				Success: false,
-				Message: atkResp.ResponseMessage,
-			})
-			return
+		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", withdrawal.OrderID).Update("status", "Success").Error; err != nil {
+			return err
 		}
-	}
-
-	if atkResp.ResponseData.AccessToken == "" {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Token pembayaran kosong",
-		})
-		return
-	}
-
-	// 2) Create payout transfer
-	payoutBody := map[string]interface{}{
-		"reference_id": withdrawal.OrderID,
-		"amount":       int64(withdrawal.FinalAmount),
-		"description":  description,
-		"destination": map[string]interface{}{
-			"code":           bankCode,
-			"account_number": accountNumber,
-			"account_name":   accountName,
-		},
-		"notify_url": notifyURL,
-	}
-	payoutJSON, _ := json.Marshal(payoutBody)
-
-	req2, err := http.NewRequest(http.MethodPost, apiURL+"/payouts/transfers", bytes.NewReader(payoutJSON))
-	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal membuat request payout",
-		})
-		return
-	}
-
-	req2.Header.Set("Content-Type", "application/json")
-	req2.Header.Set("Authorization", "Bearer "+atkResp.ResponseData.AccessToken)
-
-	resp2, err := client.Do(req2)
-	if err != nil {
+		return recordWithdrawalEvent(tx, webhook.EventWithdrawalSettled, &withdrawal, payout)
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errWithdrawalNotOpen):
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Koneksi ke payment gateway gagal: " + err.Error(),
+			Message: "Hanya penarikan dengan status Pending yang dapat disetujui",
 		})
 		return
-	}
-	defer resp2.Body.Close()
-
-	// Read response body terlebih dahulu
-	payoutBodyBytes, readErr2 := io.ReadAll(resp2.Body)
-	if readErr2 != nil {
+	case errors.Is(payoutErr, kyta.ErrNotConfigured):
+		h.Alerts.Notify(alert.KeyPayoutFailed, "Auto withdraw aktif tetapi KytaPay belum dikonfigurasi (penarikan %s)", withdrawal.OrderID)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Gagal membaca response payout",
+			Message: "Konfigurasi payment gateway tidak lengkap",
 		})
 		return
-	}
-
-	// Parse response
-	var payoutResp struct {
-		ResponseCode    string `json:"response_code"`
-		ResponseMessage string `json:"response_message"`
-		ResponseData    struct {
-			ID          string `json:"id"`
-			ReferenceID string `json:"reference_id"`
-			Amount      int64  `json:"amount"`
-			PayoutData  struct {
-				Code          string `json:"code"`
-				AccountNumber string `json:"account_number"`
-				AccountName   string `json:"account_name"`
-			} `json:"payout_data,omitempty"`
-			MerchantURL struct {
-				NotifyURL string `json:"notify_url"`
-			} `json:"merchant_url,omitempty"`
-			RequestTime string `json:"request_time,omitempty"`
-		} `json:"response_data,omitempty"`
-	}
-
-	parseErr2 := json.Unmarshal(payoutBodyBytes, &payoutResp)
-
-	// Cek HTTP status code
-	if resp2.StatusCode < 200 || resp2.StatusCode >= 300 {
-		errorMsg := "Gagal memproses payout"
-		if parseErr2 == nil && payoutResp.ResponseMessage != "" {
-			errorMsg = payoutResp.ResponseMessage
-		} else if len(payoutBodyBytes) > 0 && len(payoutBodyBytes) < 500 {
-			errorMsg = string(payoutBodyBytes)
+	case payoutErr != nil:
+		utils.LogError(r, "ApproveWithdrawal", err)
+		h.Alerts.Notify(alert.KeyPayoutFailed, "Payout %s (Rp%d) gagal: %v", withdrawal.OrderID, withdrawal.FinalAmount, err)
+		var kerr *kyta.Error
+		message := "Gagal memproses payout"
+		if errors.As(err, &kerr) && kerr.Message != "" {
+			message = kerr.Message
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+		utils.WriteJSON(w, http.StatusBadGateway, utils.APIResponse{
 			Success: false,
-			Message: errorMsg,
+			Message: message,
+			Code:    utils.CodePaymentGatewayError,
 		})
 		return
-	}
-
-	// Cek parsing error
-	if parseErr2 != nil {
+	case sent:
+		utils.LogError(r, "ApproveWithdrawal: payout sent", err, "order_id", withdrawal.OrderID, "gateway_payout_id", utils.GetStringValue(withdrawal.GatewayPayoutID))
+		h.Alerts.Notify(alert.KeyPayoutFailed, "Payout %s (KytaPay %s) sudah dikirim tetapi status gagal disimpan: %v", withdrawal.OrderID, utils.GetStringValue(withdrawal.GatewayPayoutID), err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Gagal parsing response payout: " + string(payoutBodyBytes),
+			Message: "Gagal menyimpan perubahan",
 		})
 		return
-	}
-
-	// Check response code - support kedua platform
-	if payoutResp.ResponseCode != "" {
-		// Accept: 200, 2001000 (KYTAPAY), atau code lain yang diawali 200
-		isSuccess := payoutResp.ResponseCode == "200" ||
-			payoutResp.ResponseCode == "2001000" ||
-			strings.HasPrefix(payoutResp.ResponseCode, "200")
-
-		if !isSuccess {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-				Success: false,
-				Message: payoutResp.ResponseMessage,
-			})
+	default:
+		utils.LogError(r, "ApproveWithdrawal", err)
+		if utils.WriteDBTimeout(w, r, err) {
 			return
 		}
-	}
-
-	// Start transaction
-	tx := database.DB.Begin()
-
-	// Update withdrawal status
-	withdrawal.Status = "Success"
-	if err := tx.Save(&withdrawal).Error; err != nil {
-		tx.Rollback()
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Gagal memperbarui status penarikan",
-		})
-		return
-	}
-
-	// Update related transaction status
-	if err := tx.Model(&models.Transaction{}).Where("order_id = ?", withdrawal.OrderID).Update("status", "Success").Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui status transaksi",
+			Message: "Gagal menyimpan perubahan",
 		})
 		return
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal menyimpan perubahan",
-		})
+	auditLog(r, "withdrawal.approve", map[string]interface{}{"id": withdrawal.ID, "status": "Pending"}, map[string]interface{}{"id": withdrawal.ID, "order_id": withdrawal.OrderID, "status": withdrawal.Status, "payout": payout})
+	h.Notifier.Enqueue(notify.WithdrawalStatus(withdrawal.UserID, withdrawal.OrderID, withdrawal.Status, withdrawal.FinalAmount))
+	if payout == "manual" {
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Penarikan berhasil disetujui (transfer manual)"})
 		return
 	}
-
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Penarikan berhasil diproses otomatis",
@@ -472,10 +413,14 @@ func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func RejectWithdrawal(w http.ResponseWriter, r *http.Request) {
+// PUT /api/admin/withdrawals/{id}/reject
+// The withdrawal is locked, checked still Pending or On Hold, marked Failed
+// with its transaction and refunded to the balance in one transaction.
+func (h *WithdrawalHandler) Reject(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
+		utils.LogError(r, "RejectWithdrawal", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "ID penarikan tidak valid",
@@ -484,78 +429,56 @@ func RejectWithdrawal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var withdrawal models.Withdrawal
-	if err := database.DB.First(&withdrawal, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
-				Success: false,
-				Message: "Penarikan tidak ditemukan",
-			})
-			return
+	var before map[string]interface{}
+	err = utils.WithTxOptions(r.Context(), h.DB, utils.TxOptions{Retries: withdrawalTxRetries}, func(tx *gorm.DB) error {
+		withdrawal = models.Withdrawal{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&withdrawal, id).Error; err != nil {
+			return err
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal mengambil data penarikan",
-		})
-		return
-	}
-
-	// Only allow rejecting pending withdrawals
-	if withdrawal.Status != "Pending" {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Hanya penarikan dengan status Pending yang dapat ditolak",
-		})
-		return
-	}
-
-	// Start transaction
-	tx := database.DB.Begin()
-
-	// Update withdrawal status
-	withdrawal.Status = "Failed"
-	if err := tx.Save(&withdrawal).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui status penarikan",
-		})
-		return
-	}
-
-	// Update related transaction status
-	if err := tx.Model(&models.Transaction{}).
-		Where("order_id = ?", withdrawal.OrderID).
-		Update("status", "Failed").Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui status transaksi",
-		})
-		return
-	}
+		// Only allow rejecting pending or held withdrawals
+		if withdrawal.Status != "Pending" && withdrawal.Status != models.WithdrawalOnHold {
+			return errWithdrawalNotOpen
+		}
+		before = map[string]interface{}{"id": withdrawal.ID, "status": withdrawal.Status}
 
-	// Refund the amount to user's balance
-	var user models.User
-	if err := tx.First(&user, withdrawal.UserID).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+		withdrawal.Status = "Failed"
+		markProcessed(r, &withdrawal)
+		if err := tx.Save(&withdrawal).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", withdrawal.OrderID).Update("status", "Failed").Error; err != nil {
+			return err
+		}
+		// Refund the amount to user's balance
+		res := tx.Model(&models.User{}).Where("id = ?", withdrawal.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", withdrawal.Amount))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("refund withdrawal %d: user %d missing", withdrawal.ID, withdrawal.UserID)
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
 			Success: false,
-			Message: "Gagal mengambil data pengguna",
+			Message: "Penarikan tidak ditemukan",
+			Code:    utils.CodeWithdrawalNotFound,
 		})
 		return
-	}
-
-	user.Balance += withdrawal.Amount
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
+	case errors.Is(err, errWithdrawalNotOpen):
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Gagal memperbarui saldo pengguna",
+			Message: "Hanya penarikan dengan status Pending atau On Hold yang dapat ditolak",
 		})
 		return
-	}
-
-	if err := tx.Commit().Error; err != nil {
+	default:
+		utils.LogError(r, "RejectWithdrawal", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal menyimpan perubahan",
@@ -563,6 +486,8 @@ func RejectWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditLog(r, "withdrawal.reject", before, map[string]interface{}{"id": withdrawal.ID, "order_id": withdrawal.OrderID, "status": withdrawal.Status, "refunded": withdrawal.Amount})
+	h.Notifier.Enqueue(notify.WithdrawalStatus(withdrawal.UserID, withdrawal.OrderID, withdrawal.Status, withdrawal.Amount))
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Penarikan berhasil ditolak",
@@ -573,8 +498,45 @@ func RejectWithdrawal(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// PUT /api/admin/withdrawals/{id}/release
+// Moves a withdrawal a risk rule put On Hold to Pending, where it can be
+// approved as usual.
+func (h *WithdrawalHandler) Release(w http.ResponseWriter, r *http.Request) {
+	id, ok := idFromRequest(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID penarikan tidak valid"})
+		return
+	}
+	res := h.DB.Model(&models.Withdrawal{}).
+		Where("id = ? AND status = ?", id, models.WithdrawalOnHold).
+		Update("status", "Pending")
+	if res.Error != nil {
+		utils.LogError(r, "ReleaseWithdrawal", res.Error)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui status penarikan"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		var n int64
+		h.DB.Model(&models.Withdrawal{}).Where("id = ?", id).Count(&n)
+		if n == 0 {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Penarikan tidak ditemukan", Code: utils.CodeWithdrawalNotFound})
+			return
+		}
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hanya penarikan dengan status On Hold yang dapat dilepas"})
+		return
+	}
+	auditLog(r, "withdrawal.release", map[string]interface{}{"id": id, "status": models.WithdrawalOnHold}, map[string]interface{}{"id": id, "status": "Pending"})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Penarikan dilepas ke Pending",
+		Data:    map[string]interface{}{"id": id, "status": "Pending"},
+	})
+}
+
 // POST /v3/callback/payouts
-func KytaPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
+// Withdrawal payouts are settled at approval, so only a failure changes them.
+// Refund payouts (RefundPayoutOrderPrefix) go to refundPayoutCallback.
+func (h *WithdrawalHandler) KytaPayoutCallback(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		CallbackCode    string `json:"callback_code"`
 		CallbackMessage string `json:"callback_message"`
@@ -595,11 +557,8 @@ func KytaPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		} `json:"callback_data"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Invalid JSON",
-		})
+	if err := utils.DecodeJSON(w, r, &payload, utils.MaxJSONBodyBytes); err != nil {
+		utils.LogError(r, "payout callback: decode payload", err)
 		return
 	}
 
@@ -623,6 +582,12 @@ func KytaPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refunds of cancelled investments are confirmed by the callback
+	if strings.HasPrefix(referenceID, utils.RefundPayoutOrderPrefix) {
+		h.refundPayoutCallback(w, r, referenceID, status, payload.CallbackData.ID, payload.CallbackMessage)
+		return
+	}
+
 	// If status is Success or Pending, return 200 OK without updating database
 	if status == "Success" || status == "Pending" {
 		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
@@ -632,51 +597,50 @@ func KytaPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If status is Failed, update withdrawal status to Pending
-	db := database.DB
+	// If status is Failed, put the withdrawal back to Pending with its
+	// transaction, in one transaction
+	h.Alerts.Notify(alert.KeyPayoutFailed, "Payout %s (KytaPay %s) gagal di KytaPay: %s", referenceID, payload.CallbackData.ID, payload.CallbackMessage)
 	var withdrawal models.Withdrawal
-	if err := db.Where("order_id = ?", referenceID).First(&withdrawal).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
-				Success: false,
-				Message: "Penarikan tidak ditemukan",
-			})
-			return
+	err := utils.WithTxOptions(r.Context(), h.DB, utils.TxOptions{Retries: withdrawalTxRetries}, func(tx *gorm.DB) error {
+		withdrawal = models.Withdrawal{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", referenceID).First(&withdrawal).Error; err != nil {
+			return err
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal mengambil data penarikan",
-		})
-		return
-	}
 
-	// Start transaction to update withdrawal and transaction status to Pending
-	tx := db.Begin()
-
-	// Update withdrawal status to Pending
-	withdrawal.Status = "Pending"
-	if err := tx.Save(&withdrawal).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui status penarikan",
-		})
-		return
-	}
-
-	// Update related transaction status to Pending
-	if err := tx.Model(&models.Transaction{}).
-		Where("order_id = ?", withdrawal.OrderID).
-		Update("status", "Pending").Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+		// It needs a fresh approval
+		wasSettled := withdrawal.Status == "Success"
+		withdrawal.Status = "Pending"
+		if id := strings.TrimSpace(payload.CallbackData.ID); id != "" {
+			withdrawal.GatewayPayoutID = &id
+		}
+		withdrawal.ProcessedBy = nil
+		withdrawal.ProcessedAt = nil
+		if err := tx.Save(&withdrawal).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", withdrawal.OrderID).Update("status", "Pending").Error; err != nil {
+			return err
+		}
+		// Downstream systems were told it settled
+		if wasSettled {
+			return recordWithdrawalEvent(tx, webhook.EventWithdrawalReversed, &withdrawal, "kytapay")
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
 			Success: false,
-			Message: "Gagal memperbarui status transaksi",
+			Message: "Penarikan tidak ditemukan",
+			Code:    utils.CodeWithdrawalNotFound,
 		})
 		return
 	}
-
-	if err := tx.Commit().Error; err != nil {
+	if err != nil {
+		// A 5xx makes the gateway retry the callback
+		utils.LogError(r, "KytaPayoutCallbackHandler", err, "reference_id", referenceID)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal menyimpan perubahan",
@@ -685,6 +649,7 @@ func KytaPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return 200 OK after successful update
+	h.Notifier.Enqueue(notify.WithdrawalStatus(withdrawal.UserID, withdrawal.OrderID, withdrawal.Status, withdrawal.FinalAmount))
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Status penarikan dikembalikan ke Pending",
@@ -694,3 +659,33 @@ func KytaPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// payoutRequest asks Kyta to pay wd's final amount to ba.
+func payoutRequest(wd *models.Withdrawal, ba *models.BankAccount) kyta.PayoutRequest {
+	return kyta.PayoutRequest{
+		ReferenceID:   wd.OrderID,
+		Amount:        wd.FinalAmount,
+		Description:   fmt.Sprintf("Penarikan # %s", wd.OrderID),
+		BankCode:      ba.Bank.PayoutCode(),
+		AccountNumber: ba.AccountNumber,
+		AccountName:   ba.AccountName,
+	}
+}
+
+// markProcessed records the acting admin and time on a withdrawal being approved or rejected.
+func markProcessed(r *http.Request, wd *models.Withdrawal) {
+	now := time.Now()
+	wd.ProcessedAt = &now
+	if adminID, ok := utils.GetAdminID(r); ok {
+		wd.ProcessedBy = &adminID
+	}
+}
+
+// recordWithdrawalEvent notifies webhook endpoints about wd, paid out by
+// payout (manual or kytapay). It must run inside the transaction changing wd.
+func recordWithdrawalEvent(tx *gorm.DB, eventType string, wd *models.Withdrawal, payout string) error {
+	return webhook.Record(tx, eventType, wd.UserID, map[string]interface{}{
+		"withdrawal_id": wd.ID, "order_id": wd.OrderID, "amount": wd.Amount, "charge": wd.Charge, "final_amount": wd.FinalAmount,
+		"payout": payout, "gateway_payout_id": utils.GetStringValue(wd.GatewayPayoutID),
+	})
+}
@@ -0,0 +1,147 @@
+package admins
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/controllers/users"
+	"project/database"
+	"project/models"
+	"project/testutil"
+
+	"github.com/gorilla/mux"
+)
+
+// A write failing at any step of rejecting, approving or settling a
+// withdrawal leaves the withdrawal, its transaction and the balance as they
+// were.
+func TestWithdrawalRollsBackOnFailure(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, WithdrawCharge: 10, ExpressWithdraw: true, ExpressWithdrawCharge: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	suffix := time.Now().UnixNano() % 1000000000
+	bank := models.Bank{Name: "Bank Gagal", Code: fmt.Sprintf("TF%d", suffix), GatewayCode: "TFGW", Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	admin := NewWithdrawalHandler(tx, &testutil.Kyta{})
+
+	n := 0
+	newWithdrawal := func() (models.User, models.Withdrawal) {
+		t.Helper()
+		n++
+		user := testutil.NewUser(t, tx, models.User{Name: "Gagal", Balance: 200000})
+		acc := models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: "Gagal", AccountNumber: fmt.Sprintf("%d%09d", n, suffix)}
+		if err := tx.Create(&acc).Error; err != nil {
+			t.Fatal(err)
+		}
+		body := fmt.Sprintf(`{"amount":100000,"bank_account_id":%d,"express":true}`, acc.ID)
+		rec := httptest.NewRecorder()
+		users.NewWithdrawalHandler(tx).Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/withdrawal", strings.NewReader(body)), user.ID))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var wd models.Withdrawal
+		if err := tx.Where("user_id = ?", user.ID).First(&wd).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.First(&user, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return user, wd
+	}
+	call := func(handle http.HandlerFunc, method string, wd models.Withdrawal) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(method, fmt.Sprintf("/v3/admin/withdrawals/%d", wd.ID), nil), map[string]string{"id": fmt.Sprint(wd.ID)})
+		rec := httptest.NewRecorder()
+		handle(rec, req)
+		return rec
+	}
+	expect := func(label string, user models.User, wd models.Withdrawal, status string, balance int64) {
+		t.Helper()
+		var got models.Withdrawal
+		if err := tx.First(&got, wd.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		var trx models.Transaction
+		if err := tx.Where("order_id = ?", wd.OrderID).First(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+		var u models.User
+		if err := tx.First(&u, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != status || trx.Status != status || u.Balance != balance {
+			t.Errorf("%s: withdrawal %q, transaction %q, balance %d; want %q and %d", label, got.Status, trx.Status, u.Balance, status, balance)
+		}
+	}
+
+	for _, tc := range []struct {
+		table  string
+		panics bool
+	}{
+		{"withdrawals", false},
+		{"transactions", false},
+		{"users", false},
+		{"transactions", true},
+	} {
+		label := fmt.Sprintf("reject, %s failing (panic %v)", tc.table, tc.panics)
+		user, wd := newWithdrawal()
+		t.Run(label, func(t *testing.T) {
+			testutil.FailWrites(t, tx, tc.table, tc.panics)
+			if rec := call(admin.Reject, http.MethodPut, wd); rec.Code != http.StatusInternalServerError {
+				t.Errorf("expected 500, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+		expect(label, user, wd, "Pending", user.Balance)
+	}
+
+	for _, table := range []string{"withdrawals", "transactions"} {
+		label := fmt.Sprintf("approve, %s failing", table)
+		user, wd := newWithdrawal()
+		t.Run(label, func(t *testing.T) {
+			testutil.FailWrites(t, tx, table, false)
+			if rec := call(admin.Approve, http.MethodPut, wd); rec.Code != http.StatusInternalServerError {
+				t.Errorf("expected 500, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+		expect(label, user, wd, "Pending", user.Balance)
+	}
+
+	// With nothing failing the same calls go through
+	user, wd := newWithdrawal()
+	if rec := call(admin.Reject, http.MethodPut, wd); rec.Code != http.StatusOK {
+		t.Fatalf("reject: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	expect("reject", user, wd, "Failed", user.Balance+wd.Amount)
+	user, wd = newWithdrawal()
+	if rec := call(admin.Approve, http.MethodPut, wd); rec.Code != http.StatusOK {
+		t.Fatalf("approve: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	expect("approve", user, wd, "Success", user.Balance)
+
+	// A failed payout reported while the transaction cannot be written keeps
+	// the withdrawal settled, for the gateway to retry
+	callback := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"po-f","reference_id":%q,"amount":%d,"status":"Failed"}}`, wd.OrderID, wd.FinalAmount)
+	t.Run("payout callback, transactions failing", func(t *testing.T) {
+		testutil.FailWrites(t, tx, "transactions", false)
+		rec := httptest.NewRecorder()
+		admin.KytaPayoutCallback(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/withdrawals", strings.NewReader(callback)))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected 500, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+	expect("payout callback", user, wd, "Success", user.Balance)
+}
@@ -41,9 +41,10 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var user models.User
 	if err := db.Where("number = ?", req.Number).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Nomor telpon atau password salah"})
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Nomor telpon atau password salah", Code: utils.CodeInvalidCredentials})
 			return
 		}
+		utils.LogError(r, "LoginHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
@@ -57,21 +58,26 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		// record failed login attempt for lockout tracking
 		middleware.RecordFailedLogin(user.ID)
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Nomor telpon atau password salah"})
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Nomor telpon atau password salah", Code: utils.CodeInvalidCredentials})
 		return
 	}
 
 	// on successful login reset failed login counter
 	middleware.ResetFailedLogin(user.ID)
+	if err := models.RecordUserSignal(db, user.ID, models.UserSignalLogin, middleware.ClientIP(r), r.Header.Get(models.DeviceFingerprintHeader), r.UserAgent()); err != nil {
+		utils.LogError(r, "LoginHandler: record signal", err)
+	}
 
 	// generate access token (short-lived) and refresh token (stored in DB)
 	accessToken, err := utils.GenerateAccessToken(user.ID, "user")
 	if err != nil {
+		utils.LogError(r, "LoginHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal login"})
 		return
 	}
 	refreshJTI, _, err := utils.GenerateRefreshToken(user.ID)
 	if err != nil {
+		utils.LogError(r, "LoginHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan refresh token"})
 		return
 	}
@@ -104,7 +110,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 				"name":             user.Name,
 				"number":           user.Number,
 				"reff_code":        user.ReffCode,
-				"balance":          int64(user.Balance),
+				"balance":          user.Balance,
 				"level":            user.Level,
 				"total_invest":     int64(user.TotalInvest),
 				"total_invest_vip": int64(user.TotalInvestVIP),
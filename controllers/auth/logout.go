@@ -20,7 +20,7 @@ type LogoutRequest struct {
 func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	var req LogoutRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON body"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON body", Code: utils.CodeInvalidJSON})
 		return
 	}
 	if req.RefreshToken == "" {
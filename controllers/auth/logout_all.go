@@ -6,12 +6,16 @@ import (
 	"time"
 
 	"project/database"
+	"project/middleware"
+	"project/models"
 	"project/utils"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-// LogoutAllHandler revokes all refresh tokens for the authenticated user
+// LogoutAllHandler revokes all refresh tokens for the authenticated user and
+// bumps their token version, so access tokens on other devices stop working too
 func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
 	uid, ok := utils.GetUserID(r)
 	if !ok {
@@ -53,9 +57,12 @@ func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
-	if err := database.DB.Model(&map[string]interface{}{}).Where("user_id = ?", uid).Table("refresh_tokens").Update("revoked", true).Error; err != nil {
+	// Bumping the token version also ends the access tokens of other devices
+	if err := database.DB.Transaction(func(tx *gorm.DB) error { return models.RevokeSessions(tx, uid) }); err != nil {
+		utils.LogError(r, "LogoutAllHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
+	middleware.InvalidateTokenVersion(uid)
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "All sessions revoked"})
 }
@@ -17,7 +17,7 @@ type RefreshRequest struct {
 func RefreshHandler(w http.ResponseWriter, r *http.Request) {
 	var req RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON body"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON body", Code: utils.CodeInvalidJSON})
 		return
 	}
 	if req.RefreshToken == "" {
@@ -28,25 +28,35 @@ func RefreshHandler(w http.ResponseWriter, r *http.Request) {
 	// validate existing refresh token
 	rt, err := utils.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Invalid refresh token"})
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Invalid refresh token", Code: utils.CodeInvalidRefreshToken})
 		return
 	}
 
 	// rotate: revoke old token and create new one in a transaction
 	tx := database.DB.Begin()
-	rt.Revoked = true
-	if err := tx.Save(rt).Error; err != nil {
+	// Only the request that flips revoked wins; a concurrent refresh or a
+	// logout-all in between leaves nothing to rotate
+	res := tx.Model(rt).Where("revoked = ?", false).Update("revoked", true)
+	if res.Error != nil {
+		utils.LogError(r, "RefreshHandler", res.Error)
 		tx.Rollback()
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Invalid refresh token", Code: utils.CodeInvalidRefreshToken})
+		return
+	}
 	newJTI, _, err := utils.GenerateRefreshToken(rt.UserID)
 	if err != nil {
+		utils.LogError(r, "RefreshHandler", err)
 		tx.Rollback()
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
 	if err := tx.Commit().Error; err != nil {
+		utils.LogError(r, "RefreshHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
@@ -54,6 +64,7 @@ func RefreshHandler(w http.ResponseWriter, r *http.Request) {
 	// issue new access token
 	accessToken, err := utils.GenerateAccessToken(rt.UserID, "user")
 	if err != nil {
+		utils.LogError(r, "RefreshHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
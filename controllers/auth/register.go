@@ -11,6 +11,7 @@ import (
 	"project/database"
 	"project/middleware"
 	"project/models"
+	"project/referral"
 	"project/utils"
 
 	"golang.org/x/crypto/bcrypt"
@@ -70,7 +71,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if req.Password != req.PasswordConfirmation {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Password tidak cocok"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Password tidak cocok", Code: utils.CodePasswordMismatch})
 		return
 	}
 	if req.ReferralCode == "" {
@@ -83,7 +84,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	// Ensure unique number
 	var existing models.User
 	if err := db.Where("number = ?", req.Number).First(&existing).Error; err == nil {
-		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Nomor telepon sudah terdaftar"})
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Nomor telepon sudah terdaftar", Code: utils.CodePhoneRegistered})
 		return
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
@@ -96,9 +97,21 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		var refOwner models.User
 		if err := db.Where("reff_code = ?", req.ReferralCode).First(&refOwner).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode referral tidak valid"})
+				utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode referral tidak valid", Code: utils.CodeInvalidReferralCode})
 				return
 			}
+			utils.LogError(r, "RegisterHandler", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
+			return
+		}
+		// A new account cannot close a loop, but a corrupt upline would
+		// still send bonuses around one
+		if err := referral.ValidateReferrer(db, 0, refOwner.ID); err != nil {
+			if errors.Is(err, referral.ErrReferralCycle) {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode referral tidak valid", Code: utils.CodeInvalidReferralCode})
+				return
+			}
+			utils.LogError(r, "RegisterHandler", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 			return
 		}
@@ -108,6 +121,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	// Hash password
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
+		utils.LogError(r, "RegisterHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
@@ -115,6 +129,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	// Generate unique referral code
 	code, err := generateUniqueReffCode(db, 8)
 	if err != nil {
+		utils.LogError(r, "RegisterHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
@@ -131,15 +146,20 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := db.Create(&newUser).Error; err != nil {
+		utils.LogError(r, "RegisterHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Registrasi gagal, silakan coba lagi"})
 		return
 	}
 
+	if err := models.RecordUserSignal(db, newUser.ID, models.UserSignalRegister, middleware.ClientIP(r), r.Header.Get(models.DeviceFingerprintHeader), r.UserAgent()); err != nil {
+		utils.LogError(r, "RegisterHandler: record signal", err)
+	}
+
 	newTransaction := models.Transaction{
 		UserID:          newUser.ID,
 		Amount:          2000,
 		Charge:          0,
-		OrderID:         utils.GenerateOrderID(newUser.ID),
+		OrderID:         utils.GenerateOrderID(utils.OrderBonus, newUser.ID),
 		TransactionFlow: "debit",
 		TransactionType: "bonus",
 		Message:         ptrString("Bonus pendaftaran"),
@@ -147,6 +167,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := db.Create(&newTransaction).Error; err != nil {
+		utils.LogError(r, "RegisterHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
 		return
 	}
@@ -154,11 +175,13 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	// Generate access and refresh tokens
 	accessToken, err := utils.GenerateAccessToken(newUser.ID, "user")
 	if err != nil {
+		utils.LogError(r, "RegisterHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat token"})
 		return
 	}
 	refreshJTI, _, err := utils.GenerateRefreshToken(newUser.ID)
 	if err != nil {
+		utils.LogError(r, "RegisterHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan refresh token"})
 		return
 	}
@@ -190,7 +213,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 				"name":             newUser.Name,
 				"number":           newUser.Number,
 				"reff_code":        newUser.ReffCode,
-				"balance":          int64(newUser.Balance),
+				"balance":          newUser.Balance,
 				"level":            newUser.Level,
 				"total_invest":     int64(newUser.TotalInvest),
 				"total_invest_vip": int64(newUser.TotalInvestVIP),
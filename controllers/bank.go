@@ -12,6 +12,7 @@ func BankListHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
 	var banks []models.Bank
 	if err := db.Where("status = ?", "Active").Order("name ASC").Find(&banks).Error; err != nil {
+		utils.LogError(r, "BankListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 		return
 	}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var certificateNoPattern = regexp.MustCompile(`^[A-Z]+-[0-9]{4}-[0-9]{6,}$`)
+
+// amountBands are the ranges the public verification shows instead of the
+// exact amount, as [upper bound, label]; the last band has no bound.
+var amountBands = []struct {
+	below int64
+	label string
+}{
+	{1000000, "< Rp1 juta"},
+	{10000000, "Rp1 juta - Rp10 juta"},
+	{100000000, "Rp10 juta - Rp100 juta"},
+	{0, ">= Rp100 juta"},
+}
+
+func amountBand(amount int64) string {
+	for _, b := range amountBands {
+		if b.below == 0 || amount < b.below {
+			return b.label
+		}
+	}
+	return ""
+}
+
+// GET /api/verify/{certificate_no}
+// Public confirmation that a certificate number belongs to a real
+// investment. Only the product, an amount band, the certification date and
+// the status are shown, never the owner; the route is rate limited per IP so
+// the sequential numbers cannot be walked.
+func VerifyCertificateHandler(w http.ResponseWriter, r *http.Request) {
+	certNo := strings.ToUpper(strings.TrimSpace(mux.Vars(r)["certificate_no"]))
+	notFound := func() {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Sertifikat tidak ditemukan"})
+	}
+	if !certificateNoPattern.MatchString(certNo) {
+		notFound()
+		return
+	}
+
+	// Archived investments still verify
+	var inv models.Investment
+	if err := database.DB.Unscoped().Select("product_name, amount, status, certificate_no, certified_at").
+		Where("certificate_no = ?", certNo).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			notFound()
+			return
+		}
+		utils.LogError(r, "VerifyCertificateHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	certifiedOn := ""
+	if inv.CertifiedAt != nil {
+		certifiedOn = inv.CertifiedAt.In(utils.AppLocation()).Format("2006-01-02")
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Sertifikat valid",
+		Data: map[string]interface{}{
+			"certificate_no": certNo,
+			"product":        inv.ProductName,
+			"amount_band":    amountBand(inv.Amount),
+			"certified_on":   certifiedOn,
+			"status":         inv.Status,
+		},
+	})
+}
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"net/http"
+
+	"project/docs"
+)
+
+// GET /v3/docs/openapi.json
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(docs.OpenAPI)
+}
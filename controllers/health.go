@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"project/database"
+	"project/kyta"
+	"project/utils"
+)
+
+const healthDBTimeout = 2 * time.Second
+
+// GET /v3/health
+// Readiness: 503 when a critical dependency (the database) is down, so the
+// instance is taken out of rotation. The payment gateway, Redis and the read
+// replica are reported but do not fail the check; while the replica is down
+// its reads go to the primary.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{}
+	healthy := true
+
+	checks["database"] = "up"
+	ctx, cancel := context.WithTimeout(r.Context(), healthDBTimeout)
+	defer cancel()
+	if database.DB == nil {
+		checks["database"] = "down"
+		healthy = false
+	} else if sqlDB, err := database.DB.DB(); err != nil {
+		checks["database"] = "down"
+		healthy = false
+	} else if err := sqlDB.PingContext(ctx); err != nil {
+		utils.LogError(r, "health: database ping", err)
+		checks["database"] = "down"
+		healthy = false
+	}
+
+	// Last result of the replica's own health check (database.ReadReplica.Watch)
+	checks["database_replica"] = database.Reads.Status()
+
+	if utils.RedisClient == nil {
+		checks["redis"] = "disabled"
+	} else if err := utils.RedisClient.Ping(ctx).Err(); err != nil {
+		checks["redis"] = "down"
+	} else {
+		checks["redis"] = "up"
+	}
+
+	checks["payment_gateway"] = kyta.Status()
+
+	status, code := "healthy", http.StatusOK
+	if !healthy {
+		status, code = "unhealthy", http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"timestamp": time.Now().Unix(),
+		"service":   "stoneform-api",
+		"checks":    checks,
+	})
+}
+
+// GET /v3/health/live
+// Liveness: only proves the process is serving HTTP. Never touches
+// dependencies, so a database outage does not get the container restarted.
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "alive",
+		"timestamp": time.Now().Unix(),
+	})
+}
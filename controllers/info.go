@@ -2,19 +2,22 @@ package controllers
 
 import (
 	"net/http"
+	"time"
 
 	"project/database"
 	"project/models"
 	"project/utils"
 )
 
+// GET /api/info
+// The settings come from the shared cache, like every other settings read.
 func InfoPublicHandler(w http.ResponseWriter, r *http.Request) {
-	db := database.DB
+	// The request context carries the cache bypass flag
+	db := database.DB.WithContext(r.Context())
 
-	var setting models.Setting
-	if err := db.Model(&models.Setting{}).
-		Select("name, company, maintenance, closed_register").
-		Take(&setting).Error; err != nil {
+	setting, err := models.GetCachedSetting(db)
+	if err != nil {
+		utils.LogError(r, "InfoPublicHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil informasi aplikasi",
@@ -22,14 +25,45 @@ func InfoPublicHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Regular withdrawals only; express ones skip the window
+	open, change := setting.WithdrawalWindow(time.Now(), utils.AppLocation())
+	window := map[string]interface{}{
+		"start_hour": setting.WithdrawStartHour,
+		"end_hour":   setting.WithdrawEndHour,
+		"open":       open,
+		"closes_at":  nil,
+		"opens_at":   nil,
+	}
+	windowTimes := map[string]*time.Time{"closes_at": nil, "opens_at": nil}
+	if open {
+		window["closes_at"], windowTimes["closes_at"] = utils.FormatTime(change), &change
+	} else {
+		window["opens_at"], windowTimes["opens_at"] = utils.FormatTime(change), &change
+	}
+	utils.AddLocalTimes(r, window, windowTimes)
+
+	data := map[string]interface{}{
+		"name":            setting.Name,
+		"company":         setting.Company,
+		"maintenance":     setting.Maintenance,
+		"closed_register": setting.ClosedRegister,
+		// Lets the app show a banner before users hit a 503
+		"maintenance_features": map[string]bool{
+			models.FeatureInvestment: setting.InMaintenance(models.FeatureInvestment),
+			models.FeatureWithdrawal: setting.InMaintenance(models.FeatureWithdrawal),
+		},
+		"maintenance_message": setting.MaintenanceMessage,
+		"maintenance_until":   setting.MaintenanceUntil,
+		// Whether purchases can pick MANUAL bank transfer
+		"manual_payment":    setting.ManualPaymentAvailable(),
+		"withdrawal_window": window,
+	}
+	utils.AddLocalTimes(r, data, map[string]*time.Time{"maintenance_until": setting.MaintenanceUntil})
+	utils.AddServerTime(r, data)
+
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data: map[string]interface{}{
-			"name":            setting.Name,
-			"company":         setting.Company,
-			"maintenance":     setting.Maintenance,
-			"closed_register": setting.ClosedRegister,
-		},
+		Data:    data,
 	})
 }
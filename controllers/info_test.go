@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+// The window the info endpoint shows matches the withdrawal quote's rule:
+// start to end hour, Monday to Saturday, in the app timezone.
+func TestWithdrawalWindow(t *testing.T) {
+	wib := time.FixedZone("WIB", 7*3600)
+	s := models.Setting{WithdrawStartHour: 9, WithdrawEndHour: 17}
+	at := func(day, hour int) time.Time { return time.Date(2026, 10, day, hour, 0, 0, 0, wib) }
+
+	cases := []struct {
+		name   string
+		now    time.Time
+		open   bool
+		change time.Time
+	}{
+		{"friday before opening", at(16, 7), false, at(16, 9)},
+		{"friday open", at(16, 12), true, at(16, 17)},
+		{"friday closing hour", at(16, 17), false, at(17, 9)},
+		{"saturday evening skips sunday", at(17, 20), false, at(19, 9)},
+		{"sunday noon", at(18, 12), false, at(19, 9)},
+	}
+	for _, c := range cases {
+		open, change := s.WithdrawalWindow(c.now.UTC(), wib)
+		if open != c.open || !change.Equal(c.change) {
+			t.Errorf("%s: got open=%v change=%s, want open=%v change=%s", c.name, open, change, c.open, c.change)
+		}
+	}
+}
@@ -44,7 +44,7 @@ func PutPaymentInfo(w http.ResponseWriter, r *http.Request) {
 	
 	var body models.PaymentSettings
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON", Code: utils.CodeInvalidJSON})
 		return
 	}
 	
@@ -66,6 +66,7 @@ func PutPaymentInfo(w http.ResponseWriter, r *http.Request) {
 			WishlistID:     body.WishlistID,
 		}
 		if err := db.Create(&ps).Error; err != nil {
+			utils.LogError(r, "PutPaymentInfo", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to create"})
 			return
 		}
@@ -82,6 +83,7 @@ func PutPaymentInfo(w http.ResponseWriter, r *http.Request) {
 		ps.WishlistID = body.WishlistID
 		
 		if err := db.Save(&ps).Error; err != nil {
+			utils.LogError(r, "PutPaymentInfo", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to update"})
 			return
 		}
@@ -2,32 +2,67 @@ package controllers
 
 import (
 	"net/http"
+	"time"
 
 	"project/database"
 	"project/models"
 	"project/utils"
 )
 
+// GET /api/products
+// Active products grouped by category. With a user token each product also
+// carries the caller's eligibility, worked out on top of the cached catalog.
 func ProductListHandler(w http.ResponseWriter, r *http.Request) {
-	db := database.DB
+	// The request context carries the cache bypass flag
+	db := database.DB.WithContext(r.Context())
 
-	// Get active categories (prioritize category ID 1)
-	var categories []models.Category
-	if err := db.Where("status = ?", "Active").Order("CASE WHEN id = 1 THEN 0 ELSE id END ASC").Find(&categories).Error; err != nil {
+	// Active categories in display order, their active products and the
+	// boosts running, shared by every caller
+	catalog, outcome, err := models.CachedProductCatalog(db)
+	if err != nil {
+		utils.LogError(r, "ProductListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 		return
 	}
+	w.Header().Set("X-Cache", string(outcome))
+	categories, products := catalog.Categories, catalog.Products
 
-	// Get active products with category info
-	var products []models.Product
-	if err := db.Preload("Category").Where("status = ?", "Active").Order("category_id ASC, id ASC").Find(&products).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
-		return
+	// Signed-in users see whether each product's purchase cooldown is running
+	var lastPurchases map[uint]time.Time
+	if uid, ok := utils.GetUserID(r); ok && uid != 0 {
+		ids := make([]uint, 0, len(products))
+		for _, p := range products {
+			if p.PurchaseCooldownHours > 0 {
+				ids = append(ids, p.ID)
+			}
+		}
+		if lastPurchases, err = models.LastPurchases(db, uid, ids); err != nil {
+			utils.LogError(r, "ProductListHandler: last purchases", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+			return
+		}
 	}
+	now := time.Now()
 
-	// Group products by category name
+	// Group products by category name. p is a copy, so the per-request
+	// fields never reach the cached catalog
 	categoryMap := make(map[string][]models.Product)
 	for _, p := range products {
+		imageURL, err := utils.StoredImageURL(p.Image, utils.ImageURLExpiry)
+		if err != nil {
+			// The app falls back to its placeholder
+			utils.LogError(r, "ProductListHandler: image url", err, "product_id", p.ID)
+		}
+		p.ImageURL = imageURL
+		if lastPurchases != nil {
+			p.Eligibility = &models.ProductEligibility{}
+			if next := p.NextPurchaseAt(lastPurchases[p.ID]); next != nil && now.Before(*next) {
+				p.Eligibility.CoolingDown = true
+				p.Eligibility.NextPurchaseAt = next
+			}
+		}
+		// Boosts running now, for the app's promo badge
+		p.Boost = models.BoostFor(catalog.Boosts, p.ID, p.CategoryID, now)
 		if p.Category != nil {
 			categoryMap[p.Category.Name] = append(categoryMap[p.Category.Name], p)
 		}
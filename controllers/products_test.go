@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/controllers/admins"
+	"project/database"
+	"project/middleware"
+	"project/models"
+	"project/testutil"
+
+	"github.com/gorilla/mux"
+)
+
+// The product listing is served from the shared cache until an admin write
+// empties it or a request bypasses it, while each caller's eligibility is
+// still worked out per request.
+func TestProductListingCache(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	t.Setenv("CACHE_BYPASS_KEY", "bypass-test")
+	suffix := time.Now().UnixNano() % 1000000000
+
+	buyer := testutil.NewUser(t, tx, models.User{Name: "Pembeli"})
+	browser := testutil.NewUser(t, tx, models.User{Name: "Pengunjung"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Cache 1", Duration: 30, PurchaseCooldownHours: 24})
+	inv := models.Investment{UserID: buyer.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: product.Amount, DailyProfit: product.DailyProfit, Duration: product.Duration, OrderID: fmt.Sprintf("INV-C%d", suffix), Status: "Running"}
+	if err := tx.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	handler := middleware.CacheBypassMiddleware(http.HandlerFunc(ProductListHandler))
+	list := func(uid uint, bypass string) (string, []models.Product) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v3/products", nil)
+		if bypass != "" {
+			req.Header.Set(middleware.CacheBypassHeader, bypass)
+		}
+		if uid != 0 {
+			req = testutil.AsUser(req, uid)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("products: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data map[string][]models.Product `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Header().Get("X-Cache"), resp.Data[category.Name]
+	}
+
+	if outcome, got := list(buyer.ID, ""); outcome != "MISS" || len(got) != 1 || got[0].Eligibility == nil || !got[0].Eligibility.CoolingDown {
+		t.Fatalf("buyer: expected a MISS with the cooldown running, got %s %+v", outcome, got)
+	}
+	if outcome, got := list(browser.ID, ""); outcome != "HIT" || len(got) != 1 || got[0].Eligibility == nil || got[0].Eligibility.CoolingDown {
+		t.Fatalf("browser: expected a HIT without the buyer's cooldown, got %s %+v", outcome, got)
+	}
+	if outcome, got := list(0, ""); outcome != "HIT" || len(got) != 1 || got[0].Eligibility != nil {
+		t.Fatalf("anonymous: expected a HIT without eligibility, got %s %+v", outcome, got)
+	}
+
+	// A change made behind the admin API shows only once bypassed, and a
+	// wrong key does not bypass
+	if err := tx.Model(&product).Update("name", "Cache 1 renamed").Error; err != nil {
+		t.Fatal(err)
+	}
+	if outcome, got := list(0, "wrong"); outcome != "HIT" || got[0].Name != "Cache 1" {
+		t.Fatalf("wrong key: expected the cached name, got %s %q", outcome, got[0].Name)
+	}
+	if outcome, got := list(0, "bypass-test"); outcome != "BYPASS" || got[0].Name != "Cache 1 renamed" {
+		t.Fatalf("bypass: expected the fresh name, got %s %q", outcome, got[0].Name)
+	}
+
+	// Archiving through the admin API empties the cache at once
+	rec := httptest.NewRecorder()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/v3/admin/products/x", nil), map[string]string{"id": fmt.Sprint(product.ID)})
+	admins.ArchiveProductHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("archive: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if outcome, got := list(0, ""); outcome != "MISS" || len(got) != 0 {
+		t.Fatalf("after archive: expected a MISS without the product, got %s %+v", outcome, got)
+	}
+}
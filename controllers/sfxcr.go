@@ -1,18 +1,34 @@
 package controllers
 
 import (
+	"crypto/hmac"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"os"
+	"project/alert"
+	"project/middleware"
 	"project/models"
+	"project/notify"
 	"project/utils"
+	"project/webhook"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SFXCRController struct {
 	DB *gorm.DB
+	// Notifier receives push events after commits; nil disables them
+	Notifier *notify.Notifier
+	// Alerts receives ops alerts; nil only logs them
+	Alerts *alert.Alerter
 }
 
 func NewSFXCRController(db *gorm.DB) *SFXCRController {
@@ -31,19 +47,19 @@ func (c *SFXCRController) GetPendingWithdrawals(w http.ResponseWriter, r *http.R
 	}
 
 	var withdrawals []struct {
-		UserID        uint    `json:"user_id"`
-		UserName      string  `json:"user_name"`
-		Phone         string  `json:"phone"`
-		BankAccountID uint    `json:"bank_account_id"`
-		BankName      string  `json:"bank_name"`
-		AccountName   string  `json:"account_name"`
-		AccountNumber string  `json:"account_number"`
-		Amount        float64 `json:"amount"`
-		Charge        float64 `json:"charge"`
-		FinalAmount   float64 `json:"final_amount"`
-		OrderID       string  `json:"order_id"`
-		Status        string  `json:"status"`
-		CreatedAt     string  `json:"created_at"`
+		UserID        uint   `json:"user_id"`
+		UserName      string `json:"user_name"`
+		Phone         string `json:"phone"`
+		BankAccountID uint   `json:"bank_account_id"`
+		BankName      string `json:"bank_name"`
+		AccountName   string `json:"account_name"`
+		AccountNumber string `json:"account_number"`
+		Amount        int64  `json:"amount"`
+		Charge        int64  `json:"charge"`
+		FinalAmount   int64  `json:"final_amount"`
+		OrderID       string `json:"order_id"`
+		Status        string `json:"status"`
+		CreatedAt     string `json:"created_at"`
 	}
 
 	// Query pending withdrawals dengan join ke tabel terkait
@@ -60,6 +76,7 @@ func (c *SFXCRController) GetPendingWithdrawals(w http.ResponseWriter, r *http.R
 		Find(&withdrawals).Error
 
 	if err != nil {
+		utils.LogError(r, "GetPendingWithdrawals", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data penarikan",
@@ -95,19 +112,19 @@ func (c *SFXCRController) GetPendingWithdrawalByOrderID(w http.ResponseWriter, r
 	orderID := vars["order_id"]
 
 	var withdrawal struct {
-		UserID        uint    `json:"user_id"`
-		UserName      string  `json:"user_name"`
-		Phone         string  `json:"phone"`
-		BankAccountID uint    `json:"bank_account_id"`
-		BankName      string  `json:"bank_name"`
-		AccountName   string  `json:"account_name"`
-		AccountNumber string  `json:"account_number"`
-		Amount        float64 `json:"amount"`
-		Charge        float64 `json:"charge"`
-		FinalAmount   float64 `json:"final_amount"`
-		OrderID       string  `json:"order_id"`
-		Status        string  `json:"status"`
-		CreatedAt     string  `json:"created_at"`
+		UserID        uint   `json:"user_id"`
+		UserName      string `json:"user_name"`
+		Phone         string `json:"phone"`
+		BankAccountID uint   `json:"bank_account_id"`
+		BankName      string `json:"bank_name"`
+		AccountName   string `json:"account_name"`
+		AccountNumber string `json:"account_number"`
+		Amount        int64  `json:"amount"`
+		Charge        int64  `json:"charge"`
+		FinalAmount   int64  `json:"final_amount"`
+		OrderID       string `json:"order_id"`
+		Status        string `json:"status"`
+		CreatedAt     string `json:"created_at"`
 	}
 
 	err := c.DB.Table("withdrawals").
@@ -126,9 +143,11 @@ func (c *SFXCRController) GetPendingWithdrawalByOrderID(w http.ResponseWriter, r
 			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
 				Success: false,
 				Message: "Penarikan tidak ditemukan",
+				Code:    utils.CodeWithdrawalNotFound,
 			})
 			return
 		}
+		utils.LogError(r, "GetPendingWithdrawalByOrderID", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data penarikan",
@@ -144,8 +163,13 @@ func (c *SFXCRController) GetPendingWithdrawalByOrderID(w http.ResponseWriter, r
 }
 
 // WithdrawalCallback - API untuk menerima callback dari StoneForm
+// Body: {"order_id","status","callback_id"}. Each (order_id, callback_id) is
+// applied once; see sfxcrStatus for the statuses understood.
 func (c *SFXCRController) WithdrawalCallback(w http.ResponseWriter, r *http.Request) {
+	ip := middleware.ClientIP(r)
 	if !c.verifyAPIKey(r) {
+		c.Alerts.Notify(alert.KeyWebhookRejected, "Callback SFXCR ditolak: API key tidak valid (ip %s)", ip)
+		logSFXCRCallback(r, "rejected", "", "", "", "reason", "api_key", "ip", ip)
 		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
 			Success: false,
 			Message: "Unauthorized",
@@ -153,74 +177,134 @@ func (c *SFXCRController) WithdrawalCallback(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	var callback struct {
-		OrderID string `json:"order_id"`
-		Status  string `json:"status"`
+	body, err := io.ReadAll(io.LimitReader(r.Body, utils.MaxJSONBodyBytes+1))
+	if err != nil || int64(len(body)) > utils.MaxJSONBodyBytes {
+		utils.LogError(r, "sfxcr callback: read body", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Code:    utils.CodeInvalidJSON,
+		})
+		return
+	}
+	if secret := os.Getenv("SFXCR_CALLBACK_SECRET"); secret != "" {
+		if err := verifySFXCRSignature(secret, r.Header.Get(sfxcrTimestampHeader), r.Header.Get(sfxcrSignatureHeader), body, time.Now()); err != nil {
+			c.Alerts.Notify(alert.KeyWebhookRejected, "Callback SFXCR ditolak: %v (ip %s)", err, ip)
+			logSFXCRCallback(r, "rejected", "", "", "", "reason", err.Error(), "ip", ip)
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
+				Success: false,
+				Message: "Unauthorized",
+			})
+			return
+		}
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&callback); err != nil {
+	var callback struct {
+		OrderID    string `json:"order_id"`
+		Status     string `json:"status"`
+		CallbackID string `json:"callback_id"`
+	}
+	if err := json.Unmarshal(body, &callback); err != nil {
+		utils.LogError(r, "sfxcr callback: decode payload", err)
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
 			Message: "Invalid request body",
+			Code:    utils.CodeInvalidJSON,
 		})
 		return
 	}
-
-	// Validasi status
-	if callback.Status != "Success" && callback.Status != "Failed" {
+	callback.OrderID = strings.TrimSpace(callback.OrderID)
+	callback.CallbackID = strings.TrimSpace(callback.CallbackID)
+	if callback.OrderID == "" {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
-			Message: "Status harus Success atau Failed",
+			Message: "order_id kosong",
 		})
 		return
 	}
-
-	// Untuk status Failed, hanya kirim response success tanpa update database
-	if callback.Status == "Failed" {
-		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
-			Success: true,
-			Message: "Rejected berhasil diterima",
+	target, ok := sfxcrStatus(callback.Status)
+	if !ok {
+		logSFXCRCallback(r, "invalid_status", callback.OrderID, callback.CallbackID, callback.Status)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Status tidak dikenal",
 		})
 		return
 	}
+	callbackID := callback.CallbackID
+	if callbackID == "" {
+		callbackID = "status:" + strings.ToLower(strings.TrimSpace(callback.Status))
+	}
 
-	// Untuk status Success, update database
 	tx := c.DB.Begin()
-
-	// Update withdrawal
-	var withdrawal models.Withdrawal
-	if err := tx.Where("order_id = ?", callback.OrderID).First(&withdrawal).Error; err != nil {
+	record := models.SFXCRCallback{OrderID: callback.OrderID, CallbackID: callbackID, Status: callback.Status, RemoteIP: ip}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if res.Error != nil {
 		tx.Rollback()
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
+		utils.LogError(r, "WithdrawalCallback", res.Error, "order_id", callback.OrderID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Withdrawal tidak ditemukan",
+			Message: "Gagal menyimpan perubahan",
+		})
+		return
+	}
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		logSFXCRCallback(r, "duplicate", callback.OrderID, callbackID, callback.Status)
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+			Success: true,
+			Message: "Callback sudah diproses",
 		})
 		return
 	}
 
-	withdrawal.Status = callback.Status
-	if err := tx.Save(&withdrawal).Error; err != nil {
+	var withdrawal models.Withdrawal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", callback.OrderID).First(&withdrawal).Error; err != nil {
 		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Alerts.Notify(alert.KeyWebhookRejected, "Callback SFXCR untuk penarikan tidak dikenal %s", callback.OrderID)
+			logSFXCRCallback(r, "not_found", callback.OrderID, callbackID, callback.Status)
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
+				Success: false,
+				Message: "Withdrawal tidak ditemukan",
+				Code:    utils.CodeWithdrawalNotFound,
+			})
+			return
+		}
+		utils.LogError(r, "WithdrawalCallback", err, "order_id", callback.OrderID)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Gagal memperbarui status penarikan",
+			Message: "Gagal mengambil data penarikan",
 		})
 		return
 	}
 
-	// Update related transaction
-	if err := tx.Model(&models.Transaction{}).
-		Where("order_id = ?", callback.OrderID).
-		Update("status", callback.Status).Error; err != nil {
+	before := withdrawal.Status
+	outcome, err := applySFXCRStatus(tx, &withdrawal, target)
+	if err == nil {
+		err = tx.Model(&record).Update("outcome", outcome).Error
+	}
+	if err != nil {
 		tx.Rollback()
+		if errors.Is(err, errSFXCRConflict) {
+			c.Alerts.Notify(alert.KeyPayoutFailed, "Callback SFXCR %s untuk penarikan %s berstatus %s ditolak", callback.Status, withdrawal.OrderID, before)
+			logSFXCRCallback(r, "conflict", callback.OrderID, callbackID, callback.Status, "withdrawal_status", before)
+			utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{
+				Success: false,
+				Message: "Status penarikan tidak dapat diubah oleh callback ini",
+			})
+			return
+		}
+		utils.LogError(r, "WithdrawalCallback", err, "order_id", callback.OrderID)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Gagal memperbarui status transaksi",
+			Message: "Gagal memperbarui status penarikan",
 		})
 		return
 	}
 
 	if err := tx.Commit().Error; err != nil {
+		utils.LogError(r, "WithdrawalCallback", err, "order_id", callback.OrderID)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal menyimpan perubahan",
@@ -228,26 +312,149 @@ func (c *SFXCRController) WithdrawalCallback(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	logSFXCRCallback(r, outcome, callback.OrderID, callbackID, callback.Status, "withdrawal_status_before", before, "withdrawal_status", withdrawal.Status)
+	switch outcome {
+	case models.SFXCRSettled:
+		c.Notifier.Enqueue(notify.WithdrawalStatus(withdrawal.UserID, withdrawal.OrderID, withdrawal.Status, withdrawal.FinalAmount))
+	case models.SFXCRRefunded:
+		c.Alerts.Notify(alert.KeyPayoutFailed, "Payout %s gagal di StoneForm; Rp%d dikembalikan ke saldo", withdrawal.OrderID, withdrawal.Amount)
+		c.Notifier.Enqueue(notify.WithdrawalStatus(withdrawal.UserID, withdrawal.OrderID, withdrawal.Status, withdrawal.Amount))
+	}
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
-		Message: "Penarikan berhasil diproses",
+		Message: "Callback berhasil diproses",
+		Data: map[string]interface{}{
+			"order_id": withdrawal.OrderID,
+			"status":   withdrawal.Status,
+			"outcome":  outcome,
+		},
 	})
 }
 
-// verifyAPIKey - Verifikasi API key dari StoneForm
+// Signature headers of a StoneForm callback, checked when
+// SFXCR_CALLBACK_SECRET is set.
+const (
+	sfxcrTimestampHeader = "X-SFXCR-Timestamp"
+	sfxcrSignatureHeader = "X-SFXCR-Signature"
+)
+
+// sfxcrSignatureTolerance is how far a callback's timestamp may be from now.
+const sfxcrSignatureTolerance = 5 * time.Minute
+
+// errSFXCRConflict is returned by applySFXCRStatus for a status the
+// withdrawal cannot move to from its current one.
+var errSFXCRConflict = errors.New("sfxcr: status conflicts with withdrawal")
+
+// sfxcrStatus maps a StoneForm status, in any case, to ours: Success and
+// Failed are final, Pending means still in progress. ok is false for a
+// status StoneForm is not known to send.
+func sfxcrStatus(status string) (target string, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "success", "completed", "paid":
+		return "Success", true
+	case "failed", "rejected", "cancelled", "canceled":
+		return "Failed", true
+	case "pending", "processing":
+		return "Pending", true
+	}
+	return "", false
+}
+
+// applySFXCRStatus moves wd, locked in tx, to target and returns the outcome:
+//   - Success settles a Pending withdrawal;
+//   - Failed fails a Pending or settled one and puts its amount back in the
+//     balance, as a rejection does;
+//   - Pending changes nothing.
+//
+// A withdrawal already in target is left alone; any other move, such as
+// settling a refunded withdrawal, is errSFXCRConflict.
+func applySFXCRStatus(tx *gorm.DB, wd *models.Withdrawal, target string) (string, error) {
+	if target == "Pending" {
+		return models.SFXCRAcknowledged, nil
+	}
+	if wd.Status == target {
+		return models.SFXCRUnchanged, nil
+	}
+	wasSettled := wd.Status == "Success"
+	switch {
+	case target == "Success" && wd.Status == "Pending":
+	case target == "Failed" && (wd.Status == "Pending" || wasSettled):
+	default:
+		return "", errSFXCRConflict
+	}
+
+	if err := tx.Model(wd).Update("status", target).Error; err != nil {
+		return "", err
+	}
+	wd.Status = target
+	if err := tx.Model(&models.Transaction{}).Where("order_id = ?", wd.OrderID).Update("status", target).Error; err != nil {
+		return "", err
+	}
+	if target == "Success" {
+		return models.SFXCRSettled, recordSFXCRWithdrawalEvent(tx, webhook.EventWithdrawalSettled, wd)
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", wd.UserID).
+		UpdateColumn("balance", gorm.Expr("balance + ?", wd.Amount)).Error; err != nil {
+		return "", err
+	}
+	// Downstream systems were told it settled
+	if wasSettled {
+		if err := recordSFXCRWithdrawalEvent(tx, webhook.EventWithdrawalReversed, wd); err != nil {
+			return "", err
+		}
+	}
+	return models.SFXCRRefunded, nil
+}
+
+// recordSFXCRWithdrawalEvent notifies webhook endpoints about wd, paid out
+// through StoneForm. It must run inside the transaction changing wd.
+func recordSFXCRWithdrawalEvent(tx *gorm.DB, eventType string, wd *models.Withdrawal) error {
+	return webhook.Record(tx, eventType, wd.UserID, map[string]interface{}{
+		"withdrawal_id": wd.ID, "order_id": wd.OrderID, "amount": wd.Amount, "charge": wd.Charge, "final_amount": wd.FinalAmount,
+		"payout": "sfxcr", "gateway_payout_id": "",
+	})
+}
+
+// verifySFXCRSignature checks that signature is the hex HMAC-SHA256 of
+// "timestamp.body" under secret, sent within sfxcrSignatureTolerance of now.
+func verifySFXCRSignature(secret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return errors.New("timestamp tidak valid")
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > sfxcrSignatureTolerance || d < -sfxcrSignatureTolerance {
+		return errors.New("timestamp kedaluwarsa")
+	}
+	want := webhook.Sign(secret, ts, body)
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(strings.TrimSpace(signature)))) {
+		return errors.New("signature tidak valid")
+	}
+	return nil
+}
+
+// logSFXCRCallback writes one structured log line per callback handled.
+func logSFXCRCallback(r *http.Request, outcome, orderID, callbackID, status string, args ...any) {
+	attrs := []any{
+		"request_id", utils.GetRequestID(r),
+		"outcome", outcome,
+		"order_id", orderID,
+		"callback_id", callbackID,
+		"status", status,
+	}
+	utils.Logger.Info("sfxcr callback", append(attrs, args...)...)
+}
+
+// verifyAPIKey - Verifikasi API key dari StoneForm (SFXCR_API_KEY). Tanpa
+// key yang dikonfigurasi semua request ditolak.
 func (c *SFXCRController) verifyAPIKey(r *http.Request) bool {
+	expectedAPIKey := os.Getenv("SFXCR_API_KEY")
 	authHeader := r.Header.Get("Authorization")
-	expectedAPIKey := "pxloNUadKfHzjPVbSxdwjMHgUjlgVoPj" // Simpan di environment variable
-
-	if authHeader == "" {
+	if expectedAPIKey == "" || authHeader == "" {
 		return false
 	}
 
 	// Format: "Bearer {api_key}" atau langsung api_key
-	token := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		token = authHeader[7:]
-	}
+	token := strings.TrimPrefix(authHeader, "Bearer ")
 
-	return token == expectedAPIKey
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expectedAPIKey)) == 1
 }
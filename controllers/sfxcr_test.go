@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"project/webhook"
+)
+
+func TestSFXCRStatus(t *testing.T) {
+	cases := map[string]string{
+		"Success": "Success", "completed": "Success", " PAID ": "Success",
+		"Failed": "Failed", "rejected": "Failed", "Cancelled": "Failed", "canceled": "Failed",
+		"Pending": "Pending", "processing": "Pending",
+	}
+	for in, want := range cases {
+		if got, ok := sfxcrStatus(in); !ok || got != want {
+			t.Errorf("sfxcrStatus(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "Refund", "ok"} {
+		if _, ok := sfxcrStatus(in); ok {
+			t.Errorf("sfxcrStatus(%q) accepted", in)
+		}
+	}
+}
+
+func TestVerifySFXCRSignature(t *testing.T) {
+	body := []byte(`{"order_id":"WD-1","status":"Success","callback_id":"cb-1"}`)
+	now := time.Unix(1760000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := webhook.Sign("s3cret", now.Unix(), body)
+
+	if err := verifySFXCRSignature("s3cret", ts, sig, body, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := verifySFXCRSignature("s3cret", ts, sig, append(body, ' '), now); err == nil {
+		t.Error("signature accepted for a changed body")
+	}
+	if err := verifySFXCRSignature("other", ts, sig, body, now); err == nil {
+		t.Error("signature accepted under another secret")
+	}
+	if err := verifySFXCRSignature("s3cret", ts, sig, body, now.Add(6*time.Minute)); err == nil {
+		t.Error("stale timestamp accepted")
+	}
+	if err := verifySFXCRSignature("s3cret", "", sig, body, now); err == nil {
+		t.Error("missing timestamp accepted")
+	}
+}
+
+func TestSFXCRVerifyAPIKey(t *testing.T) {
+	c := &SFXCRController{}
+	req := httptest.NewRequest("GET", "/v3/sfxcr/withdrawals/pending", nil)
+	req.Header.Set("Authorization", "Bearer k3y")
+
+	t.Setenv("SFXCR_API_KEY", "")
+	if c.verifyAPIKey(req) {
+		t.Error("accepted a key with SFXCR_API_KEY unset")
+	}
+	t.Setenv("SFXCR_API_KEY", "k3y")
+	if !c.verifyAPIKey(req) {
+		t.Error("rejected the Bearer key")
+	}
+	req.Header.Set("Authorization", "k3y")
+	if !c.verifyAPIKey(req) {
+		t.Error("rejected the bare key")
+	}
+	req.Header.Set("Authorization", "Bearer k3y-other")
+	if c.verifyAPIKey(req) {
+		t.Error("accepted a wrong key")
+	}
+}
@@ -0,0 +1,137 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GET /api/users/announcements
+// Returns the currently published announcements targeted at the caller's VIP level,
+// pinned first, with per-user read state and an unread count for badging.
+func AnnouncementListHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	db := database.DB
+	var user models.User
+	if err := db.Select("id, level").First(&user, uid).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found", Code: utils.CodeUserNotFound})
+			return
+		}
+		utils.LogError(r, "AnnouncementListHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	level := uint(0)
+	if user.Level != nil {
+		level = *user.Level
+	}
+
+	type announcementRow struct {
+		models.Announcement
+		ReadAt *time.Time
+	}
+	now := time.Now()
+	var rows []announcementRow
+	if err := db.Table("announcements").
+		Select("announcements.*, notifications.read_at").
+		Joins("LEFT JOIN notifications ON notifications.announcement_id = announcements.id AND notifications.user_id = ?", uid).
+		Where("announcements.status = ? AND announcements.publish_at <= ?", "Active", now).
+		Where("announcements.expire_at IS NULL OR announcements.expire_at > ?", now).
+		Where("announcements.min_level IS NULL OR announcements.min_level <= ?", level).
+		Where("announcements.max_level IS NULL OR announcements.max_level >= ?", level).
+		Order("announcements.pinned DESC, announcements.publish_at DESC").
+		Limit(50).
+		Find(&rows).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	unread := 0
+	resp := make([]map[string]interface{}, 0, len(rows))
+	for _, a := range rows {
+		if a.ReadAt == nil {
+			unread++
+		}
+		resp = append(resp, map[string]interface{}{
+			"id":         a.ID,
+			"title":      a.Title,
+			"body":       a.Body,
+			"pinned":     a.Pinned,
+			"publish_at": a.PublishAt,
+			"expire_at":  a.ExpireAt,
+			"read":       a.ReadAt != nil,
+			"read_at":    a.ReadAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"announcements": resp,
+			"unread_count":  unread,
+		},
+	})
+}
+
+// POST /api/users/announcements/{id}/read
+func AnnouncementReadHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id64 == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	db := database.DB
+	var ann models.Announcement
+	if err := db.Where("id = ? AND status = ?", uint(id64), "Active").First(&ann).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengumuman tidak ditemukan"})
+			return
+		}
+		utils.LogError(r, "AnnouncementReadHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	// The notification row may not exist yet if batch delivery is still running
+	now := time.Now()
+	annID := ann.ID
+	n := models.Notification{
+		UserID:         uid,
+		AnnouncementID: &annID,
+		Type:           "announcement",
+		Title:          ann.Title,
+		Body:           ann.Body,
+		ReadAt:         &now,
+	}
+	if err := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"read_at": gorm.Expr("COALESCE(read_at, ?)", now)}),
+	}).Create(&n).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully"})
+}
@@ -0,0 +1,78 @@
+package users
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"project/models"
+	"project/testutil"
+
+	"gorm.io/gorm"
+)
+
+// Debits racing each other and credits on one user never take the balance
+// below zero, and every rupiah is accounted for: what is left is the start
+// plus the credits minus the debits that went through.
+func TestConcurrentDebitsNeverOverdraw(t *testing.T) {
+	// Parallel writers need their own connections, so no wrapping transaction
+	db := testutil.DB(t)
+
+	const start, debit, credit = int64(100000), int64(30000), int64(1000)
+	user := testutil.NewUser(t, db, models.User{Name: "Rebutan", Balance: start})
+	t.Cleanup(func() { db.Delete(&user) })
+
+	const debits, credits = 20, 20
+	var mu sync.Mutex
+	var debited, short int
+	var wg sync.WaitGroup
+	errs := make(chan error, debits+credits)
+	for i := 0; i < debits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return models.DebitBalance(tx, user.ID, debit)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				debited++
+			case errors.Is(err, models.ErrInsufficientBalance):
+				short++
+			default:
+				errs <- err
+			}
+		}()
+	}
+	for i := 0; i < credits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("balance", gorm.Expr("balance + ?", credit)).Error; err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	var after models.User
+	if err := db.Select("balance").First(&after, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if after.Balance < 0 {
+		t.Fatalf("balance went negative: %d", after.Balance)
+	}
+	if want := start + credits*credit - int64(debited)*debit; after.Balance != want {
+		t.Fatalf("expected balance %d after %d debits, got %d", want, debited, after.Balance)
+	}
+	// The start covers three debits and the credits one more at most
+	if debited < 3 || debited > 4 || short != debits-debited {
+		t.Fatalf("unexpected outcome: %d debited, %d short, balance %d", debited, short, after.Balance)
+	}
+}
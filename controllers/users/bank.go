@@ -0,0 +1,67 @@
+package users
+
+import (
+	"net/http"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// BankOption is a bank the app offers when registering an account.
+// InstantPayout says withdrawals to it can be paid out by KytaPay without
+// manual transfer, i.e. the bank has a gateway code.
+type BankOption struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Code          string `json:"code"`
+	LogoURL       string `json:"logo_url"`
+	InstantPayout bool   `json:"instant_payout"`
+}
+
+// GET /api/users/banks
+// Lists the active banks, by name, for the account registration form; these
+// are the only banks POST /users/bank accepts.
+func ListBanksHandler(w http.ResponseWriter, r *http.Request) {
+	var banks []models.Bank
+	if err := database.DB.Where("status = ?", "Active").Order("name ASC").Find(&banks).Error; err != nil {
+		utils.LogError(r, "ListBanksHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	items := make([]BankOption, 0, len(banks))
+	for _, b := range banks {
+		logoURL, err := utils.StoredImageURL(b.Logo, utils.ImageURLExpiry)
+		if err != nil {
+			utils.LogError(r, "ListBanksHandler: logo", err, "bank_id", b.ID)
+		}
+		items = append(items, BankOption{ID: b.ID, Name: b.Name, Code: b.Code, LogoURL: logoURL, InstantPayout: b.GatewayCode != ""})
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: items})
+}
+
+// registrableBank loads the bank an account is being registered at and
+// writes the refusal when the app's bank list would not offer it.
+func registrableBank(w http.ResponseWriter, r *http.Request, bankID uint) (models.Bank, bool) {
+	var bank models.Bank
+	if err := database.DB.First(&bank, bankID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank yang dipilih tidak tersedia", Code: utils.CodeBankUnavailable})
+			return bank, false
+		}
+		utils.LogError(r, "registrableBank", err, "bank_id", bankID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return bank, false
+	}
+	if bank.Status != "Active" {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeBankDisabled)
+		return bank, false
+	}
+	if !bankPayable(r, database.DB, &bank) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank ini belum mendukung penarikan otomatis, silakan pilih bank lain", Code: utils.CodeBankUnavailable})
+		return bank, false
+	}
+	return bank, true
+}
@@ -2,6 +2,7 @@ package users
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"regexp"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"project/models"
 	"project/utils"
 
+	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
@@ -30,7 +32,7 @@ func AddBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	req.AccountNumber = strings.TrimSpace(req.AccountNumber)
 
 	if req.BankID == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank tidak tersedia saat ini"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank tidak tersedia saat ini", Code: utils.CodeBankUnavailable})
 		return
 	}
 
@@ -42,41 +44,34 @@ func AddBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 
 	db := database.DB
 
-	// Validate bank exists and Active
-	var bank models.Bank
-	if err := db.First(&bank, req.BankID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank yang dipilih tidak tersedia"})
-			return
-		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
-		return
-	}
-	if bank.Status != "Active" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank yang dipilih tidak tersedia"})
+	// Only banks offered by GET /users/banks
+	bank, ok := registrableBank(w, r, req.BankID)
+	if !ok {
 		return
 	}
 
 	// Count user bank accounts (limit 3)
 	var cnt int64
 	if err := db.Model(&models.BankAccount{}).Where("user_id = ?", uid).Count(&cnt).Error; err != nil {
+		utils.LogError(r, "AddBankAccountHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 		return
 	}
 	if cnt >= 3 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Anda sudah mencapai batas maksimal 3 rekening bank"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Anda sudah mencapai batas maksimal 3 rekening bank", Code: utils.CodeBankAccountLimitReached})
 		return
 	}
 
-	// Duplicate check: user_id + bank_id + account_number
+	// Duplicate check: user_id + bank_id + normalized account_number
+	normalized := models.NormalizeAccountNumber(bank.Code, req.AccountNumber)
 	var dup models.BankAccount
-	if err := db.Where("user_id = ? AND bank_id = ? AND account_number = ?", uid, req.BankID, req.AccountNumber).First(&dup).Error; err != nil {
+	if err := db.Where("user_id = ? AND bank_id = ? AND account_number_normalized = ?", uid, req.BankID, normalized).First(&dup).Error; err != nil {
 		if err != gorm.ErrRecordNotFound {
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 			return
 		}
 	} else {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Rekening ini sudah pernah didaftarkan"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Rekening ini sudah pernah didaftarkan", Code: utils.CodeBankAccountDuplicate})
 		return
 	}
 
@@ -101,16 +96,23 @@ func AddBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	acc := models.BankAccount{
-		UserID:        uid,
-		BankID:        req.BankID,
-		AccountName:   req.AccountName,
-		AccountNumber: req.AccountNumber,
+		UserID:                  uid,
+		BankID:                  req.BankID,
+		AccountName:             req.AccountName,
+		AccountNumber:           req.AccountNumber,
+		AccountNumberNormalized: normalized,
 	}
 
 	if err := db.Create(&acc).Error; err != nil {
+		utils.LogError(r, "AddBankAccountHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 		return
 	}
+	// An account already registered by another user is allowed but flagged;
+	// withdrawals to it are held by the risk rules
+	if _, err := models.FlagSharedBankAccount(db, &acc); err != nil {
+		utils.LogError(r, "AddBankAccountHandler: flag shared", err, "bank_account_id", acc.ID)
+	}
 
 	// Load bank for response
 	_ = db.First(&bank, acc.BankID).Error
@@ -138,16 +140,12 @@ func GetBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	db := database.DB
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	var idStr string
-	if len(parts) >= 5 {
-		idStr = parts[4]
-	}
+	idStr := mux.Vars(r)["id"]
 	if idStr == "" {
 		// List all bank accounts for user
 		var accounts []models.BankAccount
 		if err := db.Where("user_id = ?", uid).Find(&accounts).Error; err != nil {
+			utils.LogError(r, "GetBankAccountHandler", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data rekening"})
 			return
 		}
@@ -175,7 +173,7 @@ func GetBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	// Get by id
 	var acc models.BankAccount
 	if err := db.Where("user_id = ? AND id = ?", uid, idStr).First(&acc).Error; err != nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Rekening tidak ditemukan"})
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Rekening tidak ditemukan", Code: utils.CodeBankAccountNotFound})
 		return
 	}
 	var bank models.Bank
@@ -222,7 +220,7 @@ func EditBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
 	var acc models.BankAccount
 	if err := db.Where("user_id = ? AND id = ?", uid, req.ID).First(&acc).Error; err != nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Rekening tidak ditemukan"})
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Rekening tidak ditemukan", Code: utils.CodeBankAccountNotFound})
 		return
 	}
 	update := map[string]interface{}{}
@@ -232,7 +230,10 @@ func EditBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	if req.AccountNumber != "" {
 		update["account_number"] = req.AccountNumber
 	}
-	if req.BankID != 0 {
+	if req.BankID != 0 && req.BankID != acc.BankID {
+		if _, ok := registrableBank(w, r, req.BankID); !ok {
+			return
+		}
 		update["bank_id"] = req.BankID
 	}
 	if len(update) == 0 {
@@ -240,9 +241,15 @@ func EditBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := db.Model(&acc).Updates(update).Error; err != nil {
+		utils.LogError(r, "EditBankAccountHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate rekening"})
 		return
 	}
+	if req.AccountNumber != "" || req.BankID != 0 {
+		if err := renormalizeBankAccount(db, acc.ID); err != nil {
+			utils.LogError(r, "EditBankAccountHandler: normalize", err, "bank_account_id", acc.ID)
+		}
+	}
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Rekening berhasil diupdate"})
 }
 
@@ -262,8 +269,43 @@ func DeleteBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	db := database.DB
 	if err := db.Where("user_id = ? AND id = ?", uid, req.ID).Delete(&models.BankAccount{}).Error; err != nil {
+		utils.LogError(r, "DeleteBankAccountHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus rekening"})
 		return
 	}
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Rekening berhasil dihapus"})
 }
+
+// bankPayable reports whether withdrawals to bank can be paid out. With
+// auto_withdraw on, payouts go straight to KytaPay, so the bank needs an
+// explicit gateway_code; approved by hand, any bank will do. A settings read
+// error does not block registration.
+func bankPayable(r *http.Request, db *gorm.DB, bank *models.Bank) bool {
+	setting, err := models.GetCachedSetting(db)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(r, "bankPayable", err)
+		}
+		return true
+	}
+	return !setting.AutoWithdraw || bank.GatewayCode != ""
+}
+
+// renormalizeBankAccount recomputes an edited account's normalized number and
+// flags it when another user holds the same account.
+func renormalizeBankAccount(db *gorm.DB, id uint) error {
+	var acc models.BankAccount
+	if err := db.Preload("Bank").First(&acc, id).Error; err != nil {
+		return err
+	}
+	code := ""
+	if acc.Bank != nil {
+		code = acc.Bank.Code
+	}
+	acc.AccountNumberNormalized = models.NormalizeAccountNumber(code, acc.AccountNumber)
+	if err := db.Model(&acc).Update("account_number_normalized", acc.AccountNumberNormalized).Error; err != nil {
+		return err
+	}
+	_, err := models.FlagSharedBankAccount(db, &acc)
+	return err
+}
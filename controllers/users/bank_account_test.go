@@ -0,0 +1,95 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+)
+
+// With auto_withdraw on, accounts can only be registered at banks KytaPay
+// has a code for; approved by hand, any active bank will do. Disabled banks
+// are neither listed nor accepted.
+func TestAddBankAccountBankChecks(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	setAutoWithdraw := func(on bool) {
+		if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, AutoWithdraw: on}).Error; err != nil {
+			t.Fatal(err)
+		}
+		models.InvalidateSettingCache()
+	}
+	t.Cleanup(models.InvalidateSettingCache)
+	suffix := time.Now().UnixNano() % 1000000000
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Rekening"})
+	unmapped := models.Bank{Name: "Bank Tanpa Kode", Code: fmt.Sprintf("NM%d", suffix), Status: "Active"}
+	mapped := models.Bank{Name: "Bank Berkode", Code: fmt.Sprintf("GM%d", suffix), GatewayCode: "GWCODE", Status: "Active"}
+	for _, b := range []*models.Bank{&unmapped, &mapped} {
+		if err := tx.Create(b).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	add := func(bankID uint, number string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"bank_id":%d,"account_name":"Pemilik Rekening","account_number":%q}`, bankID, number)
+		rec := httptest.NewRecorder()
+		AddBankAccountHandler(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/bank", strings.NewReader(body)), user.ID))
+		return rec
+	}
+
+	setAutoWithdraw(true)
+	if rec := add(unmapped.ID, "1234500001"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "BANK_UNAVAILABLE") {
+		t.Fatalf("unmapped bank with auto_withdraw: expected 400 BANK_UNAVAILABLE, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := add(mapped.ID, "1234500002"); rec.Code != http.StatusCreated {
+		t.Fatalf("mapped bank with auto_withdraw: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	setAutoWithdraw(false)
+	if rec := add(unmapped.ID, "1234500003"); rec.Code != http.StatusCreated {
+		t.Fatalf("unmapped bank without auto_withdraw: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The app's list offers active banks only, flagging instant payout
+	disabled := models.Bank{Name: "Bank Nonaktif", Code: fmt.Sprintf("DS%d", suffix), GatewayCode: "DSGW", Status: "Inactive"}
+	if err := tx.Create(&disabled).Error; err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	ListBanksHandler(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/banks", nil), user.ID))
+	var list struct {
+		Data []BankOption `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	offered := map[uint]BankOption{}
+	for _, b := range list.Data {
+		offered[b.ID] = b
+	}
+	if _, ok := offered[disabled.ID]; ok || !offered[mapped.ID].InstantPayout || offered[unmapped.ID].InstantPayout || offered[unmapped.ID].Code != unmapped.Code {
+		t.Fatalf("unexpected bank list %+v", list.Data)
+	}
+	if rec := add(disabled.ID, "1234500004"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "BANK_DISABLED") {
+		t.Fatalf("disabled bank: expected 400 BANK_DISABLED, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if got := (&models.Bank{Code: "BNC"}).PayoutCode(); got != "BNC" {
+		t.Fatalf("PayoutCode without mapping = %q, want BNC", got)
+	}
+	if got := mapped.PayoutCode(); got != "GWCODE" {
+		t.Fatalf("PayoutCode with mapping = %q, want GWCODE", got)
+	}
+}
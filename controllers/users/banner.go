@@ -0,0 +1,93 @@
+package users
+
+import (
+	"net/http"
+	"time"
+
+	"project/database"
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// bannerURLExpiry is how long presigned banner image URLs stay valid; the app
+// refetches the carousel far more often than this.
+const bannerURLExpiry = 24 * 60 * 60
+
+// maxBanners caps the carousel.
+const maxBanners = 20
+
+// BannerResponse is a banner as shown in the app carousel.
+type BannerResponse struct {
+	ID        uint    `json:"id"`
+	Title     string  `json:"title"`
+	ImageURL  string  `json:"image_url"`
+	Link      *string `json:"link"`
+	SortOrder int     `json:"sort_order"`
+}
+
+// GET /api/banners
+// Public; with a bearer token the list is filtered by the caller's VIP level,
+// anonymous visitors count as level 0. Only Active banners inside their
+// window are returned, in sort order. The banners come from the shared cache
+// and are filtered for the caller here.
+func BannerListHandler(w http.ResponseWriter, r *http.Request) {
+	db := database.DB.WithContext(r.Context())
+	level := uint(0)
+	if uid, ok := utils.GetUserID(r); ok && uid != 0 {
+		var user models.User
+		if err := db.Select("id, level").First(&user, uid).Error; err == nil && user.Level != nil {
+			level = *user.Level
+		}
+	}
+
+	banners, outcome, err := models.CachedBanners(db)
+	if err != nil {
+		utils.LogError(r, "BannerListHandler", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	w.Header().Set("X-Cache", string(outcome))
+
+	now := time.Now()
+	resp := make([]BannerResponse, 0, len(banners))
+	for _, b := range banners {
+		if len(resp) == maxBanners {
+			break
+		}
+		if !b.VisibleTo(level, now) {
+			continue
+		}
+		imageURL, err := bannerImageURL(b.Image)
+		if err != nil {
+			// A slide without its picture is worse than no slide
+			utils.LogError(r, "BannerListHandler: image url", err, "banner_id", b.ID)
+			continue
+		}
+		resp = append(resp, BannerResponse{ID: b.ID, Title: b.Title, ImageURL: imageURL, Link: b.Link, SortOrder: b.SortOrder})
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: resp})
+}
+
+// activeBanners returns the banners visible to a user of level at now.
+func activeBanners(db *gorm.DB, level uint, now time.Time) ([]models.Banner, error) {
+	var banners []models.Banner
+	err := db.
+		Where("status = ? AND starts_at <= ?", "Active", now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Where("min_level IS NULL OR min_level <= ?", level).
+		Where("max_level IS NULL OR max_level >= ?", level).
+		Where("image <> ''").
+		Order("sort_order ASC, starts_at DESC, id ASC").
+		Limit(maxBanners).
+		Find(&banners).Error
+	return banners, err
+}
+
+// bannerImageURL resolves a stored image: absolute URLs are used as is,
+// anything else is an object key in the upload bucket.
+func bannerImageURL(image string) (string, error) {
+	return utils.StoredImageURL(image, bannerURLExpiry)
+}
@@ -0,0 +1,55 @@
+package users
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+func TestActiveBannersWindowStatusAndLevel(t *testing.T) {
+	tx := testutil.Tx(t)
+	if err := tx.Where("1 = 1").Delete(&models.Banner{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	lvl := func(v uint) *uint { return &v }
+
+	banners := []models.Banner{
+		{Title: "second", Image: "banners/2.png", SortOrder: 2, StartsAt: earlier, Status: "Active"},
+		{Title: "first", Image: "banners/1.png", SortOrder: 1, StartsAt: earlier, EndsAt: &later, Status: "Active"},
+		{Title: "vip3", Image: "banners/3.png", SortOrder: 0, StartsAt: earlier, MinLevel: lvl(3), Status: "Active"},
+		{Title: "newbies", Image: "banners/4.png", SortOrder: 3, StartsAt: earlier, MaxLevel: lvl(0), Status: "Active"},
+		{Title: "inactive", Image: "banners/5.png", StartsAt: earlier, Status: "Inactive"},
+		{Title: "future", Image: "banners/6.png", StartsAt: later, Status: "Active"},
+		{Title: "ended", Image: "banners/7.png", StartsAt: now.Add(-2 * time.Hour), EndsAt: &earlier, Status: "Active"},
+		{Title: "no image", StartsAt: earlier, Status: "Active"},
+	}
+	for i := range banners {
+		if err := tx.Create(&banners[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	titles := func(level uint) []string {
+		got, err := activeBanners(tx, level, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, b := range got {
+			out = append(out, b.Title)
+		}
+		return out
+	}
+
+	if got, want := titles(0), []string{"first", "second", "newbies"}; !slices.Equal(got, want) {
+		t.Fatalf("level 0: expected %v, got %v", want, got)
+	}
+	if got, want := titles(3), []string{"vip3", "first", "second"}; !slices.Equal(got, want) {
+		t.Fatalf("level 3: expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,210 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"project/database"
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// campaignCacheControl lets the app reuse a campaign page for a minute; it is
+// private because the banners and eligibility follow the caller's VIP level.
+const campaignCacheControl = "private, max-age=60"
+
+// CampaignResponse is a campaign landing page, composed in one response.
+type CampaignResponse struct {
+	Slug        string  `json:"slug"`
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+	// StartsAt and EndsAt are the countdown boundaries: the app counts down
+	// to StartsAt before the campaign opens and to EndsAt while it runs
+	StartsAt     time.Time             `json:"starts_at"`
+	EndsAt       time.Time             `json:"ends_at"`
+	Banners      []BannerResponse      `json:"banners"`
+	Products     []CampaignProduct     `json:"products"`
+	DepositBonus *CampaignDepositBonus `json:"deposit_bonus"`
+}
+
+// CampaignProduct is a featured product with whether the caller's VIP level
+// may buy it.
+type CampaignProduct struct {
+	models.Product
+	Eligible bool `json:"eligible"`
+}
+
+// CampaignDepositBonus is the terms of the campaign's deposit bonus.
+// Available turns false once its budget is spent.
+type CampaignDepositBonus struct {
+	Name             string    `json:"name"`
+	MinAmount        int64     `json:"min_amount"`
+	BonusPercent     float64   `json:"bonus_percent"`
+	BonusFlat        int64     `json:"bonus_flat"`
+	FirstDepositOnly bool      `json:"first_deposit_only"`
+	StartsAt         time.Time `json:"starts_at"`
+	EndsAt           time.Time `json:"ends_at"`
+	Available        bool      `json:"available"`
+}
+
+// GET /api/users/campaigns/{slug}
+// The promo tab's whole screen in one call. The page is the same for every
+// user of a VIP level, so it carries an ETag and may be cached for 60
+// seconds; a matching If-None-Match answers 304. Inactive and ended
+// campaigns answer CAMPAIGN_NOT_FOUND.
+func CampaignHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	db := database.DB
+	now := time.Now()
+
+	var campaign models.Campaign
+	if err := db.Where("slug = ? AND status = ? AND ends_at > ?", mux.Vars(r)["slug"], "Active", now).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeCampaignNotFound)
+			return
+		}
+		utils.LogError(r, "CampaignHandler", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	var user models.User
+	if err := db.Select("id, level").First(&user, uid).Error; err != nil {
+		utils.LogError(r, "CampaignHandler: user", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	level := uint(0)
+	if user.Level != nil {
+		level = *user.Level
+	}
+
+	page, err := campaignPage(db, &campaign, level, now)
+	if err != nil {
+		utils.LogError(r, "CampaignHandler", err, "campaign_id", campaign.ID)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	resp := utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: page}
+	// Tagged before image keys become presigned URLs, which differ on every
+	// call, so the tag only changes with the campaign itself
+	etag, err := utils.ETag(resp)
+	if err != nil {
+		utils.LogError(r, "CampaignHandler: etag", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", campaignCacheControl)
+	if utils.NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	banners := page.Banners[:0]
+	for _, b := range page.Banners {
+		imageURL, err := bannerImageURL(b.ImageURL)
+		if err != nil {
+			utils.LogError(r, "CampaignHandler: banner image url", err, "banner_id", b.ID)
+			continue
+		}
+		b.ImageURL = imageURL
+		banners = append(banners, b)
+	}
+	page.Banners = banners
+	for i := range page.Products {
+		p := &page.Products[i]
+		imageURL, err := utils.StoredImageURL(p.Image, utils.ImageURLExpiry)
+		if err != nil {
+			// The app falls back to its placeholder
+			utils.LogError(r, "CampaignHandler: product image url", err, "product_id", p.ID)
+		}
+		p.ImageURL = imageURL
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// campaignPage composes campaign as a user of level sees it at now. Banner
+// ImageURLs hold the stored image, left for the caller to resolve.
+func campaignPage(db *gorm.DB, campaign *models.Campaign, level uint, now time.Time) (*CampaignResponse, error) {
+	campaigns := []models.Campaign{*campaign}
+	if err := models.LoadCampaignItems(db, campaigns); err != nil {
+		return nil, err
+	}
+	items := campaigns[0]
+	page := &CampaignResponse{
+		Slug:        campaign.Slug,
+		Title:       campaign.Title,
+		Description: campaign.Description,
+		StartsAt:    campaign.StartsAt,
+		EndsAt:      campaign.EndsAt,
+		Banners:     []BannerResponse{},
+		Products:    []CampaignProduct{},
+	}
+
+	if len(items.BannerIDs) > 0 {
+		banners, err := activeBanners(db.Where("id IN ?", items.BannerIDs), level, now)
+		if err != nil {
+			return nil, err
+		}
+		sortByPosition(banners, items.BannerIDs, func(b models.Banner) uint { return b.ID })
+		for _, b := range banners {
+			page.Banners = append(page.Banners, BannerResponse{ID: b.ID, Title: b.Title, ImageURL: b.Image, Link: b.Link, SortOrder: b.SortOrder})
+		}
+	}
+
+	if len(items.ProductIDs) > 0 {
+		var products []models.Product
+		if err := db.Where("id IN ? AND status = ?", items.ProductIDs, "Active").Find(&products).Error; err != nil {
+			return nil, err
+		}
+		sortByPosition(products, items.ProductIDs, func(p models.Product) uint { return p.ID })
+		boosts, err := models.ProfitBoostsBetween(db, now, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range products {
+			p.Boost = models.BoostFor(boosts, p.ID, p.CategoryID, now)
+			page.Products = append(page.Products, CampaignProduct{Product: p, Eligible: uint(p.RequiredVIP) <= level})
+		}
+	}
+
+	if campaign.DepositCampaignID != nil {
+		var dc models.DepositCampaign
+		err := db.Where("id = ? AND status = ? AND ends_at > ?", *campaign.DepositCampaignID, "Active", now).First(&dc).Error
+		switch {
+		case err == nil:
+			page.DepositBonus = &CampaignDepositBonus{
+				Name:             dc.Name,
+				MinAmount:        dc.MinAmount,
+				BonusPercent:     dc.BonusPercent,
+				BonusFlat:        dc.BonusFlat,
+				FirstDepositOnly: dc.FirstDepositOnly,
+				StartsAt:         dc.StartsAt,
+				EndsAt:           dc.EndsAt,
+				Available:        dc.Budget == 0 || dc.BudgetUsed < dc.Budget,
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// sortByPosition orders items as their ids appear in order.
+func sortByPosition[T any](items []T, order []uint, id func(T) uint) {
+	pos := make(map[uint]int, len(order))
+	for i, v := range order {
+		pos[v] = i
+	}
+	sort.SliceStable(items, func(i, j int) bool { return pos[id(items[i])] < pos[id(items[j])] })
+}
@@ -0,0 +1,85 @@
+package users
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+// The page keeps the campaign's order, shows banners and bonus terms as the
+// caller's level and the budget allow, and marks products above the level.
+func TestCampaignPage(t *testing.T) {
+	tx := testutil.Tx(t)
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	suffix := time.Now().UnixNano() % 1000000000
+	lvl := func(v uint) *uint { return &v }
+
+	category := testutil.NewCategory(t, tx, "unlocked")
+	products := []models.Product{
+		{CategoryID: category.ID, Name: "Starter", Amount: 100000, DailyProfit: 5000, Duration: 10, Status: "Active"},
+		{CategoryID: category.ID, Name: "VIP Only", Amount: 1000000, DailyProfit: 60000, Duration: 10, RequiredVIP: 3, Status: "Active"},
+		{CategoryID: category.ID, Name: "Retired", Amount: 100000, DailyProfit: 5000, Duration: 10, Status: "Inactive"},
+	}
+	for i := range products {
+		if err := tx.Create(&products[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	banners := []models.Banner{
+		{Title: "everyone", Image: "banners/a.png", StartsAt: earlier, Status: "Active"},
+		{Title: "vip", Image: "banners/b.png", StartsAt: earlier, MinLevel: lvl(3), Status: "Active"},
+		{Title: "second", Image: "banners/c.png", StartsAt: earlier, Status: "Active"},
+	}
+	for i := range banners {
+		if err := tx.Create(&banners[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	bonus := models.DepositCampaign{Name: "Bonus Gajian", MinAmount: 500000, BonusPercent: 5, StartsAt: earlier, EndsAt: now.Add(24 * time.Hour), Budget: 100000, BudgetUsed: 100000, Status: "Active"}
+	if err := tx.Create(&bonus).Error; err != nil {
+		t.Fatal(err)
+	}
+	campaign := models.Campaign{Slug: fmt.Sprintf("gajian-%d", suffix), Title: "Promo Gajian", DepositCampaignID: &bonus.ID, StartsAt: earlier, EndsAt: now.Add(24 * time.Hour), Status: "Active"}
+	if err := tx.Create(&campaign).Error; err != nil {
+		t.Fatal(err)
+	}
+	bannerIDs := []uint{banners[2].ID, banners[1].ID, banners[0].ID}
+	productIDs := []uint{products[1].ID, products[2].ID, products[0].ID}
+	if err := models.ReplaceCampaignItems(tx, campaign.ID, bannerIDs, productIDs); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := campaignPage(tx, &campaign, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Banners) != 2 || page.Banners[0].Title != "second" || page.Banners[1].Title != "everyone" {
+		t.Errorf("level 0 banners: %+v", page.Banners)
+	}
+	if len(page.Products) != 2 || page.Products[0].Name != "VIP Only" || page.Products[0].Eligible || !page.Products[1].Eligible {
+		t.Errorf("level 0 products: %+v", page.Products)
+	}
+	if page.DepositBonus == nil || page.DepositBonus.Available || page.DepositBonus.MinAmount != 500000 {
+		t.Errorf("expected the spent bonus shown as unavailable, got %+v", page.DepositBonus)
+	}
+
+	page, err = campaignPage(tx, &campaign, 3, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Banners) != 3 || !page.Products[0].Eligible {
+		t.Errorf("level 3 page: banners %d, products %+v", len(page.Banners), page.Products)
+	}
+
+	// The bonus drops out once deactivated
+	if err := tx.Model(&bonus).Update("status", "Inactive").Error; err != nil {
+		t.Fatal(err)
+	}
+	if page, err = campaignPage(tx, &campaign, 0, now); err != nil || page.DepositBonus != nil {
+		t.Errorf("expected no deposit bonus, got %+v (%v)", page.DepositBonus, err)
+	}
+}
@@ -0,0 +1,83 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"project/controllers"
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestInvestmentCertificateIssuedAndVerified(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Sertifikat"})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Cert 1", Amount: 2500000, DailyProfit: 50000})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/investments", strings.NewReader(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true}`, user.ID, product.ID)))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		rec := httptest.NewRecorder()
+		h.AdminCreate(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	var invs []models.Investment
+	if err := tx.Where("user_id = ?", user.ID).Order("id ASC").Find(&invs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(invs) != 2 || invs[0].CertificateNo == nil || invs[1].CertificateNo == nil || invs[0].CertifiedAt == nil {
+		t.Fatalf("expected both investments certified, got %+v", invs)
+	}
+	year := time.Now().In(utils.AppLocation()).Year()
+	seq := func(certNo string) int {
+		n, _ := strconv.Atoi(certNo[strings.LastIndex(certNo, "-")+1:])
+		return n
+	}
+	if !strings.HasPrefix(*invs[0].CertificateNo, fmt.Sprintf("%s-%d-", models.CertificatePrefix, year)) || seq(*invs[1].CertificateNo) != seq(*invs[0].CertificateNo)+1 {
+		t.Fatalf("expected consecutive %s-%d numbers, got %s and %s", models.CertificatePrefix, year, *invs[0].CertificateNo, *invs[1].CertificateNo)
+	}
+
+	verify := func(certNo string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v3/verify/x", nil), map[string]string{"certificate_no": certNo})
+		rec := httptest.NewRecorder()
+		controllers.VerifyCertificateHandler(rec, req)
+		return rec
+	}
+	rec := verify(strings.ToLower(*invs[0].CertificateNo))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["product"] != "Cert 1" || resp.Data["amount_band"] != "Rp1 juta - Rp10 juta" || resp.Data["status"] != "Running" {
+		t.Fatalf("unexpected verification: %+v", resp.Data)
+	}
+	if strings.Contains(rec.Body.String(), user.Number) || strings.Contains(rec.Body.String(), "2500000") {
+		t.Fatalf("verification leaks the owner or exact amount: %s", rec.Body.String())
+	}
+	if rec := verify(fmt.Sprintf("%s-%d-999999999", models.CertificatePrefix, year)); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown certificate: expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,179 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"project/alert"
+	"project/kyta"
+	"project/models"
+	"project/referral"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// isChargeback reports whether a callback status means the gateway took a
+// settled payment back from the merchant.
+func isChargeback(status string) bool {
+	return status == "CHARGEBACK" || status == "REVERSED" || status == "REFUNDED"
+}
+
+// confirmChargeback asks the gateway whether referenceID was really reversed.
+// The callback URL is unauthenticated, so a chargeback is only acted on once
+// the gateway's own record says so. A client that cannot look payments up,
+// or a reference the gateway does not know, leaves it unconfirmed; any other
+// failure is returned so the callback is retried.
+func (h *InvestmentHandler) confirmChargeback(ctx context.Context, referenceID string) (bool, string, error) {
+	pc, ok := h.Kyta.(kyta.PaymentChecker)
+	if !ok {
+		return false, "", nil
+	}
+	resp, err := pc.CheckPayment(ctx, referenceID)
+	var ke *kyta.Error
+	if errors.Is(err, kyta.ErrCheckUnsupported) || (errors.As(err, &ke) && ke.Status == http.StatusNotFound) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	status := strings.ToUpper(strings.TrimSpace(resp.ResponseData.Status))
+	return isChargeback(status), status, nil
+}
+
+// applyChargeback handles a payment reversed after it was settled. Nothing
+// changes until the gateway confirms the reversal; an unconfirmed one is
+// alerted and ignored. A confirmed one suspends the investment and claws back
+// the referral bonus it funded following REFERRAL_CLAWBACK_POLICY; profit
+// already paid is left for ops, who are alerted. Replays are harmless because a reversed bonus is skipped.
+// Like the payment callback, the reference's order type routes deposits and
+// investment top-ups to their own handling.
+func (h *InvestmentHandler) applyChargeback(w http.ResponseWriter, r *http.Request, referenceID string) {
+	confirmed, status, err := h.confirmChargeback(r.Context(), referenceID)
+	if err != nil {
+		// A 5xx makes the gateway retry the callback
+		utils.LogError(r, "payment webhook: confirm chargeback", err, "reference_id", referenceID)
+		utils.WriteJSON(w, http.StatusBadGateway, utils.APIResponse{Success: false, Message: "Gagal memeriksa status pembayaran"})
+		return
+	}
+	if !confirmed {
+		h.Alerts.Notify(alert.KeyWebhookRejected, "Chargeback %s ditolak: tidak dikonfirmasi gateway (status %q, ip %s)", referenceID, status, r.RemoteAddr)
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Ignored"})
+		return
+	}
+
+	order, _ := utils.ParseOrderID(referenceID)
+	switch order.Type {
+	case utils.OrderDeposit:
+		// A reversed wallet top-up cannot be unwound safely once the balance
+		// was spent
+		h.Alerts.Notify(alert.KeyChargeback+":"+referenceID, "Chargeback deposit %s: periksa saldo pengguna secara manual", referenceID)
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Ignored"})
+		return
+	case utils.OrderTopup:
+		h.applyTopupChargeback(w, r, referenceID)
+		return
+	}
+
+	ignored := false
+	var inv models.Investment
+	var res referral.Result
+	err = utils.WithTxOptions(r.Context(), h.DB, paymentTxOptions, func(tx *gorm.DB) error {
+		ignored, inv, res = false, models.Investment{}, referral.Result{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", referenceID).First(&inv).Error; err != nil {
+			return err
+		}
+		// Never settled, so there is nothing to reverse
+		if inv.Status == "Pending" {
+			ignored = true
+			return nil
+		}
+		if inv.Status == "Running" {
+			if err := tx.Model(&inv).Update("status", "Suspended").Error; err != nil {
+				return err
+			}
+		}
+		var err error
+		res, err = referral.Clawback(tx, inv.OrderID, referral.Policy(), "chargeback pembayaran")
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogError(r, "payment webhook: chargeback", err, "reference_id", referenceID)
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pembayaran tidak ditemukan", Code: utils.CodePaymentNotFound})
+		return
+	}
+	if err != nil {
+		// A 5xx makes the gateway retry the callback
+		utils.LogError(r, "payment webhook: chargeback", err, "reference_id", referenceID, "investment_id", inv.ID)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	if ignored {
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Ignored"})
+		return
+	}
+
+	h.Alerts.Notify(alert.KeyChargeback+":"+inv.OrderID, "Chargeback investasi %s (Rp%d, user #%d): status %s, bonus rekomendasi Rp%d ditarik Rp%d",
+		inv.OrderID, inv.Amount, inv.UserID, inv.Status, res.Bonus, res.Recovered)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "OK"})
+}
+
+// applyTopupChargeback handles a reversed investment top-up. A settled one
+// raised the investment's principal and rate with money the gateway took
+// back, so the investment is suspended like a reversed purchase; top-ups pay
+// no referral bonus. One that failed may have been refunded to the balance
+// because its investment had stopped, which is alerted for a manual check
+// like a deposit.
+func (h *InvestmentHandler) applyTopupChargeback(w http.ResponseWriter, r *http.Request, referenceID string) {
+	var topup models.InvestmentTopup
+	var inv models.Investment
+	err := utils.WithTxOptions(r.Context(), h.DB, paymentTxOptions, func(tx *gorm.DB) error {
+		topup, inv = models.InvestmentTopup{}, models.Investment{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", referenceID).First(&topup).Error; err != nil {
+			return err
+		}
+		if topup.Status != "Success" {
+			return nil
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, topup.InvestmentID).Error; err != nil {
+			return err
+		}
+		if inv.Status == "Running" {
+			return tx.Model(&inv).Update("status", "Suspended").Error
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogError(r, "payment webhook: top-up chargeback", err, "reference_id", referenceID)
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pembayaran tidak ditemukan", Code: utils.CodePaymentNotFound})
+		return
+	}
+	if err != nil {
+		// A 5xx makes the gateway retry the callback
+		utils.LogError(r, "payment webhook: top-up chargeback", err, "reference_id", referenceID, "investment_id", topup.InvestmentID)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	switch topup.Status {
+	case "Pending":
+		// Never settled, so there is nothing to reverse
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Ignored"})
+	case "Failed":
+		h.Alerts.Notify(alert.KeyChargeback+":"+referenceID, "Chargeback tambah modal %s (Rp%d, user #%d): periksa saldo pengguna secara manual", referenceID, topup.Amount, topup.UserID)
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Ignored"})
+	default:
+		h.Alerts.Notify(alert.KeyChargeback+":"+referenceID, "Chargeback tambah modal %s (Rp%d, user #%d): investasi %s status %s",
+			referenceID, topup.Amount, topup.UserID, inv.OrderID, inv.Status)
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "OK"})
+	}
+}
@@ -0,0 +1,224 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/referral"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestChargebackClawsBackReferralBonus(t *testing.T) {
+	for _, policy := range []string{referral.PolicyNegative, referral.PolicyPartial} {
+		t.Run(policy, func(t *testing.T) {
+			tx := testutil.Tx(t)
+			t.Setenv("REFERRAL_CLAWBACK_POLICY", policy)
+
+			referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+			user := testutil.NewUser(t, tx, models.User{Name: "Penipu", ReffBy: &referrer.ID})
+			category := testutil.NewCategory(t, tx, "unlocked")
+			product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Chargeback 1"})
+
+			gateway := &testutil.Kyta{}
+			h := NewInvestmentHandler(tx, gateway)
+			rec := httptest.NewRecorder()
+			h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var inv models.Investment
+			if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+				t.Fatal(err)
+			}
+			callback := func(status string) *httptest.ResponseRecorder {
+				body := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-1","reference_id":%q,"amount":%d,"status":%q}}`, inv.OrderID, inv.Amount, status)
+				rec := httptest.NewRecorder()
+				h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(body)))
+				return rec
+			}
+			if rec := callback("SUCCESS"); rec.Code != http.StatusOK {
+				t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var bonus models.Transaction
+			if err := tx.Where("user_id = ? AND transaction_type = ?", referrer.ID, "team").First(&bonus).Error; err != nil {
+				t.Fatal(err)
+			}
+			if bonus.SourceOrderID == nil || *bonus.SourceOrderID != inv.OrderID {
+				t.Fatalf("expected bonus linked to %s, got %v", inv.OrderID, bonus.SourceOrderID)
+			}
+			// The referrer already withdrew part of the bonus
+			spent := bonus.Amount / 2
+			if err := tx.Model(&models.User{}).Where("id = ?", referrer.ID).Update("balance", bonus.Amount-spent).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			gateway.Statuses = map[string]string{inv.OrderID: "CHARGEBACK"}
+			for n := 1; n <= 2; n++ {
+				if rec := callback("CHARGEBACK"); rec.Code != http.StatusOK {
+					t.Fatalf("chargeback %d: expected 200, got %d: %s", n, rec.Code, rec.Body.String())
+				}
+			}
+
+			var ref models.User
+			tx.First(&ref, referrer.ID)
+			want := -spent
+			if policy == referral.PolicyPartial {
+				want = 0
+			}
+			if ref.Balance != want {
+				t.Fatalf("expected referrer balance %d, got %d", want, ref.Balance)
+			}
+			var clawbacks int64
+			tx.Model(&models.Transaction{}).Where("user_id = ? AND transaction_type = ? AND source_order_id = ?", referrer.ID, "referral_clawback", bonus.OrderID).Count(&clawbacks)
+			if clawbacks != 1 {
+				t.Fatalf("expected one clawback transaction, got %d", clawbacks)
+			}
+			if err := tx.First(&inv, inv.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if inv.Status != "Suspended" {
+				t.Fatalf("expected Suspended, got %s", inv.Status)
+			}
+		})
+	}
+}
+
+// A reversed gateway top-up is looked up as a top-up, not as an investment:
+// unpaid it is ignored, settled it suspends the investment it added to.
+func TestChargebackOfInvestmentTopup(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Tarik"})
+	category := testutil.NewCategory(t, tx, "locked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Chargeback 2", Duration: 3, TopupMin: 10000, TopupMax: 200000})
+	next := time.Now().Add(time.Hour)
+	inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: 100000, DailyProfit: 5000, Duration: 3,
+		NextReturnAt: &next, OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Running"}
+	if err := tx.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := utils.GenerateOrderID(utils.OrderTopup, user.ID)
+	gateway := &testutil.Kyta{}
+	h := NewInvestmentHandler(tx, gateway)
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v3/users/investments/%d/topup", inv.ID), strings.NewReader(`{"amount":20000,"payment_method":"QRIS"}`))
+	rec := httptest.NewRecorder()
+	h.Topup(rec, testutil.AsUser(mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(inv.ID)}), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("top-up: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data TopupResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	callback := func(reference, status string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-cbt","reference_id":%q,"amount":%d,"status":%q}}`, reference, created.Data.GrossAmount, status)
+		rec := httptest.NewRecorder()
+		h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(body)))
+		return rec
+	}
+	status := func() string {
+		var got models.Investment
+		if err := tx.First(&got, inv.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return got.Status
+	}
+	gateway.Statuses = map[string]string{created.Data.OrderID: "CHARGEBACK", unknown: "CHARGEBACK"}
+
+	if rec := callback(created.Data.OrderID, "CHARGEBACK"); rec.Code != http.StatusOK || status() != "Running" {
+		t.Fatalf("unpaid top-up: expected 200 with the investment Running, got %d (%s): %s", rec.Code, status(), rec.Body.String())
+	}
+	if rec := callback(created.Data.OrderID, "SUCCESS"); rec.Code != http.StatusOK {
+		t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := callback(created.Data.OrderID, "CHARGEBACK"); rec.Code != http.StatusOK || status() != "Suspended" {
+		t.Fatalf("settled top-up: expected 200 with the investment Suspended, got %d (%s): %s", rec.Code, status(), rec.Body.String())
+	}
+	if rec := callback(unknown, "CHARGEBACK"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown top-up: expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// The callback URL is unauthenticated: a chargeback the gateway does not
+// confirm, because it still reports the payment settled or does not know the
+// reference, leaves the investment, the referral bonus and every balance as
+// they were.
+func TestUnconfirmedChargebackChangesNothing(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Jujur", ReffBy: &referrer.ID})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Chargeback 3"})
+
+	gateway := &testutil.Kyta{}
+	h := NewInvestmentHandler(tx, gateway)
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	callback := func(status string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-1","reference_id":%q,"amount":%d,"status":%q}}`, inv.OrderID, inv.Amount, status)
+		rec := httptest.NewRecorder()
+		h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(body)))
+		return rec
+	}
+	if rec := callback("SUCCESS"); rec.Code != http.StatusOK {
+		t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var before models.User
+	if err := tx.First(&before, referrer.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		statuses map[string]string
+	}{
+		{"still settled", map[string]string{inv.OrderID: "SUCCESS"}},
+		{"unknown reference", nil},
+	} {
+		gateway.Statuses = tc.statuses
+		if rec := callback("CHARGEBACK"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Ignored") {
+			t.Fatalf("%s: expected 200 Ignored, got %d: %s", tc.name, rec.Code, rec.Body.String())
+		}
+	}
+
+	var got models.Investment
+	if err := tx.First(&got, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "Running" {
+		t.Fatalf("expected Running, got %s", got.Status)
+	}
+	var after models.User
+	if err := tx.First(&after, referrer.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if after.Balance != before.Balance || before.Balance == 0 {
+		t.Fatalf("expected referrer balance to stay %d, got %d", before.Balance, after.Balance)
+	}
+	var clawbacks int64
+	tx.Model(&models.Transaction{}).Where("user_id = ? AND transaction_type = ?", referrer.ID, "referral_clawback").Count(&clawbacks)
+	if clawbacks != 0 {
+		t.Fatalf("expected no clawback, got %d", clawbacks)
+	}
+}
@@ -0,0 +1,275 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"project/i18n"
+	"project/kyta"
+	"project/models"
+	"project/money"
+	"project/utils"
+	"project/webhook"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DepositHandler serves balance top-ups. Deposits are paid through the same
+// gateway as investments; their callbacks arrive on the investment webhook,
+// which hands DEP- order ids to settleDeposit.
+type DepositHandler struct {
+	DB   *gorm.DB
+	Kyta kyta.Client
+}
+
+func NewDepositHandler(db *gorm.DB, kc kyta.Client) *DepositHandler {
+	return &DepositHandler{DB: db, Kyta: kc}
+}
+
+type CreateDepositRequest struct {
+	Amount         int64  `json:"amount" validate:"required,gt=0"`
+	PaymentMethod  string `json:"payment_method" validate:"required,oneof=QRIS BANK"`
+	PaymentChannel string `json:"payment_channel" validate:"required_if=PaymentMethod BANK"`
+}
+
+// Normalize upper-cases the method and channel so "qris" and " bca " are accepted.
+func (req *CreateDepositRequest) Normalize() {
+	req.PaymentMethod = strings.ToUpper(strings.TrimSpace(req.PaymentMethod))
+	req.PaymentChannel = strings.ToUpper(strings.TrimSpace(req.PaymentChannel))
+}
+
+// POST /api/users/deposits
+func (h *DepositHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateDepositRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	method := req.PaymentMethod
+	channel := req.PaymentChannel
+	if method == "BANK" {
+		if !vaChannel(channel) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentBankInvalid), Code: utils.CodeBankUnavailable})
+			return
+		}
+	}
+
+	db := h.DB
+	setting, err := models.GetCachedSetting(db)
+	if err != nil {
+		utils.LogError(r, "CreateDepositHandler: load settings", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if req.Amount < money.FromFloat(setting.MinDeposit) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgDepositMin, setting.MinDeposit), Code: utils.CodeDepositAmountRange})
+		return
+	}
+	if req.Amount > money.FromFloat(setting.MaxDeposit) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgDepositMax, setting.MaxDeposit), Code: utils.CodeDepositAmountRange})
+		return
+	}
+	// Deposits pay no channel fee and are not switched off with the
+	// channels; only the gateway's bounds apply
+	if option := evaluatePayment(method, channel, nil, req.Amount, ""); !option.Available {
+		writePaymentUnavailable(w, r, option)
+		return
+	}
+
+	orderID := utils.GenerateOrderID(utils.OrderDeposit, uid)
+	var payResp *kyta.PaymentResponse
+	if method == "QRIS" {
+		payResp, err = h.Kyta.CreateQRIS(r.Context(), kyta.PaymentRequest{ReferenceID: orderID, Amount: req.Amount})
+	} else {
+		payResp, err = h.Kyta.CreateVA(r.Context(), kyta.PaymentRequest{ReferenceID: orderID, Amount: req.Amount, BankCode: channel})
+	}
+	if errors.Is(err, kyta.ErrNotConfigured) {
+		utils.LogError(r, "CreateDepositHandler: kytapay", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "CreateDepositHandler: kytapay create payment", err)
+		utils.WriteError(w, r, http.StatusBadGateway, utils.CodePaymentGatewayError)
+		return
+	}
+	if payResp == nil {
+		utils.WriteJSON(w, http.StatusBadGateway, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPaymentGatewayNoResponse), Code: utils.CodePaymentGatewayError})
+		return
+	}
+
+	code, link, expiredAt := gatewayPaymentDetails(method, payResp)
+	deposit := models.Deposit{
+		UserID:        uid,
+		Amount:        req.Amount,
+		OrderID:       orderID,
+		PaymentMethod: method,
+		PaymentCode:   code,
+		PaymentLink:   link,
+		Status:        "Pending",
+		ExpiredAt:     expiredAt,
+	}
+	if method == "BANK" {
+		deposit.PaymentChannel = &channel
+	}
+	deposit.ReferenceID = &orderID
+	deposit.GatewayPaymentID = payResp.PaymentID()
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&deposit).Error; err != nil {
+			return err
+		}
+		msg := "Deposit saldo"
+		trx := models.Transaction{
+			UserID:          uid,
+			Amount:          deposit.Amount,
+			Charge:          0,
+			OrderID:         deposit.OrderID,
+			TransactionFlow: "debit",
+			TransactionType: "deposit",
+			Message:         &msg,
+			Status:          "Pending",
+		}
+		return tx.Create(&trx).Error
+	}); err != nil {
+		utils.LogError(r, "CreateDepositHandler: save deposit", err, "order_id", orderID, "gateway_payment_id", utils.GetStringValue(deposit.GatewayPaymentID))
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgDepositCreateFailed)})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgDepositCreated), Data: newDepositResponse(deposit)})
+}
+
+// GET /api/users/deposits
+func (h *DepositHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	status := strings.TrimSpace(r.URL.Query().Get("status"))
+
+	query := h.DB.Model(&models.Deposit{}).Where("user_id = ?", uid)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ListDepositsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgDepositListFailed)})
+		return
+	}
+
+	var rows []models.Deposit
+	if err := query.Order("id DESC").Limit(pg.Limit).Offset(pg.Offset).Find(&rows).Error; err != nil {
+		utils.LogError(r, "ListDepositsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgDepositListFailed)})
+		return
+	}
+
+	items := make([]DepositResponse, len(rows))
+	for i, d := range rows {
+		items[i] = newDepositResponse(d)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: utils.NewPaginated(items, pg, totalRows)})
+}
+
+// DepositResponse is a deposit as shown to its owner.
+type DepositResponse struct {
+	ID             uint    `json:"id"`
+	OrderID        string  `json:"order_id"`
+	Amount         int64   `json:"amount"`
+	PaymentMethod  string  `json:"payment_method"`
+	PaymentChannel *string `json:"payment_channel,omitempty"`
+	PaymentCode    *string `json:"payment_code,omitempty"`
+	PaymentLink    *string `json:"payment_link,omitempty"`
+	Status         string  `json:"status"`
+	ExpiredAt      *string `json:"expired_at,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+func newDepositResponse(d models.Deposit) DepositResponse {
+	resp := DepositResponse{
+		ID:             d.ID,
+		OrderID:        d.OrderID,
+		Amount:         d.Amount,
+		PaymentMethod:  d.PaymentMethod,
+		PaymentChannel: d.PaymentChannel,
+		Status:         d.Status,
+		ExpiredAt:      utils.FormatTimePtr(d.ExpiredAt),
+		CreatedAt:      utils.FormatTime(d.CreatedAt),
+	}
+	// Payment instructions are only useful while the deposit can still be paid
+	if d.Status == "Pending" {
+		resp.PaymentCode = d.PaymentCode
+		resp.PaymentLink = d.PaymentLink
+	}
+	return resp
+}
+
+// settleDeposit applies a gateway callback to the deposit with orderID. A
+// successful payment credits the user's balance, marks the deposit
+// transaction Success and pays any campaign bonus; a failed one marks both
+// Failed. The deposit row is
+// locked, so a repeated callback finds it no longer Pending and returns
+// ignored without touching the balance again. The locked deposit is returned
+// so the caller can notify its owner once the transaction has committed.
+func settleDeposit(db *gorm.DB, orderID, paymentID string, success bool) (deposit models.Deposit, ignored bool, err error) {
+	err = utils.WithTxOptions(db.Statement.Context, db, paymentTxOptions, func(tx *gorm.DB) error {
+		deposit, ignored = models.Deposit{}, false
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&deposit).Error; err != nil {
+			return err
+		}
+		if deposit.Status != "Pending" {
+			ignored = true
+			return nil
+		}
+
+		status := "Failed"
+		if success {
+			status = "Success"
+		}
+		updates := map[string]interface{}{"status": status}
+		if paymentID != "" {
+			updates["gateway_payment_id"] = paymentID
+		}
+		if err := tx.Model(&deposit).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", deposit.OrderID).Update("status", status).Error; err != nil {
+			return err
+		}
+		if !success {
+			return nil
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", deposit.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", deposit.Amount)).Error; err != nil {
+			return err
+		}
+		if err := applyDepositCampaign(tx, deposit.UserID, deposit.Amount, deposit.OrderID); err != nil {
+			return err
+		}
+		gatewayID := paymentID
+		if gatewayID == "" {
+			gatewayID = utils.GetStringValue(deposit.GatewayPaymentID)
+		}
+		return webhook.Record(tx, webhook.EventDepositConfirmed, deposit.UserID, map[string]interface{}{
+			"deposit_id": deposit.ID, "order_id": deposit.OrderID, "amount": deposit.Amount, "payment_method": deposit.PaymentMethod, "gateway_payment_id": gatewayID,
+		})
+	})
+	return deposit, ignored, err
+}
@@ -0,0 +1,81 @@
+package users
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// applyDepositCampaign pays the best running campaign bonus for a successful
+// gateway payment of amount by userID. Campaigns do not stack: the largest
+// bonus whose remaining budget covers it is paid, as a "campaign_bonus"
+// transaction. It must run inside the transaction that settles orderID.
+func applyDepositCampaign(tx *gorm.DB, userID uint, amount int64, orderID string) error {
+	now := time.Now()
+	var campaigns []models.DepositCampaign
+	if err := tx.Where("status = 'Active' AND starts_at <= ? AND ends_at > ? AND min_amount <= ?", now, now, amount).
+		Where("budget = 0 OR budget_used < budget").
+		Find(&campaigns).Error; err != nil {
+		return err
+	}
+	if len(campaigns) == 0 {
+		return nil
+	}
+
+	firstDeposit := false
+	for _, c := range campaigns {
+		if c.FirstDepositOnly {
+			var earlier int64
+			if err := tx.Model(&models.Transaction{}).
+				Where("user_id = ? AND transaction_type IN ? AND status = 'Success' AND order_id <> ?", userID, []string{"deposit", "investment"}, orderID).
+				Count(&earlier).Error; err != nil {
+				return err
+			}
+			firstDeposit = earlier == 0
+			break
+		}
+	}
+
+	sort.SliceStable(campaigns, func(i, j int) bool {
+		return campaigns[i].BonusFor(amount) > campaigns[j].BonusFor(amount)
+	})
+	for _, c := range campaigns {
+		bonus := c.BonusFor(amount)
+		if bonus <= 0 || (c.FirstDepositOnly && !firstDeposit) {
+			continue
+		}
+		// Re-check the window and budget in the UPDATE itself so a campaign
+		// that just ended or ran out is never charged past its cap
+		res := tx.Model(&models.DepositCampaign{}).
+			Where("id = ? AND status = 'Active' AND ends_at > ? AND (budget = 0 OR budget_used + ? <= budget)", c.ID, now, bonus).
+			UpdateColumn("budget_used", gorm.Expr("budget_used + ?", bonus))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("balance", gorm.Expr("balance + ?", bonus)).Error; err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("Bonus %s", c.Name)
+		trx := models.Transaction{
+			UserID:          userID,
+			Amount:          bonus,
+			Charge:          0,
+			OrderID:         utils.GenerateOrderID(utils.OrderBonus, userID),
+			TransactionFlow: "debit",
+			TransactionType: "campaign_bonus",
+			Message:         &msg,
+			Status:          "Success",
+		}
+		return tx.Create(&trx).Error
+	}
+	return nil
+}
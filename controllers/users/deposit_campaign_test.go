@@ -0,0 +1,57 @@
+package users
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+func TestDepositCampaignBudgetCap(t *testing.T) {
+	tx := testutil.Tx(t)
+	suffix := time.Now().UnixNano() % 1000000000
+	user := testutil.NewUser(t, tx, models.User{Name: "Promo"})
+	now := time.Now()
+	campaign := models.DepositCampaign{
+		Name: "Promo 500k", MinAmount: 500000, BonusPercent: 5,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Budget: 40000, Status: "Active",
+	}
+	ended := models.DepositCampaign{
+		Name: "Ended", MinAmount: 0, BonusFlat: 99999,
+		StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour), Status: "Active",
+	}
+	if err := tx.Create(&campaign).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&ended).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 5% of 600k = 30k fits the 40k budget once; the second payment would overshoot it
+	for i, amount := range []int64{600000, 600000, 100000} {
+		if err := applyDepositCampaign(tx, user.ID, amount, fmt.Sprintf("DEP-test-%d-%d", suffix, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got models.User
+	if err := tx.First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != 30000 {
+		t.Fatalf("expected exactly one 30000 bonus, balance %d", got.Balance)
+	}
+	if err := tx.First(&campaign, campaign.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if campaign.BudgetUsed != 30000 {
+		t.Fatalf("expected budget_used 30000, got %d", campaign.BudgetUsed)
+	}
+	var bonuses int64
+	tx.Model(&models.Transaction{}).Where("user_id = ? AND transaction_type = 'campaign_bonus'", user.ID).Count(&bonuses)
+	if bonuses != 1 {
+		t.Fatalf("expected one campaign_bonus transaction, got %d", bonuses)
+	}
+}
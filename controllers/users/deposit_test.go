@@ -0,0 +1,76 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/models"
+	"project/testutil"
+)
+
+func TestDepositTopUp(t *testing.T) {
+	tx := testutil.Tx(t)
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, MinDeposit: 20000, MaxDeposit: 5000000}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Depositor"})
+
+	gateway := &testutil.Kyta{}
+	deposits := NewDepositHandler(tx, gateway)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		deposits.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/deposits", strings.NewReader(body)), user.ID))
+		return rec
+	}
+
+	if rec := post(`{"amount":10000,"payment_method":"QRIS"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("below min_deposit: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(`{"amount":100000,"payment_method":"QRIS"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("deposit: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(gateway.Payments) != 1 || !strings.HasPrefix(gateway.Payments[0].ReferenceID, "DEP-") {
+		t.Fatalf("expected one DEP- gateway payment, got %+v", gateway.Payments)
+	}
+	orderID := gateway.Payments[0].ReferenceID
+
+	// The shared payment webhook credits the balance once, even when replayed
+	webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-d","reference_id":%q,"amount":100000,"status":"SUCCESS"}}`, orderID)
+	investments := NewInvestmentHandler(tx, gateway)
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		investments.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("webhook %d: expected 200, got %d: %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+	var got models.User
+	if err := tx.First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != 100000 {
+		t.Fatalf("expected balance 100000, got %d", got.Balance)
+	}
+	var trx models.Transaction
+	if err := tx.Where("order_id = ?", orderID).First(&trx).Error; err != nil {
+		t.Fatal(err)
+	}
+	if trx.TransactionType != "deposit" || trx.Status != "Success" {
+		t.Fatalf("unexpected deposit transaction %+v", trx)
+	}
+
+	rec := httptest.NewRecorder()
+	deposits.List(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/deposits?status=Success", nil), user.ID))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), orderID) {
+		t.Fatalf("history: expected the deposit, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
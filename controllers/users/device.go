@@ -0,0 +1,133 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Platform string `json:"platform" validate:"required,oneof=android ios web"`
+}
+
+// Normalize trims the token and lower-cases the platform.
+func (req *RegisterDeviceRequest) Normalize() {
+	req.Token = strings.TrimSpace(req.Token)
+	req.Platform = strings.ToLower(strings.TrimSpace(req.Platform))
+}
+
+// POST /api/users/devices
+// Registers the app's push token for the caller. Called on every app start, so
+// an existing token just has its owner and last_seen_at refreshed.
+func RegisterDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	var req RegisterDeviceRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	device := models.UserDevice{UserID: uid, Token: req.Token, Platform: req.Platform, LastSeenAt: time.Now()}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "last_seen_at", "updated_at"}),
+	}).Create(&device).Error; err != nil {
+		utils.LogError(r, "RegisterDeviceHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgDeviceRegisterFailed)})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgDeviceRegistered)})
+}
+
+type NotificationPreferenceRequest struct {
+	Payment    *bool `json:"payment"`
+	Profit     *bool `json:"profit"`
+	Withdrawal *bool `json:"withdrawal"`
+}
+
+// GET /api/users/notification-preferences
+func GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	pref, err := loadNotificationPreference(database.DB, uid)
+	if err != nil {
+		utils.LogError(r, "GetNotificationPreferencesHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPreferencesLoadFailed)})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: pref})
+}
+
+// PUT /api/users/notification-preferences
+// Omitted fields keep their current value.
+func UpdateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	var req NotificationPreferenceRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	db := database.DB
+	pref, err := loadNotificationPreference(db, uid)
+	if err != nil {
+		utils.LogError(r, "UpdateNotificationPreferencesHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPreferencesLoadFailed)})
+		return
+	}
+	applyNotificationPreferenceRequest(&pref, &req)
+
+	// Save upserts on the user_id primary key and writes false values too
+	if err := db.Save(&pref).Error; err != nil {
+		utils.LogError(r, "UpdateNotificationPreferencesHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPreferencesSaveFailed)})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgPreferencesUpdated), Data: pref})
+}
+
+// loadNotificationPreference returns the stored preference, or the all-enabled
+// default for users who never changed it.
+func loadNotificationPreference(db *gorm.DB, uid uint) (models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := db.Where("user_id = ?", uid).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultNotificationPreference(uid), nil
+	}
+	return pref, err
+}
+
+func applyNotificationPreferenceRequest(pref *models.NotificationPreference, req *NotificationPreferenceRequest) {
+	if req.Payment != nil {
+		pref.Payment = *req.Payment
+	}
+	if req.Profit != nil {
+		pref.Profit = *req.Profit
+	}
+	if req.Withdrawal != nil {
+		pref.Withdrawal = *req.Withdrawal
+	}
+}
@@ -0,0 +1,76 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+// An express withdrawal is quoted with its fee and accepted outside the
+// withdrawal window.
+func TestExpressWithdrawal(t *testing.T) {
+	tx := testutil.Tx(t)
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, WithdrawCharge: 10, ExpressWithdraw: true, ExpressWithdrawCharge: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	suffix := time.Now().UnixNano() % 1000000000
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Express", Balance: 200000})
+	bank := models.Bank{Name: "Bank Ekspres", Code: fmt.Sprintf("EX%d", suffix), GatewayCode: "EXGW", Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	acc := models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: "Express", AccountNumber: fmt.Sprintf("%09d", suffix)}
+	if err := tx.Create(&acc).Error; err != nil {
+		t.Fatal(err)
+	}
+	h := NewWithdrawalHandler(tx)
+
+	// The quote shows the express fee before confirmation
+	rec := httptest.NewRecorder()
+	h.Quote(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/users/withdrawals/quote?amount=100000&bank_account_id=%d&express=true", acc.ID), nil), user.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("quote: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var quote struct {
+		Data struct {
+			Charge      int64 `json:"charge"`
+			ExpressFee  int64 `json:"express_fee"`
+			FinalAmount int64 `json:"final_amount"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &quote); err != nil {
+		t.Fatal(err)
+	}
+	if quote.Data.Charge != 15000 || quote.Data.ExpressFee != 5000 || quote.Data.FinalAmount != 85000 {
+		t.Fatalf("unexpected quote %+v", quote.Data)
+	}
+
+	// Accepted at any hour, with the fee quoted
+	body := fmt.Sprintf(`{"amount":100000,"bank_account_id":%d,"express":true}`, acc.ID)
+	rec = httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/withdrawal", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var wd models.Withdrawal
+	if err := tx.Where("user_id = ?", user.ID).First(&wd).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !wd.Express || wd.ExpressFee != 5000 || wd.Charge != 15000 || wd.FinalAmount != 85000 || wd.Status != "Pending" {
+		t.Fatalf("unexpected withdrawal %+v", wd)
+	}
+
+}
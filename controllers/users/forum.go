@@ -2,13 +2,8 @@ package users
 
 import (
 	"bytes"
-	"image"
-	"image/jpeg"
-	"image/png"
-	"io"
-	"math"
+	"errors"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -32,34 +27,24 @@ func ForumListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	db := database.DB
 
-	// Get query parameters
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	// Parse pagination with defaults
-	page, _ := strconv.Atoi(pageStr)
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(limitStr)
-	if limit < 1 {
-		limit = 10
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
 
 	// Count total rows
 	var totalRows int64
 	if err := db.Model(&models.Forum{}).Where("status = ?", "Accepted").Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "ForumListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
 
-	// Calculate pagination
-	totalPages := int(math.Ceil(float64(totalRows) / float64(limit)))
-	offset := (page - 1) * limit
-
 	// Query forums with pagination
 	var forums []models.Forum
-	if err := db.Where("status = ?", "Accepted").Order("created_at DESC").Limit(limit).Offset(offset).Find(&forums).Error; err != nil {
+	if err := db.Where("status = ?", "Accepted").Order("created_at DESC").Limit(pg.Limit).Offset(pg.Offset).Find(&forums).Error; err != nil {
+		utils.LogError(r, "ForumListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
@@ -112,16 +97,7 @@ func ForumListHandler(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	// Build response with pagination
-	responseData := map[string]interface{}{
-		"data": resp,
-		"pagination": map[string]interface{}{
-			"page":       page,
-			"limit":      limit,
-			"total_rows": totalRows,
-			"total_pages": totalPages,
-		},
-	}
+	responseData := utils.NewPaginated(resp, pg, totalRows)
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: responseData})
 }
@@ -163,72 +139,27 @@ func ForumSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	file, handler, err := r.FormFile("image")
 	if err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar diperlukan"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar diperlukan", Code: utils.CodeInvalidImage})
 		return
 	}
 	defer file.Close()
-	ext := strings.ToLower(filepath.Ext(handler.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar harus JPG/PNG"})
-		return
-	}
-	if handler.Size > 2<<20 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar maksimal 2MB"})
-		return
-	}
 
-	// Read first 512 bytes to detect MIME type (magic-bytes)
-	buf := make([]byte, 512)
-	n, err := file.Read(buf)
-	if err != nil && err != http.ErrBodyReadAfterClose && err != io.EOF {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gagal membaca gambar"})
-		return
-	}
-	detected := http.DetectContentType(buf[:n])
-	if detected != "image/jpeg" && detected != "image/png" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar harus JPG/PNG"})
+	// Placeholder: perform malware scan here (e.g., send the bytes to ClamAV or cloud scanner)
+	// Re-encoding sanitizes metadata and ensures a valid image
+	imageBytes, ext, err := utils.SanitizeImage(file, handler.Filename, handler.Size, 2<<20)
+	var imgErr *utils.ImageError
+	if errors.As(err, &imgErr) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: imgErr.Message, Code: utils.CodeInvalidImage})
 		return
 	}
-
-	// Rewind and decode/re-encode image to sanitize content
-	// Need the full image bytes: combine the head we read with the rest
-	rest, err := io.ReadAll(file)
-	if err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gagal membaca gambar"})
-		return
-	}
-	imageBytes := append(buf[:n], rest...)
-
-	// Placeholder: perform malware scan here (e.g., send imageBytes to ClamAV or cloud scanner)
-	// If scan fails, return an error. For now we only leave a comment and proceed.
-
-	// Decode and re-encode to sanitize metadata and ensure a valid image
-	imgReader := bytes.NewReader(imageBytes)
-	img, format, err := image.Decode(imgReader)
 	if err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid image format"})
-		return
-	}
-
-	var outBuf bytes.Buffer
-	switch format {
-	case "jpeg":
-		if err := jpeg.Encode(&outBuf, img, &jpeg.Options{Quality: 85}); err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses gambar"})
-			return
-		}
-	case "png":
-		if err := png.Encode(&outBuf, img); err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses gambar"})
-			return
-		}
-	default:
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gambar harus JPG/PNG"})
+		utils.LogError(r, "ForumSubmitHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses gambar"})
 		return
 	}
 
 	// Prepare a ReadSeeker for S3 upload and presign
-	reader := bytes.NewReader(outBuf.Bytes())
+	reader := bytes.NewReader(imageBytes)
 
 	// Check withdrawal in last 3 days
 	var count int64
@@ -236,7 +167,7 @@ func ForumSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
 	db.Model(&models.Withdrawal{}).Where("user_id = ? AND created_at >= ?", uid, threeDaysAgo).Count(&count)
 	if count == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak ada penarikan dalam 3 hari terakhir"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak ada penarikan dalam 3 hari terakhir", Code: utils.CodeForumWithdrawalRequired})
 		return
 	}
 	// Upload image to S3 (private) and get presigned URL
@@ -244,7 +175,7 @@ func ForumSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	uidUint := uid
 	imgName := strconv.FormatUint(uint64(uidUint), 10) + "_" + strconv.FormatInt(randomNum, 10) + ext
 	// use UploadToS3AndPresign which expects a ReadSeeker and returns a presigned URL
-	presignedURL, upErr := utils.UploadToS3AndPresign(imgName, reader, int64(outBuf.Len()), 3600)
+	presignedURL, upErr := utils.UploadToS3AndPresign(imgName, reader, int64(len(imageBytes)), 3600)
 	if upErr != nil {
 		_ = upErr
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to upload image. Please try again later."})
@@ -259,6 +190,7 @@ func ForumSubmitHandler(w http.ResponseWriter, r *http.Request) {
 		Status:      "Pending",
 	}
 	if err := db.Create(&forum).Error; err != nil {
+		utils.LogError(r, "ForumSubmitHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
@@ -0,0 +1,172 @@
+package users
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"project/database"
+	"project/utils"
+)
+
+// incomeSeriesDays is how many days, today included, the income chart covers.
+const incomeSeriesDays = 30
+
+// IncomeSource is one transaction type's share of an income window.
+type IncomeSource struct {
+	TransactionType string `json:"transaction_type"`
+	Amount          int64  `json:"amount"`
+	Count           int64  `json:"count"`
+}
+
+// IncomeWindow totals the income since From (nil for lifetime), largest
+// source first.
+type IncomeWindow struct {
+	From    *string        `json:"from"`
+	Total   int64          `json:"total"`
+	Sources []IncomeSource `json:"sources"`
+}
+
+// IncomeDay is one local day of the income chart.
+type IncomeDay struct {
+	Date   string `json:"date"`
+	Amount int64  `json:"amount"`
+}
+
+// IncomeSummary answers GET /users/income/summary.
+type IncomeSummary struct {
+	Period   string       `json:"period"`
+	Today    IncomeWindow `json:"today"`
+	Current  IncomeWindow `json:"current_period"`
+	Lifetime IncomeWindow `json:"lifetime"`
+	Daily    []IncomeDay  `json:"daily"`
+}
+
+// incomePeriodStart is the start of the week (Monday), month or year that
+// today falls in; ok is false for any other period.
+func incomePeriodStart(period string, today time.Time) (time.Time, bool) {
+	switch period {
+	case "week":
+		return today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7)), true
+	case "month":
+		return time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location()), true
+	case "year":
+		return time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, today.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// GET /api/users/income/summary?period=week|month|year
+// Totals the user's income, i.e. their Success debit transactions, by
+// transaction_type for today, the current period (default month) and their
+// whole history, plus the daily total of the last 30 days. Days are cut in
+// APP_TIMEZONE. Each figure is the sum of the transaction list filtered to
+// that type, Success, debit and the window.
+func GetIncomeSummary(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "month"
+	}
+	appLoc := utils.AppLocation()
+	now := time.Now().In(appLoc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, appLoc)
+	periodStart, ok := incomePeriodStart(period, today)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "period harus week, month atau year"})
+		return
+	}
+	seriesStart := today.AddDate(0, 0, -(incomeSeriesDays - 1))
+
+	// Timestamps are stored in UTC; shifting them by the app timezone's offset
+	// lets MySQL bucket them by local date
+	_, offset := now.Zone()
+	args := map[string]interface{}{
+		"uid":    uid,
+		"today":  today.UTC(),
+		"period": periodStart.UTC(),
+		"since":  seriesStart.UTC(),
+		"offset": offset,
+	}
+	// Served by idx_transactions_user_flow_status_created
+	const income = `FROM transactions WHERE user_id = @uid AND transaction_flow = 'debit' AND status = 'Success'`
+
+	type sourceRow struct {
+		TransactionType string
+		Amount          int64
+		Count           int64
+		TodayAmount     int64
+		TodayCount      int64
+		PeriodAmount    int64
+		PeriodCount     int64
+	}
+	db := database.DB
+	var rows []sourceRow
+	if err := db.Raw(`SELECT transaction_type, COALESCE(SUM(amount), 0) AS amount, COUNT(*) AS count,
+			COALESCE(SUM(CASE WHEN created_at >= @today THEN amount END), 0) AS today_amount,
+			COUNT(CASE WHEN created_at >= @today THEN 1 END) AS today_count,
+			COALESCE(SUM(CASE WHEN created_at >= @period THEN amount END), 0) AS period_amount,
+			COUNT(CASE WHEN created_at >= @period THEN 1 END) AS period_count
+		`+income+` GROUP BY transaction_type`, args).Scan(&rows).Error; err != nil {
+		utils.LogError(r, "GetIncomeSummary", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Database error"})
+		return
+	}
+	type dayRow struct {
+		Day    string
+		Amount int64
+	}
+	var days []dayRow
+	if err := db.Raw(`SELECT DATE_FORMAT(created_at + INTERVAL @offset SECOND, '%Y-%m-%d') AS day, COALESCE(SUM(amount), 0) AS amount
+		`+income+` AND created_at >= @since GROUP BY day`, args).Scan(&days).Error; err != nil {
+		utils.LogError(r, "GetIncomeSummary", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Database error"})
+		return
+	}
+
+	summary := IncomeSummary{
+		Period:   period,
+		Today:    IncomeWindow{From: utils.FormatTimePtr(&today), Sources: []IncomeSource{}},
+		Current:  IncomeWindow{From: utils.FormatTimePtr(&periodStart), Sources: []IncomeSource{}},
+		Lifetime: IncomeWindow{Sources: []IncomeSource{}},
+		Daily:    make([]IncomeDay, incomeSeriesDays),
+	}
+	add := func(win *IncomeWindow, typ string, amount, count int64) {
+		if count == 0 {
+			return
+		}
+		win.Total += amount
+		win.Sources = append(win.Sources, IncomeSource{TransactionType: typ, Amount: amount, Count: count})
+	}
+	for _, row := range rows {
+		add(&summary.Today, row.TransactionType, row.TodayAmount, row.TodayCount)
+		add(&summary.Current, row.TransactionType, row.PeriodAmount, row.PeriodCount)
+		add(&summary.Lifetime, row.TransactionType, row.Amount, row.Count)
+	}
+	for _, win := range []*IncomeWindow{&summary.Today, &summary.Current, &summary.Lifetime} {
+		sort.SliceStable(win.Sources, func(i, j int) bool {
+			if win.Sources[i].Amount != win.Sources[j].Amount {
+				return win.Sources[i].Amount > win.Sources[j].Amount
+			}
+			return win.Sources[i].TransactionType < win.Sources[j].TransactionType
+		})
+	}
+	byDay := make(map[string]int64, len(days))
+	for _, d := range days {
+		byDay[d.Day] = d.Amount
+	}
+	for i := range summary.Daily {
+		date := seriesStart.AddDate(0, 0, i).Format("2006-01-02")
+		summary.Daily[i] = IncomeDay{Date: date, Amount: byDay[date]}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    summary,
+	})
+}
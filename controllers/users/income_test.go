@@ -0,0 +1,131 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// Every figure of the summary equals the transaction list, filtered to the
+// same type, Success, debit and window, summed by hand.
+func TestIncomeSummaryTiesOutWithTransactionList(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	suffix := time.Now().UnixNano() % 1000000000
+	user := testutil.NewUser(t, tx, models.User{Name: "Income"})
+	now := time.Now().In(utils.AppLocation())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	seed := []struct {
+		typ, flow, status string
+		amount            int64
+		at                time.Time
+	}{
+		{"return", "debit", "Success", 5000, today.Add(time.Second)},
+		{"team", "debit", "Success", 30000, monthStart.Add(time.Second)},
+		{"return", "debit", "Success", 7000, today.AddDate(-1, 0, -40)},
+		{"bonus", "debit", "Pending", 9999, today.Add(time.Second)},
+		{"withdrawal", "credit", "Success", 50000, today.Add(time.Second)},
+	}
+	for i, s := range seed {
+		trx := models.Transaction{UserID: user.ID, Amount: s.amount, OrderID: fmt.Sprintf("INC-%d-%d", suffix, i), TransactionFlow: s.flow, TransactionType: s.typ, Status: s.status, CreatedAt: s.at, UpdatedAt: s.at}
+		if err := tx.Create(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		GetIncomeSummary(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/income/summary"+query, nil), user.ID))
+		return rec
+	}
+	if rec := get("?period=decade"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown period: expected 400, got %d", rec.Code)
+	}
+	rec := get("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data IncomeSummary `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	summary := resp.Data
+
+	// listTotal sums the Success debit rows of one type listed since from
+	listTotal := func(typ string, from time.Time) int64 {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v3/users/transaction/"+typ+"?limit=100", nil), map[string]string{"type": typ})
+		rec := httptest.NewRecorder()
+		GetTransactionHistory(rec, testutil.AsUser(req, user.ID))
+		var list struct {
+			Data struct {
+				Data []transactionDTO `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		var total int64
+		for _, item := range list.Data.Data {
+			at, err := time.Parse(time.RFC3339, item.CreatedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Status == "Success" && item.TransactionFlow == "debit" && !at.Before(from) {
+				total += item.Amount
+			}
+		}
+		return total
+	}
+	check := func(name string, win IncomeWindow, from time.Time) {
+		var total int64
+		for _, typ := range []string{"return", "team", "bonus", "withdrawal"} {
+			var got int64
+			for _, src := range win.Sources {
+				if src.TransactionType == typ {
+					got = src.Amount
+				}
+			}
+			want := listTotal(typ, from)
+			if got != want {
+				t.Fatalf("%s %s: summary %d, list %d", name, typ, got, want)
+			}
+			total += want
+		}
+		if win.Total != total {
+			t.Fatalf("%s: total %d, list %d", name, win.Total, total)
+		}
+	}
+	check("today", summary.Today, today)
+	check("month", summary.Current, monthStart)
+	check("lifetime", summary.Lifetime, time.Time{})
+	if summary.Lifetime.Total != 42000 {
+		t.Fatalf("lifetime: expected 42000, got %+v", summary.Lifetime)
+	}
+
+	if len(summary.Daily) != incomeSeriesDays || summary.Daily[incomeSeriesDays-1].Date != today.Format("2006-01-02") {
+		t.Fatalf("daily series should end today, got %+v", summary.Daily)
+	}
+	var series int64
+	for _, d := range summary.Daily {
+		series += d.Amount
+	}
+	if want := listTotal("return", today.AddDate(0, 0, -(incomeSeriesDays-1))) + listTotal("team", today.AddDate(0, 0, -(incomeSeriesDays-1))); series != want {
+		t.Fatalf("daily series sums to %d, list %d", series, want)
+	}
+}
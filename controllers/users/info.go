@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"project/database"
+	"project/i18n"
+	"project/middleware"
 	"project/models"
 	"project/utils"
 
@@ -24,17 +26,16 @@ func InfoHandler(w http.ResponseWriter, r *http.Request) {
 	var user models.User
 	if err := db.First(&user, uid).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found", Code: utils.CodeUserNotFound})
 			return
 		}
+		utils.LogError(r, "InfoHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Database error"})
 		return
 	}
 
-	var setting models.Setting
-	err := db.Model(&models.Setting{}).
-		Select("name, company, logo, min_withdraw, max_withdraw, withdraw_charge, link_cs, link_group, link_app").
-		Take(&setting).Error
+	// Shared with every caller, so read through the settings cache
+	setting, err := models.GetCachedSetting(db.WithContext(r.Context()))
 	healthy := true
 	if err != nil {
 		healthy = false
@@ -53,13 +54,14 @@ func InfoHandler(w http.ResponseWriter, r *http.Request) {
 				"name":           user.Name,
 				"number":         user.Number,
 				"reff_code":      user.ReffCode,
-				"balance":        int64(user.Balance),
+				"balance":        user.Balance,
 				"level":          user.Level,
 				"total_invest":   int64(user.TotalInvest),
 				"total_invest_vip": int64(user.TotalInvestVIP),
 				"total_withdraw": int64(TotalWithdraw),
 				"spin_ticket":    user.SpinTicket,
 				"active":         strings.ToLower(user.InvestmentStatus) == "active",
+				"locale":         user.Locale,
 			},
 			"application": map[string]interface{}{
 				"name":            setting.Name,
@@ -76,3 +78,39 @@ func InfoHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+type UpdateLocaleRequest struct {
+	Locale *string `json:"locale" validate:"omitempty,oneof=id en"` // null clears the preference
+}
+
+// PUT /api/users/locale
+func UpdateLocaleHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	var req UpdateLocaleRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", uid).Update("locale", req.Locale).Error; err != nil {
+		utils.LogError(r, "UpdateLocaleHandler", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	middleware.InvalidateUserLocale(uid)
+
+	// Answer in the newly chosen language
+	loc := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+	if req.Locale != nil {
+		loc = i18n.Locale(*req.Locale)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: i18n.T(loc, i18n.MsgLocaleUpdated),
+		Data:    map[string]interface{}{"locale": req.Locale},
+	})
+}
@@ -1,88 +1,82 @@
 package users
 
 import (
-	"bytes"
-	"context"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"math"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"project/alert"
 	"project/database"
+	"project/i18n"
+	"project/kyta"
 	"project/models"
+	"project/notify"
+	"project/outbox"
 	"project/utils"
+	"project/webhook"
 
+	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-type KytaAccessTokenResponse struct {
-	ResponseCode    string `json:"response_code"`
-	ResponseMessage string `json:"response_message"`
-	ResponseData    struct {
-		AccessToken string `json:"access_token"`
-		TokenType   string `json:"token_type"`
-		ExpiresIn   int    `json:"expires_in"`
-		RequestTime string `json:"request_time"`
-	} `json:"response_data"`
+// InvestmentHandler serves investment purchases, their payments and the
+// daily return cron. Routes build one in InitRouter.
+type InvestmentHandler struct {
+	DB   *gorm.DB
+	Kyta kyta.Client
+	// Notifier receives push events after commits; nil disables them
+	Notifier *notify.Notifier
+	// Alerts receives ops alerts; nil only logs them
+	Alerts *alert.Alerter
+	// Outbox carries out the side effects recorded with a payment right after
+	// its commit; the outbox cron retries what fails
+	Outbox *outbox.Dispatcher
 }
 
-type KytaPaymentResponse struct {
-	ResponseCode    string `json:"response_code"`
-	ResponseMessage string `json:"response_message"`
-	ResponseData    struct {
-		ID          string `json:"id"`
-		ReferenceID string `json:"reference_id"`
-		Amount      int64  `json:"amount"`
-		PaymentData struct {
-			QRString      string `json:"qr_string,omitempty"`
-			BankCode      string `json:"bank_code,omitempty"`
-			AccountNumber string `json:"account_number,omitempty"`
-			AccountName   string `json:"account_name,omitempty"`
-		} `json:"payment_data"`
-		MerchantURL struct {
-			NotifyURL  string `json:"notify_url"`
-			SuccessURL string `json:"success_url"`
-			FailedURL  string `json:"failed_url"`
-		} `json:"merchant_url"`
-		CheckoutURL string `json:"checkout_url"`
-		ExpiresAt   string `json:"expires_at"`
-		RequestTime string `json:"request_time"`
-	} `json:"response_data"`
+func NewInvestmentHandler(db *gorm.DB, kc kyta.Client) *InvestmentHandler {
+	return &InvestmentHandler{DB: db, Kyta: kc, Outbox: NewOutboxDispatcher(db)}
 }
 
 type CreateInvestmentRequest struct {
-	ProductID      uint   `json:"product_id"`
-	PaymentMethod  string `json:"payment_method"`
-	PaymentChannel string `json:"payment_channel"`
+	ProductID      uint   `json:"product_id" validate:"required"`
+	PaymentMethod  string `json:"payment_method" validate:"required,oneof=QRIS BANK MANUAL"`
+	PaymentChannel string `json:"payment_channel" validate:"required_if=PaymentMethod BANK"`
+}
+
+// Normalize upper-cases the method and channel so "qris" and " bca " are accepted.
+func (req *CreateInvestmentRequest) Normalize() {
+	req.PaymentMethod = strings.ToUpper(strings.TrimSpace(req.PaymentMethod))
+	req.PaymentChannel = strings.ToUpper(strings.TrimSpace(req.PaymentChannel))
 }
 
 // GET /api/users/investment/active
-func GetActiveInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *InvestmentHandler) GetActive(w http.ResponseWriter, r *http.Request) {
 	uid, ok := utils.GetUserID(r)
 	if !ok || uid == 0 {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
 		return
 	}
-	db := database.DB
+	db := h.DB
 
-	// Get active categories (prioritize category ID 1)
+	// Get active categories in their configured display order
 	var categories []models.Category
-	if err := db.Where("status = ?", "Active").Order("CASE WHEN id = 1 THEN 0 ELSE id END ASC").Find(&categories).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil kategori"})
+	if err := db.Where("status = ?", "Active").Order("sort_priority ASC, id ASC").Find(&categories).Error; err != nil {
+		utils.LogError(r, "GetActiveInvestmentsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgCategoryFailed)})
 		return
 	}
 
 	var investments []models.Investment
-	if err := db.Preload("Category").Where("user_id = ? AND status IN ?", uid, []string{"Running", "Completed", "Suspended"}).Order("CASE WHEN category_id = 1 THEN 0 ELSE category_id END ASC, product_id ASC, id DESC").Find(&investments).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil investasi"})
+	if err := db.Preload("Category").
+		Joins("JOIN categories ON categories.id = investments.category_id").
+		Where("investments.user_id = ? AND investments.status IN ?", uid, []string{"Running", "Completed", "Suspended"}).
+		Order("categories.sort_priority ASC, investments.category_id ASC, investments.product_id ASC, investments.id DESC").Find(&investments).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgListFailed)})
 		return
 	}
 
@@ -94,6 +88,11 @@ func GetActiveInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
+		productName := inv.ProductName
+		if productName == "" {
+			productName = product.Name
+		}
+
 		catName := ""
 		if inv.Category != nil {
 			catName = inv.Category.Name
@@ -114,18 +113,19 @@ func GetActiveInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
 			"id":               inv.ID,
 			"user_id":          inv.UserID,
 			"product_id":       inv.ProductID,
-			"product_name":     product.Name,
+			"product_name":     productName,
 			"product_category": productCategory,
 			"category_id":      inv.CategoryID,
 			"category_name":    catName,
-			"amount":           int64(inv.Amount),
+			"amount":           inv.Amount,
 			"duration":         inv.Duration,
-			"daily_profit":     int64(inv.DailyProfit),
+			"daily_profit":     inv.DailyProfit,
 			"total_paid":       inv.TotalPaid,
-			"total_returned":   int64(inv.TotalReturned),
-			"last_return_at":   inv.LastReturnAt,
-			"next_return_at":   inv.NextReturnAt,
+			"total_returned":   inv.TotalReturned,
+			"last_return_at":   utils.FormatTimePtr(inv.LastReturnAt),
+			"next_return_at":   utils.FormatTimePtr(inv.NextReturnAt),
 			"order_id":         inv.OrderID,
+			"certificate_no":   inv.CertificateNo,
 			"status":           inv.Status,
 		}
 		categoryMap[catName] = append(categoryMap[catName], m)
@@ -141,136 +141,153 @@ func GetActiveInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: resp})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: resp})
 }
 
 // POST /api/users/investments - FIXED VERSION
-func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *InvestmentHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateInvestmentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Not valid JSON"})
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
 	uid, ok := utils.GetUserID(r)
 	if !ok || uid == 0 {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
 		return
 	}
 
-	method := strings.ToUpper(strings.TrimSpace(req.PaymentMethod))
-	channel := strings.ToUpper(strings.TrimSpace(req.PaymentChannel))
-	if method != "QRIS" && method != "BANK" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Silahkan pilih metode pembayaran"})
-		return
-	}
+	method := req.PaymentMethod
+	channel := req.PaymentChannel
 	if method == "BANK" {
-		allowed := map[string]struct{}{"BCA": {}, "BRI": {}, "BNI": {}, "MANDIRI": {}, "PERMATA": {}, "BNC": {}}
-		if _, ok := allowed[channel]; !ok {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank tidak valid"})
+		if !vaChannel(channel) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentBankInvalid), Code: utils.CodeBankUnavailable})
 			return
 		}
 	}
 
-	db := database.DB
+	// The checks and the transaction after the gateway call get separate
+	// deadlines, so a slow gateway does not eat into the second
+	db, cancel := database.WithTimeout(r.Context(), h.DB)
+	defer cancel()
 	var product models.Product
 	if err := db.Preload("Category").Where("id = ? AND status = 'Active'", req.ProductID).First(&product).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan"})
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeProductNotFound)
+			return
+		}
+		utils.LogError(r, "CreateInvestmentHandler", err)
+		if utils.WriteDBTimeout(w, r, err) {
 			return
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan, coba lagi"})
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
 		return
 	}
 
 	if product.Category == nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kategori produk tidak valid"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentCategoryInvalid)})
 		return
 	}
 
-	var user models.User
-	if err := db.Select("level").Where("id = ?", uid).First(&user).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan, coba lagi"})
+	if refusal, err := purchaseBlocked(db, uid, &product); err != nil {
+		utils.LogError(r, "CreateInvestmentHandler", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	} else if refusal != nil {
+		refusal.write(w, r)
 		return
 	}
 
-	userLevel := uint(0)
-	if user.Level != nil {
-		userLevel = *user.Level
-	}
-
-	if userLevel < uint(product.RequiredVIP) {
-		msg := fmt.Sprintf("Produk %s memerlukan VIP level %d. Level VIP Anda saat ini: %d", product.Name, product.RequiredVIP, userLevel)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+	// VIP level cap on investments held at once, checked again under the
+	// user's lock below
+	limits, err := userVIPLimits(db, uid)
+	if err != nil {
+		utils.LogError(r, "CreateInvestmentHandler: vip limits", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
 		return
 	}
-
-	if product.PurchaseLimit > 0 {
-		var purchaseCount int64
-		if err := db.Model(&models.Investment{}).
-			Where("user_id = ? AND product_id = ? AND status IN ?", uid, product.ID, []string{"Running", "Completed", "Suspended"}).
-			Count(&purchaseCount).Error; err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan, coba lagi"})
+	if limits.MaxActiveInvestments > 0 {
+		active, err := activeInvestmentCount(db, uid, time.Now())
+		if err != nil {
+			utils.LogError(r, "CreateInvestmentHandler: active investments", err)
+			if utils.WriteDBTimeout(w, r, err) {
+				return
+			}
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
 			return
 		}
-		if purchaseCount >= int64(product.PurchaseLimit) {
-			msg := fmt.Sprintf("Anda telah mencapai batas pembelian untuk produk %s (maksimal %dx)", product.Name, product.PurchaseLimit)
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+		if active >= int64(limits.MaxActiveInvestments) {
+			writeVIPLimit(w, r, utils.CodeVIPActiveInvestmentLimit, VIPLimitUsage{Limit: vipLimitActiveInvestments, Level: limits.Level, Max: int64(limits.MaxActiveInvestments), Used: active})
 			return
 		}
 	}
 
-	kytapayBase := os.Getenv("KYTAPAY_BASE_URL")
-	if kytapayBase == "" {
-		kytapayBase = "https://api.kytapay.com/v2"
-	}
-	kytapayClientID := os.Getenv("KYTAPAY_CLIENT_ID")
-	kytapayClientSecret := os.Getenv("KYTAPAY_CLIENT_SECRET")
-	notifyURL := os.Getenv("NOTIFY_URL")
-	successURL := os.Getenv("SUCCESS_URL")
-	failedURL := os.Getenv("FAILED_URL")
-
-	if kytapayClientID == "" || kytapayClientSecret == "" {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
-		return
-	}
-
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-	orderID := utils.GenerateOrderID(uid)
+	orderID := utils.GenerateOrderID(utils.OrderInvestment, uid)
 	referenceID := orderID
 
-	accessToken, _, err := getKytaAccessTokenSafe(r.Context(), httpClient, kytapayBase, kytapayClientID, kytapayClientSecret)
-	if err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Terjadi kesalahan saat memanggil layanan pembayaran"})
-		return
-	}
-
 	amount := product.Amount
-
-	if method == "QRIS" && amount > 10000000 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Jumlah pembayaran maksimal menggunakan QRIS adalah Rp 10.000.000, Silahkan gunakan metode pembayaran lain"})
+	// The channel fee, when passed through, is charged on top of the price.
+	// GET /api/users/payment/methods previews this same evaluation
+	option, err := paymentOption(db, method, channel, amount)
+	if err != nil {
+		utils.LogError(r, "CreateInvestmentHandler: payment channel", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
 		return
 	}
-
-	if method == "BANK" && amount < 10000 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Jumlah pembayaran minimal menggunakan BANK adalah Rp 10.000, Silahkan gunakan metode pembayaran lain"})
+	if !option.Available {
+		writePaymentUnavailable(w, r, option)
 		return
 	}
+	fee, gross := option.Fee, option.GrossAmount
 
-	var payResp *KytaPaymentResponse
-	if method == "QRIS" {
-		payResp, _, err = createKytaQRISSafe(r.Context(), httpClient, kytapayBase, accessToken, referenceID, int64(amount), notifyURL, successURL, failedURL)
+	// MANUAL is a bank transfer reviewed by an admin, offered while the
+	// gateway is down; everything after this block is the same for both
+	var payResp *kyta.PaymentResponse
+	var manual *ManualTransfer
+	if method == "MANUAL" {
+		manual, err = newManualTransfer(db, time.Now())
+		if errors.Is(err, errManualPaymentUnavailable) {
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeManualPaymentUnavailable)
+			return
+		}
+		if err != nil {
+			utils.LogError(r, "CreateInvestmentHandler: manual transfer", err)
+			if utils.WriteDBTimeout(w, r, err) {
+				return
+			}
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+			return
+		}
 	} else {
-		payResp, _, err = createKytaVASafe(r.Context(), httpClient, kytapayBase, accessToken, referenceID, int64(amount), channel, notifyURL, successURL, failedURL)
-	}
+		if method == "QRIS" {
+			payResp, err = h.Kyta.CreateQRIS(r.Context(), kyta.PaymentRequest{ReferenceID: referenceID, Amount: gross})
+		} else {
+			payResp, err = h.Kyta.CreateVA(r.Context(), kyta.PaymentRequest{ReferenceID: referenceID, Amount: gross, BankCode: channel})
+		}
 
-	if err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Terjadi kesalahan saat memanggil layanan pembayaran"})
-		return
-	}
-	if payResp == nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mendapatkan jawaban dari layanan pembayaran"})
-		return
+		if errors.Is(err, kyta.ErrNotConfigured) {
+			utils.LogError(r, "CreateInvestmentHandler: kytapay", err)
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+			return
+		}
+		if err != nil {
+			utils.LogError(r, "CreateInvestmentHandler: kytapay create payment", err)
+			utils.WriteError(w, r, http.StatusBadGateway, utils.CodePaymentGatewayError)
+			return
+		}
+		if payResp == nil {
+			utils.WriteJSON(w, http.StatusBadGateway, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPaymentGatewayNoResponse), Code: utils.CodePaymentGatewayError})
+			return
+		}
 	}
 
 	daily := product.DailyProfit
@@ -279,6 +296,7 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 		UserID:        uid,
 		ProductID:     product.ID,
 		CategoryID:    product.CategoryID,
+		ProductName:   product.Name,
 		Amount:        amount,
 		DailyProfit:   daily,
 		Duration:      product.Duration,
@@ -288,37 +306,60 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 		Status:        "Pending",
 	}
 
-	if err := db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&inv).Error; err != nil {
-			return err
+	txDB, cancelTx := database.WithTimeout(r.Context(), h.DB)
+	defer cancelTx()
+	var used int64
+	var lastPurchase time.Time
+	if err := txDB.Transaction(func(tx *gorm.DB) error {
+		if manual != nil {
+			if err := claimTransferCode(tx, manual); err != nil {
+				return err
+			}
 		}
-
-		var paymentCode *string
-		var expiredAt *time.Time
-
-		methodToSave := strings.ToUpper(method)
-
-		if method == "QRIS" {
-			if qr := strings.TrimSpace(payResp.ResponseData.PaymentData.QRString); qr != "" {
-				paymentCode = &qr
+		// Checked again under the user's lock: a purchase made in parallel
+		// has committed by now and counts as a payable Pending investment
+		if product.PurchaseLimit > 0 || product.PurchaseCooldownHours > 0 || limits.MaxActiveInvestments > 0 {
+			if err := lockUserPurchases(tx, uid); err != nil {
+				return err
 			}
-		} else {
-			if accNum := strings.TrimSpace(payResp.ResponseData.PaymentData.AccountNumber); accNum != "" {
-				paymentCode = &accNum
+		}
+		if limits.MaxActiveInvestments > 0 {
+			active, err := activeInvestmentCount(tx, uid, time.Now())
+			if err != nil {
+				return err
+			}
+			if active >= int64(limits.MaxActiveInvestments) {
+				used = active
+				return errVIPActiveInvestmentLimit
 			}
 		}
-
-		if expiredStr := strings.TrimSpace(payResp.ResponseData.ExpiresAt); expiredStr != "" {
-			if t, err := parseTimeFlexible(expiredStr); err == nil {
-				tt := t.UTC()
-				expiredAt = &tt
-			} else {
-				t := time.Now().Add(15 * time.Minute)
-				expiredAt = &t
+		if product.PurchaseLimit > 0 {
+			purchases, err := purchaseCount(tx, uid, product.ID, time.Now())
+			if err != nil {
+				return err
 			}
+			if purchases >= int64(product.PurchaseLimit) {
+				used = purchases
+				return errPurchaseLimitReached
+			}
+		}
+		if last, cooling, err := purchaseCooldown(tx, uid, &product, time.Now()); err != nil {
+			return err
+		} else if cooling {
+			lastPurchase = last
+			return errPurchaseCooldown
+		}
+		if err := tx.Create(&inv).Error; err != nil {
+			return err
+		}
+
+		methodToSave := strings.ToUpper(method)
+		var paymentCode, paymentLink *string
+		var expiredAt *time.Time
+		if manual != nil {
+			paymentCode, expiredAt = &manual.TransferCode, &manual.expiresAt
 		} else {
-			t := time.Now().Add(15 * time.Minute)
-			expiredAt = &t
+			paymentCode, paymentLink, expiredAt = gatewayPaymentDetails(method, payResp)
 		}
 
 		payment := models.Payment{
@@ -336,14 +377,13 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 				return nil
 			}(),
 			PaymentCode: paymentCode,
-			PaymentLink: func() *string {
-				if url := strings.TrimSpace(payResp.ResponseData.CheckoutURL); url != "" {
-					return &url
-				}
-				return nil
-			}(),
-			Status:    "Pending",
+			PaymentLink: paymentLink,
+			Amount:      amount,
+			Fee:         fee,
+			Status:      "Pending",
 			ExpiredAt: expiredAt,
+			// nil for MANUAL payments
+			GatewayPaymentID: payResp.PaymentID(),
 		}
 
 		if err := tx.Create(&payment).Error; err != nil {
@@ -359,8 +399,9 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 		msg := fmt.Sprintf("Investasi %s", product.Name)
 		trx := models.Transaction{
 			UserID:          uid,
+			InvestmentID:    &inv.ID,
 			Amount:          inv.Amount,
-			Charge:          0,
+			Charge:          fee,
 			OrderID:         inv.OrderID,
 			TransactionFlow: "credit",
 			TransactionType: "investment",
@@ -371,14 +412,29 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 		return nil
-	}); err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat investasi"})
+	}); errors.Is(err, errPurchaseLimitReached) {
+		purchaseLimitRefusal(&product, used).write(w, r)
+		return
+	} else if errors.Is(err, errVIPActiveInvestmentLimit) {
+		writeVIPLimit(w, r, utils.CodeVIPActiveInvestmentLimit, VIPLimitUsage{Limit: vipLimitActiveInvestments, Level: limits.Level, Max: int64(limits.MaxActiveInvestments), Used: used})
+		return
+	} else if errors.Is(err, errPurchaseCooldown) {
+		purchaseCooldownRefusal(&product, lastPurchase).write(w, r)
+		return
+	} else if err != nil {
+		utils.LogError(r, "CreateInvestmentHandler: save investment", err, "order_id", orderID, "gateway_payment_id", utils.GetStringValue(payResp.PaymentID()))
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentCreateFailed)})
 		return
 	}
 
 	resp := map[string]interface{}{
 		"order_id":     inv.OrderID,
 		"amount":       inv.Amount,
+		"fee":          fee,
+		"gross_amount": gross,
 		"product":      product.Name,
 		"category":     product.Category.Name,
 		"category_id":  product.CategoryID,
@@ -386,139 +442,185 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 		"daily_profit": daily,
 		"status":       inv.Status,
 	}
-	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Pembelian berhasil, silakan lakukan pembayaran", Data: resp})
+	if manual != nil {
+		resp["manual_transfer"] = manual
+	}
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgInvestmentCreated), Data: resp})
+}
+
+// InvestmentResponse is an investment as shown to its owner, with
+// timestamps formatted in the app timezone.
+type InvestmentResponse struct {
+	ID            uint    `json:"id"`
+	UserID        uint    `json:"user_id"`
+	ProductID     uint    `json:"product_id"`
+	CategoryID    uint    `json:"category_id"`
+	ProductName   string  `json:"product_name"`
+	Amount        int64   `json:"amount"`
+	DailyProfit   int64   `json:"daily_profit"`
+	Duration      int     `json:"duration"`
+	TotalPaid     int     `json:"total_paid"`
+	TotalReturned int64   `json:"total_returned"`
+	LastReturnAt  *string `json:"last_return_at,omitempty"`
+	NextReturnAt  *string `json:"next_return_at,omitempty"`
+	OrderID       string  `json:"order_id"`
+	CertificateNo *string `json:"certificate_no,omitempty"`
+	Status        string  `json:"status"`
+	Archived      bool    `json:"archived,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
+
+	// Product is set on the detail only
+	Product *InvestmentProduct `json:"product,omitempty"`
+}
+
+func newInvestmentResponse(inv models.Investment) InvestmentResponse {
+	return InvestmentResponse{
+		ID:            inv.ID,
+		UserID:        inv.UserID,
+		ProductID:     inv.ProductID,
+		CategoryID:    inv.CategoryID,
+		ProductName:   inv.ProductName,
+		Amount:        inv.Amount,
+		DailyProfit:   inv.DailyProfit,
+		Duration:      inv.Duration,
+		TotalPaid:     inv.TotalPaid,
+		TotalReturned: inv.TotalReturned,
+		LastReturnAt:  utils.FormatTimePtr(inv.LastReturnAt),
+		NextReturnAt:  utils.FormatTimePtr(inv.NextReturnAt),
+		OrderID:       inv.OrderID,
+		CertificateNo: inv.CertificateNo,
+		Status:        inv.Status,
+		Archived:      inv.DeletedAt.Valid,
+		CreatedAt:     utils.FormatTime(inv.CreatedAt),
+		UpdatedAt:     utils.FormatTime(inv.UpdatedAt),
+	}
 }
 
-// GET /api/users/investments
-func ListInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
+// GET /api/users/investments?include_archived=true
+// Archived (old cancelled or expired) investments are left out unless
+// include_archived is set.
+func (h *InvestmentHandler) List(w http.ResponseWriter, r *http.Request) {
 	uid, ok := utils.GetUserID(r)
 	if !ok || uid == 0 {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
 		return
 	}
 
-	// Get query parameters
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
 	searchQuery := strings.TrimSpace(r.URL.Query().Get("search"))
 
-	// Parse pagination with defaults
-	page, _ := strconv.Atoi(pageStr)
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(limitStr)
-	if limit < 1 {
-		limit = 10
+	db := h.DB
+	if includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived")); includeArchived {
+		db = db.Unscoped()
 	}
 
-	db := database.DB
-
 	// Build base query for counting
 	countQuery := db.Model(&models.Investment{}).Where("user_id = ?", uid)
 	if searchQuery != "" {
-		countQuery = countQuery.Where("order_id LIKE ?", "%"+searchQuery+"%")
+		countQuery = countQuery.Where("order_id LIKE ?", utils.PrefixLike(searchQuery))
 	}
 
 	// Count total rows
 	var totalRows int64
 	if err := countQuery.Count(&totalRows).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		utils.LogError(r, "ListInvestmentsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgGenericError)})
 		return
 	}
 
-	// Calculate pagination
-	totalPages := int(math.Ceil(float64(totalRows) / float64(limit)))
-	offset := (page - 1) * limit
-
 	// Build query for fetching data
 	var rows []models.Investment
 	query := db.Where("user_id = ?", uid)
 	if searchQuery != "" {
-		query = query.Where("order_id LIKE ?", "%"+searchQuery+"%")
+		query = query.Where("order_id LIKE ?", utils.PrefixLike(searchQuery))
 	}
-	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&rows).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+	if err := query.Order("id DESC").Limit(pg.Limit).Offset(pg.Offset).Find(&rows).Error; err != nil {
+		utils.LogError(r, "ListInvestmentsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgGenericError)})
 		return
 	}
 
-	// Build response with pagination
-	responseData := map[string]interface{}{
-		"data": rows,
-		"pagination": map[string]interface{}{
-			"page":       page,
-			"limit":      limit,
-			"total_rows": totalRows,
-			"total_pages": totalPages,
-		},
+	items := make([]InvestmentResponse, len(rows))
+	for i, inv := range rows {
+		items[i] = newInvestmentResponse(inv)
 	}
+	responseData := utils.NewPaginated(items, pg, totalRows)
 
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: responseData})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: responseData})
 }
 
 // GET /api/users/investments/{id}
-func GetInvestmentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *InvestmentHandler) Get(w http.ResponseWriter, r *http.Request) {
 	uid, ok := utils.GetUserID(r)
 	if !ok || uid == 0 {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
 		return
 	}
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var idStr string
-	if len(parts) >= 4 {
-		idStr = parts[3]
-	}
-	id64, err := strconv.ParseUint(idStr, 10, 64)
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
 	if err != nil || id64 == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvalidID)})
 		return
 	}
-	db := database.DB
+	// Archived investments stay reachable by id, e.g. from a transaction
+	db := h.DB.Unscoped()
 	var row models.Investment
 	if err := db.Where("id = ? AND user_id = ?", uint(id64), uid).First(&row).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Data tidak ditemukan"})
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgDataNotFound)})
 			return
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		utils.LogError(r, "GetInvestmentHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgGenericError)})
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: row})
+	resp := newInvestmentResponse(row)
+	resp.Product = investmentProduct(r, h.DB, row.ProductID)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: resp})
 }
 
-// GET /api/users/payment/{order_id}
-func GetPaymentDetailsHandler(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	var orderID string
-	if len(parts) >= 3 {
-		orderID = parts[len(parts)-1]
+// GET /api/users/payments/{order_id}
+func (h *InvestmentHandler) PaymentDetails(w http.ResponseWriter, r *http.Request) {
+	orderID := strings.TrimSpace(mux.Vars(r)["order_id"])
+	if orderID == "" {
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodePaymentNotFound)
+		return
 	}
 
-	db := database.DB
+	db := h.DB
 	var payment models.Payment
 	if err := db.Where("order_id = ?", orderID).First(&payment).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Data pembayaran tidak ditemukan"})
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodePaymentNotFound)
 			return
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		utils.LogError(r, "GetPaymentDetailsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgGenericError)})
 		return
 	}
 
 	var inv models.Investment
 	if err := db.Where("id = ?", payment.InvestmentID).First(&inv).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan mengambil data investasi"})
+		utils.LogError(r, "GetPaymentDetailsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPaymentInvestmentFailed)})
 		return
 	}
-	var product models.Product
-	if err := db.Select("name").Where("id = ?", inv.ProductID).First(&product).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan mengambil data produk"})
+	productName, err := investmentProductName(db, &inv)
+	if err != nil {
+		utils.LogError(r, "GetPaymentDetailsHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPaymentProductFailed)})
 		return
 	}
 	resp := map[string]interface{}{
-		"product":  product.Name,
-		"order_id": payment.OrderID,
-		"amount":   inv.Amount,
+		"product":      productName,
+		"order_id":     payment.OrderID,
+		"amount":       inv.Amount,
+		"fee":          payment.Fee,
+		"gross_amount": payment.Gross(inv.Amount),
 		"payment_code": func() interface{} {
 			if payment.PaymentCode == nil {
 				return nil
@@ -541,16 +643,25 @@ func GetPaymentDetailsHandler(w http.ResponseWriter, r *http.Request) {
 			if payment.ExpiredAt == nil {
 				return nil
 			}
-			return payment.ExpiredAt.UTC().Format(time.RFC3339)
+			return utils.FormatTime(*payment.ExpiredAt)
 		}(),
-		"status": payment.Status,
+		"status":         payment.Status,
+		"certificate_no": inv.CertificateNo,
+	}
+	if payment.PaymentMethod != nil && *payment.PaymentMethod == "MANUAL" {
+		resp["manual_transfer"] = manualTransferDetails(r, db, &payment)
 	}
+	// For the expiry countdown
+	utils.AddLocalTimes(r, resp, map[string]*time.Time{"expired_at": payment.ExpiredAt})
+	utils.AddServerTime(r, resp)
 
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: resp})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: resp})
 }
 
 // POST /api/payments/kyta/webhook
-func KytaWebhookHandler(w http.ResponseWriter, r *http.Request) {
+// Each settlement writes in a single utils.WithTx call, so a failure at any
+// step answers 5xx with nothing persisted and the gateway's retry starts over.
+func (h *InvestmentHandler) KytaWebhook(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		CallbackCode    string `json:"callback_code"`
 		CallbackMessage string `json:"callback_message"`
@@ -575,8 +686,8 @@ func KytaWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		} `json:"callback_data"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
+	if err := utils.DecodeJSON(w, r, &payload, utils.MaxJSONBodyBytes); err != nil {
+		utils.LogError(r, "payment webhook: decode payload", err)
 		return
 	}
 
@@ -589,153 +700,420 @@ func KytaWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isChargeback(status) {
+		h.applyChargeback(w, r, referenceID)
+		return
+	}
+
 	success := status == "SUCCESS" || status == "PAID" || status == "COMPLETED"
 
-	db := database.DB
+	// A 503 on a database timeout makes the gateway retry the callback
+	db, cancel := database.WithTimeout(r.Context(), h.DB)
+	defer cancel()
+
+	// Wallet top-ups share this callback URL; the reference's order type
+	// routes the callback
+	order, _ := utils.ParseOrderID(referenceID)
+	if order.Type == utils.OrderDeposit {
+		deposit, ignored, err := settleDeposit(db, referenceID, paymentID, success)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(r, "payment webhook: load deposit", err, "reference_id", referenceID, "gateway_payment_id", paymentID)
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pembayaran tidak ditemukan", Code: utils.CodePaymentNotFound})
+			return
+		}
+		if err != nil {
+			utils.LogError(r, "payment webhook: settle deposit", err, "reference_id", referenceID, "gateway_payment_id", paymentID)
+			if utils.WriteDBTimeout(w, r, err) {
+				return
+			}
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		message := "OK"
+		if ignored {
+			message = "Ignored"
+		} else if success {
+			h.Notifier.Enqueue(notify.PaymentSuccess(deposit.UserID, deposit.OrderID, deposit.Amount))
+		}
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message})
+		return
+	}
+
+	// So do investment top-ups
+	if order.Type == utils.OrderTopup {
+		topup, ignored, refunded, err := settleTopup(db, referenceID, paymentID, success)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(r, "payment webhook: load top-up", err, "reference_id", referenceID, "gateway_payment_id", paymentID)
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pembayaran tidak ditemukan", Code: utils.CodePaymentNotFound})
+			return
+		}
+		if err != nil {
+			utils.LogError(r, "payment webhook: settle top-up", err, "reference_id", referenceID, "gateway_payment_id", paymentID)
+			if utils.WriteDBTimeout(w, r, err) {
+				return
+			}
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		message := "OK"
+		switch {
+		case ignored:
+			message = "Ignored"
+		case refunded:
+			message = "Refunded"
+			h.Notifier.Enqueue(notify.PaymentRefunded(topup.UserID, topup.OrderID, topup.Amount))
+		case success:
+			h.Notifier.Enqueue(notify.PaymentSuccess(topup.UserID, topup.OrderID, topup.Amount))
+		}
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message})
+		return
+	}
 
 	var payment models.Payment
 	if err := db.Where("order_id = ?", referenceID).First(&payment).Error; err != nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pembayaran tidak ditemukan"})
+		utils.LogError(r, "payment webhook: load payment", err, "reference_id", referenceID, "gateway_payment_id", paymentID)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		h.Alerts.Notify(alert.KeyWebhookRejected, "Webhook pembayaran ditolak: reference %q tidak dikenal (ip %s)", referenceID, r.RemoteAddr)
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pembayaran tidak ditemukan", Code: utils.CodePaymentNotFound})
 		return
 	}
 
-	paymentUpdates := map[string]interface{}{}
-	if paymentID != "" {
-		paymentUpdates["reference_id"] = paymentID
+	// Rewards, pushes and alerts are recorded as outbox events and carried
+	// out after the commit, so none of them can hold up the confirmation
+	var events outbox.Batch
+	// A virtual account paid in several transfers calls back once for each;
+	// the callback time tells the next transfer from a redelivered one
+	receipt := strings.TrimSpace(payload.CallbackData.CallbackTime)
+	if receipt == "" {
+		receipt = fmt.Sprintf("amount:%d", payload.CallbackData.Amount)
 	}
-	if success {
-		paymentUpdates["status"] = "Success"
-	} else {
-		paymentUpdates["status"] = "Failed"
-	}
-	if len(paymentUpdates) > 0 {
-		_ = db.Model(&payment).Updates(paymentUpdates).Error
+	_, ignored, refunded, err := settleInvestmentPayment(db, &payment, success, payload.CallbackData.Amount, paymentID, receipt, &events)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.LogError(r, "payment webhook: load investment", err, "reference_id", referenceID, "gateway_payment_id", paymentID, "investment_id", payment.InvestmentID)
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Investasi tidak ditemukan", Code: utils.CodeInvestmentNotFound})
+		return
 	}
-
-	var inv models.Investment
-	if err := db.Where("id = ?", payment.InvestmentID).First(&inv).Error; err != nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Investasi tidak ditemukan"})
+	if err != nil {
+		// A 5xx makes the gateway retry the callback
+		utils.LogError(r, "payment webhook: apply payment", err, "reference_id", referenceID, "gateway_payment_id", paymentID, "investment_id", payment.InvestmentID)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
-
-	if inv.Status != "Pending" {
+	h.Outbox.Dispatch(events)
+	if !ignored {
+		paymentEvents.Publish(payment.OrderID)
+	}
+	switch {
+	case ignored:
 		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Ignored"})
-		return
+	case refunded:
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Refunded"})
+	case payment.Status == "Partial":
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Partial"})
+	case success:
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "OK"})
+	default:
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Failed updated"})
 	}
+}
 
-	if success {
-		now := time.Now()
-		next := now.Add(24 * time.Hour)
-		_ = db.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Updates(map[string]interface{}{"status": "Success"}).Error; err != nil {
+// paymentTxOptions retries a payment settlement MySQL picked as a deadlock
+// victim, which concurrent callbacks for one user can cause, before the
+// gateway is answered with a 5xx.
+var paymentTxOptions = utils.TxOptions{Retries: 2}
+
+// settleInvestmentPayment applies the outcome of payment, received of it
+// paid when success, to its Pending investment: it activates it, or waits as
+// Partial when short, refunds it when over the purchase limit, or cancels it
+// when the payment failed. A Partial payment adds each further transfer to
+// what it received and activates once the total covers the gross. receipt,
+// when set, identifies the transfer, so a replay of it is ignored rather than
+// counted twice. paymentID, when set, is stored as the gateway_payment_id.
+// Everything runs in one transaction with the investment row locked, so a
+// failure leaves the payment untouched for a retry and a duplicate finds the
+// investment no longer Pending (ignored). Rewards, pushes and alerts are
+// recorded in events and carried out after the commit. The gateway webhook
+// and the review of manual transfers both settle through here.
+func settleInvestmentPayment(db *gorm.DB, payment *models.Payment, success bool, received int64, paymentID, receipt string, events *outbox.Batch) (inv models.Investment, ignored, refunded bool, err error) {
+	recorded := len(*events)
+	err = utils.WithTxOptions(db.Statement.Context, db, paymentTxOptions, func(tx *gorm.DB) error {
+		// A deadlock retry starts over
+		inv, ignored, refunded = models.Investment{}, false, false
+		*events = (*events)[:recorded]
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", payment.InvestmentID).First(&inv).Error; err != nil {
+			return err
+		}
+		// Reloaded under the lock: a concurrent callback may have marked it Partial
+		if err := tx.First(payment, payment.ID).Error; err != nil {
+			return err
+		}
+		// Money already came in on a Partial payment, so only the refund cron
+		// may end it unpaid
+		if inv.Status != "Pending" || (payment.Status == "Partial" && !success) {
+			ignored = true
+			return nil
+		}
+		if success && receipt != "" {
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.PaymentReceipt{PaymentID: payment.ID, CallbackKey: receipt, Amount: received})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				ignored = true
+				return nil
+			}
+		}
+		total := received
+		if payment.Status == "Partial" {
+			total += payment.AmountReceived
+		}
+		// The buyer owes the price plus any passed-through fee. Less waits as
+		// Partial for the refund cron; more activates and holds the excess
+		// for review.
+		gross := payment.Gross(inv.Amount)
+		if success && total < gross {
+			if err := markPartialPayment(tx, payment, total, paymentID); err != nil {
 				return err
 			}
-			updates := map[string]interface{}{"status": "Running", "last_return_at": nil, "next_return_at": next}
-			if err := tx.Model(&inv).Updates(updates).Error; err != nil {
+			// Keyed by the total so each further short transfer is told too
+			key := fmt.Sprintf("%s:%d", inv.OrderID, total)
+			if err := events.Alert(tx, alert.KeyAmountMismatch+":"+key, alert.KeyAmountMismatch, "Pembayaran %s kurang bayar: diterima %d dari %d", payment.OrderID, total, gross); err != nil {
 				return err
 			}
+			return events.Push(tx, "payment_partial:"+key, notify.PaymentPartial(inv.UserID, inv.OrderID, total, gross-total))
+		}
 
-			// Get category info to determine if this is Monitor (locked profit)
-			var category models.Category
-			isMonitor := false
-			if err := tx.Where("id = ?", inv.CategoryID).First(&category).Error; err == nil {
-				if category.ProfitType == "locked" {
-					isMonitor = true
+		// Backstop for purchases that got past the limit checks at creation:
+		// the payment goes back to the balance instead of over the limit
+		if success {
+			if err := lockUserPurchases(tx, inv.UserID); err != nil {
+				return err
+			}
+			over, err := overPurchaseLimit(tx, &inv)
+			if err != nil {
+				return err
+			}
+			if over {
+				refunded = true
+				refund, err := refundOverLimit(tx, &inv, payment, total, paymentID)
+				if err != nil {
+					return err
 				}
+				return events.Push(tx, "payment_refunded:"+inv.OrderID, notify.PaymentRefunded(inv.UserID, inv.OrderID, refund))
 			}
+		}
+
+		paymentUpdates := map[string]interface{}{"status": "Failed"}
+		if success {
+			paymentUpdates["status"] = "Success"
+			paymentUpdates["amount_received"] = total
+		}
+		if paymentID != "" {
+			paymentUpdates["gateway_payment_id"] = paymentID
+		}
+		if err := tx.Model(payment).Updates(paymentUpdates).Error; err != nil {
+			return err
+		}
 
-			// Update user total_invest and total_invest_vip
-			userUpdates := map[string]interface{}{
-				"total_invest":      gorm.Expr("total_invest + ?", inv.Amount),
-				"investment_status": "Active",
+		if !success {
+			if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Update("status", "Failed").Error; err != nil {
+				return err
 			}
-			if isMonitor {
-				userUpdates["total_invest_vip"] = gorm.Expr("total_invest_vip + ?", inv.Amount)
+			return tx.Model(&inv).Update("status", "Cancelled").Error
+		}
+		if err := activateInvestment(tx, &inv, events); err != nil {
+			return err
+		}
+		if err := events.Push(tx, "payment_success:"+inv.OrderID, notify.PaymentSuccess(inv.UserID, inv.OrderID, inv.Amount)); err != nil {
+			return err
+		}
+		if total > gross {
+			excess := total - gross
+			if err := holdOverpayment(tx, &inv, excess); err != nil {
+				return err
 			}
-			if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Updates(userUpdates).Error; err != nil {
+			if err := events.Alert(tx, alert.KeyAmountMismatch+":"+inv.OrderID, alert.KeyAmountMismatch, "Pembayaran %s lebih bayar %d, ditahan untuk ditinjau", payment.OrderID, excess); err != nil {
 				return err
 			}
-
-			// Calculate VIP level based on total_invest_vip for locked categories
-			if isMonitor {
-				var user models.User
-				if err := tx.Model(&models.User{}).Select("total_invest_vip").Where("id = ?", inv.UserID).First(&user).Error; err == nil {
-					newLevel := calculateVIPLevel(user.TotalInvestVIP)
-					if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Update("level", newLevel).Error; err != nil {
-						return err
-					}
-				}
+			if err := events.Push(tx, "payment_overpaid:"+inv.OrderID, notify.PaymentOverpaid(inv.UserID, inv.OrderID, excess)); err != nil {
+				return err
 			}
+		}
+		return applyDepositCampaign(tx, inv.UserID, inv.Amount, inv.OrderID)
+	})
+	return inv, ignored, refunded, err
+}
 
-			// Bonus rekomendasi investor hanya untuk level 1: 30% dari amount
-			var user models.User
-			if err := tx.Select("id, reff_by").Where("id = ?", inv.UserID).First(&user).Error; err == nil && user.ReffBy != nil {
-				var level1 models.User
-				if err := tx.Select("id, spin_ticket").Where("id = ?", *user.ReffBy).First(&level1).Error; err == nil {
-					// Give spin ticket if investment >= 100k
-					if inv.Amount >= 100000 {
-						if level1.SpinTicket == nil {
-							one := uint(1)
-							tx.Model(&models.User{}).Where("id = ?", level1.ID).Update("spin_ticket", one)
-						} else {
-							tx.Model(&models.User{}).Where("id = ?", level1.ID).UpdateColumn("spin_ticket", gorm.Expr("spin_ticket + 1"))
-						}
-					}
+// purchaseBlocked reports why uid may not buy product: its VIP requirement,
+// purchase limit or purchase cooldown. A nil refusal means the purchase is
+// allowed.
+func purchaseBlocked(db *gorm.DB, uid uint, product *models.Product) (*purchaseRefusal, error) {
+	var user models.User
+	if err := db.Select("level").Where("id = ?", uid).First(&user).Error; err != nil {
+		return nil, err
+	}
+	userLevel := uint(0)
+	if user.Level != nil {
+		userLevel = *user.Level
+	}
+	if userLevel < uint(product.RequiredVIP) {
+		return &purchaseRefusal{
+			Code:    utils.CodeVIPRequired,
+			Args:    []interface{}{product.Name, product.RequiredVIP, userLevel},
+			Details: VIPRequiredDetails{RequiredLevel: uint(product.RequiredVIP), CurrentLevel: userLevel},
+		}, nil
+	}
 
-					// Give 30% bonus to direct referrer
-					bonus := round3(inv.Amount * 0.30)
-					tx.Model(&models.User{}).Where("id = ?", level1.ID).UpdateColumn("balance", gorm.Expr("balance + ?", bonus))
-					msg := "Bonus rekomendasi investor"
-					trx := models.Transaction{
-						UserID:          level1.ID,
-						Amount:          bonus,
-						Charge:          0,
-						OrderID:         utils.GenerateOrderID(level1.ID),
-						TransactionFlow: "debit",
-						TransactionType: "team",
-						Message:         &msg,
-						Status:          "Success",
-					}
-					tx.Create(&trx)
-				}
-			}
-			return nil
-		})
-		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "OK"})
-		return
+	if product.PurchaseLimit > 0 {
+		purchases, err := purchaseCount(db, uid, product.ID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if purchases >= int64(product.PurchaseLimit) {
+			return purchaseLimitRefusal(product, purchases), nil
+		}
 	}
 
-	_ = db.Transaction(func(tx *gorm.DB) error {
-		_ = tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Update("status", "Failed").Error
-		_ = tx.Model(&inv).Update("status", "Cancelled").Error
-		return nil
-	})
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Failed updated"})
+	if last, cooling, err := purchaseCooldown(db, uid, product, time.Now()); err != nil {
+		return nil, err
+	} else if cooling {
+		return purchaseCooldownRefusal(product, last), nil
+	}
+	return nil, nil
+}
+
+// activateInvestment starts a paid investment: marks its transaction
+// successful, schedules the first return and updates the investor's totals.
+// The VIP level, missions and referral rewards follow through an outbox event
+// added to events. It must run inside tx.
+func activateInvestment(tx *gorm.DB, inv *models.Investment, events *outbox.Batch) error {
+	next := time.Now().UTC().Add(24 * time.Hour)
+	// Counted before this investment turns Running, to spot a first investment
+	var earlier int64
+	if err := tx.Unscoped().Model(&models.Investment{}).
+		Where("user_id = ? AND id <> ? AND status IN ?", inv.UserID, inv.ID, []string{"Running", "Completed", "Suspended"}).
+		Count(&earlier).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Updates(map[string]interface{}{"status": "Success"}).Error; err != nil {
+		return err
+	}
+	updates := map[string]interface{}{"status": "Running", "last_return_at": nil, "next_return_at": next}
+	// The certificate number is issued once, numbered per year in APP_TIMEZONE
+	if inv.CertificateNo == nil {
+		now := time.Now()
+		certNo, err := models.NextCertificateNo(tx, now.In(utils.AppLocation()).Year())
+		if err != nil {
+			return err
+		}
+		updates["certificate_no"] = certNo
+		updates["certified_at"] = now
+	}
+	if err := tx.Model(inv).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// Get category info to determine if this is Monitor (locked profit)
+	var category models.Category
+	if err := tx.Where("id = ?", inv.CategoryID).First(&category).Error; err != nil {
+		return err
+	}
+	isMonitor := category.ProfitType == "locked"
+
+	// Update user total_invest and total_invest_vip
+	userUpdates := map[string]interface{}{
+		"total_invest":      gorm.Expr("total_invest + ?", inv.Amount),
+		"investment_status": "Active",
+	}
+	if isMonitor {
+		userUpdates["total_invest_vip"] = gorm.Expr("total_invest_vip + ?", inv.Amount)
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Updates(userUpdates).Error; err != nil {
+		return err
+	}
+
+	if err := webhook.Record(tx, webhook.EventInvestmentPaid, inv.UserID, map[string]interface{}{
+		"investment_id": inv.ID, "order_id": inv.OrderID, "product_id": inv.ProductID, "amount": inv.Amount,
+	}); err != nil {
+		return err
+	}
+	return events.Add(tx, outboxInvestmentActivated, inv.OrderID, investmentActivation{InvestmentID: inv.ID, FirstInvestment: earlier == 0, VIP: isMonitor})
+}
+
+// errReturnNotDue marks a due investment another run or an admin changed
+// before its return was credited.
+var errReturnNotDue = errors.New("investment return no longer due")
+
+// dueInvestments selects the running investments whose next return is due.
+// The filter is served by idx_investments_status_next_return.
+func dueInvestments(db *gorm.DB, now time.Time) ([]models.Investment, error) {
+	var due []models.Investment
+	err := db.Where("status = 'Running' AND next_return_at IS NOT NULL AND next_return_at <= ? AND total_paid < duration", now).Find(&due).Error
+	return due, err
 }
 
 // POST /api/cron/daily-returns
-func CronDailyReturnsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *InvestmentHandler) CronDailyReturns(w http.ResponseWriter, r *http.Request) {
 	key := r.Header.Get("X-CRON-KEY")
 	if key == "" || key != os.Getenv("CRON_KEY") {
 		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
 		return
 	}
 
-	db := database.DB
 	now := time.Now()
-	var due []models.Investment
-	if err := db.Where("status = 'Running' AND next_return_at IS NOT NULL AND next_return_at <= ? AND total_paid < duration", now).Find(&due).Error; err != nil {
+	loadDB, cancel := database.WithTimeout(r.Context(), h.DB)
+	due, err := dueInvestments(loadDB, now)
+	var boosts []models.ProfitBoost
+	if err == nil && len(due) > 0 {
+		// Profit boosts running at any of the due payout dates
+		from := now
+		for _, inv := range due {
+			if inv.NextReturnAt.Before(from) {
+				from = *inv.NextReturnAt
+			}
+		}
+		boosts, err = models.ProfitBoostsBetween(loadDB, from, now)
+	}
+	cancel()
+	if err != nil {
+		utils.LogError(r, "daily returns cron: load due investments", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
-	processed := 0
+	processed, failed, skipped := 0, 0, 0
+	interrupted, stalled := false, false
 	for i := range due {
+		// Stop between investments on shutdown; the rest stay due for the next run
+		if utils.ShuttingDown(r) {
+			interrupted = true
+			break
+		}
 		inv := due[i]
-		_ = db.Transaction(func(tx *gorm.DB) error {
+		// Set when profit reaches the balance; pushed only after the commit
+		var credited *notify.Event
+		// Each investment gets its own deadline
+		db, cancel := database.WithTimeout(r.Context(), h.DB)
+		err := db.Transaction(func(tx *gorm.DB) error {
 			var user models.User
 			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, inv.UserID).Error; err != nil {
 				return err
 			}
+			// Reloaded under lock so a top-up applied since the due query
+			// counts in the profit and the capital returned
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, inv.ID).Error; err != nil {
+				return err
+			}
+			if inv.Status != "Running" || inv.TotalPaid >= inv.Duration {
+				return errReturnNotDue
+			}
 
 			// Get category to check profit type
 			var category models.Category
@@ -745,25 +1123,34 @@ func CronDailyReturnsHandler(w http.ResponseWriter, r *http.Request) {
 
 			amount := inv.DailyProfit
 			paid := inv.TotalPaid + 1
-			returned := round3(inv.TotalReturned + amount)
+			returned := inv.TotalReturned + amount
+			// A boost applies by the payout's scheduled date, not the run's
+			payoutAt := now
+			if inv.NextReturnAt != nil {
+				payoutAt = *inv.NextReturnAt
+			}
+			boost := models.BoostFor(boosts, inv.ProductID, inv.CategoryID, payoutAt)
+			boostAmount := boost.Amount(inv.Amount)
+			totalBoost := inv.TotalBoost + boostAmount
 
-			var product models.Product
-			if err := tx.Where("id = ?", inv.ProductID).First(&product).Error; err != nil {
+			productName, err := investmentProductName(tx, &inv)
+			if err != nil {
 				return err
 			}
 
 			// For locked (Monitor) category: Don't pay to balance until completion, just accumulate
 			// For unlocked (Insight/AutoPilot): Pay to balance immediately
 			if category.ProfitType == "unlocked" {
-				newBalance := round3(user.Balance + amount)
+				newBalance := user.Balance + amount
 				if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
 					return err
 				}
 
-				orderID := utils.GenerateOrderID(inv.UserID)
-				msg := fmt.Sprintf("Profit investasi produk %s", product.Name)
+				orderID := utils.GenerateOrderID(utils.OrderReturn, inv.UserID)
+				msg := fmt.Sprintf("Profit investasi produk %s", productName)
 				trx := models.Transaction{
 					UserID:          inv.UserID,
+					InvestmentID:    &inv.ID,
 					Amount:          amount,
 					Charge:          0,
 					OrderID:         orderID,
@@ -775,20 +1162,27 @@ func CronDailyReturnsHandler(w http.ResponseWriter, r *http.Request) {
 				if err := tx.Create(&trx).Error; err != nil {
 					return err
 				}
+				if boostAmount > 0 {
+					if err := creditProfitBoost(tx, &user, &inv, boostAmount, fmt.Sprintf("Bonus promo %s produk %s", boost.Name, productName)); err != nil {
+						return err
+					}
+				}
 			}
 
-			// For locked (Monitor): If completing, pay total accumulated profit
+			// For locked (Monitor): If completing, pay total accumulated profit;
+			// accumulated day by day, so a top-up only raises the days after it
 			if category.ProfitType == "locked" && paid >= inv.Duration {
-				totalProfit := round3(inv.DailyProfit * float64(inv.Duration))
-				newBalance := round3(user.Balance + totalProfit)
+				totalProfit := returned
+				newBalance := user.Balance + totalProfit
 				if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
 					return err
 				}
 
-				orderID := utils.GenerateOrderID(inv.UserID)
-				msg := fmt.Sprintf("Total profit investasi produk %s selesai", product.Name)
+				orderID := utils.GenerateOrderID(utils.OrderReturn, inv.UserID)
+				msg := fmt.Sprintf("Total profit investasi produk %s selesai", productName)
 				trx := models.Transaction{
 					UserID:          inv.UserID,
+					InvestmentID:    &inv.ID,
 					Amount:          totalProfit,
 					Charge:          0,
 					OrderID:         orderID,
@@ -800,25 +1194,34 @@ func CronDailyReturnsHandler(w http.ResponseWriter, r *http.Request) {
 				if err := tx.Create(&trx).Error; err != nil {
 					return err
 				}
+				// Boosts earned along the way are paid with it, as their own row
+				if totalBoost > 0 {
+					if err := creditProfitBoost(tx, &user, &inv, totalBoost, fmt.Sprintf("Total bonus promo investasi produk %s", productName)); err != nil {
+						return err
+					}
+				}
 			}
 
 			// NO TEAM BONUSES - removed completely
 
-			nowTime := time.Now()
+			nowTime := time.Now().UTC()
 			nextTime := nowTime.Add(24 * time.Hour)
-			updates := map[string]interface{}{"total_paid": paid, "total_returned": returned, "last_return_at": nowTime, "next_return_at": nextTime}
+			// Set with the completion's recap
+			var completed *notify.Event
+			updates := map[string]interface{}{"total_paid": paid, "total_returned": returned, "total_boost": totalBoost, "last_return_at": nowTime, "next_return_at": nextTime}
 			if paid >= inv.Duration {
 				updates["status"] = "Completed"
 
-				newBalance := round3(user.Balance + inv.Amount)
+				newBalance := user.Balance + inv.Amount
 				if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
 					return err
 				}
 
-				orderID := utils.GenerateOrderID(inv.UserID)
-				msg := fmt.Sprintf("Pengembalian modal investasi produk %s", product.Name)
+				orderID := utils.GenerateOrderID(utils.OrderReturn, inv.UserID)
+				msg := fmt.Sprintf("Pengembalian modal investasi produk %s", productName)
 				trx := models.Transaction{
 					UserID:          inv.UserID,
+					InvestmentID:    &inv.ID,
 					Amount:          inv.Amount,
 					Charge:          0,
 					OrderID:         orderID,
@@ -830,240 +1233,140 @@ func CronDailyReturnsHandler(w http.ResponseWriter, r *http.Request) {
 				if err := tx.Create(&trx).Error; err != nil {
 					return err
 				}
+				if err := webhook.Record(tx, webhook.EventInvestmentCompleted, inv.UserID, map[string]interface{}{
+					"investment_id": inv.ID, "order_id": inv.OrderID, "product_id": inv.ProductID, "amount": inv.Amount, "total_returned": returned,
+				}); err != nil {
+					return err
+				}
+				// The recap is summed from the rows written above and before
+				recap, err := recordInvestmentRecap(tx, &inv, productName, &trx, paid, nowTime)
+				if err != nil {
+					return err
+				}
+				e := notify.InvestmentCompleted(inv.UserID, inv.ID, productName, recap.Principal, recap.TotalEarned, recap.ROIPercent)
+				locale, err := notify.UserLocale(tx, inv.UserID)
+				if err != nil {
+					return err
+				}
+				inbox := notify.Inbox(e, locale, "investment_completed")
+				if err := tx.Create(&inbox).Error; err != nil {
+					return err
+				}
+				completed = &e
 			}
 			if err := tx.Model(&inv).Updates(updates).Error; err != nil {
 				return err
 			}
 			processed++
+			// A completion's recap covers its last profit too
+			if completed != nil {
+				credited = completed
+			} else if category.ProfitType == "unlocked" {
+				e := notify.ProfitCredited(inv.UserID, inv.ID, productName, amount+boostAmount)
+				credited = &e
+			}
 			return nil
 		})
-	}
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"processed": processed}})
-}
-
-func parseTimeFlexible(s string) (time.Time, error) {
-	if s == "" {
-		return time.Time{}, errors.New("empty")
-	}
-	if t, err := time.Parse(time.RFC3339, s); err == nil {
-		return t, nil
-	}
-	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
-		return t, nil
-	}
-	if t, err := time.Parse("2006-01-02T15:04:05.000Z07:00", s); err == nil {
-		return t, nil
-	}
-	return time.Time{}, fmt.Errorf("cannot parse time: %s", s)
-}
-
-// FIXED: getKytaAccessToken with proper error handling
-func getKytaAccessTokenSafe(ctx context.Context, client *http.Client, baseURL, clientID, clientSecret string) (string, string, error) {
-	url := strings.TrimRight(baseURL, "/") + "/access-token"
-
-	credentials := clientID + ":" + clientSecret
-	encodedCredentials := base64.StdEncoding.EncodeToString([]byte(credentials))
-
-	payload := map[string]string{"grant_type": "client_credentials"}
-	body, _ := json.Marshal(payload)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return "", "Gagal membuat request token", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Basic "+encodedCredentials)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", "Koneksi ke layanan pembayaran gagal", err
-	}
-	defer resp.Body.Close()
-
-	// Baca response body terlebih dahulu
-	tokenBodyBytes, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		return "", "Gagal membaca response token", readErr
-	}
-
-	// Parse response
-	var tokenResp KytaAccessTokenResponse
-	parseErr := json.Unmarshal(tokenBodyBytes, &tokenResp)
-
-	// Cek HTTP status
-	if resp.StatusCode != http.StatusOK {
-		errorMsg := "Gagal mendapatkan token pembayaran"
-		if parseErr == nil && tokenResp.ResponseMessage != "" {
-			errorMsg = tokenResp.ResponseMessage
-		} else if len(tokenBodyBytes) > 0 && len(tokenBodyBytes) < 500 {
-			errorMsg = string(tokenBodyBytes)
+		cancel()
+		if errors.Is(err, errReturnNotDue) {
+			skipped++
+			continue
+		}
+		// MySQL is stalled: stop instead of waiting out every remaining
+		// investment; this one rolled back and stays due with the rest
+		if database.IsTimeout(err) {
+			utils.LogError(r, "daily returns cron: credit investment", err, "investment_id", inv.ID, "user_id", inv.UserID)
+			stalled = true
+			break
+		}
+		if err != nil {
+			failed++
+			utils.LogError(r, "daily returns cron: credit investment", err, "investment_id", inv.ID, "user_id", inv.UserID)
+		} else if credited != nil {
+			h.Notifier.Enqueue(*credited)
 		}
-		return "", errorMsg, fmt.Errorf("status %d", resp.StatusCode)
 	}
-
-	// Cek parsing error setelah HTTP OK
-	if parseErr != nil {
-		return "", "Gagal parsing response token", parseErr
+	if failed > 0 {
+		h.Alerts.Notify(alert.KeyCronFailed, "Cron daily returns: %d investasi gagal diproses, %d berhasil", failed, processed)
 	}
-
-	// Cek response code
-	if tokenResp.ResponseCode != "" && tokenResp.ResponseCode != "2000100" && tokenResp.ResponseCode != "200" && !strings.HasPrefix(tokenResp.ResponseCode, "200") {
-		return "", tokenResp.ResponseMessage, errors.New("kytapay error")
+	if stalled {
+		h.Alerts.Notify(alert.KeyCronFailed, "Cron daily returns berhenti: database timeout setelah %d investasi", processed)
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.APIResponse{Success: false, Message: utils.T(r, string(utils.CodeDatabaseTimeout)), Code: utils.CodeDatabaseTimeout, Data: map[string]interface{}{"processed": processed, "failed": failed, "remaining": len(due) - processed - failed - skipped}})
+		return
 	}
-
-	if tokenResp.ResponseData.AccessToken == "" {
-		return "", "Token pembayaran kosong", errors.New("empty token")
+	if interrupted {
+		utils.Logger.Warn("daily returns cron interrupted by shutdown", "request_id", utils.GetRequestID(r), "processed", processed, "remaining", len(due)-processed-failed-skipped)
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.APIResponse{Success: false, Message: "Cron interrupted by shutdown", Data: map[string]interface{}{"processed": processed, "failed": failed, "remaining": len(due) - processed - failed - skipped}})
+		return
 	}
-
-	return tokenResp.ResponseData.AccessToken, "", nil
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"processed": processed, "failed": failed}})
 }
 
-// FIXED: createKytaQRIS with proper error handling
-func createKytaQRISSafe(ctx context.Context, client *http.Client, baseURL, accessToken, referenceID string, amount int64, notifyURL, successURL, failedURL string) (*KytaPaymentResponse, string, error) {
-	url := strings.TrimRight(baseURL, "/") + "/payments/create/qris"
-
-	payload := map[string]interface{}{
-		"reference_id": referenceID,
-		"amount":       amount,
-		"notify_url":   notifyURL,
-		"success_url":  successURL,
-		"failed_url":   failedURL,
-		"expires_time": 900,
-	}
-	body, _ := json.Marshal(payload)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return nil, "Gagal membuat request QRIS", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+accessToken)
+// creditProfitBoost adds a profit boost to the user's balance as its own
+// "profit_boost" transaction, so promotions can be reported apart from the
+// products' profit. It must run inside the transaction crediting inv.
+func creditProfitBoost(tx *gorm.DB, user *models.User, inv *models.Investment, amount int64, msg string) error {
+	if err := tx.Model(user).Update("balance", user.Balance+amount).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.Transaction{
+		UserID:          inv.UserID,
+		InvestmentID:    &inv.ID,
+		Amount:          amount,
+		OrderID:         utils.GenerateOrderID(utils.OrderReturn, inv.UserID),
+		TransactionFlow: "debit",
+		TransactionType: "profit_boost",
+		Message:         &msg,
+		Status:          "Success",
+	}).Error
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, "Koneksi ke layanan pembayaran gagal", err
+// investmentProductName returns the product name snapshotted on the investment,
+// falling back to the products table for rows created before the snapshot existed.
+func investmentProductName(db *gorm.DB, inv *models.Investment) (string, error) {
+	if inv.ProductName != "" {
+		return inv.ProductName, nil
 	}
-	defer resp.Body.Close()
-
-	// Baca response body terlebih dahulu
-	paymentBodyBytes, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, "Gagal membaca response pembayaran", readErr
+	var product models.Product
+	if err := db.Select("name").Where("id = ?", inv.ProductID).First(&product).Error; err != nil {
+		return "", err
 	}
+	return product.Name, nil
+}
 
-	// Parse response
-	var paymentResp KytaPaymentResponse
-	parseErr := json.Unmarshal(paymentBodyBytes, &paymentResp)
-
-	// Cek HTTP status
-	if resp.StatusCode != http.StatusOK {
-		errorMsg := "Gagal membuat pembayaran QRIS"
-		if parseErr == nil && paymentResp.ResponseMessage != "" {
-			errorMsg = paymentResp.ResponseMessage
-		} else if len(paymentBodyBytes) > 0 && len(paymentBodyBytes) < 500 {
-			errorMsg = string(paymentBodyBytes)
+// gatewayPaymentDetails extracts what the user needs to pay from a gateway
+// response: the QRIS string or VA number, the checkout link and the expiry
+// (15 minutes from now when the gateway sends none).
+func gatewayPaymentDetails(method string, resp *kyta.PaymentResponse) (code, link *string, expiredAt *time.Time) {
+	if method == "QRIS" {
+		if qr := strings.TrimSpace(resp.ResponseData.PaymentData.QRString); qr != "" {
+			code = &qr
 		}
-		return nil, errorMsg, fmt.Errorf("status %d", resp.StatusCode)
+	} else if accNum := strings.TrimSpace(resp.ResponseData.PaymentData.AccountNumber); accNum != "" {
+		code = &accNum
 	}
-
-	// Cek parsing error setelah HTTP OK
-	if parseErr != nil {
-		return nil, "Gagal parsing response pembayaran", parseErr
+	if url := strings.TrimSpace(resp.ResponseData.CheckoutURL); url != "" {
+		link = &url
 	}
-
-	// Cek response code
-	if paymentResp.ResponseCode != "" && paymentResp.ResponseCode != "2001100" && paymentResp.ResponseCode != "200" && !strings.HasPrefix(paymentResp.ResponseCode, "200") {
-		return nil, paymentResp.ResponseMessage, errors.New("kytapay error")
+	t := time.Now().UTC().Add(15 * time.Minute)
+	if parsed, err := parseTimeFlexible(strings.TrimSpace(resp.ResponseData.ExpiresAt)); err == nil {
+		t = parsed.UTC()
 	}
-
-	return &paymentResp, "", nil
+	return code, link, &t
 }
 
-// FIXED: createKytaVA with proper error handling
-func createKytaVASafe(ctx context.Context, client *http.Client, baseURL, accessToken, referenceID string, amount int64, bankCode, notifyURL, successURL, failedURL string) (*KytaPaymentResponse, string, error) {
-	url := strings.TrimRight(baseURL, "/") + "/payments/create/va"
-
-	payload := map[string]interface{}{
-		"reference_id": referenceID,
-		"amount":       amount,
-		"bank_code":    bankCode,
-		"notify_url":   notifyURL,
-		"success_url":  successURL,
-		"failed_url":   failedURL,
-		"expires_time": 900,
-	}
-	body, _ := json.Marshal(payload)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return nil, "Gagal membuat request VA", err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, "Koneksi ke layanan pembayaran gagal", err
-	}
-	defer resp.Body.Close()
-
-	// Baca response body terlebih dahulu
-	paymentBodyBytes, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, "Gagal membaca response pembayaran", readErr
+func parseTimeFlexible(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("empty")
 	}
-
-	// Parse response
-	var paymentResp KytaPaymentResponse
-	parseErr := json.Unmarshal(paymentBodyBytes, &paymentResp)
-
-	// Cek HTTP status
-	if resp.StatusCode != http.StatusOK {
-		errorMsg := "Gagal membuat pembayaran Virtual Account"
-		if parseErr == nil && paymentResp.ResponseMessage != "" {
-			errorMsg = paymentResp.ResponseMessage
-		} else if len(paymentBodyBytes) > 0 && len(paymentBodyBytes) < 500 {
-			errorMsg = string(paymentBodyBytes)
-		}
-		return nil, errorMsg, fmt.Errorf("status %d", resp.StatusCode)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
 	}
-
-	// Cek parsing error setelah HTTP OK
-	if parseErr != nil {
-		return nil, "Gagal parsing response pembayaran", parseErr
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
 	}
-
-	// Cek response code
-	if paymentResp.ResponseCode != "" && paymentResp.ResponseCode != "2001200" && paymentResp.ResponseCode != "200" && !strings.HasPrefix(paymentResp.ResponseCode, "200") {
-		return nil, paymentResp.ResponseMessage, errors.New("kytapay error")
+	if t, err := time.Parse("2006-01-02T15:04:05.000Z07:00", s); err == nil {
+		return t, nil
 	}
-
-	return &paymentResp, "", nil
-}
-
-func round3(f float64) float64 {
-	return float64(int(f*100+0.5)) / 100
-}
-
-// calculateVIPLevel determines VIP level based on total locked category investments
-// VIP1: 50k, VIP2: 1.2M, VIP3: 7M, VIP4: 30M, VIP5: 150M
-func calculateVIPLevel(totalInvestVIP float64) uint {
-	if totalInvestVIP >= 150000000 {
-		return 5
-	} else if totalInvestVIP >= 30000000 {
-		return 4
-	} else if totalInvestVIP >= 7000000 {
-		return 3
-	} else if totalInvestVIP >= 1200000 {
-		return 2
-	} else if totalInvestVIP >= 50000 {
-		return 1
-	}
-	return 0
+	return time.Time{}, fmt.Errorf("cannot parse time: %s", s)
 }
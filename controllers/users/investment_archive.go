@@ -0,0 +1,108 @@
+package users
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultArchiveAfterDays is used when ARCHIVE_AFTER_DAYS is not set.
+	defaultArchiveAfterDays = 90
+	// archiveBatchSize bounds the rows one archive transaction touches.
+	archiveBatchSize = 500
+)
+
+// POST /api/cron/archive-investments
+// Soft-deletes investments that never ran and were last touched more than
+// ARCHIVE_AFTER_DAYS ago, with their payments: Cancelled ones, and Pending
+// ones whose payment expired (these are first marked Cancelled with their
+// transaction Failed). Running, Suspended and Completed investments and all
+// transactions are kept forever.
+func (h *InvestmentHandler) CronArchiveInvestments(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	days := defaultArchiveAfterDays
+	if v, err := strconv.Atoi(os.Getenv("ARCHIVE_AFTER_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	archived := 0
+	for {
+		n, err := archiveInvestmentBatch(h.DB, cutoff)
+		if err != nil {
+			utils.LogError(r, "archive cron: archive batch", err, "archived", archived)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+			return
+		}
+		archived += n
+		if n < archiveBatchSize {
+			break
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Cron executed",
+		Data:    map[string]interface{}{"archived": archived, "cutoff": utils.FormatTime(cutoff)},
+	})
+}
+
+// archiveInvestmentBatch archives up to archiveBatchSize investments last
+// updated before cutoff and returns how many it archived.
+func archiveInvestmentBatch(db *gorm.DB, cutoff time.Time) (int, error) {
+	var inv []models.Investment
+	if err := db.Select("id, order_id, status").
+		Where("updated_at < ?", cutoff).
+		Where("status = ? OR (status = ? AND id IN (?))", "Cancelled", "Pending",
+			db.Model(&models.Payment{}).Select("investment_id").Where("status = ? AND expired_at < ?", "Pending", cutoff)).
+		Order("id ASC").Limit(archiveBatchSize).
+		Find(&inv).Error; err != nil {
+		return 0, err
+	}
+	if len(inv) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, 0, len(inv))
+	var expired []string
+	for _, i := range inv {
+		ids = append(ids, i.ID)
+		if i.Status == "Pending" {
+			expired = append(expired, i.OrderID)
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(expired) > 0 {
+			// Re-checked as Pending: a late payment may have activated one
+			if err := tx.Model(&models.Investment{}).Where("order_id IN ? AND status = ?", expired, "Pending").Update("status", "Cancelled").Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Transaction{}).Where("order_id IN ? AND status = ?", expired, "Pending").Update("status", "Failed").Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Payment{}).Where("order_id IN ? AND status = ?", expired, "Pending").Update("status", "Failed").Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id IN ? AND status = ?", ids, "Cancelled").Delete(&models.Investment{}).Error; err != nil {
+			return err
+		}
+		archived := tx.Unscoped().Model(&models.Investment{}).Select("id").Where("id IN ? AND deleted_at IS NOT NULL", ids)
+		return tx.Where("investment_id IN (?)", archived).Delete(&models.Payment{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(inv), nil
+}
@@ -0,0 +1,82 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+func TestArchiveExpiredInvestment(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	t.Setenv("ARCHIVE_AFTER_DAYS", "90")
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Arsip"})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Archive 1"})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	// The payment expired and nothing touched the investment for 100 days
+	old := time.Now().AddDate(0, 0, -100)
+	tx.Model(&models.Payment{}).Where("investment_id = ?", inv.ID).UpdateColumn("expired_at", old)
+	tx.Model(&models.Investment{}).Where("id = ?", inv.ID).UpdateColumn("updated_at", old)
+
+	cron := httptest.NewRequest(http.MethodPost, "/v3/cron/archive-investments", nil)
+	cron.Header.Set("X-CRON-KEY", "cron-test")
+	rec = httptest.NewRecorder()
+	h.CronArchiveInvestments(rec, cron)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cron: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var archived models.Investment
+	if err := tx.Unscoped().First(&archived, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if archived.Status != "Cancelled" || !archived.DeletedAt.Valid {
+		t.Fatalf("expected a cancelled, archived investment, got status %s deleted_at %v", archived.Status, archived.DeletedAt)
+	}
+	var pending int64
+	tx.Model(&models.Transaction{}).Where("order_id = ? AND status = ?", inv.OrderID, "Pending").Count(&pending)
+	if pending != 0 {
+		t.Fatalf("expected the purchase transaction to be failed, %d still pending", pending)
+	}
+
+	list := func(query string) []InvestmentResponse {
+		rec := httptest.NewRecorder()
+		h.List(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/investments"+query, nil), user.ID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data struct {
+				Items []InvestmentResponse `json:"data"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data.Items
+	}
+	if items := list(""); len(items) != 0 {
+		t.Fatalf("expected archived investments to be hidden, got %d", len(items))
+	}
+	if items := list("?include_archived=true"); len(items) != 1 || !items[0].Archived {
+		t.Fatalf("expected the archived investment with include_archived, got %+v", items)
+	}
+}
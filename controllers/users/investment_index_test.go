@@ -0,0 +1,103 @@
+package users
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// dueFixtureRows is the size of the investments fixture for the cron query.
+	dueFixtureRows = 300000
+	// dueQueryBudget is the most the due-investments selection may take on it.
+	dueQueryBudget = 200 * time.Millisecond
+)
+
+// seedDueFixture inserts dueFixtureRows investments, mostly finished or not
+// yet due, with dueCount running investments that are due now.
+func seedDueFixture(tb testing.TB, tx *gorm.DB, dueCount int) {
+	tb.Helper()
+	suffix := time.Now().UnixNano() % 1000000000
+	category := testutil.NewCategory(tb, tx, "unlocked")
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(24*time.Hour)
+	// Each row binds about 20 columns; MySQL takes at most 65535 placeholders
+	// per statement
+	batch := make([]models.Investment, 0, 2000)
+	for i := 0; i < dueFixtureRows; i++ {
+		inv := models.Investment{
+			UserID: uint(i%20000 + 1), ProductID: 1, CategoryID: category.ID, ProductName: "Fixture",
+			Amount: 100000, DailyProfit: 5000, Duration: 30, OrderID: fmt.Sprintf("FIX-%d-%07d", suffix, i),
+		}
+		switch {
+		case i < dueCount:
+			inv.Status, inv.NextReturnAt = "Running", &past
+		case i%3 == 0:
+			inv.Status, inv.NextReturnAt = "Running", &future
+		case i%3 == 1:
+			inv.Status, inv.TotalPaid = "Completed", 30
+		default:
+			inv.Status = "Pending"
+		}
+		batch = append(batch, inv)
+		if len(batch) == cap(batch) {
+			if err := tx.Create(&batch).Error; err != nil {
+				tb.Fatal(err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := tx.Create(&batch).Error; err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestDueInvestmentsQueryBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("seeds a large fixture")
+	}
+	tx := testutil.Tx(t)
+	const dueCount = 500
+	seedDueFixture(t, tx, dueCount)
+
+	var plan []struct {
+		Key *string `gorm:"column:key"`
+	}
+	if err := tx.Raw("EXPLAIN SELECT * FROM investments WHERE status = 'Running' AND next_return_at IS NOT NULL AND next_return_at <= ? AND total_paid < duration", time.Now()).Scan(&plan).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) == 0 || plan[0].Key == nil || *plan[0].Key != "idx_investments_status_next_return" {
+		t.Fatalf("cron selection does not use idx_investments_status_next_return: %+v", plan)
+	}
+
+	start := time.Now()
+	due, err := dueInvestments(tx, time.Now())
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) < dueCount {
+		t.Fatalf("expected at least %d due investments, got %d", dueCount, len(due))
+	}
+	if elapsed > dueQueryBudget {
+		t.Fatalf("due selection took %s on %d rows, budget %s", elapsed, dueFixtureRows, dueQueryBudget)
+	}
+}
+
+func BenchmarkDueInvestments(b *testing.B) {
+	tx := testutil.Tx(b)
+	seedDueFixture(b, tx, 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dueInvestments(tx, time.Now()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
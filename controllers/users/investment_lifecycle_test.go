@@ -0,0 +1,144 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/kyta"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestInvestmentLifecycle(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Investor", ReffBy: &referrer.ID})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Insight 1"})
+
+	gateway := &testutil.Kyta{}
+	h := NewInvestmentHandler(tx, gateway)
+
+	// 1. Purchase creates a pending investment, payment and transaction
+	body := fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID)
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(gateway.Payments) != 1 || gateway.Payments[0].Amount != product.Amount {
+		t.Fatalf("expected one gateway payment of %d, got %+v", product.Amount, gateway.Payments)
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Pending" || inv.OrderID != gateway.Payments[0].ReferenceID {
+		t.Fatalf("unexpected investment after purchase: %+v", inv)
+	}
+
+	// 2. Gateway webhook activates it and pays the referral bonus
+	webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-1","reference_id":%q,"amount":%d,"status":"SUCCESS"}}`, inv.OrderID, inv.Amount)
+	rec = httptest.NewRecorder()
+	h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := tx.First(&inv, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Running" || inv.NextReturnAt == nil {
+		t.Fatalf("unexpected investment after webhook: %+v", inv)
+	}
+	// The gateway's id is kept apart from the reference we sent
+	var payment models.Payment
+	if err := tx.Where("order_id = ?", inv.OrderID).First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if utils.GetStringValue(payment.ReferenceID) != inv.OrderID || utils.GetStringValue(payment.GatewayPaymentID) != "pay-1" {
+		t.Fatalf("expected reference %s and gateway id pay-1, got %v and %v", inv.OrderID, payment.ReferenceID, payment.GatewayPaymentID)
+	}
+	var ref models.User
+	if err := tx.First(&ref, referrer.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ref.Balance <= 0 {
+		t.Fatalf("expected referral bonus for referrer, balance %d", ref.Balance)
+	}
+
+	// A replayed callback is acknowledged without paying the bonus again
+	rec = httptest.NewRecorder()
+	h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("replayed webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var replayed models.User
+	if err := tx.First(&replayed, referrer.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Balance != ref.Balance {
+		t.Fatalf("replayed webhook changed referrer balance from %d to %d", ref.Balance, replayed.Balance)
+	}
+
+	// 3. Two daily-return runs pay the profit, then complete and return the capital
+	for day := 1; day <= product.Duration; day++ {
+		if err := tx.Model(&models.Investment{}).Where("id = ?", inv.ID).Update("next_return_at", time.Now().Add(-time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v3/cron/daily-returns", nil)
+		req.Header.Set("X-CRON-KEY", "cron-test")
+		rec = httptest.NewRecorder()
+		h.CronDailyReturns(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cron day %d: expected 200, got %d: %s", day, rec.Code, rec.Body.String())
+		}
+	}
+	if err := tx.First(&inv, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Completed" || inv.TotalPaid != product.Duration {
+		t.Fatalf("unexpected investment after returns: %+v", inv)
+	}
+	var investor models.User
+	if err := tx.First(&investor, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := product.Amount + product.DailyProfit*int64(product.Duration); investor.Balance != want {
+		t.Fatalf("expected balance %d, got %d", want, investor.Balance)
+	}
+}
+
+func TestCreateInvestmentGatewayFailure(t *testing.T) {
+	tx := testutil.Tx(t)
+	user := testutil.NewUser(t, tx, models.User{Name: "Investor"})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Insight 1"})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{Err: &kyta.Error{Message: "down", Err: context.DeadlineExceeded}})
+	body := fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID)
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp utils.APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != utils.CodePaymentGatewayError {
+		t.Fatalf("expected %s, got %s", utils.CodePaymentGatewayError, resp.Code)
+	}
+	var count int64
+	tx.Model(&models.Investment{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("no investment should be stored when the gateway fails, got %d", count)
+	}
+}
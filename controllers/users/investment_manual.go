@@ -0,0 +1,153 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/models"
+	"project/notify"
+	"project/outbox"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// ManualInvestmentRequest registers an investment paid outside the gateway,
+// such as an offline bank transfer taken by sales. Paid must be true to
+// confirm the money was received. OverrideReason skips the VIP and purchase
+// limit checks and is kept on the transaction; SkipEffects starts the
+// investment without touching total_invest, VIP level, missions or the
+// referrer's bonus.
+type ManualInvestmentRequest struct {
+	UserID         uint   `json:"user_id" validate:"required"`
+	ProductID      uint   `json:"product_id" validate:"required"`
+	Paid           bool   `json:"paid"`
+	SkipEffects    bool   `json:"skip_effects"`
+	OverrideReason string `json:"override_reason" validate:"max=255"`
+}
+
+// POST /api/admin/investments
+// Admin-only despite living here: it shares activateInvestment with the
+// payment webhook so a manual investment has the same effects as a paid one.
+func (h *InvestmentHandler) AdminCreate(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := utils.GetAdminID(r)
+	if !ok {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	var req ManualInvestmentRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	if !req.Paid {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Investasi manual hanya untuk pembayaran yang sudah diterima (paid harus true)"})
+		return
+	}
+	reason := strings.TrimSpace(req.OverrideReason)
+
+	db := h.DB
+	var product models.Product
+	if err := db.Where("id = ? AND status = 'Active'", req.ProductID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeProductNotFound)
+			return
+		}
+		utils.LogError(r, "AdminCreateInvestment", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	if reason == "" {
+		refusal, err := purchaseBlocked(db, req.UserID, &product)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeUserNotFound)
+			return
+		}
+		if err != nil {
+			utils.LogError(r, "AdminCreateInvestment", err)
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+			return
+		}
+		if refusal != nil {
+			refusal.write(w, r)
+			return
+		}
+	} else {
+		var count int64
+		if err := db.Model(&models.User{}).Where("id = ?", req.UserID).Count(&count).Error; err != nil {
+			utils.LogError(r, "AdminCreateInvestment", err)
+			utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+			return
+		}
+		if count == 0 {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeUserNotFound)
+			return
+		}
+	}
+
+	inv := models.Investment{
+		UserID:      req.UserID,
+		ProductID:   product.ID,
+		CategoryID:  product.CategoryID,
+		ProductName: product.Name,
+		Amount:      product.Amount,
+		DailyProfit: product.DailyProfit,
+		Duration:    product.Duration,
+		OrderID:     utils.GenerateOrderID(utils.OrderInvestment, req.UserID),
+		Status:      "Pending",
+		CreatedBy:   &adminID,
+	}
+	msg := fmt.Sprintf("Investasi %s (manual oleh admin #%d)", product.Name, adminID)
+	if reason != "" {
+		msg += ": " + reason
+	}
+
+	var events outbox.Batch
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&inv).Error; err != nil {
+			return err
+		}
+		trx := models.Transaction{
+			UserID:          inv.UserID,
+			InvestmentID:    &inv.ID,
+			Amount:          inv.Amount,
+			Charge:          0,
+			OrderID:         inv.OrderID,
+			TransactionFlow: "credit",
+			TransactionType: "investment",
+			Message:         &msg,
+			Status:          "Pending",
+		}
+		if err := tx.Create(&trx).Error; err != nil {
+			return err
+		}
+		if !req.SkipEffects {
+			return activateInvestment(tx, &inv, &events)
+		}
+		if err := tx.Model(&trx).Update("status", "Success").Error; err != nil {
+			return err
+		}
+		next := time.Now().UTC().Add(24 * time.Hour)
+		return tx.Model(&inv).Updates(map[string]interface{}{"status": "Running", "next_return_at": next}).Error
+	})
+	if err != nil {
+		utils.LogError(r, "AdminCreateInvestment", err, "user_id", req.UserID, "product_id", req.ProductID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat investasi"})
+		return
+	}
+	h.Outbox.Dispatch(events)
+	h.Notifier.Enqueue(notify.PaymentSuccess(inv.UserID, inv.OrderID, inv.Amount))
+
+	if err := db.First(&inv, inv.ID).Error; err != nil {
+		utils.LogError(r, "AdminCreateInvestment: reload", err, "investment_id", inv.ID)
+	}
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Investasi berhasil dibuat",
+		Data:    inv,
+	})
+}
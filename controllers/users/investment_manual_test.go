@@ -0,0 +1,75 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestAdminCreateManualInvestment(t *testing.T) {
+	tx := testutil.Tx(t)
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Offline", ReffBy: &referrer.ID})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Insight VIP", Amount: 200000, Duration: 10, RequiredVIP: 3})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/investments", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(7)))
+		rec := httptest.NewRecorder()
+		h.AdminCreate(rec, req)
+		return rec
+	}
+
+	// The VIP requirement applies unless overridden
+	if rec := create(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true}`, user.ID, product.ID)); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), string(utils.CodeVIPRequired)) {
+		t.Fatalf("expected %s, got %d: %s", utils.CodeVIPRequired, rec.Code, rec.Body.String())
+	}
+	if rec := create(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":false,"override_reason":"x"}`, user.ID, product.ID)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unpaid: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := create(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true,"override_reason":"Transfer BCA 12/05"}`, user.ID, product.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("override: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Running" || inv.NextReturnAt == nil || inv.CreatedBy == nil || *inv.CreatedBy != 7 {
+		t.Fatalf("unexpected manual investment: %+v", inv)
+	}
+	var trx models.Transaction
+	if err := tx.Where("order_id = ?", inv.OrderID).First(&trx).Error; err != nil {
+		t.Fatal(err)
+	}
+	if trx.Status != "Success" || trx.Message == nil || !strings.Contains(*trx.Message, "manual") {
+		t.Fatalf("unexpected manual transaction: %+v", trx)
+	}
+	var got, ref models.User
+	tx.First(&got, user.ID)
+	tx.First(&ref, referrer.ID)
+	if got.TotalInvest != product.Amount || ref.Balance <= 0 {
+		t.Fatalf("expected investment effects, total_invest %d referrer balance %d", got.TotalInvest, ref.Balance)
+	}
+
+	// skip_effects starts the investment without touching totals or the referrer
+	rec = create(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true,"override_reason":"Koreksi","skip_effects":true}`, user.ID, product.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("skip_effects: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var after, refAfter models.User
+	tx.First(&after, user.ID)
+	tx.First(&refAfter, referrer.ID)
+	if after.TotalInvest != got.TotalInvest || refAfter.Balance != ref.Balance {
+		t.Fatalf("skip_effects changed totals: total_invest %d -> %d, referrer %d -> %d", got.TotalInvest, after.TotalInvest, ref.Balance, refAfter.Balance)
+	}
+}
@@ -0,0 +1,86 @@
+package users
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// recordInvestmentRecap writes the recap of inv, which completes in tx with
+// capital as its capital return. Profit and boosts are summed from the
+// investment's Success return and profit_boost transactions, the capital
+// return left out, so the recap holds what was actually paid.
+func recordInvestmentRecap(tx *gorm.DB, inv *models.Investment, productName string, capital *models.Transaction, duration int, completedAt time.Time) (*models.InvestmentRecap, error) {
+	var sums struct {
+		Profit int64
+		Boost  int64
+	}
+	if err := tx.Model(&models.Transaction{}).
+		Select(`COALESCE(SUM(CASE WHEN transaction_type = 'return' THEN amount END), 0) AS profit,
+			COALESCE(SUM(CASE WHEN transaction_type = 'profit_boost' THEN amount END), 0) AS boost`).
+		Where("investment_id = ? AND id <> ? AND transaction_flow = ? AND status = ?", inv.ID, capital.ID, "debit", "Success").
+		Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+	recap := models.InvestmentRecap{
+		InvestmentID: inv.ID,
+		UserID:       inv.UserID,
+		OrderID:      inv.OrderID,
+		ProductName:  productName,
+		Principal:    capital.Amount,
+		Profit:       sums.Profit,
+		Boost:        sums.Boost,
+		TotalEarned:  sums.Profit + sums.Boost,
+		Duration:     duration,
+		StartedAt:    inv.CertifiedAt,
+		CompletedAt:  completedAt,
+	}
+	if recap.StartedAt == nil {
+		recap.StartedAt = &inv.CreatedAt
+	}
+	if capital.Amount > 0 {
+		recap.ROIPercent = math.Round(float64(recap.TotalEarned)*10000/float64(capital.Amount)) / 100
+	}
+	if err := tx.Create(&recap).Error; err != nil {
+		return nil, err
+	}
+	return &recap, nil
+}
+
+// GET /api/users/investments/{id}/recap
+// The earnings recap written when the investment completed: principal
+// returned, profit, boosts, total earned, duration and ROI. Investments still
+// running, and those completed before recaps were kept, answer
+// INVESTMENT_RECAP_NOT_FOUND.
+func (h *InvestmentHandler) Recap(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id64 == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvalidID)})
+		return
+	}
+	var recap models.InvestmentRecap
+	if err := h.DB.Where("investment_id = ? AND user_id = ?", uint(id64), uid).First(&recap).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeRecapNotFound)
+			return
+		}
+		utils.LogError(r, "InvestmentRecap", err, "investment_id", id64)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgGenericError)})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: recap})
+}
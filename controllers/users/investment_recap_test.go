@@ -0,0 +1,99 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// Completing an investment stores a recap summed from its transactions and
+// an inbox notification; the recap is served afterwards unchanged by edits
+// to the product or the investment.
+func TestInvestmentCompletionRecap(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Rekap"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Rekap 1"})
+	due := time.Now().Add(-time.Minute)
+	inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: product.Amount, DailyProfit: product.DailyProfit, Duration: product.Duration,
+		NextReturnAt: &due, OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Running"}
+	if err := tx.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	runCron := func() {
+		if err := tx.Model(&models.Investment{}).Where("id = ?", inv.ID).Update("next_return_at", time.Now().Add(-time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v3/cron/daily-returns", nil)
+		req.Header.Set("X-CRON-KEY", "cron-test")
+		rec := httptest.NewRecorder()
+		h.CronDailyReturns(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cron: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	getRecap := func(uid uint) *httptest.ResponseRecorder {
+		id := strconv.FormatUint(uint64(inv.ID), 10)
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v3/users/investments/"+id+"/recap", nil), map[string]string{"id": id})
+		rec := httptest.NewRecorder()
+		h.Recap(rec, testutil.AsUser(req, uid))
+		return rec
+	}
+
+	runCron()
+	if rec := getRecap(user.ID); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "INVESTMENT_RECAP_NOT_FOUND") {
+		t.Fatalf("running investment: expected 404 INVESTMENT_RECAP_NOT_FOUND, got %d: %s", rec.Code, rec.Body.String())
+	}
+	runCron()
+
+	// Later edits must not rewrite the recap
+	if err := tx.Model(&product).Update("daily_profit", 9000).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Model(&models.Investment{}).Where("id = ?", inv.ID).Update("daily_profit", 9000).Error; err != nil {
+		t.Fatal(err)
+	}
+	rec := getRecap(user.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data models.InvestmentRecap `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	recap := resp.Data
+	if recap.Principal != 100000 || recap.Profit != 10000 || recap.TotalEarned != 10000 || recap.Duration != 2 || recap.ROIPercent != 10 {
+		t.Fatalf("unexpected recap %+v", recap)
+	}
+	// Ties out with the ledger: every return and boost row of the investment
+	var ledger int64
+	tx.Model(&models.Transaction{}).Where("investment_id = ? AND transaction_type IN ? AND status = ?", inv.ID, []string{"return", "profit_boost"}, "Success").
+		Select("COALESCE(SUM(amount), 0)").Scan(&ledger)
+	if ledger != recap.Principal+recap.TotalEarned {
+		t.Fatalf("recap %d + %d does not match ledger %d", recap.Principal, recap.TotalEarned, ledger)
+	}
+	if rec := getRecap(user.ID + 1); rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's recap: expected 404, got %d", rec.Code)
+	}
+	var inbox int64
+	tx.Model(&models.Notification{}).Where("user_id = ? AND type = ?", user.ID, "investment_completed").Count(&inbox)
+	if inbox != 1 {
+		t.Fatalf("expected one completion notification, got %d", inbox)
+	}
+}
@@ -0,0 +1,80 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/money"
+	"project/testutil"
+	"project/utils"
+)
+
+// The daily returns cron, run once per day over an investment's whole
+// duration, credits return rows whose profit adds up to exactly
+// DailyProfit*Duration on both schedules, plus the capital at completion.
+func TestDailyReturnsSumToProductTotal(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+
+	for _, profitType := range []string{"unlocked", "locked"} {
+		user := testutil.NewUser(t, tx, models.User{Name: "Returns"})
+		category := testutil.NewCategory(t, tx, profitType)
+		// An odd daily profit, where float balances used to drift
+		product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Returns", Amount: 250000, DailyProfit: 1333, Duration: 7})
+		next := time.Now().Add(-time.Minute)
+		inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: product.Amount, DailyProfit: product.DailyProfit, Duration: product.Duration,
+			NextReturnAt: &next, OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Running"}
+		if err := tx.Create(&inv).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		for day := 1; day <= product.Duration; day++ {
+			if err := tx.Model(&models.Investment{}).Where("id = ?", inv.ID).Update("next_return_at", time.Now().Add(-time.Minute)).Error; err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/v3/cron/daily-returns", nil)
+			req.Header.Set("X-CRON-KEY", "cron-test")
+			rec := httptest.NewRecorder()
+			h.CronDailyReturns(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: cron day %d: expected 200, got %d: %s", profitType, day, rec.Code, rec.Body.String())
+			}
+		}
+
+		var rows []models.Transaction
+		if err := tx.Where("investment_id = ? AND transaction_type = ? AND status = ?", inv.ID, "return", "Success").Order("id").Find(&rows).Error; err != nil {
+			t.Fatal(err)
+		}
+		var profit, capital int64
+		profitRows := 0
+		for _, row := range rows {
+			if strings.HasPrefix(utils.GetStringValue(row.Message), "Pengembalian modal") {
+				capital += row.Amount
+				continue
+			}
+			profit += row.Amount
+			profitRows++
+		}
+		wantRows := map[string]int{"unlocked": product.Duration, "locked": 1}[profitType]
+		if total := money.Total(product.DailyProfit, product.Duration); profit != total || profitRows != wantRows || capital != product.Amount {
+			t.Fatalf("%s: expected %d profit rows summing to %d and capital %d, got %d rows summing to %d and capital %d", profitType, wantRows, total, product.Amount, profitRows, profit, capital)
+		}
+
+		var got models.Investment
+		if err := tx.First(&got, inv.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		var u models.User
+		if err := tx.First(&u, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != "Completed" || got.TotalReturned != profit || u.Balance != profit+capital {
+			t.Fatalf("%s: expected Completed with %d returned and balance %d, got %s, %d and %d", profitType, profit, profit+capital, got.Status, got.TotalReturned, u.Balance)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/i18n"
+	"project/kyta"
+	"project/models"
+	"project/money"
+	"project/utils"
+	"project/vip"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errTopupNotRunning marks a top-up whose investment stopped running, or
+	// has no days left, before it could be applied.
+	errTopupNotRunning = errors.New("investment no longer running")
+)
+
+type TopupInvestmentRequest struct {
+	Amount         int64  `json:"amount" validate:"required,gt=0"`
+	PaymentMethod  string `json:"payment_method" validate:"required,oneof=BALANCE QRIS BANK"`
+	PaymentChannel string `json:"payment_channel" validate:"required_if=PaymentMethod BANK"`
+}
+
+// Normalize upper-cases the method and channel so "qris" and " bca " are accepted.
+func (req *TopupInvestmentRequest) Normalize() {
+	req.PaymentMethod = strings.ToUpper(strings.TrimSpace(req.PaymentMethod))
+	req.PaymentChannel = strings.ToUpper(strings.TrimSpace(req.PaymentChannel))
+}
+
+// TopupResponse is a top-up as shown to its owner.
+type TopupResponse struct {
+	ID                uint    `json:"id"`
+	InvestmentID      uint    `json:"investment_id"`
+	OrderID           string  `json:"order_id"`
+	Amount            int64   `json:"amount"`
+	Fee               int64   `json:"fee"`
+	GrossAmount       int64   `json:"gross_amount"`
+	PaymentMethod     string  `json:"payment_method"`
+	PaymentChannel    *string `json:"payment_channel,omitempty"`
+	PaymentCode       *string `json:"payment_code,omitempty"`
+	PaymentLink       *string `json:"payment_link,omitempty"`
+	DailyProfitBefore int64   `json:"daily_profit_before,omitempty"`
+	DailyProfitAfter  int64   `json:"daily_profit_after,omitempty"`
+	Status            string  `json:"status"`
+	ExpiredAt         *string `json:"expired_at,omitempty"`
+	CreatedAt         string  `json:"created_at"`
+}
+
+func newTopupResponse(t models.InvestmentTopup) TopupResponse {
+	resp := TopupResponse{
+		ID:                t.ID,
+		InvestmentID:      t.InvestmentID,
+		OrderID:           t.OrderID,
+		Amount:            t.Amount,
+		Fee:               t.Fee,
+		GrossAmount:       t.Amount + t.Fee,
+		PaymentMethod:     t.PaymentMethod,
+		PaymentChannel:    t.PaymentChannel,
+		DailyProfitBefore: t.DailyProfitBefore,
+		DailyProfitAfter:  t.DailyProfitAfter,
+		Status:            t.Status,
+		ExpiredAt:         utils.FormatTimePtr(t.ExpiredAt),
+		CreatedAt:         utils.FormatTime(t.CreatedAt),
+	}
+	// Payment instructions are only useful while the top-up can still be paid
+	if t.Status == "Pending" {
+		resp.PaymentCode = t.PaymentCode
+		resp.PaymentLink = t.PaymentLink
+	}
+	return resp
+}
+
+// POST /api/users/investments/{id}/topup
+// Adds principal to a Running investment instead of buying another slot.
+// BALANCE applies it at once; QRIS and BANK return payment instructions and
+// the webhook applies it once paid.
+func (h *InvestmentHandler) Topup(w http.ResponseWriter, r *http.Request) {
+	var req TopupInvestmentRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	id64, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id64 == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvalidID)})
+		return
+	}
+
+	method := req.PaymentMethod
+	channel := req.PaymentChannel
+	if method == "BANK" {
+		if !vaChannel(channel) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentBankInvalid), Code: utils.CodeBankUnavailable})
+			return
+		}
+	}
+
+	db := h.DB
+	var inv models.Investment
+	if err := db.Where("id = ? AND user_id = ?", uint(id64), uid).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeInvestmentNotFound)
+			return
+		}
+		utils.LogError(r, "TopupInvestmentHandler", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if code, args, err := topupBlocked(db, &inv, req.Amount, time.Now()); err != nil {
+		utils.LogError(r, "TopupInvestmentHandler", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	} else if code != "" {
+		status := http.StatusBadRequest
+		if code == utils.CodeTopupPending {
+			status = http.StatusConflict
+		}
+		utils.WriteError(w, r, status, code, args...)
+		return
+	}
+
+	if method == "BALANCE" {
+		h.topupFromBalance(w, r, &inv, req.Amount)
+		return
+	}
+
+	amount := req.Amount
+	option, err := paymentOption(db, method, channel, amount)
+	if err != nil {
+		utils.LogError(r, "TopupInvestmentHandler: payment channel", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if !option.Available {
+		writePaymentUnavailable(w, r, option)
+		return
+	}
+	fee, gross := option.Fee, option.GrossAmount
+
+	orderID := utils.GenerateOrderID(utils.OrderTopup, uid)
+	var payResp *kyta.PaymentResponse
+	if method == "QRIS" {
+		payResp, err = h.Kyta.CreateQRIS(r.Context(), kyta.PaymentRequest{ReferenceID: orderID, Amount: gross})
+	} else {
+		payResp, err = h.Kyta.CreateVA(r.Context(), kyta.PaymentRequest{ReferenceID: orderID, Amount: gross, BankCode: channel})
+	}
+	if errors.Is(err, kyta.ErrNotConfigured) {
+		utils.LogError(r, "TopupInvestmentHandler: kytapay", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "TopupInvestmentHandler: kytapay create payment", err)
+		utils.WriteError(w, r, http.StatusBadGateway, utils.CodePaymentGatewayError)
+		return
+	}
+	if payResp == nil {
+		utils.WriteJSON(w, http.StatusBadGateway, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgPaymentGatewayNoResponse), Code: utils.CodePaymentGatewayError})
+		return
+	}
+
+	code, link, expiredAt := gatewayPaymentDetails(method, payResp)
+	topup := models.InvestmentTopup{
+		InvestmentID:  inv.ID,
+		UserID:        uid,
+		Amount:        amount,
+		Fee:           fee,
+		OrderID:       orderID,
+		PaymentMethod: method,
+		PaymentCode:   code,
+		PaymentLink:   link,
+		ExpiredAt:     expiredAt,
+		Status:        "Pending",
+		ReferenceID:   &orderID,
+	}
+	if method == "BANK" {
+		topup.PaymentChannel = &channel
+	}
+	topup.GatewayPaymentID = payResp.PaymentID()
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&topup).Error; err != nil {
+			return err
+		}
+		return tx.Create(topupTransaction(&inv, &topup, "Pending")).Error
+	}); err != nil {
+		utils.LogError(r, "TopupInvestmentHandler: save top-up", err, "order_id", orderID, "gateway_payment_id", utils.GetStringValue(topup.GatewayPaymentID))
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentTopupFailed)})
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgInvestmentTopupCreated), Data: newTopupResponse(topup)})
+}
+
+// topupFromBalance pays a top-up of inv from the user's balance and applies
+// it in one transaction.
+func (h *InvestmentHandler) topupFromBalance(w http.ResponseWriter, r *http.Request, inv *models.Investment, amount int64) {
+	topup := models.InvestmentTopup{
+		InvestmentID: inv.ID,
+		UserID:       inv.UserID,
+		Amount:       amount,
+		// Not TUP-: that prefix marks top-ups paid through the gateway,
+		// which the webhook settles and the balance audit leaves out
+		OrderID:       utils.GenerateOrderID(utils.OrderInvestment, inv.UserID),
+		PaymentMethod: "BALANCE",
+		Status:        "Pending",
+	}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.DebitBalance(tx, inv.UserID, amount); err != nil {
+			return err
+		}
+		if err := tx.Create(&topup).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(topupTransaction(inv, &topup, "Pending")).Error; err != nil {
+			return err
+		}
+		return applyTopup(tx, &topup)
+	})
+	switch {
+	case errors.Is(err, models.ErrInsufficientBalance):
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInsufficientBalance)
+	case errors.Is(err, errTopupNotRunning):
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeTopupUnavailable)
+	case err != nil:
+		utils.LogError(r, "TopupInvestmentHandler: balance top-up", err, "investment_id", inv.ID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentTopupFailed)})
+	default:
+		utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgInvestmentTopupApplied), Data: newTopupResponse(topup)})
+	}
+}
+
+// topupBlocked reports why inv may not take a top-up of amount now, with the
+// message arguments for the code. An empty code means it may.
+func topupBlocked(db *gorm.DB, inv *models.Investment, amount int64, now time.Time) (utils.ErrorCode, []interface{}, error) {
+	if inv.Status != "Running" || inv.TotalPaid >= inv.Duration {
+		return utils.CodeTopupUnavailable, nil, nil
+	}
+	var product models.Product
+	if err := db.Select("id, status, topup_min, topup_max").First(&product, inv.ProductID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.CodeTopupUnavailable, nil, nil
+		}
+		return "", nil, err
+	}
+	if product.Status != "Active" || product.TopupMax <= 0 {
+		return utils.CodeTopupUnavailable, nil, nil
+	}
+	if amount < product.TopupMin || amount > product.TopupMax {
+		return utils.CodeTopupAmountRange, []interface{}{product.TopupMin, product.TopupMax}, nil
+	}
+
+	// One gateway top-up at a time, until it is paid or lapses
+	var pending int64
+	if err := db.Model(&models.InvestmentTopup{}).
+		Where("investment_id = ? AND status = ? AND (expired_at IS NULL OR expired_at > ?)", inv.ID, "Pending", now).
+		Count(&pending).Error; err != nil {
+		return "", nil, err
+	}
+	if pending > 0 {
+		return utils.CodeTopupPending, nil, nil
+	}
+	return "", nil, nil
+}
+
+// topupTransaction is the transaction documenting topup. Gateway top-ups
+// carry the TUP- order id, which the balance audit leaves out of the ledger.
+func topupTransaction(inv *models.Investment, topup *models.InvestmentTopup, status string) *models.Transaction {
+	msg := fmt.Sprintf("Tambah modal investasi %s", inv.ProductName)
+	return &models.Transaction{
+		UserID:          topup.UserID,
+		InvestmentID:    &inv.ID,
+		Amount:          topup.Amount,
+		Charge:          topup.Fee,
+		OrderID:         topup.OrderID,
+		TransactionFlow: "credit",
+		TransactionType: "investment_topup",
+		Message:         &msg,
+		Status:          status,
+	}
+}
+
+// applyTopup adds a paid topup to its investment: the principal grows and
+// DailyProfit is rescaled at the rate the investment was bought at, so
+// product edits made since do not change it. Days paid and the profit already
+// accrued (TotalReturned) are kept; the daily returns cron pays the new rate
+// from the next return and the new principal at completion. The investor's
+// totals and VIP level follow. Lock the user row before calling; it returns
+// errTopupNotRunning when the investment can no longer take it.
+func applyTopup(tx *gorm.DB, topup *models.InvestmentTopup) error {
+	var inv models.Investment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, topup.InvestmentID).Error; err != nil {
+		return err
+	}
+	if inv.Status != "Running" || inv.TotalPaid >= inv.Duration {
+		return errTopupNotRunning
+	}
+
+	rateAmount, rateDaily := inv.RateAmount, inv.RateDailyProfit
+	if rateAmount == 0 {
+		rateAmount, rateDaily = inv.Amount, inv.DailyProfit
+	}
+	amount := inv.Amount + topup.Amount
+	daily := money.Scale(rateDaily, amount, rateAmount)
+	if err := tx.Model(&inv).Updates(map[string]interface{}{
+		"amount":            amount,
+		"daily_profit":      daily,
+		"rate_amount":       rateAmount,
+		"rate_daily_profit": rateDaily,
+	}).Error; err != nil {
+		return err
+	}
+
+	topup.Status = "Success"
+	topup.DailyProfitBefore = inv.DailyProfit
+	topup.DailyProfitAfter = daily
+	if err := tx.Model(topup).Updates(map[string]interface{}{
+		"status":              topup.Status,
+		"daily_profit_before": topup.DailyProfitBefore,
+		"daily_profit_after":  topup.DailyProfitAfter,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Transaction{}).Where("order_id = ?", topup.OrderID).Update("status", "Success").Error; err != nil {
+		return err
+	}
+
+	// Totals as on activation; the VIP level only follows locked categories
+	var category models.Category
+	if err := tx.Select("id, profit_type").First(&category, inv.CategoryID).Error; err != nil {
+		return err
+	}
+	userUpdates := map[string]interface{}{"total_invest": gorm.Expr("total_invest + ?", topup.Amount)}
+	if category.ProfitType == "locked" {
+		userUpdates["total_invest_vip"] = gorm.Expr("total_invest_vip + ?", topup.Amount)
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Updates(userUpdates).Error; err != nil {
+		return err
+	}
+	if category.ProfitType == "locked" {
+		if _, err := vip.Recalculate(tx, inv.UserID, nil, vip.SourcePayment, vip.Policy()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settleTopup applies a gateway callback to the top-up with orderID, like
+// settleDeposit. A paid top-up whose investment stopped running meanwhile is
+// credited to the balance instead and marked Failed (refunded true). The
+// top-up is returned so the caller can notify its owner after the commit.
+func settleTopup(db *gorm.DB, orderID, paymentID string, success bool) (topup models.InvestmentTopup, ignored, refunded bool, err error) {
+	err = utils.WithTxOptions(db.Statement.Context, db, paymentTxOptions, func(tx *gorm.DB) error {
+		topup, ignored, refunded = models.InvestmentTopup{}, false, false
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&topup).Error; err != nil {
+			return err
+		}
+		if topup.Status != "Pending" {
+			ignored = true
+			return nil
+		}
+		if paymentID != "" {
+			if err := tx.Model(&topup).Update("gateway_payment_id", paymentID).Error; err != nil {
+				return err
+			}
+		}
+		if !success {
+			return failTopup(tx, &topup)
+		}
+
+		// Same lock order as the daily returns cron: user, then investment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, topup.UserID).Error; err != nil {
+			return err
+		}
+		// applyTopup refuses before writing anything
+		err := applyTopup(tx, &topup)
+		if !errors.Is(err, errTopupNotRunning) {
+			return err
+		}
+		refunded = true
+		if err := failTopup(tx, &topup); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", topup.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", topup.Amount)).Error; err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("Pengembalian tambah modal %s", topup.OrderID)
+		return tx.Create(&models.Transaction{
+			UserID:          topup.UserID,
+			InvestmentID:    &topup.InvestmentID,
+			Amount:          topup.Amount,
+			OrderID:         utils.GenerateOrderID(utils.OrderRefund, topup.UserID),
+			TransactionFlow: "debit",
+			TransactionType: "refund",
+			Message:         &msg,
+			Status:          "Success",
+		}).Error
+	})
+	return topup, ignored, refunded, err
+}
+
+// failTopup marks topup and its transaction Failed.
+func failTopup(tx *gorm.DB, topup *models.InvestmentTopup) error {
+	topup.Status = "Failed"
+	if err := tx.Model(topup).Update("status", topup.Status).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Transaction{}).Where("order_id = ?", topup.OrderID).Update("status", "Failed").Error
+}
@@ -0,0 +1,121 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestInvestmentTopup(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Topup", Balance: 60000})
+	category := testutil.NewCategory(t, tx, "locked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Monitor 1", Duration: 3, TopupMin: 10000, TopupMax: 200000})
+	next := time.Now().Add(-time.Minute)
+	inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: 100000, DailyProfit: 5000, Duration: 3,
+		TotalPaid: 1, TotalReturned: 5000, NextReturnAt: &next, OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Running"}
+	if err := tx.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	// A later product edit must not change the rate of this investment
+	if err := tx.Model(&product).Update("daily_profit", 9000).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	topup := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/v3/users/investments/%d/topup", inv.ID), strings.NewReader(body))
+		h.Topup(rec, testutil.AsUser(mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(inv.ID)}), user.ID))
+		return rec
+	}
+	code := func(rec *httptest.ResponseRecorder) utils.ErrorCode {
+		var resp utils.APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Code
+	}
+
+	if rec := topup(`{"amount":300000,"payment_method":"BALANCE"}`); rec.Code != http.StatusBadRequest || code(rec) != utils.CodeTopupAmountRange {
+		t.Fatalf("over topup_max: expected 400 %s, got %d: %s", utils.CodeTopupAmountRange, rec.Code, rec.Body.String())
+	}
+
+	// From the balance: applied at once at the rate bought
+	if rec := topup(`{"amount":50000,"payment_method":"BALANCE"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("balance top-up: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := tx.First(&inv, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Amount != 150000 || inv.DailyProfit != 7500 || inv.TotalReturned != 5000 || inv.TotalPaid != 1 {
+		t.Fatalf("unexpected investment after balance top-up: %+v", inv)
+	}
+
+	// Through the gateway: one at a time, applied by the webhook
+	rec := topup(`{"amount":20000,"payment_method":"QRIS"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("gateway top-up: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data TopupResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if rec := topup(`{"amount":20000,"payment_method":"QRIS"}`); rec.Code != http.StatusConflict || code(rec) != utils.CodeTopupPending {
+		t.Fatalf("second gateway top-up: expected 409 %s, got %d: %s", utils.CodeTopupPending, rec.Code, rec.Body.String())
+	}
+	webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-t","reference_id":%q,"amount":%d,"status":"SUCCESS"}}`, created.Data.OrderID, created.Data.GrossAmount)
+	rec = httptest.NewRecorder()
+	h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := tx.First(&inv, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Amount != 170000 || inv.DailyProfit != 8500 {
+		t.Fatalf("unexpected investment after gateway top-up: %+v", inv)
+	}
+
+	// The remaining days pay the new rate; completion pays the profit accrued
+	// before and after the top-ups and returns the new principal
+	for day := 2; day <= inv.Duration; day++ {
+		if err := tx.Model(&models.Investment{}).Where("id = ?", inv.ID).Update("next_return_at", time.Now().Add(-time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v3/cron/daily-returns", nil)
+		req.Header.Set("X-CRON-KEY", "cron-test")
+		rec = httptest.NewRecorder()
+		h.CronDailyReturns(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cron day %d: expected 200, got %d: %s", day, rec.Code, rec.Body.String())
+		}
+	}
+	if err := tx.First(&inv, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Completed" || inv.TotalReturned != 22000 {
+		t.Fatalf("unexpected investment after returns: %+v", inv)
+	}
+	var investor models.User
+	if err := tx.First(&investor, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(10000 + 22000 + 170000); investor.Balance != want {
+		t.Fatalf("expected balance %d, got %d", want, investor.Balance)
+	}
+}
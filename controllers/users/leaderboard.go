@@ -0,0 +1,139 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"project/database"
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	leaderboardDefaultTop = 10
+	leaderboardMaxTop     = 100
+)
+
+// LeaderboardEntry is one ranked referrer. Names of other users are masked.
+type LeaderboardEntry struct {
+	Rank       int    `json:"rank"`
+	Name       string `json:"name"`
+	TeamVolume int64  `json:"team_volume"`
+	Prize      int64  `json:"prize"`
+}
+
+// LeaderboardResponse is the top of a period's ranking plus the caller's own
+// entry, which is nil when their team has no volume in the period.
+type LeaderboardResponse struct {
+	Period     string             `json:"period"`
+	Status     string             `json:"status"`
+	SnapshotAt *time.Time         `json:"snapshot_at"`
+	Top        []LeaderboardEntry `json:"top"`
+	Me         *LeaderboardEntry  `json:"me"`
+}
+
+// GET /api/users/leaderboard?period=YYYY-MM&limit=
+// Serves the last stored snapshot of the period (default: the current
+// month). Before the first snapshot the ranking is empty.
+func LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = time.Now().In(utils.AppLocation()).Format("2006-01")
+	}
+	if _, _, err := models.LeaderboardPeriodRange(period, utils.AppLocation()); err != nil {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeBadRequest)
+		return
+	}
+	limit := leaderboardDefaultTop
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > leaderboardMaxTop {
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	resp, err := leaderboardView(database.DB, uid, period, limit)
+	if err != nil {
+		utils.LogError(r, "LeaderboardHandler", err, "period", period)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: resp})
+}
+
+func leaderboardView(db *gorm.DB, uid uint, period string, limit int) (*LeaderboardResponse, error) {
+	resp := &LeaderboardResponse{Period: period, Top: []LeaderboardEntry{}}
+	var p models.LeaderboardPeriod
+	if err := db.Where("period = ?", period).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return resp, nil
+		}
+		return nil, err
+	}
+	resp.Status = p.Status
+	resp.SnapshotAt = &p.SnapshotAt
+
+	type row struct {
+		Rank       int
+		UserID     uint
+		Name       string
+		TeamVolume int64
+		Prize      int64
+	}
+	var top []row
+	if err := db.Table("leaderboard_snapshots AS s").
+		Select("s.`rank`, s.user_id, users.name, s.team_volume, s.prize").
+		Joins("JOIN users ON users.id = s.user_id").
+		Where("s.period = ?", period).
+		Order("s.`rank` ASC").Limit(limit).
+		Scan(&top).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range top {
+		name := maskName(t.Name)
+		if t.UserID == uid {
+			name = t.Name
+		}
+		resp.Top = append(resp.Top, LeaderboardEntry{Rank: t.Rank, Name: name, TeamVolume: t.TeamVolume, Prize: t.Prize})
+	}
+
+	var mine []row
+	if err := db.Table("leaderboard_snapshots AS s").
+		Select("s.`rank`, s.user_id, users.name, s.team_volume, s.prize").
+		Joins("JOIN users ON users.id = s.user_id").
+		Where("s.period = ? AND s.user_id = ?", period, uid).
+		Limit(1).
+		Scan(&mine).Error; err != nil {
+		return nil, err
+	}
+	if len(mine) > 0 {
+		m := mine[0]
+		resp.Me = &LeaderboardEntry{Rank: m.Rank, Name: m.Name, TeamVolume: m.TeamVolume, Prize: m.Prize}
+	}
+	return resp, nil
+}
+
+// maskName keeps the first letter of each word: "Budi Santoso" -> "B*** S******".
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		first, size := utf8.DecodeRuneInString(w)
+		words[i] = string(first) + strings.Repeat("*", utf8.RuneCountInString(w[size:]))
+	}
+	return strings.Join(words, " ")
+}
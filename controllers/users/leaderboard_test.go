@@ -0,0 +1,93 @@
+package users
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+func TestMaskName(t *testing.T) {
+	cases := map[string]string{
+		"Budi Santoso": "B*** S******",
+		"Ani":          "A**",
+		"  Élan  ":     "É***",
+		"":             "",
+	}
+	for in, want := range cases {
+		if got := maskName(in); got != want {
+			t.Errorf("maskName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLeaderboardSnapshot(t *testing.T) {
+	tx := testutil.Tx(t)
+	suffix := time.Now().UnixNano() % 1000000000
+	newUser := func(prefix string, reffBy *uint) models.User {
+		u := testutil.NewUser(t, tx, models.User{Name: "Budi " + prefix, ReffBy: reffBy})
+		return u
+	}
+	// top <- mid <- leaf
+	top := newUser("88", nil)
+	mid := newUser("89", &top.ID)
+	leaf := newUser("90", &mid.ID)
+
+	loc := time.UTC
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 1, 0)
+	invest := func(uid uint, amount int64, status string, at time.Time) {
+		trx := models.Transaction{UserID: uid, Amount: amount, OrderID: fmt.Sprintf("LB%d-%d-%d", suffix, uid, at.UnixNano()), TransactionFlow: "credit", TransactionType: "investment", Status: status, CreatedAt: at, UpdatedAt: at}
+		if err := tx.Create(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	invest(leaf.ID, 100000, "Success", start.Add(time.Hour))
+	invest(mid.ID, 50000, "Success", start.Add(2*time.Hour))
+	invest(leaf.ID, 70000, "Pending", start.Add(3*time.Hour))
+	invest(leaf.ID, 90000, "Success", end.Add(time.Hour))
+
+	volumes := func(scope string) map[uint]models.LeaderboardSnapshot {
+		snapshot, err := models.BuildLeaderboardSnapshot(tx, "2024-05", start, end, scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[uint]models.LeaderboardSnapshot{}
+		for _, s := range snapshot {
+			got[s.UserID] = s
+		}
+		return got
+	}
+
+	level1 := volumes(models.LeaderboardScopeLevel1)
+	if level1[mid.ID].TeamVolume != 100000 || level1[top.ID].TeamVolume != 50000 {
+		t.Fatalf("level1 scope: unexpected volumes %+v", level1)
+	}
+	tree := volumes(models.LeaderboardScopeTree)
+	if tree[top.ID].TeamVolume != 150000 || tree[top.ID].Rank != 1 || tree[mid.ID].Rank != 2 {
+		t.Fatalf("tree scope: unexpected ranking %+v", tree)
+	}
+	if _, ok := tree[leaf.ID]; ok {
+		t.Fatalf("a user without a team should not be ranked: %+v", tree)
+	}
+
+	snapshot, err := models.BuildLeaderboardSnapshot(tx, "2024-05", start, end, models.LeaderboardScopeTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.SaveLeaderboardSnapshot(tx, &models.LeaderboardPeriod{Period: "2024-05", Scope: models.LeaderboardScopeTree, Status: "Open"}, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	view, err := leaderboardView(tx, mid.ID, "2024-05", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(view.Top) != 1 || view.Top[0].Name != maskName(top.Name) {
+		t.Fatalf("expected only the masked leader, got %+v", view.Top)
+	}
+	if view.Me == nil || view.Me.Rank != 2 || view.Me.TeamVolume != 100000 {
+		t.Fatalf("unexpected own entry %+v", view.Me)
+	}
+}
@@ -0,0 +1,482 @@
+package users
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/i18n"
+	"project/middleware"
+	"project/models"
+	"project/notify"
+	"project/outbox"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultManualPaymentMinutes is how long a MANUAL payment waits for its
+	// transfer when MANUAL_PAYMENT_EXPIRY_MINUTES is not set.
+	defaultManualPaymentMinutes = 180
+	// manualProofMaxBytes bounds transfer proof screenshots.
+	manualProofMaxBytes = 2 << 20
+	// manualProofURLExpiry is how long presigned proof URLs stay valid.
+	manualProofURLExpiry = 60 * 60
+	// transferCodeAlphabet leaves out characters that read alike (0/O, 1/I).
+	transferCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	transferCodeLength   = 6
+	transferCodeAttempts = 5
+)
+
+// errManualPaymentUnavailable means MANUAL is switched off or has no
+// receiving account.
+var errManualPaymentUnavailable = errors.New("manual payment unavailable")
+
+// errPaymentClosed means a manual payment is no longer open for a proof or a
+// review.
+var errPaymentClosed = errors.New("payment closed")
+
+// ManualTransfer tells the buyer where to send a MANUAL payment and what to
+// quote in the transfer description.
+type ManualTransfer struct {
+	BankName        string  `json:"bank_name"`
+	AccountNumber   string  `json:"account_number"`
+	AccountName     string  `json:"account_name"`
+	TransferCode    string  `json:"transfer_code"`
+	ExpiredAt       string  `json:"expired_at"`
+	ProofUploadedAt *string `json:"proof_uploaded_at,omitempty"`
+	ReviewNote      *string `json:"review_note,omitempty"`
+
+	expiresAt time.Time
+}
+
+// manualPaymentExpiry reads MANUAL_PAYMENT_EXPIRY_MINUTES.
+func manualPaymentExpiry() time.Duration {
+	minutes := defaultManualPaymentMinutes
+	if v, err := strconv.Atoi(os.Getenv("MANUAL_PAYMENT_EXPIRY_MINUTES")); err == nil && v > 0 {
+		minutes = v
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// newManualTransfer prepares a MANUAL payment made at now with the receiving
+// account from the settings. Its transfer code is claimed inside the purchase
+// transaction by claimTransferCode.
+func newManualTransfer(db *gorm.DB, now time.Time) (*ManualTransfer, error) {
+	setting, err := models.GetCachedSetting(db)
+	if err != nil {
+		return nil, err
+	}
+	if !setting.ManualPaymentAvailable() {
+		return nil, errManualPaymentUnavailable
+	}
+	expiresAt := now.UTC().Add(manualPaymentExpiry())
+	return &ManualTransfer{
+		BankName:      setting.ManualBankName,
+		AccountNumber: setting.ManualAccountNumber,
+		AccountName:   setting.ManualAccountName,
+		ExpiredAt:     utils.FormatTime(expiresAt),
+		expiresAt:     expiresAt,
+	}, nil
+}
+
+// claimTransferCode gives mt a code no other Pending manual payment quotes.
+// It must run first in the transaction that creates the payment: the lock on
+// the settings row serializes manual purchases, so the lookup that follows
+// sees the payment of every purchase that held it before.
+func claimTransferCode(tx *gorm.DB, mt *ManualTransfer) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Setting{}).Error; err != nil {
+		return err
+	}
+	code, err := uniqueTransferCode(tx)
+	if err != nil {
+		return err
+	}
+	mt.TransferCode = code
+	return nil
+}
+
+// uniqueTransferCode draws codes until one is not quoted by a Pending manual
+// payment.
+func uniqueTransferCode(db *gorm.DB) (string, error) {
+	for i := 0; i < transferCodeAttempts; i++ {
+		buf := make([]byte, transferCodeLength)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for j := range buf {
+			buf[j] = transferCodeAlphabet[int(buf[j])%len(transferCodeAlphabet)]
+		}
+		var taken int64
+		if err := db.Model(&models.Payment{}).Where("payment_method = ? AND status = ? AND payment_code = ?", "MANUAL", "Pending", string(buf)).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return string(buf), nil
+		}
+	}
+	return "", errors.New("no free transfer code")
+}
+
+// manualTransferDetails describes a MANUAL payment with the receiving account
+// as currently configured. A settings error is logged and leaves the account
+// empty.
+func manualTransferDetails(r *http.Request, db *gorm.DB, p *models.Payment) *ManualTransfer {
+	mt := &ManualTransfer{
+		TransferCode:    utils.GetStringValue(p.PaymentCode),
+		ProofUploadedAt: utils.FormatTimePtr(p.ProofUploadedAt),
+		ReviewNote:      p.ReviewNote,
+	}
+	if p.ExpiredAt != nil {
+		mt.ExpiredAt = utils.FormatTime(*p.ExpiredAt)
+	}
+	setting, err := models.GetCachedSetting(db)
+	if err != nil {
+		utils.LogError(r, "manualTransferDetails: settings", err)
+		return mt
+	}
+	mt.BankName, mt.AccountNumber, mt.AccountName = setting.ManualBankName, setting.ManualAccountNumber, setting.ManualAccountName
+	return mt
+}
+
+// POST /api/users/payments/{order_id}/proof
+// Takes the transfer proof of the caller's MANUAL payment as a multipart
+// "image". A new proof replaces the previous one until an admin reviews it;
+// none is taken after the payment expired.
+func (h *InvestmentHandler) UploadPaymentProof(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	orderID := strings.TrimSpace(mux.Vars(r)["order_id"])
+
+	db := h.DB
+	var payment models.Payment
+	if err := db.Joins("JOIN investments ON investments.id = payments.investment_id").
+		Where("payments.order_id = ? AND investments.user_id = ?", orderID, uid).
+		First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodePaymentNotFound)
+			return
+		}
+		utils.LogError(r, "UploadPaymentProof", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	now := time.Now()
+	if !manualPaymentOpen(&payment) || (payment.ExpiredAt != nil && !now.Before(*payment.ExpiredAt)) {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodePaymentClosed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(manualProofMaxBytes); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gagal membaca gambar", Code: utils.CodeInvalidImage})
+		return
+	}
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bukti transfer wajib diunggah", Code: utils.CodeInvalidImage})
+		return
+	}
+	defer file.Close()
+	imageBytes, ext, err := utils.SanitizeImage(file, header.Filename, header.Size, manualProofMaxBytes)
+	var imgErr *utils.ImageError
+	if errors.As(err, &imgErr) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: imgErr.Message, Code: utils.CodeInvalidImage})
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "UploadPaymentProof", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses gambar"})
+		return
+	}
+	objectName := "payment-proofs/" + strconv.FormatUint(uint64(uid), 10) + "_" + strconv.FormatInt(now.UnixNano(), 10) + ext
+	if err := utils.UploadToS3(objectName, bytes.NewReader(imageBytes), int64(len(imageBytes))); err != nil {
+		utils.LogError(r, "UploadPaymentProof", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengunggah gambar"})
+		return
+	}
+
+	// Claimed only while still open, so a proof never lands on a payment an
+	// admin reviewed in the meantime
+	res := db.Model(&models.Payment{}).
+		Where("id = ? AND status = ? AND reviewed_at IS NULL", payment.ID, "Pending").
+		Updates(map[string]interface{}{"proof_image": objectName, "proof_uploaded_at": now})
+	if res.Error != nil {
+		utils.LogError(r, "UploadPaymentProof", res.Error, "order_id", payment.OrderID)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodePaymentClosed)
+		return
+	}
+	payment.ProofImage, payment.ProofUploadedAt = &objectName, &now
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgPaymentProofUploaded), Data: map[string]interface{}{
+		"order_id":        payment.OrderID,
+		"status":          payment.Status,
+		"manual_transfer": manualTransferDetails(r, db, &payment),
+	}})
+}
+
+// manualPaymentOpen reports whether p is a MANUAL payment still waiting for
+// its transfer to be reviewed.
+func manualPaymentOpen(p *models.Payment) bool {
+	return p.PaymentMethod != nil && *p.PaymentMethod == "MANUAL" && p.Status == "Pending" && p.ReviewedAt == nil
+}
+
+// ManualPaymentResponse is one MANUAL payment in the admin review queue.
+type ManualPaymentResponse struct {
+	ID              uint    `json:"id"`
+	OrderID         string  `json:"order_id"`
+	UserID          uint    `json:"user_id"`
+	UserName        string  `json:"user_name"`
+	UserNumber      string  `json:"user_number"`
+	ProductName     string  `json:"product_name"`
+	Amount          int64   `json:"amount"`
+	GrossAmount     int64   `json:"gross_amount"`
+	TransferCode    string  `json:"transfer_code"`
+	Status          string  `json:"status"`
+	ProofURL        *string `json:"proof_url"`
+	ProofUploadedAt *string `json:"proof_uploaded_at"`
+	ExpiredAt       *string `json:"expired_at"`
+	ReviewedBy      *uint   `json:"reviewed_by,omitempty"`
+	ReviewedAt      *string `json:"reviewed_at,omitempty"`
+	ReviewNote      *string `json:"review_note,omitempty"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+// GET /api/admin/payments/manual
+// The review queue: MANUAL payments with a proof awaiting review, oldest proof
+// first. ?status=reviewed lists the reviewed ones instead, newest first, and
+// ?status=awaiting_proof the open ones still without a proof.
+// Admin-only despite living here, like the review endpoints below.
+func (h *InvestmentHandler) AdminManualPayments(w http.ResponseWriter, r *http.Request) {
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	query := h.DB.Model(&models.Payment{}).Where("payments.payment_method = ?", "MANUAL")
+	switch r.URL.Query().Get("status") {
+	case "", "pending":
+		query = query.Where("payments.reviewed_at IS NULL AND payments.proof_uploaded_at IS NOT NULL AND payments.status = ?", "Pending").
+			Order("payments.proof_uploaded_at ASC")
+	case "awaiting_proof":
+		query = query.Where("payments.reviewed_at IS NULL AND payments.proof_uploaded_at IS NULL AND payments.status = ?", "Pending").
+			Order("payments.created_at DESC")
+	case "reviewed":
+		query = query.Where("payments.reviewed_at IS NOT NULL").Order("payments.reviewed_at DESC")
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Status harus pending, awaiting_proof atau reviewed"})
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		utils.LogError(r, "AdminManualPayments", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	var rows []struct {
+		models.Payment
+		UserID           uint
+		UserName         string
+		UserNumber       string
+		ProductName      string
+		InvestmentAmount int64
+	}
+	if err := query.Select("payments.*, investments.user_id, users.name AS user_name, users.number AS user_number, investments.product_name, investments.amount AS investment_amount").
+		Joins("JOIN investments ON investments.id = payments.investment_id").
+		Joins("JOIN users ON users.id = investments.user_id").
+		Offset(pg.Offset).Limit(pg.Limit).
+		Scan(&rows).Error; err != nil {
+		utils.LogError(r, "AdminManualPayments", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	items := make([]ManualPaymentResponse, 0, len(rows))
+	for _, row := range rows {
+		item := ManualPaymentResponse{
+			ID:              row.ID,
+			OrderID:         row.OrderID,
+			UserID:          row.UserID,
+			UserName:        row.UserName,
+			UserNumber:      row.UserNumber,
+			ProductName:     row.ProductName,
+			Amount:          row.InvestmentAmount,
+			GrossAmount:     row.Payment.Gross(row.InvestmentAmount),
+			TransferCode:    utils.GetStringValue(row.PaymentCode),
+			Status:          row.Status,
+			ProofUploadedAt: utils.FormatTimePtr(row.ProofUploadedAt),
+			ExpiredAt:       utils.FormatTimePtr(row.ExpiredAt),
+			ReviewedBy:      row.ReviewedBy,
+			ReviewedAt:      utils.FormatTimePtr(row.ReviewedAt),
+			ReviewNote:      row.ReviewNote,
+			CreatedAt:       utils.FormatTime(row.CreatedAt),
+		}
+		if row.ProofImage != nil {
+			if url, err := utils.ObjectURL(*row.ProofImage, manualProofURLExpiry); err != nil {
+				utils.LogError(r, "AdminManualPayments: proof url", err, "payment_id", row.ID)
+			} else {
+				item.ProofURL = &url
+			}
+		}
+		items = append(items, item)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: utils.NewPaginated(items, pg, total)})
+}
+
+// ManualReviewRequest is an admin's decision on a MANUAL payment.
+// AmountReceived defaults to the gross; less leaves the payment Partial for
+// the partial refund cron, like a short gateway payment. A rejection needs a
+// note, which is shown to the buyer.
+type ManualReviewRequest struct {
+	AmountReceived *int64 `json:"amount_received" validate:"omitempty,gt=0"`
+	Note           string `json:"note" validate:"max=255"`
+}
+
+// POST /api/admin/payments/{id}/approve
+func (h *InvestmentHandler) AdminApproveManualPayment(w http.ResponseWriter, r *http.Request) {
+	h.reviewManualPayment(w, r, true)
+}
+
+// POST /api/admin/payments/{id}/reject
+func (h *InvestmentHandler) AdminRejectManualPayment(w http.ResponseWriter, r *http.Request) {
+	h.reviewManualPayment(w, r, false)
+}
+
+// reviewManualPayment settles a MANUAL payment through the same path as a
+// gateway callback: an approval as a successful payment of the amount
+// received, a rejection as a failed one. A payment that expired meanwhile can
+// still be approved, as a late gateway confirmation would be.
+func (h *InvestmentHandler) reviewManualPayment(w http.ResponseWriter, r *http.Request, approve bool) {
+	adminID, ok := utils.GetAdminID(r)
+	if !ok {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req ManualReviewRequest
+	if !utils.DecodeAndValidate(w, r, &req) {
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if !approve && note == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Alasan penolakan wajib diisi"})
+		return
+	}
+
+	db := h.DB
+	var payment models.Payment
+	if err := db.First(&payment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodePaymentNotFound)
+			return
+		}
+		utils.LogError(r, "reviewManualPayment", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if !manualPaymentOpen(&payment) {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodePaymentClosed)
+		return
+	}
+
+	action := "payment.manual_reject"
+	if approve {
+		action = "payment.manual_approve"
+	}
+	reviewer := uint(adminID)
+	var events outbox.Batch
+	var refunded bool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		updates := map[string]interface{}{"reviewed_by": reviewer, "reviewed_at": now, "review_note": nil}
+		if note != "" {
+			updates["review_note"] = note
+		}
+		// The claim makes a second review of the same payment a conflict
+		res := tx.Model(&models.Payment{}).Where("id = ? AND status = ? AND reviewed_at IS NULL", payment.ID, "Pending").Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errPaymentClosed
+		}
+
+		var priced models.Investment
+		if err := tx.Select("amount").First(&priced, payment.InvestmentID).Error; err != nil {
+			return err
+		}
+		received := int64(0)
+		if approve {
+			received = payment.Gross(priced.Amount)
+			if req.AmountReceived != nil {
+				received = *req.AmountReceived
+			}
+		}
+		inv, ignored, wasRefunded, err := settleInvestmentPayment(tx, &payment, approve, received, "", "", &events)
+		if err != nil {
+			return err
+		}
+		if ignored {
+			return errPaymentClosed
+		}
+		refunded = wasRefunded
+		if !approve {
+			if err := events.Push(tx, "payment_rejected:"+inv.OrderID, notify.PaymentRejected(inv.UserID, inv.OrderID, note)); err != nil {
+				return err
+			}
+		}
+
+		after, _ := json.Marshal(map[string]interface{}{"order_id": payment.OrderID, "status": payment.Status, "amount_received": received, "note": note})
+		snapshot := string(after)
+		return tx.Create(&models.AdminAuditLog{
+			AdminID: reviewer, Action: action, TargetType: "payment", TargetID: &payment.ID,
+			After: &snapshot, IP: middleware.ClientIP(r),
+		}).Error
+	})
+	if errors.Is(err, errPaymentClosed) {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodePaymentClosed)
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "reviewManualPayment", err, "payment_id", payment.ID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses pembayaran"})
+		return
+	}
+	h.Outbox.Dispatch(events)
+	paymentEvents.Publish(payment.OrderID)
+
+	message := "Pembayaran ditolak"
+	switch {
+	case refunded:
+		message = "Batas pembelian sudah tercapai, dana dikembalikan ke saldo pengguna"
+	case payment.Status == "Partial":
+		message = "Pembayaran kurang, dana akan dikembalikan ke pengguna"
+	case approve:
+		message = "Pembayaran disetujui"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message, Data: map[string]interface{}{
+		"order_id":        payment.OrderID,
+		"status":          payment.Status,
+		"amount_received": payment.AmountReceived,
+	}})
+}
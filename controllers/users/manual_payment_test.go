@@ -0,0 +1,166 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestManualTransferPurchase(t *testing.T) {
+	tx := testutil.Tx(t)
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	setting := models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, ManualPayment: true, ManualBankName: "BCA", ManualAccountNumber: "1234567890", ManualAccountName: "PT Xinxun"}
+	if err := tx.Create(&setting).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Transfer"})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Transfer 1", Duration: 10, PurchaseLimit: 1})
+
+	gateway := &testutil.Kyta{}
+	h := NewInvestmentHandler(tx, gateway)
+	buy := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"MANUAL"}`, product.ID))), user.ID))
+		return rec
+	}
+	asAdmin := func(r *http.Request, id uint) *http.Request {
+		r = mux.SetURLVars(r, map[string]string{"id": fmt.Sprint(id)})
+		return r.WithContext(context.WithValue(r.Context(), utils.AdminIDKey, int64(3)))
+	}
+
+	// The purchase shows where to transfer instead of calling the gateway
+	rec := buy()
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data struct {
+			OrderID        string         `json:"order_id"`
+			ManualTransfer ManualTransfer `json:"manual_transfer"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	mt := created.Data.ManualTransfer
+	if mt.AccountNumber != "1234567890" || len(mt.TransferCode) != transferCodeLength || mt.ExpiredAt == "" || len(gateway.Payments) != 0 {
+		t.Fatalf("expected manual transfer details without a gateway call, got %+v (%d gateway calls)", mt, len(gateway.Payments))
+	}
+	var payment models.Payment
+	if err := tx.Where("order_id = ?", created.Data.OrderID).First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if utils.GetStringValue(payment.PaymentMethod) != "MANUAL" || utils.GetStringValue(payment.PaymentCode) != mt.TransferCode || payment.ExpiredAt == nil {
+		t.Fatalf("unexpected manual payment: %+v", payment)
+	}
+
+	// An open manual payment holds the purchase limit like a gateway one
+	if rec := buy(); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), string(utils.CodePurchaseLimitReached)) {
+		t.Fatalf("second purchase: expected %s, got %d: %s", utils.CodePurchaseLimitReached, rec.Code, rec.Body.String())
+	}
+
+	// The upload goes to S3; record the proof as it would
+	now := time.Now()
+	proof := "payment-proofs/test.png"
+	tx.Model(&payment).Updates(map[string]interface{}{"proof_image": proof, "proof_uploaded_at": now})
+
+	rec = httptest.NewRecorder()
+	h.AdminManualPayments(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/payments/manual", nil))
+	var queue struct {
+		Data struct {
+			Items []ManualPaymentResponse `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &queue); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("queue: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(queue.Data.Items) != 1 || queue.Data.Items[0].OrderID != payment.OrderID || queue.Data.Items[0].GrossAmount != product.Amount {
+		t.Fatalf("expected the payment in the review queue, got %+v", queue.Data.Items)
+	}
+
+	// Approval settles like a gateway callback
+	rec = httptest.NewRecorder()
+	h.AdminApproveManualPayment(rec, asAdmin(httptest.NewRequest(http.MethodPost, "/v3/admin/payments/x/approve", strings.NewReader(`{}`)), payment.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("approve: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	tx.First(&inv, payment.InvestmentID)
+	tx.First(&payment, payment.ID)
+	var trx models.Transaction
+	tx.Where("order_id = ?", inv.OrderID).First(&trx)
+	if inv.Status != "Running" || inv.CertificateNo == nil || payment.Status != "Success" || payment.AmountReceived != product.Amount || trx.Status != "Success" {
+		t.Fatalf("expected an activated purchase, investment %s payment %s transaction %s", inv.Status, payment.Status, trx.Status)
+	}
+	if payment.ReviewedBy == nil || *payment.ReviewedBy != 3 {
+		t.Fatalf("expected the reviewer on the payment, got %v", payment.ReviewedBy)
+	}
+	var audits int64
+	tx.Model(&models.AdminAuditLog{}).Where("action = ? AND target_id = ?", "payment.manual_approve", payment.ID).Count(&audits)
+	if audits != 1 {
+		t.Fatalf("expected one audit log, got %d", audits)
+	}
+	rec = httptest.NewRecorder()
+	h.AdminApproveManualPayment(rec, asAdmin(httptest.NewRequest(http.MethodPost, "/v3/admin/payments/x/approve", strings.NewReader(`{}`)), payment.ID))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second approval: expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A rejection cancels the purchase and closes it to further proof
+	tx.Model(&product).Update("purchase_limit", 0)
+	rec = buy()
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	var rejected models.Payment
+	tx.Where("order_id = ?", created.Data.OrderID).First(&rejected)
+	rec = httptest.NewRecorder()
+	h.AdminRejectManualPayment(rec, asAdmin(httptest.NewRequest(http.MethodPost, "/v3/admin/payments/x/reject", strings.NewReader(`{}`)), rejected.ID))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("reject without a note: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.AdminRejectManualPayment(rec, asAdmin(httptest.NewRequest(http.MethodPost, "/v3/admin/payments/x/reject", strings.NewReader(`{"note":"Nominal tidak sesuai"}`)), rejected.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reject: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var cancelled models.Investment
+	tx.First(&cancelled, rejected.InvestmentID)
+	tx.First(&rejected, rejected.ID)
+	if cancelled.Status != "Cancelled" || rejected.Status != "Failed" || rejected.ReviewNote == nil {
+		t.Fatalf("expected a cancelled purchase, investment %s payment %s", cancelled.Status, rejected.Status)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v3/users/payments/x/proof", nil)
+	req = mux.SetURLVars(testutil.AsUser(req, user.ID), map[string]string{"order_id": rejected.OrderID})
+	rec = httptest.NewRecorder()
+	h.UploadPaymentProof(rec, req)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), string(utils.CodePaymentClosed)) {
+		t.Fatalf("proof after review: expected %s, got %d: %s", utils.CodePaymentClosed, rec.Code, rec.Body.String())
+	}
+
+	// Switched off, MANUAL is refused
+	tx.Model(&models.Setting{}).Where("id = ?", setting.ID).Update("manual_payment", false)
+	models.InvalidateSettingCache()
+	if rec := buy(); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), string(utils.CodeManualPaymentUnavailable)) {
+		t.Fatalf("disabled: expected %s, got %d: %s", utils.CodeManualPaymentUnavailable, rec.Code, rec.Body.String())
+	}
+}
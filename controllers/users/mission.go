@@ -0,0 +1,197 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MissionHandler serves mission progress and reward claims.
+type MissionHandler struct {
+	DB *gorm.DB
+}
+
+func NewMissionHandler(db *gorm.DB) *MissionHandler {
+	return &MissionHandler{DB: db}
+}
+
+// MissionResponse is a running mission with the caller's progress.
+type MissionResponse struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Type         string     `json:"type"`
+	TargetCount  int64      `json:"target_count"`
+	Progress     int64      `json:"progress"`
+	RewardType   string     `json:"reward_type"`
+	RewardAmount int64      `json:"reward_amount"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Completed    bool       `json:"completed"`
+	Claimed      bool       `json:"claimed"`
+	Claimable    bool       `json:"claimable"`
+}
+
+var (
+	errMissionNotFound   = errors.New("mission not found")
+	errMissionExpired    = errors.New("mission expired")
+	errMissionIncomplete = errors.New("mission not completed")
+	errMissionClaimed    = errors.New("mission already claimed")
+)
+
+// GET /api/users/missions
+// Lists running missions; ended or deactivated ones drop out of the list.
+func (h *MissionHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	type missionRow struct {
+		models.Mission
+		Progress    *int64
+		CompletedAt *time.Time
+		ClaimedAt   *time.Time
+	}
+	now := time.Now()
+	var rows []missionRow
+	if err := h.DB.Table("missions").
+		Select("missions.*, user_missions.progress, user_missions.completed_at, user_missions.claimed_at").
+		Joins("LEFT JOIN user_missions ON user_missions.mission_id = missions.id AND user_missions.user_id = ?", uid).
+		Where("missions.status = ? AND missions.starts_at <= ?", "Active", now).
+		Where("missions.ends_at IS NULL OR missions.ends_at > ?", now).
+		Order("missions.id ASC").
+		Find(&rows).Error; err != nil {
+		utils.LogError(r, "MissionHandler.List", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	resp := make([]MissionResponse, 0, len(rows))
+	for _, m := range rows {
+		item := MissionResponse{
+			ID: m.ID, Name: m.Name, Description: m.Description, Type: m.Type,
+			TargetCount: m.TargetCount, RewardType: m.RewardType, RewardAmount: m.RewardAmount, EndsAt: m.EndsAt,
+			Completed: m.CompletedAt != nil, Claimed: m.ClaimedAt != nil,
+		}
+		if m.Progress != nil {
+			item.Progress = *m.Progress
+		}
+		item.Claimable = item.Completed && !item.Claimed
+		resp = append(resp, item)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: resp})
+}
+
+// POST /api/users/missions/{id}/claim
+// Pays the reward of a completed mission once. The user's progress row is
+// locked for the claim, so concurrent requests cannot both pay.
+func (h *MissionHandler) Claim(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvalidID)})
+		return
+	}
+
+	var mission models.Mission
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&mission, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errMissionNotFound
+			}
+			return err
+		}
+		return claimMission(tx, uid, &mission, time.Now())
+	})
+	switch {
+	case errors.Is(err, errMissionNotFound):
+		utils.WriteError(w, r, http.StatusNotFound, utils.CodeMissionNotFound)
+		return
+	case errors.Is(err, errMissionClaimed):
+		utils.WriteError(w, r, http.StatusConflict, utils.CodeMissionAlreadyClaimed)
+		return
+	case errors.Is(err, errMissionExpired):
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeMissionExpired)
+		return
+	case errors.Is(err, errMissionIncomplete):
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeMissionNotCompleted)
+		return
+	case err != nil:
+		utils.LogError(r, "MissionHandler.Claim", err, "mission_id", id)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: utils.T(r, i18n.MsgMissionClaimed),
+		Data: map[string]interface{}{
+			"mission_id":    mission.ID,
+			"reward_type":   mission.RewardType,
+			"reward_amount": mission.RewardAmount,
+		},
+	})
+}
+
+// claimMission marks uid's completed progress on mission claimed and pays the
+// reward. It must run inside a transaction.
+func claimMission(tx *gorm.DB, uid uint, mission *models.Mission, now time.Time) error {
+	var um models.UserMission
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND mission_id = ?", uid, mission.ID).
+		First(&um).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if !mission.RunningAt(now) {
+				return errMissionExpired
+			}
+			return errMissionIncomplete
+		}
+		return err
+	}
+	if um.ClaimedAt != nil {
+		return errMissionClaimed
+	}
+	if !mission.RunningAt(now) {
+		return errMissionExpired
+	}
+	if um.CompletedAt == nil {
+		return errMissionIncomplete
+	}
+
+	if err := tx.Model(&um).UpdateColumn("claimed_at", now).Error; err != nil {
+		return err
+	}
+	if mission.RewardType == "spin_ticket" {
+		_, err := models.GrantSpinTickets(tx, uid, models.TicketSourceMission, strconv.FormatUint(uint64(um.ID), 10), uint(mission.RewardAmount))
+		return err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", uid).UpdateColumn("balance", gorm.Expr("balance + ?", mission.RewardAmount)).Error; err != nil {
+		return err
+	}
+	msg := "Hadiah misi " + mission.Name
+	trx := models.Transaction{
+		UserID:          uid,
+		Amount:          mission.RewardAmount,
+		Charge:          0,
+		OrderID:         utils.GenerateOrderID(utils.OrderBonus, uid),
+		TransactionFlow: "debit",
+		TransactionType: "mission",
+		Message:         &msg,
+		Status:          "Success",
+	}
+	return tx.Create(&trx).Error
+}
@@ -0,0 +1,87 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMissionProgressAndClaim(t *testing.T) {
+	tx := testutil.Tx(t)
+	user := testutil.NewUser(t, tx, models.User{Name: "Misi"})
+	now := time.Now()
+	ended := now.Add(-time.Minute)
+	first := models.Mission{Name: "Investasi pertama", Type: models.MissionInvestment, TargetCount: 1, RewardType: "balance", RewardAmount: 10000, StartsAt: now.Add(-time.Hour), Status: "Active"}
+	twice := models.Mission{Name: "Investasi 2x", Type: models.MissionInvestment, TargetCount: 2, RewardType: "spin_ticket", RewardAmount: 1, StartsAt: now.Add(-time.Hour), Status: "Active"}
+	for _, m := range []*models.Mission{&first, &twice} {
+		if err := tx.Create(m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := models.RecordMissionProgress(tx, user.ID, models.MissionInvestment, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewMissionHandler(tx)
+	claim := func(id uint) (int, utils.ErrorCode) {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/v3/users/missions/x/claim", nil), map[string]string{"id": fmt.Sprint(id)})
+		rec := httptest.NewRecorder()
+		h.Claim(rec, testutil.AsUser(req, user.ID))
+		var resp utils.APIResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp.Code
+	}
+
+	rec := httptest.NewRecorder()
+	h.List(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/missions", nil), user.ID))
+	var list struct {
+		Data []MissionResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	progress := map[uint]MissionResponse{}
+	for _, m := range list.Data {
+		progress[m.ID] = m
+	}
+	if !progress[first.ID].Claimable || progress[twice.ID].Progress != 1 || progress[twice.ID].Completed {
+		t.Fatalf("unexpected mission list: %+v", list.Data)
+	}
+
+	if code, ec := claim(twice.ID); code != http.StatusBadRequest || ec != utils.CodeMissionNotCompleted {
+		t.Fatalf("incomplete mission: expected 400 %s, got %d %s", utils.CodeMissionNotCompleted, code, ec)
+	}
+	if code, _ := claim(first.ID); code != http.StatusOK {
+		t.Fatalf("claim: expected 200, got %d", code)
+	}
+	if code, ec := claim(first.ID); code != http.StatusConflict || ec != utils.CodeMissionAlreadyClaimed {
+		t.Fatalf("second claim: expected 409 %s, got %d %s", utils.CodeMissionAlreadyClaimed, code, ec)
+	}
+	var got models.User
+	if err := tx.First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != first.RewardAmount {
+		t.Fatalf("expected balance %d after one claim, got %d", first.RewardAmount, got.Balance)
+	}
+
+	// Completed but ended before the claim: the reward is gone
+	if err := models.RecordMissionProgress(tx, user.ID, models.MissionInvestment, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Model(&twice).Update("ends_at", ended).Error; err != nil {
+		t.Fatal(err)
+	}
+	if code, ec := claim(twice.ID); code != http.StatusBadRequest || ec != utils.CodeMissionExpired {
+		t.Fatalf("expired mission: expected 400 %s, got %d %s", utils.CodeMissionExpired, code, ec)
+	}
+}
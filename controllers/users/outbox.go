@@ -0,0 +1,130 @@
+package users
+
+import (
+	"strings"
+
+	"project/models"
+	"project/outbox"
+	"project/referral"
+	"project/utils"
+	"project/vip"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// outboxInvestmentActivated carries the effects of a paid investment beyond
+// its own state, keyed by order id.
+const outboxInvestmentActivated = "investment.activated"
+
+type investmentActivation struct {
+	InvestmentID uint `json:"investment_id"`
+	// FirstInvestment is whether the investor had none active or finished
+	// when this one was paid
+	FirstInvestment bool `json:"first_investment"`
+	// VIP is set for locked (Monitor) categories, which count towards the level
+	VIP bool `json:"vip"`
+}
+
+// NewOutboxDispatcher returns the dispatcher for the events recorded by the
+// user-facing flows. Routes share one between the handlers and the cron.
+func NewOutboxDispatcher(db *gorm.DB) *outbox.Dispatcher {
+	d := outbox.NewDispatcher(db)
+	d.Handle(outboxInvestmentActivated, applyInvestmentActivated)
+	return d
+}
+
+// applyInvestmentActivated recalculates the investor's VIP level, advances
+// missions and rewards the direct referrer with spin tickets and the referral
+// bonus. A purchase taken back (chargeback, cancellation) before this runs
+// gets no rewards.
+func applyInvestmentActivated(tx *gorm.DB, e *models.OutboxEvent) error {
+	var p investmentActivation
+	if err := outbox.Decode(e, &p); err != nil {
+		return err
+	}
+	var inv models.Investment
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, p.InvestmentID).Error; err != nil {
+		return err
+	}
+
+	// Calculate VIP level based on total_invest_vip for locked categories
+	if p.VIP {
+		if _, err := vip.Recalculate(tx, inv.UserID, nil, vip.SourcePayment, vip.Policy()); err != nil {
+			return err
+		}
+	}
+	if inv.Status != "Running" && inv.Status != "Completed" {
+		return nil
+	}
+
+	if err := models.RecordMissionProgress(tx, inv.UserID, models.MissionInvestment, 1); err != nil {
+		return err
+	}
+
+	// Bonus rekomendasi investor hanya untuk level 1, persentase dari settings
+	// (default 30% untuk pembelian pertama dan ulang)
+	setting, err := models.GetCachedSetting(tx)
+	if err != nil {
+		setting = models.Setting{ReferralBonusPercent: 30, ReferralRepeatPercent: 30, SpinTicketMinAmount: 100000, SpinTicketsPerPurchase: 1}
+	}
+	var user models.User
+	if err := tx.Select("id, reff_by").Where("id = ?", inv.UserID).First(&user).Error; err != nil || user.ReffBy == nil {
+		return nil
+	}
+	var level1 models.User
+	if err := tx.Select("id, spin_ticket").Where("id = ?", *user.ReffBy).First(&level1).Error; err != nil {
+		return nil
+	}
+	// Spin tickets per settings, granted once per purchase order
+	tickets, err := referral.SpinTickets(tx, setting, level1.ID, inv.Amount)
+	if err != nil {
+		return err
+	}
+	if _, err := models.GrantSpinTickets(tx, level1.ID, models.TicketSourceReferral, inv.OrderID, tickets); err != nil {
+		return err
+	}
+
+	// A friend's first investment counts towards invite missions
+	if p.FirstInvestment {
+		if err := models.RecordMissionProgress(tx, level1.ID, models.MissionInviteInvestor, 1); err != nil {
+			return err
+		}
+	}
+
+	// Give referral bonus to direct referrer
+	bonus, err := referral.ComputeBonus(tx, setting, level1.ID, &inv)
+	if err != nil {
+		return err
+	}
+	if bonus.Amount <= 0 {
+		return nil
+	}
+	// A bonus between accounts that look like one person waits for
+	// admin review instead of reaching the balance
+	signals, err := referral.Signals(tx, inv.UserID, level1.ID, referral.IPWindow())
+	if err != nil {
+		return err
+	}
+	msg := bonus.Message()
+	status := "Success"
+	if len(signals) > 0 {
+		msg += " (ditahan: " + strings.Join(signals, ", ") + ")"
+		status = "Held"
+	} else if err := tx.Model(&models.User{}).Where("id = ?", level1.ID).UpdateColumn("balance", gorm.Expr("balance + ?", bonus.Amount)).Error; err != nil {
+		return err
+	}
+	trx := models.Transaction{
+		UserID:          level1.ID,
+		InvestmentID:    &inv.ID,
+		SourceOrderID:   &inv.OrderID,
+		Amount:          bonus.Amount,
+		Charge:          0,
+		OrderID:         utils.GenerateOrderID(utils.OrderBonus, level1.ID),
+		TransactionFlow: "debit",
+		TransactionType: "team",
+		Message:         &msg,
+		Status:          status,
+	}
+	return tx.Create(&trx).Error
+}
@@ -0,0 +1,93 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestPaymentConfirmedWhileOutboxHandlerFails(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Outbox", ReffBy: &referrer.ID})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Outbox 1"})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	// The rewards processor is down
+	h.Outbox.Handle(outboxInvestmentActivated, func(*gorm.DB, *models.OutboxEvent) error {
+		return errors.New("rewards unavailable")
+	})
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-o","reference_id":%q,"amount":%d,"status":"SUCCESS"}}`, inv.OrderID, inv.Amount)
+	rec = httptest.NewRecorder()
+	h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Confirmed without the rewards, which wait for a retry
+	if err := tx.First(&inv, inv.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Running" {
+		t.Fatalf("expected Running, got %s", inv.Status)
+	}
+	var event models.OutboxEvent
+	if err := tx.Where("kind = ? AND event_key = ?", outboxInvestmentActivated, inv.OrderID).First(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	if event.Status != models.OutboxPending || event.Attempts != 1 || event.LastError == nil {
+		t.Fatalf("expected a pending event after one failed attempt, got %+v", event)
+	}
+	var pushes int64
+	tx.Model(&models.OutboxEvent{}).Where("kind = ? AND event_key = ? AND status = ?", "push", "payment_success:"+inv.OrderID, models.OutboxDone).Count(&pushes)
+	if pushes != 1 {
+		t.Fatalf("expected the payment push handed off, got %d", pushes)
+	}
+	bonuses := func() int64 {
+		var n int64
+		tx.Model(&models.Transaction{}).Where("user_id = ? AND transaction_type = ?", referrer.ID, "team").Count(&n)
+		return n
+	}
+	if n := bonuses(); n != 0 {
+		t.Fatalf("expected no bonus yet, got %d", n)
+	}
+
+	// The processor is back; retrying the due events pays the bonus once
+	h.Outbox.Handle(outboxInvestmentActivated, applyInvestmentActivated)
+	if err := tx.Model(&event).Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	for run := 1; run <= 2; run++ {
+		if _, err := h.Outbox.Run(10, nil); err != nil {
+			t.Fatalf("outbox run %d: %v", run, err)
+		}
+	}
+	if n := bonuses(); n != 1 {
+		t.Fatalf("expected one bonus after the retries, got %d", n)
+	}
+	if err := tx.First(&event, event.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if event.Status != models.OutboxDone || event.Attempts != 2 || event.ProcessedAt == nil {
+		t.Fatalf("expected the event done on its second attempt, got %+v", event)
+	}
+}
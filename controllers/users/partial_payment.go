@@ -0,0 +1,212 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"project/alert"
+	"project/models"
+	"project/notify"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultPartialRefundMinutes is how long a short payment waits before it
+	// is refunded when PARTIAL_PAYMENT_REFUND_MINUTES is not set.
+	defaultPartialRefundMinutes = 60
+	// partialRefundBatchSize bounds the payments one cron run refunds.
+	partialRefundBatchSize = 100
+)
+
+// markPartialPayment records that received, everything paid so far, is less
+// than the payment's gross. The investment stays Pending until a further
+// transfer covers the gross or the refund cron, whose wait runs from the
+// first short transfer.
+func markPartialPayment(tx *gorm.DB, payment *models.Payment, received int64, paymentID string) error {
+	updates := map[string]interface{}{"status": "Partial", "amount_received": received}
+	if payment.PartialAt == nil {
+		updates["partial_at"] = time.Now()
+	}
+	if paymentID != "" {
+		updates["gateway_payment_id"] = paymentID
+	}
+	return tx.Model(payment).Updates(updates).Error
+}
+
+// holdOverpayment records what was paid beyond the gross of inv's payment as
+// a Held overpayment transaction for an admin to release to the investor's
+// balance. The amount comes from the unauthenticated webhook, so it never
+// reaches the withdrawable balance unreviewed.
+func holdOverpayment(tx *gorm.DB, inv *models.Investment, excess int64) error {
+	msg := fmt.Sprintf("Kelebihan pembayaran %s", inv.OrderID)
+	return tx.Create(&models.Transaction{
+		UserID:          inv.UserID,
+		InvestmentID:    &inv.ID,
+		Amount:          excess,
+		OrderID:         utils.GenerateOrderID(utils.OrderRefund, inv.UserID),
+		TransactionFlow: "debit",
+		TransactionType: "overpayment",
+		Message:         &msg,
+		Status:          "Held",
+	}).Error
+}
+
+// partialRefund is a short payment the refund cron has refunded.
+type partialRefund struct {
+	UserID  uint
+	OrderID string
+	Amount  int64
+	// Payout is set when the refund was queued as a withdrawal to the
+	// user's bank account rather than credited to the balance.
+	Payout bool
+}
+
+// POST /api/cron/partial-refunds
+// Refunds investment payments left Partial for PARTIAL_PAYMENT_REFUND_MINUTES:
+// the investment is cancelled and the amount received is paid back to the
+// user's latest bank account as a withdrawal, which goes through the usual
+// payout approval, or credited to the balance when the user has no usable
+// bank account.
+func (h *InvestmentHandler) CronPartialRefunds(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	wait := defaultPartialRefundMinutes
+	if v, err := strconv.Atoi(os.Getenv("PARTIAL_PAYMENT_REFUND_MINUTES")); err == nil && v > 0 {
+		wait = v
+	}
+	cutoff := time.Now().Add(-time.Duration(wait) * time.Minute)
+
+	var ids []uint
+	if err := h.DB.Model(&models.Payment{}).
+		Where("status = ? AND partial_at <= ?", "Partial", cutoff).
+		Order("id ASC").Limit(partialRefundBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		utils.LogError(r, "partial refund cron: load payments", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	refunded, payouts, failed := 0, 0, 0
+	for _, id := range ids {
+		ref, err := refundPartialPayment(h.DB, id)
+		if err != nil {
+			utils.LogError(r, "partial refund cron: refund payment", err, "payment_id", id)
+			failed++
+			continue
+		}
+		if ref == nil {
+			continue
+		}
+		h.Notifier.Enqueue(notify.PaymentRefunded(ref.UserID, ref.OrderID, ref.Amount))
+		refunded++
+		if ref.Payout {
+			payouts++
+		}
+	}
+	if failed > 0 {
+		h.Alerts.Notify(alert.KeyCronFailed, "Cron partial refunds: %d pembayaran gagal dikembalikan, %d berhasil", failed, refunded)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Cron executed",
+		Data:    map[string]interface{}{"refunded": refunded, "payouts": payouts, "failed": failed},
+	})
+}
+
+// refundPartialPayment refunds one Partial payment. It returns nil when the
+// payment is no longer Partial, e.g. refunded by an overlapping run.
+func refundPartialPayment(db *gorm.DB, paymentID uint) (*partialRefund, error) {
+	var ref *partialRefund
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		if err := tx.First(&payment, paymentID).Error; err != nil {
+			return err
+		}
+		// Same lock order as the webhook: investment first, then the payment
+		var inv models.Investment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, payment.InvestmentID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&payment, paymentID).Error; err != nil {
+			return err
+		}
+		if payment.Status != "Partial" || inv.Status != "Pending" {
+			return nil
+		}
+
+		if err := tx.Model(&payment).Update("status", "Refunded").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&inv).Update("status", "Cancelled").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Update("status", "Failed").Error; err != nil {
+			return err
+		}
+
+		amount := payment.AmountReceived
+		msg := fmt.Sprintf("Pengembalian pembayaran kurang %s", inv.OrderID)
+		if err := tx.Create(&models.Transaction{
+			UserID:          inv.UserID,
+			InvestmentID:    &inv.ID,
+			Amount:          amount,
+			OrderID:         utils.GenerateOrderID(utils.OrderRefund, inv.UserID),
+			TransactionFlow: "debit",
+			TransactionType: "partial_refund",
+			Message:         &msg,
+			Status:          "Success",
+		}).Error; err != nil {
+			return err
+		}
+		ref = &partialRefund{UserID: inv.UserID, OrderID: inv.OrderID, Amount: amount}
+
+		// The refund lands in the balance and, with a usable bank account,
+		// leaves it again at once as a withdrawal awaiting payout
+		var acc models.BankAccount
+		err := tx.Joins("Bank").Where("bank_accounts.user_id = ? AND Bank.status = ?", inv.UserID, "Active").
+			Order("bank_accounts.id DESC").First(&acc).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Model(&models.User{}).Where("id = ?", inv.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", amount)).Error
+		}
+		if err != nil {
+			return err
+		}
+		orderID := utils.GenerateOrderID(utils.OrderWithdrawal, inv.UserID)
+		if err := tx.Create(&models.Withdrawal{
+			UserID:        inv.UserID,
+			BankAccountID: acc.ID,
+			Amount:        amount,
+			FinalAmount:   amount,
+			OrderID:       orderID,
+			Status:        "Pending",
+		}).Error; err != nil {
+			return err
+		}
+		wdMsg := fmt.Sprintf("Pengembalian ke %s %s", acc.Bank.Name, MaskAccountNumber(acc.AccountNumber))
+		if err := tx.Create(&models.Transaction{
+			UserID:          inv.UserID,
+			Amount:          amount,
+			OrderID:         orderID,
+			TransactionFlow: "credit",
+			TransactionType: "withdrawal",
+			Message:         &wdMsg,
+			Status:          "Pending",
+		}).Error; err != nil {
+			return err
+		}
+		ref.Payout = true
+		return nil
+	})
+	return ref, err
+}
@@ -0,0 +1,151 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+)
+
+func TestPartialAndOverpaidInvestmentPayments(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	suffix := time.Now().UnixNano() % 1000000000
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Kurang"})
+	bank := models.Bank{Name: "Bank Uji", Code: fmt.Sprintf("PP%d", suffix), Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: "Kurang", AccountNumber: "1234567890"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Partial 1"})
+
+	gateway := &testutil.Kyta{}
+	h := NewInvestmentHandler(tx, gateway)
+	buy := func() string {
+		body := fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID)
+		rec := httptest.NewRecorder()
+		h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(body)), user.ID))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return gateway.Payments[len(gateway.Payments)-1].ReferenceID
+	}
+	// Each transfer into the VA calls back with its own callback_time
+	webhook := func(orderID string, amount int64, at string) string {
+		body := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-p","reference_id":%q,"amount":%d,"status":"SUCCESS","callback_time":%q}}`, orderID, amount, at)
+		rec := httptest.NewRecorder()
+		h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Message
+	}
+	investment := func(orderID string) models.Investment {
+		var inv models.Investment
+		if err := tx.Where("order_id = ?", orderID).First(&inv).Error; err != nil {
+			t.Fatal(err)
+		}
+		return inv
+	}
+
+	// 1. An underpayment waits as Partial and a second short transfer adds
+	// to it; a replayed callback changes nothing
+	short := buy()
+	if msg := webhook(short, 60000, "2025-01-01T10:00:00Z"); msg != "Partial" {
+		t.Fatalf("underpayment: expected Partial, got %q", msg)
+	}
+	if msg := webhook(short, 60000, "2025-01-01T10:00:00Z"); msg != "Ignored" {
+		t.Fatalf("replayed underpayment: expected Ignored, got %q", msg)
+	}
+	if msg := webhook(short, 25000, "2025-01-01T10:05:00Z"); msg != "Partial" {
+		t.Fatalf("second underpayment: expected Partial, got %q", msg)
+	}
+	var payment models.Payment
+	if err := tx.Where("order_id = ?", short).First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != "Partial" || payment.AmountReceived != 85000 || payment.PartialAt == nil {
+		t.Fatalf("unexpected payment after underpayment: %+v", payment)
+	}
+	if inv := investment(short); inv.Status != "Pending" {
+		t.Fatalf("expected the investment to stay Pending, got %s", inv.Status)
+	}
+
+	// 2. After the wait the refund cron cancels it and pays both transfers
+	// back
+	if err := tx.Model(&payment).Update("partial_at", time.Now().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v3/cron/partial-refunds", nil)
+	req.Header.Set("X-CRON-KEY", "cron-test")
+	rec := httptest.NewRecorder()
+	h.CronPartialRefunds(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"payouts":1`) {
+		t.Fatalf("refund cron: expected one payout, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if inv := investment(short); inv.Status != "Cancelled" {
+		t.Fatalf("expected the short investment Cancelled, got %s", inv.Status)
+	}
+	var wd models.Withdrawal
+	if err := tx.Where("user_id = ?", user.ID).First(&wd).Error; err != nil {
+		t.Fatal(err)
+	}
+	if wd.Amount != 85000 || wd.Status != "Pending" {
+		t.Fatalf("expected a pending 85000 refund payout, got %+v", wd)
+	}
+
+	// 3. Short transfers that together cover the gross activate it
+	topped := buy()
+	if msg := webhook(topped, 40000, "2025-01-01T11:00:00Z"); msg != "Partial" {
+		t.Fatalf("first transfer: expected Partial, got %q", msg)
+	}
+	if msg := webhook(topped, 60000, "2025-01-01T11:05:00Z"); msg != "OK" {
+		t.Fatalf("second transfer: expected OK, got %q", msg)
+	}
+	if inv := investment(topped); inv.Status != "Running" {
+		t.Fatalf("expected the topped-up investment Running, got %s", inv.Status)
+	}
+	var paid models.Payment
+	if err := tx.Where("order_id = ?", topped).First(&paid).Error; err != nil {
+		t.Fatal(err)
+	}
+	if paid.Status != "Success" || paid.AmountReceived != 100000 {
+		t.Fatalf("expected a Success payment of 100000, got %s %d", paid.Status, paid.AmountReceived)
+	}
+
+	// 4. An overpayment activates and holds the excess for review, since its
+	// amount is only the webhook's word
+	over := buy()
+	if msg := webhook(over, 130000, "2025-01-01T12:00:00Z"); msg != "OK" {
+		t.Fatalf("overpayment: expected OK, got %q", msg)
+	}
+	if inv := investment(over); inv.Status != "Running" {
+		t.Fatalf("expected the overpaid investment Running, got %s", inv.Status)
+	}
+	var held models.Transaction
+	if err := tx.Where("user_id = ? AND transaction_type = ?", user.ID, "overpayment").First(&held).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got models.User
+	tx.First(&got, user.ID)
+	// The refund passed through the balance to the payout, and the excess
+	// waits outside it
+	if held.Amount != 30000 || held.Status != "Held" || got.Balance != 0 {
+		t.Fatalf("expected 30000 held and nothing credited, got transaction %d %s and balance %d", held.Amount, held.Status, got.Balance)
+	}
+}
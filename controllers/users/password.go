@@ -3,12 +3,15 @@ package users
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"project/database"
+	"project/middleware"
 	"project/models"
 	"project/utils"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type ChangePasswordRequest struct {
@@ -33,29 +36,61 @@ func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if req.Password != req.ConfirmationPassword {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Konfirmasi kata sandi tidak cocok"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Konfirmasi kata sandi tidak cocok", Code: utils.CodePasswordMismatch})
 		return
 	}
 	db := database.DB
 	var user models.User
 	if err := db.First(&user, uid).Error; err != nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found", Code: utils.CodeUserNotFound})
 		return
 	}
 	// Validate current password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kata sandi saat ini tidak cocok"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kata sandi saat ini tidak cocok", Code: utils.CodeWrongPassword})
 		return
 	}
 	// Hash new password
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
+		utils.LogError(r, "ChangePasswordHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to hash password"})
 		return
 	}
-	if err := db.Model(&user).Update("password", string(hash)).Error; err != nil {
+	// The new password signs out every other session; this one gets a fresh
+	// pair below, issued after the bump so it carries the new version
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", string(hash)).Error; err != nil {
+			return err
+		}
+		return models.RevokeSessions(tx, user.ID)
+	})
+	if err != nil {
+		utils.LogError(r, "ChangePasswordHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to update password"})
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Kata sandi berhasil diubah"})
+	middleware.InvalidateTokenVersion(user.ID)
+
+	accessToken, err := utils.GenerateAccessToken(user.ID, "user")
+	if err != nil {
+		utils.LogError(r, "ChangePasswordHandler: access token", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to issue session"})
+		return
+	}
+	refreshJTI, _, err := utils.GenerateRefreshToken(user.ID)
+	if err != nil {
+		utils.LogError(r, "ChangePasswordHandler: refresh token", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to issue session"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Kata sandi berhasil diubah",
+		Data: map[string]interface{}{
+			"access_token":  accessToken,
+			"access_expire": time.Now().Add(15 * time.Minute).UTC().Format(time.RFC3339),
+			"refresh_token": refreshJTI,
+		},
+	})
 }
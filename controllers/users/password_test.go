@@ -0,0 +1,82 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/database"
+	"project/middleware"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Changing the password signs out the user's other sessions but hands the
+// device that made the change a new pair that still works.
+func TestChangePasswordKeepsCurrentSession(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	t.Setenv("JWT_SECRET", "password-test-secret")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("lama123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := testutil.NewUser(t, tx, models.User{Name: "Sandi", Password: string(hash)})
+	t.Cleanup(func() { middleware.InvalidateTokenVersion(user.ID) })
+	oldAccess, err := utils.GenerateAccessToken(user.ID, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldRefresh, _, err := utils.GenerateRefreshToken(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"current_password":"lama123","password":"baru123","confirmation_password":"baru123"}`
+	ChangePasswordHandler(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/change-password", strings.NewReader(body)), user.ID))
+	var resp struct {
+		Data struct {
+			AccessToken  string `json:"access_token"`
+			RefreshToken string `json:"refresh_token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK || resp.Data.AccessToken == "" || resp.Data.RefreshToken == "" {
+		t.Fatalf("expected 200 with a new token pair, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var refresh []models.RefreshToken
+	tx.Where("user_id = ?", user.ID).Find(&refresh)
+	revoked := map[string]bool{}
+	for _, rt := range refresh {
+		revoked[rt.ID] = rt.Revoked
+	}
+	if len(refresh) != 2 || !revoked[oldRefresh] || revoked[resp.Data.RefreshToken] {
+		t.Fatalf("expected the old refresh token revoked and the new one live, got %+v", refresh)
+	}
+
+	auth := middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v3/users/info", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		auth.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := call(oldAccess); code != http.StatusUnauthorized {
+		t.Fatalf("old access token: expected 401, got %d", code)
+	}
+	if code := call(resp.Data.AccessToken); code != http.StatusNoContent {
+		t.Fatalf("new access token: expected 204, got %d", code)
+	}
+}
@@ -0,0 +1,154 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"project/i18n"
+	"project/models"
+	"project/pubsub"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	// paymentEventsWait is how long a payment page's long-poll waits for its
+	// payment to change before answering with the status unchanged.
+	paymentEventsWait = 25 * time.Second
+	// maxPaymentWaitsPerUser caps the long-polls one user holds open at once.
+	maxPaymentWaitsPerUser = 3
+)
+
+// paymentEvents wakes the long-polls waiting on an order id. The webhook and
+// the manual review publish to it after committing a payment's new status.
+var paymentEvents = pubsub.New()
+
+// paymentWaits counts the long-polls each user holds open.
+var paymentWaits = struct {
+	sync.Mutex
+	n map[uint]int
+}{n: map[uint]int{}}
+
+func acquirePaymentWait(uid uint) bool {
+	paymentWaits.Lock()
+	defer paymentWaits.Unlock()
+	if paymentWaits.n[uid] >= maxPaymentWaitsPerUser {
+		return false
+	}
+	paymentWaits.n[uid]++
+	return true
+}
+
+func releasePaymentWait(uid uint) {
+	paymentWaits.Lock()
+	defer paymentWaits.Unlock()
+	if paymentWaits.n[uid]--; paymentWaits.n[uid] <= 0 {
+		delete(paymentWaits.n, uid)
+	}
+}
+
+// PaymentEvent answers GET /users/payments/{order_id}/events.
+type PaymentEvent struct {
+	OrderID    string `json:"order_id"`
+	Status     string `json:"status"`
+	Changed    bool   `json:"changed"`
+	ServerTime string `json:"server_time"`
+}
+
+// paymentStatusOf reads the status of the user's payment orderID.
+func paymentStatusOf(db *gorm.DB, uid uint, orderID string) (string, error) {
+	var payment models.Payment
+	err := db.Select("payments.status").
+		Joins("JOIN investments ON investments.id = payments.investment_id").
+		Where("payments.order_id = ? AND investments.user_id = ?", orderID, uid).
+		First(&payment).Error
+	return payment.Status, err
+}
+
+// GET /api/users/payments/{order_id}/events?status=Pending
+// Long-poll for the payment page. status is the one the page shows (default
+// Pending); when the payment's differs the answer is immediate, otherwise the
+// request waits up to 25 seconds for the webhook or a manual review to change
+// it. Either way it answers with the current status, changed telling whether
+// it differs from status, so the app simply polls again when it did not.
+// Wakes come from this instance only; a change committed elsewhere is
+// picked up when the wait runs out. A user holds at most 3 waits at once.
+func (h *InvestmentHandler) PaymentEvents(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	orderID := strings.TrimSpace(mux.Vars(r)["order_id"])
+	known := r.URL.Query().Get("status")
+	if known == "" {
+		known = "Pending"
+	}
+	db := h.DB.WithContext(r.Context())
+	reply := func(status string) {
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: PaymentEvent{
+			OrderID:    orderID,
+			Status:     status,
+			Changed:    status != known,
+			ServerTime: utils.FormatTime(time.Now()),
+		}})
+	}
+	fail := func(err error) {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodePaymentNotFound)
+			return
+		}
+		utils.LogError(r, "PaymentEvents", err, "order_id", orderID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgGenericError)})
+	}
+
+	status, err := paymentStatusOf(db, uid, orderID)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if status != known {
+		reply(status)
+		return
+	}
+	if !acquirePaymentWait(uid) {
+		utils.WriteError(w, r, http.StatusTooManyRequests, utils.CodeRateLimited)
+		return
+	}
+	defer releasePaymentWait(uid)
+
+	// Subscribed before the second read, a change committed between the two
+	// is either seen by the read or wakes the wait
+	changed, cancel := paymentEvents.Subscribe(orderID)
+	defer cancel()
+	if status, err = paymentStatusOf(db, uid, orderID); err != nil {
+		fail(err)
+		return
+	}
+	if status != known {
+		reply(status)
+		return
+	}
+
+	// The server's write timeout is shorter than the wait
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(paymentEventsWait + 10*time.Second))
+	timer := time.NewTimer(paymentEventsWait)
+	defer timer.Stop()
+	select {
+	case <-changed:
+	case <-timer.C:
+	case <-utils.ShutdownSignal(r):
+	case <-r.Context().Done():
+		return
+	}
+	if status, err = paymentStatusOf(db, uid, orderID); err != nil {
+		fail(err)
+		return
+	}
+	reply(status)
+}
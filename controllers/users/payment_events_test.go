@@ -0,0 +1,113 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// A payment page's long-poll answers at once when the payment already moved
+// on, wakes when the payment is published to, and is refused past the
+// per-user cap. Other users' order ids are not found.
+func TestPaymentEventsLongPoll(t *testing.T) {
+	// The waiting request reads on its own connection, so no wrapping
+	// transaction
+	db := testutil.DB(t)
+	user := testutil.NewUser(t, db, models.User{Name: "Menunggu"})
+	category := testutil.NewCategory(t, db, "unlocked")
+	product := testutil.NewProduct(t, db, models.Product{CategoryID: category.ID, Name: "Events 1"})
+	inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: product.Amount, DailyProfit: 5000, Duration: 2,
+		OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Pending"}
+	if err := db.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour)
+	payment := models.Payment{InvestmentID: inv.ID, OrderID: inv.OrderID, Amount: inv.Amount, Status: "Pending", ExpiredAt: &exp}
+	if err := db.Create(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&payment)
+		db.Unscoped().Delete(&inv)
+		db.Delete(&product)
+		db.Delete(&category)
+		db.Delete(&user)
+	})
+
+	h := NewInvestmentHandler(db, &testutil.Kyta{})
+	poll := func(uid uint, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v3/users/payments/"+inv.OrderID+"/events"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"order_id": inv.OrderID})
+		rec := httptest.NewRecorder()
+		h.PaymentEvents(rec, testutil.AsUser(req, uid))
+		return rec
+	}
+	event := func(rec *httptest.ResponseRecorder) PaymentEvent {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data PaymentEvent `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+
+	if rec := poll(user.ID+1, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's payment: expected 404, got %d", rec.Code)
+	}
+	// The page still shows Expired while the payment is Pending: immediate
+	start := time.Now()
+	if ev := event(poll(user.ID, "?status=Expired")); !ev.Changed || ev.Status != "Pending" || time.Since(start) > 5*time.Second {
+		t.Fatalf("stale status should answer at once, got %+v after %s", ev, time.Since(start))
+	}
+
+	paymentWaits.Lock()
+	paymentWaits.n[user.ID] = maxPaymentWaitsPerUser
+	paymentWaits.Unlock()
+	if rec := poll(user.ID, ""); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the cap: expected 429, got %d", rec.Code)
+	}
+	paymentWaits.Lock()
+	delete(paymentWaits.n, user.ID)
+	paymentWaits.Unlock()
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- poll(user.ID, "") }()
+	deadline := time.Now().Add(5 * time.Second)
+	for paymentEvents.Waiting(inv.OrderID) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("long-poll never started waiting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := db.Model(&payment).Update("status", "Success").Error; err != nil {
+		t.Fatal(err)
+	}
+	paymentEvents.Publish(inv.OrderID)
+	select {
+	case rec := <-done:
+		if ev := event(rec); !ev.Changed || ev.Status != "Success" {
+			t.Fatalf("expected the new status, got %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish did not wake the long-poll")
+	}
+	paymentWaits.Lock()
+	left := paymentWaits.n[user.ID]
+	paymentWaits.Unlock()
+	if left != 0 {
+		t.Fatalf("wait not released, %d still counted", left)
+	}
+}
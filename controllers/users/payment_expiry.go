@@ -0,0 +1,120 @@
+package users
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"project/models"
+	"project/notify"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// defaultExpiryWarnMinutes is how long before expiry a pending payment is
+// reminded when PAYMENT_EXPIRY_WARN_MINUTES is not set. Gateway payments
+// expire 15 minutes after creation.
+const defaultExpiryWarnMinutes = 5
+
+// expiringPayment is a pending investment payment or deposit about to lapse.
+// ProductName is empty for a deposit.
+type expiringPayment struct {
+	ID          uint
+	UserID      uint
+	OrderID     string
+	ProductName string
+	ExpiredAt   time.Time
+}
+
+// POST /api/cron/payment-expiry
+// Reminds the owner of each pending investment payment and deposit that
+// expires within the warning window, with an inbox notification and a push.
+// Each payment is claimed by stamping expiry_notified_at while it is still
+// Pending, so overlapping runs remind only once and a payment settled since
+// it was selected is skipped. The Pending transactions of investment
+// payments that have expired are marked Failed, so abandoned purchases leave
+// the statement.
+func (h *InvestmentHandler) CronPaymentExpiry(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != os.Getenv("CRON_KEY") {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	warn := defaultExpiryWarnMinutes
+	if v, err := strconv.Atoi(os.Getenv("PAYMENT_EXPIRY_WARN_MINUTES")); err == nil && v > 0 {
+		warn = v
+	}
+	now := time.Now()
+	until := now.Add(time.Duration(warn) * time.Minute)
+
+	db := h.DB
+	failed, err := models.FailExpiredInvestmentTransactions(db, now)
+	if err != nil {
+		utils.LogError(r, "payment expiry cron: fail expired transactions", err, "failed", failed)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	var payments []expiringPayment
+	if err := db.Model(&models.Payment{}).
+		Select("payments.id, investments.user_id, payments.order_id, investments.product_name, payments.expired_at").
+		Joins("JOIN investments ON investments.id = payments.investment_id").
+		Where("payments.status = ? AND payments.expiry_notified_at IS NULL AND payments.expired_at > ? AND payments.expired_at <= ?", "Pending", now, until).
+		Scan(&payments).Error; err != nil {
+		utils.LogError(r, "payment expiry cron: load payments", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+	var deposits []expiringPayment
+	if err := db.Model(&models.Deposit{}).
+		Select("id, user_id, order_id, expired_at").
+		Where("status = ? AND expiry_notified_at IS NULL AND expired_at > ? AND expired_at <= ?", "Pending", now, until).
+		Scan(&deposits).Error; err != nil {
+		utils.LogError(r, "payment expiry cron: load deposits", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	notified := remindExpiring(r, db, &models.Payment{}, payments, now, h.Notifier)
+	notified += remindExpiring(r, db, &models.Deposit{}, deposits, now, h.Notifier)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"notified": notified, "failed": failed}})
+}
+
+// remindExpiring claims each row of model in rows, stores its inbox
+// notification and queues its push, returning how many were reminded. A row
+// another run already claimed, or that is no longer Pending, is skipped.
+func remindExpiring(r *http.Request, db *gorm.DB, model interface{}, rows []expiringPayment, now time.Time, n *notify.Notifier) int {
+	count := 0
+	for _, p := range rows {
+		event := notify.PaymentExpiring(p.UserID, p.OrderID, p.ProductName, p.ExpiredAt)
+		claimed := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(model).Where("id = ? AND status = ? AND expiry_notified_at IS NULL", p.ID, "Pending").Update("expiry_notified_at", now)
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			locale, err := notify.UserLocale(tx, p.UserID)
+			if err != nil {
+				return err
+			}
+			inbox := notify.Inbox(event, locale, "payment_expiring")
+			if err := tx.Create(&inbox).Error; err != nil {
+				return err
+			}
+			claimed = true
+			return nil
+		})
+		if err != nil {
+			utils.LogError(r, "payment expiry cron: claim reminder", err, "order_id", p.OrderID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		n.Enqueue(event)
+		count++
+	}
+	return count
+}
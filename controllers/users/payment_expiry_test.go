@@ -0,0 +1,170 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/controllers/admins"
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestPaymentExpiryRemindsOnce(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	suffix := time.Now().UnixNano() % 1000000000
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Reminder"})
+	soon, later := time.Now().Add(3*time.Minute), time.Now().Add(time.Hour)
+	for i, exp := range []time.Time{soon, later} {
+		d := models.Deposit{UserID: user.ID, Amount: 50000, OrderID: fmt.Sprintf("DEP-EXP-%d-%d", suffix, i), PaymentMethod: "QRIS", Status: "Pending", ExpiredAt: &exp}
+		if err := tx.Create(&d).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	run := func() int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v3/cron/payment-expiry", nil)
+		req.Header.Set("X-CRON-KEY", "cron-test")
+		h.CronPaymentExpiry(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data struct {
+				Notified int `json:"notified"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data.Notified
+	}
+
+	if n := run(); n < 1 {
+		t.Fatalf("expected the deposit expiring soon to be reminded, got %d", n)
+	}
+	if n := run(); n != 0 {
+		t.Fatalf("expected no reminders on the second run, got %d", n)
+	}
+	var notified int64
+	tx.Model(&models.Deposit{}).Where("user_id = ? AND expiry_notified_at IS NOT NULL", user.ID).Count(&notified)
+	if notified != 1 {
+		t.Fatalf("expected only the deposit expiring soon to be claimed, got %d", notified)
+	}
+	var inbox []models.Notification
+	tx.Where("user_id = ? AND type = ?", user.ID, "payment_expiring").Find(&inbox)
+	if len(inbox) != 1 || !strings.Contains(inbox[0].Body, fmt.Sprintf("DEP-EXP-%d-0", suffix)) {
+		t.Fatalf("expected one inbox reminder for the deposit expiring soon, got %+v", inbox)
+	}
+}
+
+func TestPaymentExpirySkipsSettledPayment(t *testing.T) {
+	tx := testutil.Tx(t)
+	suffix := time.Now().UnixNano() % 1000000000
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Settled"})
+	exp := time.Now().Add(3 * time.Minute)
+	d := models.Deposit{UserID: user.ID, Amount: 50000, OrderID: fmt.Sprintf("DEP-SET-%d", suffix), PaymentMethod: "QRIS", Status: "Pending", ExpiredAt: &exp}
+	if err := tx.Create(&d).Error; err != nil {
+		t.Fatal(err)
+	}
+	selected := []expiringPayment{{ID: d.ID, UserID: user.ID, OrderID: d.OrderID, ExpiredAt: exp}}
+
+	// The deposit is paid after the cron selected it
+	if err := tx.Model(&d).Update("status", "Success").Error; err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v3/cron/payment-expiry", nil)
+	if n := remindExpiring(req, tx, &models.Deposit{}, selected, time.Now(), nil); n != 0 {
+		t.Fatalf("expected no reminder for a settled deposit, got %d", n)
+	}
+	var count int64
+	tx.Model(&models.Notification{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no inbox notification, got %d", count)
+	}
+}
+
+func TestExpiredPurchaseTransactionsFail(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Abandon"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Abandon 1"})
+	// purchase leaves a Pending investment, payment and transaction whose
+	// payment expires at exp
+	purchase := func(exp time.Time) string {
+		inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: product.Amount, DailyProfit: 5000, Duration: 2,
+			OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Pending"}
+		if err := tx.Create(&inv).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Create(&models.Payment{InvestmentID: inv.ID, OrderID: inv.OrderID, Amount: inv.Amount, Status: "Pending", ExpiredAt: &exp}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Create(&models.Transaction{UserID: user.ID, InvestmentID: &inv.ID, Amount: inv.Amount, OrderID: inv.OrderID, TransactionFlow: "credit", TransactionType: "investment", Status: "Pending"}).Error; err != nil {
+			t.Fatal(err)
+		}
+		return inv.OrderID
+	}
+	status := func(orderID string) string {
+		var trx models.Transaction
+		if err := tx.Where("order_id = ?", orderID).First(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+		return trx.Status
+	}
+	stale, open := purchase(time.Now().Add(-time.Hour)), purchase(time.Now().Add(10*time.Minute))
+
+	// The statement leaves the abandoned purchase out before anything is fixed
+	rec := httptest.NewRecorder()
+	GetTransactionHistory(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/transaction?type=investment", nil), user.ID))
+	var statement struct {
+		Data utils.Paginated[transactionDTO] `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &statement); err != nil {
+		t.Fatal(err)
+	}
+	if len(statement.Data.Data) != 1 || statement.Data.Data[0].OrderID != open || statement.Data.Pagination.TotalRows != 1 {
+		t.Fatalf("expected only the payable purchase on the statement, got %s", rec.Body.String())
+	}
+
+	// The backfill fixes the existing stale row
+	rec = httptest.NewRecorder()
+	admins.FailStaleTransactions(rec, httptest.NewRequest(http.MethodPost, "/v3/admin/transactions/fail-stale", nil))
+	var backfill struct {
+		Data struct {
+			Fixed int64 `json:"fixed"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &backfill); err != nil || rec.Code != http.StatusOK || backfill.Data.Fixed < 1 {
+		t.Fatalf("expected the backfill to fix the stale transaction, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if status(stale) != "Failed" || status(open) != "Pending" {
+		t.Fatalf("expected stale Failed and open Pending, got %s / %s", status(stale), status(open))
+	}
+
+	// From then on the payment-expiry cron fails each purchase that lapses
+	lapsed := purchase(time.Now().Add(-time.Minute))
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v3/cron/payment-expiry", nil)
+	req.Header.Set("X-CRON-KEY", "cron-test")
+	NewInvestmentHandler(tx, &testutil.Kyta{}).CronPaymentExpiry(rec, req)
+	if rec.Code != http.StatusOK || status(lapsed) != "Failed" || status(open) != "Pending" {
+		t.Fatalf("expected the cron to fail only the lapsed purchase, got %d (%s / %s): %s", rec.Code, status(lapsed), status(open), rec.Body.String())
+	}
+}
@@ -0,0 +1,179 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/database"
+	"project/kyta"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPaymentFeePassThrough(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	// 2500 flat + 1.5% of 100000 = 4000 on top of the price
+	if err := tx.Where("method = 'BANK' AND code = 'BCA'").Delete(&models.PaymentChannel{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.PaymentChannel{Method: "BANK", Code: "BCA", FeeFlat: 2500, FeePercent: 1.5, PassFee: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	user := testutil.NewUser(t, tx, models.User{Name: "Fee"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Fee 1"})
+
+	gateway := &testutil.Kyta{}
+	h := NewInvestmentHandler(tx, gateway)
+	body := fmt.Sprintf(`{"product_id":%d,"payment_method":"BANK","payment_channel":"BCA"}`, product.ID)
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(gateway.Payments) != 1 || gateway.Payments[0].Amount != 104000 {
+		t.Fatalf("expected one gateway payment of 104000, got %+v", gateway.Payments)
+	}
+	orderID := gateway.Payments[0].ReferenceID
+	var trx models.Transaction
+	if err := tx.Where("order_id = ?", orderID).First(&trx).Error; err != nil {
+		t.Fatal(err)
+	}
+	if trx.Amount != 100000 || trx.Charge != 4000 {
+		t.Fatalf("expected amount 100000 and charge 4000, got %+v", trx)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v3/users/payments/x", nil), map[string]string{"order_id": orderID})
+	rec = httptest.NewRecorder()
+	h.PaymentDetails(rec, req)
+	var details struct {
+		Data struct {
+			Amount      int64 `json:"amount"`
+			Fee         int64 `json:"fee"`
+			GrossAmount int64 `json:"gross_amount"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &details); err != nil {
+		t.Fatal(err)
+	}
+	if details.Data.Amount != 100000 || details.Data.Fee != 4000 || details.Data.GrossAmount != 104000 {
+		t.Fatalf("unexpected fee breakdown: %+v", details.Data)
+	}
+
+	// The gateway reports the gross as paid
+	webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-f","reference_id":%q,"amount":104000,"status":"SUCCESS"}}`, orderID)
+	rec = httptest.NewRecorder()
+	h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("order_id = ?", orderID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "Running" {
+		t.Fatalf("expected Running after the gross payment, got %s", inv.Status)
+	}
+}
+
+// The method list and a purchase evaluate a method the same way: the QRIS
+// cap, the virtual account floor, channels switched off and the gateway.
+func TestPaymentMethods(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	// Without a gateway URL kyta.Status never reports it down
+	t.Setenv("KYTAPAY_BASE_URL", "")
+
+	if err := tx.Where("1 = 1").Delete(&models.PaymentChannel{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	channels := []models.PaymentChannel{
+		{Method: "QRIS", Code: "QRIS", FeePercent: 1, PassFee: true},
+		{Method: "BANK", Code: "BRI", Disabled: true},
+	}
+	if err := tx.Create(&channels).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	methods := func(amount int64) map[string]PaymentOption {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		PaymentMethodsHandler(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/users/payment/methods?amount=%d", amount), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("methods: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data []PaymentOption `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		byChannel := map[string]PaymentOption{}
+		for _, o := range resp.Data {
+			byChannel[o.Channel] = o
+		}
+		return byChannel
+	}
+
+	// 1% of 9,950,000 pushes QRIS over its 10,000,000 cap
+	got := methods(9950000)
+	if q := got["QRIS"]; q.Available || q.Reason != "over_max" || q.GrossAmount != 10049500 || q.Message == "" {
+		t.Fatalf("qris over cap: got %+v", q)
+	}
+	if b := got["BCA"]; !b.Available || b.Fee != 0 {
+		t.Fatalf("bca: expected available without a fee, got %+v", b)
+	}
+	if b := got["BRI"]; b.Available || b.Reason != "channel_disabled" {
+		t.Fatalf("bri: expected disabled, got %+v", b)
+	}
+	got = methods(5000)
+	if q, b := got["QRIS"], got["BCA"]; !q.Available || q.Fee != 50 || b.Available || b.Reason != "under_min" {
+		t.Fatalf("small amount: expected QRIS only, got %+v and %+v", q, b)
+	}
+	rec := httptest.NewRecorder()
+	PaymentMethodsHandler(rec, httptest.NewRequest(http.MethodGet, "/v3/users/payment/methods?amount=abc", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad amount: expected 400, got %d", rec.Code)
+	}
+
+	// A degraded gateway takes every gateway method down, but not MANUAL
+	if o := evaluatePayment("BANK", "BCA", nil, 100000, kyta.StatusDown); o.Available || o.Reason != "gateway_degraded" {
+		t.Fatalf("degraded: got %+v", o)
+	}
+	if o := evaluatePayment("MANUAL", "", nil, 100000, kyta.StatusDown); !o.Available {
+		t.Fatalf("manual while degraded: got %+v", o)
+	}
+
+	// The purchase refuses what the list marks unavailable
+	user := testutil.NewUser(t, tx, models.User{Name: "Metode"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Metode 1", Amount: 9950000})
+	gateway := &testutil.Kyta{}
+	h := NewInvestmentHandler(tx, gateway)
+	for _, tc := range []struct {
+		body string
+		code utils.ErrorCode
+	}{
+		{fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID), utils.CodePaymentAmountOutOfRange},
+		{fmt.Sprintf(`{"product_id":%d,"payment_method":"BANK","payment_channel":"BRI"}`, product.ID), utils.CodePaymentChannelDisabled},
+	} {
+		rec := httptest.NewRecorder()
+		h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(tc.body)), user.ID))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), string(tc.code)) {
+			t.Fatalf("%s: expected 400 %s, got %d: %s", tc.body, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+	if len(gateway.Payments) != 0 {
+		t.Fatalf("expected no gateway payment, got %+v", gateway.Payments)
+	}
+}
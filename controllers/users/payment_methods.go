@@ -0,0 +1,177 @@
+package users
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"project/database"
+	"project/i18n"
+	"project/kyta"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// vaChannels are the banks KytaPay issues virtual accounts for, in the order
+// the app lists them.
+var vaChannels = []string{"BCA", "BRI", "BNI", "MANDIRI", "PERMATA", "BNC"}
+
+func vaChannel(code string) bool {
+	for _, c := range vaChannels {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Why a payment option is unavailable, in PaymentOption.Reason.
+const (
+	paymentOverMax         = "over_max"
+	paymentUnderMin        = "under_min"
+	paymentChannelDisabled = "channel_disabled"
+	paymentGatewayDegraded = "gateway_degraded"
+)
+
+// PaymentOption is whether an amount can be paid through one method and
+// channel, and what the buyer pays. Min and Max are the method's bounds, 0
+// for none on that side; Reason and Message say why it is unavailable.
+type PaymentOption struct {
+	Method      string `json:"method"`
+	Channel     string `json:"channel"`
+	Available   bool   `json:"available"`
+	Fee         int64  `json:"fee"`
+	GrossAmount int64  `json:"gross_amount"`
+	Min         int64  `json:"min"`
+	Max         int64  `json:"max"`
+	Reason      string `json:"reason,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+// evaluatePayment decides whether amount can be paid through method and
+// channel. ch is the channel's payment_channels row, nil when it has none,
+// and gateway the last kyta.Status. Purchases and the method list both go
+// through it, so what the app offers is what a purchase accepts.
+func evaluatePayment(method, channel string, ch *models.PaymentChannel, amount int64, gateway string) PaymentOption {
+	opt := PaymentOption{Method: method, Channel: channel}
+	if ch != nil {
+		opt.Fee = ch.BuyerFee(amount)
+	}
+	opt.GrossAmount = amount + opt.Fee
+	switch method {
+	case "QRIS":
+		opt.Max = qrisMaxAmount
+	case "BANK":
+		opt.Min = bankMinAmount
+	}
+	switch {
+	case ch != nil && ch.Disabled:
+		opt.Reason = paymentChannelDisabled
+	case method != "MANUAL" && gateway == kyta.StatusDown:
+		opt.Reason = paymentGatewayDegraded
+	case method == "QRIS" && opt.GrossAmount > qrisMaxAmount:
+		opt.Reason = paymentOverMax
+	case method == "BANK" && amount < bankMinAmount:
+		opt.Reason = paymentUnderMin
+	default:
+		opt.Available = true
+	}
+	return opt
+}
+
+// paymentOption evaluates one method and channel for a payment of amount.
+func paymentOption(db *gorm.DB, method, channel string, amount int64) (PaymentOption, error) {
+	if method == "QRIS" {
+		channel = "QRIS"
+	}
+	var ch models.PaymentChannel
+	row := &ch
+	if err := db.Where("method = ? AND code = ?", method, channel).First(&ch).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		row = nil
+	} else if err != nil {
+		return PaymentOption{}, err
+	}
+	return evaluatePayment(method, channel, row, amount, kyta.Status()), nil
+}
+
+// writePaymentUnavailable refuses a payment through an unavailable option.
+func writePaymentUnavailable(w http.ResponseWriter, r *http.Request, opt PaymentOption) {
+	status := http.StatusBadRequest
+	resp := utils.APIResponse{Success: false, Message: paymentUnavailableMessage(r, opt)}
+	switch opt.Reason {
+	case paymentOverMax, paymentUnderMin:
+		resp.Code = utils.CodePaymentAmountOutOfRange
+		resp.Details = PaymentAmountDetails{Method: opt.Method, Min: opt.Min, Max: opt.Max}
+	case paymentChannelDisabled:
+		resp.Code = utils.CodePaymentChannelDisabled
+	default:
+		status = http.StatusServiceUnavailable
+		resp.Code = utils.CodePaymentGatewayDegraded
+	}
+	utils.WriteJSON(w, status, resp)
+}
+
+// paymentUnavailableMessage is why opt is unavailable, in the caller's
+// language.
+func paymentUnavailableMessage(r *http.Request, opt PaymentOption) string {
+	switch opt.Reason {
+	case paymentOverMax:
+		return utils.T(r, i18n.MsgPaymentQRISMax)
+	case paymentUnderMin:
+		return utils.T(r, i18n.MsgPaymentBankMin)
+	case paymentChannelDisabled:
+		return utils.T(r, string(utils.CodePaymentChannelDisabled))
+	default:
+		return utils.T(r, string(utils.CodePaymentGatewayDegraded))
+	}
+}
+
+// GET /api/users/payment/methods?amount=
+// Lists QRIS and every virtual account bank with whether amount can be paid
+// through it, the fee and gross the buyer would pay, and the reason when it
+// cannot. MANUAL is listed while admins have it switched on. A purchase
+// through an unavailable option is refused for the same reason.
+func PaymentMethodsHandler(w http.ResponseWriter, r *http.Request) {
+	amount, err := strconv.ParseInt(strings.TrimSpace(r.URL.Query().Get("amount")), 10, 64)
+	// amount times a fee in basis points must fit in int64
+	if err != nil || amount <= 0 || amount > math.MaxInt64/10000 {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeValidationFailed)
+		return
+	}
+
+	db, cancel := database.WithTimeout(r.Context(), database.DB)
+	defer cancel()
+	var rows []models.PaymentChannel
+	if err := db.Find(&rows).Error; err != nil {
+		utils.LogError(r, "PaymentMethodsHandler", err)
+		if utils.WriteDBTimeout(w, r, err) {
+			return
+		}
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	channels := make(map[string]*models.PaymentChannel, len(rows))
+	for i := range rows {
+		channels[rows[i].Method+"/"+rows[i].Code] = &rows[i]
+	}
+
+	gateway := kyta.Status()
+	options := make([]PaymentOption, 0, len(vaChannels)+2)
+	options = append(options, evaluatePayment("QRIS", "QRIS", channels["QRIS/QRIS"], amount, gateway))
+	for _, code := range vaChannels {
+		options = append(options, evaluatePayment("BANK", code, channels["BANK/"+code], amount, gateway))
+	}
+	if setting, err := models.GetCachedSetting(db); err == nil && setting.ManualPaymentAvailable() {
+		options = append(options, evaluatePayment("MANUAL", "", nil, amount, gateway))
+	}
+	for i := range options {
+		if !options[i].Available {
+			options[i].Message = paymentUnavailableMessage(r, options[i])
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: options})
+}
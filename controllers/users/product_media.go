@@ -0,0 +1,39 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// InvestmentProduct is how the product of an investment is presented now:
+// its image, description, highlights and badge. The figures of the
+// investment come from its own snapshot, never from here.
+type InvestmentProduct struct {
+	ImageURL    string                   `json:"image_url"`
+	Description *string                  `json:"description"`
+	Highlights  models.ProductHighlights `json:"highlights"`
+	Badge       string                   `json:"badge"`
+}
+
+// investmentProduct loads the presentation of productID whatever its status.
+// It returns nil when the product row is gone or cannot be read, so an
+// investment detail always renders.
+func investmentProduct(r *http.Request, db *gorm.DB, productID uint) *InvestmentProduct {
+	var p models.Product
+	if err := db.Select("id, image, description, highlights, badge").First(&p, productID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.LogError(r, "investment product", err, "product_id", productID)
+		}
+		return nil
+	}
+	imageURL, err := utils.StoredImageURL(p.Image, utils.ImageURLExpiry)
+	if err != nil {
+		utils.LogError(r, "investment product: image url", err, "product_id", productID)
+	}
+	return &InvestmentProduct{ImageURL: imageURL, Description: p.Description, Highlights: p.Highlights, Badge: p.Badge}
+}
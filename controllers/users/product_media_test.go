@@ -0,0 +1,97 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/controllers"
+	"project/database"
+	"project/models"
+	"project/testutil"
+
+	"github.com/gorilla/mux"
+)
+
+func TestProductMediaInListAndInvestmentDetail(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Media"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	description := "Profit harian selama 30 hari"
+	product := models.Product{
+		CategoryID: category.ID, Name: "Media 1", Amount: 100000, DailyProfit: 5000, Duration: 30, Status: "Active",
+		Image:       "https://cdn.example.com/products/media-1.png",
+		Description: &description,
+		Highlights:  models.ProductHighlights{"Modal kembali", "Profit harian"},
+		Badge:       "Baru",
+	}
+	if err := tx.Create(&product).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	controllers.ProductListHandler(rec, httptest.NewRequest(http.MethodGet, "/v3/products", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("products: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var list struct {
+		Data map[string][]models.Product `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	listed := list.Data[category.Name]
+	if len(listed) != 1 || listed[0].ImageURL != product.Image || listed[0].Badge != "Baru" || len(listed[0].Highlights) != 2 ||
+		listed[0].Description == nil || *listed[0].Description != description {
+		t.Fatalf("expected the product media in the list, got %+v", listed)
+	}
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	rec = httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	detail := func() InvestmentResponse {
+		t.Helper()
+		req := testutil.AsUser(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/users/investments/%d", inv.ID), nil), user.ID)
+		req = mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(inv.ID)})
+		rec := httptest.NewRecorder()
+		h.Get(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("detail: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data InvestmentResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+	got := detail()
+	if got.Product == nil || got.Product.ImageURL != product.Image || got.Product.Badge != "Baru" || len(got.Product.Highlights) != 2 {
+		t.Fatalf("expected the product media on the investment detail, got %+v", got.Product)
+	}
+
+	// The detail still renders from the investment's own snapshot once the
+	// product row is gone
+	if err := tx.Exec("DELETE FROM products WHERE id = ?", product.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := detail(); got.Product != nil || got.ProductName == "" {
+		t.Fatalf("expected the detail without product media, got %+v", got)
+	}
+}
@@ -0,0 +1,122 @@
+package users
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"project/i18n"
+	"project/models"
+	"project/money"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Payout schedules of a projection.
+const (
+	// ScheduleDaily pays the profit to the balance every day and the
+	// principal with the last return (unlocked categories)
+	ScheduleDaily = "daily"
+	// ScheduleCompletion pays the whole profit and the principal at once
+	// when the investment ends (locked categories)
+	ScheduleCompletion = "completion"
+)
+
+// ProjectedPayout is one credit to the balance, Day days after payment.
+type ProjectedPayout struct {
+	Day       int   `json:"day"`
+	Profit    int64 `json:"profit"`
+	Principal int64 `json:"principal"`
+	Amount    int64 `json:"amount"`
+}
+
+// Projection is what investing Amount in a product pays, as the daily
+// returns cron will credit it.
+type Projection struct {
+	ProductID   uint    `json:"product_id"`
+	Amount      int64   `json:"amount"`
+	Duration    int     `json:"duration"`
+	DailyProfit int64   `json:"daily_profit"`
+	TotalProfit int64   `json:"total_profit"`
+	TotalReturn int64   `json:"total_return"`
+	ROIPercent  float64 `json:"roi_percent"`
+	Schedule    string  `json:"schedule"`
+	// Payouts are the credits in order; one for ScheduleCompletion
+	Payouts []ProjectedPayout `json:"payouts"`
+}
+
+// projectReturns projects amount invested in p. The daily profit is p's
+// rescaled to amount with money.Scale, as a top-up rescales it, and the
+// profit adds up day by day as the cron credits it.
+func projectReturns(p *models.Product, amount int64, locked bool) Projection {
+	daily := money.Scale(p.DailyProfit, amount, p.Amount)
+	total := money.Total(daily, p.Duration)
+	proj := Projection{
+		ProductID:   p.ID,
+		Amount:      amount,
+		Duration:    p.Duration,
+		DailyProfit: daily,
+		TotalProfit: total,
+		TotalReturn: amount + total,
+		ROIPercent:  math.Round(float64(total)*10000/float64(amount)) / 100,
+		Schedule:    ScheduleDaily,
+	}
+	if locked {
+		proj.Schedule = ScheduleCompletion
+		proj.Payouts = []ProjectedPayout{{Day: p.Duration, Profit: total, Principal: amount, Amount: amount + total}}
+		return proj
+	}
+	proj.Payouts = make([]ProjectedPayout, p.Duration)
+	for day := 1; day <= p.Duration; day++ {
+		payout := ProjectedPayout{Day: day, Profit: daily, Amount: daily}
+		if day == p.Duration {
+			payout.Principal = amount
+			payout.Amount += amount
+		}
+		proj.Payouts[day-1] = payout
+	}
+	return proj
+}
+
+// GET /api/users/products/{id}/projection?amount=
+// What investing amount (default the product's price) in an active product
+// pays: the daily and total profit, the total returned with the principal,
+// the ROI and the payouts, rounded as the daily returns cron credits them.
+func (h *InvestmentHandler) Projection(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvalidID)})
+		return
+	}
+	var product models.Product
+	if err := h.DB.Preload("Category").Where("id = ? AND status = 'Active'", id).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeProductNotFound)
+			return
+		}
+		utils.LogError(r, "ProductProjection", err, "product_id", id)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	if product.Category == nil || product.Amount <= 0 || product.Duration <= 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentCategoryInvalid)})
+		return
+	}
+
+	amount := product.Amount
+	if s := strings.TrimSpace(r.URL.Query().Get("amount")); s != "" {
+		amount, err = strconv.ParseInt(s, 10, 64)
+		// amount*daily_profit and the total must fit in int64
+		if err != nil || amount <= 0 || (product.DailyProfit > 0 && amount > math.MaxInt64/product.DailyProfit/int64(product.Duration)) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvestmentAmountInvalid)})
+			return
+		}
+	}
+
+	proj := projectReturns(&product, amount, product.Category.ProfitType == "locked")
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: proj})
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// The projection matches, to the rupiah, what the daily returns cron credits
+// for an investment of the projected amount, on both schedules.
+func TestProductProjectionMatchesCron(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+
+	for _, profitType := range []string{"unlocked", "locked"} {
+		user := testutil.NewUser(t, tx, models.User{Name: "Projection"})
+		category := testutil.NewCategory(t, tx, profitType)
+		product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Projection", Amount: 300000, DailyProfit: 7777, Duration: 3})
+
+		get := func(query string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/users/products/%d/projection%s", product.ID, query), nil)
+			rec := httptest.NewRecorder()
+			h.Projection(rec, testutil.AsUser(mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(product.ID)}), user.ID))
+			return rec
+		}
+		if rec := get("?amount=-5"); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: negative amount: expected 400, got %d", profitType, rec.Code)
+		}
+		if rec := get(""); rec.Code != http.StatusOK {
+			t.Fatalf("%s: default amount: expected 200, got %d: %s", profitType, rec.Code, rec.Body.String())
+		}
+		rec := get("?amount=100000")
+		var resp struct {
+			Data Projection `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		proj := resp.Data
+		// 7777 per 300000 is 2592.33 per 100000
+		if proj.DailyProfit != 2592 || proj.TotalProfit != 7776 || proj.TotalReturn != 107776 || proj.ROIPercent != 7.78 {
+			t.Fatalf("%s: unexpected projection %+v", profitType, proj)
+		}
+		if want := map[string]int{"unlocked": 3, "locked": 1}[profitType]; len(proj.Payouts) != want {
+			t.Fatalf("%s: expected %d payouts, got %+v", profitType, want, proj.Payouts)
+		}
+
+		// An investment of the same amount at the same rate, run to completion
+		next := time.Now().Add(-time.Minute)
+		inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: proj.Amount, DailyProfit: proj.DailyProfit, Duration: product.Duration,
+			NextReturnAt: &next, OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Running"}
+		if err := tx.Create(&inv).Error; err != nil {
+			t.Fatal(err)
+		}
+		var balance int64
+		credited := []int64{}
+		for day := 1; day <= product.Duration; day++ {
+			if err := tx.Model(&models.Investment{}).Where("id = ?", inv.ID).Update("next_return_at", time.Now().Add(-time.Minute)).Error; err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/v3/cron/daily-returns", nil)
+			req.Header.Set("X-CRON-KEY", "cron-test")
+			rec := httptest.NewRecorder()
+			h.CronDailyReturns(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: cron day %d: expected 200, got %d: %s", profitType, day, rec.Code, rec.Body.String())
+			}
+			var u models.User
+			if err := tx.First(&u, user.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if u.Balance != balance {
+				credited = append(credited, u.Balance-balance)
+				balance = u.Balance
+			}
+		}
+		if balance != proj.TotalReturn {
+			t.Fatalf("%s: cron credited %d, projected %d", profitType, balance, proj.TotalReturn)
+		}
+		for i, p := range proj.Payouts {
+			if i >= len(credited) || credited[i] != p.Amount {
+				t.Fatalf("%s: credits %v do not match payouts %+v", profitType, credited, proj.Payouts)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+// Boosts apply by each payout's scheduled date: one ending mid-term pays the
+// payouts due before its end only, as separate profit_boost transactions.
+// Locked categories collect them until completion.
+func TestProfitBoostPerPayoutDate(t *testing.T) {
+	tx := testutil.Tx(t)
+	t.Setenv("CRON_KEY", "cron-test")
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Boost"})
+	unlocked := testutil.NewCategory(t, tx, "unlocked")
+	locked := testutil.NewCategory(t, tx, "locked")
+	daily := testutil.NewProduct(t, tx, models.Product{CategoryID: unlocked.ID, Name: "Neura 1"})
+	monitor := testutil.NewProduct(t, tx, models.Product{CategoryID: locked.ID, Name: "Monitor 1", Amount: 200000, DailyProfit: 8000})
+	plain := testutil.NewProduct(t, tx, models.Product{Name: "Lain 1"})
+
+	// Day 1 is due before the boosts end, day 2 after
+	now := time.Now()
+	endsAt := now.Add(-time.Hour)
+	day1 := endsAt.Add(-time.Hour)
+	boosts := []models.ProfitBoost{
+		{Name: "Promo Neura", CategoryID: &unlocked.ID, ExtraPercent: 1, StartsAt: now.AddDate(0, 0, -7), EndsAt: endsAt, Status: "Active"},
+		{Name: "Promo Monitor", ProductID: &monitor.ID, ExtraPercent: 0.5, StartsAt: now.AddDate(0, 0, -7), EndsAt: endsAt, Status: "Active"},
+	}
+	for i := range boosts {
+		if err := tx.Create(&boosts[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	invs := make([]models.Investment, 0, 3)
+	for _, p := range []models.Product{daily, monitor, plain} {
+		inv := models.Investment{UserID: user.ID, ProductID: p.ID, CategoryID: p.CategoryID, ProductName: p.Name, Amount: p.Amount, DailyProfit: p.DailyProfit, Duration: p.Duration,
+			NextReturnAt: &day1, OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Running"}
+		if err := tx.Create(&inv).Error; err != nil {
+			t.Fatal(err)
+		}
+		invs = append(invs, inv)
+	}
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	run := func(day int) {
+		req := httptest.NewRequest(http.MethodPost, "/v3/cron/daily-returns", nil)
+		req.Header.Set("X-CRON-KEY", "cron-test")
+		rec := httptest.NewRecorder()
+		h.CronDailyReturns(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cron day %d: expected 200, got %d: %s", day, rec.Code, rec.Body.String())
+		}
+	}
+	boostPaid := func(inv models.Investment) int64 {
+		var total int64
+		if err := tx.Model(&models.Transaction{}).Where("investment_id = ? AND transaction_type = ? AND status = ?", inv.ID, "profit_boost", "Success").
+			Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+
+	run(1)
+	if got := boostPaid(invs[0]); got != 1000 {
+		t.Fatalf("unlocked day 1: expected boost 1000, got %d", got)
+	}
+	if got := boostPaid(invs[1]); got != 0 {
+		t.Fatalf("locked day 1: boost must wait for completion, got %d", got)
+	}
+
+	if err := tx.Model(&models.Investment{}).Where("user_id = ?", user.ID).Update("next_return_at", now.Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	run(2)
+	if got := boostPaid(invs[0]); got != 1000 {
+		t.Fatalf("unlocked day 2 is past the boost's end: expected boost to stay 1000, got %d", got)
+	}
+	if got := boostPaid(invs[1]); got != 1000 {
+		t.Fatalf("locked completion: expected the day 1 boost of 1000, got %d", got)
+	}
+	if got := boostPaid(invs[2]); got != 0 {
+		t.Fatalf("other category: expected no boost, got %d", got)
+	}
+	var done models.Investment
+	if err := tx.First(&done, invs[1].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != "Completed" || done.TotalBoost != 1000 || done.TotalReturned != 16000 {
+		t.Fatalf("unexpected locked investment: %+v", done)
+	}
+	var investor models.User
+	if err := tx.First(&investor, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Principal, profit and boosts of all three
+	if want := int64(100000 + 10000 + 1000 + 200000 + 16000 + 1000 + 100000 + 10000); investor.Balance != want {
+		t.Fatalf("expected balance %d, got %d", want, investor.Balance)
+	}
+}
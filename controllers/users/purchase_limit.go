@@ -0,0 +1,121 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errPurchaseLimitReached aborts a purchase transaction that found the limit
+// taken by a purchase made in parallel.
+var errPurchaseLimitReached = errors.New("purchase limit reached")
+
+// errPurchaseCooldown aborts a purchase transaction that found an investment
+// in the product created in parallel within its cooldown.
+var errPurchaseCooldown = errors.New("purchase cooldown")
+
+// purchaseCount counts uid's purchases of productID towards its purchase
+// limit: paid investments, archived ones included, and those still awaiting a
+// payment that has not expired, so a second purchase cannot be started while
+// the first is being paid.
+func purchaseCount(db *gorm.DB, uid, productID uint, now time.Time) (int64, error) {
+	var n int64
+	err := db.Unscoped().Model(&models.Investment{}).
+		Where("user_id = ? AND product_id = ?", uid, productID).
+		Where("status IN ? OR (status = ? AND EXISTS (SELECT 1 FROM payments p WHERE p.investment_id = investments.id AND p.status = ? AND p.deleted_at IS NULL AND (p.expired_at IS NULL OR p.expired_at > ?)))",
+			[]string{"Running", "Completed", "Suspended"}, "Pending", "Pending", now).
+		Count(&n).Error
+	return n, err
+}
+
+// purchaseCooldown returns when uid last created a non-cancelled investment
+// in product and whether product's cooldown since then is still running at
+// now. It reports false for a product without a cooldown.
+func purchaseCooldown(db *gorm.DB, uid uint, product *models.Product, now time.Time) (time.Time, bool, error) {
+	if product.PurchaseCooldownHours <= 0 {
+		return time.Time{}, false, nil
+	}
+	last, err := models.LastPurchases(db, uid, []uint{product.ID})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	at, ok := last[product.ID]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	return at, now.Before(*product.NextPurchaseAt(at)), nil
+}
+
+// lockUserPurchases locks uid's user row, serializing the purchase limit
+// checks of parallel purchases and payments. It must run inside tx.
+func lockUserPurchases(tx *gorm.DB, uid uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, uid).Error
+}
+
+// overPurchaseLimit reports whether paying inv would take its owner past the
+// product's purchase limit, counting only paid investments. It is the
+// backstop for purchases that got past the checks at creation, and runs
+// inside the webhook transaction after lockUserPurchases.
+func overPurchaseLimit(tx *gorm.DB, inv *models.Investment) (bool, error) {
+	var limit int
+	if err := tx.Model(&models.Product{}).Select("purchase_limit").Where("id = ?", inv.ProductID).Scan(&limit).Error; err != nil {
+		return false, err
+	}
+	if limit <= 0 {
+		return false, nil
+	}
+	var paid int64
+	if err := tx.Unscoped().Model(&models.Investment{}).
+		Where("user_id = ? AND product_id = ? AND id <> ? AND status IN ?", inv.UserID, inv.ProductID, inv.ID, []string{"Running", "Completed", "Suspended"}).
+		Count(&paid).Error; err != nil {
+		return false, err
+	}
+	return paid >= int64(limit), nil
+}
+
+// refundOverLimit cancels inv, whose payment of received arrived after the
+// purchase limit was used up, and credits the payment to the balance up to
+// its gross; anything paid beyond that is held like an overpayment. It
+// returns the amount credited.
+func refundOverLimit(tx *gorm.DB, inv *models.Investment, payment *models.Payment, received int64, paymentID string) (int64, error) {
+	updates := map[string]interface{}{"status": "Refunded", "amount_received": received}
+	if paymentID != "" {
+		updates["gateway_payment_id"] = paymentID
+	}
+	if err := tx.Model(payment).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(inv).Update("status", "Cancelled").Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Update("status", "Failed").Error; err != nil {
+		return 0, err
+	}
+	refund := received
+	if gross := payment.Gross(inv.Amount); refund > gross {
+		if err := holdOverpayment(tx, inv, refund-gross); err != nil {
+			return 0, err
+		}
+		refund = gross
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).UpdateColumn("balance", gorm.Expr("balance + ?", refund)).Error; err != nil {
+		return 0, err
+	}
+	msg := fmt.Sprintf("Pengembalian pembayaran %s: batas pembelian produk tercapai", inv.OrderID)
+	return refund, tx.Create(&models.Transaction{
+		UserID:          inv.UserID,
+		InvestmentID:    &inv.ID,
+		Amount:          refund,
+		OrderID:         utils.GenerateOrderID(utils.OrderRefund, inv.UserID),
+		TransactionFlow: "debit",
+		TransactionType: "refund",
+		Message:         &msg,
+		Status:          "Success",
+	}).Error
+}
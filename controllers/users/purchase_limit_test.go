@@ -0,0 +1,168 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestParallelPurchasesRespectLimit(t *testing.T) {
+	// Parallel requests need their own connections, so no wrapping transaction
+	db := testutil.DB(t)
+
+	user := testutil.NewUser(t, db, models.User{Name: "Borong"})
+	category := testutil.NewCategory(t, db, "unlocked")
+	product := testutil.NewProduct(t, db, models.Product{CategoryID: category.ID, Name: "Limit 1", PurchaseLimit: 1})
+	t.Cleanup(func() {
+		db.Unscoped().Where("investment_id IN (?)", db.Unscoped().Model(&models.Investment{}).Select("id").Where("user_id = ?", user.ID)).Delete(&models.Payment{})
+		db.Where("user_id = ?", user.ID).Delete(&models.Transaction{})
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Investment{})
+		db.Delete(&product)
+		db.Delete(&category)
+		db.Delete(&user)
+	})
+
+	h := NewInvestmentHandler(db, &testutil.Kyta{})
+	const parallel = 5
+	codes := make([]int, parallel)
+	bodies := make([]string, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+			codes[i], bodies[i] = rec.Code, rec.Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusBadRequest:
+			var resp utils.APIResponse
+			if err := json.Unmarshal([]byte(bodies[i]), &resp); err != nil || resp.Code != utils.CodePurchaseLimitReached {
+				t.Fatalf("expected PURCHASE_LIMIT_REACHED, got %s", bodies[i])
+			}
+		default:
+			t.Fatalf("unexpected %d: %s", code, bodies[i])
+		}
+	}
+	var investments int64
+	db.Model(&models.Investment{}).Where("user_id = ? AND product_id = ?", user.ID, product.ID).Count(&investments)
+	if created != 1 || investments != 1 {
+		t.Fatalf("expected exactly one purchase, got %d created and %d stored", created, investments)
+	}
+}
+
+func TestPaymentOverPurchaseLimitIsRefunded(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Lewat"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Refund 1", PurchaseLimit: 2})
+
+	// Two purchases while the limit was 2, then an admin lowers it to 1
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("purchase %d: expected 201, got %d: %s", i+1, rec.Code, rec.Body.String())
+		}
+	}
+	if err := tx.Model(&product).Update("purchase_limit", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var invs []models.Investment
+	if err := tx.Where("user_id = ?", user.ID).Order("id ASC").Find(&invs).Error; err != nil || len(invs) != 2 {
+		t.Fatalf("expected two investments, got %d (%v)", len(invs), err)
+	}
+	for i, want := range []string{"OK", "Refunded"} {
+		webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-%d","reference_id":%q,"amount":%d,"status":"SUCCESS"}}`, i, invs[i].OrderID, invs[i].Amount)
+		rec := httptest.NewRecorder()
+		h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+		var resp utils.APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK || resp.Message != want {
+			t.Fatalf("webhook %d: expected 200 %s, got %d: %s", i+1, want, rec.Code, rec.Body.String())
+		}
+	}
+
+	var second models.Investment
+	if err := tx.First(&second, invs[1].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var payment models.Payment
+	if err := tx.Where("investment_id = ?", second.ID).First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if second.Status != "Cancelled" || payment.Status != "Refunded" {
+		t.Fatalf("expected the second purchase cancelled and refunded, got %s / %s", second.Status, payment.Status)
+	}
+	if err := tx.First(&user, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Balance != second.Amount {
+		t.Fatalf("expected the payment of %d credited, balance %d", second.Amount, user.Balance)
+	}
+}
+
+func TestPurchaseCooldown(t *testing.T) {
+	tx := testutil.Tx(t)
+	user := testutil.NewUser(t, tx, models.User{Name: "Sabar"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Harian", PurchaseCooldownHours: 24})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	buy := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+		return rec
+	}
+	if rec := buy(); rec.Code != http.StatusCreated {
+		t.Fatalf("first purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The unpaid attempt starts the clock
+	rec := buy()
+	var resp struct {
+		Code    utils.ErrorCode         `json:"code"`
+		Details PurchaseCooldownDetails `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || resp.Code != utils.CodePurchaseCooldown || resp.Details.CooldownHours != 24 || resp.Details.NextPurchaseAt == "" {
+		t.Fatalf("second purchase: expected PURCHASE_COOLDOWN, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A cancelled attempt does not count
+	if err := tx.Model(&models.Investment{}).Where("user_id = ?", user.ID).Update("status", "Cancelled").Error; err != nil {
+		t.Fatal(err)
+	}
+	if rec := buy(); rec.Code != http.StatusCreated {
+		t.Fatalf("after cancel: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Nor does one created before the cooldown
+	if err := tx.Model(&models.Investment{}).Where("user_id = ?", user.ID).Update("created_at", time.Now().Add(-25*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rec := buy(); rec.Code != http.StatusCreated {
+		t.Fatalf("after cooldown: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,74 @@
+package users
+
+import (
+	"net/http"
+	"time"
+
+	"project/models"
+	"project/utils"
+)
+
+// Gateway bounds: QRIS takes at most qrisMaxAmount gross, virtual accounts
+// at least bankMinAmount before fees.
+const (
+	qrisMaxAmount int64 = 10000000
+	bankMinAmount int64 = 10000
+)
+
+// VIPRequiredDetails are the details of a VIP_REQUIRED refusal.
+type VIPRequiredDetails struct {
+	RequiredLevel uint `json:"required_level"`
+	CurrentLevel  uint `json:"current_level"`
+}
+
+// PurchaseLimitDetails are the details of a PURCHASE_LIMIT_REACHED refusal.
+// Used counts paid purchases and those awaiting payment.
+type PurchaseLimitDetails struct {
+	Limit int   `json:"limit"`
+	Used  int64 `json:"used"`
+}
+
+// PurchaseCooldownDetails are the details of a PURCHASE_COOLDOWN refusal:
+// the last investment in the product was created at LastPurchaseAt.
+type PurchaseCooldownDetails struct {
+	CooldownHours  int    `json:"cooldown_hours"`
+	LastPurchaseAt string `json:"last_purchase_at"`
+	NextPurchaseAt string `json:"next_purchase_at"`
+}
+
+// PaymentAmountDetails are the details of a PAYMENT_AMOUNT_OUT_OF_RANGE
+// refusal; a bound of 0 means the method has none on that side.
+type PaymentAmountDetails struct {
+	Method string `json:"method"`
+	Min    int64  `json:"min"`
+	Max    int64  `json:"max"`
+}
+
+// purchaseRefusal is why a purchase is refused: the code, the arguments of
+// its message and the details the app renders.
+type purchaseRefusal struct {
+	Code    utils.ErrorCode
+	Args    []interface{}
+	Details interface{}
+}
+
+func (p *purchaseRefusal) write(w http.ResponseWriter, r *http.Request) {
+	utils.WriteErrorDetails(w, r, http.StatusBadRequest, p.Code, p.Details, p.Args...)
+}
+
+func purchaseLimitRefusal(product *models.Product, used int64) *purchaseRefusal {
+	return &purchaseRefusal{
+		Code:    utils.CodePurchaseLimitReached,
+		Args:    []interface{}{product.Name, product.PurchaseLimit},
+		Details: PurchaseLimitDetails{Limit: product.PurchaseLimit, Used: used},
+	}
+}
+
+func purchaseCooldownRefusal(product *models.Product, last time.Time) *purchaseRefusal {
+	next := product.NextPurchaseAt(last)
+	return &purchaseRefusal{
+		Code:    utils.CodePurchaseCooldown,
+		Args:    []interface{}{product.Name, utils.FormatTime(*next)},
+		Details: PurchaseCooldownDetails{CooldownHours: product.PurchaseCooldownHours, LastPurchaseAt: utils.FormatTime(last), NextPurchaseAt: utils.FormatTime(*next)},
+	}
+}
@@ -0,0 +1,73 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestCreateInvestmentRefusalDetails(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	user := testutil.NewUser(t, tx, models.User{Name: "Ditolak"})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	newProduct := func(p models.Product) models.Product {
+		p.CategoryID, p.DailyProfit, p.Duration, p.Status = category.ID, 5000, 2, "Active"
+		if p.Amount == 0 {
+			p.Amount = 100000
+		}
+		if err := tx.Create(&p).Error; err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	vipOnly := newProduct(models.Product{Name: "VIP 2", RequiredVIP: 2})
+	limited := newProduct(models.Product{Name: "Sekali", PurchaseLimit: 1})
+	large := newProduct(models.Product{Name: "Besar", Amount: 20000000})
+	small := newProduct(models.Product{Name: "Kecil", Amount: 5000})
+
+	held := models.Investment{UserID: user.ID, ProductID: limited.ID, CategoryID: category.ID, ProductName: limited.Name, Amount: limited.Amount, DailyProfit: 5000, Duration: 2,
+		OrderID: utils.GenerateOrderID(utils.OrderInvestment, user.ID), Status: "Running"}
+	if err := tx.Create(&held).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	cases := []struct {
+		name    string
+		body    string
+		code    utils.ErrorCode
+		details string
+	}{
+		{"vip", fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, vipOnly.ID), utils.CodeVIPRequired, `{"required_level":2,"current_level":0}`},
+		{"purchase limit", fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, limited.ID), utils.CodePurchaseLimitReached, `{"limit":1,"used":1}`},
+		{"qris max", fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, large.ID), utils.CodePaymentAmountOutOfRange, `{"method":"QRIS","min":0,"max":10000000}`},
+		{"bank min", fmt.Sprintf(`{"product_id":%d,"payment_method":"BANK","payment_channel":"BCA"}`, small.ID), utils.CodePaymentAmountOutOfRange, `{"method":"BANK","min":10000,"max":0}`},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(c.body)), user.ID))
+		var resp struct {
+			Code    utils.ErrorCode `json:"code"`
+			Message string          `json:"message"`
+			Details json.RawMessage `json:"details"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if rec.Code != http.StatusBadRequest || resp.Code != c.code || resp.Message == "" {
+			t.Fatalf("%s: expected 400 %s with a message, got %d: %s", c.name, c.code, rec.Code, rec.Body.String())
+		}
+		if !bytes.Equal(resp.Details, []byte(c.details)) {
+			t.Errorf("%s: expected details %s, got %s", c.name, c.details, resp.Details)
+		}
+	}
+}
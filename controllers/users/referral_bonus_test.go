@@ -0,0 +1,75 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestReferralBonusFirstAndRepeatRates(t *testing.T) {
+	tx := testutil.Tx(t)
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, ReferralBonusPercent: 30, ReferralRepeatPercent: 10, ReferralBonusCap: 45000}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Ulang", ReffBy: &referrer.ID})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Repeat 1"})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	buy := func() {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/investments", strings.NewReader(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true}`, user.ID, product.ID)))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		rec := httptest.NewRecorder()
+		h.AdminCreate(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	bonuses := func() []models.Transaction {
+		var rows []models.Transaction
+		if err := tx.Where("user_id = ? AND transaction_type = ?", referrer.ID, "team").Order("id ASC").Find(&rows).Error; err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	// 30% of the first purchase, 10% of the next, then only the 5000 left under the cap
+	for i := 0; i < 4; i++ {
+		buy()
+	}
+	got := bonuses()
+	want := []struct {
+		amount int64
+		msg    string
+	}{
+		{30000, "Bonus rekomendasi investor (pembelian pertama 30%)"},
+		{10000, "Bonus rekomendasi investor (pembelian ulang 10%)"},
+		{5000, "Bonus rekomendasi investor (pembelian ulang 10%, batas per downline)"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d bonuses, got %d", len(want), len(got))
+	}
+	for i, w := range want {
+		if got[i].Amount != w.amount || got[i].Message == nil || *got[i].Message != w.msg {
+			t.Fatalf("bonus %d: expected %d %q, got %d %v", i, w.amount, w.msg, got[i].Amount, got[i].Message)
+		}
+	}
+	var ref models.User
+	tx.First(&ref, referrer.ID)
+	if ref.Balance != 45000 {
+		t.Fatalf("expected the capped total 45000 on the referrer's balance, got %d", ref.Balance)
+	}
+}
@@ -0,0 +1,87 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/controllers/admins"
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestReferralBonusHeldOnSharedIP(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	suffix := time.Now().UnixNano() % 1000000000
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Akun Kedua", ReffBy: &referrer.ID})
+	ip := fmt.Sprintf("10.%d.%d.%d", suffix%250, suffix/250%250, suffix/62500%250)
+	for _, uid := range []uint{referrer.ID, user.ID} {
+		if err := models.RecordUserSignal(tx, uid, models.UserSignalRegister, ip, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Fraud 1"})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var inv models.Investment
+	if err := tx.Where("user_id = ?", user.ID).First(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-1","reference_id":%q,"amount":%d,"status":"SUCCESS"}}`, inv.OrderID, inv.Amount)
+	rec = httptest.NewRecorder()
+	h.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var bonus models.Transaction
+	if err := tx.Where("user_id = ? AND transaction_type = ?", referrer.ID, "team").First(&bonus).Error; err != nil {
+		t.Fatal(err)
+	}
+	var ref models.User
+	tx.First(&ref, referrer.ID)
+	if bonus.Status != "Held" || ref.Balance != 0 {
+		t.Fatalf("expected a held bonus and no credit, got status %s balance %d", bonus.Status, ref.Balance)
+	}
+
+	review := func(action string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/referral-bonuses/x/"+action, nil)
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		req = mux.SetURLVars(req, map[string]string{"id": fmt.Sprint(bonus.ID)})
+		rec := httptest.NewRecorder()
+		if action == "release" {
+			admins.ReleaseReferralBonus(rec, req)
+		} else {
+			admins.RejectReferralBonus(rec, req)
+		}
+		return rec
+	}
+	if rec := review("release"); rec.Code != http.StatusOK {
+		t.Fatalf("release: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := review("reject"); rec.Code != http.StatusConflict {
+		t.Fatalf("reject after release: expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	tx.First(&ref, referrer.ID)
+	if ref.Balance != bonus.Amount {
+		t.Fatalf("expected released bonus %d on balance, got %d", bonus.Amount, ref.Balance)
+	}
+}
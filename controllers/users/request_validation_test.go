@@ -0,0 +1,52 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/utils"
+)
+
+// These cases are rejected while decoding, before the handler touches the database.
+func TestWriteHandlersRejectInvalidPayloads(t *testing.T) {
+	investments := &InvestmentHandler{}
+	withdrawals := &WithdrawalHandler{}
+	deposits := &DepositHandler{}
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		field   string
+	}{
+		{"investment unknown field", investments.Create, `{"product_id":1,"payment_methd":"QRIS"}`, "payment_methd"},
+		{"investment wrong type", investments.Create, `{"product_id":"1","payment_method":"QRIS"}`, "product_id"},
+		{"investment missing method", investments.Create, `{"product_id":1}`, "payment_method"},
+		{"investment bank without channel", investments.Create, `{"product_id":1,"payment_method":"bank"}`, "payment_channel"},
+		{"investment missing product", investments.Create, `{"payment_method":"QRIS"}`, "product_id"},
+		{"withdrawal wrong type", withdrawals.Create, `{"amount":"100000","bank_account_id":1}`, "amount"},
+		{"withdrawal missing account", withdrawals.Create, `{"amount":100000}`, "bank_account_id"},
+		{"deposit zero amount", deposits.Create, `{"amount":0,"payment_method":"QRIS"}`, "amount"},
+		{"deposit bank without channel", deposits.Create, `{"amount":50000,"payment_method":"BANK"}`, "payment_channel"},
+		{"withdrawal unknown field", withdrawals.Create, `{"amount":100000,"bank_account_id":1,"fee":0}`, "fee"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v3/users/x", strings.NewReader(c.body))
+			rec := httptest.NewRecorder()
+			c.handler(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp utils.APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Errors[c.field] == "" {
+				t.Fatalf("expected an error for %q, got %v", c.field, resp.Errors)
+			}
+		})
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"project/database"
+	"project/i18n"
 	"project/models"
+	"project/money"
 	"project/utils"
 
 	"gorm.io/gorm"
@@ -39,6 +41,7 @@ func SpinPrizeListHandler(w http.ResponseWriter, r *http.Request) {
 
 	var prizes []models.SpinPrize
 	if err := db.Select("id, amount, code, chance_weight, status").Where("status = ?", "Active").Order("amount ASC").Find(&prizes).Error; err != nil {
+		utils.LogError(r, "SpinPrizeListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -193,12 +196,13 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user and check spin_ticket
 	var user models.User
 	if err := db.Select("id, balance, spin_ticket").Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.LogError(r, "UserSpinHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan data, silakan coba lagi"})
 		log.Println(err)
 		return
 	}
 	if user.SpinTicket == nil || *user.SpinTicket == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tiket spin Anda habis, silakan dapatkan tiket terlebih dahulu"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tiket spin Anda habis, silakan dapatkan tiket terlebih dahulu", Code: utils.CodeNoSpinTicket})
 		return
 	}
 
@@ -206,7 +210,7 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 
 	var prizes []models.SpinPrize
 	if err := db.Select("id, amount, code, chance_weight, status").Where("status = 'Active'").Order("amount ASC").Find(&prizes).Error; err != nil || len(prizes) == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah tidak valid atau sudah tidak tersedia"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah tidak valid atau sudah tidak tersedia", Code: utils.CodeSpinPrizeUnavailable})
 		return
 	}
 
@@ -218,7 +222,7 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if totalWeight <= 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah tidak valid atau sudah tidak tersedia"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah tidak valid atau sudah tidak tersedia", Code: utils.CodeSpinPrizeUnavailable})
 		return
 	}
 
@@ -236,7 +240,8 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	previousBalance := user.Balance
-	var currentBalance float64
+	var currentBalance int64
+	prizeAmount := money.FromFloat(finalPrize.Amount)
 
 	err = db.Transaction(func(tx *gorm.DB) error {
 		// Decrement spin_ticket
@@ -246,11 +251,11 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 
 		// Create transaction
 		msg := "Hadiah Spin Wheel"
-		orderID := utils.GenerateOrderID(userID)
+		orderID := utils.GenerateOrderID(utils.OrderBonus, userID)
 
 		trx := models.Transaction{
 			UserID:          userID,
-			Amount:          finalPrize.Amount,
+			Amount:          prizeAmount,
 			Charge:          0,
 			OrderID:         orderID,
 			TransactionFlow: "debit",
@@ -259,6 +264,7 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 			Status:          "Success",
 		}
 		if err := tx.Create(&trx).Error; err != nil {
+			utils.LogError(r, "UserSpinHandler", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 			log.Println(err)
 			return err
@@ -279,7 +285,7 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 		// Increase user's balance
 		if err := tx.Model(&models.User{}).
 			Where("id = ?", userID).
-			UpdateColumn("balance", gorm.Expr("balance + ?", finalPrize.Amount)).Error; err != nil {
+			UpdateColumn("balance", gorm.Expr("balance + ?", prizeAmount)).Error; err != nil {
 			return err
 		}
 
@@ -292,6 +298,7 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
+		utils.LogError(r, "UserSpinHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan server, silakan coba lagi"})
 		log.Println(err)
 		return
@@ -307,10 +314,54 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 				"code":   finalPrize.Code,
 			},
 			"balance_info": map[string]interface{}{
-				"previous_balance": int64(previousBalance),
-				"prize_amount":     int64(finalPrize.Amount),
-				"current_balance":  int64(currentBalance),
+				"previous_balance": previousBalance,
+				"prize_amount":     prizeAmount,
+				"current_balance":  currentBalance,
 			},
 		},
 	})
 }
+
+// SpinTicketGrantResponse is one batch of spin tickets and where it came from.
+type SpinTicketGrantResponse struct {
+	ID        uint   `json:"id"`
+	Source    string `json:"source"`
+	Tickets   uint   `json:"tickets"`
+	CreatedAt string `json:"created_at"`
+}
+
+// GET /api/users/spin-tickets
+// Lists the caller's spin ticket grants, newest first: "referral" for a
+// direct referral's purchase, "mission" for a claimed mission reward.
+func SpinTicketHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	query := database.DB.Model(&models.TicketGrant{}).Where("user_id = ?", uid)
+	var totalRows int64
+	if err := query.Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "SpinTicketHistoryHandler", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	var rows []models.TicketGrant
+	if err := query.Order("id DESC").Limit(pg.Limit).Offset(pg.Offset).Find(&rows).Error; err != nil {
+		utils.LogError(r, "SpinTicketHistoryHandler", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	items := make([]SpinTicketGrantResponse, len(rows))
+	for i, g := range rows {
+		items[i] = SpinTicketGrantResponse{ID: g.ID, Source: g.Source, Tickets: g.Tickets, CreatedAt: utils.FormatTime(g.CreatedAt)}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: utils.NewPaginated(items, pg, totalRows)})
+}
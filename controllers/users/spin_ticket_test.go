@@ -0,0 +1,88 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestReferralSpinTicketsCappedAndOnce(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, SpinTicketMinAmount: 100000, SpinTicketsPerPurchase: 2, SpinTicketDailyCap: 3}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Tiket", ReffBy: &referrer.ID})
+	product := testutil.NewProduct(t, tx, models.Product{Name: "Ticket 1"})
+
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v3/admin/investments", strings.NewReader(fmt.Sprintf(`{"user_id":%d,"product_id":%d,"paid":true}`, user.ID, product.ID)))
+		req = req.WithContext(context.WithValue(req.Context(), utils.AdminIDKey, int64(1)))
+		rec := httptest.NewRecorder()
+		h.AdminCreate(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	tickets := func() uint {
+		var u models.User
+		tx.Select("spin_ticket").First(&u, referrer.ID)
+		if u.SpinTicket == nil {
+			return 0
+		}
+		return *u.SpinTicket
+	}
+	// 2 for the first purchase, then the 1 left under the daily cap
+	if got := tickets(); got != 3 {
+		t.Fatalf("expected 3 tickets, got %d", got)
+	}
+
+	// A replayed grant for the same order is ignored
+	var first models.Investment
+	if err := tx.Where("user_id = ?", user.ID).Order("id ASC").First(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+	granted, err := models.GrantSpinTickets(tx, referrer.ID, models.TicketSourceReferral, first.OrderID, 2)
+	if err != nil || granted {
+		t.Fatalf("expected the replay to be skipped, got granted=%v err=%v", granted, err)
+	}
+	if got := tickets(); got != 3 {
+		t.Fatalf("expected 3 tickets after the replay, got %d", got)
+	}
+
+	rec := httptest.NewRecorder()
+	SpinTicketHistoryHandler(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/spin-tickets", nil), referrer.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("history: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Data []SpinTicketGrantResponse `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Data) != 2 || resp.Data.Data[0].Tickets != 1 || resp.Data.Data[0].Source != models.TicketSourceReferral {
+		t.Fatalf("expected two referral grants, newest of 1 ticket, got %+v", resp.Data.Data)
+	}
+}
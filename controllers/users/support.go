@@ -0,0 +1,301 @@
+package users
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// ticketImageMaxBytes bounds screenshots attached to ticket messages.
+	ticketImageMaxBytes = 2 << 20
+	// ticketImageURLExpiry is how long presigned attachment URLs stay valid.
+	ticketImageURLExpiry = 60 * 60
+)
+
+// SupportHandler serves the user side of support tickets.
+type SupportHandler struct {
+	DB *gorm.DB
+}
+
+func NewSupportHandler(db *gorm.DB) *SupportHandler {
+	return &SupportHandler{DB: db}
+}
+
+type CreateTicketRequest struct {
+	Category string `json:"category" validate:"required,oneof=account deposit investment withdrawal other"`
+	Subject  string `json:"subject" validate:"required,max=150"`
+	Message  string `json:"message" validate:"required,max=2000"`
+}
+
+type TicketReplyRequest struct {
+	Message string `json:"message" validate:"required,max=2000"`
+}
+
+// TicketMessageResponse is a ticket message with its attachment resolved to a URL.
+type TicketMessageResponse struct {
+	ID         uint      `json:"id"`
+	SenderType string    `json:"sender_type"`
+	Body       string    `json:"body"`
+	ImageURL   *string   `json:"image_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TicketMessageResponses converts messages for API responses. Attachments
+// whose URL cannot be produced are logged and left out.
+func TicketMessageResponses(r *http.Request, msgs []models.TicketMessage) []TicketMessageResponse {
+	resp := make([]TicketMessageResponse, 0, len(msgs))
+	for _, m := range msgs {
+		item := TicketMessageResponse{ID: m.ID, SenderType: m.SenderType, Body: m.Body, CreatedAt: m.CreatedAt}
+		if m.Image != nil {
+			if url, err := utils.ObjectURL(*m.Image, ticketImageURLExpiry); err != nil {
+				utils.LogError(r, "TicketMessageResponses: image url", err, "message_id", m.ID)
+			} else {
+				item.ImageURL = &url
+			}
+		}
+		resp = append(resp, item)
+	}
+	return resp
+}
+
+// POST /api/users/tickets (multipart: category, subject, message, optional image)
+func (h *SupportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	if err := r.ParseMultipartForm(ticketImageMaxBytes); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+
+	req := CreateTicketRequest{
+		Category: strings.ToLower(strings.TrimSpace(r.FormValue("category"))),
+		Subject:  strings.TrimSpace(r.FormValue("subject")),
+		Message:  strings.TrimSpace(r.FormValue("message")),
+	}
+	if !validateForm(w, r, &req) {
+		return
+	}
+
+	image, ok := uploadTicketImage(w, r, uid)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	ticket := models.SupportTicket{UserID: uid, Category: req.Category, Subject: req.Subject, Status: "Open", LastMessageAt: now}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ticket).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.TicketMessage{TicketID: ticket.ID, SenderType: "user", SenderID: int64(uid), Body: req.Message, Image: image}).Error
+	}); err != nil {
+		utils.LogError(r, "SupportHandler.Create", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Tiket berhasil dibuat", Data: ticket})
+}
+
+// GET /api/users/tickets?status=Open|Answered|Closed
+func (h *SupportHandler) List(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	query := h.DB.Model(&models.SupportTicket{}).Where("user_id = ?", uid)
+	if status := r.URL.Query().Get("status"); status == "Open" || status == "Answered" || status == "Closed" {
+		query = query.Where("status = ?", status)
+	}
+
+	var totalRows int64
+	if err := query.Session(&gorm.Session{}).Count(&totalRows).Error; err != nil {
+		utils.LogError(r, "SupportHandler.List", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	var tickets []models.SupportTicket
+	if err := query.Order("updated_at DESC, id DESC").Offset(pg.Offset).Limit(pg.Limit).Find(&tickets).Error; err != nil {
+		utils.LogError(r, "SupportHandler.List", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: utils.NewPaginated(tickets, pg, totalRows)})
+}
+
+// GET /api/users/tickets/{id}
+func (h *SupportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	ticket, ok := h.loadTicket(w, r, uid)
+	if !ok {
+		return
+	}
+
+	var msgs []models.TicketMessage
+	if err := h.DB.Where("ticket_id = ?", ticket.ID).Order("id ASC").Find(&msgs).Error; err != nil {
+		utils.LogError(r, "SupportHandler.Get", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: utils.T(r, i18n.MsgSuccess),
+		Data:    map[string]interface{}{"ticket": ticket, "messages": TicketMessageResponses(r, msgs)},
+	})
+}
+
+// POST /api/users/tickets/{id}/messages (multipart: message, optional image)
+// Replying to an Answered ticket puts it back in the support queue.
+func (h *SupportHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	if err := r.ParseMultipartForm(ticketImageMaxBytes); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+	req := TicketReplyRequest{Message: strings.TrimSpace(r.FormValue("message"))}
+	if !validateForm(w, r, &req) {
+		return
+	}
+
+	ticket, ok := h.loadTicket(w, r, uid)
+	if !ok {
+		return
+	}
+	if ticket.Status == "Closed" {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodeTicketClosed)
+		return
+	}
+
+	image, ok := uploadTicketImage(w, r, uid)
+	if !ok {
+		return
+	}
+
+	msg := models.TicketMessage{TicketID: ticket.ID, SenderType: "user", SenderID: int64(uid), Body: req.Message, Image: image}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock so a concurrent close is not undone by this reply
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticket, ticket.ID).Error; err != nil {
+			return err
+		}
+		if ticket.Status == "Closed" {
+			return errTicketClosed
+		}
+		if err := tx.Create(&msg).Error; err != nil {
+			return err
+		}
+		ticket.Status = "Open"
+		ticket.LastMessageAt = msg.CreatedAt
+		return tx.Save(&ticket).Error
+	})
+	if errors.Is(err, errTicketClosed) {
+		utils.WriteError(w, r, http.StatusConflict, utils.CodeTicketClosed)
+		return
+	}
+	if err != nil {
+		utils.LogError(r, "SupportHandler.Reply", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Pesan terkirim", Data: TicketMessageResponses(r, []models.TicketMessage{msg})[0]})
+}
+
+var errTicketClosed = errors.New("ticket closed")
+
+// loadTicket fetches the {id} ticket owned by uid, writing the 404/500
+// response when it cannot.
+func (h *SupportHandler) loadTicket(w http.ResponseWriter, r *http.Request, uid uint) (models.SupportTicket, bool) {
+	var ticket models.SupportTicket
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvalidID)})
+		return ticket, false
+	}
+	if err := h.DB.Where("id = ? AND user_id = ?", id, uid).First(&ticket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeTicketNotFound)
+			return ticket, false
+		}
+		utils.LogError(r, "SupportHandler.loadTicket", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return ticket, false
+	}
+	return ticket, true
+}
+
+// validateForm checks the `validate` tags on a request filled from form
+// values, writing the 400 response with per-field errors when invalid.
+func validateForm(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if fields := utils.ValidateRequest(req); len(fields) > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, string(utils.CodeValidationFailed)), Code: utils.CodeValidationFailed, Errors: fields})
+		return false
+	}
+	return true
+}
+
+// uploadTicketImage stores the optional "image" form file under tickets/ and
+// returns its object key, or nil when none was attached. It writes the error
+// response and returns false when the upload is rejected or fails.
+func uploadTicketImage(w http.ResponseWriter, r *http.Request, uid uint) (*string, bool) {
+	file, header, err := r.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, true
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gagal membaca gambar", Code: utils.CodeInvalidImage})
+		return nil, false
+	}
+	defer file.Close()
+
+	imageBytes, ext, err := utils.SanitizeImage(file, header.Filename, header.Size, ticketImageMaxBytes)
+	var imgErr *utils.ImageError
+	if errors.As(err, &imgErr) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: imgErr.Message, Code: utils.CodeInvalidImage})
+		return nil, false
+	}
+	if err != nil {
+		utils.LogError(r, "uploadTicketImage", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses gambar"})
+		return nil, false
+	}
+
+	objectName := "tickets/" + strconv.FormatUint(uint64(uid), 10) + "_" + strconv.FormatInt(time.Now().UnixNano(), 10) + ext
+	if err := utils.UploadToS3(objectName, bytes.NewReader(imageBytes), int64(len(imageBytes))); err != nil {
+		utils.LogError(r, "uploadTicketImage", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengunggah gambar"})
+		return nil, false
+	}
+	return &objectName, true
+}
@@ -0,0 +1,138 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/controllers/admins"
+	"project/database"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func ticketForm(t *testing.T, fields map[string]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf, mw.FormDataContentType()
+}
+
+func TestSupportTicketLifecycle(t *testing.T) {
+	tx := testutil.Tx(t)
+	// The admin side writes its audit log through database.DB
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	user := testutil.NewUser(t, tx, models.User{Name: "Tiket"})
+	support := NewSupportHandler(tx)
+	adminSupport := admins.NewSupportHandler(tx)
+
+	withID := func(r *http.Request, id uint) *http.Request {
+		return mux.SetURLVars(r, map[string]string{"id": fmt.Sprint(id)})
+	}
+	asAdmin := func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), utils.AdminIDKey, int64(1)))
+	}
+
+	body, ctype := ticketForm(t, map[string]string{"category": "deposit", "subject": "", "message": "Saldo belum masuk"})
+	req := httptest.NewRequest(http.MethodPost, "/v3/users/tickets", body)
+	req.Header.Set("Content-Type", ctype)
+	rec := httptest.NewRecorder()
+	support.Create(rec, testutil.AsUser(req, user.ID))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"subject"`) {
+		t.Fatalf("missing subject: expected 400 with a field error, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body, ctype = ticketForm(t, map[string]string{"category": "deposit", "subject": "Deposit pending", "message": "Saldo belum masuk"})
+	req = httptest.NewRequest(http.MethodPost, "/v3/users/tickets", body)
+	req.Header.Set("Content-Type", ctype)
+	rec = httptest.NewRecorder()
+	support.Create(rec, testutil.AsUser(req, user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data models.SupportTicket `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	ticketID := created.Data.ID
+
+	// Another user cannot see it
+	rec = httptest.NewRecorder()
+	support.Get(rec, withID(testutil.AsUser(httptest.NewRequest(http.MethodGet, "/", nil), user.ID+1), ticketID))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("foreign ticket: expected 404, got %d", rec.Code)
+	}
+
+	// A support reply answers the ticket and notifies the user
+	rec = httptest.NewRecorder()
+	adminSupport.Reply(rec, withID(asAdmin(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"message":"Sedang kami cek"}`))), ticketID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("admin reply: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var ticket models.SupportTicket
+	if err := tx.First(&ticket, ticketID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Status != "Answered" || ticket.AssignedTo == nil || *ticket.AssignedTo != 1 {
+		t.Fatalf("expected Answered and assigned to admin 1, got %+v", ticket)
+	}
+
+	// The user's reply puts it back in the queue
+	body, ctype = ticketForm(t, map[string]string{"message": "Terima kasih"})
+	req = httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", ctype)
+	rec = httptest.NewRecorder()
+	support.Reply(rec, withID(testutil.AsUser(req, user.ID), ticketID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("user reply: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := tx.First(&ticket, ticketID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Status != "Open" {
+		t.Fatalf("expected Open after user reply, got %s", ticket.Status)
+	}
+
+	rec = httptest.NewRecorder()
+	adminSupport.Close(rec, withID(asAdmin(httptest.NewRequest(http.MethodPut, "/", nil)), ticketID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("close: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body, ctype = ticketForm(t, map[string]string{"message": "Halo?"})
+	req = httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", ctype)
+	rec = httptest.NewRecorder()
+	support.Reply(rec, withID(testutil.AsUser(req, user.ID), ticketID))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), string(utils.CodeTicketClosed)) {
+		t.Fatalf("reply to closed: expected 409 TICKET_CLOSED, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var msgs, notifications int64
+	tx.Model(&models.TicketMessage{}).Where("ticket_id = ?", ticketID).Count(&msgs)
+	tx.Model(&models.Notification{}).Where("user_id = ? AND type = ?", user.ID, "support_ticket").Count(&notifications)
+	if msgs != 3 || notifications != 2 {
+		t.Fatalf("expected 3 messages and 2 notifications (answered, closed), got %d and %d", msgs, notifications)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"project/database"
 	"project/models"
+	"project/money"
 	"project/utils"
 	"strings"
 
@@ -21,6 +22,7 @@ func TaskListHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
 	var tasks []models.Task
 	if err := db.Where("status = ?", "Active").Order("id ASC").Find(&tasks).Error; err != nil {
+		utils.LogError(r, "TaskListHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
@@ -132,7 +134,7 @@ func TaskSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	// Check if already claimed
 	var userTask models.UserTask
 	if err := db.Where("user_id = ? AND task_id = ?", uid, task.ID).First(&userTask).Error; err == nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tugas sudah pernah diambil"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tugas sudah pernah diambil", Code: utils.CodeTaskAlreadyClaimed})
 		return
 	}
 	// Check active subordinates
@@ -188,11 +190,13 @@ func TaskSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	activeCount := getActiveCount(task.RequiredLevel)
 	if activeCount < task.RequiredActiveMembers {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Belum memenuhi syarat tugas"})
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Belum memenuhi syarat tugas", Code: utils.CodeTaskRequirementsNotMet})
 		return
 	}
+	reward := money.FromFloat(task.Reward)
 	// Add reward to user balance
-	if err := db.Model(&models.User{}).Where("id = ?", uid).Update("balance", gorm.Expr("balance + ?", task.Reward)).Error; err != nil {
+	if err := db.Model(&models.User{}).Where("id = ?", uid).Update("balance", gorm.Expr("balance + ?", reward)).Error; err != nil {
+		utils.LogError(r, "TaskSubmitHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to update balance"})
 		return
 	}
@@ -204,9 +208,9 @@ func TaskSubmitHandler(w http.ResponseWriter, r *http.Request) {
 
 	db.Model(&models.Transaction{}).Create(map[string]interface{}{
 		"user_id":          uid,
-		"amount":           task.Reward,
+		"amount":           reward,
 		"charge":           0,
-		"order_id":         utils.GenerateOrderID(uid),
+		"order_id":         utils.GenerateOrderID(utils.OrderBonus, uid),
 		"transaction_flow": "debit",
 		"transaction_type": "bonus",
 		"message":          ptrString("Reward tugas: " + task.Name),
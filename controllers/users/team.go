@@ -1,13 +1,14 @@
 package users
 
 import (
-	"math"
 	"net/http"
 	"project/database"
 	"project/models"
 	"project/utils"
 	"strconv"
 	"strings"
+
+	"github.com/gorilla/mux"
 )
 
 // GET /api/users/team-invited/{level}
@@ -20,12 +21,7 @@ func TeamInvitedHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := database.DB
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	var levelStr string
-	if len(parts) >= 5 {
-		levelStr = parts[4]
-	}
+	levelStr := mux.Vars(r)["level"]
 	level, levelErr := strconv.Atoi(levelStr)
 	hasLevel := (levelErr == nil && level >= 1 && level <= 3)
 
@@ -44,6 +40,7 @@ func TeamInvitedHandler(w http.ResponseWriter, r *http.Request) {
 	// Level 1
 	var level1 []models.User
 	if err := db.Where("reff_by = ?", uid).Find(&level1).Error; err != nil {
+		utils.LogError(r, "TeamInvitedHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
@@ -72,8 +69,8 @@ func TeamInvitedHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Helper to sum total_invest
-	sumTotalInvest := func(users []models.User) float64 {
-		total := 0.0
+	sumTotalInvest := func(users []models.User) int64 {
+		total := int64(0)
 		for _, u := range users {
 			total += u.TotalInvest
 		}
@@ -130,12 +127,7 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := database.DB
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	var levelStr string
-	if len(parts) >= 5 {
-		levelStr = parts[4]
-	}
+	levelStr := mux.Vars(r)["level"]
 	level, err := strconv.Atoi(levelStr)
 	if err != nil || level < 1 || level > 3 {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Level must be 1, 2, or 3"})
@@ -157,6 +149,7 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 	// Level 1
 	var level1 []models.User
 	if err := db.Where("reff_by = ?", uid).Find(&level1).Error; err != nil {
+		utils.LogError(r, "TeamDataHandler", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
@@ -196,20 +189,12 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 		return num[:3] + "****" + num[n-4:]
 	}
 
-	// Get query parameters
-	searchQuery := strings.TrimSpace(r.URL.Query().Get("search"))
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-
-	// Parse pagination with defaults
-	page, _ := strconv.Atoi(pageStr)
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(limitStr)
-	if limit < 1 {
-		limit = 10
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
+	searchQuery := strings.TrimSpace(r.URL.Query().Get("search"))
 
 	// Apply search filter if provided
 	filteredUsers := users
@@ -227,9 +212,8 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Calculate pagination
 	totalRows := len(filteredUsers)
-	totalPages := int(math.Ceil(float64(totalRows) / float64(limit)))
-	start := (page - 1) * limit
-	end := start + limit
+	start := pg.Offset
+	end := start + pg.Limit
 
 	// Ensure start and end are within bounds
 	if start > totalRows {
@@ -253,14 +237,9 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := map[string]interface{}{
-		"level":   level,
-		"members": data,
-		"pagination": map[string]interface{}{
-			"page":       page,
-			"limit":      limit,
-			"total_rows": totalRows,
-			"total_pages": totalPages,
-		},
+		"level":      level,
+		"members":    data,
+		"pagination": pg.Meta(int64(totalRows)),
 	}
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
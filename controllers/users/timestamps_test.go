@@ -0,0 +1,38 @@
+package users
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestInvestmentResponseTimestamps(t *testing.T) {
+	t.Setenv("APP_TIMEZONE", "Asia/Jakarta")
+	created := time.Date(2024, 5, 1, 17, 30, 0, 0, time.UTC)
+	next := created.Add(24 * time.Hour)
+	inv := models.Investment{ID: 7, OrderID: "XIN-1", Status: "Running", NextReturnAt: &next, CreatedAt: created, UpdatedAt: created}
+
+	body, err := json.Marshal(newInvestmentResponse(inv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"created_at":     "2024-05-02T00:30:00+07:00",
+		"updated_at":     "2024-05-02T00:30:00+07:00",
+		"next_return_at": "2024-05-03T00:30:00+07:00",
+	}
+	for key, v := range want {
+		if got[key] != v {
+			t.Errorf("%s: got %v, want %s", key, got[key], v)
+		}
+	}
+	if _, ok := got["last_return_at"]; ok {
+		t.Error("last_return_at should be omitted when unset")
+	}
+}
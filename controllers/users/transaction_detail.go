@@ -0,0 +1,197 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/database"
+	"project/i18n"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Context types of a transaction detail.
+const (
+	txContextInvestment = "investment"
+	txContextWithdrawal = "withdrawal"
+	txContextDeposit    = "deposit"
+	txContextDownline   = "downline"
+)
+
+// TransactionDetailResponse is a transaction plus the object it came from.
+// Context is null for types without one (bonus, mission, leaderboard...).
+type TransactionDetailResponse struct {
+	Transaction transactionDTO `json:"transaction"`
+	ContextType *string        `json:"context_type"`
+	Context     interface{}    `json:"context"`
+}
+
+// InvestmentContext is the caller's investment behind an investment, return
+// or refund transaction.
+type InvestmentContext struct {
+	Investment models.Investment `json:"investment"`
+	Product    *models.Product   `json:"product"`
+}
+
+// WithdrawalContext is the withdrawal behind a withdrawal transaction.
+type WithdrawalContext struct {
+	OrderID       string `json:"order_id"`
+	Amount        int64  `json:"amount"`
+	Charge        int64  `json:"charge"`
+	FinalAmount   int64  `json:"final_amount"`
+	Status        string `json:"status"`
+	BankName      string `json:"bank_name"`
+	AccountName   string `json:"account_name"`
+	AccountNumber string `json:"account_number"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// DownlineContext is the referred investment behind a referral bonus. The
+// downline's name is masked.
+type DownlineContext struct {
+	Name        string `json:"name"`
+	ProductName string `json:"product_name"`
+	Amount      int64  `json:"amount"`
+	InvestedAt  string `json:"invested_at"`
+}
+
+// GET /api/users/transactions/{id}
+// Resolves the object a transaction came from, always scoped to the caller:
+// their own investment, withdrawal or deposit, or the masked downline whose
+// investment paid a referral bonus.
+func GetTransactionDetail(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgInvalidID)})
+		return
+	}
+
+	db := database.DB
+	var trx models.Transaction
+	if err := db.Where("id = ? AND user_id = ?", id, uid).First(&trx).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteError(w, r, http.StatusNotFound, utils.CodeTransactionNotFound)
+			return
+		}
+		utils.LogError(r, "GetTransactionDetail", err)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+
+	resp, err := resolveTransaction(db, uid, trx)
+	if err != nil {
+		utils.LogError(r, "GetTransactionDetail: resolve", err, "transaction_id", trx.ID)
+		utils.WriteError(w, r, http.StatusInternalServerError, utils.CodeInternalError)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: resp})
+}
+
+// resolveTransaction loads the context of trx, which must belong to uid. A
+// context that no longer exists leaves it null rather than failing.
+func resolveTransaction(db *gorm.DB, uid uint, trx models.Transaction) (*TransactionDetailResponse, error) {
+	resp := &TransactionDetailResponse{Transaction: newTransactionDTO(trx)}
+	set := func(kind string, ctx interface{}) {
+		resp.ContextType = &kind
+		resp.Context = ctx
+	}
+
+	switch trx.TransactionType {
+	case "investment", "investment_topup", "return", "refund", "refund_payout", "partial_refund", "overpayment":
+		var inv models.Investment
+		q := db.Unscoped().Where("user_id = ?", uid)
+		if trx.InvestmentID != nil {
+			q = q.Where("id = ?", *trx.InvestmentID)
+		} else {
+			q = q.Where("order_id = ?", trx.OrderID)
+		}
+		if err := q.First(&inv).Error; err != nil {
+			return resp, ignoreNotFound(err)
+		}
+		ctx := InvestmentContext{Investment: inv}
+		var product models.Product
+		if err := db.First(&product, inv.ProductID).Error; err == nil {
+			ctx.Product = &product
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		set(txContextInvestment, ctx)
+
+	case "withdrawal":
+		var wd models.Withdrawal
+		if err := db.Preload("BankAccount.Bank").Where("order_id = ? AND user_id = ?", trx.OrderID, uid).First(&wd).Error; err != nil {
+			return resp, ignoreNotFound(err)
+		}
+		ctx := WithdrawalContext{
+			OrderID:     wd.OrderID,
+			Amount:      wd.Amount,
+			Charge:      wd.Charge,
+			FinalAmount: wd.FinalAmount,
+			Status:      wd.Status,
+			CreatedAt:   utils.FormatTime(wd.CreatedAt),
+		}
+		if acc := wd.BankAccount; acc != nil {
+			ctx.AccountName = acc.AccountName
+			ctx.AccountNumber = MaskAccountNumber(acc.AccountNumber)
+			if acc.Bank != nil {
+				ctx.BankName = acc.Bank.Name
+			}
+		}
+		set(txContextWithdrawal, ctx)
+
+	case "deposit":
+		var d models.Deposit
+		if err := db.Where("order_id = ? AND user_id = ?", trx.OrderID, uid).First(&d).Error; err != nil {
+			return resp, ignoreNotFound(err)
+		}
+		set(txContextDeposit, newDepositResponse(d))
+
+	case "team", "referral_clawback":
+		if trx.InvestmentID == nil {
+			return resp, nil
+		}
+		// The bonus row is the caller's, so its investment link is trusted
+		// even if the downline was later moved to another referrer
+		var row struct {
+			Name        string
+			ProductName string
+			Amount      int64
+			CreatedAt   time.Time
+		}
+		res := db.Table("investments AS i").
+			Select("u.name, i.product_name, i.amount, i.created_at").
+			Joins("JOIN users u ON u.id = i.user_id").
+			Where("i.id = ?", *trx.InvestmentID).
+			Limit(1).Scan(&row)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			return resp, nil
+		}
+		set(txContextDownline, DownlineContext{
+			Name:        maskName(row.Name),
+			ProductName: row.ProductName,
+			Amount:      row.Amount,
+			InvestedAt:  utils.FormatTime(row.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
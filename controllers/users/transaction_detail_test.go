@@ -0,0 +1,88 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTransactionDetailResolvesContext(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+
+	suffix := time.Now().UnixNano() % 1000000000
+	referrer := testutil.NewUser(t, tx, models.User{Name: "Referrer"})
+	user := testutil.NewUser(t, tx, models.User{Name: "Budi Santoso", ReffBy: &referrer.ID})
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Detail 1"})
+	inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, ProductName: product.Name, Amount: product.Amount, DailyProfit: product.DailyProfit, Duration: product.Duration, OrderID: fmt.Sprintf("INV-D%d", suffix), Status: "Running"}
+	if err := tx.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+	ret := models.Transaction{UserID: user.ID, InvestmentID: &inv.ID, Amount: 5000, OrderID: fmt.Sprintf("RET-D%d", suffix), TransactionFlow: "debit", TransactionType: "return", Status: "Success"}
+	bonus := models.Transaction{UserID: referrer.ID, InvestmentID: &inv.ID, SourceOrderID: &inv.OrderID, Amount: 30000, OrderID: fmt.Sprintf("TEAM-D%d", suffix), TransactionFlow: "debit", TransactionType: "team", Status: "Success"}
+	for _, trx := range []*models.Transaction{&ret, &bonus} {
+		if err := tx.Create(trx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(uid, id uint) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(testutil.AsUser(httptest.NewRequest(http.MethodGet, "/v3/users/transactions/x", nil), uid), map[string]string{"id": fmt.Sprint(id)})
+		rec := httptest.NewRecorder()
+		GetTransactionDetail(rec, req)
+		return rec
+	}
+	var body struct {
+		Data struct {
+			ContextType *string         `json:"context_type"`
+			Context     json.RawMessage `json:"context"`
+		} `json:"data"`
+	}
+
+	rec := get(user.ID, ret.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("return: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	var invCtx InvestmentContext
+	if err := json.Unmarshal(body.Data.Context, &invCtx); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.ContextType == nil || *body.Data.ContextType != txContextInvestment || invCtx.Investment.ID != inv.ID || invCtx.Product == nil || invCtx.Product.ID != product.ID {
+		t.Fatalf("unexpected return context: %s", rec.Body.String())
+	}
+
+	rec = get(referrer.ID, bonus.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("team: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	var downline DownlineContext
+	if err := json.Unmarshal(body.Data.Context, &downline); err != nil {
+		t.Fatal(err)
+	}
+	if *body.Data.ContextType != txContextDownline || downline.Name != "B*** S******" || downline.Amount != inv.Amount {
+		t.Fatalf("unexpected team context: %s", rec.Body.String())
+	}
+
+	// Another user's transaction is not found
+	if rec := get(referrer.ID, ret.ID); rec.Code != http.StatusNotFound {
+		t.Fatalf("foreign transaction: expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
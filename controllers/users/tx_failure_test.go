@@ -0,0 +1,65 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/database"
+	"project/models"
+	"project/testutil"
+)
+
+// A deposit webhook failing at any write leaves the deposit, its transaction
+// and the balance as they were.
+func TestDepositWebhookRollsBackOnFailure(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, MinDeposit: 20000, MaxDeposit: 5000000}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	depositor := testutil.NewUser(t, tx, models.User{Name: "Setor"})
+	gateway := &testutil.Kyta{}
+	rec := httptest.NewRecorder()
+	NewDepositHandler(tx, gateway).Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/deposits", strings.NewReader(`{"amount":100000,"payment_method":"QRIS"}`)), depositor.ID))
+	if rec.Code != http.StatusCreated || len(gateway.Payments) != 1 {
+		t.Fatalf("deposit: expected 201 and one payment, got %d: %s", rec.Code, rec.Body.String())
+	}
+	orderID := gateway.Payments[0].ReferenceID
+	webhook := fmt.Sprintf(`{"callback_code":"2000000","callback_data":{"id":"pay-f","reference_id":%q,"amount":100000,"status":"SUCCESS"}}`, orderID)
+	investments := NewInvestmentHandler(tx, gateway)
+	for _, table := range []string{"deposits", "transactions", "users"} {
+		t.Run("deposit webhook, "+table+" failing", func(t *testing.T) {
+			testutil.FailWrites(t, tx, table, false)
+			rec := httptest.NewRecorder()
+			investments.KytaWebhook(rec, httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(webhook)))
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("expected 500, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+		var deposit models.Deposit
+		if err := tx.Where("order_id = ?", orderID).First(&deposit).Error; err != nil {
+			t.Fatal(err)
+		}
+		var trx models.Transaction
+		if err := tx.Where("order_id = ?", orderID).First(&trx).Error; err != nil {
+			t.Fatal(err)
+		}
+		var got models.User
+		if err := tx.First(&got, depositor.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if deposit.Status != "Pending" || trx.Status != "Pending" || got.Balance != 0 {
+			t.Errorf("deposit webhook, %s failing: deposit %q, transaction %q, balance %d", table, deposit.Status, trx.Status, got.Balance)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package users
+
+import (
+	"testing"
+
+	"project/models"
+	"project/testutil"
+	"project/vip"
+)
+
+func TestVIPRecalculateDowngradePolicy(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	for level, min := range map[uint]int64{1: 100000, 2: 1000000, 3: 5000000} {
+		if err := tx.Save(&models.VIPLevel{Level: level, MinTotalInvest: min}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx.Where("level > 3").Delete(&models.VIPLevel{})
+	level := uint(3)
+	user := testutil.NewUser(t, tx, models.User{Name: "Level", Level: &level, TotalInvestVIP: 1500000})
+
+	// Under the flag policy the downgrade is recorded once and not applied
+	for i := 0; i < 2; i++ {
+		change, err := vip.Recalculate(tx, user.ID, nil, vip.SourceCron, vip.PolicyFlag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && (change == nil || change.Applied || change.ToLevel != 2) {
+			t.Fatalf("expected a flagged downgrade to 2, got %+v", change)
+		}
+		if i == 1 && change != nil {
+			t.Fatalf("expected the flagged downgrade not to repeat, got %+v", change)
+		}
+	}
+	var got models.User
+	if err := tx.Select("id, level").First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentVIPLevel() != 3 {
+		t.Fatalf("flag policy: expected level 3, got %d", got.CurrentVIPLevel())
+	}
+
+	// Under the apply policy it lowers the level and notifies the user
+	change, err := vip.Recalculate(tx, user.ID, nil, vip.SourceAdmin, vip.PolicyApply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change == nil || !change.Applied || change.FromLevel != 3 || change.ToLevel != 2 {
+		t.Fatalf("expected an applied downgrade 3 -> 2, got %+v", change)
+	}
+	if err := tx.Select("id, level").First(&got, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentVIPLevel() != 2 {
+		t.Fatalf("apply policy: expected level 2, got %d", got.CurrentVIPLevel())
+	}
+	var notifications int64
+	tx.Model(&models.Notification{}).Where("user_id = ?", user.ID).Count(&notifications)
+	if notifications != 1 {
+		t.Fatalf("expected 1 notification, got %d", notifications)
+	}
+
+	var history []models.VIPLevelChange
+	if err := tx.Where("user_id = ?", user.ID).Order("id ASC").Find(&history).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Applied || !history[1].Applied || history[1].Source != vip.SourceAdmin {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
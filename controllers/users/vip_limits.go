@@ -0,0 +1,87 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// errVIPActiveInvestmentLimit aborts a purchase transaction that found the
+// active investment cap taken by a purchase made in parallel.
+var errVIPActiveInvestmentLimit = errors.New("vip active investment limit")
+
+// VIPLimitUsage is the data of a VIP limit error: which cap was hit, the
+// user's level, the cap and how much of it the user has used, so the app can
+// show progress toward it. Requested is the amount of the refused withdrawal.
+type VIPLimitUsage struct {
+	Limit     string `json:"limit"`
+	Level     uint   `json:"level"`
+	Max       int64  `json:"max"`
+	Used      int64  `json:"used"`
+	Requested int64  `json:"requested,omitempty"`
+}
+
+// VIP limit names, as VIPLimitUsage.Limit.
+const (
+	vipLimitActiveInvestments = "active_investments"
+	vipLimitDailyWithdrawal   = "daily_withdrawal"
+	vipLimitSingleWithdrawal  = "single_withdrawal"
+)
+
+// writeVIPLimit refuses a request over a VIP cap with code and the usage.
+func writeVIPLimit(w http.ResponseWriter, r *http.Request, code utils.ErrorCode, u VIPLimitUsage) {
+	utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: vipLimitMessage(r, code, u), Code: code, Data: u})
+}
+
+// vipLimitMessage translates code with the arguments its message takes.
+func vipLimitMessage(r *http.Request, code utils.ErrorCode, u VIPLimitUsage) string {
+	switch code {
+	case utils.CodeVIPActiveInvestmentLimit:
+		return utils.T(r, string(code), u.Level, u.Max, u.Used)
+	case utils.CodeVIPDailyWithdrawalLimit:
+		left := u.Max - u.Used
+		if left < 0 {
+			left = 0
+		}
+		return utils.T(r, string(code), u.Level, u.Max, left)
+	default:
+		return utils.T(r, string(code), u.Level, u.Max)
+	}
+}
+
+// userVIPLimits loads uid's VIP level and its caps.
+func userVIPLimits(db *gorm.DB, uid uint) (models.VIPLevel, error) {
+	var user models.User
+	if err := db.Select("id, level").First(&user, uid).Error; err != nil {
+		return models.VIPLevel{}, err
+	}
+	return models.VIPLevelLimits(db, user.CurrentVIPLevel())
+}
+
+// activeInvestmentCount counts what the active investment cap covers: Running
+// and Suspended investments plus Pending ones whose payment can still be made.
+func activeInvestmentCount(db *gorm.DB, uid uint, now time.Time) (int64, error) {
+	var n int64
+	err := db.Model(&models.Investment{}).
+		Where("user_id = ?", uid).
+		Where("status IN ? OR (status = ? AND EXISTS (SELECT 1 FROM payments p WHERE p.investment_id = investments.id AND p.status = ? AND p.deleted_at IS NULL AND (p.expired_at IS NULL OR p.expired_at > ?)))",
+			[]string{"Running", "Suspended"}, "Pending", "Pending", now).
+		Count(&n).Error
+	return n, err
+}
+
+// withdrawnBetween sums uid's withdrawals requested in [from, to) that have
+// not failed.
+func withdrawnBetween(db *gorm.DB, uid uint, from, to time.Time) (int64, error) {
+	var sum int64
+	err := db.Model(&models.Withdrawal{}).
+		Where("user_id = ? AND status <> ? AND created_at >= ? AND created_at < ?", uid, "Failed", from, to).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
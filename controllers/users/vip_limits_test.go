@@ -0,0 +1,131 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestVIPActiveInvestmentLimit(t *testing.T) {
+	tx := testutil.Tx(t)
+
+	level := uint(3)
+	user := testutil.NewUser(t, tx, models.User{Name: "Capped", Level: &level})
+	if err := tx.Save(&models.VIPLevel{Level: level, MaxActiveInvestments: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	category := testutil.NewCategory(t, tx, "unlocked")
+	product := testutil.NewProduct(t, tx, models.Product{CategoryID: category.ID, Name: "Capped 1"})
+	h := NewInvestmentHandler(tx, &testutil.Kyta{})
+	body := fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID)
+
+	// The first purchase awaits payment and takes the only slot
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("first purchase: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("second purchase: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Code string        `json:"code"`
+		Data VIPLimitUsage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := VIPLimitUsage{Limit: vipLimitActiveInvestments, Level: level, Max: 1, Used: 1}
+	if resp.Code != string(utils.CodeVIPActiveInvestmentLimit) || resp.Data != want {
+		t.Fatalf("expected %s with %+v, got %s with %+v", utils.CodeVIPActiveInvestmentLimit, want, resp.Code, resp.Data)
+	}
+
+	// An expired payment frees the slot
+	if err := tx.Model(&models.Payment{}).Where("investment_id IN (?)", tx.Model(&models.Investment{}).Select("id").Where("user_id = ?", user.ID)).
+		Update("expired_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(body)), user.ID))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("after expiry: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// Purchases racing for the last slot are counted under the user's lock, so
+// only one gets it.
+func TestParallelPurchasesRespectVIPActiveLimit(t *testing.T) {
+	// Parallel requests need their own connections, so no wrapping transaction
+	db := testutil.DB(t)
+
+	level := uint(4)
+	user := testutil.NewUser(t, db, models.User{Name: "Berebut", Level: &level})
+	var prevLevel models.VIPLevel
+	hadLevel := db.Where("level = ?", level).Take(&prevLevel).Error == nil
+	if err := db.Save(&models.VIPLevel{Level: level, MaxActiveInvestments: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	category := testutil.NewCategory(t, db, "unlocked")
+	product := testutil.NewProduct(t, db, models.Product{CategoryID: category.ID, Name: "Berebut 1"})
+	t.Cleanup(func() {
+		db.Unscoped().Where("investment_id IN (?)", db.Unscoped().Model(&models.Investment{}).Select("id").Where("user_id = ?", user.ID)).Delete(&models.Payment{})
+		db.Where("user_id = ?", user.ID).Delete(&models.Transaction{})
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Investment{})
+		if hadLevel {
+			db.Save(&prevLevel)
+		} else {
+			db.Where("level = ?", level).Delete(&models.VIPLevel{})
+		}
+		db.Delete(&product)
+		db.Delete(&category)
+		db.Delete(&user)
+	})
+
+	h := NewInvestmentHandler(db, &testutil.Kyta{})
+	const parallel = 5
+	codes := make([]int, parallel)
+	bodies := make([]string, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/investments", strings.NewReader(fmt.Sprintf(`{"product_id":%d,"payment_method":"QRIS"}`, product.ID))), user.ID))
+			codes[i], bodies[i] = rec.Code, rec.Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusBadRequest:
+			var resp utils.APIResponse
+			if err := json.Unmarshal([]byte(bodies[i]), &resp); err != nil || resp.Code != utils.CodeVIPActiveInvestmentLimit {
+				t.Fatalf("expected %s, got %s", utils.CodeVIPActiveInvestmentLimit, bodies[i])
+			}
+		default:
+			t.Fatalf("unexpected %d: %s", code, bodies[i])
+		}
+	}
+	var investments int64
+	db.Model(&models.Investment{}).Where("user_id = ?", user.ID).Count(&investments)
+	if created != 1 || investments != 1 {
+		t.Fatalf("expected exactly one purchase, got %d created and %d stored", created, investments)
+	}
+}
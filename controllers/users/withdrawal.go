@@ -1,125 +1,92 @@
 package users
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"net/http"
-	"os"
+	"strings"
+	"time"
+
 	"project/database"
+	"project/i18n"
+	"project/kyta"
 	"project/models"
 	"project/utils"
-	"strconv"
-	"strings"
-	"time"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type WithdrawalRequest struct {
-	Amount        float64 `json:"amount"`
-	BankAccountID uint    `json:"bank_account_id"`
+	Amount        int64 `json:"amount" validate:"gt=0"` // whole rupiah
+	BankAccountID uint  `json:"bank_account_id" validate:"required"`
+	// Express pays setting.ExpressWithdrawCharge on top of the usual charge to
+	// skip the processing window and be paid out by the express cron
+	Express bool `json:"express"`
+}
+
+// WithdrawalHandler serves withdrawal requests and history for users.
+type WithdrawalHandler struct {
+	DB *gorm.DB
+	// Kyta checks the account holder's name when it is a kyta.AccountInquirer;
+	// nil skips the check
+	Kyta kyta.Client
 }
 
-func WithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+func NewWithdrawalHandler(db *gorm.DB) *WithdrawalHandler {
+	return &WithdrawalHandler{DB: db}
+}
+
+// POST /api/users/withdrawal
+// Runs the checks Quote reports, then the gateway's account inquiry: a holder
+// name matching the user's below withdraw_name_match_min puts the withdrawal
+// On Hold for an admin, as a hold risk rule does.
+func (h *WithdrawalHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req WithdrawalRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Not valid JSON"})
+	if !utils.DecodeAndValidate(w, r, &req) {
 		return
 	}
 
 	uid, ok := utils.GetUserID(r)
 	if !ok || uid == 0 {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
 		return
 	}
 
-	// Load settings
-	sqlDB, err := database.DB.DB()
+	db, cancel := database.WithTimeout(r.Context(), h.DB)
+	defer cancel()
+	plan, err := planWithdrawal(db, r, uid, req, time.Now())
 	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
-		return
-	}
-	setting, err := models.GetSetting(sqlDB)
-	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
-		return
-	}
-
-	// Validate amount
-	if req.Amount < setting.MinWithdraw {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: fmt.Sprintf("Minimal penarikan adalah Rp%.0f", setting.MinWithdraw)})
-		return
-	}
-	if req.Amount > setting.MaxWithdraw {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: fmt.Sprintf("Maksimal penarikan adalah Rp%.0f", setting.MaxWithdraw)})
-		return
-	}
-	loc, _ := time.LoadLocation("Asia/Jakarta")
-	now := time.Now().In(loc)
-	hour := now.Hour()
-	if hour < 9 || hour >= 17 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Penarikan hanya dapat dilakukan pada pukul 09:00 - 17:00 WIB"})
-		return
-	}
-
-	if now.Weekday() == time.Sunday {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Penarikan hanya dapat dilakukan pada hari Senin sampai Sabtu"})
-		return
-	}
-
-	db := database.DB
-
-	// Check if user has already made a withdrawal today
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-	endOfDay := startOfDay.Add(24 * time.Hour)
-	var todayWithdrawals int64
-	if err := db.Model(&models.Withdrawal{}).Where("user_id = ? AND created_at BETWEEN ? AND ?", uid, startOfDay, endOfDay).Count(&todayWithdrawals).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
-		return
-	}
-	if todayWithdrawals > 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Anda hanya dapat melakukan 1 kali penarikan dalam sehari"})
-		return
-	}
-
-	// Load bank account owned by user
-	var acc models.BankAccount
-	if err := db.Preload("Bank").Where("id = ? AND user_id = ?", req.BankAccountID, uid).First(&acc).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Rekening tujuan tidak ditemukan"})
+		utils.LogError(r, "WithdrawalHandler", err)
+		if utils.WriteDBTimeout(w, r, err) {
 			return
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgSystemError)})
 		return
 	}
-	if acc.Bank == nil || acc.Bank.Status != "Active" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Layanan bank ini sedang dalam pemeliharaan"})
+	if len(plan.Refusals) > 0 {
+		ref := plan.Refusals[0]
+		utils.WriteJSON(w, ref.status, utils.APIResponse{Success: false, Message: ref.Message, Code: ref.Code, Data: ref.Data})
 		return
 	}
 
-	// Compute charge and final amount
-	charge := round2(req.Amount * (setting.WithdrawCharge / 100.0))
-	finalAmount := req.Amount - charge
-	orderID := utils.GenerateOrderID(uid)
+	// The name check may hold the withdrawal. It calls the gateway, so the
+	// writes get a deadline of their own
+	inquiry := h.inquireAccount(r, plan)
+	cancel()
+	db, cancel = database.WithTimeout(r.Context(), h.DB)
+	defer cancel()
 
-	// Sentinel error for insufficient balance
-	var errInsufficientBalance = errors.New("insufficient_balance")
+	acc := plan.Account
+	var riskFlags *string
+	if len(plan.RiskFlags) > 0 {
+		joined := strings.Join(plan.RiskFlags, ",")
+		riskFlags = &joined
+	}
+	orderID := utils.GenerateOrderID(utils.OrderWithdrawal, uid)
 
 	var wd models.Withdrawal
 	if err := db.Transaction(func(tx *gorm.DB) error {
-		// Lock user row for update and validate balance
-		var user models.User
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, uid).Error; err != nil {
-			return err
-		}
-		if user.Balance < req.Amount {
-			return errInsufficientBalance
-		}
-		newBalance := round2(user.Balance - req.Amount)
-		if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
+		if err := models.DebitBalance(tx, uid, req.Amount); err != nil {
 			return err
 		}
 
@@ -128,10 +95,18 @@ func WithdrawalHandler(w http.ResponseWriter, r *http.Request) {
 			UserID:        uid,
 			BankAccountID: acc.ID,
 			Amount:        req.Amount,
-			Charge:        charge,
-			FinalAmount:   finalAmount,
+			Charge:        plan.Charge,
+			FinalAmount:   plan.FinalAmount,
 			OrderID:       orderID,
-			Status:        "Pending",
+			Status:        plan.Status,
+			RiskFlags:     riskFlags,
+			Express:       req.Express,
+			ExpressFee:    plan.ExpressFee,
+		}
+		if inquiry != nil {
+			wd.InquiryStatus = &inquiry.Status
+			wd.InquiryName = inquiry.Name
+			wd.NameMatchScore = inquiry.Score
 		}
 		if err := tx.Create(&wd).Error; err != nil {
 			return err
@@ -142,7 +117,7 @@ func WithdrawalHandler(w http.ResponseWriter, r *http.Request) {
 		trx := models.Transaction{
 			UserID:          uid,
 			Amount:          req.Amount,
-			Charge:          charge,
+			Charge:          plan.Charge,
 			OrderID:         orderID,
 			TransactionFlow: "credit",
 			TransactionType: "withdrawal",
@@ -155,11 +130,15 @@ func WithdrawalHandler(w http.ResponseWriter, r *http.Request) {
 
 		return nil
 	}); err != nil {
-		if errors.Is(err, errInsufficientBalance) {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Saldo tidak mencukupi"})
+		if errors.Is(err, models.ErrInsufficientBalance) {
+			utils.WriteError(w, r, http.StatusBadRequest, utils.CodeInsufficientBalance)
+			return
+		}
+		utils.LogError(r, "WithdrawalHandler: save withdrawal", err)
+		if utils.WriteDBTimeout(w, r, err) {
 			return
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgSystemError)})
 		return
 	}
 
@@ -169,75 +148,67 @@ func WithdrawalHandler(w http.ResponseWriter, r *http.Request) {
 			"order_id":       wd.OrderID,
 			"amount":         wd.Amount,
 			"charge":         wd.Charge,
+			"express":        wd.Express,
+			"express_fee":    wd.ExpressFee,
 			"final_amount":   wd.FinalAmount,
 			"bank_name":      acc.Bank.Name,
 			"account_name":   acc.AccountName,
 			"account_number": MaskAccountNumber(acc.AccountNumber),
 			"status":         wd.Status,
-			"created_at":     wd.CreatedAt.Format("2006-01-02 15:04:05"),
+			"created_at":     utils.FormatTime(wd.CreatedAt),
 		},
 	}
 	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
 		Success: true,
-		Message: "Permintaan penarikan berhasil diproses",
+		Message: utils.T(r, i18n.MsgWithdrawalCreated),
 		Data:    resp,
 	})
 }
 
 // GET /api/users/withdrawal
-func ListWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+func (h *WithdrawalHandler) List(w http.ResponseWriter, r *http.Request) {
 	uid, ok := utils.GetUserID(r)
 	if !ok || uid == 0 {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
 		return
 	}
 
-	// Get query parameters
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-	searchQuery := strings.TrimSpace(r.URL.Query().Get("search"))
-
-	// Parse pagination with defaults
-	page, _ := strconv.Atoi(pageStr)
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(limitStr)
-	if limit < 1 {
-		limit = 10
+	pg, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
 	}
+	searchQuery := strings.TrimSpace(r.URL.Query().Get("search"))
 
-	db := database.DB
+	db := h.DB
 
 	// Build base query for counting
 	countQuery := db.Model(&models.Withdrawal{}).Where("user_id = ?", uid)
 	if searchQuery != "" {
-		countQuery = countQuery.Where("order_id LIKE ?", "%"+searchQuery+"%")
+		countQuery = countQuery.Where("order_id LIKE ?", utils.PrefixLike(searchQuery))
 	}
 
 	// Count total rows
 	var totalRows int64
 	if err := countQuery.Count(&totalRows).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to retrieve withdrawal data"})
+		utils.LogError(r, "ListWithdrawalHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgWithdrawalListFailed)})
 		return
 	}
 
-	// Calculate pagination
-	totalPages := int(math.Ceil(float64(totalRows) / float64(limit)))
-	offset := (page - 1) * limit
-
 	// Build query for fetching data
 	var withdrawals []models.Withdrawal
 	query := db.Where("user_id = ?", uid)
 	if searchQuery != "" {
-		query = query.Where("order_id LIKE ?", "%"+searchQuery+"%")
+		query = query.Where("order_id LIKE ?", utils.PrefixLike(searchQuery))
 	}
-	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&withdrawals).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to retrieve withdrawal data"})
+	if err := query.Order("id DESC").Limit(pg.Limit).Offset(pg.Offset).Find(&withdrawals).Error; err != nil {
+		utils.LogError(r, "ListWithdrawalHandler", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgWithdrawalListFailed)})
 		return
 	}
 
-	var resp []map[string]interface{}
+	resp := make([]map[string]interface{}, 0, len(withdrawals))
 	for _, wd := range withdrawals {
 		var acc models.BankAccount
 		var bank models.Bank
@@ -246,57 +217,29 @@ func ListWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
 		resp = append(resp, map[string]interface{}{
 			"amount":          wd.Amount,
 			"charge":          wd.Charge,
+			"express":         wd.Express,
+			"express_fee":     wd.ExpressFee,
 			"final_amount":    wd.FinalAmount,
 			"order_id":        wd.OrderID,
 			"status":          wd.Status,
-			"withdrawal_time": wd.CreatedAt.Format("2006-01-02 15:04:05"),
+			"withdrawal_time": utils.FormatTime(wd.CreatedAt),
 			"account_name":    acc.AccountName,
 			"account_number":  acc.AccountNumber,
 			"bank_name":       bank.Name,
 		})
 	}
 
-	// Build response with pagination
-	responseData := map[string]interface{}{
-		"data": resp,
-		"pagination": map[string]interface{}{
-			"page":       page,
-			"limit":      limit,
-			"total_rows": totalRows,
-			"total_pages": totalPages,
-		},
-	}
+	responseData := utils.NewPaginated(resp, pg, totalRows)
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
-		Message: "Successfully",
+		Message: utils.T(r, i18n.MsgSuccess),
 		Data:    responseData,
 	})
 }
 
 // Helpers
 
-func CalculateWithdrawalCharge(amount float64) float64 {
-	percent := getWithdrawalChargePercent()
-	return round2(amount * (percent / 100.0))
-}
-
-func getWithdrawalChargePercent() float64 {
-	s := os.Getenv("WITHDRAWAL_CHARGE_PERCENT")
-	if s == "" {
-		return 10.0
-	}
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return 10.0
-	}
-	return v
-}
-
-func round2(v float64) float64 {
-	return float64(int64(v*100+0.5)) / 100
-}
-
 func MaskAccountNumber(accountNumber string) string {
 	if len(accountNumber) <= 6 {
 		return accountNumber
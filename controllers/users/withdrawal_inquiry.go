@@ -0,0 +1,61 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/kyta"
+	"project/models"
+	"project/risk"
+	"project/utils"
+)
+
+// withdrawalInquiryTimeout bounds the gateway's account inquiry; past it the
+// withdrawal goes on without the name check.
+const withdrawalInquiryTimeout = 5 * time.Second
+
+// accountInquiry is the outcome of a withdrawal's name check, stored on it.
+type accountInquiry struct {
+	Status string
+	Name   *string
+	Score  *int
+}
+
+// inquireAccount asks the gateway who holds the plan's account and scores
+// that name against the user's. Below the settings' withdraw_name_match_min
+// it flags the plan name_mismatch, which holds it. A bank the gateway cannot
+// look up, or a failed inquiry, leaves the plan as it was. It returns nil
+// when the check is off or the gateway cannot inquire at all.
+func (h *WithdrawalHandler) inquireAccount(r *http.Request, p *withdrawalPlan) *accountInquiry {
+	inquirer, ok := h.Kyta.(kyta.AccountInquirer)
+	minScore := p.Setting.WithdrawNameMatchMin
+	if !ok || minScore <= 0 || p.Account == nil || p.Account.Bank == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), withdrawalInquiryTimeout)
+	defer cancel()
+	resp, err := inquirer.InquireAccount(ctx, p.Account.Bank.PayoutCode(), p.Account.AccountNumber)
+	if errors.Is(err, kyta.ErrInquiryUnsupported) {
+		return &accountInquiry{Status: models.InquiryUnsupported}
+	}
+	if err != nil {
+		utils.LogError(r, "WithdrawalHandler: account inquiry", err, "bank_account_id", p.Account.ID)
+		return &accountInquiry{Status: models.InquiryFailed}
+	}
+	name := strings.TrimSpace(resp.ResponseData.AccountName)
+	if name == "" {
+		return &accountInquiry{Status: models.InquiryFailed}
+	}
+
+	score := risk.NameMatchScore(p.UserName, name)
+	res := &accountInquiry{Status: models.InquiryMatched, Name: &name, Score: &score}
+	if score < minScore {
+		res.Status = models.InquiryMismatch
+		p.RiskFlags = append(p.RiskFlags, risk.FlagNameMismatch)
+		p.Status = models.WithdrawalOnHold
+	}
+	return res
+}
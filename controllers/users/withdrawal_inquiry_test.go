@@ -0,0 +1,102 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/kyta"
+	"project/models"
+	"project/risk"
+	"project/testutil"
+)
+
+// inquiringKyta answers account inquiries with holder, or err.
+type inquiringKyta struct {
+	testutil.Kyta
+	holder string
+	err    error
+}
+
+func (s *inquiringKyta) InquireAccount(_ context.Context, bankCode, accountNumber string) (*kyta.AccountInquiryResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	resp := &kyta.AccountInquiryResponse{ResponseCode: "2000000"}
+	resp.ResponseData.Code = bankCode
+	resp.ResponseData.AccountNumber = accountNumber
+	resp.ResponseData.AccountName = s.holder
+	return resp, nil
+}
+
+// A holder name unlike the user's holds the withdrawal with both names kept;
+// a bank the gateway cannot look up goes through as usual.
+func TestWithdrawalAccountInquiry(t *testing.T) {
+	tx := testutil.Tx(t)
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, WithdrawCharge: 10, ExpressWithdraw: true, WithdrawNameMatchMin: 80}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	suffix := time.Now().UnixNano() % 1000000000
+	bank := models.Bank{Name: "Bank Periksa", Code: fmt.Sprintf("IQ%d", suffix), GatewayCode: "IQGW", Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		label   string
+		gateway kyta.Client
+		status  string
+		inquiry string
+		flagged bool
+	}{
+		{"same person", &inquiringKyta{holder: "SANTOSO BUDI"}, "Pending", models.InquiryMatched, false},
+		{"someone else", &inquiringKyta{holder: "AGUS HARTONO"}, models.WithdrawalOnHold, models.InquiryMismatch, true},
+		{"unsupported bank", &inquiringKyta{err: &kyta.Error{Message: "bank not supported", Status: http.StatusNotFound, Err: kyta.ErrInquiryUnsupported}}, "Pending", models.InquiryUnsupported, false},
+		{"gateway without inquiry", &testutil.Kyta{}, "Pending", "", false},
+	} {
+		user := testutil.NewUser(t, tx, models.User{Name: "Budi Santoso", Balance: 200000})
+		acc := models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: "Budi Santoso", AccountNumber: fmt.Sprintf("%d%09d", i+1, suffix)}
+		if err := tx.Create(&acc).Error; err != nil {
+			t.Fatal(err)
+		}
+		h := NewWithdrawalHandler(tx)
+		h.Kyta = tc.gateway
+
+		body := fmt.Sprintf(`{"amount":100000,"bank_account_id":%d,"express":true}`, acc.ID)
+		rec := httptest.NewRecorder()
+		h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/withdrawal", strings.NewReader(body)), user.ID))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %d: %s", tc.label, rec.Code, rec.Body.String())
+		}
+		var wd models.Withdrawal
+		if err := tx.Where("user_id = ?", user.ID).First(&wd).Error; err != nil {
+			t.Fatal(err)
+		}
+		if wd.Status != tc.status {
+			t.Errorf("%s: status %q, want %q", tc.label, wd.Status, tc.status)
+		}
+		got := ""
+		if wd.InquiryStatus != nil {
+			got = *wd.InquiryStatus
+		}
+		if got != tc.inquiry {
+			t.Errorf("%s: inquiry %q, want %q", tc.label, got, tc.inquiry)
+		}
+		flagged := wd.RiskFlags != nil && strings.Contains(*wd.RiskFlags, risk.FlagNameMismatch)
+		if flagged != tc.flagged {
+			t.Errorf("%s: name_mismatch flagged %v, want %v", tc.label, flagged, tc.flagged)
+		}
+		if tc.inquiry == models.InquiryMismatch && (wd.InquiryName == nil || *wd.InquiryName != "AGUS HARTONO" || wd.NameMatchScore == nil || *wd.NameMatchScore >= 80) {
+			t.Errorf("%s: holder and score not kept: %+v", tc.label, wd)
+		}
+	}
+}
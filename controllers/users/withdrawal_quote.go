@@ -0,0 +1,235 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/config"
+	"project/i18n"
+	"project/models"
+	"project/money"
+	"project/risk"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// withdrawalPlan is what a withdrawal request would do right now: its fees,
+// the status it would be created in and every rule refusing it. Create and
+// Quote both use planWithdrawal, so a quote always matches what Create does.
+type withdrawalPlan struct {
+	Setting     models.Setting
+	Account     *models.BankAccount // nil unless the caller owns the account
+	UserName    string              // the registered name, for the account inquiry
+	Charge      int64               // includes ExpressFee
+	ExpressFee  int64
+	FinalAmount int64
+	Status      string // Pending, or On Hold when a risk rule holds it
+	RiskFlags   []string
+	Refusals    []withdrawalRefusal // in the order Create checks them
+}
+
+// withdrawalRefusal is one rule refusing a withdrawal, as Create reports it.
+type withdrawalRefusal struct {
+	status  int
+	Code    utils.ErrorCode `json:"code"`
+	Message string          `json:"message"`
+	Data    interface{}     `json:"data,omitempty"`
+}
+
+func (p *withdrawalPlan) refuse(status int, code utils.ErrorCode, message string, data interface{}) {
+	p.Refusals = append(p.Refusals, withdrawalRefusal{status: status, Code: code, Message: message, Data: data})
+}
+
+// planWithdrawal runs every withdrawal check for uid's req at now. Only
+// database failures are returned as errors; refusals are collected in the
+// plan. The bank account is looked up among uid's own accounts only.
+func planWithdrawal(db *gorm.DB, r *http.Request, uid uint, req WithdrawalRequest, now time.Time) (*withdrawalPlan, error) {
+	setting, err := models.GetCachedSetting(db)
+	if err != nil {
+		return nil, fmt.Errorf("load settings: %w", err)
+	}
+	p := &withdrawalPlan{Setting: setting, Status: "Pending"}
+	p.Charge, p.ExpressFee = withdrawalFees(&setting, req.Amount, req.Express)
+	p.FinalAmount = req.Amount - p.Charge
+
+	// Amount and express availability
+	if req.Amount < money.FromFloat(setting.MinWithdraw) {
+		p.refuse(http.StatusBadRequest, utils.CodeWithdrawalAmountRange, utils.T(r, i18n.MsgWithdrawalMin, setting.MinWithdraw), nil)
+	}
+	if req.Amount > money.FromFloat(setting.MaxWithdraw) {
+		p.refuse(http.StatusBadRequest, utils.CodeWithdrawalAmountRange, utils.T(r, i18n.MsgWithdrawalMax, setting.MaxWithdraw), nil)
+	}
+	if req.Express && !setting.ExpressWithdraw {
+		p.refuse(http.StatusBadRequest, utils.CodeExpressUnavailable, utils.T(r, string(utils.CodeExpressUnavailable)), nil)
+	}
+
+	// Processing window; express withdrawals are paid for to skip it
+	loc := utils.AppLocation()
+	local := now.In(loc)
+	if !req.Express {
+		hour := local.Hour()
+		if hour < setting.WithdrawStartHour || hour >= setting.WithdrawEndHour {
+			p.refuse(http.StatusBadRequest, utils.CodeWithdrawalOutsideHours, utils.T(r, string(utils.CodeWithdrawalOutsideHours), setting.WithdrawStartHour, setting.WithdrawEndHour), nil)
+		}
+		if local.Weekday() == time.Sunday {
+			p.refuse(http.StatusBadRequest, utils.CodeWithdrawalOutsideHours, utils.T(r, i18n.MsgWithdrawalClosedSunday), nil)
+		}
+	}
+
+	// One withdrawal a day
+	startOfDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	endOfDay := startOfDay.Add(24 * time.Hour)
+	var todayWithdrawals int64
+	if err := db.Model(&models.Withdrawal{}).Where("user_id = ? AND created_at BETWEEN ? AND ?", uid, startOfDay, endOfDay).Count(&todayWithdrawals).Error; err != nil {
+		return nil, fmt.Errorf("count today's withdrawals: %w", err)
+	}
+	if todayWithdrawals > 0 {
+		p.refuse(http.StatusBadRequest, utils.CodeWithdrawalDailyLimit, utils.T(r, string(utils.CodeWithdrawalDailyLimit)), nil)
+	}
+
+	// VIP level caps: one request, and everything requested today that has
+	// not failed
+	limits, err := userVIPLimits(db, uid)
+	if err != nil {
+		return nil, fmt.Errorf("vip limits: %w", err)
+	}
+	if limits.MaxSingleWithdrawal > 0 && req.Amount > limits.MaxSingleWithdrawal {
+		u := VIPLimitUsage{Limit: vipLimitSingleWithdrawal, Level: limits.Level, Max: limits.MaxSingleWithdrawal, Requested: req.Amount}
+		p.refuse(http.StatusBadRequest, utils.CodeVIPSingleWithdrawalLimit, vipLimitMessage(r, utils.CodeVIPSingleWithdrawalLimit, u), u)
+	}
+	if limits.MaxDailyWithdrawal > 0 {
+		used, err := withdrawnBetween(db, uid, startOfDay, endOfDay)
+		if err != nil {
+			return nil, fmt.Errorf("withdrawn today: %w", err)
+		}
+		if used+req.Amount > limits.MaxDailyWithdrawal {
+			u := VIPLimitUsage{Limit: vipLimitDailyWithdrawal, Level: limits.Level, Max: limits.MaxDailyWithdrawal, Used: used, Requested: req.Amount}
+			p.refuse(http.StatusBadRequest, utils.CodeVIPDailyWithdrawalLimit, vipLimitMessage(r, utils.CodeVIPDailyWithdrawalLimit, u), u)
+		}
+	}
+
+	// Bank account owned by the user
+	var acc models.BankAccount
+	err = db.Preload("Bank").Where("id = ? AND user_id = ?", req.BankAccountID, uid).First(&acc).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		p.refuse(http.StatusBadRequest, utils.CodeBankAccountNotFound, utils.T(r, i18n.MsgWithdrawalAccountNotFound), nil)
+	case err != nil:
+		return nil, fmt.Errorf("load bank account: %w", err)
+	default:
+		p.Account = &acc
+		if acc.Bank == nil || acc.Bank.Status != "Active" {
+			p.refuse(http.StatusBadRequest, utils.CodeBankUnavailable, utils.T(r, i18n.MsgWithdrawalBankMaintenance), nil)
+		}
+	}
+
+	// Balance; Create checks it again under the row lock
+	var user models.User
+	if err := db.Select("id, name, balance").First(&user, uid).Error; err != nil {
+		return nil, fmt.Errorf("load user: %w", err)
+	}
+	p.UserName = user.Name
+	if user.Balance < req.Amount {
+		p.refuse(http.StatusBadRequest, utils.CodeInsufficientBalance, utils.T(r, string(utils.CodeInsufficientBalance)), nil)
+	}
+
+	// Risk rules flag the withdrawal for the reviewing admin; some put it
+	// On Hold until an admin releases it
+	if p.Account != nil {
+		p.RiskFlags, err = risk.WithdrawalFlags(db, uid, p.Account.ID)
+		if err != nil {
+			return nil, fmt.Errorf("risk rules: %w", err)
+		}
+		if risk.Holds(p.RiskFlags) {
+			p.Status = models.WithdrawalOnHold
+		}
+	}
+	return p, nil
+}
+
+// withdrawalFees returns the total charge on amount and the express part of it.
+func withdrawalFees(setting *models.Setting, amount int64, express bool) (charge, expressFee int64) {
+	charge = money.Percent(amount, setting.WithdrawCharge)
+	if express {
+		expressFee = money.Percent(amount, setting.ExpressWithdrawCharge)
+	}
+	return charge + expressFee, expressFee
+}
+
+// withdrawalSLA returns how many minutes the plan's withdrawal should take to
+// be paid and the message saying so; 0 minutes when it waits for an admin
+// to release it. Express withdrawals are only paid by the cron when no risk
+// rule fired.
+func withdrawalSLA(r *http.Request, p *withdrawalPlan, express bool) (int, string) {
+	if p.Status == models.WithdrawalOnHold {
+		return 0, utils.T(r, i18n.MsgWithdrawalSLAReview)
+	}
+	if express && len(p.RiskFlags) == 0 {
+		minutes := config.Get().Withdrawal.ExpressSLAMinutes
+		return minutes, utils.T(r, i18n.MsgWithdrawalSLAExpress, minutes)
+	}
+	hours := config.Get().Withdrawal.SLAHours
+	return hours * 60, utils.T(r, i18n.MsgWithdrawalSLAStandard, hours)
+}
+
+// GET /api/users/withdrawals/quote?amount=&bank_account_id=&express=true
+// What the same request to POST /api/users/withdrawal would cost and whether
+// it would be accepted right now, with every refusal Create would report.
+// The account is only described when it is the caller's own.
+func (h *WithdrawalHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	amount, err := strconv.ParseInt(q.Get("amount"), 10, 64)
+	if err != nil || amount <= 0 {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeValidationFailed)
+		return
+	}
+	accountID, err := strconv.ParseUint(q.Get("bank_account_id"), 10, 32)
+	if err != nil || accountID == 0 {
+		utils.WriteError(w, r, http.StatusBadRequest, utils.CodeValidationFailed)
+		return
+	}
+	express, _ := strconv.ParseBool(q.Get("express"))
+	req := WithdrawalRequest{Amount: amount, BankAccountID: uint(accountID), Express: express}
+
+	plan, err := planWithdrawal(h.DB, r, uid, req, time.Now())
+	if err != nil {
+		utils.LogError(r, "WithdrawalHandler.Quote", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: utils.T(r, i18n.MsgSystemError)})
+		return
+	}
+
+	refusals := plan.Refusals
+	if refusals == nil {
+		refusals = []withdrawalRefusal{}
+	}
+	slaMinutes, sla := withdrawalSLA(r, plan, express)
+	data := map[string]interface{}{
+		"amount":            amount,
+		"charge":            plan.Charge,
+		"express":           express,
+		"express_fee":       plan.ExpressFee,
+		"final_amount":      plan.FinalAmount,
+		"express_available": plan.Setting.ExpressWithdraw,
+		"allowed":           len(plan.Refusals) == 0,
+		"refusals":          refusals,
+		"on_hold":           plan.Status == models.WithdrawalOnHold,
+		"sla_minutes":       slaMinutes,
+		"sla":               sla,
+	}
+	if acc := plan.Account; acc != nil && acc.Bank != nil {
+		data["bank_name"] = acc.Bank.Name
+		data["account_name"] = acc.AccountName
+		data["account_number"] = MaskAccountNumber(acc.AccountNumber)
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: utils.T(r, i18n.MsgSuccess), Data: data})
+}
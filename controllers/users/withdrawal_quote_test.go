@@ -0,0 +1,89 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+func TestWithdrawalQuoteMatchesCreate(t *testing.T) {
+	tx := testutil.Tx(t)
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, WithdrawCharge: 10, ExpressWithdraw: true, ExpressWithdrawCharge: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	suffix := time.Now().UnixNano() % 1000000000
+
+	bank := models.Bank{Name: "Bank Kutipan", Code: fmt.Sprintf("QT%d", suffix), Status: "Active"}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	var users []models.User
+	var accounts []models.BankAccount
+	for i := 0; i < 2; i++ {
+		user := testutil.NewUser(t, tx, models.User{Name: fmt.Sprintf("Quote %d", i), Balance: 60000})
+		acc := models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: fmt.Sprintf("Quote %d", i), AccountNumber: fmt.Sprintf("%d%09d", i+1, suffix)}
+		if err := tx.Create(&acc).Error; err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+		accounts = append(accounts, acc)
+	}
+	h := NewWithdrawalHandler(tx)
+
+	type quote struct {
+		Allowed  bool                `json:"allowed"`
+		Refusals []withdrawalRefusal `json:"refusals"`
+		Account  string              `json:"account_number"`
+	}
+	get := func(accountID uint) quote {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.Quote(rec, testutil.AsUser(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v3/users/withdrawals/quote?amount=100000&bank_account_id=%d&express=true", accountID), nil), users[0].ID))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("quote: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Data quote `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+
+	// Another user's account is not described
+	q := get(accounts[1].ID)
+	if q.Allowed || q.Account != "" || len(q.Refusals) != 2 ||
+		q.Refusals[0].Code != utils.CodeBankAccountNotFound || q.Refusals[1].Code != utils.CodeInsufficientBalance {
+		t.Fatalf("unexpected quote for another user's account: %+v", q)
+	}
+
+	// Create refuses with the quote's first refusal
+	q = get(accounts[0].ID)
+	if q.Allowed || q.Account == "" || len(q.Refusals) != 1 || q.Refusals[0].Code != utils.CodeInsufficientBalance {
+		t.Fatalf("unexpected quote for own account: %+v", q)
+	}
+	body := fmt.Sprintf(`{"amount":100000,"bank_account_id":%d,"express":true}`, accounts[0].ID)
+	rec := httptest.NewRecorder()
+	h.Create(rec, testutil.AsUser(httptest.NewRequest(http.MethodPost, "/v3/users/withdrawal", strings.NewReader(body)), users[0].ID))
+	var created utils.APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || created.Code != q.Refusals[0].Code || created.Message != q.Refusals[0].Message {
+		t.Fatalf("expected create to refuse like the quote, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
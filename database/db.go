@@ -33,7 +33,9 @@ func Connect() (*gorm.DB, error) {
 	user := getenv("DB_USER", "root")
 	pass := getenv("DB_PASS", "")
 	name := getenv("DB_NAME", "v1")
-	params := getenv("DB_PARAMS", "charset=utf8mb4&parseTime=True&loc=Local")
+	// loc=UTC: timestamps are stored and read back as UTC; responses convert
+	// them to APP_TIMEZONE (see utils.FormatTime)
+	params := getenv("DB_PARAMS", "charset=utf8mb4&parseTime=True&loc=UTC")
 
 	// Allow explicit DSN override
 	dsn := os.Getenv("DB_DSN")
@@ -117,21 +119,13 @@ func Connect() (*gorm.DB, error) {
 		mysqldriver.RegisterTLSConfig("custom", tlsCfg)
 	}
 
-	// GORM logger: verbose in development
-	var gormLogger logger.Interface
-	if strings.ToLower(getenv("ENV", "development")) == "development" {
-		gormLogger = logger.Default.LogMode(logger.Info)
-	} else {
-		gormLogger = logger.Default.LogMode(logger.Silent)
-	}
-
 	// Retry connection with exponential backoff
 	maxRetries := atoi(getenv("DB_CONNECT_RETRIES", "5"))
 	var db *gorm.DB
 	var err error
 	backoff := time.Second
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		db, err = gorm.Open(gormmysql.Open(dsn), &gorm.Config{Logger: gormLogger})
+		db, err = gorm.Open(gormmysql.Open(dsn), &gorm.Config{Logger: gormLogger(), NowFunc: func() time.Time { return time.Now().UTC() }})
 		if err == nil {
 			break
 		}
@@ -148,13 +142,7 @@ func Connect() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	maxOpen := atoi(getenv("DB_MAX_OPEN_CONNS", "25"))
-	maxIdle := atoi(getenv("DB_MAX_IDLE_CONNS", "25"))
-	maxLifetimeSec := atoi(getenv("DB_CONN_MAX_LIFETIME", "3600"))
-
-	sqlDB.SetMaxOpenConns(maxOpen)
-	sqlDB.SetMaxIdleConns(maxIdle)
-	sqlDB.SetConnMaxLifetime(time.Duration(maxLifetimeSec) * time.Second)
+	configurePool(sqlDB)
 
 	// Optional connection validation
 	if getenv("DB_PING_ON_CONNECT", "true") == "true" {
@@ -167,6 +155,28 @@ func Connect() (*gorm.DB, error) {
 	return DB, nil
 }
 
+// gormLogger is verbose in development.
+func gormLogger() logger.Interface {
+	if strings.ToLower(getenv("ENV", "development")) == "development" {
+		return logger.Default.LogMode(logger.Info)
+	}
+	return logger.Default.LogMode(logger.Silent)
+}
+
+// configurePool applies the DB_MAX_* and DB_CONN_* pool settings.
+func configurePool(sqlDB *sql.DB) {
+	maxOpen := atoi(getenv("DB_MAX_OPEN_CONNS", "25"))
+	maxIdle := atoi(getenv("DB_MAX_IDLE_CONNS", "25"))
+	maxLifetimeSec := atoi(getenv("DB_CONN_MAX_LIFETIME", "3600"))
+	// Idle connections are dropped before MySQL's wait_timeout closes them
+	maxIdleTimeSec := atoi(getenv("DB_CONN_MAX_IDLE_TIME", "300"))
+
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	sqlDB.SetConnMaxLifetime(time.Duration(maxLifetimeSec) * time.Second)
+	sqlDB.SetConnMaxIdleTime(time.Duration(maxIdleTimeSec) * time.Second)
+}
+
 func atoi(s string) int {
 	v, _ := strconv.Atoi(s)
 	if v <= 0 {
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change, loaded from a pair of
+// NNNN_name.up.sql / NNNN_name.down.sql files.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration is a row of schema_migrations. Dirty is set while a
+// migration runs and cleared when it finishes; MySQL cannot roll back DDL, so
+// a dirty row means the schema may be half applied and needs a manual fix.
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	Dirty     bool      `gorm:"not null;default:false"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads every migration in dir of fsys, sorted by version.
+// Each version must have both an up and a down file.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationFilePattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_add_thing.up.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" || strings.TrimSpace(mig.Down) == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// SplitStatements splits a migration file into statements. Statements end
+// with a semicolon at the end of a line; "--" comment lines are dropped.
+func SplitStatements(sql string) []string {
+	var stmts []string
+	var cur strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			if s := strings.TrimSuffix(strings.TrimSpace(cur.String()), ";"); s != "" {
+				stmts = append(stmts, s)
+			}
+			cur.Reset()
+		}
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// Migrator applies and rolls back migrations, recording them in schema_migrations.
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{DB: db, Migrations: migrations}
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.DB.AutoMigrate(&SchemaMigration{})
+}
+
+// Applied returns the recorded migrations, oldest first.
+func (m *Migrator) Applied() ([]SchemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigration
+	err := m.DB.Order("version ASC").Find(&rows).Error
+	return rows, err
+}
+
+// ErrDirty is returned while a migration is recorded as partially applied.
+var ErrDirty = errors.New("a migration is dirty")
+
+// Pending returns the migrations not yet applied, in order. It fails with
+// ErrDirty when a previous run stopped halfway.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	done := map[int]bool{}
+	for _, a := range applied {
+		if a.Dirty {
+			return nil, fmt.Errorf("%w: %04d_%s; fix the schema by hand, then run `migrate force %d`", ErrDirty, a.Version, a.Name, a.Version)
+		}
+		done[a.Version] = true
+	}
+	var pending []Migration
+	for _, mig := range m.Migrations {
+		if !done[mig.Version] {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, mig := range pending {
+		row := SchemaMigration{Version: mig.Version, Name: mig.Name, Dirty: true, AppliedAt: time.Now()}
+		if err := m.DB.Create(&row).Error; err != nil {
+			return done, err
+		}
+		if err := m.exec(mig.Up); err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if err := m.DB.Model(&row).Update("dirty", false).Error; err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if _, err := m.Pending(); err != nil {
+		return nil, err
+	}
+	applied, err := m.Applied()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]Migration{}
+	for _, mig := range m.Migrations {
+		byVersion[mig.Version] = mig
+	}
+	var done []Migration
+	for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+		mig, ok := byVersion[applied[i].Version]
+		if !ok {
+			return done, fmt.Errorf("migration %d is applied but its files are missing", applied[i].Version)
+		}
+		if err := m.DB.Model(&applied[i]).Update("dirty", true).Error; err != nil {
+			return done, err
+		}
+		if err := m.exec(mig.Down); err != nil {
+			return done, fmt.Errorf("rollback %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if err := m.DB.Delete(&applied[i]).Error; err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Force records every migration up to and including version as applied and
+// clean, without running it, and forgets any later ones. Used to adopt a
+// database whose schema predates versioning, and to clear a dirty flag after
+// a manual fix.
+func (m *Migrator) Force(version int) error {
+	if err := m.ensureTable(); err != nil {
+		return err
+	}
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		for _, mig := range m.Migrations {
+			if mig.Version > version {
+				break
+			}
+			row := SchemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}
+			if err := tx.Save(&row).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("version > ?", version).Delete(&SchemaMigration{}).Error
+	})
+}
+
+func (m *Migrator) exec(sql string) error {
+	for _, stmt := range SplitStatements(sql) {
+		if err := m.DB.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
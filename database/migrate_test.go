@@ -0,0 +1,81 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"project/migrations"
+)
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	ms, err := LoadMigrations(migrations.FS, migrations.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) < 3 || ms[0].Version != 1 || ms[0].Name != "baseline" {
+		t.Fatalf("expected the baseline first, got %+v", ms)
+	}
+	for i, m := range ms {
+		if i > 0 && m.Version <= ms[i-1].Version {
+			t.Fatalf("versions out of order: %d after %d", m.Version, ms[i-1].Version)
+		}
+		if len(SplitStatements(m.Up)) == 0 || len(SplitStatements(m.Down)) == 0 {
+			t.Fatalf("%04d_%s has an empty up or down", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoadMigrationsRejectsBadFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"v/0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"bad name": {
+			"v/1-a.sql": {Data: []byte("SELECT 1;")},
+		},
+		"name mismatch": {
+			"v/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"v/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := LoadMigrations(fsys, "v"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	ms, err := LoadMigrations(fstest.MapFS{
+		"v/0010_b.up.sql":   {Data: []byte("SELECT 10;")},
+		"v/0010_b.down.sql": {Data: []byte("SELECT -10;")},
+		"v/0002_a.up.sql":   {Data: []byte("SELECT 2;")},
+		"v/0002_a.down.sql": {Data: []byte("SELECT -2;")},
+	}, "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].Version != 2 || ms[1].Version != 10 || ms[1].Down != "SELECT -10;" {
+		t.Fatalf("unexpected migrations %+v", ms)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := strings.Join([]string{
+		"-- Migration: test",
+		"-- a comment; with a semicolon",
+		"ALTER TABLE `a`",
+		"  ADD COLUMN `b` int,",
+		"  ADD COLUMN `c` varchar(10) DEFAULT ';x';",
+		"",
+		"UPDATE `a` SET `b` = 1",
+	}, "\n")
+	got := SplitStatements(sql)
+	want := []string{
+		"ALTER TABLE `a`\n  ADD COLUMN `b` int,\n  ADD COLUMN `c` varchar(10) DEFAULT ';x'",
+		"UPDATE `a` SET `b` = 1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q", got)
+	}
+}
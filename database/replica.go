@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// DefaultReplicaCheckInterval is how often the replica is pinged when
+// DB_REPLICA_CHECK_INTERVAL is unset or invalid.
+const DefaultReplicaCheckInterval = 10 * time.Second
+
+// replicaPingTimeout bounds one health check of the replica.
+const replicaPingTimeout = 2 * time.Second
+
+// Reads routes the admin lists and reports; set by main from ConnectReplica.
+var Reads *ReadReplica
+
+// ReadReplica hands out the connection for queries that only read and can
+// tolerate replication lag: the replica while its health check passes, the
+// primary otherwise. Anything that moves money or must see its own writes
+// uses the primary directly and never goes through a ReadReplica.
+//
+// A nil ReadReplica, or one without a replica, always returns the primary.
+type ReadReplica struct {
+	primary *gorm.DB
+	replica *gorm.DB
+	healthy atomic.Bool
+}
+
+// NewReadReplica routes reads to replica, or to primary when replica is nil.
+// The replica counts as healthy until a Check says otherwise.
+func NewReadReplica(primary, replica *gorm.DB) *ReadReplica {
+	rr := &ReadReplica{primary: primary, replica: replica}
+	rr.healthy.Store(replica != nil)
+	return rr
+}
+
+// DB returns the connection reads should use right now.
+func (rr *ReadReplica) DB() *gorm.DB {
+	if rr == nil {
+		return DB
+	}
+	if rr.replica != nil && rr.healthy.Load() {
+		return rr.replica
+	}
+	return rr.primary
+}
+
+// Status is "disabled" without a replica, else "up" or "down" (reads are on
+// the primary while it is down).
+func (rr *ReadReplica) Status() string {
+	switch {
+	case rr == nil || rr.replica == nil:
+		return "disabled"
+	case rr.healthy.Load():
+		return "up"
+	default:
+		return "down"
+	}
+}
+
+// Check pings the replica and records the result, logging when it changes.
+// It reports whether the replica is healthy.
+func (rr *ReadReplica) Check(ctx context.Context) bool {
+	if rr == nil || rr.replica == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+	defer cancel()
+	sqlDB, err := rr.replica.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	healthy := err == nil
+	if was := rr.healthy.Swap(healthy); was != healthy {
+		if healthy {
+			log.Printf("[database] read replica is back, routing reads to it")
+		} else {
+			log.Printf("[database] read replica unavailable, routing reads to the primary: %v", err)
+		}
+	}
+	return healthy
+}
+
+// Watch runs Check every interval until ctx is done. It returns at once
+// without a replica.
+func (rr *ReadReplica) Watch(ctx context.Context, interval time.Duration) {
+	if rr == nil || rr.replica == nil {
+		return
+	}
+	rr.Check(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rr.Check(ctx)
+		}
+	}
+}
+
+// ReplicaCheckInterval reads DB_REPLICA_CHECK_INTERVAL as a Go duration.
+func ReplicaCheckInterval() time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("DB_REPLICA_CHECK_INTERVAL"))); err == nil && d > 0 {
+		return d
+	}
+	return DefaultReplicaCheckInterval
+}
+
+// ConnectReplica opens the read replica at DB_REPLICA_DSN and routes reads
+// between it and primary. Without DB_REPLICA_DSN every read goes to primary.
+// An unreachable replica does not fail startup: it starts out down and the
+// first successful Check brings it in. Only a malformed DSN is an error.
+func ConnectReplica(primary *gorm.DB) (*ReadReplica, error) {
+	dsn := strings.TrimSpace(os.Getenv("DB_REPLICA_DSN"))
+	if dsn == "" {
+		return NewReadReplica(primary, nil), nil
+	}
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	safeDSN := dsn
+	if cfg.Passwd != "" {
+		safeDSN = strings.Replace(safeDSN, cfg.Passwd, "******", 1)
+	}
+	log.Printf("[database] using read replica DSN: %s", safeDSN)
+
+	// No version query or ping on open, so a replica that is down at boot
+	// does not hold up the primary
+	replica, err := gorm.Open(gormmysql.New(gormmysql.Config{DSN: dsn, SkipInitializeWithVersion: true}), &gorm.Config{
+		Logger:               gormLogger(),
+		NowFunc:              func() time.Time { return time.Now().UTC() },
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := replica.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB)
+
+	rr := NewReadReplica(primary, replica)
+	rr.Check(context.Background())
+	return rr, nil
+}
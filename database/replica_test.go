@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// replicaDown switches the "switchable" driver between reachable and not.
+var replicaDown atomic.Bool
+
+type switchableDriver struct{}
+
+func (switchableDriver) Open(string) (driver.Conn, error) {
+	if replicaDown.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return switchableConn{}, nil
+}
+
+type switchableConn struct{}
+
+func (switchableConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (switchableConn) Close() error                        { return nil }
+func (switchableConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (switchableConn) Ping(context.Context) error {
+	if replicaDown.Load() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func init() {
+	sql.Register("switchable", switchableDriver{})
+}
+
+func openSwitchable(t *testing.T) *gorm.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("switchable", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(gormmysql.New(gormmysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{
+		Logger:               logger.Default.LogMode(logger.Silent),
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestReadReplicaFallsBackToPrimary(t *testing.T) {
+	primary, replica := openSwitchable(t), openSwitchable(t)
+	t.Cleanup(func() { replicaDown.Store(false) })
+
+	rr := NewReadReplica(primary, replica)
+	if !rr.Check(context.Background()) || rr.DB() != replica || rr.Status() != "up" {
+		t.Fatalf("expected reads on a healthy replica, status %s", rr.Status())
+	}
+
+	replicaDown.Store(true)
+	if rr.Check(context.Background()) || rr.DB() != primary || rr.Status() != "down" {
+		t.Fatalf("expected reads on the primary while the replica is down, status %s", rr.Status())
+	}
+
+	replicaDown.Store(false)
+	if !rr.Check(context.Background()) || rr.DB() != replica {
+		t.Fatal("expected reads back on the replica once it recovers")
+	}
+}
+
+func TestReadReplicaWithoutReplica(t *testing.T) {
+	primary := openSwitchable(t)
+	if rr := NewReadReplica(primary, nil); rr.DB() != primary || rr.Status() != "disabled" || rr.Check(context.Background()) {
+		t.Fatal("expected every read on the primary without a replica")
+	}
+
+	prev := DB
+	DB = primary
+	t.Cleanup(func() { DB = prev })
+	var rr *ReadReplica
+	if rr.DB() != primary || rr.Status() != "disabled" {
+		t.Fatal("expected a nil ReadReplica to read from DB")
+	}
+}
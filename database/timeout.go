@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultQueryTimeout bounds the queries of the hot handlers when
+// DB_QUERY_TIMEOUT is unset or invalid.
+const DefaultQueryTimeout = 5 * time.Second
+
+// QueryTimeout reads DB_QUERY_TIMEOUT as a Go duration ("5s", "1500ms").
+func QueryTimeout() time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv("DB_QUERY_TIMEOUT"))); err == nil && d > 0 {
+		return d
+	}
+	return DefaultQueryTimeout
+}
+
+// WithTimeout returns db bound to a child of ctx that expires after
+// QueryTimeout, so a stalled MySQL fails the request instead of holding it
+// until the driver's read timeout. Call cancel once the queries are done.
+func WithTimeout(ctx context.Context, db *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout())
+	return db.WithContext(ctx), cancel
+}
+
+// IsTimeout reports whether err comes from a query deadline set by WithTimeout.
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stallDriver stands in for a MySQL that accepts connections but never
+// answers: every query blocks until its context ends.
+type stallDriver struct{}
+
+func (stallDriver) Open(string) (driver.Conn, error) { return stallConn{}, nil }
+
+type stallConn struct{}
+
+func (stallConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (stallConn) Close() error                        { return nil }
+func (stallConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (stallConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func init() {
+	sql.Register("stall", stallDriver{})
+}
+
+func TestWithTimeoutStopsStalledQuery(t *testing.T) {
+	t.Setenv("DB_QUERY_TIMEOUT", "50ms")
+	sqlDB, err := sql.Open("stall", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	db, err := gorm.Open(gormmysql.New(gormmysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounded, cancel := WithTimeout(context.Background(), db)
+	defer cancel()
+	start := time.Now()
+	var n int
+	err = bounded.Raw("SELECT SLEEP(60)").Scan(&n).Error
+	if !IsTimeout(err) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("query held for %s, want about 50ms", elapsed)
+	}
+
+	// A cancelled request is not a timeout and must not be retried as one
+	ctx, stop := context.WithCancel(context.Background())
+	stop()
+	err = db.WithContext(ctx).Raw("SELECT 1").Scan(&n).Error
+	if err == nil || IsTimeout(err) {
+		t.Fatalf("expected a cancellation error, got %v", err)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":       DefaultQueryTimeout,
+		"2s":     2 * time.Second,
+		"1500ms": 1500 * time.Millisecond,
+		"-1s":    DefaultQueryTimeout,
+		"five":   DefaultQueryTimeout,
+	}
+	for in, want := range cases {
+		t.Setenv("DB_QUERY_TIMEOUT", in)
+		if got := QueryTimeout(); got != want {
+			t.Errorf("DB_QUERY_TIMEOUT=%q: got %s, want %s", in, got, want)
+		}
+	}
+}
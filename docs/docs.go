@@ -0,0 +1,11 @@
+// Package docs embeds the OpenAPI document served at /v3/docs/openapi.json.
+//
+// openapi.json is maintained by hand next to the route definitions; the routes
+// package tests fail when a registered route is missing from it, or when it
+// documents a route that no longer exists.
+package docs
+
+import _ "embed"
+
+//go:embed openapi.json
+var OpenAPI []byte
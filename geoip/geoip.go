@@ -0,0 +1,185 @@
+// Package geoip resolves IP addresses to ISO 3166 country codes from a CSV
+// database loaded at startup.
+//
+// The file is GEOIP_DB_PATH, in the layout of the free DB-IP "IP to Country
+// Lite" CSV, one range per line as `start_ip,end_ip,country`; a line may
+// instead hold `cidr,country`. IPv4 and IPv6 ranges may be mixed. Without a
+// database every lookup is unknown, so geoblocking is off until one is
+// configured.
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Unknown is the country of an address no range covers, including private
+// and loopback addresses.
+const Unknown = ""
+
+type ipRange struct {
+	start, end netip.Addr
+	country    string
+}
+
+// DB is a loaded country database. The zero DB knows no address.
+type DB struct {
+	ranges []ipRange
+}
+
+// Load reads the database at path.
+func Load(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a database in the CSV layout described in the package comment.
+// Blank lines and lines starting with # are skipped; a malformed line fails
+// the whole file with its line number.
+func Parse(r io.Reader) (*DB, error) {
+	db := &DB{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rg, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("geoip line %d: %w", n, err)
+		}
+		db.ranges = append(db.ranges, rg)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(db.ranges, func(i, j int) bool { return db.ranges[i].start.Less(db.ranges[j].start) })
+	return db, nil
+}
+
+func parseLine(line string) (ipRange, error) {
+	fields := strings.Split(line, ",")
+	for i := range fields {
+		fields[i] = strings.Trim(strings.TrimSpace(fields[i]), `"`)
+	}
+	var rg ipRange
+	switch len(fields) {
+	case 2:
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return rg, err
+		}
+		prefix = prefix.Masked()
+		rg.start = prefix.Addr()
+		rg.end = lastAddr(prefix)
+	case 3:
+		var err error
+		if rg.start, err = netip.ParseAddr(fields[0]); err != nil {
+			return rg, err
+		}
+		if rg.end, err = netip.ParseAddr(fields[1]); err != nil {
+			return rg, err
+		}
+	default:
+		return rg, fmt.Errorf("want start,end,country or cidr,country, got %d fields", len(fields))
+	}
+	rg.start, rg.end = rg.start.Unmap(), rg.end.Unmap()
+	if rg.start.BitLen() != rg.end.BitLen() || rg.end.Less(rg.start) {
+		return rg, fmt.Errorf("invalid range %s-%s", rg.start, rg.end)
+	}
+	rg.country = NormalizeCountry(fields[len(fields)-1])
+	if rg.country == Unknown {
+		return rg, fmt.Errorf("invalid country %q", fields[len(fields)-1])
+	}
+	return rg, nil
+}
+
+// lastAddr is the highest address of p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// Len is the number of ranges loaded.
+func (db *DB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.ranges)
+}
+
+// Country returns the country of ip, or Unknown.
+func (db *DB) Country(ip string) string {
+	if db == nil {
+		return Unknown
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return Unknown
+	}
+	addr = addr.Unmap().WithZone("")
+	// The last range starting at or before addr is the only one that can
+	// hold it
+	i := sort.Search(len(db.ranges), func(i int) bool { return addr.Less(db.ranges[i].start) }) - 1
+	if i < 0 {
+		return Unknown
+	}
+	rg := db.ranges[i]
+	if rg.start.BitLen() != addr.BitLen() || rg.end.Less(addr) {
+		return Unknown
+	}
+	return rg.country
+}
+
+// NormalizeCountry upper-cases a two-letter country code, returning Unknown
+// for anything else.
+func NormalizeCountry(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return Unknown
+	}
+	return code
+}
+
+var current struct {
+	sync.RWMutex
+	db *DB
+}
+
+// Set makes db the database Lookup uses. main calls it once loaded.
+func Set(db *DB) {
+	current.Lock()
+	current.db = db
+	current.Unlock()
+}
+
+// Lookup returns the country of ip in the database set at startup, or
+// Unknown when none is set.
+func Lookup(ip string) string {
+	current.RLock()
+	db := current.db
+	current.RUnlock()
+	return db.Country(ip)
+}
+
+// Loaded reports whether a database with at least one range is set.
+func Loaded() bool {
+	current.RLock()
+	db := current.db
+	current.RUnlock()
+	return db.Len() > 0
+}
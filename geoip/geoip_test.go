@@ -0,0 +1,58 @@
+package geoip
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `# start,end,country
+1.0.0.0,1.0.0.255,AU
+"36.64.0.0","36.95.255.255","ID"
+103.10.0.0/16,sg
+2001:db8::/32,NL
+`
+
+func TestCountry(t *testing.T) {
+	db, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 4 {
+		t.Fatalf("loaded %d ranges, want 4", db.Len())
+	}
+	for ip, want := range map[string]string{
+		"1.0.0.0":          "AU",
+		"1.0.0.255":        "AU",
+		"1.0.1.0":          Unknown,
+		"36.80.1.2":        "ID",
+		"::ffff:36.80.1.2": "ID",
+		"103.10.255.255":   "SG",
+		"103.11.0.0":       Unknown,
+		"2001:db8::1":      "NL",
+		"2001:db9::1":      Unknown,
+		"10.0.0.1":         Unknown,
+		"not an ip":        Unknown,
+	} {
+		if got := db.Country(ip); got != want {
+			t.Errorf("Country(%q) = %q, want %q", ip, got, want)
+		}
+	}
+	var none *DB
+	if got := none.Country("36.80.1.2"); got != Unknown {
+		t.Errorf("nil DB answered %q", got)
+	}
+}
+
+func TestParseRejectsMalformedLines(t *testing.T) {
+	for _, bad := range []string{
+		"1.0.0.0,AU",
+		"1.0.0.9,1.0.0.1,AU",
+		"1.0.0.0,2001:db8::1,AU",
+		"1.0.0.0,1.0.0.255,Australia",
+		"1.0.0.0,1.0.0.255",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package i18n holds the message catalogs for user-facing API responses and
+// the helpers to pick a locale per request.
+//
+// Keys are either an error code (e.g. "PRODUCT_NOT_FOUND", the default message
+// for that code) or a dotted key for messages that are not tied to one code.
+// Indonesian is the source language: every key must exist in the id catalog,
+// other locales fall back to it.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale is a supported response language.
+type Locale string
+
+const (
+	ID Locale = "id"
+	EN Locale = "en"
+)
+
+// Default is used when neither the user nor the request asks for a locale.
+const Default = ID
+
+type ctxKey struct{}
+
+// Parse maps a language tag ("en", "en-US", "id_ID") to a supported locale.
+func Parse(tag string) (Locale, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	switch Locale(tag) {
+	case ID, EN:
+		return Locale(tag), true
+	case "in": // legacy code for Indonesian still sent by older Android builds
+		return ID, true
+	}
+	return "", false
+}
+
+// FromAcceptLanguage returns the supported locale with the highest q-value in
+// an Accept-Language header, or Default when none is supported.
+func FromAcceptLanguage(header string) Locale {
+	type candidate struct {
+		locale Locale
+		q      float64
+		pos    int
+	}
+	var cands []candidate
+	for pos, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		loc, ok := Parse(fields[0])
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, p := range fields[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			cands = append(cands, candidate{loc, q, pos})
+		}
+	}
+	if len(cands) == 0 {
+		return Default
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].q > cands[j].q })
+	return cands[0].locale
+}
+
+// WithLocale returns a copy of ctx carrying l.
+func WithLocale(ctx context.Context, l Locale) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the locale stored by WithLocale, or Default.
+func FromContext(ctx context.Context) Locale {
+	if l, ok := ctx.Value(ctxKey{}).(Locale); ok {
+		return l
+	}
+	return Default
+}
+
+// T returns the message for key in locale l, formatted with args. Missing
+// translations fall back to the Indonesian text, then to the key itself.
+func T(l Locale, key string, args ...interface{}) string {
+	msg, ok := catalogs[l][key]
+	if !ok {
+		msg, ok = catalogs[Default][key]
+	}
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Has reports whether key exists in the catalog of locale l.
+func Has(l Locale, key string) bool {
+	_, ok := catalogs[l][key]
+	return ok
+}
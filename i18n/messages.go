@@ -0,0 +1,418 @@
+package i18n
+
+// Message keys that are not error codes. Error codes (utils.Code*) are keys
+// too and resolve to the default message for that code.
+const (
+	MsgSystemError    = "error.system"
+	MsgGenericError   = "error.generic"
+	MsgLocaleUpdated  = "user.locale_updated"
+	MsgSuccess        = "common.success"
+	MsgInvalidID      = "common.invalid_id"
+	MsgDataNotFound   = "common.data_not_found"
+	MsgCategoryFailed = "investment.category_load_failed"
+	MsgListFailed     = "investment.list_failed"
+
+	MsgInvestmentBankInvalid     = "investment.bank_invalid"
+	MsgInvestmentCategoryInvalid = "investment.category_invalid"
+	MsgInvestmentCreateFailed    = "investment.create_failed"
+	MsgInvestmentCreated         = "investment.created"
+	MsgInvestmentTopupCreated    = "investment.topup_created"
+	MsgInvestmentTopupApplied    = "investment.topup_applied"
+	MsgInvestmentTopupFailed     = "investment.topup_failed"
+	MsgInvestmentAmountInvalid   = "investment.amount_invalid"
+
+	MsgPaymentQRISMax           = "payment.qris_max"
+	MsgPaymentBankMin           = "payment.bank_min"
+	MsgPaymentGatewayNoResponse = "payment.gateway_no_response"
+	MsgPaymentInvestmentFailed  = "payment.investment_load_failed"
+	MsgPaymentProductFailed     = "payment.product_load_failed"
+	MsgPaymentProofUploaded     = "payment.proof_uploaded"
+
+	MsgDepositMin          = "deposit.min_amount"
+	MsgDepositMax          = "deposit.max_amount"
+	MsgDepositCreateFailed = "deposit.create_failed"
+	MsgDepositCreated      = "deposit.created"
+	MsgDepositListFailed   = "deposit.list_failed"
+
+	MsgWithdrawalMin             = "withdrawal.min_amount"
+	MsgWithdrawalMax             = "withdrawal.max_amount"
+	MsgWithdrawalClosedSunday    = "withdrawal.closed_sunday"
+	MsgWithdrawalAccountNotFound = "withdrawal.account_not_found"
+	MsgWithdrawalBankMaintenance = "withdrawal.bank_maintenance"
+	MsgWithdrawalCreated         = "withdrawal.created"
+	MsgWithdrawalListFailed      = "withdrawal.list_failed"
+	MsgWithdrawalSLAStandard     = "withdrawal.sla_standard"
+	MsgWithdrawalSLAExpress      = "withdrawal.sla_express"
+	MsgWithdrawalSLAReview       = "withdrawal.sla_review"
+
+	MsgDeviceRegistered      = "device.registered"
+	MsgDeviceRegisterFailed  = "device.register_failed"
+	MsgPreferencesUpdated    = "notification.preferences_updated"
+	MsgPreferencesLoadFailed = "notification.preferences_load_failed"
+	MsgPreferencesSaveFailed = "notification.preferences_save_failed"
+	MsgMissionClaimed        = "mission.claimed"
+
+	MsgPushPaymentSuccessTitle    = "push.payment_success.title"
+	MsgPushPaymentSuccessBody     = "push.payment_success.body"
+	MsgPushPaymentExpiringTitle   = "push.payment_expiring.title"
+	MsgPushPaymentExpiringBody    = "push.payment_expiring.body"
+	MsgPushDepositExpiringBody    = "push.deposit_expiring.body"
+	MsgPushPaymentPartialTitle    = "push.payment_partial.title"
+	MsgPushPaymentPartialBody     = "push.payment_partial.body"
+	MsgPushPaymentOverpaidTitle   = "push.payment_overpaid.title"
+	MsgPushPaymentOverpaidBody    = "push.payment_overpaid.body"
+	MsgPushPaymentRefundedTitle   = "push.payment_refunded.title"
+	MsgPushPaymentRefundedBody    = "push.payment_refunded.body"
+	MsgPushPaymentRejectedTitle   = "push.payment_rejected.title"
+	MsgPushPaymentRejectedBody    = "push.payment_rejected.body"
+	MsgPushRefundSentTitle        = "push.refund_sent.title"
+	MsgPushRefundSentBody         = "push.refund_sent.body"
+	MsgPushRefundPaidTitle        = "push.refund_paid.title"
+	MsgPushRefundPaidBody         = "push.refund_paid.body"
+	MsgPushRefundBalanceTitle     = "push.refund_balance.title"
+	MsgPushRefundBalanceBody      = "push.refund_balance.body"
+	MsgPushProfitCreditedTitle    = "push.profit_credited.title"
+	MsgPushProfitCreditedBody     = "push.profit_credited.body"
+	MsgPushInvestmentDoneTitle    = "push.investment_completed.title"
+	MsgPushInvestmentDoneBody     = "push.investment_completed.body"
+	MsgPushWithdrawalSuccessTitle = "push.withdrawal_success.title"
+	MsgPushWithdrawalSuccessBody  = "push.withdrawal_success.body"
+	MsgPushWithdrawalFailedTitle  = "push.withdrawal_failed.title"
+	MsgPushWithdrawalFailedBody   = "push.withdrawal_failed.body"
+	MsgPushWithdrawalRetryTitle   = "push.withdrawal_retry.title"
+	MsgPushWithdrawalRetryBody    = "push.withdrawal_retry.body"
+	MsgPushTicketAnsweredTitle    = "push.ticket_answered.title"
+	MsgPushTicketAnsweredBody     = "push.ticket_answered.body"
+	MsgPushTicketClosedTitle      = "push.ticket_closed.title"
+	MsgPushTicketClosedBody       = "push.ticket_closed.body"
+	MsgPushVIPUpgradedTitle       = "push.vip_upgraded.title"
+	MsgPushVIPUpgradedBody        = "push.vip_upgraded.body"
+	MsgPushVIPDowngradedTitle     = "push.vip_downgraded.title"
+	MsgPushVIPDowngradedBody      = "push.vip_downgraded.body"
+)
+
+var catalogs = map[Locale]map[string]string{
+	ID: {
+		// Error code defaults
+		"BAD_REQUEST":                    "Permintaan tidak valid",
+		"UNAUTHORIZED":                   "Unauthorized",
+		"FORBIDDEN":                      "Akses ditolak",
+		"NOT_FOUND":                      "Data tidak ditemukan",
+		"METHOD_NOT_ALLOWED":             "Metode tidak diizinkan",
+		"CONFLICT":                       "Data bertentangan dengan kondisi saat ini",
+		"PAYLOAD_TOO_LARGE":              "Request body terlalu besar",
+		"UNSUPPORTED_MEDIA_TYPE":         "Content-Type tidak didukung",
+		"RATE_LIMITED":                   "Terlalu banyak permintaan, silakan coba lagi nanti",
+		"INTERNAL_ERROR":                 "Terjadi kesalahan, coba lagi",
+		"BAD_GATEWAY":                    "Layanan eksternal sedang bermasalah",
+		"SERVICE_UNAVAILABLE":            "Layanan sedang tidak tersedia",
+		"INVALID_JSON":                   "Format JSON tidak valid",
+		"VALIDATION_FAILED":              "Data tidak valid",
+		"INVALID_CREDENTIALS":            "Nomor telpon atau password salah",
+		"INVALID_REFRESH_TOKEN":          "Invalid refresh token",
+		"SESSION_REVOKED":                "Sesi anda telah berakhir, silahkan login kembali.",
+		"PHONE_ALREADY_REGISTERED":       "Nomor telepon sudah terdaftar",
+		"INVALID_REFERRAL_CODE":          "Kode referral tidak valid",
+		"PASSWORD_MISMATCH":              "Konfirmasi kata sandi tidak cocok",
+		"WRONG_CURRENT_PASSWORD":         "Kata sandi saat ini tidak cocok",
+		"MAINTENANCE":                    "Aplikasi sedang dalam pemeliharaan. Silakan coba lagi nanti.",
+		"REGION_BLOCKED":                 "Layanan ini tidak tersedia di wilayah Anda",
+		"DATABASE_TIMEOUT":               "Server sedang sibuk, silakan coba lagi",
+		"USER_NOT_FOUND":                 "User tidak ditemukan",
+		"PRODUCT_NOT_FOUND":              "Produk tidak ditemukan",
+		"CATEGORY_NOT_FOUND":             "Kategori tidak ditemukan",
+		"CATEGORY_IN_USE":                "Kategori masih digunakan",
+		"VIP_REQUIRED":                   "Produk %s memerlukan VIP level %d. Level VIP Anda saat ini: %d",
+		"PURCHASE_LIMIT_REACHED":         "Anda telah mencapai batas pembelian untuk produk %s (maksimal %dx)",
+		"PURCHASE_COOLDOWN":              "Produk %s baru dapat dibeli lagi pada %s",
+		"VIP_ACTIVE_INVESTMENT_LIMIT":    "VIP level %d dapat memiliki maksimal %d investasi aktif. Investasi aktif Anda: %d",
+		"INVESTMENT_NOT_FOUND":           "Investasi tidak ditemukan",
+		"INVESTMENT_RECAP_NOT_FOUND":     "Ringkasan investasi belum tersedia",
+		"PAYMENT_NOT_FOUND":              "Data pembayaran tidak ditemukan",
+		"PAYMENT_AMOUNT_OUT_OF_RANGE":    "Jumlah pembayaran di luar batas metode pembayaran",
+		"PAYMENT_GATEWAY_ERROR":          "Terjadi kesalahan saat memanggil layanan pembayaran",
+		"PAYMENT_CHANNEL_DISABLED":       "Metode pembayaran ini sedang tidak tersedia, silakan pilih metode lain",
+		"PAYMENT_GATEWAY_DEGRADED":       "Layanan pembayaran sedang mengalami gangguan, silakan coba lagi nanti",
+		"MANUAL_PAYMENT_UNAVAILABLE":     "Transfer bank manual sedang tidak tersedia",
+		"PAYMENT_CLOSED":                 "Pembayaran ini sudah tidak menunggu bukti transfer",
+		"DEPOSIT_AMOUNT_OUT_OF_RANGE":    "Jumlah deposit di luar batas",
+		"TOPUP_UNAVAILABLE":              "Investasi ini tidak dapat ditambah modal",
+		"TOPUP_AMOUNT_OUT_OF_RANGE":      "Jumlah tambah modal harus antara Rp%d dan Rp%d",
+		"TOPUP_PENDING":                  "Masih ada tambah modal yang menunggu pembayaran",
+		"INSUFFICIENT_BALANCE":           "Saldo tidak mencukupi",
+		"WITHDRAWAL_NOT_FOUND":           "Penarikan tidak ditemukan",
+		"WITHDRAWAL_AMOUNT_OUT_OF_RANGE": "Jumlah penarikan di luar batas",
+		"WITHDRAWAL_OUTSIDE_HOURS":       "Penarikan hanya dapat dilakukan pada pukul %02d:00 - %02d:00 WIB",
+		"WITHDRAWAL_DAILY_LIMIT":         "Anda hanya dapat melakukan 1 kali penarikan dalam sehari",
+		"VIP_DAILY_WITHDRAWAL_LIMIT":     "Batas penarikan harian VIP level %d adalah Rp%d. Sisa hari ini: Rp%d",
+		"VIP_SINGLE_WITHDRAWAL_LIMIT":    "Penarikan maksimal untuk VIP level %d adalah Rp%d per transaksi",
+		"EXPRESS_WITHDRAWAL_UNAVAILABLE": "Penarikan ekspres sedang tidak tersedia",
+		"BANK_ACCOUNT_NOT_FOUND":         "Rekening tidak ditemukan",
+		"BANK_ACCOUNT_LIMIT_REACHED":     "Anda sudah mencapai batas maksimal 3 rekening bank",
+		"BANK_ACCOUNT_DUPLICATE":         "Rekening ini sudah pernah didaftarkan",
+		"BANK_UNAVAILABLE":               "Bank tidak valid",
+		"BANK_DISABLED":                  "Bank ini sedang tidak tersedia, silakan pilih bank lain",
+		"NO_SPIN_TICKET":                 "Tiket spin Anda habis, silakan dapatkan tiket terlebih dahulu",
+		"SPIN_PRIZE_UNAVAILABLE":         "Hadiah tidak valid atau sudah tidak tersedia",
+		"TASK_ALREADY_CLAIMED":           "Tugas sudah pernah diambil",
+		"TASK_REQUIREMENTS_NOT_MET":      "Belum memenuhi syarat tugas",
+		"FORUM_WITHDRAWAL_REQUIRED":      "Tidak ada penarikan dalam 3 hari terakhir",
+		"INVALID_IMAGE":                  "Gambar harus JPG/PNG",
+		"TICKET_NOT_FOUND":               "Tiket tidak ditemukan",
+		"TICKET_CLOSED":                  "Tiket sudah ditutup, silakan buat tiket baru",
+		"MISSION_NOT_FOUND":              "Misi tidak ditemukan",
+		"MISSION_NOT_COMPLETED":          "Misi belum selesai",
+		"MISSION_ALREADY_CLAIMED":        "Hadiah misi sudah diambil",
+		"MISSION_EXPIRED":                "Misi sudah berakhir",
+		"TRANSACTION_NOT_FOUND":          "Transaksi tidak ditemukan",
+		"CAMPAIGN_NOT_FOUND":             "Promo tidak ditemukan atau sudah berakhir",
+
+		MsgSystemError:    "Terjadi kesalahan sistem, silakan coba lagi",
+		MsgGenericError:   "Terjadi kesalahan",
+		MsgLocaleUpdated:  "Bahasa berhasil diperbarui",
+		MsgSuccess:        "Successfully",
+		MsgInvalidID:      "ID tidak valid",
+		MsgDataNotFound:   "Data tidak ditemukan",
+		MsgCategoryFailed: "Gagal mengambil kategori",
+		MsgListFailed:     "Gagal mengambil investasi",
+
+		MsgInvestmentBankInvalid:     "Bank tidak valid",
+		MsgInvestmentCategoryInvalid: "Kategori produk tidak valid",
+		MsgInvestmentCreateFailed:    "Gagal membuat investasi",
+		MsgInvestmentCreated:         "Pembelian berhasil, silakan lakukan pembayaran",
+		MsgInvestmentTopupCreated:    "Tambah modal dibuat, silakan lakukan pembayaran",
+		MsgInvestmentTopupApplied:    "Modal investasi berhasil ditambah",
+		MsgInvestmentTopupFailed:     "Gagal menambah modal investasi",
+		MsgInvestmentAmountInvalid:   "Jumlah investasi tidak valid",
+
+		MsgPaymentQRISMax:           "Jumlah pembayaran maksimal menggunakan QRIS adalah Rp 10.000.000, Silahkan gunakan metode pembayaran lain",
+		MsgPaymentBankMin:           "Jumlah pembayaran minimal menggunakan BANK adalah Rp 10.000, Silahkan gunakan metode pembayaran lain",
+		MsgPaymentGatewayNoResponse: "Gagal mendapatkan jawaban dari layanan pembayaran",
+		MsgPaymentInvestmentFailed:  "Terjadi kesalahan mengambil data investasi",
+		MsgPaymentProductFailed:     "Terjadi kesalahan mengambil data produk",
+		MsgPaymentProofUploaded:     "Bukti transfer diterima dan sedang diperiksa",
+
+		MsgDepositMin:          "Minimal deposit adalah Rp%.0f",
+		MsgDepositMax:          "Maksimal deposit adalah Rp%.0f",
+		MsgDepositCreateFailed: "Gagal membuat deposit",
+		MsgDepositCreated:      "Deposit berhasil dibuat, silakan lakukan pembayaran",
+		MsgDepositListFailed:   "Gagal mengambil data deposit",
+
+		MsgWithdrawalMin:             "Minimal penarikan adalah Rp%.0f",
+		MsgWithdrawalMax:             "Maksimal penarikan adalah Rp%.0f",
+		MsgWithdrawalClosedSunday:    "Penarikan hanya dapat dilakukan pada hari Senin sampai Sabtu",
+		MsgWithdrawalAccountNotFound: "Rekening tujuan tidak ditemukan",
+		MsgWithdrawalBankMaintenance: "Layanan bank ini sedang dalam pemeliharaan",
+		MsgWithdrawalCreated:         "Permintaan penarikan berhasil diproses",
+		MsgWithdrawalListFailed:      "Failed to retrieve withdrawal data",
+		MsgWithdrawalSLAStandard:     "Diproses dalam %d jam",
+		MsgWithdrawalSLAExpress:      "Dibayarkan dalam %d menit",
+		MsgWithdrawalSLAReview:       "Menunggu peninjauan admin",
+
+		MsgDeviceRegistered:      "Perangkat berhasil didaftarkan",
+		MsgDeviceRegisterFailed:  "Gagal mendaftarkan perangkat",
+		MsgPreferencesUpdated:    "Pengaturan notifikasi berhasil disimpan",
+		MsgPreferencesLoadFailed: "Gagal mengambil pengaturan notifikasi",
+		MsgPreferencesSaveFailed: "Gagal menyimpan pengaturan notifikasi",
+		MsgMissionClaimed:        "Hadiah misi berhasil diambil",
+
+		MsgPushPaymentSuccessTitle:    "Pembayaran berhasil",
+		MsgPushPaymentSuccessBody:     "Pembayaran %s sebesar Rp%d telah kami terima",
+		MsgPushPaymentExpiringTitle:   "Segera selesaikan pembayaran",
+		MsgPushPaymentExpiringBody:    "Pembayaran %s untuk %s kedaluwarsa pukul %s",
+		MsgPushDepositExpiringBody:    "Pembayaran isi saldo %s kedaluwarsa pukul %s",
+		MsgPushPaymentPartialTitle:    "Pembayaran kurang",
+		MsgPushPaymentPartialBody:     "Pembayaran %s kurang Rp%d dari tagihan. Dana Rp%d yang diterima akan dikembalikan",
+		MsgPushPaymentOverpaidTitle:   "Kelebihan pembayaran",
+		MsgPushPaymentOverpaidBody:    "Kelebihan pembayaran %s sebesar Rp%d sedang ditinjau dan masuk ke saldo Anda setelah disetujui",
+		MsgPushPaymentRefundedTitle:   "Dana dikembalikan",
+		MsgPushPaymentRefundedBody:    "Dana Rp%d dari pembayaran %s yang kurang sedang dikembalikan",
+		MsgPushPaymentRejectedTitle:   "Bukti transfer ditolak",
+		MsgPushPaymentRejectedBody:    "Bukti transfer untuk pembayaran %s ditolak: %s",
+		MsgPushRefundSentTitle:        "Refund sedang ditransfer",
+		MsgPushRefundSentBody:         "Refund investasi %s sebesar Rp%d sedang ditransfer ke rekening Anda",
+		MsgPushRefundPaidTitle:        "Refund diterima",
+		MsgPushRefundPaidBody:         "Refund investasi %s sebesar Rp%d telah ditransfer ke rekening Anda",
+		MsgPushRefundBalanceTitle:     "Refund masuk ke saldo",
+		MsgPushRefundBalanceBody:      "Refund investasi %s sebesar Rp%d telah masuk ke saldo Anda. Silakan ajukan penarikan ke rekening Anda",
+		MsgPushProfitCreditedTitle:    "Profit masuk",
+		MsgPushProfitCreditedBody:     "Profit Rp%d dari %s telah masuk ke saldo Anda",
+		MsgPushInvestmentDoneTitle:    "Investasi selesai",
+		MsgPushInvestmentDoneBody:     "Investasi %s selesai: modal Rp%d telah kembali dengan total keuntungan Rp%d (%.2f%%)",
+		MsgPushWithdrawalSuccessTitle: "Penarikan berhasil",
+		MsgPushWithdrawalSuccessBody:  "Penarikan %s sebesar Rp%d telah diproses",
+		MsgPushWithdrawalFailedTitle:  "Penarikan ditolak",
+		MsgPushWithdrawalFailedBody:   "Penarikan %s ditolak, Rp%d telah dikembalikan ke saldo Anda",
+		MsgPushWithdrawalRetryTitle:   "Penarikan tertunda",
+		MsgPushWithdrawalRetryBody:    "Transfer penarikan %s gagal dan akan diproses ulang",
+		MsgPushTicketAnsweredTitle:    "Balasan dari CS",
+		MsgPushTicketAnsweredBody:     "Tiket #%d \"%s\" telah dibalas",
+		MsgPushTicketClosedTitle:      "Tiket ditutup",
+		MsgPushTicketClosedBody:       "Tiket #%d \"%s\" telah ditutup",
+		MsgPushVIPUpgradedTitle:       "Level VIP naik",
+		MsgPushVIPUpgradedBody:        "Selamat, Anda sekarang VIP %d",
+		MsgPushVIPDowngradedTitle:     "Level VIP berubah",
+		MsgPushVIPDowngradedBody:      "Level VIP Anda sekarang VIP %d",
+	},
+	EN: {
+		"BAD_REQUEST":                    "Invalid request",
+		"UNAUTHORIZED":                   "Unauthorized",
+		"FORBIDDEN":                      "Access denied",
+		"NOT_FOUND":                      "Data not found",
+		"METHOD_NOT_ALLOWED":             "Method not allowed",
+		"CONFLICT":                       "Request conflicts with the current state",
+		"PAYLOAD_TOO_LARGE":              "Request body is too large",
+		"UNSUPPORTED_MEDIA_TYPE":         "Unsupported Content-Type",
+		"RATE_LIMITED":                   "Too many requests, please try again later",
+		"INTERNAL_ERROR":                 "Something went wrong, please try again",
+		"BAD_GATEWAY":                    "An external service is having problems",
+		"SERVICE_UNAVAILABLE":            "Service is temporarily unavailable",
+		"INVALID_JSON":                   "Invalid JSON format",
+		"VALIDATION_FAILED":              "Invalid data",
+		"INVALID_CREDENTIALS":            "Wrong phone number or password",
+		"INVALID_REFRESH_TOKEN":          "Invalid refresh token",
+		"SESSION_REVOKED":                "Your session has ended, please log in again.",
+		"PHONE_ALREADY_REGISTERED":       "Phone number is already registered",
+		"INVALID_REFERRAL_CODE":          "Invalid referral code",
+		"PASSWORD_MISMATCH":              "Password confirmation does not match",
+		"WRONG_CURRENT_PASSWORD":         "Current password is incorrect",
+		"MAINTENANCE":                    "The app is under maintenance. Please try again later.",
+		"REGION_BLOCKED":                 "This service is not available in your region",
+		"DATABASE_TIMEOUT":               "The server is busy, please try again",
+		"USER_NOT_FOUND":                 "User not found",
+		"PRODUCT_NOT_FOUND":              "Product not found",
+		"CATEGORY_NOT_FOUND":             "Category not found",
+		"CATEGORY_IN_USE":                "Category is still in use",
+		"VIP_REQUIRED":                   "Product %s requires VIP level %d. Your current VIP level: %d",
+		"PURCHASE_LIMIT_REACHED":         "You have reached the purchase limit for product %s (maximum %dx)",
+		"PURCHASE_COOLDOWN":              "Product %s can be bought again at %s",
+		"VIP_ACTIVE_INVESTMENT_LIMIT":    "VIP level %d can hold at most %d active investments. Your active investments: %d",
+		"INVESTMENT_NOT_FOUND":           "Investment not found",
+		"INVESTMENT_RECAP_NOT_FOUND":     "Investment recap not available",
+		"PAYMENT_NOT_FOUND":              "Payment not found",
+		"PAYMENT_AMOUNT_OUT_OF_RANGE":    "Amount is outside the limits of this payment method",
+		"PAYMENT_GATEWAY_ERROR":          "Something went wrong while contacting the payment service",
+		"PAYMENT_CHANNEL_DISABLED":       "This payment method is not available right now, please choose another",
+		"PAYMENT_GATEWAY_DEGRADED":       "The payment service is having problems, please try again later",
+		"MANUAL_PAYMENT_UNAVAILABLE":     "Manual bank transfer is not available right now",
+		"PAYMENT_CLOSED":                 "This payment is no longer waiting for a transfer proof",
+		"DEPOSIT_AMOUNT_OUT_OF_RANGE":    "Deposit amount is out of range",
+		"TOPUP_UNAVAILABLE":              "This investment cannot be topped up",
+		"TOPUP_AMOUNT_OUT_OF_RANGE":      "The top-up amount must be between Rp%d and Rp%d",
+		"TOPUP_PENDING":                  "A top-up is still awaiting payment",
+		"INSUFFICIENT_BALANCE":           "Insufficient balance",
+		"WITHDRAWAL_NOT_FOUND":           "Withdrawal not found",
+		"WITHDRAWAL_AMOUNT_OUT_OF_RANGE": "Withdrawal amount is out of range",
+		"WITHDRAWAL_OUTSIDE_HOURS":       "Withdrawals are only available between %02d:00 and %02d:00 WIB",
+		"WITHDRAWAL_DAILY_LIMIT":         "You can only make 1 withdrawal per day",
+		"VIP_DAILY_WITHDRAWAL_LIMIT":     "The daily withdrawal limit for VIP level %d is Rp%d. Left today: Rp%d",
+		"VIP_SINGLE_WITHDRAWAL_LIMIT":    "The maximum withdrawal for VIP level %d is Rp%d per request",
+		"EXPRESS_WITHDRAWAL_UNAVAILABLE": "Express withdrawals are currently unavailable",
+		"BANK_ACCOUNT_NOT_FOUND":         "Bank account not found",
+		"BANK_ACCOUNT_LIMIT_REACHED":     "You already have the maximum of 3 bank accounts",
+		"BANK_ACCOUNT_DUPLICATE":         "This bank account is already registered",
+		"BANK_UNAVAILABLE":               "Invalid bank",
+		"BANK_DISABLED":                  "This bank is currently unavailable, please choose another",
+		"NO_SPIN_TICKET":                 "You have no spin tickets left, please get a ticket first",
+		"SPIN_PRIZE_UNAVAILABLE":         "Prize is invalid or no longer available",
+		"TASK_ALREADY_CLAIMED":           "Task reward was already claimed",
+		"TASK_REQUIREMENTS_NOT_MET":      "Task requirements are not met yet",
+		"FORUM_WITHDRAWAL_REQUIRED":      "No withdrawal in the last 3 days",
+		"INVALID_IMAGE":                  "Image must be JPG/PNG",
+		"TICKET_NOT_FOUND":               "Ticket not found",
+		"TICKET_CLOSED":                  "This ticket is closed, please open a new one",
+		"MISSION_NOT_FOUND":              "Mission not found",
+		"MISSION_NOT_COMPLETED":          "Mission is not completed yet",
+		"MISSION_ALREADY_CLAIMED":        "Mission reward was already claimed",
+		"MISSION_EXPIRED":                "Mission has ended",
+		"TRANSACTION_NOT_FOUND":          "Transaction not found",
+		"CAMPAIGN_NOT_FOUND":             "Campaign not found or has ended",
+
+		MsgSystemError:    "A system error occurred, please try again",
+		MsgGenericError:   "Something went wrong",
+		MsgLocaleUpdated:  "Language updated",
+		MsgSuccess:        "Successfully",
+		MsgInvalidID:      "Invalid ID",
+		MsgDataNotFound:   "Data not found",
+		MsgCategoryFailed: "Failed to load categories",
+		MsgListFailed:     "Failed to load investments",
+
+		MsgInvestmentBankInvalid:     "Invalid bank",
+		MsgInvestmentCategoryInvalid: "Invalid product category",
+		MsgInvestmentCreateFailed:    "Failed to create investment",
+		MsgInvestmentCreated:         "Purchase successful, please complete the payment",
+		MsgInvestmentTopupCreated:    "Top-up created, please complete the payment",
+		MsgInvestmentTopupApplied:    "Investment topped up",
+		MsgInvestmentTopupFailed:     "Failed to top up the investment",
+		MsgInvestmentAmountInvalid:   "Invalid investment amount",
+
+		MsgPaymentQRISMax:           "The maximum QRIS payment is Rp 10,000,000, please use another payment method",
+		MsgPaymentBankMin:           "The minimum BANK payment is Rp 10,000, please use another payment method",
+		MsgPaymentGatewayNoResponse: "No response from the payment service",
+		MsgPaymentInvestmentFailed:  "Failed to load investment data",
+		MsgPaymentProductFailed:     "Failed to load product data",
+		MsgPaymentProofUploaded:     "Transfer proof received and under review",
+
+		MsgDepositMin:          "The minimum deposit is Rp%.0f",
+		MsgDepositMax:          "The maximum deposit is Rp%.0f",
+		MsgDepositCreateFailed: "Failed to create deposit",
+		MsgDepositCreated:      "Deposit created, please complete the payment",
+		MsgDepositListFailed:   "Failed to load deposits",
+
+		MsgWithdrawalMin:             "The minimum withdrawal is Rp%.0f",
+		MsgWithdrawalMax:             "The maximum withdrawal is Rp%.0f",
+		MsgWithdrawalClosedSunday:    "Withdrawals are only available Monday to Saturday",
+		MsgWithdrawalAccountNotFound: "Destination bank account not found",
+		MsgWithdrawalBankMaintenance: "This bank is under maintenance",
+		MsgWithdrawalCreated:         "Withdrawal request submitted",
+		MsgWithdrawalListFailed:      "Failed to retrieve withdrawal data",
+		MsgWithdrawalSLAStandard:     "Processed within %d hours",
+		MsgWithdrawalSLAExpress:      "Paid out within %d minutes",
+		MsgWithdrawalSLAReview:       "Awaiting admin review",
+
+		MsgDeviceRegistered:      "Device registered",
+		MsgDeviceRegisterFailed:  "Failed to register device",
+		MsgPreferencesUpdated:    "Notification settings saved",
+		MsgPreferencesLoadFailed: "Failed to load notification settings",
+		MsgPreferencesSaveFailed: "Failed to save notification settings",
+		MsgMissionClaimed:        "Mission reward claimed",
+
+		MsgPushPaymentSuccessTitle:    "Payment received",
+		MsgPushPaymentSuccessBody:     "We received your payment %s of Rp%d",
+		MsgPushPaymentExpiringTitle:   "Complete your payment",
+		MsgPushPaymentExpiringBody:    "Your payment %s for %s expires at %s",
+		MsgPushDepositExpiringBody:    "Your top-up payment %s expires at %s",
+		MsgPushPaymentPartialTitle:    "Payment incomplete",
+		MsgPushPaymentPartialBody:     "Payment %s is Rp%d short. The Rp%d received will be refunded",
+		MsgPushPaymentOverpaidTitle:   "Overpayment under review",
+		MsgPushPaymentOverpaidBody:    "The Rp%[2]d overpaid on %[1]s is being reviewed and will be added to your balance once approved",
+		MsgPushPaymentRefundedTitle:   "Payment refunded",
+		MsgPushPaymentRefundedBody:    "Rp%d from your incomplete payment %s is being refunded",
+		MsgPushPaymentRejectedTitle:   "Transfer proof rejected",
+		MsgPushPaymentRejectedBody:    "The transfer proof for payment %s was rejected: %s",
+		MsgPushRefundSentTitle:        "Refund on its way",
+		MsgPushRefundSentBody:         "The Rp%[2]d refund of your %[1]s investment is being transferred to your bank account",
+		MsgPushRefundPaidTitle:        "Refund received",
+		MsgPushRefundPaidBody:         "The Rp%[2]d refund of your %[1]s investment has been transferred to your bank account",
+		MsgPushRefundBalanceTitle:     "Refund added to balance",
+		MsgPushRefundBalanceBody:      "The Rp%[2]d refund of your %[1]s investment was added to your balance. Please request a withdrawal to your bank account",
+		MsgPushProfitCreditedTitle:    "Profit credited",
+		MsgPushProfitCreditedBody:     "Profit of Rp%d from %s was added to your balance",
+		MsgPushInvestmentDoneTitle:    "Investment completed",
+		MsgPushInvestmentDoneBody:     "Your %s investment is complete: Rp%d capital returned with Rp%d earned in total (%.2f%%)",
+		MsgPushWithdrawalSuccessTitle: "Withdrawal completed",
+		MsgPushWithdrawalSuccessBody:  "Withdrawal %s of Rp%d has been processed",
+		MsgPushWithdrawalFailedTitle:  "Withdrawal rejected",
+		MsgPushWithdrawalFailedBody:   "Withdrawal %s was rejected and Rp%d was returned to your balance",
+		MsgPushWithdrawalRetryTitle:   "Withdrawal delayed",
+		MsgPushWithdrawalRetryBody:    "The transfer for withdrawal %s failed and will be retried",
+		MsgPushTicketAnsweredTitle:    "Support replied",
+		MsgPushTicketAnsweredBody:     "Ticket #%d \"%s\" has a new reply",
+		MsgPushTicketClosedTitle:      "Ticket closed",
+		MsgPushTicketClosedBody:       "Ticket #%d \"%s\" has been closed",
+		MsgPushVIPUpgradedTitle:       "VIP level up",
+		MsgPushVIPUpgradedBody:        "Congratulations, you are now VIP %d",
+		MsgPushVIPDowngradedTitle:     "VIP level changed",
+		MsgPushVIPDowngradedBody:      "Your VIP level is now VIP %d",
+	},
+}
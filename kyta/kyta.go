@@ -0,0 +1,383 @@
+// Package kyta is the client for the KytaPay payment gateway: QRIS and virtual
+// account payments for investments, and payout transfers for withdrawals.
+//
+// Handlers depend on the Client interface so tests can swap in a stub or an
+// HTTPClient pointed at an httptest server.
+package kyta
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/config"
+)
+
+// DefaultBaseURL is used when the configuration has no base URL.
+const DefaultBaseURL = "https://api.kytapay.com/v2"
+
+// ErrNotConfigured is returned when the client id or secret is missing.
+var ErrNotConfigured = errors.New("kyta: client id or secret not configured")
+
+// ErrInquiryUnsupported is returned by InquireAccount when the gateway cannot
+// look up accounts of the bank.
+var ErrInquiryUnsupported = errors.New("kyta: account inquiry not supported for this bank")
+
+// ErrCheckUnsupported is returned by a PaymentChecker that wraps a Client
+// unable to look payments up.
+var ErrCheckUnsupported = errors.New("kyta: payment status lookup not supported")
+
+// Client creates payments and payouts on the gateway.
+type Client interface {
+	CreateQRIS(ctx context.Context, p PaymentRequest) (*PaymentResponse, error)
+	CreateVA(ctx context.Context, p PaymentRequest) (*PaymentResponse, error)
+	CreatePayout(ctx context.Context, p PayoutRequest) (*PayoutResponse, error)
+}
+
+// AccountInquirer looks up the holder of a bank account before a payout to
+// it. Not every Client can; callers type-assert and skip the check otherwise.
+type AccountInquirer interface {
+	InquireAccount(ctx context.Context, bankCode, accountNumber string) (*AccountInquiryResponse, error)
+}
+
+// PaymentChecker looks up a payment's current status on the gateway, so a
+// callback that would undo a settled payment can be confirmed before it is
+// applied. Not every Client can; callers type-assert and treat the callback
+// as unconfirmed otherwise.
+type PaymentChecker interface {
+	CheckPayment(ctx context.Context, referenceID string) (*PaymentStatusResponse, error)
+}
+
+// PaymentRequest is a QRIS or virtual account payment. BankCode is only used for VA.
+type PaymentRequest struct {
+	ReferenceID string
+	Amount      int64
+	BankCode    string
+}
+
+// PayoutRequest is a transfer to a user's bank account.
+type PayoutRequest struct {
+	ReferenceID   string
+	Amount        int64
+	Description   string
+	BankCode      string
+	AccountNumber string
+	AccountName   string
+}
+
+type AccessTokenResponse struct {
+	ResponseCode    string `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+	ResponseData    struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+		RequestTime string `json:"request_time"`
+	} `json:"response_data"`
+}
+
+type PaymentResponse struct {
+	ResponseCode    string `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+	ResponseData    struct {
+		ID          string `json:"id"`
+		ReferenceID string `json:"reference_id"`
+		Amount      int64  `json:"amount"`
+		PaymentData struct {
+			QRString      string `json:"qr_string,omitempty"`
+			BankCode      string `json:"bank_code,omitempty"`
+			AccountNumber string `json:"account_number,omitempty"`
+			AccountName   string `json:"account_name,omitempty"`
+		} `json:"payment_data"`
+		MerchantURL struct {
+			NotifyURL  string `json:"notify_url"`
+			SuccessURL string `json:"success_url"`
+			FailedURL  string `json:"failed_url"`
+		} `json:"merchant_url"`
+		CheckoutURL string `json:"checkout_url"`
+		ExpiresAt   string `json:"expires_at"`
+		RequestTime string `json:"request_time"`
+	} `json:"response_data"`
+}
+
+type PayoutResponse struct {
+	ResponseCode    string `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+	ResponseData    struct {
+		ID          string `json:"id"`
+		ReferenceID string `json:"reference_id"`
+		Amount      int64  `json:"amount"`
+		PayoutData  struct {
+			Code          string `json:"code"`
+			AccountNumber string `json:"account_number"`
+			AccountName   string `json:"account_name"`
+		} `json:"payout_data,omitempty"`
+		MerchantURL struct {
+			NotifyURL string `json:"notify_url"`
+		} `json:"merchant_url,omitempty"`
+		RequestTime string `json:"request_time,omitempty"`
+	} `json:"response_data,omitempty"`
+}
+
+// PaymentStatusResponse is the gateway's current record of a payment.
+type PaymentStatusResponse struct {
+	ResponseCode    string `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+	ResponseData    struct {
+		ID          string `json:"id"`
+		ReferenceID string `json:"reference_id"`
+		Amount      int64  `json:"amount"`
+		Status      string `json:"status"`
+	} `json:"response_data"`
+}
+
+type AccountInquiryResponse struct {
+	ResponseCode    string `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+	ResponseData    struct {
+		Code          string `json:"code"`
+		AccountNumber string `json:"account_number"`
+		AccountName   string `json:"account_name"`
+	} `json:"response_data"`
+}
+
+// PaymentID is the gateway's id of the payment, nil when the response has none.
+func (r *PaymentResponse) PaymentID() *string {
+	if r == nil {
+		return nil
+	}
+	return nonEmpty(r.ResponseData.ID)
+}
+
+// PayoutID is the gateway's id of the payout, nil when the response has none.
+func (r *PayoutResponse) PayoutID() *string {
+	if r == nil {
+		return nil
+	}
+	return nonEmpty(r.ResponseData.ID)
+}
+
+func nonEmpty(s string) *string {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Error is a failed gateway call. Message is safe to show to an admin: it is
+// the gateway's response_message when there is one.
+type Error struct {
+	Message string
+	// Status is the HTTP status of a non-2xx reply, 0 otherwise
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string { return e.Message + ": " + e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// HTTPClient is the Client backed by the KytaPay REST API. It fetches a fresh
+// access token for every call.
+type HTTPClient struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+
+	// Callback URLs sent with payments and payouts
+	NotifyURL       string
+	SuccessURL      string
+	FailedURL       string
+	PayoutNotifyURL string
+
+	HTTP *http.Client
+}
+
+// New builds an HTTPClient from the KytaPay configuration.
+func New(c config.Kyta) *HTTPClient {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return &HTTPClient{
+		BaseURL:         base,
+		ClientID:        c.ClientID,
+		ClientSecret:    c.ClientSecret,
+		NotifyURL:       c.NotifyURL,
+		SuccessURL:      c.SuccessURL,
+		FailedURL:       c.FailedURL,
+		PayoutNotifyURL: c.PayoutNotifyURL,
+		HTTP:            &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *HTTPClient) CreateQRIS(ctx context.Context, p PaymentRequest) (*PaymentResponse, error) {
+	payload := map[string]interface{}{
+		"reference_id": p.ReferenceID,
+		"amount":       p.Amount,
+		"notify_url":   c.NotifyURL,
+		"success_url":  c.SuccessURL,
+		"failed_url":   c.FailedURL,
+		"expires_time": 900,
+	}
+	var resp PaymentResponse
+	if err := c.authorizedCall(ctx, "/payments/create/qris", payload, &resp, "Gagal membuat pembayaran QRIS"); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *HTTPClient) CreateVA(ctx context.Context, p PaymentRequest) (*PaymentResponse, error) {
+	payload := map[string]interface{}{
+		"reference_id": p.ReferenceID,
+		"amount":       p.Amount,
+		"bank_code":    p.BankCode,
+		"notify_url":   c.NotifyURL,
+		"success_url":  c.SuccessURL,
+		"failed_url":   c.FailedURL,
+		"expires_time": 900,
+	}
+	var resp PaymentResponse
+	if err := c.authorizedCall(ctx, "/payments/create/va", payload, &resp, "Gagal membuat pembayaran Virtual Account"); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *HTTPClient) CreatePayout(ctx context.Context, p PayoutRequest) (*PayoutResponse, error) {
+	payload := map[string]interface{}{
+		"reference_id": p.ReferenceID,
+		"amount":       p.Amount,
+		"description":  p.Description,
+		"destination": map[string]interface{}{
+			"code":           p.BankCode,
+			"account_number": p.AccountNumber,
+			"account_name":   p.AccountName,
+		},
+		"notify_url": c.PayoutNotifyURL,
+	}
+	var resp PayoutResponse
+	if err := c.authorizedCall(ctx, "/payouts/transfers", payload, &resp, "Gagal memproses payout"); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// InquireAccount asks the bank, through the gateway, who holds the account.
+// A 404 or 422 reply means the gateway cannot look up that bank's accounts
+// and is returned as ErrInquiryUnsupported.
+func (c *HTTPClient) InquireAccount(ctx context.Context, bankCode, accountNumber string) (*AccountInquiryResponse, error) {
+	payload := map[string]interface{}{
+		"code":           bankCode,
+		"account_number": accountNumber,
+	}
+	var resp AccountInquiryResponse
+	if err := c.authorizedCall(ctx, "/payouts/inquiry", payload, &resp, "Gagal memeriksa rekening"); err != nil {
+		var ke *Error
+		if errors.As(err, &ke) && (ke.Status == http.StatusNotFound || ke.Status == http.StatusUnprocessableEntity) {
+			return nil, &Error{Message: ke.Message, Status: ke.Status, Err: ErrInquiryUnsupported}
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CheckPayment fetches the gateway's record of the payment created with
+// referenceID. An unknown reference fails with a 404 *Error.
+func (c *HTTPClient) CheckPayment(ctx context.Context, referenceID string) (*PaymentStatusResponse, error) {
+	payload := map[string]interface{}{
+		"reference_id": referenceID,
+	}
+	var resp PaymentStatusResponse
+	if err := c.authorizedCall(ctx, "/payments/status", payload, &resp, "Gagal memeriksa status pembayaran"); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// accessToken exchanges the client credentials for a bearer token.
+func (c *HTTPClient) accessToken(ctx context.Context) (string, error) {
+	if c.ClientID == "" || c.ClientSecret == "" {
+		return "", ErrNotConfigured
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte(c.ClientID + ":" + c.ClientSecret))
+	var resp AccessTokenResponse
+	if err := c.post(ctx, "/access-token", "Basic "+basic, map[string]string{"grant_type": "client_credentials"}, &resp, "Gagal mendapatkan token pembayaran"); err != nil {
+		return "", err
+	}
+	if resp.ResponseData.AccessToken == "" {
+		return "", &Error{Message: "Token pembayaran kosong", Err: errors.New("empty token")}
+	}
+	return resp.ResponseData.AccessToken, nil
+}
+
+func (c *HTTPClient) authorizedCall(ctx context.Context, path string, payload, out interface{}, failMsg string) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	return c.post(ctx, path, "Bearer "+token, payload, out, failMsg)
+}
+
+// envelope is the part of every gateway reply that says whether it succeeded.
+type envelope struct {
+	ResponseCode    string `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+}
+
+// post sends payload as JSON and decodes the reply into out. The gateway's own
+// response_message is surfaced on failure; any response_code starting with
+// "200" counts as success.
+func (c *HTTPClient) post(ctx context.Context, path, authorization string, payload, out interface{}, failMsg string) error {
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return &Error{Message: failMsg, Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", authorization)
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return &Error{Message: "Koneksi ke layanan pembayaran gagal", Err: err}
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &Error{Message: "Gagal membaca response pembayaran", Err: err}
+	}
+	var env envelope
+	parseErr := json.Unmarshal(raw, &env)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := failMsg
+		if parseErr == nil && env.ResponseMessage != "" {
+			msg = env.ResponseMessage
+		} else if len(raw) > 0 && len(raw) < 500 {
+			msg = string(raw)
+		}
+		return &Error{Message: msg, Status: resp.StatusCode, Err: fmt.Errorf("status %d", resp.StatusCode)}
+	}
+	if parseErr == nil {
+		parseErr = json.Unmarshal(raw, out)
+	}
+	if parseErr != nil {
+		return &Error{Message: "Gagal parsing response pembayaran", Err: parseErr}
+	}
+	if env.ResponseCode != "" && !strings.HasPrefix(env.ResponseCode, "200") {
+		return &Error{Message: env.ResponseMessage, Err: fmt.Errorf("response code %s", env.ResponseCode)}
+	}
+	return nil
+}
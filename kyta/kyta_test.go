@@ -0,0 +1,166 @@
+package kyta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubGateway answers the token endpoint and records the last payment or
+// payout body. reply overrides the response for non-token paths.
+type stubGateway struct {
+	lastPath string
+	lastAuth string
+	lastBody map[string]interface{}
+	reply    func(w http.ResponseWriter)
+}
+
+func (g *stubGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/access-token" {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "id" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"response_code":"4010000","response_message":"Unauthorized"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"response_code":"2000100","response_data":{"access_token":"tok"}}`))
+		return
+	}
+	g.lastPath = r.URL.Path
+	g.lastAuth = r.Header.Get("Authorization")
+	g.lastBody = nil
+	_ = json.NewDecoder(r.Body).Decode(&g.lastBody)
+	if g.reply != nil {
+		g.reply(w)
+		return
+	}
+	_, _ = w.Write([]byte(`{"response_code":"2001100","response_data":{"id":"pay-1","reference_id":"XIN-1","amount":100000,"payment_data":{"qr_string":"000201"},"expires_at":"2025-01-01T00:15:00Z"}}`))
+}
+
+func newStubClient(t *testing.T, g *stubGateway) *HTTPClient {
+	t.Helper()
+	srv := httptest.NewServer(g)
+	t.Cleanup(srv.Close)
+	return &HTTPClient{BaseURL: srv.URL + "/", ClientID: "id", ClientSecret: "secret", NotifyURL: "https://example.test/notify", PayoutNotifyURL: "https://example.test/payout", HTTP: srv.Client()}
+}
+
+func TestCreateQRISSendsBearerTokenAndPayload(t *testing.T) {
+	g := &stubGateway{}
+	c := newStubClient(t, g)
+
+	resp, err := c.CreateQRIS(context.Background(), PaymentRequest{ReferenceID: "XIN-1", Amount: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.lastPath != "/payments/create/qris" || g.lastAuth != "Bearer tok" {
+		t.Fatalf("unexpected request %s with %q", g.lastPath, g.lastAuth)
+	}
+	if g.lastBody["reference_id"] != "XIN-1" || g.lastBody["amount"] != float64(100000) || g.lastBody["notify_url"] != "https://example.test/notify" {
+		t.Fatalf("unexpected body %v", g.lastBody)
+	}
+	if resp.ResponseData.PaymentData.QRString != "000201" {
+		t.Fatalf("response not decoded: %+v", resp.ResponseData)
+	}
+}
+
+func TestCreateVAAndPayoutPayloads(t *testing.T) {
+	g := &stubGateway{}
+	c := newStubClient(t, g)
+
+	if _, err := c.CreateVA(context.Background(), PaymentRequest{ReferenceID: "XIN-2", Amount: 50000, BankCode: "BCA"}); err != nil {
+		t.Fatal(err)
+	}
+	if g.lastPath != "/payments/create/va" || g.lastBody["bank_code"] != "BCA" {
+		t.Fatalf("unexpected VA request %s %v", g.lastPath, g.lastBody)
+	}
+
+	g.reply = func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{"response_code":"2001000","response_data":{"id":"po-1","reference_id":"XIN-3"}}`))
+	}
+	resp, err := c.CreatePayout(context.Background(), PayoutRequest{ReferenceID: "XIN-3", Amount: 90000, BankCode: "014", AccountNumber: "123", AccountName: "Budi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest, _ := g.lastBody["destination"].(map[string]interface{})
+	if g.lastPath != "/payouts/transfers" || dest["code"] != "014" || dest["account_name"] != "Budi" || g.lastBody["notify_url"] != "https://example.test/payout" {
+		t.Fatalf("unexpected payout request %s %v", g.lastPath, g.lastBody)
+	}
+	if resp.ResponseData.ID != "po-1" {
+		t.Fatalf("response not decoded: %+v", resp.ResponseData)
+	}
+}
+
+func TestCheckPayment(t *testing.T) {
+	g := &stubGateway{reply: func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{"response_code":"2001200","response_data":{"id":"pay-1","reference_id":"XIN-1","amount":100000,"status":"CHARGEBACK"}}`))
+	}}
+	c := newStubClient(t, g)
+
+	resp, err := c.CheckPayment(context.Background(), "XIN-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.lastPath != "/payments/status" || g.lastAuth != "Bearer tok" || g.lastBody["reference_id"] != "XIN-1" {
+		t.Fatalf("unexpected request %s with %q: %v", g.lastPath, g.lastAuth, g.lastBody)
+	}
+	if resp.ResponseData.Status != "CHARGEBACK" || resp.ResponseData.Amount != 100000 {
+		t.Fatalf("response not decoded: %+v", resp.ResponseData)
+	}
+}
+
+func TestGatewayErrorsCarryTheGatewayMessage(t *testing.T) {
+	cases := []struct {
+		name  string
+		reply func(w http.ResponseWriter)
+		want  string
+	}{
+		{"http error with message", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"response_code":"4001100","response_message":"Saldo merchant tidak cukup"}`))
+		}, "Saldo merchant tidak cukup"},
+		{"http error without json", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`upstream down`))
+		}, "upstream down"},
+		{"rejected response code", func(w http.ResponseWriter) {
+			_, _ = w.Write([]byte(`{"response_code":"4041100","response_message":"Bank tidak didukung"}`))
+		}, "Bank tidak didukung"},
+		{"malformed body", func(w http.ResponseWriter) {
+			_, _ = w.Write([]byte(`{`))
+		}, "Gagal parsing response pembayaran"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newStubClient(t, &stubGateway{reply: tc.reply})
+			_, err := c.CreateQRIS(context.Background(), PaymentRequest{ReferenceID: "XIN-1", Amount: 1000})
+			var kerr *Error
+			if !errors.As(err, &kerr) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if kerr.Message != tc.want {
+				t.Fatalf("expected message %q, got %q", tc.want, kerr.Message)
+			}
+		})
+	}
+}
+
+func TestMissingCredentials(t *testing.T) {
+	g := &stubGateway{}
+	c := newStubClient(t, g)
+	c.ClientSecret = ""
+	if _, err := c.CreatePayout(context.Background(), PayoutRequest{ReferenceID: "XIN-1"}); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+	if g.lastPath != "" {
+		t.Fatalf("gateway should not be called, got %s", g.lastPath)
+	}
+
+	c.ClientSecret = "wrong"
+	_, err := c.CreateQRIS(context.Background(), PaymentRequest{ReferenceID: "XIN-1"})
+	var kerr *Error
+	if !errors.As(err, &kerr) || kerr.Message != "Unauthorized" {
+		t.Fatalf("expected token error, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package kyta
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	statusTTL          = time.Minute
+	statusProbeTimeout = 3 * time.Second
+)
+
+// StatusDown is what Status reports while the last probe could not reach the
+// gateway. Purchases through it are refused as degraded until a probe
+// succeeds again.
+const StatusDown = "down"
+
+// probe caches the last reachability check so callers never wait on (or
+// hammer) the gateway; a stale result triggers one refresh in the background.
+var probe struct {
+	mu        sync.Mutex
+	status    string
+	checkedAt time.Time
+	running   bool
+}
+
+// Status returns the cached gateway reachability: "up", StatusDown,
+// "disabled" without KYTAPAY_BASE_URL, or "unknown" until the first probe
+// finishes. A stale result starts a refresh.
+func Status() string {
+	base := os.Getenv("KYTAPAY_BASE_URL")
+	if base == "" {
+		return "disabled"
+	}
+
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+	if time.Since(probe.checkedAt) > statusTTL && !probe.running {
+		probe.running = true
+		go probeGateway(base)
+	}
+	if probe.status == "" {
+		return "unknown"
+	}
+	return probe.status
+}
+
+// probeGateway treats any HTTP response as reachable; only transport errors
+// (DNS, TLS, timeouts) count as down.
+func probeGateway(base string) {
+	status := "up"
+	client := &http.Client{Timeout: statusProbeTimeout}
+	resp, err := client.Head(base)
+	if err != nil {
+		status = StatusDown
+	} else {
+		resp.Body.Close()
+	}
+
+	probe.mu.Lock()
+	probe.status = status
+	probe.checkedAt = time.Now()
+	probe.running = false
+	probe.mu.Unlock()
+}
@@ -3,16 +3,20 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"project/config"
 	"project/database"
+	"project/geoip"
 	"project/middleware"
-	"project/models"
+	"project/notify"
 	"project/routes"
 
 	"github.com/joho/godotenv"
@@ -31,13 +35,12 @@ func main() {
 		}
 	}
 
-	// Validate required environment variables
-	requiredEnvVars := []string{"DB_HOST", "DB_USER", "DB_PASS", "DB_NAME", "JWT_SECRET"}
-	for _, envVar := range requiredEnvVars {
-		if os.Getenv(envVar) == "" {
-			log.Fatalf("Required environment variable %s is not set", envVar)
-		}
+	// Every required integration must be configured, KytaPay's callback URLs
+	// included; the error lists every variable missing
+	if err := config.Validate(); err != nil {
+		log.Fatalf("refusing to start: %v", err)
 	}
+	config.Set(config.FromEnv())
 
 	// Connect to the database
 	db, err := database.Connect()
@@ -45,40 +48,60 @@ func main() {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	// Auto-migrate only in development to avoid accidental production schema changes
-	if strings.ToLower(os.Getenv("ENV")) == "development" {
-		log.Println("Running in development mode - performing auto-migration")
-		if err := db.AutoMigrate(
-			&models.Admin{}, 
-			&models.RefreshToken{}, 
-			&models.User{},
-			&models.Category{},
-			&models.Product{},
-			&models.Investment{},
-			&models.UserSpin{}, 
-			&models.Setting{ClosedRegister: false, Maintenance: false}, 
-			&models.Payment{}, 
-			&models.PaymentSettings{},
-		); err != nil {
-			log.Fatalf("failed to migrate database: %v", err)
+	// Versioned schema migrations (migrations/versions). `go run . migrate ...`
+	// manages them by hand; development and MIGRATE_ON_START=true apply them here.
+	migrator, err := newMigrator(db)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(migrator, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	applyMigrations := strings.ToLower(os.Getenv("ENV")) == "development" || strings.ToLower(os.Getenv("MIGRATE_ON_START")) == "true"
+	if err := startupMigrations(migrator, applyMigrations); err != nil {
+		log.Fatalf("refusing to start: %v", err)
+	}
+
+	// Admin lists and reports read from DB_REPLICA_DSN when it is set and
+	// healthy, and from the primary otherwise
+	reads, err := database.ConnectReplica(db)
+	if err != nil {
+		log.Printf("read replica disabled: %v", err)
+		reads = database.NewReadReplica(db, nil)
+	}
+	database.Reads = reads
+	go reads.Watch(context.Background(), database.ReplicaCheckInterval())
+
+	// Country lookups for geoblocking. A database that is configured but
+	// unreadable stops the start, since the country lists would not apply
+	if path := strings.TrimSpace(os.Getenv("GEOIP_DB_PATH")); path != "" {
+		geo, err := geoip.Load(path)
+		if err != nil {
+			log.Fatalf("refusing to start: geoip database: %v", err)
 		}
-		log.Println("Auto-migration completed successfully")
+		geoip.Set(geo)
+		log.Printf("geoip: %d ranges loaded", geo.Len())
 	} else {
-		log.Println("Running in production mode - skipping auto-migration")
+		log.Printf("geoip: GEOIP_DB_PATH not set, geoblocking is off")
 	}
 
 	// Initialize router
 	router := routes.InitRouter()
 
 	// Wrap router with global middleware in recommended order
-	// Security headers / CORS -> Request ID -> Max Body -> Timeout -> Recovery -> Metrics -> Suspicious Activity
+	// Security headers -> Request ID -> Max Body -> Timeout -> Recovery -> Metrics -> Suspicious Activity -> Cache Bypass
 	handler := middleware.SecurityHeadersMiddleware(
 		middleware.RequestIDMiddleware(
 			middleware.MaxBodyMiddleware(
 				middleware.TimeoutMiddleware(
 					middleware.RecoveryMiddleware(
 						middleware.MetricsMiddleware(
-							middleware.SuspiciousActivityMiddleware(router),
+							middleware.SuspiciousActivityMiddleware(
+								middleware.CacheBypassMiddleware(router),
+							),
 						),
 					),
 				),
@@ -101,26 +124,46 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Start server in a goroutine
-	go func() {
-		log.Printf("Server starting on port %s", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
-		}
-	}()
+	drain := defaultDrainTimeout
+	if v, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT_SEC")); err == nil && v > 0 {
+		drain = time.Duration(v) * time.Second
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("server error: %v", err)
+	}
 
-	// Wait for interrupt signal to gracefully shutdown the server
+	// Stop on interrupt; in-flight requests are drained before the DB pool closes
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+	stop := make(chan struct{})
+	go func() {
+		<-quit
+		log.Printf("Shutting down server, draining requests for up to %s...", drain)
+		close(stop)
+	}()
+
+	log.Printf("Server starting on port %s", port)
+	if err := serve(server, ln, stop, drain); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
 
-	// Give outstanding requests 30 seconds to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Stop the rate limiter janitors now that no request can reach them
+	middleware.StopRateLimiters()
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	// Deliver push notifications queued by the last requests while the DB is still open
+	pushCtx, cancelPush := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := notify.CloseAll(pushCtx); err != nil {
+		log.Printf("push queue not drained: %v", err)
+	}
+	cancelPush()
+
+	// Close the connection pool last, after every handler has returned
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("failed to close database: %v", err)
+		}
 	}
 
 	log.Println("Server exited")
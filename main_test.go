@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/routes"
+	"project/utils"
+)
+
+func TestServeDrainsInFlightRequestOnShutdown(t *testing.T) {
+	router := routes.InitRouter()
+	started := make(chan struct{})
+	sawShutdown := make(chan bool, 1)
+	router.HandleFunc("/test/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		sawShutdown <- utils.ShuttingDown(r)
+		// The request context must still be usable for the final commit
+		if err := r.Context().Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: router}
+	stop := make(chan struct{})
+	served := make(chan error, 1)
+	go func() { served <- serve(srv, ln, stop, 5*time.Second) }()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/test/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		resCh <- result{status: resp.StatusCode, body: string(b)}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow request never reached the handler")
+	}
+	close(stop)
+
+	res := <-resCh
+	if res.err != nil {
+		t.Fatalf("in-flight request failed: %v", res.err)
+	}
+	if res.status != http.StatusOK || res.body != "done" {
+		t.Fatalf("expected 200 done, got %d %q", res.status, res.body)
+	}
+	if !<-sawShutdown {
+		t.Fatal("handler did not observe the shutdown signal")
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("serve returned error: %v", err)
+	}
+
+	// New connections are refused once shutdown completes
+	if _, err := http.Get("http://" + ln.Addr().String() + "/test/slow"); err == nil {
+		t.Fatal("expected request after shutdown to fail")
+	}
+}
+
+func TestRouterPreflightChecksOrigin(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://stoneform.co.id,https://*.stoneform.co.id")
+	router := routes.InitRouter()
+
+	cases := []struct {
+		origin string
+		status int
+	}{
+		{"https://stoneform.co.id", http.StatusNoContent},
+		{"https://staging.stoneform.co.id", http.StatusNoContent},
+		{"https://evil.example.com", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodOptions, "/v3/users/investments", nil)
+		req.Header.Set("Origin", c.origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Errorf("origin %s: expected %d, got %d", c.origin, c.status, rec.Code)
+		}
+		allowOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+		if (c.status == http.StatusNoContent) != (allowOrigin == c.origin) {
+			t.Errorf("origin %s: unexpected Allow-Origin %q", c.origin, allowOrigin)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"project/database"
@@ -12,6 +13,12 @@ import (
 // AdminAuthMiddleware verifies that the request is from an authenticated admin
 func AdminAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Integrations send an API key instead of a token
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			apiKeyAuth(w, r, key, next)
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
@@ -81,7 +88,9 @@ func AdminAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Admin is authenticated, proceed
-		next.ServeHTTP(w, r)
+		// Admin is authenticated, proceed with the admin ID available to handlers
+		utils.SetRequestAdmin(r, admin.ID)
+		ctx := context.WithValue(r.Context(), utils.AdminIDKey, admin.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHeader carries an admin API key in place of the admin's token.
+const APIKeyHeader = "X-API-KEY"
+
+// APIKeyRoutes are the only admin routes an API key may call, keyed by method
+// and the route's path below /admin, with the scope each needs. All are
+// read-only: approvals, settings and anything else that changes state are
+// left out on purpose, so no scope can ever reach them.
+var APIKeyRoutes = map[string]string{
+	"GET /transactions/export":        models.ScopeTransactions,
+	"GET /withdrawals/export":         models.ScopeWithdrawals,
+	"GET /reports/daily":              models.ScopeReports,
+	"GET /reports/daily/export":       models.ScopeReports,
+	"GET /reports/products":           models.ScopeReports,
+	"GET /reports/products/export":    models.ScopeReports,
+	"GET /reports/cohorts":            models.ScopeReports,
+	"GET /reports/settlements":        models.ScopeReports,
+	"GET /reports/settlements/{date}": models.ScopeReports,
+}
+
+// apiKeyTouchInterval bounds how often a key's last use is written.
+const apiKeyTouchInterval = time.Minute
+
+// apiKeyRouteScope returns the scope the matched route needs from an API key,
+// or false when API keys may not call it.
+func apiKeyRouteScope(r *http.Request) (string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+	i := strings.Index(tpl, "/admin/")
+	if i < 0 {
+		return "", false
+	}
+	scope, ok := APIKeyRoutes[r.Method+" "+tpl[i+len("/admin"):]]
+	return scope, ok
+}
+
+// apiKeyAuth authenticates an admin request made with an API key. The request
+// runs as the admin who created the key, within the key's scopes and its own
+// per-minute rate limit.
+func apiKeyAuth(w http.ResponseWriter, r *http.Request, raw string, next http.Handler) {
+	var key models.APIKey
+	if err := database.DB.Where("key_hash = ?", models.HashAPIKey(raw)).First(&key).Error; err != nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized: Invalid API key"})
+		return
+	}
+	now := time.Now()
+	if !key.Usable(now) {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized: API key revoked or expired"})
+		return
+	}
+	scope, ok := apiKeyRouteScope(r)
+	if !ok {
+		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Forbidden: API keys cannot call this route"})
+		return
+	}
+	if !key.HasScope(scope) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Forbidden: API key lacks the " + scope + " scope"})
+		return
+	}
+
+	// The key stops working with its admin
+	var admin models.Admin
+	if err := database.DB.First(&admin, key.AdminID).Error; err != nil || !admin.IsActive {
+		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Forbidden"})
+		return
+	}
+
+	if retry, ok := apiKeys().allow(key.ID, key.RateLimit); !ok {
+		writeRateLimited(w, retry, "Too many requests for this API key. Please try again later.")
+		return
+	}
+
+	ip := ClientIP(r)
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval || key.LastUsedIP != ip {
+		if err := database.DB.Model(&models.APIKey{}).Where("id = ?", key.ID).
+			Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error; err != nil {
+			utils.LogError(r, "APIKeyAuth: last used", err, "api_key_id", key.ID)
+		}
+	}
+
+	utils.SetRequestAdmin(r, admin.ID)
+	ctx := context.WithValue(r.Context(), utils.AdminIDKey, admin.ID)
+	ctx = context.WithValue(ctx, utils.APIKeyIDKey, key.ID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// apiKeyLimiter is a one-minute sliding window per API key, each key with the
+// limit stored on it.
+type apiKeyLimiter struct {
+	mu    sync.Mutex
+	state map[string]timestamps
+	janitor
+}
+
+var (
+	apiKeyLimiterOnce sync.Once
+	apiKeyLimiterInst *apiKeyLimiter
+)
+
+// apiKeys returns the shared API key limiter, starting it on first use.
+func apiKeys() *apiKeyLimiter {
+	apiKeyLimiterOnce.Do(func() {
+		l := &apiKeyLimiter{state: make(map[string]timestamps), janitor: newJanitor()}
+		l.name = "api_keys"
+		registerLimiter(l)
+		go l.run(getEnvDuration("RATE_CLEANUP_SECONDS", 60*time.Second), l.sweep)
+		apiKeyLimiterInst = l
+	})
+	return apiKeyLimiterInst
+}
+
+// allow records a request by key id unless it is over limit, in which case
+// it returns how long until one is allowed.
+func (l *apiKeyLimiter) allow(id uint, limit int) (time.Duration, bool) {
+	if limit < 1 {
+		limit = 1
+	}
+	key := fmt.Sprintf("k:%d", id)
+	now := nowUnix()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	filtered := inWindow(l.state[key], now-int64(time.Minute))
+	if len(filtered) >= limit {
+		l.state[key] = filtered
+		return retryAfter(filtered[len(filtered)-limit], time.Minute, now), false
+	}
+	l.state[key] = append(filtered, now)
+	return 0, true
+}
+
+// Stats reports the number of tracked keys.
+func (l *apiKeyLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimiterStats{Name: l.name, Kind: "api_key", Entries: len(l.state)}
+}
+
+// Stop ends the janitor goroutine and removes the limiter from the metrics.
+func (l *apiKeyLimiter) Stop() {
+	l.stop()
+	unregisterLimiter(l)
+}
+
+// sweep evicts keys with no request inside the window.
+func (l *apiKeyLimiter) sweep(now int64) {
+	l.mu.Lock()
+	pruneState(l.state, now-int64(time.Minute))
+	l.mu.Unlock()
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/models"
+
+	"github.com/gorilla/mux"
+)
+
+// API keys only ever reach read-only routes, whatever their scopes.
+func TestAPIKeyRoutesAreReadOnly(t *testing.T) {
+	valid := map[string]bool{}
+	for _, s := range models.APIKeyScopes {
+		valid[s] = true
+	}
+	for route, scope := range APIKeyRoutes {
+		if !strings.HasPrefix(route, "GET /") {
+			t.Errorf("%s: API keys may only call GET routes", route)
+		}
+		for _, banned := range []string{"approve", "reject", "release", "settings", "api-keys"} {
+			if strings.Contains(route, banned) {
+				t.Errorf("%s: API keys must never reach %s routes", route, banned)
+			}
+		}
+		if !valid[scope] {
+			t.Errorf("%s: unknown scope %q", route, scope)
+		}
+	}
+}
+
+func TestAPIKeyRouteScope(t *testing.T) {
+	r := mux.NewRouter()
+	admin := r.PathPrefix("/v3/admin").Subrouter()
+	var got string
+	var allowed bool
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got, allowed = apiKeyRouteScope(r) })
+	admin.Handle("/transactions/export", h).Methods(http.MethodGet)
+	admin.Handle("/withdrawals/{id:[0-9]+}/approve", h).Methods(http.MethodPut)
+	admin.Handle("/settings", h).Methods(http.MethodGet)
+
+	for _, tc := range []struct {
+		method, path string
+		scope        string
+		ok           bool
+	}{
+		{http.MethodGet, "/v3/admin/transactions/export", models.ScopeTransactions, true},
+		{http.MethodPut, "/v3/admin/withdrawals/7/approve", "", false},
+		{http.MethodGet, "/v3/admin/settings", "", false},
+	} {
+		got, allowed = "", false
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+		if got != tc.scope || allowed != tc.ok {
+			t.Errorf("%s %s: got (%q, %v), want (%q, %v)", tc.method, tc.path, got, allowed, tc.scope, tc.ok)
+		}
+	}
+}
+
+func TestAPIKeyLimiterPerKey(t *testing.T) {
+	l := apiKeys()
+	for i := 0; i < 2; i++ {
+		if _, ok := l.allow(900001, 2); !ok {
+			t.Fatalf("request %d refused under the limit", i+1)
+		}
+	}
+	if retry, ok := l.allow(900001, 2); ok || retry <= 0 {
+		t.Fatalf("third request: expected refusal with a retry, got %v %v", ok, retry)
+	}
+	// Another key has its own window
+	if _, ok := l.allow(900002, 2); !ok {
+		t.Fatal("a different key was limited")
+	}
+}
@@ -76,9 +76,32 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Tokens issued before the last password/phone change or forced
+		// logout carry an older version
+		if current, ok := tokenVersion(userID); ok && claimTokenVersion(claims) < current {
+			utils.WriteError(w, r, http.StatusUnauthorized, utils.CodeSessionRevoked)
+			return
+		}
+
+		utils.SetRequestUser(r, userID)
 		ctx := context.WithValue(r.Context(), utils.UserIDKey, userID)
 		ctx = context.WithValue(ctx, utils.UserRoleKey, role)
+		ctx = withUserLocale(ctx, userID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// OptionalAuthMiddleware authenticates requests that carry an Authorization
+// header exactly like AuthMiddleware and passes anonymous ones through, for
+// public endpoints that personalise their answer for signed-in users.
+func OptionalAuthMiddleware(next http.Handler) http.Handler {
+	authed := AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authed.ServeHTTP(w, r)
+	})
+}
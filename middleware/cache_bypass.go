@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"project/cache"
+)
+
+// CacheBypassHeader makes a request skip the read caches when it carries
+// CACHE_BYPASS_KEY. Support uses it to check whether a staleness complaint is
+// the cache or the data; the fresh read also refreshes the cached copy.
+const CacheBypassHeader = "X-Cache-Bypass"
+
+// CacheBypassMiddleware marks the request context for cache.Bypassed when
+// CacheBypassHeader matches CACHE_BYPASS_KEY. Without the key configured the
+// header is ignored, so clients cannot turn the caches off.
+func CacheBypassMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(CacheBypassHeader); v != "" {
+			key := strings.TrimSpace(os.Getenv("CACHE_BYPASS_KEY"))
+			if key != "" && subtle.ConstantTimeCompare([]byte(v), []byte(key)) == 1 {
+				r = r.WithContext(cache.WithBypass(r.Context()))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
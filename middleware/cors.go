@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Defaults used when the CORS_* variables are unset.
+var (
+	defaultCORSOrigins = []string{"https://ciroos.ca", "https://stoneform.co.id", "https://api.stoneform.co.id", "http://localhost:3000"}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-VLA-KEY", "X-CRON-KEY", "X-Requested-With", "X-Request-ID", "X-Device-Fingerprint"}
+	corsMethods        = "GET, POST, PUT, DELETE, OPTIONS"
+)
+
+// CORSConfig is the allowed-origin policy. Origins are exact
+// "scheme://host[:port]" values or "scheme://*.domain" wildcards, which match
+// any subdomain of domain (but not domain itself).
+type CORSConfig struct {
+	Origins          []string
+	Headers          []string
+	AllowCredentials bool
+}
+
+// LoadCORSConfig reads CORS_ALLOWED_ORIGINS, CORS_ALLOWED_HEADERS (comma
+// separated) and CORS_ALLOW_CREDENTIALS, falling back to the defaults, and
+// rejects malformed origins so a typo fails at startup rather than silently
+// blocking the frontend.
+func LoadCORSConfig() (CORSConfig, error) {
+	cfg := CORSConfig{
+		Origins:          defaultCORSOrigins,
+		Headers:          defaultCORSHeaders,
+		AllowCredentials: true,
+	}
+	if v := os.Getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+		cfg.Origins = splitList(v)
+	}
+	if v := os.Getenv("CORS_ALLOWED_HEADERS"); v != "" {
+		cfg.Headers = splitList(v)
+	}
+	if v := os.Getenv("CORS_ALLOW_CREDENTIALS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("CORS_ALLOW_CREDENTIALS: %w", err)
+		}
+		cfg.AllowCredentials = b
+	}
+
+	if len(cfg.Origins) == 0 {
+		return cfg, fmt.Errorf("CORS_ALLOWED_ORIGINS: no origins")
+	}
+	for _, o := range cfg.Origins {
+		if err := validateOrigin(o); err != nil {
+			return cfg, fmt.Errorf("CORS_ALLOWED_ORIGINS: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func validateOrigin(o string) error {
+	if o == "*" {
+		return fmt.Errorf("bare * is not allowed with credentials; list origins or use scheme://*.domain")
+	}
+	u, err := url.Parse(o)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid origin %q", o)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("origin %q must not have a path, query or credentials", o)
+	}
+	host := u.Hostname()
+	if strings.Contains(host, "*") && (!strings.HasPrefix(host, "*.") || strings.Count(host, "*") > 1 || strings.Count(host, ".") < 2) {
+		return fmt.Errorf("invalid wildcard origin %q, expected scheme://*.example.com", o)
+	}
+	return nil
+}
+
+// OriginAllowed reports whether origin matches one of the configured entries.
+func (c CORSConfig) OriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, o := range c.Origins {
+		if o == origin {
+			return true
+		}
+		// scheme://*.domain[:port] matches scheme://<sub>.domain[:port]
+		if i := strings.Index(o, "://*."); i >= 0 {
+			prefix, suffix := o[:i+3], o[i+4:]
+			if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				sub := strings.TrimSuffix(strings.TrimPrefix(origin, prefix), suffix)
+				if sub != "" && !strings.ContainsAny(sub, "/:@") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// CORSMiddleware applies cfg. Preflight requests are answered here: 204 with
+// the allow headers for permitted origins, 403 otherwise, so they never fall
+// through to the catch-all OPTIONS route.
+func CORSMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	headers := strings.Join(cfg.Headers, ", ")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			allowed := cfg.OriginAllowed(origin)
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+				if cfg.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				if !allowed {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
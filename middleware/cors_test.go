@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func preflight(h http.Handler, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "http://example.local/v3/login", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLoadCORSConfig_Defaults(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("CORS_ALLOWED_HEADERS", "")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "")
+	cfg, err := LoadCORSConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.AllowCredentials || !cfg.OriginAllowed("https://stoneform.co.id") || !cfg.OriginAllowed("http://localhost:3000") {
+		t.Fatalf("defaults not applied: %+v", cfg)
+	}
+}
+
+func TestLoadCORSConfig_FromEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", " https://staging.example.com , https://*.stoneform.co.id")
+	t.Setenv("CORS_ALLOWED_HEADERS", "Content-Type,Authorization")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "false")
+	cfg, err := LoadCORSConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Origins) != 2 || len(cfg.Headers) != 2 || cfg.AllowCredentials {
+		t.Fatalf("env not applied: %+v", cfg)
+	}
+}
+
+func TestLoadCORSConfig_RejectsInvalidOrigins(t *testing.T) {
+	for _, o := range []string{"*", "stoneform.co.id", "ftp://stoneform.co.id", "https://stoneform.co.id/app", "https://*", "https://a.*.co.id", "https://*.com"} {
+		t.Setenv("CORS_ALLOWED_ORIGINS", o)
+		if _, err := LoadCORSConfig(); err == nil {
+			t.Errorf("expected %q to be rejected", o)
+		}
+	}
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://stoneform.co.id")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "maybe")
+	if _, err := LoadCORSConfig(); err == nil {
+		t.Error("expected invalid CORS_ALLOW_CREDENTIALS to be rejected")
+	}
+}
+
+func TestCORSConfig_WildcardSubdomain(t *testing.T) {
+	cfg := CORSConfig{Origins: []string{"https://*.stoneform.co.id"}}
+	cases := map[string]bool{
+		"https://app.stoneform.co.id":         true,
+		"https://a.b.stoneform.co.id":         true,
+		"https://stoneform.co.id":             false,
+		"http://app.stoneform.co.id":          false,
+		"https://evilstoneform.co.id":         false,
+		"https://app.stoneform.co.id.evil.io": false,
+		"https://app.stoneform.co.id:8443":    false,
+		"":                                    false,
+	}
+	for origin, want := range cases {
+		if got := cfg.OriginAllowed(origin); got != want {
+			t.Errorf("OriginAllowed(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestCORSMiddleware_PreflightAllowed(t *testing.T) {
+	cfg := CORSConfig{Origins: []string{"https://stoneform.co.id"}, Headers: []string{"Content-Type", "Authorization"}, AllowCredentials: true}
+	called := false
+	h := CORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+
+	rec := preflight(h, "https://stoneform.co.id")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if called {
+		t.Fatal("preflight should not reach the next handler")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://stoneform.co.id" {
+		t.Fatalf("unexpected Allow-Origin %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatal("expected Allow-Credentials")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, Authorization" {
+		t.Fatalf("unexpected Allow-Headers %q", got)
+	}
+}
+
+func TestCORSMiddleware_PreflightDisallowed(t *testing.T) {
+	cfg := CORSConfig{Origins: []string{"https://stoneform.co.id"}, AllowCredentials: true}
+	h := CORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := preflight(h, "https://evil.example.com")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("disallowed origin must not get Allow-Origin")
+	}
+}
+
+func TestCORSMiddleware_SimpleRequestPassesThrough(t *testing.T) {
+	cfg := CORSConfig{Origins: []string{"https://stoneform.co.id"}}
+	h := CORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.local/v3/products", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected pass-through without CORS headers, got %d %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/geoip"
+	"project/models"
+	"project/testutil"
+	"project/utils"
+)
+
+// Registration, purchases and withdrawals are refused by the caller's
+// country as the settings' lists say, unless the user has an override.
+func TestGeoBlocking(t *testing.T) {
+	tx := testutil.Tx(t)
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() { database.DB = prev })
+	geo, err := geoip.Parse(strings.NewReader("36.64.0.0,36.95.255.255,ID\n103.10.0.0/16,SG\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	geoip.Set(geo)
+	t.Cleanup(func() { geoip.Set(nil) })
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	if err := tx.Where("1 = 1").Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	setting := models.Setting{MinWithdraw: 50000, MaxWithdraw: 1000000, MinDeposit: 10000, MaxDeposit: 10000000, WithdrawStartHour: 9, WithdrawEndHour: 17, GeoDenyCountries: "SG"}
+	if err := tx.Create(&setting).Error; err != nil {
+		t.Fatal(err)
+	}
+	models.InvalidateSettingCache()
+	t.Cleanup(models.InvalidateSettingCache)
+	setLists := func(allow, deny string) {
+		t.Helper()
+		if err := tx.Model(&setting).Select("geo_allow_countries", "geo_deny_countries").Updates(models.Setting{GeoAllowCountries: allow, GeoDenyCountries: deny}).Error; err != nil {
+			t.Fatal(err)
+		}
+		models.InvalidateSettingCache()
+	}
+	traveller := testutil.NewUser(t, tx, models.User{Name: "Pelancong"})
+
+	guarded := GeoBlockMiddleware(GeoFeatureWithdrawal)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func(remote, xff string, uid uint) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v3/users/withdrawal", nil)
+		req.RemoteAddr = remote + ":4321"
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		if uid != 0 {
+			req = testutil.AsUser(req, uid)
+		}
+		rec := httptest.NewRecorder()
+		guarded.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		label       string
+		remote, xff string
+		want        int
+	}{
+		{"denied country", "103.10.1.1", "", http.StatusUnavailableForLegalReasons},
+		{"other country", "36.80.1.1", "", http.StatusOK},
+		{"unknown address", "192.0.2.1", "", http.StatusOK},
+		{"denied country behind a trusted proxy", "10.1.1.1", "103.10.1.1", http.StatusUnavailableForLegalReasons},
+		{"forwarded header from an untrusted peer", "36.80.1.1", "103.10.1.1", http.StatusOK},
+		{"private address forged in front of the proxy's entry", "10.1.1.1", "10.0.0.1, 103.10.1.1", http.StatusUnavailableForLegalReasons},
+		{"allowed address forged in front of the proxy's entry", "10.1.1.1", "36.80.1.1, 103.10.1.1", http.StatusUnavailableForLegalReasons},
+	} {
+		rec := call(tc.remote, tc.xff, 0)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.label, tc.want, rec.Code, rec.Body.String())
+		}
+		if tc.want != http.StatusOK {
+			var resp struct {
+				Code    string            `json:"code"`
+				Details map[string]string `json:"details"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Code != string(utils.CodeRegionBlocked) || resp.Details["country"] != "SG" {
+				t.Errorf("%s: unexpected body %s", tc.label, rec.Body.String())
+			}
+		}
+	}
+
+	// An allow list admits only its countries, so an unknown address cannot
+	// pass it, forged or not
+	setLists("ID", "")
+	if rec := call("36.80.1.1", "", 0); rec.Code != http.StatusOK {
+		t.Errorf("allowed country: expected 200, got %d", rec.Code)
+	}
+	if rec := call("10.1.1.1", "36.80.1.1", 0); rec.Code != http.StatusOK {
+		t.Errorf("allowed country behind a trusted proxy: expected 200, got %d", rec.Code)
+	}
+	for _, tc := range []struct{ label, remote, xff string }{
+		{"unknown address", "192.0.2.1", ""},
+		{"private address forged behind a trusted proxy", "10.1.1.1", "10.0.0.1"},
+	} {
+		if rec := call(tc.remote, tc.xff, 0); rec.Code != http.StatusUnavailableForLegalReasons {
+			t.Errorf("%s: expected 451, got %d", tc.label, rec.Code)
+		}
+	}
+	if rec := call("103.10.1.1", "", traveller.ID); rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("outside the allow list: expected 451, got %d", rec.Code)
+	}
+
+	// An override lets the user through until it expires
+	override := models.GeoOverride{UserID: traveller.ID, Reason: "Dinas ke Singapura", AdminID: 7}
+	if err := tx.Create(&override).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rec := call("103.10.1.1", "", traveller.ID); rec.Code != http.StatusOK {
+		t.Errorf("with override: expected 200, got %d", rec.Code)
+	}
+	if rec := call("103.10.1.1", "", 0); rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("override applied to an anonymous request: got %d", rec.Code)
+	}
+	if err := tx.Model(&override).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rec := call("103.10.1.1", "", traveller.ID); rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("expired override: expected 451, got %d", rec.Code)
+	}
+
+	// Empty lists turn geoblocking off
+	setLists("", "")
+	if rec := call("103.10.1.1", "", 0); rec.Code != http.StatusOK {
+		t.Errorf("no lists: expected 200, got %d", rec.Code)
+	}
+}
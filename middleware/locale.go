@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"project/database"
+	"project/i18n"
+	"project/models"
+)
+
+// userLocaleCacheTTL bounds how long a user's language preference is served
+// from memory; UpdateLocale invalidates the entry immediately on this instance.
+const userLocaleCacheTTL = 5 * time.Minute
+
+type userLocaleEntry struct {
+	locale   string // "" when the user has no preference
+	loadedAt time.Time
+}
+
+var userLocaleCache = struct {
+	mu      sync.RWMutex
+	entries map[uint]userLocaleEntry
+}{entries: map[uint]userLocaleEntry{}}
+
+// LocaleMiddleware picks the response language from Accept-Language, falling
+// back to Indonesian. AuthMiddleware later overrides it with the user's saved
+// preference when one is set.
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loc := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), loc)))
+	})
+}
+
+// withUserLocale applies the user's saved language preference to ctx, if any.
+func withUserLocale(ctx context.Context, userID uint) context.Context {
+	if loc, ok := i18n.Parse(userLocale(userID)); ok {
+		return i18n.WithLocale(ctx, loc)
+	}
+	return ctx
+}
+
+// userLocale returns the users.locale value for userID, cached for
+// userLocaleCacheTTL. Lookup errors are treated as "no preference".
+func userLocale(userID uint) string {
+	if userID == 0 || database.DB == nil {
+		return ""
+	}
+	userLocaleCache.mu.RLock()
+	e, ok := userLocaleCache.entries[userID]
+	userLocaleCache.mu.RUnlock()
+	if ok && time.Since(e.loadedAt) < userLocaleCacheTTL {
+		return e.locale
+	}
+
+	var user models.User
+	if err := database.DB.Select("locale").Where("id = ?", userID).Take(&user).Error; err != nil {
+		return ""
+	}
+	e = userLocaleEntry{loadedAt: time.Now()}
+	if user.Locale != nil {
+		e.locale = *user.Locale
+	}
+	userLocaleCache.mu.Lock()
+	userLocaleCache.entries[userID] = e
+	userLocaleCache.mu.Unlock()
+	return e.locale
+}
+
+// InvalidateUserLocale drops the cached language preference of userID.
+func InvalidateUserLocale(userID uint) {
+	userLocaleCache.mu.Lock()
+	delete(userLocaleCache.entries, userID)
+	userLocaleCache.mu.Unlock()
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+)
+
+// MaintenanceMiddleware rejects write requests with 503 while the global
+// maintenance flag or the given feature flag is on. Reads pass through, and
+// webhook/cron routes are simply not wrapped. An empty feature only checks the
+// global flag.
+func MaintenanceMiddleware(feature string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setting, err := models.GetCachedSetting(database.DB)
+			if err != nil || !setting.InMaintenance(feature) {
+				// fail open: a settings read error must not block the API
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			msg := setting.MaintenanceMessage
+			if msg == "" {
+				switch {
+				case setting.Maintenance:
+					msg = "Aplikasi sedang dalam pemeliharaan. Silakan coba lagi nanti."
+				case feature == models.FeatureInvestment:
+					msg = "Pembelian produk sedang dalam pemeliharaan. Silakan coba lagi nanti."
+				case feature == models.FeatureWithdrawal:
+					msg = "Penarikan sedang dalam pemeliharaan. Silakan coba lagi nanti."
+				}
+			}
+
+			data := map[string]interface{}{"maintenance": true, "feature": feature}
+			if setting.MaintenanceUntil != nil && setting.MaintenanceUntil.After(time.Now()) {
+				data["maintenance_until"] = setting.MaintenanceUntil.Format(time.RFC3339)
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(*setting.MaintenanceUntil).Seconds())+1))
+			}
+			utils.WriteJSON(w, http.StatusServiceUnavailable, utils.APIResponse{Success: false, Message: msg, Code: utils.CodeMaintenance, Data: data})
+		})
+	}
+}
@@ -2,8 +2,8 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
 	"os"
@@ -51,6 +51,7 @@ type IPRateLimiter struct {
 	trustedCIDR []string
 	// optional per-instance max override (used by compatibility wrapper)
 	instanceMax int
+	janitor
 }
 
 // NewIPRateLimiter creates an IPRateLimiter with an instance-level max requests and window.
@@ -61,83 +62,116 @@ func NewIPRateLimiter(maxReq int, window time.Duration) *IPRateLimiter {
 		state:       make(map[string]timestamps),
 		cleanupTick: getEnvDuration("RATE_CLEANUP_SECONDS", 60*time.Second),
 		instanceMax: maxReq,
+		janitor:     newJanitor(),
 	}
 	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
 		l.trustedCIDR = strings.Split(v, ",")
 	}
-	go l.cleanupLoop()
+	registerLimiter(l)
+	go l.run(l.cleanupTick, l.sweep)
 	return l
 }
 
-// clientIP returns the client IP, using X-Forwarded-For only when the remote
-// address is in the configured trusted proxies.
-// (removed wrapper) use clientIPGeneric directly where needed
+// Named sets the name reported by the metrics endpoint.
+func (l *IPRateLimiter) Named(name string) *IPRateLimiter {
+	l.mu.Lock()
+	l.name = name
+	l.mu.Unlock()
+	return l
+}
+
+// Stats reports the number of tracked IPs.
+func (l *IPRateLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimiterStats{Name: l.name, Kind: "ip", Entries: len(l.state)}
+}
+
+// Stop ends the janitor goroutine and removes the limiter from the metrics.
+// The middleware keeps working; its map is just no longer swept.
+func (l *IPRateLimiter) Stop() {
+	l.stop()
+	unregisterLimiter(l)
+}
 
-// clientIPGeneric returns the client IP string. If trustedCIDR is provided,
-// X-Forwarded-For / X-Real-IP headers are honored when remote addr is inside
-// one of the trusted CIDRs or IPs.
+// ClientIP returns the client IP, using X-Forwarded-For only when the remote
+// address is in TRUSTED_PROXIES, and then the rightmost entry that is not.
+func ClientIP(r *http.Request) string {
+	var trusted []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		trusted = strings.Split(v, ",")
+	}
+	return clientIPGeneric(r, trusted)
+}
+
+// clientIPGeneric returns the client IP string. When the remote address is
+// inside one of trustedCIDR (CIDRs or IPs), X-Forwarded-For is walked from
+// the right past the trusted proxies: each proxy appends the address it saw,
+// so only the entries after the first untrusted one are trustworthy and
+// anything to its left may be forged by the client. X-Real-IP is used when
+// there is no X-Forwarded-For.
 func clientIPGeneric(r *http.Request, trustedCIDR []string) string {
-	remoteHost, _, _ := net.SplitHostPort(r.RemoteAddr)
-	remoteIP := net.ParseIP(remoteHost)
-	trusted := false
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !ipTrusted(net.ParseIP(host), trustedCIDR) {
+		return host
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		parts := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(parts) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(parts[i])
+			ip := net.ParseIP(hop)
+			if ip == nil {
+				break
+			}
+			client = hop
+			if !ipTrusted(ip, trustedCIDR) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+	if xr := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xr) != nil {
+		return xr
+	}
+	return host
+}
+
+// ipTrusted reports whether ip is one of trustedCIDR, given as CIDRs or IPs.
+func ipTrusted(ip net.IP, trustedCIDR []string) bool {
+	if ip == nil {
+		return false
+	}
 	for _, cidr := range trustedCIDR {
 		cidr = strings.TrimSpace(cidr)
 		if cidr == "" {
 			continue
 		}
 		if strings.Contains(cidr, "/") {
-			if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
-				if remoteIP != nil && ipnet.Contains(remoteIP) {
-					trusted = true
-					break
-				}
+			if _, ipnet, err := net.ParseCIDR(cidr); err == nil && ipnet.Contains(ip) {
+				return true
 			}
 			continue
 		}
-		if ip := net.ParseIP(cidr); ip != nil && remoteIP != nil && ip.Equal(remoteIP) {
-			trusted = true
-			break
-		}
-	}
-	if trusted {
-		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-			parts := strings.Split(xff, ",")
-			if len(parts) > 0 {
-				return strings.TrimSpace(parts[0])
-			}
-		}
-		if xr := r.Header.Get("X-Real-IP"); xr != "" {
-			return strings.TrimSpace(xr)
+		if t := net.ParseIP(cidr); t != nil && t.Equal(ip) {
+			return true
 		}
 	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return host
+	return false
 }
 
-// Middleware applies per-IP limits and sets rate-limit headers.
+// Middleware applies per-IP limits and sets rate-limit headers. Rejected
+// requests are not recorded, so an IP's entry never holds more than limit
+// timestamps however hard it is flooded.
 func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip := clientIPGeneric(r, l.trustedCIDR)
 		now := nowUnix()
-		windowNs := int64(l.window)
-
-		l.mu.Lock()
-		arr := l.state[ip]
-		// filter within window
-		var filtered timestamps
-		cutoff := now - windowNs
-		for _, ts := range arr {
-			if ts >= cutoff {
-				filtered = append(filtered, ts)
-			}
-		}
-		filtered = append(filtered, now)
-		l.state[ip] = filtered
-		count := len(filtered)
-		l.mu.Unlock()
 
 		// Determine limit based on endpoint category. Prefer constructor-provided instanceMax
 		// and fall back to env var defaults.
@@ -154,6 +188,24 @@ func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
 				limit = getEnvInt("RATE_IP_AUTH", 5)
 			}
 		}
+		if limit < 1 {
+			limit = 1
+		}
+
+		l.mu.Lock()
+		filtered := inWindow(l.state[ip], now-int64(l.window))
+		allowed := len(filtered) < limit
+		if allowed {
+			filtered = append(filtered, now)
+		}
+		l.state[ip] = filtered
+		count := len(filtered)
+		var oldest int64
+		if !allowed {
+			// the request whose expiry frees the next slot
+			oldest = filtered[count-limit]
+		}
+		l.mu.Unlock()
 
 		remaining := limit - count
 		if remaining < 0 {
@@ -162,42 +214,20 @@ func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
 		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
 		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
 
-		if count > limit {
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(l.window.Seconds())))
-			w.WriteHeader(http.StatusTooManyRequests)
-			_ = json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"message": fmt.Sprintf("Terlalu banyak permintaan, Coba lagi dalam %d detik", int(l.window.Seconds())),
-			})
+		if !allowed {
+			retry := retryAfter(oldest, l.window, now)
+			writeRateLimited(w, retry, fmt.Sprintf("Terlalu banyak permintaan, Coba lagi dalam %d detik", int(math.Ceil(retry.Seconds()))))
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (l *IPRateLimiter) cleanupLoop() {
-	tick := time.NewTicker(l.cleanupTick)
-	defer tick.Stop()
-	for range tick.C {
-		l.mu.Lock()
-		now := nowUnix()
-		for k, arr := range l.state {
-			// drop entries that have no timestamps within window
-			cutoff := now - int64(l.window)
-			var filtered timestamps
-			for _, ts := range arr {
-				if ts >= cutoff {
-					filtered = append(filtered, ts)
-				}
-			}
-			if len(filtered) == 0 {
-				delete(l.state, k)
-			} else {
-				l.state[k] = filtered
-			}
-		}
-		l.mu.Unlock()
-	}
+// sweep evicts IPs with no request inside the window.
+func (l *IPRateLimiter) sweep(now int64) {
+	l.mu.Lock()
+	pruneState(l.state, now-int64(l.window))
+	l.mu.Unlock()
 }
 
 // UserRateLimiter implements sliding window per user with per-endpoint rules and penalties
@@ -209,6 +239,7 @@ type UserRateLimiter struct {
 	cleanupTick   time.Duration
 	instanceRead  int
 	instanceWrite int
+	janitor
 }
 
 type penaltyInfo struct {
@@ -228,11 +259,34 @@ func NewUserRateLimiter(maxReqRead, maxReqWrite int, windowSec int) *UserRateLim
 		// set instance overrides
 		instanceRead:  maxReqRead,
 		instanceWrite: maxReqWrite,
+		janitor:       newJanitor(),
 	}
-	go l.cleanupLoop()
+	registerLimiter(l)
+	go l.run(l.cleanupTick, l.sweep)
+	return l
+}
+
+// Named sets the name reported by the metrics endpoint.
+func (l *UserRateLimiter) Named(name string) *UserRateLimiter {
+	l.mu.Lock()
+	l.name = name
+	l.mu.Unlock()
 	return l
 }
 
+// Stats reports the number of tracked user/category keys and active penalties.
+func (l *UserRateLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimiterStats{Name: l.name, Kind: "user", Entries: len(l.state), Penalties: len(l.penalty)}
+}
+
+// Stop ends the janitor goroutine and removes the limiter from the metrics.
+func (l *UserRateLimiter) Stop() {
+	l.stop()
+	unregisterLimiter(l)
+}
+
 func routeCategory(path string) string {
 	if strings.HasPrefix(path, "/auth") {
 		return "auth"
@@ -290,28 +344,22 @@ func (l *UserRateLimiter) Middleware(next http.Handler) http.Handler {
 		cutoff := now - int64(window)
 
 		l.mu.Lock()
-		arr := l.state[key]
-		var filtered timestamps
-		for _, ts := range arr {
-			if ts >= cutoff {
-				filtered = append(filtered, ts)
-			}
-		}
-		filtered = append(filtered, now)
-		l.state[key] = filtered
-		count := len(filtered)
-
 		// check penalties
 		pi := l.penalty[key]
 		if pi.Until > now {
-			retry := time.Duration(pi.Until-now) * time.Nanosecond
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(retry.Seconds())))
-			w.WriteHeader(http.StatusTooManyRequests)
-			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Too many requests (user), temporary penalty in effect."})
 			l.mu.Unlock()
+			writeRateLimited(w, time.Duration(pi.Until-now), "Too many requests (user), temporary penalty in effect.")
 			return
 		}
 
+		filtered := inWindow(l.state[key], cutoff)
+		allowed := len(filtered) < limit
+		if allowed {
+			filtered = append(filtered, now)
+		}
+		l.state[key] = filtered
+		count := len(filtered)
+
 		remaining := limit - count
 		if remaining < 0 {
 			remaining = 0
@@ -319,7 +367,7 @@ func (l *UserRateLimiter) Middleware(next http.Handler) http.Handler {
 		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
 		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
 
-		if count > limit {
+		if !allowed {
 			// apply penalty: exponential backoff based on previous level
 			newLevel := pi.Level + 1
 			// penalty durations in minutes: 1,5,15,30 -> convert to seconds
@@ -335,10 +383,8 @@ func (l *UserRateLimiter) Middleware(next http.Handler) http.Handler {
 				durationSec = 30 * 60
 			}
 			l.penalty[key] = penaltyInfo{Level: newLevel, Until: now + int64(time.Duration(durationSec)*time.Second)}
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", durationSec))
-			w.WriteHeader(http.StatusTooManyRequests)
-			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Too many requests (user). Temporary penalty applied."})
 			l.mu.Unlock()
+			writeRateLimited(w, time.Duration(durationSec)*time.Second, "Too many requests (user). Temporary penalty applied.")
 			return
 		}
 		l.mu.Unlock()
@@ -346,35 +392,21 @@ func (l *UserRateLimiter) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-func (l *UserRateLimiter) cleanupLoop() {
-	tick := time.NewTicker(l.cleanupTick)
-	defer tick.Stop()
-	for range tick.C {
-		l.mu.Lock()
-		now := nowUnix()
-		// cleanup state
-		for k, arr := range l.state {
-			cutoff := now - int64(l.windowDefault)
-			var filtered timestamps
-			for _, ts := range arr {
-				if ts >= cutoff {
-					filtered = append(filtered, ts)
-				}
-			}
-			if len(filtered) == 0 {
-				delete(l.state, k)
-			} else {
-				l.state[k] = filtered
-			}
-		}
-		// cleanup penalties
-		for k, p := range l.penalty {
-			if p.Until < now {
-				delete(l.penalty, k)
-			}
+// sweep evicts keys idle for longer than the window and expired penalties.
+func (l *UserRateLimiter) sweep(now int64) {
+	// category windows are one minute; never sweep inside a live window
+	window := l.windowDefault
+	if window < time.Minute {
+		window = time.Minute
+	}
+	l.mu.Lock()
+	pruneState(l.state, now-int64(window))
+	for k, p := range l.penalty {
+		if p.Until < now {
+			delete(l.penalty, k)
 		}
-		l.mu.Unlock()
 	}
+	l.mu.Unlock()
 }
 
 // Account lockout tracker for failed logins
@@ -488,6 +520,7 @@ type WebhookLimiter struct {
 	whitelist map[string]bool
 	mu        sync.Mutex
 	state     map[string]timestamps // ip -> timestamps
+	janitor
 }
 
 func NewWebhookLimiter(maxReq int, window time.Duration, whitelist []string) *WebhookLimiter {
@@ -495,12 +528,37 @@ func NewWebhookLimiter(maxReq int, window time.Duration, whitelist []string) *We
 	for _, ip := range whitelist {
 		wl[ip] = true
 	}
-	return &WebhookLimiter{
+	l := &WebhookLimiter{
 		maxReq:    maxReq,
 		window:    window,
 		whitelist: wl,
 		state:     make(map[string]timestamps),
+		janitor:   newJanitor(),
 	}
+	registerLimiter(l)
+	go l.run(getEnvDuration("RATE_CLEANUP_SECONDS", 60*time.Second), l.sweep)
+	return l
+}
+
+// Named sets the name reported by the metrics endpoint.
+func (l *WebhookLimiter) Named(name string) *WebhookLimiter {
+	l.mu.Lock()
+	l.name = name
+	l.mu.Unlock()
+	return l
+}
+
+// Stats reports the number of tracked IPs.
+func (l *WebhookLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimiterStats{Name: l.name, Kind: "webhook", Entries: len(l.state)}
+}
+
+// Stop ends the janitor goroutine and removes the limiter from the metrics.
+func (l *WebhookLimiter) Stop() {
+	l.stop()
+	unregisterLimiter(l)
 }
 
 func (l *WebhookLimiter) Middleware(next http.Handler) http.Handler {
@@ -510,26 +568,34 @@ func (l *WebhookLimiter) Middleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		limit := l.maxReq
+		if limit < 1 {
+			limit = 1
+		}
 		now := nowUnix()
 		l.mu.Lock()
-		arr := l.state[ip]
-		cutoff := now - int64(l.window)
-		var filtered timestamps
-		for _, ts := range arr {
-			if ts >= cutoff {
-				filtered = append(filtered, ts)
-			}
+		filtered := inWindow(l.state[ip], now-int64(l.window))
+		allowed := len(filtered) < limit
+		if allowed {
+			filtered = append(filtered, now)
 		}
-		filtered = append(filtered, now)
 		l.state[ip] = filtered
-		count := len(filtered)
+		var oldest int64
+		if !allowed {
+			oldest = filtered[len(filtered)-limit]
+		}
 		l.mu.Unlock()
-		if count > l.maxReq {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Too many webhook requests. Please try again later."})
+		if !allowed {
+			writeRateLimited(w, retryAfter(oldest, l.window, now), "Too many webhook requests. Please try again later.")
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// sweep evicts IPs with no request inside the window.
+func (l *WebhookLimiter) sweep(now int64) {
+	l.mu.Lock()
+	pruneState(l.state, now-int64(l.window))
+	l.mu.Unlock()
+}
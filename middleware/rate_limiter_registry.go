@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"project/utils"
+)
+
+// RateLimiterStats is the current size of one limiter's in-memory state.
+type RateLimiterStats struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Entries   int    `json:"entries"`
+	Penalties int    `json:"penalties,omitempty"`
+}
+
+type registeredLimiter interface {
+	Stats() RateLimiterStats
+	Stop()
+}
+
+// Every limiter registers itself on construction so the metrics endpoint can
+// report its size and shutdown can stop its janitor.
+var limiterRegistry = struct {
+	mu       sync.Mutex
+	limiters map[registeredLimiter]struct{}
+}{limiters: map[registeredLimiter]struct{}{}}
+
+func registerLimiter(l registeredLimiter) {
+	limiterRegistry.mu.Lock()
+	limiterRegistry.limiters[l] = struct{}{}
+	limiterRegistry.mu.Unlock()
+}
+
+func unregisterLimiter(l registeredLimiter) {
+	limiterRegistry.mu.Lock()
+	delete(limiterRegistry.limiters, l)
+	limiterRegistry.mu.Unlock()
+}
+
+// RateLimiterSnapshot returns the stats of every running limiter, sorted by name.
+func RateLimiterSnapshot() []RateLimiterStats {
+	limiterRegistry.mu.Lock()
+	ls := make([]registeredLimiter, 0, len(limiterRegistry.limiters))
+	for l := range limiterRegistry.limiters {
+		ls = append(ls, l)
+	}
+	limiterRegistry.mu.Unlock()
+
+	out := make([]RateLimiterStats, 0, len(ls))
+	for _, l := range ls {
+		out = append(out, l.Stats())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Kind < out[j].Kind
+	})
+	return out
+}
+
+// StopRateLimiters stops the janitor of every running limiter. Called on shutdown.
+func StopRateLimiters() {
+	limiterRegistry.mu.Lock()
+	ls := make([]registeredLimiter, 0, len(limiterRegistry.limiters))
+	for l := range limiterRegistry.limiters {
+		ls = append(ls, l)
+	}
+	limiterRegistry.mu.Unlock()
+	for _, l := range ls {
+		l.Stop()
+	}
+}
+
+// janitor runs a limiter's sweep on a ticker until stopped.
+type janitor struct {
+	name     string
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newJanitor() janitor {
+	return janitor{done: make(chan struct{})}
+}
+
+func (j *janitor) run(tick time.Duration, sweep func(now int64)) {
+	if tick <= 0 {
+		tick = time.Minute
+	}
+	t := time.NewTicker(tick)
+	defer t.Stop()
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-t.C:
+			sweep(nowUnix())
+		}
+	}
+}
+
+func (j *janitor) stop() {
+	j.stopOnce.Do(func() { close(j.done) })
+}
+
+// inWindow returns the timestamps in arr at or after cutoff. It reuses arr's
+// backing array, which is safe because callers replace the map entry.
+func inWindow(arr timestamps, cutoff int64) timestamps {
+	i := 0
+	for i < len(arr) && arr[i] < cutoff {
+		i++
+	}
+	if i == len(arr) {
+		return nil
+	}
+	return arr[i:]
+}
+
+// pruneState drops timestamps older than cutoff and deletes keys left empty.
+func pruneState(state map[string]timestamps, cutoff int64) {
+	for k, arr := range state {
+		if kept := inWindow(arr, cutoff); len(kept) == 0 {
+			delete(state, k)
+		} else if len(kept) != len(arr) {
+			// copy so the evicted prefix can be garbage collected
+			state[k] = append(timestamps(nil), kept...)
+		}
+	}
+}
+
+// retryAfter is how long until the oldest request in a full window expires.
+func retryAfter(oldest int64, window time.Duration, now int64) time.Duration {
+	d := time.Duration(oldest + int64(window) - now)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// writeRateLimited writes a 429 with Retry-After in whole seconds.
+func writeRateLimited(w http.ResponseWriter, retry time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	utils.WriteJSON(w, http.StatusTooManyRequests, utils.APIResponse{Success: false, Message: message, Code: utils.CodeRateLimited})
+}
@@ -1,8 +1,17 @@
 package middleware
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"strconv"
 	"testing"
+	"time"
+
+	"project/utils"
 )
 
 func TestClientIPGeneric_DirectRemote(t *testing.T) {
@@ -25,6 +34,24 @@ func TestClientIPGeneric_TrustedProxyXFF(t *testing.T) {
 	}
 }
 
+func TestClientIPGeneric_ForgedXFFEntriesIgnored(t *testing.T) {
+	// The client sent X-Forwarded-For itself; each proxy appended the
+	// address it saw
+	req := httptest.NewRequest("GET", "http://example.local/", nil)
+	req.RemoteAddr = "10.0.0.2:443"
+	req.Header.Set("X-Forwarded-For", "10.9.9.9, 203.0.113.9, 10.0.0.3")
+	ip := clientIPGeneric(req, []string{"10.0.0.0/24"})
+	if ip != "203.0.113.9" {
+		t.Fatalf("expected the rightmost untrusted entry, got %s", ip)
+	}
+
+	req.Header.Del("X-Forwarded-For")
+	req.Header.Set("X-Real-IP", "203.0.113.10")
+	if ip := clientIPGeneric(req, []string{"10.0.0.0/24"}); ip != "203.0.113.10" {
+		t.Fatalf("expected X-Real-IP without X-Forwarded-For, got %s", ip)
+	}
+}
+
 func TestClientIPGeneric_UntrustedProxyIgnoresXFF(t *testing.T) {
 	req := httptest.NewRequest("GET", "http://example.local/", nil)
 	req.RemoteAddr = "198.51.100.11:443"
@@ -34,3 +61,203 @@ func TestClientIPGeneric_UntrustedProxyIgnoresXFF(t *testing.T) {
 		t.Fatalf("expected remote IP when proxy untrusted, got %s", ip)
 	}
 }
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+func hitFrom(h http.Handler, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v3/login", nil)
+	req.RemoteAddr = ip + ":40000"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func randomIP(rng *rand.Rand) string {
+	return fmt.Sprintf("10.%d.%d.%d", rng.Intn(256), rng.Intn(256), rng.Intn(256))
+}
+
+func TestIPRateLimiter_Returns429WithRetryAfter(t *testing.T) {
+	l := NewIPRateLimiter(2, time.Minute)
+	defer l.Stop()
+	h := l.Middleware(okHandler)
+
+	for i := 0; i < 2; i++ {
+		if rec := hitFrom(h, "203.0.113.1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := hitFrom(h, "203.0.113.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", rec.Code)
+	}
+	retry, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retry < 1 || retry > 60 {
+		t.Fatalf("Retry-After = %q, want 1..60 seconds", rec.Header().Get("Retry-After"))
+	}
+	var resp utils.APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Code != utils.CodeRateLimited {
+		t.Fatalf("body = %s, want code %s", rec.Body.String(), utils.CodeRateLimited)
+	}
+	// other IPs are unaffected
+	if rec := hitFrom(h, "203.0.113.2"); rec.Code != http.StatusOK {
+		t.Fatalf("other IP: status %d, want 200", rec.Code)
+	}
+}
+
+func TestWebhookLimiter_Returns429WithRetryAfter(t *testing.T) {
+	l := NewWebhookLimiter(1, time.Hour, []string{"127.0.0.1"})
+	defer l.Stop()
+	h := l.Middleware(okHandler)
+
+	hitFrom(h, "198.51.100.1")
+	rec := hitFrom(h, "198.51.100.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("status %d Retry-After %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	for i := 0; i < 5; i++ {
+		if rec := hitFrom(h, "127.0.0.1"); rec.Code != http.StatusOK {
+			t.Fatalf("whitelisted IP: status %d, want 200", rec.Code)
+		}
+	}
+}
+
+func TestIPRateLimiter_FloodKeepsEntryBounded(t *testing.T) {
+	l := NewIPRateLimiter(5, time.Minute)
+	defer l.Stop()
+	h := l.Middleware(okHandler)
+
+	for i := 0; i < 10000; i++ {
+		hitFrom(h, "203.0.113.9")
+	}
+	l.mu.Lock()
+	n := len(l.state["203.0.113.9"])
+	l.mu.Unlock()
+	if n != 5 {
+		t.Fatalf("stored %d timestamps for a flooding IP, want 5 (the limit)", n)
+	}
+}
+
+func TestIPRateLimiter_SweepEvictsIdleEntries(t *testing.T) {
+	l := NewIPRateLimiter(100, time.Minute)
+	defer l.Stop()
+	h := l.Middleware(okHandler)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 5000; i++ {
+		hitFrom(h, randomIP(rng))
+	}
+	if n := l.Stats().Entries; n == 0 {
+		t.Fatal("expected tracked entries before sweep")
+	}
+	l.sweep(nowUnix())
+	if n := l.Stats().Entries; n == 0 {
+		t.Fatal("sweep evicted entries still inside the window")
+	}
+	l.sweep(nowUnix() + int64(time.Minute) + 1)
+	if n := l.Stats().Entries; n != 0 {
+		t.Fatalf("%d entries left after the window passed, want 0", n)
+	}
+}
+
+func TestUserRateLimiter_SweepDropsExpiredPenalties(t *testing.T) {
+	l := NewUserRateLimiter(1, 1, 60)
+	defer l.Stop()
+	now := nowUnix()
+	l.mu.Lock()
+	l.state["u:1:api"] = timestamps{now}
+	l.penalty["u:1:api"] = penaltyInfo{Level: 1, Until: now + int64(time.Minute)}
+	l.mu.Unlock()
+
+	l.sweep(now + int64(2*time.Minute))
+	if st := l.Stats(); st.Entries != 0 || st.Penalties != 0 {
+		t.Fatalf("stats after sweep = %+v, want no entries or penalties", st)
+	}
+}
+
+func TestRateLimiter_JanitorRunsAndStops(t *testing.T) {
+	t.Setenv("RATE_CLEANUP_SECONDS", "1")
+	l := NewIPRateLimiter(10, 10*time.Millisecond).Named("janitor-test")
+	h := l.Middleware(okHandler)
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 1000; i++ {
+		hitFrom(h, randomIP(rng))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Stats().Entries != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not evict idle entries: %d left", l.Stats().Entries)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	found := false
+	for _, st := range RateLimiterSnapshot() {
+		found = found || st.Name == "janitor-test"
+	}
+	if !found {
+		t.Fatal("limiter missing from RateLimiterSnapshot")
+	}
+	StopRateLimiters()
+	l.Stop() // idempotent
+	for _, st := range RateLimiterSnapshot() {
+		if st.Name == "janitor-test" {
+			t.Fatal("stopped limiter still reported")
+		}
+	}
+	select {
+	case <-l.done:
+	default:
+		t.Fatal("janitor channel not closed after Stop")
+	}
+}
+
+// TestIPRateLimiter_MemoryStableUnderRandomIPLoad drives waves of traffic from
+// random IPs through the limiter, sweeping between waves the way the janitor
+// does, and checks that neither the entry count nor the heap keeps growing.
+func TestIPRateLimiter_MemoryStableUnderRandomIPLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test")
+	}
+	const (
+		waves       = 30
+		perWave     = 20000
+		window      = 20 * time.Millisecond
+		warmupWaves = 5
+	)
+	l := NewIPRateLimiter(50, window)
+	defer l.Stop()
+	h := l.Middleware(okHandler)
+	rng := rand.New(rand.NewSource(3))
+
+	heapAfter := func() uint64 {
+		runtime.GC()
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		return ms.HeapAlloc
+	}
+
+	var baseline uint64
+	for wave := 1; wave <= waves; wave++ {
+		for i := 0; i < perWave; i++ {
+			hitFrom(h, randomIP(rng))
+		}
+		if n := l.Stats().Entries; n > perWave {
+			t.Fatalf("wave %d: %d entries, more than the %d IPs seen in one window", wave, n, perWave)
+		}
+		time.Sleep(window)
+		l.sweep(nowUnix())
+		if n := l.Stats().Entries; n != 0 {
+			t.Fatalf("wave %d: %d entries survived the sweep", wave, n)
+		}
+		if wave == warmupWaves {
+			baseline = heapAfter()
+		}
+	}
+	final := heapAfter()
+	// 600k distinct IPs went through; without eviction the map alone would
+	// hold tens of MB. Allow slack for runtime noise but not linear growth.
+	if limit := baseline*2 + 4<<20; final > limit {
+		t.Fatalf("heap grew from %d to %d bytes across waves (limit %d)", baseline, final, limit)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"project/utils"
+)
+
+// panicCount is the number of handler panics recovered since start.
+var panicCount atomic.Int64
+
+// PanicCount returns the number of panics RecoveryMiddleware has recovered.
+func PanicCount() int64 {
+	return panicCount.Load()
+}
+
+// RecoveryMiddleware turns a handler panic into a logged 500 with the usual
+// APIResponse body, so clients always get JSON instead of a dropped
+// connection. The stack goes to the log with the request id; nothing about the
+// panic is sent to the client. http.ErrAbortHandler is re-raised so the
+// server still aborts the response as the handler asked.
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &headerTracker{ResponseWriter: w}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+			panicCount.Add(1)
+			utils.LogError(r, "panic recovered", fmt.Errorf("%v", rec), "stack", string(debug.Stack()))
+			if tw.wroteHeader {
+				// Too late for a clean error response; the client sees a truncated body
+				return
+			}
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+				Success: false,
+				Message: utils.T(r, string(utils.CodeInternalError)),
+				Code:    utils.CodeInternalError,
+				Data:    map[string]interface{}{"request_id": utils.GetRequestID(r)},
+			})
+		}()
+		next.ServeHTTP(tw, r)
+	})
+}
+
+// headerTracker records whether the handler already started the response.
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(code int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to extend
+// the write deadline of a long-poll.
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// Flush keeps streaming responses working through the tracker.
+func (t *headerTracker) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+)
+
+// RouteRateLimiter is a sliding window per signed-in user (or admin) for one
+// class of expensive routes, such as purchases or exports. Its limit is read
+// on every request, so it can follow the settings without a redeploy. It must
+// sit inside the auth middleware; unauthenticated requests fall back to the
+// client IP.
+type RouteRateLimiter struct {
+	limit  func() int
+	window time.Duration
+	mu     sync.Mutex
+	state  map[string]timestamps
+	janitor
+}
+
+// NewRouteRateLimiter creates a RouteRateLimiter allowing limit() requests per
+// window. A limit of 0 or less turns the limiter off.
+func NewRouteRateLimiter(limit func() int, window time.Duration) *RouteRateLimiter {
+	l := &RouteRateLimiter{
+		limit:   limit,
+		window:  window,
+		state:   make(map[string]timestamps),
+		janitor: newJanitor(),
+	}
+	registerLimiter(l)
+	go l.run(getEnvDuration("RATE_CLEANUP_SECONDS", 60*time.Second), l.sweep)
+	return l
+}
+
+// SettingLimit reads a route limit from the cached settings, falling back to
+// def when the settings cannot be read.
+func SettingLimit(pick func(models.Setting) int, def int) func() int {
+	return func() int {
+		setting, err := models.GetCachedSetting(database.DB)
+		if err != nil {
+			return def
+		}
+		return pick(setting)
+	}
+}
+
+// Named sets the name reported by the metrics endpoint.
+func (l *RouteRateLimiter) Named(name string) *RouteRateLimiter {
+	l.mu.Lock()
+	l.name = name
+	l.mu.Unlock()
+	return l
+}
+
+// Stats reports the number of tracked users.
+func (l *RouteRateLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimiterStats{Name: l.name, Kind: "route", Entries: len(l.state)}
+}
+
+// Stop ends the janitor goroutine and removes the limiter from the metrics.
+func (l *RouteRateLimiter) Stop() {
+	l.stop()
+	unregisterLimiter(l)
+}
+
+// routeLimitKey identifies the caller: the user, else the API key, else the
+// admin, else the IP.
+func routeLimitKey(r *http.Request) string {
+	if uid, ok := utils.GetUserID(r); ok {
+		return fmt.Sprintf("u:%d", uid)
+	}
+	if kid, ok := utils.GetAPIKeyID(r); ok {
+		return fmt.Sprintf("k:%d", kid)
+	}
+	if aid, ok := utils.GetAdminID(r); ok {
+		return fmt.Sprintf("a:%d", aid)
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// Middleware applies the limit and sets rate-limit headers. Rejected requests
+// are not recorded, so retrying early does not push the window out.
+func (l *RouteRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := l.limit()
+		if limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := routeLimitKey(r)
+		now := nowUnix()
+
+		l.mu.Lock()
+		filtered := inWindow(l.state[key], now-int64(l.window))
+		// a lowered limit may leave more than limit timestamps in the window
+		allowed := len(filtered) < limit
+		if allowed {
+			filtered = append(filtered, now)
+		}
+		l.state[key] = filtered
+		count := len(filtered)
+		var oldest int64
+		if !allowed {
+			oldest = filtered[count-limit]
+		}
+		l.mu.Unlock()
+
+		remaining := limit - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
+
+		if !allowed {
+			retry := retryAfter(oldest, l.window, now)
+			writeRateLimited(w, retry, fmt.Sprintf("Terlalu banyak permintaan, Coba lagi dalam %d detik", int(math.Ceil(retry.Seconds()))))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sweep evicts users with no request inside the window.
+func (l *RouteRateLimiter) sweep(now int64) {
+	l.mu.Lock()
+	pruneState(l.state, now-int64(l.window))
+	l.mu.Unlock()
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/utils"
+)
+
+func hitAsUser(h http.Handler, uid uint) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v3/users/investments", nil)
+	req = req.WithContext(context.WithValue(req.Context(), utils.UserIDKey, uid))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouteRateLimiter_PerUserWithAdjustableLimit(t *testing.T) {
+	var limit atomic.Int64
+	limit.Store(2)
+	l := NewRouteRateLimiter(func() int { return int(limit.Load()) }, time.Minute)
+	defer l.Stop()
+	h := l.Middleware(okHandler)
+
+	for i := 0; i < 2; i++ {
+		if rec := hitAsUser(h, 1); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := hitAsUser(h, 1)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", rec.Code)
+	}
+	retry, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retry < 1 || retry > 60 {
+		t.Fatalf("Retry-After = %q, want 1..60 seconds", rec.Header().Get("Retry-After"))
+	}
+	var resp utils.APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Code != utils.CodeRateLimited {
+		t.Fatalf("body %s, want code %s", rec.Body.String(), utils.CodeRateLimited)
+	}
+
+	// Another user has their own window
+	if rec := hitAsUser(h, 2); rec.Code != http.StatusOK {
+		t.Fatalf("other user: status %d, want 200", rec.Code)
+	}
+
+	// A raised limit applies on the next request; 0 turns the limiter off
+	limit.Store(3)
+	if rec := hitAsUser(h, 1); rec.Code != http.StatusOK {
+		t.Fatalf("raised limit: status %d, want 200", rec.Code)
+	}
+	if rec := hitAsUser(h, 1); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("raised limit used up: status %d, want 429", rec.Code)
+	}
+	limit.Store(0)
+	if rec := hitAsUser(h, 1); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatalf("limit off: status %d, want 200 without rate-limit headers", rec.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"project/database"
+	"project/models"
+)
+
+// tokenVersionCacheTTL bounds how long a user's token version is served from
+// memory, and so how long another instance keeps accepting tokens after a
+// bump. The instance that bumps it invalidates its entry immediately.
+var tokenVersionCacheTTL = func() time.Duration {
+	if n, err := strconv.Atoi(os.Getenv("TOKEN_VERSION_CACHE_SECONDS")); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second
+	}
+	return 5 * time.Second
+}()
+
+type tokenVersionEntry struct {
+	version  int
+	loadedAt time.Time
+}
+
+var tokenVersionCache = struct {
+	mu      sync.RWMutex
+	entries map[uint]tokenVersionEntry
+	janitor sync.Once
+}{entries: map[uint]tokenVersionEntry{}}
+
+// sweepTokenVersions evicts entries older than tokenVersionCacheTTL, which a
+// lookup would reload anyway, so the cache holds only recently seen users.
+func sweepTokenVersions(now int64) {
+	cutoff := time.Unix(0, now).Add(-tokenVersionCacheTTL)
+	tokenVersionCache.mu.Lock()
+	for id, e := range tokenVersionCache.entries {
+		if e.loadedAt.Before(cutoff) {
+			delete(tokenVersionCache.entries, id)
+		}
+	}
+	tokenVersionCache.mu.Unlock()
+}
+
+// tokenVersion returns the users.token_version value for userID, cached for
+// tokenVersionCacheTTL. ok is false when it could not be loaded, in which
+// case the caller lets the token through rather than failing on a DB outage.
+func tokenVersion(userID uint) (version int, ok bool) {
+	if userID == 0 {
+		return 0, false
+	}
+	tokenVersionCache.mu.RLock()
+	e, cached := tokenVersionCache.entries[userID]
+	tokenVersionCache.mu.RUnlock()
+	if cached && time.Since(e.loadedAt) < tokenVersionCacheTTL {
+		return e.version, true
+	}
+	if database.DB == nil {
+		return 0, false
+	}
+
+	v, err := models.TokenVersion(database.DB, userID)
+	if err != nil {
+		return 0, false
+	}
+	tokenVersionCache.janitor.Do(func() {
+		j := newJanitor()
+		go j.run(getEnvDuration("RATE_CLEANUP_SECONDS", 60*time.Second), sweepTokenVersions)
+	})
+	tokenVersionCache.mu.Lock()
+	tokenVersionCache.entries[userID] = tokenVersionEntry{version: v, loadedAt: time.Now()}
+	tokenVersionCache.mu.Unlock()
+	return v, true
+}
+
+// claimTokenVersion reads the "tv" claim; tokens issued before it existed
+// carry version 0.
+func claimTokenVersion(claims map[string]interface{}) int {
+	if v, ok := claims["tv"].(float64); ok {
+		return int(v)
+	}
+	return 0
+}
+
+// InvalidateTokenVersion drops the cached token version of userID, so a
+// bump made by models.RevokeSessions takes effect on this instance at once.
+func InvalidateTokenVersion(userID uint) {
+	tokenVersionCache.mu.Lock()
+	delete(tokenVersionCache.entries, userID)
+	tokenVersionCache.mu.Unlock()
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/utils"
+)
+
+// Tokens older than the cached version get SESSION_REVOKED; dropping or
+// aging out the entry lets the next lookup decide again.
+func TestAuthMiddlewareTokenVersion(t *testing.T) {
+	t.Setenv("JWT_SECRET", "session-test-secret")
+	const uid = 4242
+	token, err := utils.GenerateAccessToken(uid, "user") // no DB: "tv" is 0
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InvalidateTokenVersion(uid) })
+
+	h := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v3/users/info", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	seed := func(version int, loadedAt time.Time) {
+		tokenVersionCache.mu.Lock()
+		tokenVersionCache.entries[uid] = tokenVersionEntry{version: version, loadedAt: loadedAt}
+		tokenVersionCache.mu.Unlock()
+	}
+
+	seed(0, time.Now())
+	if rec := call(); rec.Code != http.StatusNoContent {
+		t.Fatalf("current version: expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	seed(1, time.Now())
+	rec := call()
+	var resp struct {
+		Code string `json:"code"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusUnauthorized || resp.Code != string(utils.CodeSessionRevoked) {
+		t.Fatalf("bumped version: expected 401 SESSION_REVOKED, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// An expired entry is reloaded; without a DB the token is let through
+	seed(1, time.Now().Add(-tokenVersionCacheTTL-time.Second))
+	if rec := call(); rec.Code != http.StatusNoContent {
+		t.Fatalf("stale entry: expected 204, got %d", rec.Code)
+	}
+
+	seed(1, time.Now())
+	InvalidateTokenVersion(uid)
+	if rec := call(); rec.Code != http.StatusNoContent {
+		t.Fatalf("invalidated entry: expected 204, got %d", rec.Code)
+	}
+}
+
+// The sweep drops aged-out entries and keeps fresh ones.
+func TestSweepTokenVersions(t *testing.T) {
+	const stale, fresh = 4243, 4244
+	t.Cleanup(func() {
+		InvalidateTokenVersion(stale)
+		InvalidateTokenVersion(fresh)
+	})
+	tokenVersionCache.mu.Lock()
+	tokenVersionCache.entries[stale] = tokenVersionEntry{loadedAt: time.Now().Add(-tokenVersionCacheTTL - time.Second)}
+	tokenVersionCache.entries[fresh] = tokenVersionEntry{loadedAt: time.Now()}
+	tokenVersionCache.mu.Unlock()
+
+	sweepTokenVersions(nowUnix())
+
+	tokenVersionCache.mu.RLock()
+	_, staleKept := tokenVersionCache.entries[stale]
+	_, freshKept := tokenVersionCache.entries[fresh]
+	tokenVersionCache.mu.RUnlock()
+	if staleKept || !freshKept {
+		t.Fatalf("expected only the fresh entry kept, got stale %v fresh %v", staleKept, freshKept)
+	}
+}
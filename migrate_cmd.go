@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"project/database"
+	"project/migrations"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status | force <version>"
+
+func newMigrator(db *gorm.DB) (*database.Migrator, error) {
+	ms, err := database.LoadMigrations(migrations.FS, migrations.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return database.NewMigrator(db, ms), nil
+}
+
+// runMigrate handles the `migrate` subcommand.
+func runMigrate(m *database.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	switch args[0] {
+	case "up":
+		done, err := m.Up()
+		for _, mig := range done {
+			log.Printf("applied %04d_%s", mig.Version, mig.Name)
+		}
+		if err == nil && len(done) == 0 {
+			log.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return errors.New(migrateUsage)
+			}
+			steps = n
+		}
+		done, err := m.Down(steps)
+		for _, mig := range done {
+			log.Printf("rolled back %04d_%s", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		applied, err := m.Applied()
+		if err != nil {
+			return err
+		}
+		for _, a := range applied {
+			state := "applied"
+			if a.Dirty {
+				state = "DIRTY"
+			}
+			fmt.Printf("%04d_%s\t%s\t%s\n", a.Version, a.Name, state, a.AppliedAt.Format("2006-01-02 15:04:05"))
+		}
+		pending, err := m.Pending()
+		if err != nil {
+			return err
+		}
+		for _, mig := range pending {
+			fmt.Printf("%04d_%s\tpending\n", mig.Version, mig.Name)
+		}
+		return nil
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return errors.New(migrateUsage)
+		}
+		return m.Force(version)
+	}
+	return errors.New(migrateUsage)
+}
+
+// startupMigrations applies pending migrations when apply is set, then
+// checks that none are left. With MIGRATIONS_STRICT=true a pending or dirty
+// migration stops startup; otherwise it is only logged.
+func startupMigrations(m *database.Migrator, apply bool) error {
+	if apply {
+		done, err := m.Up()
+		for _, mig := range done {
+			log.Printf("applied migration %04d_%s", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	pending, err := m.Pending()
+	if err == nil && len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, mig := range pending {
+			names[i] = fmt.Sprintf("%04d_%s", mig.Version, mig.Name)
+		}
+		err = fmt.Errorf("%d pending migration(s): %s; run `migrate up`", len(pending), strings.Join(names, ", "))
+	}
+	if err == nil {
+		return nil
+	}
+	if strings.ToLower(os.Getenv("MIGRATIONS_STRICT")) == "true" {
+		return err
+	}
+	log.Printf("WARNING: %v", err)
+	return nil
+}
@@ -0,0 +1,6 @@
+-- Migration: Indexes for the admin user list filters
+
+CREATE INDEX IF NOT EXISTS `idx_users_level` ON `users` (`level`);
+CREATE INDEX IF NOT EXISTS `idx_users_investment_status` ON `users` (`investment_status`);
+CREATE INDEX IF NOT EXISTS `idx_users_created_at` ON `users` (`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_transactions_user_created` ON `transactions` (`user_id`, `created_at`);
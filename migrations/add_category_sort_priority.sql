@@ -0,0 +1,12 @@
+-- Migration: Explicit category sort priority
+-- Replaces the hardcoded "CASE WHEN id = 1 THEN 0 ELSE id END" ordering.
+-- Lower values are listed first; ties are broken by id.
+
+ALTER TABLE `categories`
+  ADD COLUMN IF NOT EXISTS `sort_priority` int NOT NULL DEFAULT '10'
+  COMMENT 'Display order, lower first';
+
+-- Keep category 1 first as before
+UPDATE `categories` SET `sort_priority` = 0 WHERE `id` = 1;
+
+CREATE INDEX IF NOT EXISTS `idx_categories_sort_priority` ON `categories` (`sort_priority`);
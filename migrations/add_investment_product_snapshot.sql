@@ -0,0 +1,14 @@
+-- Migration: Snapshot product name on investments
+-- Product fields used after purchase (amount, daily_profit, duration and now name)
+-- are copied onto the investment so product edits never affect existing investments.
+
+ALTER TABLE `investments`
+  ADD COLUMN IF NOT EXISTS `product_name` varchar(100) NOT NULL DEFAULT ''
+  COMMENT 'Product name at purchase time'
+  AFTER `category_id`;
+
+-- Backfill existing investments from the current product names
+UPDATE `investments` i
+  JOIN `products` p ON p.id = i.product_id
+  SET i.product_name = p.name
+  WHERE i.product_name = '';
@@ -0,0 +1,17 @@
+-- Migration: Per-feature maintenance flags with optional message and ETA
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `maintenance_investment` tinyint(1) NOT NULL DEFAULT '0'
+  COMMENT 'Blocks new investments while set';
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `maintenance_withdrawal` tinyint(1) NOT NULL DEFAULT '0'
+  COMMENT 'Blocks new withdrawal requests while set';
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `maintenance_message` varchar(255) NOT NULL DEFAULT ''
+  COMMENT 'Optional message shown instead of the default maintenance text';
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `maintenance_until` datetime NULL DEFAULT NULL
+  COMMENT 'Expected end of maintenance, returned to clients as an ETA';
@@ -0,0 +1,16 @@
+-- Migration: Runtime settings managed from the admin panel
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `withdraw_start_hour` int NOT NULL DEFAULT '9'
+  COMMENT 'Withdrawal window start hour (Asia/Jakarta)';
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `withdraw_end_hour` int NOT NULL DEFAULT '17'
+  COMMENT 'Withdrawal window end hour, exclusive (Asia/Jakarta)';
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `referral_bonus_percent` decimal(5,2) NOT NULL DEFAULT '30.00'
+  COMMENT 'Direct referrer bonus as percent of the investment amount';
+
+ALTER TABLE `settings`
+  ADD COLUMN IF NOT EXISTS `auto_withdraw` tinyint(1) NOT NULL DEFAULT '0';
@@ -0,0 +1,17 @@
+-- Migration: Link transactions to investments
+-- Purchase, return and referral-bonus transactions now carry the investment they belong to.
+-- Older rows keep investment_id NULL and are matched through the purchase order_id.
+
+ALTER TABLE `transactions`
+  ADD COLUMN IF NOT EXISTS `investment_id` int unsigned NULL DEFAULT NULL
+  AFTER `user_id`;
+
+CREATE INDEX IF NOT EXISTS `idx_transactions_investment_id` ON `transactions` (`investment_id`);
+
+-- Backfill the purchase transactions, which share the investment order_id
+UPDATE `transactions` t
+  JOIN `investments` i ON i.order_id = t.order_id
+  SET t.investment_id = i.id
+  WHERE t.investment_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS `idx_investments_created_at` ON `investments` (`created_at`);
@@ -0,0 +1,6 @@
+-- Migration: Per-user response language
+-- NULL means "follow the Accept-Language header"; otherwise 'id' or 'en'.
+
+ALTER TABLE `users`
+  ADD COLUMN IF NOT EXISTS `locale` varchar(5) NULL DEFAULT NULL
+  COMMENT 'Response language preference (id, en)';
@@ -0,0 +1,37 @@
+-- Migration: Store money as whole rupiah (bigint) instead of decimal(15,2)
+-- Values are rounded half away from zero, matching money.FromFloat.
+-- Run in a maintenance window: every ALTER rebuilds its table.
+
+ALTER TABLE `users`
+  MODIFY COLUMN `balance` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `total_invest` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `total_invest_vip` bigint NOT NULL DEFAULT '0';
+
+ALTER TABLE `products`
+  MODIFY COLUMN `amount` bigint NOT NULL,
+  MODIFY COLUMN `daily_profit` bigint NOT NULL;
+
+ALTER TABLE `investments`
+  MODIFY COLUMN `amount` bigint NOT NULL,
+  MODIFY COLUMN `daily_profit` bigint NOT NULL,
+  MODIFY COLUMN `total_returned` bigint NOT NULL DEFAULT '0';
+
+ALTER TABLE `transactions`
+  MODIFY COLUMN `amount` bigint NOT NULL,
+  MODIFY COLUMN `charge` bigint NOT NULL DEFAULT '0';
+
+ALTER TABLE `withdrawals`
+  MODIFY COLUMN `amount` bigint NOT NULL,
+  MODIFY COLUMN `charge` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `final_amount` bigint NOT NULL;
+
+ALTER TABLE `daily_reports`
+  MODIFY COLUMN `total_deposits` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `profit_paid` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `capital_returned` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `referral_bonuses` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `other_bonuses` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `withdrawals_settled` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `withdrawal_charges` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `total_user_balance` bigint NOT NULL DEFAULT '0',
+  MODIFY COLUMN `balance_delta` bigint NOT NULL DEFAULT '0';
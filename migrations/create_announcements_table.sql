@@ -0,0 +1,33 @@
+-- Migration: Announcements and per-user notifications
+
+CREATE TABLE IF NOT EXISTS `announcements` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `title` varchar(150) NOT NULL,
+  `body` text NOT NULL,
+  `min_level` int unsigned NULL DEFAULT NULL COMMENT 'Lowest targeted VIP level, NULL = no bound',
+  `max_level` int unsigned NULL DEFAULT NULL COMMENT 'Highest targeted VIP level, NULL = no bound',
+  `publish_at` datetime NOT NULL,
+  `expire_at` datetime NULL DEFAULT NULL,
+  `pinned` tinyint(1) NOT NULL DEFAULT '0',
+  `status` enum('Active','Inactive') DEFAULT 'Active',
+  `delivered_at` datetime NULL DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_announcements_publish_at` (`publish_at`),
+  KEY `idx_announcements_expire_at` (`expire_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `notifications` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int unsigned NOT NULL,
+  `announcement_id` int unsigned NULL DEFAULT NULL,
+  `type` varchar(32) NOT NULL,
+  `title` varchar(150) NOT NULL,
+  `body` text,
+  `read_at` datetime NULL DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_notifications_announcement_user` (`announcement_id`, `user_id`),
+  KEY `idx_notifications_user_read` (`user_id`, `read_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
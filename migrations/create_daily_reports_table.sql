@@ -0,0 +1,23 @@
+-- Migration: Daily finance snapshots written by POST /v3/cron/daily-report
+
+CREATE TABLE IF NOT EXISTS `daily_reports` (
+  `id` int unsigned NOT NULL AUTO_INCREMENT,
+  `report_date` char(10) NOT NULL COMMENT 'Asia/Jakarta day, YYYY-MM-DD',
+  `total_deposits` decimal(15,2) NOT NULL DEFAULT '0.00',
+  `profit_paid` decimal(15,2) NOT NULL DEFAULT '0.00',
+  `capital_returned` decimal(15,2) NOT NULL DEFAULT '0.00',
+  `referral_bonuses` decimal(15,2) NOT NULL DEFAULT '0.00',
+  `other_bonuses` decimal(15,2) NOT NULL DEFAULT '0.00',
+  `withdrawals_settled` decimal(15,2) NOT NULL DEFAULT '0.00',
+  `withdrawal_charges` decimal(15,2) NOT NULL DEFAULT '0.00',
+  `total_user_balance` decimal(18,2) NOT NULL DEFAULT '0.00' COMMENT 'SUM(users.balance) when the row was generated',
+  `balance_delta` decimal(18,2) NOT NULL DEFAULT '0.00' COMMENT 'Change against the previous day snapshot',
+  `generated_at` datetime NOT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_daily_reports_report_date` (`report_date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Report aggregation scans Success transactions by settlement time
+CREATE INDEX IF NOT EXISTS `idx_transactions_status_updated_at` ON `transactions` (`status`, `updated_at`);
@@ -0,0 +1,15 @@
+// Package migrations embeds the versioned schema migrations applied by
+// database.Migrator. Each change is a pair of versions/NNNN_name.up.sql and
+// NNNN_name.down.sql files; never edit a version that has shipped, add a new one.
+//
+// The loose *.sql files in this directory predate versioning and are kept for
+// reference only; their effect is part of the 0001 baseline.
+package migrations
+
+import "embed"
+
+//go:embed versions/*.sql
+var FS embed.FS
+
+// Dir is the directory inside FS holding the migration files.
+const Dir = "versions"
@@ -0,0 +1,24 @@
+-- Migration: Baseline schema (rollback drops every table)
+
+DROP TABLE IF EXISTS `revoked_tokens`;
+DROP TABLE IF EXISTS `daily_reports`;
+DROP TABLE IF EXISTS `notifications`;
+DROP TABLE IF EXISTS `announcements`;
+DROP TABLE IF EXISTS `payment_settings`;
+DROP TABLE IF EXISTS `settings`;
+DROP TABLE IF EXISTS `forums`;
+DROP TABLE IF EXISTS `user_tasks`;
+DROP TABLE IF EXISTS `tasks`;
+DROP TABLE IF EXISTS `user_spins`;
+DROP TABLE IF EXISTS `spin_prizes`;
+DROP TABLE IF EXISTS `withdrawals`;
+DROP TABLE IF EXISTS `bank_accounts`;
+DROP TABLE IF EXISTS `banks`;
+DROP TABLE IF EXISTS `transactions`;
+DROP TABLE IF EXISTS `payments`;
+DROP TABLE IF EXISTS `investments`;
+DROP TABLE IF EXISTS `products`;
+DROP TABLE IF EXISTS `categories`;
+DROP TABLE IF EXISTS `users`;
+DROP TABLE IF EXISTS `refresh_tokens`;
+DROP TABLE IF EXISTS `admins`;
@@ -0,0 +1,334 @@
+-- Migration: Baseline schema
+-- Generated from the GORM models when versioned migrations were introduced.
+-- Databases created earlier from database/db.sql or the loose migrations/*.sql
+-- files already have these tables: run `go run . migrate force 1` on them once
+-- instead of applying this file.
+
+CREATE TABLE `admins` (
+  `id` bigint AUTO_INCREMENT,
+  `username` varchar(191) NOT NULL,
+  `password` longtext NOT NULL,
+  `name` longtext NOT NULL,
+  `email` varchar(191),
+  `role` varchar(191) DEFAULT 'admin',
+  `is_active` boolean DEFAULT true,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT `uni_admins_username` UNIQUE (`username`),
+  CONSTRAINT `uni_admins_email` UNIQUE (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `refresh_tokens` (
+  `id` char(36),
+  `user_id` bigint unsigned,
+  `expires_at` datetime(3) NULL,
+  `revoked` boolean,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_refresh_tokens_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `users` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `number` varchar(20) NOT NULL,
+  `password` varchar(255) NOT NULL,
+  `reff_code` varchar(20) NOT NULL,
+  `reff_by` bigint unsigned,
+  `balance` bigint DEFAULT 0,
+  `level` bigint unsigned DEFAULT 0,
+  `total_invest` bigint DEFAULT 0,
+  `total_invest_vip` bigint DEFAULT 0,
+  `spin_ticket` bigint unsigned DEFAULT 0,
+  `status` enum('Active','Inactive','Suspend') DEFAULT 'Active',
+  `investment_status` enum('Active','Inactive') DEFAULT 'Inactive',
+  `locale` varchar(5),
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_users_reff_code` (`reff_code`),
+  UNIQUE INDEX `idx_users_number` (`number`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `categories` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `description` text,
+  `profit_type` enum('locked','unlocked') DEFAULT 'unlocked',
+  `status` enum('Active','Inactive') DEFAULT 'Active',
+  `sort_priority` bigint NOT NULL DEFAULT 10,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_categories_sort_priority` (`sort_priority`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `products` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `category_id` bigint unsigned NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `amount` bigint NOT NULL,
+  `daily_profit` bigint NOT NULL,
+  `duration` bigint NOT NULL,
+  `required_vip` bigint DEFAULT 0,
+  `purchase_limit` bigint DEFAULT 0,
+  `status` enum('Active','Inactive') DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_products_category_id` (`category_id`),
+  CONSTRAINT `fk_products_category` FOREIGN KEY (`category_id`) REFERENCES `categories`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `investments` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `product_id` bigint unsigned NOT NULL,
+  `category_id` bigint unsigned NOT NULL,
+  `product_name` varchar(100) NOT NULL DEFAULT '',
+  `amount` bigint NOT NULL,
+  `daily_profit` bigint NOT NULL,
+  `duration` bigint NOT NULL,
+  `total_paid` bigint NOT NULL DEFAULT 0,
+  `total_returned` bigint NOT NULL DEFAULT 0,
+  `last_return_at` datetime(3) NULL,
+  `next_return_at` datetime(3) NULL,
+  `order_id` varchar(191) NOT NULL,
+  `status` enum('Pending','Running','Completed','Suspended','Cancelled') DEFAULT 'Pending',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_investments_user_id` (`user_id`),
+  INDEX `idx_investments_product_id` (`product_id`),
+  INDEX `idx_investments_category_id` (`category_id`),
+  UNIQUE INDEX `idx_investments_order_id` (`order_id`),
+  CONSTRAINT `fk_investments_category` FOREIGN KEY (`category_id`) REFERENCES `categories`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `payments` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `investment_id` bigint unsigned NOT NULL,
+  `reference_id` varchar(191),
+  `order_id` varchar(191) NOT NULL,
+  `payment_method` varchar(16),
+  `payment_channel` varchar(16),
+  `payment_code` text,
+  `payment_link` text,
+  `status` varchar(16) DEFAULT 'Pending',
+  `expired_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_payments_investment_id` (`investment_id`),
+  UNIQUE INDEX `idx_payments_order_id` (`order_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `transactions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `investment_id` bigint unsigned,
+  `amount` bigint NOT NULL,
+  `charge` bigint NOT NULL DEFAULT 0,
+  `order_id` varchar(191) NOT NULL,
+  `transaction_flow` enum('debit','credit') NOT NULL,
+  `transaction_type` varchar(50) NOT NULL,
+  `message` text,
+  `status` enum('Success','Pending','Failed') NOT NULL DEFAULT 'Pending',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_transactions_user_id` (`user_id`),
+  INDEX `idx_transactions_investment_id` (`investment_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `banks` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `code` varchar(20) NOT NULL,
+  `status` enum('Active','Inactive') DEFAULT 'Active',
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_banks_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `bank_accounts` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `bank_id` bigint unsigned NOT NULL,
+  `account_name` varchar(100) NOT NULL,
+  `account_number` varchar(50) NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_bank_accounts_user_id` (`user_id`),
+  INDEX `idx_bank_accounts_bank_id` (`bank_id`),
+  CONSTRAINT `fk_bank_accounts_bank` FOREIGN KEY (`bank_id`) REFERENCES `banks`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `withdrawals` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `bank_account_id` bigint unsigned NOT NULL,
+  `amount` bigint NOT NULL,
+  `charge` bigint NOT NULL DEFAULT 0,
+  `final_amount` bigint NOT NULL,
+  `order_id` varchar(191) NOT NULL,
+  `status` enum('Success','Pending','Failed') NOT NULL DEFAULT 'Pending',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_withdrawals_user_id` (`user_id`),
+  INDEX `idx_withdrawals_bank_account_id` (`bank_account_id`),
+  UNIQUE INDEX `idx_withdrawals_order_id` (`order_id`),
+  CONSTRAINT `fk_withdrawals_bank_account` FOREIGN KEY (`bank_account_id`) REFERENCES `bank_accounts`(`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `spin_prizes` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `amount` decimal(15,2) NOT NULL,
+  `code` varchar(20) NOT NULL,
+  `chance` bigint NOT NULL,
+  `chance_weight` bigint NOT NULL,
+  `status` enum('Active','Inactive') NOT NULL DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_spin_prizes_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `user_spins` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `prize_id` bigint unsigned NOT NULL,
+  `amount` decimal(15,2) NOT NULL,
+  `code` varchar(20) NOT NULL,
+  `won_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_spins_user_id` (`user_id`),
+  INDEX `idx_user_spins_prize_id` (`prize_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `tasks` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `reward` decimal(15,2) NOT NULL,
+  `required_level` bigint NOT NULL,
+  `required_active_members` bigint NOT NULL,
+  `status` enum('Active','Inactive') DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `user_tasks` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `task_id` bigint unsigned NOT NULL,
+  `claimed_at` datetime(3) NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `forums` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `reward` decimal(15,2) DEFAULT 0,
+  `description` varchar(60) NOT NULL,
+  `image` varchar(255) NOT NULL,
+  `status` enum('Accepted','Pending','Rejected') DEFAULT 'Pending',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `settings` (
+  `id` bigint AUTO_INCREMENT,
+  `name` longtext,
+  `company` longtext,
+  `logo` longtext,
+  `min_withdraw` double,
+  `max_withdraw` double,
+  `withdraw_charge` double,
+  `withdraw_start_hour` bigint DEFAULT 9,
+  `withdraw_end_hour` bigint DEFAULT 17,
+  `referral_bonus_percent` decimal(5,2) DEFAULT 30,
+  `auto_withdraw` boolean,
+  `maintenance` boolean,
+  `maintenance_investment` boolean DEFAULT false,
+  `maintenance_withdrawal` boolean DEFAULT false,
+  `maintenance_message` varchar(255),
+  `maintenance_until` datetime(3) NULL,
+  `closed_register` boolean,
+  `link_cs` longtext,
+  `link_group` longtext,
+  `link_app` longtext,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `payment_settings` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `pakasir_api_key` varchar(191),
+  `pakasir_project` varchar(191),
+  `deposit_amount` decimal(15,2),
+  `bank_name` varchar(100),
+  `bank_code` varchar(50),
+  `account_number` varchar(100),
+  `account_name` varchar(100),
+  `withdraw_amount` decimal(15,2),
+  `wishlist_id` text,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `announcements` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `title` varchar(150) NOT NULL,
+  `body` text NOT NULL,
+  `min_level` bigint unsigned,
+  `max_level` bigint unsigned,
+  `publish_at` datetime(3) NOT NULL,
+  `expire_at` datetime(3) NULL,
+  `pinned` boolean NOT NULL DEFAULT false,
+  `status` enum('Active','Inactive') DEFAULT 'Active',
+  `delivered_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_announcements_publish_at` (`publish_at`),
+  INDEX `idx_announcements_expire_at` (`expire_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `notifications` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `announcement_id` bigint unsigned,
+  `type` varchar(32) NOT NULL,
+  `title` varchar(150) NOT NULL,
+  `body` text,
+  `read_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_notifications_user_read` (`user_id`,`read_at`),
+  UNIQUE INDEX `idx_notifications_announcement_user` (`announcement_id`,`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `daily_reports` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `report_date` char(10) NOT NULL,
+  `total_deposits` bigint NOT NULL DEFAULT 0,
+  `profit_paid` bigint NOT NULL DEFAULT 0,
+  `capital_returned` bigint NOT NULL DEFAULT 0,
+  `referral_bonuses` bigint NOT NULL DEFAULT 0,
+  `other_bonuses` bigint NOT NULL DEFAULT 0,
+  `withdrawals_settled` bigint NOT NULL DEFAULT 0,
+  `withdrawal_charges` bigint NOT NULL DEFAULT 0,
+  `total_user_balance` bigint NOT NULL DEFAULT 0,
+  `balance_delta` bigint NOT NULL DEFAULT 0,
+  `generated_at` datetime(3) NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_daily_reports_report_date` (`report_date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `revoked_tokens` (
+  `id` varchar(128) NOT NULL,
+  `revoked_at` datetime NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
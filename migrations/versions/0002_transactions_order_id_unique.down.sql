@@ -0,0 +1,4 @@
+-- Migration: Unique order_id on transactions (rollback)
+
+ALTER TABLE `transactions`
+  DROP INDEX `idx_transactions_order_id`;
@@ -0,0 +1,7 @@
+-- Migration: Unique order_id on transactions
+-- Webhooks and admin actions update transactions by order_id, so a duplicate
+-- would settle two rows. Fails if duplicates exist; resolve them first with:
+--   SELECT order_id, COUNT(*) FROM transactions GROUP BY order_id HAVING COUNT(*) > 1;
+
+ALTER TABLE `transactions`
+  ADD UNIQUE INDEX `idx_transactions_order_id` (`order_id`);
@@ -0,0 +1,6 @@
+-- Migration: Record which admin approved or rejected a withdrawal (rollback)
+
+ALTER TABLE `withdrawals`
+  DROP INDEX `idx_withdrawals_processed_by`,
+  DROP COLUMN `processed_at`,
+  DROP COLUMN `processed_by`;
@@ -0,0 +1,7 @@
+-- Migration: Record which admin approved or rejected a withdrawal
+-- NULL for withdrawals still pending and for rows processed before this column existed.
+
+ALTER TABLE `withdrawals`
+  ADD COLUMN `processed_by` bigint NULL DEFAULT NULL COMMENT 'admins.id of the approving/rejecting admin',
+  ADD COLUMN `processed_at` datetime(3) NULL DEFAULT NULL,
+  ADD INDEX `idx_withdrawals_processed_by` (`processed_by`);
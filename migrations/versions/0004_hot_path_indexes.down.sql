@@ -0,0 +1,8 @@
+-- Migration: Composite indexes for the daily-returns cron and the list endpoints (rollback)
+
+ALTER TABLE `withdrawals`
+  DROP INDEX `idx_withdrawals_status_created`;
+
+ALTER TABLE `investments`
+  DROP INDEX `idx_investments_user_status`,
+  DROP INDEX `idx_investments_status_next_return`;
@@ -0,0 +1,9 @@
+-- Migration: Composite indexes for the daily-returns cron and the list endpoints
+-- payments.order_id and transactions.order_id are already unique (0001, 0002).
+
+ALTER TABLE `investments`
+  ADD INDEX `idx_investments_status_next_return` (`status`, `next_return_at`),
+  ADD INDEX `idx_investments_user_status` (`user_id`, `status`);
+
+ALTER TABLE `withdrawals`
+  ADD INDEX `idx_withdrawals_status_created` (`status`, `created_at`);
@@ -0,0 +1,7 @@
+-- Migration: Wallet top-ups (deposits) and their limits (rollback)
+
+ALTER TABLE `settings`
+  DROP COLUMN `max_deposit`,
+  DROP COLUMN `min_deposit`;
+
+DROP TABLE IF EXISTS `deposits`;
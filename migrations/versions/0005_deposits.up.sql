@@ -0,0 +1,25 @@
+-- Migration: Wallet top-ups (deposits) and their limits
+-- Deposit order ids start with DEP- so the gateway webhook can route them.
+
+CREATE TABLE `deposits` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `amount` bigint NOT NULL,
+  `order_id` varchar(191) NOT NULL,
+  `reference_id` varchar(191),
+  `payment_method` varchar(16) NOT NULL,
+  `payment_channel` varchar(16),
+  `payment_code` text,
+  `payment_link` text,
+  `status` enum('Success','Pending','Failed') NOT NULL DEFAULT 'Pending',
+  `expired_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_deposits_order_id` (`order_id`),
+  INDEX `idx_deposits_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `settings`
+  ADD COLUMN `min_deposit` double DEFAULT 10000,
+  ADD COLUMN `max_deposit` double DEFAULT 10000000;
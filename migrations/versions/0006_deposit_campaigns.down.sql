@@ -0,0 +1,3 @@
+-- Migration: Bonus campaigns for deposits and investment payments (rollback)
+
+DROP TABLE IF EXISTS `deposit_campaigns`;
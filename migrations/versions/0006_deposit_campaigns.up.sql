@@ -0,0 +1,18 @@
+-- Migration: Bonus campaigns for deposits and investment payments
+
+CREATE TABLE `deposit_campaigns` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `min_amount` bigint NOT NULL DEFAULT 0,
+  `bonus_percent` decimal(5,2) NOT NULL DEFAULT 0,
+  `bonus_flat` bigint NOT NULL DEFAULT 0,
+  `first_deposit_only` boolean NOT NULL DEFAULT false,
+  `starts_at` datetime(3) NOT NULL,
+  `ends_at` datetime(3) NOT NULL,
+  `budget` bigint NOT NULL DEFAULT 0 COMMENT '0 = uncapped',
+  `budget_used` bigint NOT NULL DEFAULT 0,
+  `status` enum('Active','Inactive') NOT NULL DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
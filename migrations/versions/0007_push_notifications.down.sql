@@ -0,0 +1,6 @@
+-- Migration: Push notification devices, preferences and expiry reminders (rollback)
+
+ALTER TABLE `deposits` DROP COLUMN `expiry_notified_at`;
+ALTER TABLE `payments` DROP COLUMN `expiry_notified_at`;
+DROP TABLE IF EXISTS `notification_preferences`;
+DROP TABLE IF EXISTS `user_devices`;
@@ -0,0 +1,26 @@
+-- Migration: Push notification devices, preferences and expiry reminders
+
+CREATE TABLE `user_devices` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `token` varchar(255) NOT NULL,
+  `platform` enum('android','ios','web') NOT NULL,
+  `last_seen_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_user_devices_token` (`token`),
+  INDEX `idx_user_devices_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `notification_preferences` (
+  `user_id` bigint unsigned NOT NULL,
+  `payment` boolean NOT NULL DEFAULT true,
+  `profit` boolean NOT NULL DEFAULT true,
+  `withdrawal` boolean NOT NULL DEFAULT true,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `payments` ADD COLUMN `expiry_notified_at` datetime(3) NULL;
+ALTER TABLE `deposits` ADD COLUMN `expiry_notified_at` datetime(3) NULL;
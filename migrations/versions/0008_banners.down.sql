@@ -0,0 +1,3 @@
+-- Migration: Home screen banners (rollback)
+
+DROP TABLE IF EXISTS `banners`;
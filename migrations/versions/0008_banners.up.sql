@@ -0,0 +1,18 @@
+-- Migration: Home screen banners
+
+CREATE TABLE `banners` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `title` varchar(150) NOT NULL,
+  `image` varchar(255) NULL,
+  `link` varchar(500) NULL,
+  `sort_order` bigint NOT NULL DEFAULT 0,
+  `starts_at` datetime(3) NOT NULL,
+  `ends_at` datetime(3) NULL,
+  `min_level` bigint unsigned NULL,
+  `max_level` bigint unsigned NULL,
+  `status` enum('Active','Inactive') DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_banners_status_starts` (`status`, `starts_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
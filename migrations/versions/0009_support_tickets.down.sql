@@ -0,0 +1,5 @@
+-- Migration: In-app support tickets and canned responses (rollback)
+
+DROP TABLE IF EXISTS `canned_responses`;
+DROP TABLE IF EXISTS `ticket_messages`;
+DROP TABLE IF EXISTS `support_tickets`;
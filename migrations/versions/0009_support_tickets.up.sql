@@ -0,0 +1,39 @@
+-- Migration: In-app support tickets and canned responses
+
+CREATE TABLE `support_tickets` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `category` enum('account','deposit','investment','withdrawal','other') NOT NULL,
+  `subject` varchar(150) NOT NULL,
+  `status` enum('Open','Answered','Closed') NOT NULL DEFAULT 'Open',
+  `assigned_to` bigint NULL,
+  `last_message_at` datetime(3) NOT NULL,
+  `closed_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_support_tickets_user_updated` (`user_id`, `updated_at`),
+  INDEX `idx_support_tickets_status_last` (`status`, `last_message_at`),
+  INDEX `idx_support_tickets_assigned_to` (`assigned_to`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `ticket_messages` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `ticket_id` bigint unsigned NOT NULL,
+  `sender_type` enum('user','admin') NOT NULL,
+  `sender_id` bigint NOT NULL,
+  `body` text NOT NULL,
+  `image` varchar(255) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_ticket_messages_ticket_id` (`ticket_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `canned_responses` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `title` varchar(100) NOT NULL,
+  `body` text NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
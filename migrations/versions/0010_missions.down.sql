@@ -0,0 +1,4 @@
+-- Migration: Missions rewarding platform actions (rollback)
+
+DROP TABLE IF EXISTS `user_missions`;
+DROP TABLE IF EXISTS `missions`;
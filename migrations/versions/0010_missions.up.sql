@@ -0,0 +1,31 @@
+-- Migration: Missions rewarding platform actions
+
+CREATE TABLE `missions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `description` text,
+  `type` enum('investment','invite_investor','kyc') NOT NULL,
+  `target_count` bigint NOT NULL DEFAULT 1,
+  `reward_type` enum('balance','spin_ticket') NOT NULL,
+  `reward_amount` bigint NOT NULL,
+  `starts_at` datetime(3) NOT NULL,
+  `ends_at` datetime(3) NULL,
+  `status` enum('Active','Inactive') NOT NULL DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_missions_type_status` (`type`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `user_missions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `mission_id` bigint unsigned NOT NULL,
+  `progress` bigint NOT NULL DEFAULT 0,
+  `completed_at` datetime(3) NULL,
+  `claimed_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_user_missions_user_mission` (`user_id`, `mission_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
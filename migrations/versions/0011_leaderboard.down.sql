@@ -0,0 +1,4 @@
+-- Migration: Monthly team leaderboard (rollback)
+
+DROP TABLE IF EXISTS `leaderboard_snapshots`;
+DROP TABLE IF EXISTS `leaderboard_periods`;
@@ -0,0 +1,28 @@
+-- Migration: Monthly team leaderboard
+
+CREATE TABLE `leaderboard_periods` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `period` char(7) NOT NULL,
+  `scope` enum('level1','tree') NOT NULL,
+  `status` enum('Open','Closed') NOT NULL DEFAULT 'Open',
+  `snapshot_at` datetime(3) NOT NULL,
+  `closed_at` datetime(3) NULL,
+  `closed_by` bigint NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_leaderboard_periods_period` (`period`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `leaderboard_snapshots` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `period` char(7) NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `team_volume` bigint NOT NULL,
+  `rank` bigint NOT NULL,
+  `prize` bigint NOT NULL DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_leaderboard_snapshots_period_user` (`period`, `user_id`),
+  INDEX `idx_leaderboard_snapshots_period_rank` (`period`, `rank`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
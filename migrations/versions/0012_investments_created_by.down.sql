@@ -0,0 +1,5 @@
+-- Migration: Record which admin registered a manual investment (rollback)
+
+ALTER TABLE `investments`
+  DROP INDEX `idx_investments_created_by`,
+  DROP COLUMN `created_by`;
@@ -0,0 +1,6 @@
+-- Migration: Record which admin registered a manual investment
+-- NULL for investments bought through the payment gateway.
+
+ALTER TABLE `investments`
+  ADD COLUMN `created_by` bigint NULL DEFAULT NULL COMMENT 'admins.id that registered the investment manually',
+  ADD INDEX `idx_investments_created_by` (`created_by`);
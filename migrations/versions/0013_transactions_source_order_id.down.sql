@@ -0,0 +1,5 @@
+-- Migration: Link referral bonuses to the order that funded them (rollback)
+
+ALTER TABLE `transactions`
+  DROP INDEX `idx_transactions_source_order_id`,
+  DROP COLUMN `source_order_id`;
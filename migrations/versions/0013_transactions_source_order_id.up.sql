@@ -0,0 +1,18 @@
+-- Migration: Link referral bonuses to the order that funded them
+-- Existing bonuses are backfilled from their investment so chargebacks can
+-- still reverse them.
+
+ALTER TABLE `transactions`
+  ADD COLUMN `source_order_id` varchar(191) NULL DEFAULT NULL COMMENT 'order whose payment funded this transaction',
+  ADD INDEX `idx_transactions_source_order_id` (`source_order_id`);
+
+UPDATE `transactions` t
+  JOIN `investments` i ON i.`id` = t.`investment_id`
+  SET t.`source_order_id` = i.`order_id`
+  WHERE t.`transaction_type` = 'team';
+
+-- Earlier clawbacks point at the bonus they reversed
+UPDATE `transactions` c
+  JOIN `transactions` b ON b.`investment_id` = c.`investment_id` AND b.`user_id` = c.`user_id` AND b.`transaction_type` = 'team'
+  SET c.`source_order_id` = b.`order_id`
+  WHERE c.`transaction_type` = 'referral_clawback' AND c.`source_order_id` IS NULL;
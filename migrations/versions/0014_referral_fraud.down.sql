@@ -0,0 +1,9 @@
+-- Migration: Referral fraud signals and held referral bonuses (rollback)
+-- Held bonuses are failed first; they were never credited.
+
+UPDATE `transactions` SET `status` = 'Failed' WHERE `status` = 'Held';
+
+ALTER TABLE `transactions`
+  MODIFY COLUMN `status` enum('Success','Pending','Failed') NOT NULL DEFAULT 'Pending';
+
+DROP TABLE IF EXISTS `user_signals`;
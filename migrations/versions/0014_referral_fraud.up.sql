@@ -0,0 +1,18 @@
+-- Migration: Referral fraud signals and held referral bonuses
+
+CREATE TABLE `user_signals` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `event` enum('register','login') NOT NULL,
+  `ip` varchar(45) NOT NULL,
+  `device_fingerprint` varchar(128) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_signals_user_id` (`user_id`),
+  INDEX `idx_user_signals_ip_created` (`ip`, `created_at`),
+  INDEX `idx_user_signals_device_fingerprint` (`device_fingerprint`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Held: a referral bonus waiting for admin review instead of the balance
+ALTER TABLE `transactions`
+  MODIFY COLUMN `status` enum('Success','Pending','Failed','Held') NOT NULL DEFAULT 'Pending';
@@ -0,0 +1,9 @@
+-- Migration: Soft delete for archived investments and their payments (rollback)
+
+ALTER TABLE `payments`
+  DROP INDEX `idx_payments_deleted_at`,
+  DROP COLUMN `deleted_at`;
+
+ALTER TABLE `investments`
+  DROP INDEX `idx_investments_deleted_at`,
+  DROP COLUMN `deleted_at`;
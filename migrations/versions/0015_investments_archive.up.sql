@@ -0,0 +1,9 @@
+-- Migration: Soft delete for archived investments and their payments
+
+ALTER TABLE `investments`
+  ADD COLUMN `deleted_at` datetime(3) NULL,
+  ADD INDEX `idx_investments_deleted_at` (`deleted_at`);
+
+ALTER TABLE `payments`
+  ADD COLUMN `deleted_at` datetime(3) NULL,
+  ADD INDEX `idx_payments_deleted_at` (`deleted_at`);
@@ -0,0 +1,5 @@
+-- Migration: Separate referral bonus rates for first and repeat purchases (rollback)
+
+ALTER TABLE `settings`
+  DROP COLUMN `referral_bonus_cap`,
+  DROP COLUMN `referral_repeat_percent`;
@@ -0,0 +1,9 @@
+-- Migration: Separate referral bonus rates for first and repeat purchases
+-- The repeat rate starts equal to the current rate, so bonuses stay the same
+-- until finance lowers it.
+
+ALTER TABLE `settings`
+  ADD COLUMN `referral_repeat_percent` decimal(5,2) NOT NULL DEFAULT 30,
+  ADD COLUMN `referral_bonus_cap` bigint NOT NULL DEFAULT 0 COMMENT 'rupiah one downline may earn its referrer, 0 = no cap';
+
+UPDATE `settings` SET `referral_repeat_percent` = `referral_bonus_percent` WHERE `referral_bonus_percent` IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Migration: Configurable spin ticket grants, recorded once per source (rollback)
+
+DROP TABLE IF EXISTS `ticket_grants`;
+
+ALTER TABLE `settings`
+  DROP COLUMN `spin_ticket_daily_cap`,
+  DROP COLUMN `spin_tickets_per_purchase`,
+  DROP COLUMN `spin_ticket_min_amount`;
@@ -0,0 +1,18 @@
+-- Migration: Configurable spin ticket grants, recorded once per source
+
+ALTER TABLE `settings`
+  ADD COLUMN `spin_ticket_min_amount` bigint NOT NULL DEFAULT 100000,
+  ADD COLUMN `spin_tickets_per_purchase` int unsigned NOT NULL DEFAULT 1,
+  ADD COLUMN `spin_ticket_daily_cap` int unsigned NOT NULL DEFAULT 0 COMMENT 'referral tickets per referrer per day, 0 = no cap';
+
+CREATE TABLE `ticket_grants` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `source` enum('referral','mission') NOT NULL,
+  `source_ref` varchar(191) NOT NULL COMMENT 'purchase order id or user_missions id',
+  `tickets` int unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_ticket_grants_source_ref` (`source`, `source_ref`),
+  INDEX `idx_ticket_grants_user_created` (`user_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
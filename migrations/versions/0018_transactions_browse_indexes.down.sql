@@ -0,0 +1,5 @@
+-- Migration: Indexes for the admin transaction browser and export (rollback)
+
+ALTER TABLE `transactions`
+  DROP INDEX `idx_transactions_user_created`,
+  DROP INDEX `idx_transactions_type_created`;
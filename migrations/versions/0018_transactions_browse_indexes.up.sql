@@ -0,0 +1,6 @@
+-- Migration: Indexes for the admin transaction browser and export
+-- Month-wide queries by type or by user use a range on created_at.
+
+ALTER TABLE `transactions`
+  ADD INDEX `idx_transactions_type_created` (`transaction_type`, `created_at`),
+  ADD INDEX `idx_transactions_user_created` (`user_id`, `created_at`);
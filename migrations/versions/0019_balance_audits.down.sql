@@ -0,0 +1,3 @@
+-- Migration: Balances that disagree with the transaction ledger (rollback)
+
+DROP TABLE IF EXISTS `balance_audits`;
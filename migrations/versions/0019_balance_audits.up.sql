@@ -0,0 +1,16 @@
+-- Migration: Balances that disagree with the transaction ledger
+
+CREATE TABLE `balance_audits` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `run_id` varchar(32) NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `balance` bigint NOT NULL,
+  `ledger_balance` bigint NOT NULL,
+  `drift` bigint NOT NULL COMMENT 'balance - ledger_balance',
+  `repaired_at` datetime(3) NULL,
+  `repaired_by` bigint NULL COMMENT 'admin id',
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_balance_audits_run_id` (`run_id`),
+  INDEX `idx_balance_audits_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,7 @@
+-- Migration: Per-channel payment fees, optionally passed through to the buyer (rollback)
+
+ALTER TABLE `payments`
+  DROP COLUMN `fee`,
+  DROP COLUMN `amount`;
+
+DROP TABLE IF EXISTS `payment_channels`;
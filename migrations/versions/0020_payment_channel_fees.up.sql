@@ -0,0 +1,28 @@
+-- Migration: Per-channel payment fees, optionally passed through to the buyer
+
+CREATE TABLE `payment_channels` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `method` enum('QRIS','BANK') NOT NULL,
+  `code` varchar(16) NOT NULL COMMENT 'bank code, or QRIS',
+  `fee_flat` bigint NOT NULL DEFAULT 0,
+  `fee_percent` decimal(5,2) NOT NULL DEFAULT 0,
+  `pass_fee` tinyint(1) NOT NULL DEFAULT 0 COMMENT '1 = buyer pays the fee on top of the price',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_payment_channels_method_code` (`method`, `code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO `payment_channels` (`method`, `code`, `created_at`, `updated_at`) VALUES
+  ('QRIS', 'QRIS', NOW(3), NOW(3)),
+  ('BANK', 'BCA', NOW(3), NOW(3)),
+  ('BANK', 'BRI', NOW(3), NOW(3)),
+  ('BANK', 'BNI', NOW(3), NOW(3)),
+  ('BANK', 'MANDIRI', NOW(3), NOW(3)),
+  ('BANK', 'PERMATA', NOW(3), NOW(3)),
+  ('BANK', 'BNC', NOW(3), NOW(3));
+
+-- Existing payments keep 0/0 and fall back to the investment amount
+ALTER TABLE `payments`
+  ADD COLUMN `amount` bigint NOT NULL DEFAULT 0 COMMENT 'product price, excluding fee',
+  ADD COLUMN `fee` bigint NOT NULL DEFAULT 0 COMMENT 'channel fee paid by the buyer';
@@ -0,0 +1,4 @@
+-- Migration: Index the payment-expiry reminder selection (rollback)
+
+DROP INDEX `idx_deposits_expiry_reminder` ON `deposits`;
+DROP INDEX `idx_payments_expiry_reminder` ON `payments`;
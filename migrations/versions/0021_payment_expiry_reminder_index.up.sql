@@ -0,0 +1,4 @@
+-- Migration: Index the payment-expiry reminder selection
+
+CREATE INDEX `idx_payments_expiry_reminder` ON `payments` (`status`, `expired_at`, `expiry_notified_at`);
+CREATE INDEX `idx_deposits_expiry_reminder` ON `deposits` (`status`, `expired_at`, `expiry_notified_at`);
@@ -0,0 +1,8 @@
+-- Migration: Investment certificate numbers (rollback)
+
+DROP TABLE IF EXISTS `certificate_sequences`;
+
+ALTER TABLE `investments`
+  DROP INDEX `idx_investments_certificate_no`,
+  DROP COLUMN `certified_at`,
+  DROP COLUMN `certificate_no`;
@@ -0,0 +1,30 @@
+-- Migration: Investment certificate numbers
+
+ALTER TABLE `investments`
+  ADD COLUMN `certificate_no` varchar(32) NULL AFTER `created_by`,
+  ADD COLUMN `certified_at` datetime(3) NULL AFTER `certificate_no`,
+  ADD UNIQUE INDEX `idx_investments_certificate_no` (`certificate_no`);
+
+CREATE TABLE `certificate_sequences` (
+  `year` bigint NOT NULL,
+  `last_no` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`year`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Investments confirmed before this migration are numbered by creation year,
+-- oldest first, and certified as of their creation
+UPDATE `investments` i
+JOIN (
+  SELECT `id`, YEAR(`created_at`) AS `yr`,
+         ROW_NUMBER() OVER (PARTITION BY YEAR(`created_at`) ORDER BY `id`) AS `seq`
+  FROM `investments`
+  WHERE `status` IN ('Running', 'Completed', 'Suspended')
+) n ON n.`id` = i.`id`
+SET i.`certificate_no` = CONCAT('XINC-', n.`yr`, '-', LPAD(n.`seq`, 6, '0')),
+    i.`certified_at` = i.`created_at`;
+
+INSERT INTO `certificate_sequences` (`year`, `last_no`)
+SELECT YEAR(`created_at`), COUNT(*)
+FROM `investments`
+WHERE `certificate_no` IS NOT NULL
+GROUP BY YEAR(`created_at`);
@@ -0,0 +1,6 @@
+-- Migration: Partial and overpaid investment payments (rollback)
+
+ALTER TABLE `payments`
+  DROP INDEX `idx_payments_partial_at`,
+  DROP COLUMN `partial_at`,
+  DROP COLUMN `amount_received`;
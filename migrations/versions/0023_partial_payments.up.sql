@@ -0,0 +1,12 @@
+-- Migration: Partial and overpaid investment payments
+
+ALTER TABLE `payments`
+  ADD COLUMN `amount_received` bigint NOT NULL DEFAULT 0 COMMENT 'amount the gateway reported paid' AFTER `fee`,
+  ADD COLUMN `partial_at` datetime(3) NULL COMMENT 'set when a short payment arrived' AFTER `amount_received`,
+  ADD INDEX `idx_payments_partial_at` (`partial_at`);
+
+-- Settled payments were paid exactly what was billed
+UPDATE `payments` p
+JOIN `investments` i ON i.`id` = p.`investment_id`
+SET p.`amount_received` = IF(p.`amount` = 0, i.`amount`, p.`amount` + p.`fee`)
+WHERE p.`status` = 'Success';
@@ -0,0 +1,7 @@
+-- Migration: User agent on register/login signals and withdrawal risk flags (rollback)
+
+ALTER TABLE `withdrawals`
+  DROP COLUMN `risk_flags`;
+
+ALTER TABLE `user_signals`
+  DROP COLUMN `user_agent`;
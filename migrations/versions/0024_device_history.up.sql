@@ -0,0 +1,7 @@
+-- Migration: User agent on register/login signals and withdrawal risk flags
+
+ALTER TABLE `user_signals`
+  ADD COLUMN `user_agent` varchar(255) NOT NULL DEFAULT '' AFTER `device_fingerprint`;
+
+ALTER TABLE `withdrawals`
+  ADD COLUMN `risk_flags` varchar(255) NULL COMMENT 'comma-separated risk rules that fired at request time' AFTER `processed_at`;
@@ -0,0 +1,11 @@
+-- Migration: Shared bank account detection and On Hold withdrawals (rollback)
+
+UPDATE `withdrawals` SET `status` = 'Pending' WHERE `status` = 'On Hold';
+
+ALTER TABLE `withdrawals`
+  MODIFY COLUMN `status` enum('Success','Pending','Failed') NOT NULL DEFAULT 'Pending';
+
+ALTER TABLE `bank_accounts`
+  DROP INDEX `idx_bank_accounts_bank_normalized`,
+  DROP COLUMN `shared`,
+  DROP COLUMN `account_number_normalized`;
@@ -0,0 +1,27 @@
+-- Migration: Shared bank account detection and On Hold withdrawals
+
+ALTER TABLE `bank_accounts`
+  ADD COLUMN `account_number_normalized` varchar(50) NOT NULL DEFAULT '' AFTER `account_number`,
+  ADD COLUMN `shared` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'another user registered the same account' AFTER `account_number_normalized`,
+  ADD INDEX `idx_bank_accounts_bank_normalized` (`bank_id`, `account_number_normalized`);
+
+-- Same rules as models.NormalizeAccountNumber; existing numbers are already
+-- alphanumeric, so only case, the e-wallet country code and leading zeros differ
+UPDATE `bank_accounts` a
+JOIN `banks` b ON b.`id` = a.`bank_id`
+SET a.`account_number_normalized` = TRIM(LEADING '0' FROM
+  IF(b.`code` IN ('DANA', 'OVO', 'GOPAY', 'SHOPEEPAY', 'LINKAJA') AND UPPER(a.`account_number`) LIKE '62%',
+     SUBSTRING(UPPER(a.`account_number`), 3), UPPER(a.`account_number`)));
+
+UPDATE `bank_accounts` a
+JOIN (
+  SELECT `bank_id`, `account_number_normalized`
+  FROM `bank_accounts`
+  WHERE `account_number_normalized` <> ''
+  GROUP BY `bank_id`, `account_number_normalized`
+  HAVING COUNT(DISTINCT `user_id`) > 1
+) s ON s.`bank_id` = a.`bank_id` AND s.`account_number_normalized` = a.`account_number_normalized`
+SET a.`shared` = 1;
+
+ALTER TABLE `withdrawals`
+  MODIFY COLUMN `status` enum('Success','Pending','On Hold','Failed') NOT NULL DEFAULT 'Pending';
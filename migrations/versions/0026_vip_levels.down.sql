@@ -0,0 +1,3 @@
+-- Migration: Investment and withdrawal caps per VIP level (rollback)
+
+DROP TABLE IF EXISTS `vip_levels`;
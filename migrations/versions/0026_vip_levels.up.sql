@@ -0,0 +1,18 @@
+-- Migration: Investment and withdrawal caps per VIP level
+
+CREATE TABLE IF NOT EXISTS `vip_levels` (
+  `level` int unsigned NOT NULL,
+  `max_active_investments` int NOT NULL DEFAULT 0 COMMENT '0 = no limit',
+  `max_daily_withdrawal` bigint NOT NULL DEFAULT 0 COMMENT '0 = no limit',
+  `max_single_withdrawal` bigint NOT NULL DEFAULT 0 COMMENT '0 = no limit',
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`level`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT IGNORE INTO `vip_levels` (`level`, `max_active_investments`, `max_daily_withdrawal`, `max_single_withdrawal`, `updated_at`) VALUES
+  (0, 3, 2000000, 2000000, NOW(3)),
+  (1, 5, 5000000, 5000000, NOW(3)),
+  (2, 10, 10000000, 10000000, NOW(3)),
+  (3, 20, 25000000, 20000000, NOW(3)),
+  (4, 50, 50000000, 50000000, NOW(3)),
+  (5, 0, 100000000, 100000000, NOW(3));
@@ -0,0 +1,10 @@
+-- Migration: Chargeable express withdrawals (rollback)
+
+ALTER TABLE `withdrawals`
+  DROP INDEX `idx_withdrawals_express`,
+  DROP COLUMN `express_fee`,
+  DROP COLUMN `express`;
+
+ALTER TABLE `settings`
+  DROP COLUMN `express_withdraw_charge`,
+  DROP COLUMN `express_withdraw`;
@@ -0,0 +1,10 @@
+-- Migration: Chargeable express withdrawals
+
+ALTER TABLE `settings`
+  ADD COLUMN `express_withdraw` tinyint(1) NOT NULL DEFAULT 0,
+  ADD COLUMN `express_withdraw_charge` decimal(5,2) NOT NULL DEFAULT 0 COMMENT 'percent on top of withdraw_charge';
+
+ALTER TABLE `withdrawals`
+  ADD COLUMN `express` tinyint(1) NOT NULL DEFAULT 0,
+  ADD COLUMN `express_fee` bigint NOT NULL DEFAULT 0 COMMENT 'part of charge paid for express',
+  ADD INDEX `idx_withdrawals_express` (`express`);
@@ -0,0 +1,6 @@
+-- Migration: VIP level thresholds and level change history (rollback)
+
+DROP TABLE IF EXISTS `vip_level_changes`;
+
+ALTER TABLE `vip_levels`
+  DROP COLUMN `min_total_invest`;
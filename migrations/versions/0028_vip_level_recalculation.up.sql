@@ -0,0 +1,26 @@
+-- Migration: VIP level thresholds and level change history
+
+ALTER TABLE `vip_levels`
+  ADD COLUMN `min_total_invest` bigint NOT NULL DEFAULT 0 COMMENT 'total_invest_vip needed for the level' AFTER `level`;
+
+UPDATE `vip_levels` SET `min_total_invest` = CASE `level`
+  WHEN 1 THEN 50000
+  WHEN 2 THEN 1200000
+  WHEN 3 THEN 7000000
+  WHEN 4 THEN 30000000
+  WHEN 5 THEN 150000000
+  ELSE 0
+END;
+
+CREATE TABLE IF NOT EXISTS `vip_level_changes` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `from_level` int unsigned NOT NULL,
+  `to_level` int unsigned NOT NULL,
+  `total_invest_vip` bigint NOT NULL,
+  `source` varchar(16) NOT NULL COMMENT 'payment, cancel, cron or admin',
+  `applied` tinyint(1) NOT NULL COMMENT '0 = downgrade only flagged',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_vip_level_changes_user` (`user_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,11 @@
+-- Migration: Investment top-ups (rollback)
+
+DROP TABLE IF EXISTS `investment_topups`;
+
+ALTER TABLE `investments`
+  DROP COLUMN `rate_daily_profit`,
+  DROP COLUMN `rate_amount`;
+
+ALTER TABLE `products`
+  DROP COLUMN `topup_max`,
+  DROP COLUMN `topup_min`;
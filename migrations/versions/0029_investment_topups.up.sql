@@ -0,0 +1,34 @@
+-- Migration: Investment top-ups
+
+ALTER TABLE `products`
+  ADD COLUMN `topup_min` bigint NOT NULL DEFAULT 0 AFTER `purchase_limit`,
+  ADD COLUMN `topup_max` bigint NOT NULL DEFAULT 0 COMMENT '0 = no top-ups' AFTER `topup_min`;
+
+ALTER TABLE `investments`
+  ADD COLUMN `rate_amount` bigint NOT NULL DEFAULT 0 COMMENT 'amount bought; set by the first top-up' AFTER `daily_profit`,
+  ADD COLUMN `rate_daily_profit` bigint NOT NULL DEFAULT 0 COMMENT 'daily profit bought; set by the first top-up' AFTER `rate_amount`;
+
+CREATE TABLE IF NOT EXISTS `investment_topups` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `investment_id` bigint unsigned NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `amount` bigint NOT NULL,
+  `fee` bigint NOT NULL DEFAULT 0,
+  `order_id` varchar(191) NOT NULL,
+  `payment_method` varchar(16) NOT NULL COMMENT 'BALANCE, QRIS or BANK',
+  `payment_channel` varchar(16) DEFAULT NULL,
+  `reference_id` varchar(191) DEFAULT NULL,
+  `payment_code` text,
+  `payment_link` text,
+  `expired_at` datetime(3) DEFAULT NULL,
+  `daily_profit_before` bigint NOT NULL DEFAULT 0,
+  `daily_profit_after` bigint NOT NULL DEFAULT 0,
+  `status` enum('Success','Pending','Failed') NOT NULL DEFAULT 'Pending',
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_investment_topups_order_id` (`order_id`),
+  KEY `idx_investment_topups_investment_id` (`investment_id`),
+  KEY `idx_investment_topups_user_id` (`user_id`),
+  KEY `idx_investment_topups_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+-- Migration: Outbox events for payment side effects (rollback)
+
+DROP TABLE IF EXISTS `outbox_events`;
@@ -0,0 +1,18 @@
+-- Migration: Outbox events for payment side effects
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `kind` varchar(64) NOT NULL,
+  `event_key` varchar(191) NOT NULL,
+  `payload` text NOT NULL,
+  `status` enum('Pending','Done','Failed') NOT NULL DEFAULT 'Pending',
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime(3) NOT NULL,
+  `last_error` text,
+  `processed_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_outbox_events_kind_key` (`kind`, `event_key`),
+  KEY `idx_outbox_events_due` (`status`, `next_attempt_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+-- Migration: Append-only admin audit logs (rollback)
+
+DROP TABLE IF EXISTS `admin_audit_logs`;
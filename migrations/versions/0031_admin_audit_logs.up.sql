@@ -0,0 +1,19 @@
+-- Migration: Append-only admin audit logs
+
+CREATE TABLE IF NOT EXISTS `admin_audit_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `admin_id` bigint unsigned NOT NULL,
+  `action` varchar(64) NOT NULL,
+  `target_type` varchar(32) NOT NULL,
+  `target_id` bigint unsigned DEFAULT NULL,
+  `before` text,
+  `after` text,
+  `ip` varchar(45) NOT NULL DEFAULT '',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_admin_audit_logs_admin` (`admin_id`, `created_at`),
+  KEY `idx_admin_audit_logs_action` (`action`, `created_at`),
+  KEY `idx_admin_audit_logs_target` (`target_type`, `target_id`),
+  KEY `idx_admin_audit_logs_created` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
@@ -0,0 +1,7 @@
+-- Migration: Product images and metadata (rollback)
+
+ALTER TABLE `products`
+  DROP COLUMN `badge`,
+  DROP COLUMN `highlights`,
+  DROP COLUMN `description`,
+  DROP COLUMN `image`;
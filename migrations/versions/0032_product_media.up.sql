@@ -0,0 +1,7 @@
+-- Migration: Product images and metadata
+
+ALTER TABLE `products`
+  ADD COLUMN `image` varchar(255) NOT NULL DEFAULT '' COMMENT 'object key in the upload bucket, or an absolute URL' AFTER `topup_max`,
+  ADD COLUMN `description` text AFTER `image`,
+  ADD COLUMN `highlights` text COMMENT 'JSON array of strings' AFTER `description`,
+  ADD COLUMN `badge` varchar(32) NOT NULL DEFAULT '' AFTER `highlights`;
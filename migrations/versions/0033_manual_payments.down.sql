@@ -0,0 +1,15 @@
+-- Migration: Manual bank-transfer payments reviewed by an admin (rollback)
+
+ALTER TABLE `payments`
+  DROP INDEX `idx_payments_manual_review`,
+  DROP COLUMN `review_note`,
+  DROP COLUMN `reviewed_at`,
+  DROP COLUMN `reviewed_by`,
+  DROP COLUMN `proof_uploaded_at`,
+  DROP COLUMN `proof_image`;
+
+ALTER TABLE `settings`
+  DROP COLUMN `manual_account_name`,
+  DROP COLUMN `manual_account_number`,
+  DROP COLUMN `manual_bank_name`,
+  DROP COLUMN `manual_payment`;
@@ -0,0 +1,15 @@
+-- Migration: Manual bank-transfer payments reviewed by an admin
+
+ALTER TABLE `settings`
+  ADD COLUMN `manual_payment` tinyint(1) NOT NULL DEFAULT 0,
+  ADD COLUMN `manual_bank_name` varchar(100) NOT NULL DEFAULT '',
+  ADD COLUMN `manual_account_number` varchar(50) NOT NULL DEFAULT '',
+  ADD COLUMN `manual_account_name` varchar(100) NOT NULL DEFAULT '';
+
+ALTER TABLE `payments`
+  ADD COLUMN `proof_image` varchar(255) DEFAULT NULL COMMENT 'object key of the transfer proof',
+  ADD COLUMN `proof_uploaded_at` datetime(3) DEFAULT NULL,
+  ADD COLUMN `reviewed_by` bigint unsigned DEFAULT NULL,
+  ADD COLUMN `reviewed_at` datetime(3) DEFAULT NULL,
+  ADD COLUMN `review_note` varchar(255) DEFAULT NULL,
+  ADD INDEX `idx_payments_manual_review` (`payment_method`, `reviewed_at`, `proof_uploaded_at`);
@@ -0,0 +1,6 @@
+-- Migration: Per-minute limits of each user on expensive route classes (rollback)
+
+ALTER TABLE `settings`
+  DROP COLUMN `rate_limit_read`,
+  DROP COLUMN `rate_limit_export`,
+  DROP COLUMN `rate_limit_purchase`;
@@ -0,0 +1,6 @@
+-- Migration: Per-minute limits of each user on expensive route classes
+
+ALTER TABLE `settings`
+  ADD COLUMN `rate_limit_purchase` int NOT NULL DEFAULT 5,
+  ADD COLUMN `rate_limit_export` int NOT NULL DEFAULT 1,
+  ADD COLUMN `rate_limit_read` int NOT NULL DEFAULT 30;
@@ -0,0 +1,21 @@
+-- Migration: KytaPay payment and payout ids in their own columns (rollback)
+
+UPDATE `payments` SET `reference_id` = `gateway_payment_id` WHERE `gateway_payment_id` IS NOT NULL;
+UPDATE `deposits` SET `reference_id` = `gateway_payment_id` WHERE `gateway_payment_id` IS NOT NULL;
+UPDATE `investment_topups` SET `reference_id` = `gateway_payment_id` WHERE `gateway_payment_id` IS NOT NULL;
+
+ALTER TABLE `withdrawals`
+  DROP INDEX `idx_withdrawals_gateway_payout_id`,
+  DROP COLUMN `gateway_payout_id`;
+
+ALTER TABLE `investment_topups`
+  DROP INDEX `idx_investment_topups_gateway_payment_id`,
+  DROP COLUMN `gateway_payment_id`;
+
+ALTER TABLE `deposits`
+  DROP INDEX `idx_deposits_gateway_payment_id`,
+  DROP COLUMN `gateway_payment_id`;
+
+ALTER TABLE `payments`
+  DROP INDEX `idx_payments_gateway_payment_id`,
+  DROP COLUMN `gateway_payment_id`;
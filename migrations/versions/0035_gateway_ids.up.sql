@@ -0,0 +1,27 @@
+-- Migration: KytaPay payment and payout ids in their own columns
+
+ALTER TABLE `payments`
+  ADD COLUMN `gateway_payment_id` varchar(191) DEFAULT NULL,
+  ADD INDEX `idx_payments_gateway_payment_id` (`gateway_payment_id`);
+
+ALTER TABLE `deposits`
+  ADD COLUMN `gateway_payment_id` varchar(191) DEFAULT NULL,
+  ADD INDEX `idx_deposits_gateway_payment_id` (`gateway_payment_id`);
+
+ALTER TABLE `investment_topups`
+  ADD COLUMN `gateway_payment_id` varchar(191) DEFAULT NULL,
+  ADD INDEX `idx_investment_topups_gateway_payment_id` (`gateway_payment_id`);
+
+ALTER TABLE `withdrawals`
+  ADD COLUMN `gateway_payout_id` varchar(191) DEFAULT NULL,
+  ADD INDEX `idx_withdrawals_gateway_payout_id` (`gateway_payout_id`);
+
+-- The webhook used to overwrite reference_id with the gateway's id, and
+-- deposits stored the gateway's id there from the start: move it over and
+-- restore our reference, which was always the order id
+UPDATE `payments` SET `gateway_payment_id` = `reference_id`, `reference_id` = `order_id`
+  WHERE `reference_id` IS NOT NULL AND `reference_id` <> `order_id`;
+UPDATE `deposits` SET `gateway_payment_id` = `reference_id`, `reference_id` = `order_id`
+  WHERE `reference_id` IS NOT NULL AND `reference_id` <> `order_id`;
+UPDATE `investment_topups` SET `gateway_payment_id` = `reference_id`, `reference_id` = `order_id`
+  WHERE `reference_id` IS NOT NULL AND `reference_id` <> `order_id`;
@@ -0,0 +1,4 @@
+-- Migration: Outbound webhooks to downstream systems and their delivery log (rollback)
+
+DROP TABLE IF EXISTS `webhook_deliveries`;
+DROP TABLE IF EXISTS `webhook_endpoints`;
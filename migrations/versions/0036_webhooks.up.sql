@@ -0,0 +1,35 @@
+-- Migration: Outbound webhooks to downstream systems and their delivery log
+
+CREATE TABLE IF NOT EXISTS `webhook_endpoints` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `url` varchar(500) NOT NULL,
+  `secret` varchar(128) NOT NULL,
+  `events` varchar(255) NOT NULL,
+  `include_pii` tinyint(1) NOT NULL DEFAULT 0,
+  `active` tinyint(1) NOT NULL DEFAULT 1,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `endpoint_id` bigint unsigned NOT NULL,
+  `event_id` varchar(64) NOT NULL,
+  `event_type` varchar(64) NOT NULL,
+  `payload` text NOT NULL,
+  `status` enum('Pending','Success','Failed') NOT NULL DEFAULT 'Pending',
+  `attempts` int NOT NULL DEFAULT 0,
+  `response_status` int NOT NULL DEFAULT 0,
+  `last_error` text,
+  `last_attempt_at` datetime(3) DEFAULT NULL,
+  `delivered_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_webhook_deliveries_endpoint` (`endpoint_id`, `created_at`),
+  KEY `idx_webhook_deliveries_event_id` (`event_id`),
+  KEY `idx_webhook_deliveries_type` (`event_type`),
+  KEY `idx_webhook_deliveries_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+-- Migration: Applied SFXCR withdrawal callbacks, so a retried one is ignored (rollback)
+
+DROP TABLE IF EXISTS `sfxcr_callbacks`;
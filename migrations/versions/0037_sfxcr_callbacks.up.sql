@@ -0,0 +1,13 @@
+-- Migration: Applied SFXCR withdrawal callbacks, so a retried one is ignored
+
+CREATE TABLE IF NOT EXISTS `sfxcr_callbacks` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `order_id` varchar(191) NOT NULL,
+  `callback_id` varchar(191) NOT NULL,
+  `status` varchar(32) NOT NULL,
+  `outcome` varchar(32) NOT NULL,
+  `remote_ip` varchar(64) NOT NULL DEFAULT '',
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_sfxcr_callbacks_order_callback` (`order_id`, `callback_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,4 @@
+-- Migration: Per-product cooldown between one user's purchases (rollback)
+
+ALTER TABLE `products`
+  DROP COLUMN `purchase_cooldown_hours`;
@@ -0,0 +1,4 @@
+-- Migration: Per-product cooldown between one user's purchases
+
+ALTER TABLE `products`
+  ADD COLUMN `purchase_cooldown_hours` int NOT NULL DEFAULT 0 COMMENT '0 = no cooldown' AFTER `topup_max`;
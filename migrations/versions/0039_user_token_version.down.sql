@@ -0,0 +1,4 @@
+-- Migration: Per-user token version; bumping it invalidates every access token issued before (rollback)
+
+ALTER TABLE `users`
+  DROP COLUMN `token_version`;
@@ -0,0 +1,4 @@
+-- Migration: Per-user token version; bumping it invalidates every access token issued before
+
+ALTER TABLE `users`
+  ADD COLUMN `token_version` int NOT NULL DEFAULT 0 COMMENT 'bumped on password/phone change and forced logout';
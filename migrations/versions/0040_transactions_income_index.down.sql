@@ -0,0 +1,4 @@
+-- Migration: Index for the user income summary (rollback)
+
+ALTER TABLE `transactions`
+  DROP INDEX `idx_transactions_user_flow_status_created`;
@@ -0,0 +1,5 @@
+-- Migration: Index for the user income summary
+-- Success debit transactions of one user, optionally from a created_at onwards.
+
+ALTER TABLE `transactions`
+  ADD INDEX `idx_transactions_user_flow_status_created` (`user_id`, `transaction_flow`, `status`, `created_at`);
@@ -0,0 +1,4 @@
+-- Migration: KytaPay bank code per bank, for banks whose gateway code differs from ours (rollback)
+
+ALTER TABLE `banks`
+  DROP COLUMN `gateway_code`;
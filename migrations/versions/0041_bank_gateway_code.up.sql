@@ -0,0 +1,4 @@
+-- Migration: KytaPay bank code per bank, for banks whose gateway code differs from ours
+
+ALTER TABLE `banks`
+  ADD COLUMN `gateway_code` varchar(20) NOT NULL DEFAULT '' COMMENT 'empty = payouts use code' AFTER `code`;
@@ -0,0 +1,4 @@
+-- Migration: Bank logos for the app's account registration form (rollback)
+
+ALTER TABLE `banks`
+  DROP COLUMN `logo`;
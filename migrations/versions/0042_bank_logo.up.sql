@@ -0,0 +1,4 @@
+-- Migration: Bank logos for the app's account registration form
+
+ALTER TABLE `banks`
+  ADD COLUMN `logo` varchar(255) NOT NULL DEFAULT '' COMMENT 'object key or absolute URL' AFTER `gateway_code`;
@@ -0,0 +1,6 @@
+-- Migration: Time-boxed profit boosts on categories and products (rollback)
+
+ALTER TABLE `investments`
+  DROP COLUMN `total_boost`;
+
+DROP TABLE IF EXISTS `profit_boosts`;
@@ -0,0 +1,21 @@
+-- Migration: Time-boxed profit boosts on categories and products
+
+CREATE TABLE `profit_boosts` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `category_id` bigint unsigned NULL,
+  `product_id` bigint unsigned NULL,
+  `extra_percent` decimal(5,2) NOT NULL COMMENT 'of the invested amount, per payout',
+  `starts_at` datetime(3) NOT NULL,
+  `ends_at` datetime(3) NOT NULL,
+  `status` enum('Active','Inactive') NOT NULL DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_profit_boosts_category_id` (`category_id`),
+  KEY `idx_profit_boosts_product_id` (`product_id`),
+  KEY `idx_profit_boosts_status_window` (`status`, `starts_at`, `ends_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `investments`
+  ADD COLUMN `total_boost` bigint NOT NULL DEFAULT 0 COMMENT 'profit boost earned, apart from total_returned' AFTER `total_returned`;
@@ -0,0 +1,9 @@
+-- Migration: Admin grant batches of spin tickets and balance (rollback)
+
+DELETE FROM `ticket_grants` WHERE `source` = 'admin';
+
+ALTER TABLE `ticket_grants`
+  MODIFY COLUMN `source` enum('referral','mission') NOT NULL;
+
+DROP TABLE IF EXISTS `grant_batch_items`;
+DROP TABLE IF EXISTS `grant_batches`;
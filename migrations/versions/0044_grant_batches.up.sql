@@ -0,0 +1,39 @@
+-- Migration: Admin grant batches of spin tickets and balance
+
+CREATE TABLE `grant_batches` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `batch_key` varchar(64) NOT NULL,
+  `admin_id` bigint unsigned NOT NULL,
+  `target` enum('all','vip','users','invested') NOT NULL,
+  `min_level` bigint unsigned NULL,
+  `user_ids` text NULL,
+  `invested_from` datetime(3) NULL,
+  `invested_to` datetime(3) NULL,
+  `grant_type` enum('spin_ticket','balance') NOT NULL,
+  `amount` bigint NOT NULL COMMENT 'tickets or rupiah per user',
+  `message` varchar(255) NOT NULL,
+  `max_user_id` bigint unsigned NOT NULL,
+  `last_user_id` bigint unsigned NOT NULL DEFAULT 0,
+  `granted` bigint NOT NULL DEFAULT 0,
+  `status` enum('Running','Completed') NOT NULL DEFAULT 'Running',
+  `completed_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_grant_batches_batch_key` (`batch_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `grant_batch_items` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `batch_id` bigint unsigned NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `amount` bigint NOT NULL,
+  `order_id` varchar(191) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_grant_batch_items_batch_user` (`batch_id`, `user_id`),
+  KEY `idx_grant_batch_items_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `ticket_grants`
+  MODIFY COLUMN `source` enum('referral','mission','admin') NOT NULL;
@@ -0,0 +1,10 @@
+-- Migration: Retention days of the log tables and the cron_runs record (rollback)
+
+DROP TABLE IF EXISTS `cron_runs`;
+
+ALTER TABLE `settings`
+  DROP COLUMN `retention_notifications_days`,
+  DROP COLUMN `retention_user_signals_days`,
+  DROP COLUMN `retention_webhook_deliveries_days`,
+  DROP COLUMN `retention_outbox_events_days`,
+  DROP COLUMN `retention_cron_runs_days`;
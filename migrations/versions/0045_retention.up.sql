@@ -0,0 +1,20 @@
+-- Migration: Retention days of the log tables and the cron_runs record
+
+ALTER TABLE `settings`
+  ADD COLUMN `retention_notifications_days` int NOT NULL DEFAULT 90,
+  ADD COLUMN `retention_user_signals_days` int NOT NULL DEFAULT 180,
+  ADD COLUMN `retention_webhook_deliveries_days` int NOT NULL DEFAULT 30,
+  ADD COLUMN `retention_outbox_events_days` int NOT NULL DEFAULT 30,
+  ADD COLUMN `retention_cron_runs_days` int NOT NULL DEFAULT 90;
+
+CREATE TABLE `cron_runs` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(64) NOT NULL,
+  `status` enum('Success','Partial','Failed') NOT NULL,
+  `result` text NULL,
+  `error` text NULL,
+  `started_at` datetime(3) NOT NULL,
+  `finished_at` datetime(3) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_cron_runs_name_started` (`name`, `started_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
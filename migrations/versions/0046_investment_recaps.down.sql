@@ -0,0 +1,3 @@
+-- Migration: Earnings recap of completed investments (rollback)
+
+DROP TABLE IF EXISTS `investment_recaps`;
@@ -0,0 +1,21 @@
+-- Migration: Earnings recap of completed investments
+
+CREATE TABLE `investment_recaps` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `investment_id` bigint unsigned NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `order_id` varchar(191) NOT NULL,
+  `product_name` varchar(100) NOT NULL,
+  `principal` bigint NOT NULL,
+  `profit` bigint NOT NULL,
+  `boost` bigint NOT NULL DEFAULT 0,
+  `total_earned` bigint NOT NULL,
+  `duration` bigint NOT NULL,
+  `roi_percent` decimal(9,2) NOT NULL,
+  `started_at` datetime(3) NULL,
+  `completed_at` datetime(3) NOT NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_investment_recaps_investment_id` (`investment_id`),
+  KEY `idx_investment_recaps_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+-- Migration: Hashed admin API keys for read-only integrations (rollback)
+
+DROP TABLE IF EXISTS `api_keys`;
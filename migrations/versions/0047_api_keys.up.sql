@@ -0,0 +1,19 @@
+-- Migration: Hashed admin API keys for read-only integrations
+
+CREATE TABLE `api_keys` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `admin_id` bigint NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `hint` varchar(16) NOT NULL,
+  `key_hash` char(64) NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `rate_limit` bigint NOT NULL DEFAULT 60,
+  `expires_at` datetime(3) NULL,
+  `revoked_at` datetime(3) NULL,
+  `last_used_at` datetime(3) NULL,
+  `last_used_ip` varchar(45) NOT NULL DEFAULT '',
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_api_keys_key_hash` (`key_hash`),
+  KEY `idx_api_keys_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
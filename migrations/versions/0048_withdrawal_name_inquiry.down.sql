@@ -0,0 +1,9 @@
+-- Migration: Account holder inquiry on withdrawals and its name match threshold (rollback)
+
+ALTER TABLE `settings`
+  DROP COLUMN `withdraw_name_match_min`;
+
+ALTER TABLE `withdrawals`
+  DROP COLUMN `name_match_score`,
+  DROP COLUMN `inquiry_name`,
+  DROP COLUMN `inquiry_status`;
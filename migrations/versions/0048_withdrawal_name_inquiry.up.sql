@@ -0,0 +1,9 @@
+-- Migration: Account holder inquiry on withdrawals and its name match threshold
+
+ALTER TABLE `withdrawals`
+  ADD COLUMN `inquiry_status` varchar(16) NULL,
+  ADD COLUMN `inquiry_name` varchar(191) NULL,
+  ADD COLUMN `name_match_score` int NULL;
+
+ALTER TABLE `settings`
+  ADD COLUMN `withdraw_name_match_min` int NOT NULL DEFAULT 80;
@@ -0,0 +1,5 @@
+-- Migration: Promo campaign landing pages linking banners, products and a deposit campaign (rollback)
+
+DROP TABLE IF EXISTS `campaign_products`;
+DROP TABLE IF EXISTS `campaign_banners`;
+DROP TABLE IF EXISTS `campaigns`;
@@ -0,0 +1,33 @@
+-- Migration: Promo campaign landing pages linking banners, products and a deposit campaign
+
+CREATE TABLE `campaigns` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `slug` varchar(64) NOT NULL,
+  `title` varchar(150) NOT NULL,
+  `description` text,
+  `deposit_campaign_id` bigint unsigned NULL,
+  `starts_at` datetime(3) NOT NULL,
+  `ends_at` datetime(3) NOT NULL,
+  `status` enum('Active','Inactive') NOT NULL DEFAULT 'Active',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_campaigns_slug` (`slug`),
+  KEY `idx_campaigns_deposit_campaign_id` (`deposit_campaign_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `campaign_banners` (
+  `campaign_id` bigint unsigned NOT NULL,
+  `banner_id` bigint unsigned NOT NULL,
+  `position` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`campaign_id`, `banner_id`),
+  KEY `idx_campaign_banners_banner_id` (`banner_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE `campaign_products` (
+  `campaign_id` bigint unsigned NOT NULL,
+  `product_id` bigint unsigned NOT NULL,
+  `position` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`campaign_id`, `product_id`),
+  KEY `idx_campaign_products_product_id` (`product_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,7 @@
+-- Migration: Country allow and deny lists for geoblocking and per-user overrides (rollback)
+
+DROP TABLE IF EXISTS `geo_overrides`;
+
+ALTER TABLE `settings`
+  DROP COLUMN `geo_deny_countries`,
+  DROP COLUMN `geo_allow_countries`;
@@ -0,0 +1,16 @@
+-- Migration: Country allow and deny lists for geoblocking and per-user overrides
+
+ALTER TABLE `settings`
+  ADD COLUMN `geo_allow_countries` varchar(1000) NOT NULL DEFAULT '',
+  ADD COLUMN `geo_deny_countries` varchar(1000) NOT NULL DEFAULT '';
+
+CREATE TABLE `geo_overrides` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `reason` varchar(255) NOT NULL,
+  `admin_id` bigint NOT NULL,
+  `expires_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_geo_overrides_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,7 @@
+-- Migration: Refund payouts for cancelled investments and refund totals in daily reports (rollback)
+
+ALTER TABLE `daily_reports`
+  DROP COLUMN `refunds_paid_out`,
+  DROP COLUMN `refunds_to_balance`;
+
+DROP TABLE IF EXISTS `refund_payouts`;
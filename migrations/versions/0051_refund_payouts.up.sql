@@ -0,0 +1,25 @@
+-- Migration: Refund payouts for cancelled investments and refund totals in daily reports
+
+CREATE TABLE `refund_payouts` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `investment_id` bigint unsigned NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `bank_account_id` bigint unsigned NOT NULL,
+  `amount` bigint NOT NULL,
+  `order_id` varchar(191) NOT NULL,
+  `status` enum('Pending','Success','Failed') NOT NULL DEFAULT 'Pending',
+  `gateway_payout_id` varchar(191) NULL,
+  `failure_reason` varchar(255) NULL,
+  `admin_id` bigint NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_refund_payouts_investment_id` (`investment_id`),
+  UNIQUE KEY `idx_refund_payouts_order_id` (`order_id`),
+  KEY `idx_refund_payouts_user_id` (`user_id`),
+  KEY `idx_refund_payouts_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE `daily_reports`
+  ADD COLUMN `refunds_to_balance` bigint NOT NULL DEFAULT 0 AFTER `withdrawal_charges`,
+  ADD COLUMN `refunds_paid_out` bigint NOT NULL DEFAULT 0 AFTER `refunds_to_balance`;
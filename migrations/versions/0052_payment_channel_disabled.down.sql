@@ -0,0 +1,4 @@
+-- Migration: Let admins switch payment channels off (rollback)
+
+ALTER TABLE `payment_channels`
+  DROP COLUMN `disabled`;
@@ -0,0 +1,4 @@
+-- Migration: Let admins switch payment channels off
+
+ALTER TABLE `payment_channels`
+  ADD COLUMN `disabled` boolean NOT NULL DEFAULT false AFTER `pass_fee`;
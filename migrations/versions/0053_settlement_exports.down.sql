@@ -0,0 +1,3 @@
+-- Migration: Daily settlement files exported to object storage (rollback)
+
+DROP TABLE IF EXISTS `settlement_exports`;
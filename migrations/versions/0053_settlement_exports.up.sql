@@ -0,0 +1,16 @@
+-- Migration: Daily settlement files exported to object storage
+
+CREATE TABLE `settlement_exports` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `export_date` char(10) NOT NULL,
+  `kind` enum('payments','payouts','profits') NOT NULL,
+  `object_key` varchar(255) NOT NULL,
+  `row_count` bigint NOT NULL,
+  `total_amount` bigint NOT NULL,
+  `size` bigint NOT NULL,
+  `sha256` char(64) NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_settlement_exports_date_kind` (`export_date`, `kind`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
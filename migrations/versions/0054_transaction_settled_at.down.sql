@@ -0,0 +1,5 @@
+-- Migration: Record when each transaction settled (rollback)
+
+ALTER TABLE `transactions`
+  DROP INDEX `idx_transactions_settled_at`,
+  DROP COLUMN `settled_at`;
@@ -0,0 +1,8 @@
+-- Migration: Record when each transaction settled
+
+ALTER TABLE `transactions`
+  ADD COLUMN `settled_at` datetime(3) NULL AFTER `updated_at`,
+  ADD INDEX `idx_transactions_settled_at` (`settled_at`);
+
+-- Until now a Success row's updated_at stood for its settlement time
+UPDATE `transactions` SET `settled_at` = `updated_at` WHERE `status` = 'Success';
@@ -0,0 +1,4 @@
+-- Migration: Refresh token ids are 48 characters, longer than char(36) (rollback)
+
+ALTER TABLE `refresh_tokens`
+  MODIFY COLUMN `id` char(36) NOT NULL;
@@ -0,0 +1,3 @@
+-- Migration: Transfers reported for investment payments, so a payment paid in several transfers adds each once (rollback)
+
+DROP TABLE IF EXISTS `payment_receipts`;
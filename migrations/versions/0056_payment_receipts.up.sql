@@ -0,0 +1,11 @@
+-- Migration: Transfers reported for investment payments, so a payment paid in several transfers adds each once
+
+CREATE TABLE IF NOT EXISTS `payment_receipts` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `payment_id` bigint unsigned NOT NULL,
+  `callback_key` varchar(191) NOT NULL,
+  `amount` bigint NOT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_payment_receipts_payment_callback` (`payment_id`, `callback_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditLogAppendOnly is returned when something tries to change or remove
+// an admin audit log.
+var ErrAuditLogAppendOnly = errors.New("admin audit logs are append-only")
+
+// AdminAuditLog records one admin change with JSON snapshots of the target
+// before and after it. Rows are only ever inserted.
+type AdminAuditLog struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	AdminID uint   `gorm:"not null;index:idx_admin_audit_logs_admin,priority:1" json:"admin_id"`
+	Action  string `gorm:"type:varchar(64);not null;index:idx_admin_audit_logs_action,priority:1" json:"action"` // e.g. withdrawal.approve
+	// TargetType and TargetID name the changed record; TargetID is nil for
+	// singletons like settings
+	TargetType string    `gorm:"type:varchar(32);not null;index:idx_admin_audit_logs_target,priority:1" json:"target_type"`
+	TargetID   *uint     `gorm:"index:idx_admin_audit_logs_target,priority:2" json:"target_id"`
+	Before     *string   `gorm:"type:text" json:"before"`
+	After      *string   `gorm:"type:text" json:"after"`
+	IP         string    `gorm:"column:ip;type:varchar(45);not null;default:''" json:"ip"`
+	CreatedAt  time.Time `gorm:"index:idx_admin_audit_logs_admin,priority:2;index:idx_admin_audit_logs_action,priority:2;index:idx_admin_audit_logs_created" json:"created_at"`
+}
+
+func (AdminAuditLog) TableName() string {
+	return "admin_audit_logs"
+}
+
+func (*AdminAuditLog) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditLogAppendOnly
+}
+
+func (*AdminAuditLog) BeforeDelete(*gorm.DB) error {
+	return ErrAuditLogAppendOnly
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Announcement is a broadcast message shown to all users or to a VIP level segment.
+// MinLevel/MaxLevel nil means no bound on that side.
+type Announcement struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Title       string     `gorm:"size:150;not null" json:"title"`
+	Body        string     `gorm:"type:text;not null" json:"body"`
+	MinLevel    *uint      `gorm:"column:min_level" json:"min_level"`
+	MaxLevel    *uint      `gorm:"column:max_level" json:"max_level"`
+	PublishAt   time.Time  `gorm:"not null;index" json:"publish_at"`
+	ExpireAt    *time.Time `gorm:"index" json:"expire_at"`
+	Pinned      bool       `gorm:"not null;default:false" json:"pinned"`
+	Status      string     `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (Announcement) TableName() string {
+	return "announcements"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrInsufficientBalance is returned by DebitBalance when the balance does
+// not cover the amount.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// DebitBalance takes amount from the balance of userID in one conditional
+// UPDATE that only matches while the balance still covers it, so debits
+// racing each other or a credit can never take the balance below zero. No
+// row matched means the funds were short: ErrInsufficientBalance, with
+// nothing changed. Every debit a user can trigger goes through here; the
+// balance is never read, checked and written back in separate statements.
+func DebitBalance(db *gorm.DB, userID uint, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	res := db.Model(&User{}).Where("id = ? AND balance >= ?", userID, amount).
+		UpdateColumn("balance", gorm.Expr("balance - ?", amount))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
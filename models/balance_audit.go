@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// BalanceAudit is a user whose balance disagreed with their transaction
+// ledger in one run of the balance audit. Drift is Balance minus
+// LedgerBalance: positive means the user holds more than the ledger explains.
+type BalanceAudit struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	RunID         string     `gorm:"type:varchar(32);not null;index" json:"run_id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Balance       int64      `gorm:"type:bigint;not null" json:"balance"`
+	LedgerBalance int64      `gorm:"type:bigint;not null" json:"ledger_balance"`
+	Drift         int64      `gorm:"type:bigint;not null" json:"drift"`
+	RepairedAt    *time.Time `json:"repaired_at"`
+	RepairedBy    *int64     `json:"repaired_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (BalanceAudit) TableName() string {
+	return "balance_audits"
+}
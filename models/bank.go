@@ -1,12 +1,23 @@
 package models
 
 type Bank struct {
-	ID     uint   `gorm:"primaryKey" json:"id"`
-	Name   string `gorm:"size:100;not null" json:"name"`
-	Code   string `gorm:"size:20;uniqueIndex;not null" json:"code"`
-	Status string `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Code        string `gorm:"size:20;uniqueIndex;not null" json:"code"`
+	GatewayCode string `gorm:"size:20;not null;default:''" json:"gateway_code"` // KytaPay's code where it differs from Code (BNC, Seabank, Jago)
+	Logo        string `gorm:"size:255;not null;default:''" json:"logo"`        // object key in the upload bucket or an absolute URL
+	Status      string `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
 }
 
 func (Bank) TableName() string {
 	return "banks"
 }
+
+// PayoutCode is the bank code sent to KytaPay for payouts: GatewayCode, or
+// Code when no mapping is set.
+func (b *Bank) PayoutCode() string {
+	if b.GatewayCode != "" {
+		return b.GatewayCode
+	}
+	return b.Code
+}
@@ -1,14 +1,78 @@
 package models
 
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
 type BankAccount struct {
 	ID            uint   `gorm:"primaryKey" json:"id"`
 	UserID        uint   `gorm:"not null;index" json:"user_id"`
-	BankID        uint   `gorm:"not null;index" json:"bank_id"`
+	BankID        uint   `gorm:"not null;index;index:idx_bank_accounts_bank_normalized,priority:1" json:"bank_id"`
 	AccountName   string `gorm:"size:100;not null" json:"account_name"`
 	AccountNumber string `gorm:"size:50;not null" json:"account_number"`
-	Bank          *Bank  `gorm:"foreignKey:BankID" json:"bank,omitempty"`
+	// AccountNumberNormalized is AccountNumber as NormalizeAccountNumber
+	// compares it across users
+	AccountNumberNormalized string `gorm:"size:50;not null;default:'';index:idx_bank_accounts_bank_normalized,priority:2" json:"-"`
+	// Shared is set when another user has registered the same account
+	Shared bool  `gorm:"not null;default:false" json:"-"`
+	Bank   *Bank `gorm:"foreignKey:BankID" json:"bank,omitempty"`
 }
 
 func (BankAccount) TableName() string {
 	return "bank_accounts"
 }
+
+// phoneNumberBanks are the e-wallets whose account number is the owner's
+// phone number.
+var phoneNumberBanks = map[string]bool{
+	"DANA":      true,
+	"OVO":       true,
+	"GOPAY":     true,
+	"SHOPEEPAY": true,
+	"LINKAJA":   true,
+}
+
+// NormalizeAccountNumber reduces an account number to the form compared
+// across users: letters and digits only, upper-cased, without leading zeros.
+// E-wallet numbers also lose the 62 country code, so 0812..., 62812... and
+// +62 812... match.
+func NormalizeAccountNumber(bankCode, number string) string {
+	n := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, number)
+	if phoneNumberBanks[strings.ToUpper(bankCode)] {
+		n = strings.TrimPrefix(n, "62")
+	}
+	return strings.TrimLeft(n, "0")
+}
+
+// FlagSharedBankAccount reports whether another user holds acc's bank and
+// normalized number and, if so, marks every account with that pair as
+// shared. acc must be saved with AccountNumberNormalized set.
+func FlagSharedBankAccount(db *gorm.DB, acc *BankAccount) (bool, error) {
+	if acc.AccountNumberNormalized == "" {
+		return false, nil
+	}
+	var others int64
+	if err := db.Model(&BankAccount{}).
+		Where("bank_id = ? AND account_number_normalized = ? AND user_id <> ?", acc.BankID, acc.AccountNumberNormalized, acc.UserID).
+		Count(&others).Error; err != nil {
+		return false, err
+	}
+	if others == 0 {
+		return false, nil
+	}
+	if err := db.Model(&BankAccount{}).
+		Where("bank_id = ? AND account_number_normalized = ?", acc.BankID, acc.AccountNumberNormalized).
+		Update("shared", true).Error; err != nil {
+		return false, err
+	}
+	acc.Shared = true
+	return true, nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Banner is a slide in the app home screen carousel. Image is an object key
+// in the upload bucket or an absolute URL. MinLevel/MaxLevel nil means no
+// bound on that side; anonymous visitors count as level 0.
+type Banner struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Title     string     `gorm:"size:150;not null" json:"title"`
+	Image     string     `gorm:"type:varchar(255)" json:"image"`
+	Link      *string    `gorm:"type:varchar(500)" json:"link"`
+	SortOrder int        `gorm:"not null;default:0" json:"sort_order"`
+	StartsAt  time.Time  `gorm:"not null;index:idx_banners_status_starts,priority:2" json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	MinLevel  *uint      `gorm:"column:min_level" json:"min_level"`
+	MaxLevel  *uint      `gorm:"column:max_level" json:"max_level"`
+	Status    string     `gorm:"type:enum('Active','Inactive');default:'Active';index:idx_banners_status_starts,priority:1" json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (Banner) TableName() string {
+	return "banners"
+}
+
+// VisibleTo reports whether the banner shows to a user of level at now: it
+// is Active with an image, inside its window and within its level bounds.
+func (b *Banner) VisibleTo(level uint, now time.Time) bool {
+	if b.Status != "Active" || b.Image == "" || now.Before(b.StartsAt) || (b.EndsAt != nil && !now.Before(*b.EndsAt)) {
+		return false
+	}
+	return (b.MinLevel == nil || *b.MinLevel <= level) && (b.MaxLevel == nil || *b.MaxLevel >= level)
+}
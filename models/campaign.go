@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Campaign is a promo landing page in the app, served whole by
+// GET /users/campaigns/{slug}: banners and featured products picked from the
+// existing tables, in the order given, and optionally the terms of a deposit
+// bonus campaign. The app counts down to StartsAt before the campaign opens
+// and to EndsAt while it runs.
+type Campaign struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Slug              string    `gorm:"size:64;not null;uniqueIndex" json:"slug"`
+	Title             string    `gorm:"size:150;not null" json:"title"`
+	Description       *string   `gorm:"type:text" json:"description"`
+	DepositCampaignID *uint     `gorm:"index" json:"deposit_campaign_id"`
+	StartsAt          time.Time `gorm:"not null" json:"starts_at"`
+	EndsAt            time.Time `gorm:"not null" json:"ends_at"`
+	Status            string    `gorm:"type:enum('Active','Inactive');not null;default:'Active'" json:"status"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// BannerIDs and ProductIDs are the campaign's items in display order,
+	// set by LoadCampaignItems
+	BannerIDs  []uint `gorm:"-" json:"banner_ids"`
+	ProductIDs []uint `gorm:"-" json:"product_ids"`
+}
+
+func (Campaign) TableName() string {
+	return "campaigns"
+}
+
+// CampaignBanner places a banner on a campaign at Position.
+type CampaignBanner struct {
+	CampaignID uint `gorm:"primaryKey;autoIncrement:false"`
+	BannerID   uint `gorm:"primaryKey;autoIncrement:false;index"`
+	Position   int  `gorm:"not null;default:0"`
+}
+
+func (CampaignBanner) TableName() string {
+	return "campaign_banners"
+}
+
+// CampaignProduct features a product on a campaign at Position.
+type CampaignProduct struct {
+	CampaignID uint `gorm:"primaryKey;autoIncrement:false"`
+	ProductID  uint `gorm:"primaryKey;autoIncrement:false;index"`
+	Position   int  `gorm:"not null;default:0"`
+}
+
+func (CampaignProduct) TableName() string {
+	return "campaign_products"
+}
+
+// LoadCampaignItems sets the BannerIDs and ProductIDs of each campaign.
+func LoadCampaignItems(db *gorm.DB, campaigns []Campaign) error {
+	if len(campaigns) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(campaigns))
+	index := make(map[uint]int, len(campaigns))
+	for i := range campaigns {
+		ids[i] = campaigns[i].ID
+		index[campaigns[i].ID] = i
+		campaigns[i].BannerIDs = []uint{}
+		campaigns[i].ProductIDs = []uint{}
+	}
+	var banners []CampaignBanner
+	if err := db.Where("campaign_id IN ?", ids).Order("campaign_id, position, banner_id").Find(&banners).Error; err != nil {
+		return err
+	}
+	for _, b := range banners {
+		c := &campaigns[index[b.CampaignID]]
+		c.BannerIDs = append(c.BannerIDs, b.BannerID)
+	}
+	var products []CampaignProduct
+	if err := db.Where("campaign_id IN ?", ids).Order("campaign_id, position, product_id").Find(&products).Error; err != nil {
+		return err
+	}
+	for _, p := range products {
+		c := &campaigns[index[p.CampaignID]]
+		c.ProductIDs = append(c.ProductIDs, p.ProductID)
+	}
+	return nil
+}
+
+// ReplaceCampaignItems sets the banners and products of campaign id to the
+// ids given, in that order. A nil list leaves that side as it is.
+func ReplaceCampaignItems(tx *gorm.DB, id uint, bannerIDs, productIDs []uint) error {
+	if bannerIDs != nil {
+		if err := tx.Where("campaign_id = ?", id).Delete(&CampaignBanner{}).Error; err != nil {
+			return err
+		}
+		rows := make([]CampaignBanner, len(bannerIDs))
+		for i, b := range bannerIDs {
+			rows[i] = CampaignBanner{CampaignID: id, BannerID: b, Position: i}
+		}
+		if len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if productIDs != nil {
+		if err := tx.Where("campaign_id = ?", id).Delete(&CampaignProduct{}).Error; err != nil {
+			return err
+		}
+		rows := make([]CampaignProduct, len(productIDs))
+		for i, p := range productIDs {
+			rows[i] = CampaignProduct{CampaignID: id, ProductID: p, Position: i}
+		}
+		if len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -2,17 +2,26 @@ package models
 
 import "time"
 
+// Profit types understood by the payment webhook and the daily returns cron.
+const (
+	ProfitTypeLocked   = "locked"
+	ProfitTypeUnlocked = "unlocked"
+)
+
+// DefaultCategorySortPriority is used for new categories; lower values are listed first.
+const DefaultCategorySortPriority = 10
+
 type Category struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"size:100;not null" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
-	ProfitType  string    `gorm:"type:enum('locked','unlocked');default:'unlocked'" json:"profit_type"`
-	Status      string    `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"size:100;not null" json:"name"`
+	Description  string    `gorm:"type:text" json:"description"`
+	ProfitType   string    `gorm:"type:enum('locked','unlocked');default:'unlocked'" json:"profit_type"`
+	Status       string    `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
+	SortPriority int       `gorm:"column:sort_priority;not null;default:10;index" json:"sort_priority"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 func (Category) TableName() string {
 	return "categories"
 }
-
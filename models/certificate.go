@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CertificatePrefix starts every investment certificate number,
+// e.g. XINC-2026-000042.
+const CertificatePrefix = "XINC"
+
+// CertificateSequence is the last certificate number issued in a year.
+type CertificateSequence struct {
+	Year   int   `gorm:"primaryKey;autoIncrement:false" json:"year"`
+	LastNo int64 `gorm:"not null;default:0" json:"last_no"`
+}
+
+func (CertificateSequence) TableName() string {
+	return "certificate_sequences"
+}
+
+// NextCertificateNo issues the next certificate number of year. It must run
+// inside the transaction that stores the number: the year's row stays locked
+// until it commits, so concurrent activations never share or skip a number.
+func NextCertificateNo(tx *gorm.DB, year int) (string, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&CertificateSequence{Year: year}).Error; err != nil {
+		return "", err
+	}
+	var seq CertificateSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("year = ?", year).First(&seq).Error; err != nil {
+		return "", err
+	}
+	seq.LastNo++
+	if err := tx.Model(&seq).Update("last_no", seq.LastNo).Error; err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%06d", CertificatePrefix, year, seq.LastNo), nil
+}
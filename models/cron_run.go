@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// CronRun records one run of a cron that reports what it did, such as the
+// rows the retention cron deleted per table.
+type CronRun struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"type:varchar(64);not null;index:idx_cron_runs_name_started,priority:1" json:"name"`
+	// Partial runs stopped early (time budget, shutdown) and resume next time
+	Status string `gorm:"type:enum('Success','Partial','Failed');not null" json:"status"`
+	// Result is the run's JSON report
+	Result     *string   `gorm:"type:text" json:"result"`
+	Error      *string   `gorm:"type:text" json:"error"`
+	StartedAt  time.Time `gorm:"not null;index:idx_cron_runs_name_started,priority:2" json:"started_at"`
+	FinishedAt time.Time `gorm:"not null" json:"finished_at"`
+}
+
+func (CronRun) TableName() string {
+	return "cron_runs"
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// DailyReport is the finance snapshot for one Asia/Jakarta calendar day.
+// Amounts are sums of Success transactions settled on that day; the cron
+// upserts on ReportDate so re-running a day replaces its row.
+type DailyReport struct {
+	ID                 uint   `gorm:"primaryKey" json:"id"`
+	ReportDate         string `gorm:"type:char(10);not null;uniqueIndex" json:"report_date"`
+	TotalDeposits      int64  `gorm:"type:bigint;not null;default:0" json:"total_deposits"`
+	ProfitPaid         int64  `gorm:"type:bigint;not null;default:0" json:"profit_paid"`
+	CapitalReturned    int64  `gorm:"type:bigint;not null;default:0" json:"capital_returned"`
+	ReferralBonuses    int64  `gorm:"type:bigint;not null;default:0" json:"referral_bonuses"`
+	OtherBonuses       int64  `gorm:"type:bigint;not null;default:0" json:"other_bonuses"`
+	WithdrawalsSettled int64  `gorm:"type:bigint;not null;default:0" json:"withdrawals_settled"`
+	WithdrawalCharges  int64  `gorm:"type:bigint;not null;default:0" json:"withdrawal_charges"`
+	// RefundsToBalance are refunds credited to balances, RefundsPaidOut
+	// those sent to users' banks, cancelled investments' payouts included
+	RefundsToBalance int64     `gorm:"type:bigint;not null;default:0" json:"refunds_to_balance"`
+	RefundsPaidOut   int64     `gorm:"type:bigint;not null;default:0" json:"refunds_paid_out"`
+	TotalUserBalance int64     `gorm:"type:bigint;not null;default:0" json:"total_user_balance"`
+	BalanceDelta     int64     `gorm:"type:bigint;not null;default:0" json:"balance_delta"`
+	GeneratedAt      time.Time `gorm:"not null" json:"generated_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (DailyReport) TableName() string {
+	return "daily_reports"
+}
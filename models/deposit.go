@@ -2,22 +2,29 @@ package models
 
 import "time"
 
+// Deposit is a balance top-up paid through the payment gateway. Its OrderID
+// carries utils.DepositOrderPrefix so the shared gateway webhook can tell it
+// apart from an investment payment.
 type Deposit struct {
 	ID             uint       `gorm:"primaryKey" json:"id"`
-	UserID         uint       `gorm:"not null" json:"user_id"`
-	User           *User      `gorm:"foreignKey:UserID" json:"-"`
-	Amount         float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
-	OrderID        string     `gorm:"type:varchar(191);uniqueIndex;not null" json:"order_id"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	Amount         int64      `gorm:"type:bigint;not null" json:"amount"`
+	OrderID        string     `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
 	ReferenceID    *string    `gorm:"type:varchar(191)" json:"reference_id,omitempty"`
-	PaymentMethod  string     `gorm:"type:enum('QRIS','BANK');not null" json:"payment_method"`
-	PaymentChannel *string    `gorm:"type:enum('BCA','BRI','BNI','MANDIRI','PERMATA','BNC')" json:"payment_channel,omitempty"`
-	PaymentToken   *string    `gorm:"type:text" json:"-"`
+	PaymentMethod  string     `gorm:"type:varchar(16);not null" json:"payment_method"`
+	PaymentChannel *string    `gorm:"type:varchar(16)" json:"payment_channel,omitempty"`
 	PaymentCode    *string    `gorm:"type:text" json:"payment_code,omitempty"`
 	PaymentLink    *string    `gorm:"type:text" json:"payment_link,omitempty"`
-	Status         string     `gorm:"type:enum('Success','Pending','Failed');default:'Pending'" json:"status"`
-	ExpiredAt      time.Time  `gorm:"not null" json:"expired_at"`
-	CreatedAt      time.Time  `json:"-"`
-	UpdatedAt      time.Time  `json:"-"`
+	Status         string     `gorm:"type:enum('Success','Pending','Failed');not null;default:'Pending';index:idx_deposits_expiry_reminder,priority:1" json:"status"`
+	ExpiredAt      *time.Time `gorm:"index:idx_deposits_expiry_reminder,priority:2" json:"expired_at,omitempty"`
+	// ExpiryNotifiedAt is set once the payment-expiry reminder has been sent
+	ExpiryNotifiedAt *time.Time `gorm:"index:idx_deposits_expiry_reminder,priority:3" json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// GatewayPaymentID is KytaPay's id of the payment, from the create
+	// response or the webhook. ReferenceID stays the reference we sent.
+	GatewayPaymentID *string `gorm:"type:varchar(191);index" json:"gateway_payment_id,omitempty"`
 }
 
 func (Deposit) TableName() string {
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"project/money"
+)
+
+// DepositCampaign pays a bonus on gateway payments (wallet deposits and
+// investment purchases) of at least MinAmount made while the campaign runs.
+// The bonus is either BonusPercent of the payment or BonusFlat. Budget caps the
+// total bonus paid (0 means uncapped); BudgetUsed is only ever raised by a
+// conditional UPDATE so concurrent payments cannot overshoot it.
+type DepositCampaign struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"size:100;not null" json:"name"`
+	MinAmount        int64     `gorm:"type:bigint;not null;default:0" json:"min_amount"`
+	BonusPercent     float64   `gorm:"type:decimal(5,2);not null;default:0" json:"bonus_percent"`
+	BonusFlat        int64     `gorm:"type:bigint;not null;default:0" json:"bonus_flat"`
+	FirstDepositOnly bool      `gorm:"not null;default:false" json:"first_deposit_only"`
+	StartsAt         time.Time `gorm:"not null" json:"starts_at"`
+	EndsAt           time.Time `gorm:"not null" json:"ends_at"`
+	Budget           int64     `gorm:"type:bigint;not null;default:0" json:"budget"`
+	BudgetUsed       int64     `gorm:"type:bigint;not null;default:0" json:"budget_used"`
+	Status           string    `gorm:"type:enum('Active','Inactive');not null;default:'Active'" json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (DepositCampaign) TableName() string {
+	return "deposit_campaigns"
+}
+
+// BonusFor returns the bonus this campaign pays on a payment of amount.
+func (c *DepositCampaign) BonusFor(amount int64) int64 {
+	if c.BonusFlat > 0 {
+		return c.BonusFlat
+	}
+	return money.Percent(amount, c.BonusPercent)
+}
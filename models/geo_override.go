@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GeoOverride lets one user past geoblocking, for support cases such as a
+// customer travelling abroad. ExpiresAt nil keeps it until it is removed.
+type GeoOverride struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	Reason    string     `gorm:"size:255;not null" json:"reason"`
+	AdminID   int64      `gorm:"not null" json:"admin_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (GeoOverride) TableName() string {
+	return "geo_overrides"
+}
+
+// HasGeoOverride reports whether userID has an override in force at now.
+func HasGeoOverride(db *gorm.DB, userID uint, now time.Time) (bool, error) {
+	var n int64
+	err := db.Model(&GeoOverride{}).
+		Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&n).Error
+	return n > 0, err
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Grant batch targets.
+const (
+	GrantTargetAll      = "all"
+	GrantTargetVIP      = "vip"
+	GrantTargetUsers    = "users"
+	GrantTargetInvested = "invested"
+)
+
+// Grant batch types.
+const (
+	GrantTypeSpinTicket = "spin_ticket"
+	GrantTypeBalance    = "balance"
+)
+
+// Grant batch statuses. A Running batch stopped part way, by a crash or a
+// shutdown, is resumed by submitting its batch key again.
+const (
+	GrantBatchRunning   = "Running"
+	GrantBatchCompleted = "Completed"
+)
+
+// GrantBatch is an admin promo crediting spin tickets or balance to every
+// active user matching a target. Users are walked in id order up to
+// MaxUserID, fixed at creation so users signing up meanwhile are left out;
+// LastUserID is the cursor a resumed run continues from.
+type GrantBatch struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	BatchKey string `gorm:"type:varchar(64);not null;uniqueIndex" json:"batch_id"`
+	AdminID  uint   `gorm:"not null" json:"admin_id"`
+	Target   string `gorm:"type:enum('all','vip','users','invested');not null" json:"target"`
+	// MinLevel is the lowest VIP level of a vip target
+	MinLevel *uint `json:"min_level,omitempty"`
+	// UserIDs is the JSON id list of a users target
+	UserIDs *string `gorm:"type:text" json:"user_ids,omitempty"`
+	// InvestedFrom and InvestedTo bound the purchase dates of an invested
+	// target, to exclusive
+	InvestedFrom *time.Time `json:"invested_from,omitempty"`
+	InvestedTo   *time.Time `json:"invested_to,omitempty"`
+	GrantType    string     `gorm:"type:enum('spin_ticket','balance');not null" json:"grant_type"`
+	// Amount is tickets or rupiah per user
+	Amount      int64      `gorm:"type:bigint;not null" json:"amount"`
+	Message     string     `gorm:"size:255;not null" json:"message"`
+	MaxUserID   uint       `gorm:"not null" json:"max_user_id"`
+	LastUserID  uint       `gorm:"not null;default:0" json:"last_user_id"`
+	Granted     int        `gorm:"not null;default:0" json:"granted"`
+	Status      string     `gorm:"type:enum('Running','Completed');not null;default:'Running'" json:"status"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (GrantBatch) TableName() string {
+	return "grant_batches"
+}
+
+// GrantBatchItem is one user's credit from a batch. The unique (batch_id,
+// user_id) pair is written in the transaction that credits the user, so a
+// re-run can never credit anyone twice.
+type GrantBatchItem struct {
+	ID      uint  `gorm:"primaryKey" json:"id"`
+	BatchID uint  `gorm:"not null;uniqueIndex:idx_grant_batch_items_batch_user,priority:1" json:"batch_id"`
+	UserID  uint  `gorm:"not null;uniqueIndex:idx_grant_batch_items_batch_user,priority:2;index" json:"user_id"`
+	Amount  int64 `gorm:"type:bigint;not null" json:"amount"`
+	// OrderID is the bonus transaction of a balance grant
+	OrderID   *string   `gorm:"type:varchar(191)" json:"order_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (GrantBatchItem) TableName() string {
+	return "grant_batch_items"
+}
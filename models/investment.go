@@ -1,23 +1,44 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Investment struct {
 	ID            uint       `gorm:"primaryKey" json:"id"`
-	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	UserID        uint       `gorm:"not null;index;index:idx_investments_user_status,priority:1" json:"user_id"`
 	ProductID     uint       `gorm:"not null;index" json:"product_id"`
 	CategoryID    uint       `gorm:"not null;index" json:"category_id"`
-	Amount        float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
-	DailyProfit   float64    `gorm:"type:decimal(15,2);not null" json:"daily_profit"`
+	ProductName   string     `gorm:"size:100;not null;default:''" json:"product_name"`
+	Amount        int64      `gorm:"type:bigint;not null" json:"amount"`
+	DailyProfit   int64      `gorm:"type:bigint;not null" json:"daily_profit"`
 	Duration      int        `gorm:"not null" json:"duration"`
 	TotalPaid     int        `gorm:"not null;default:0" json:"total_paid"`
-	TotalReturned float64    `gorm:"type:decimal(15,2);not null;default:0.00" json:"total_returned"`
+	TotalReturned int64      `gorm:"type:bigint;not null;default:0" json:"total_returned"`
 	LastReturnAt  *time.Time `json:"last_return_at,omitempty"`
-	NextReturnAt  *time.Time `json:"next_return_at,omitempty"`
+	NextReturnAt  *time.Time `gorm:"index:idx_investments_status_next_return,priority:2" json:"next_return_at,omitempty"`
 	OrderID       string     `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
-	Status        string     `gorm:"type:enum('Pending','Running','Completed','Suspended','Cancelled');default:'Pending'" json:"status"`
+	Status        string     `gorm:"type:enum('Pending','Running','Completed','Suspended','Cancelled');default:'Pending';index:idx_investments_status_next_return,priority:1;index:idx_investments_user_status,priority:2" json:"status"`
+	CreatedBy     *int64     `gorm:"index" json:"created_by,omitempty"` // admins.id for investments registered manually
+	// CertificateNo is issued when the investment is confirmed; users and third
+	// parties can check it at /verify/{certificate_no}
+	CertificateNo *string    `gorm:"type:varchar(32);uniqueIndex" json:"certificate_no,omitempty"`
+	CertifiedAt   *time.Time `json:"certified_at,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
+	// RateAmount and RateDailyProfit snapshot the amount and daily profit
+	// bought, set by the first top-up; top-ups scale DailyProfit by this rate
+	// so later product edits do not change it
+	RateAmount      int64 `gorm:"type:bigint;not null;default:0" json:"-"`
+	RateDailyProfit int64 `gorm:"type:bigint;not null;default:0" json:"-"`
+	// TotalBoost is the extra profit earned from profit boosts, kept apart
+	// from TotalReturned; locked categories pay it out at completion
+	TotalBoost int64 `gorm:"type:bigint;not null;default:0" json:"total_boost"`
+	// DeletedAt marks an investment archived by the archive cron; default
+	// queries skip it, Unscoped() sees it
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	
 	// Relations
 	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// InvestmentRecap is the earnings summary written when an investment
+// completes. Its amounts are summed from the transactions written for the
+// investment, so editing the product later cannot change it.
+type InvestmentRecap struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	InvestmentID uint   `gorm:"not null;uniqueIndex" json:"investment_id"`
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	OrderID      string `gorm:"type:varchar(191);not null" json:"order_id"`
+	ProductName  string `gorm:"size:100;not null" json:"product_name"`
+	// Principal is the capital returned, top-ups included
+	Principal int64 `gorm:"type:bigint;not null" json:"principal"`
+	// Profit is the product's profit paid, Boost the profit boosts on top
+	Profit      int64 `gorm:"type:bigint;not null" json:"profit"`
+	Boost       int64 `gorm:"type:bigint;not null;default:0" json:"boost"`
+	TotalEarned int64 `gorm:"type:bigint;not null" json:"total_earned"`
+	// Duration is the number of daily returns the investment ran for
+	Duration int `gorm:"not null" json:"duration"`
+	// ROIPercent is TotalEarned as a percentage of Principal
+	ROIPercent  float64    `gorm:"type:decimal(9,2);not null" json:"roi_percent"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt time.Time  `gorm:"not null" json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (InvestmentRecap) TableName() string {
+	return "investment_recaps"
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// InvestmentTopup adds principal to a Running investment, paid from the
+// balance or through the payment gateway. Gateway top-ups carry
+// utils.TopupOrderPrefix in OrderID so the shared webhook can settle them.
+type InvestmentTopup struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	InvestmentID uint   `gorm:"not null;index" json:"investment_id"`
+	UserID       uint   `gorm:"not null;index" json:"user_id"`
+	Amount       int64  `gorm:"type:bigint;not null" json:"amount"`
+	Fee          int64  `gorm:"type:bigint;not null;default:0" json:"fee"`
+	OrderID      string `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
+	// PaymentMethod is BALANCE, QRIS or BANK
+	PaymentMethod  string     `gorm:"type:varchar(16);not null" json:"payment_method"`
+	PaymentChannel *string    `gorm:"type:varchar(16)" json:"payment_channel,omitempty"`
+	ReferenceID    *string    `gorm:"type:varchar(191)" json:"reference_id,omitempty"`
+	PaymentCode    *string    `gorm:"type:text" json:"payment_code,omitempty"`
+	PaymentLink    *string    `gorm:"type:text" json:"payment_link,omitempty"`
+	ExpiredAt      *time.Time `json:"expired_at,omitempty"`
+	// DailyProfitBefore and DailyProfitAfter record the rate change once the
+	// top-up is applied
+	DailyProfitBefore int64 `gorm:"type:bigint;not null;default:0" json:"daily_profit_before"`
+	DailyProfitAfter  int64 `gorm:"type:bigint;not null;default:0" json:"daily_profit_after"`
+	// Status is Failed when the payment failed, or when it arrived after the
+	// investment stopped running and was credited to the balance instead
+	Status    string    `gorm:"type:enum('Success','Pending','Failed');not null;default:'Pending';index" json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// GatewayPaymentID is KytaPay's id of the payment, from the create
+	// response or the webhook. ReferenceID stays the reference we sent.
+	GatewayPaymentID *string `gorm:"type:varchar(191);index" json:"gateway_payment_id,omitempty"`
+}
+
+func (InvestmentTopup) TableName() string {
+	return "investment_topups"
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Leaderboard scopes: which part of a referrer's downline counts as the team.
+const (
+	LeaderboardScopeLevel1 = "level1"
+	LeaderboardScopeTree   = "tree"
+)
+
+// leaderboardMaxDepth stops the upline walk on corrupt reff_by cycles.
+const leaderboardMaxDepth = 100
+
+// LeaderboardPeriod is one monthly competition, keyed "YYYY-MM" in the app
+// timezone. Snapshots can be rebuilt while it is Open; closing it freezes the
+// ranking and pays the prizes.
+type LeaderboardPeriod struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Period     string     `gorm:"type:char(7);not null;uniqueIndex" json:"period"`
+	Scope      string     `gorm:"type:enum('level1','tree');not null" json:"scope"`
+	Status     string     `gorm:"type:enum('Open','Closed');not null;default:'Open'" json:"status"`
+	SnapshotAt time.Time  `gorm:"not null" json:"snapshot_at"`
+	ClosedAt   *time.Time `json:"closed_at"`
+	ClosedBy   *int64     `json:"closed_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (LeaderboardPeriod) TableName() string {
+	return "leaderboard_periods"
+}
+
+// LeaderboardSnapshot is a referrer's team investment volume and rank in a
+// period. Prize is set when the period is closed.
+type LeaderboardSnapshot struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Period     string    `gorm:"type:char(7);not null;uniqueIndex:idx_leaderboard_snapshots_period_user,priority:1;index:idx_leaderboard_snapshots_period_rank,priority:1" json:"period"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_leaderboard_snapshots_period_user,priority:2" json:"user_id"`
+	TeamVolume int64     `gorm:"type:bigint;not null" json:"team_volume"`
+	Rank       int       `gorm:"not null;index:idx_leaderboard_snapshots_period_rank,priority:2" json:"rank"`
+	Prize      int64     `gorm:"type:bigint;not null;default:0" json:"prize"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (LeaderboardSnapshot) TableName() string {
+	return "leaderboard_snapshots"
+}
+
+// LeaderboardPeriodRange parses a "YYYY-MM" period into its [start, end) range
+// in loc.
+func LeaderboardPeriodRange(period string, loc *time.Location) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", period, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period %q: %w", period, err)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// BuildLeaderboardSnapshot ranks referrers by the Success investment
+// transactions their team settled in [start, end). With
+// LeaderboardScopeLevel1 only direct referrals count; LeaderboardScopeTree
+// credits every upline. The result is sorted by rank; nothing is written.
+func BuildLeaderboardSnapshot(db *gorm.DB, period string, start, end time.Time, scope string) ([]LeaderboardSnapshot, error) {
+	type memberVolume struct {
+		UserID uint
+		Amount int64
+	}
+	var volumes []memberVolume
+	if err := db.Model(&Transaction{}).
+		Select("user_id, SUM(amount) AS amount").
+		Where("transaction_type = ? AND status = ? AND updated_at >= ? AND updated_at < ?", "investment", "Success", start, end).
+		Group("user_id").
+		Scan(&volumes).Error; err != nil {
+		return nil, err
+	}
+
+	maxDepth := leaderboardMaxDepth
+	if scope == LeaderboardScopeLevel1 {
+		maxDepth = 1
+	}
+
+	// Walk each member's upline one level per query, crediting every referrer
+	// on the way
+	uplines := map[uint]*uint{}
+	team := map[uint]int64{}
+	current := make(map[uint]int64, len(volumes))
+	for _, v := range volumes {
+		current[v.UserID] += v.Amount
+	}
+	for depth := 0; depth < maxDepth && len(current) > 0; depth++ {
+		var missing []uint
+		for id := range current {
+			if _, ok := uplines[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			type upline struct {
+				ID     uint
+				ReffBy *uint
+			}
+			var rows []upline
+			if err := db.Model(&User{}).Select("id, reff_by").Where("id IN ?", missing).Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+			for _, id := range missing {
+				uplines[id] = nil
+			}
+			for _, u := range rows {
+				uplines[u.ID] = u.ReffBy
+			}
+		}
+		next := map[uint]int64{}
+		for id, amount := range current {
+			if ref := uplines[id]; ref != nil {
+				team[*ref] += amount
+				next[*ref] += amount
+			}
+		}
+		current = next
+	}
+
+	snapshot := make([]LeaderboardSnapshot, 0, len(team))
+	for uid, volume := range team {
+		if volume > 0 {
+			snapshot = append(snapshot, LeaderboardSnapshot{Period: period, UserID: uid, TeamVolume: volume})
+		}
+	}
+	// Ties go to the older account
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].TeamVolume != snapshot[j].TeamVolume {
+			return snapshot[i].TeamVolume > snapshot[j].TeamVolume
+		}
+		return snapshot[i].UserID < snapshot[j].UserID
+	})
+	for i := range snapshot {
+		snapshot[i].Rank = i + 1
+	}
+	return snapshot, nil
+}
+
+// SaveLeaderboardSnapshot replaces the stored ranking of period with snapshot
+// and stamps the period row, creating it as Open when missing. It must run
+// inside a transaction that holds the period row lock when the row exists.
+func SaveLeaderboardSnapshot(tx *gorm.DB, p *LeaderboardPeriod, snapshot []LeaderboardSnapshot) error {
+	if err := tx.Where("period = ?", p.Period).Delete(&LeaderboardSnapshot{}).Error; err != nil {
+		return err
+	}
+	if len(snapshot) > 0 {
+		if err := tx.CreateInBatches(snapshot, 500).Error; err != nil {
+			return err
+		}
+	}
+	p.SnapshotAt = time.Now()
+	return tx.Save(p).Error
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Mission types: the platform action that advances a mission by one.
+const (
+	// MissionInvestment counts the user's paid investments; a target of 1 is
+	// "make your first investment".
+	MissionInvestment = "investment"
+	// MissionInviteInvestor counts direct referrals making their first investment.
+	MissionInviteInvestor = "invite_investor"
+	// MissionKYC completes when the user's identity verification is approved.
+	MissionKYC = "kyc"
+)
+
+// MissionTypes lists the valid Mission.Type values.
+var MissionTypes = []string{MissionInvestment, MissionInviteInvestor, MissionKYC}
+
+// Mission is an admin-defined goal paying RewardAmount once the user performs
+// its Type action TargetCount times while the mission runs. RewardType
+// "balance" credits RewardAmount rupiah, "spin_ticket" adds RewardAmount
+// tickets. EndsAt nil means no end; after EndsAt the mission can no longer
+// progress or be claimed.
+type Mission struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"size:100;not null" json:"name"`
+	Description  string     `gorm:"type:text" json:"description"`
+	Type         string     `gorm:"type:enum('investment','invite_investor','kyc');not null;index:idx_missions_type_status,priority:1" json:"type"`
+	TargetCount  int64      `gorm:"type:bigint;not null;default:1" json:"target_count"`
+	RewardType   string     `gorm:"type:enum('balance','spin_ticket');not null" json:"reward_type"`
+	RewardAmount int64      `gorm:"type:bigint;not null" json:"reward_amount"`
+	StartsAt     time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Status       string     `gorm:"type:enum('Active','Inactive');not null;default:'Active';index:idx_missions_type_status,priority:2" json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (Mission) TableName() string {
+	return "missions"
+}
+
+// RunningAt reports whether the mission is active and inside its window at now.
+func (m *Mission) RunningAt(now time.Time) bool {
+	return m.Status == "Active" && !m.StartsAt.After(now) && (m.EndsAt == nil || m.EndsAt.After(now))
+}
+
+// UserMission is a user's progress on one mission. Progress stops at the
+// mission's target; CompletedAt is set when it gets there and ClaimedAt when
+// the reward is paid.
+type UserMission struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_user_missions_user_mission,priority:1" json:"user_id"`
+	MissionID   uint       `gorm:"not null;uniqueIndex:idx_user_missions_user_mission,priority:2" json:"mission_id"`
+	Progress    int64      `gorm:"type:bigint;not null;default:0" json:"progress"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ClaimedAt   *time.Time `json:"claimed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (UserMission) TableName() string {
+	return "user_missions"
+}
+
+// RecordMissionProgress advances userID's running missions of missionType by
+// n. Call it inside the transaction that performs the action, so progress is
+// counted exactly when the action commits.
+func RecordMissionProgress(tx *gorm.DB, userID uint, missionType string, n int64) error {
+	now := time.Now()
+	var missions []Mission
+	if err := tx.Where("type = ? AND status = 'Active' AND starts_at <= ?", missionType, now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Find(&missions).Error; err != nil {
+		return err
+	}
+	for _, m := range missions {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserMission{UserID: userID, MissionID: m.ID}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&UserMission{}).
+			Where("user_id = ? AND mission_id = ? AND completed_at IS NULL", userID, m.ID).
+			UpdateColumns(map[string]interface{}{
+				"progress":   gorm.Expr("LEAST(progress + ?, ?)", n, m.TargetCount),
+				"updated_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&UserMission{}).
+			Where("user_id = ? AND mission_id = ? AND completed_at IS NULL AND progress >= ?", userID, m.ID, m.TargetCount).
+			UpdateColumn("completed_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
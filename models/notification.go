@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Notification is a per-user inbox entry. Announcement notifications are
+// unique per (announcement, user) so batch delivery can be safely re-run.
+type Notification struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null;index:idx_notifications_user_read,priority:1;uniqueIndex:idx_notifications_announcement_user,priority:2" json:"user_id"`
+	AnnouncementID *uint      `gorm:"uniqueIndex:idx_notifications_announcement_user,priority:1" json:"announcement_id,omitempty"`
+	Type           string     `gorm:"type:varchar(32);not null" json:"type"`
+	Title          string     `gorm:"size:150;not null" json:"title"`
+	Body           string     `gorm:"type:text" json:"body"`
+	ReadAt         *time.Time `gorm:"index:idx_notifications_user_read,priority:2" json:"read_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Outbox event statuses.
+const (
+	OutboxPending = "Pending"
+	OutboxDone    = "Done"
+	// OutboxFailed is set once an event used up its attempts; an admin can
+	// put it back to Pending
+	OutboxFailed = "Failed"
+)
+
+// OutboxEvent is a side effect recorded in the same transaction as the state
+// change that causes it, and carried out afterwards by the outbox
+// dispatcher. Kind picks the handler; Kind and Key together are unique, so
+// recording the same effect twice keeps one event.
+type OutboxEvent struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Kind    string `gorm:"type:varchar(64);not null;uniqueIndex:idx_outbox_events_kind_key,priority:1" json:"kind"`
+	Key     string `gorm:"column:event_key;type:varchar(191);not null;uniqueIndex:idx_outbox_events_kind_key,priority:2" json:"key"`
+	Payload string `gorm:"type:text;not null" json:"payload"` // JSON, shaped by Kind
+	Status  string `gorm:"type:enum('Pending','Done','Failed');not null;default:'Pending';index:idx_outbox_events_due,priority:1" json:"status"`
+	// Attempts counts handler runs, the successful one included
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_events_due,priority:2" json:"next_attempt_at"`
+	LastError     *string    `gorm:"type:text" json:"last_error,omitempty"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -1,22 +1,104 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Payment struct {
-	ID             uint       `gorm:"primaryKey" json:"id"`
-	InvestmentID   uint       `gorm:"not null;index" json:"investment_id"`
-	ReferenceID    *string    `gorm:"type:varchar(191)" json:"reference_id,omitempty"`
-	OrderID        string     `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
-	PaymentMethod  *string    `gorm:"type:varchar(16)" json:"payment_method,omitempty"`
-	PaymentChannel *string    `gorm:"type:varchar(16)" json:"payment_channel,omitempty"`
-	PaymentCode    *string    `gorm:"type:text" json:"payment_code,omitempty"`
-	PaymentLink    *string    `gorm:"type:text" json:"payment_link,omitempty"`
-	Status         string     `gorm:"type:varchar(16);default:'Pending'" json:"status"`
-	ExpiredAt      *time.Time `json:"expired_at,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID             uint    `gorm:"primaryKey" json:"id"`
+	InvestmentID   uint    `gorm:"not null;index" json:"investment_id"`
+	ReferenceID    *string `gorm:"type:varchar(191)" json:"reference_id,omitempty"`
+	OrderID        string  `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
+	PaymentMethod  *string `gorm:"type:varchar(16);index:idx_payments_manual_review,priority:1" json:"payment_method,omitempty"`
+	PaymentChannel *string `gorm:"type:varchar(16)" json:"payment_channel,omitempty"`
+	PaymentCode    *string `gorm:"type:text" json:"payment_code,omitempty"`
+	PaymentLink    *string `gorm:"type:text" json:"payment_link,omitempty"`
+	// Amount is the product price and Fee the channel fee passed to the
+	// buyer; the gateway charges both. Both are 0 on payments made before
+	// fees were recorded.
+	Amount int64 `gorm:"type:bigint;not null;default:0" json:"amount"`
+	Fee    int64 `gorm:"type:bigint;not null;default:0" json:"fee"`
+	// AmountReceived is what the gateway reported paid. A payment short of
+	// its gross is Partial from PartialAt until the refund cron makes it
+	// Refunded.
+	AmountReceived int64      `gorm:"type:bigint;not null;default:0" json:"amount_received"`
+	PartialAt      *time.Time `gorm:"index" json:"partial_at,omitempty"`
+	Status         string     `gorm:"type:varchar(16);default:'Pending';index:idx_payments_expiry_reminder,priority:1" json:"status"`
+	ExpiredAt      *time.Time `gorm:"index:idx_payments_expiry_reminder,priority:2" json:"expired_at,omitempty"`
+	// ExpiryNotifiedAt is set once the payment-expiry reminder has been sent
+	ExpiryNotifiedAt *time.Time `gorm:"index:idx_payments_expiry_reminder,priority:3" json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	// DeletedAt is set when the investment is archived
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// A MANUAL payment is a bank transfer quoting PaymentCode. The buyer
+	// uploads ProofImage (an object key) and an admin approves or rejects it,
+	// which sets ReviewedBy and ReviewedAt.
+	ProofImage      *string    `gorm:"type:varchar(255)" json:"-"`
+	ProofUploadedAt *time.Time `gorm:"index:idx_payments_manual_review,priority:3" json:"proof_uploaded_at,omitempty"`
+	ReviewedBy      *uint      `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `gorm:"index:idx_payments_manual_review,priority:2" json:"reviewed_at,omitempty"`
+	ReviewNote      *string    `gorm:"type:varchar(255)" json:"review_note,omitempty"`
+
+	// GatewayPaymentID is KytaPay's id of the payment, from the create
+	// response or the webhook. ReferenceID stays the reference we sent.
+	GatewayPaymentID *string `gorm:"type:varchar(191);index" json:"gateway_payment_id,omitempty"`
 }
 
 func (Payment) TableName() string {
 	return "payments"
 }
+
+// Gross is what the gateway charges: Amount plus Fee, or fallback (the
+// investment amount) for payments made before fees were recorded.
+func (p Payment) Gross(fallback int64) int64 {
+	if p.Amount == 0 {
+		return fallback
+	}
+	return p.Amount + p.Fee
+}
+
+// staleTransactionBatchSize bounds the rows one FailExpiredInvestmentTransactions
+// update touches.
+const staleTransactionBatchSize = 500
+
+// ExpiredPendingOrders selects the order ids of payments still Pending after
+// expiring at or before now. Manual transfers with an uploaded proof are left
+// out while they await review.
+func ExpiredPendingOrders(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Model(&Payment{}).Select("order_id").
+		Where("status = ? AND expired_at <= ?", "Pending", now).
+		Where("proof_uploaded_at IS NULL")
+}
+
+// FailExpiredInvestmentTransactions marks Failed the Pending investment
+// transactions whose payment expired at or before now, in batches, and
+// returns how many it marked. The investment and payment stay Pending: a late
+// confirmation from the gateway still activates the investment and turns the
+// transaction Success.
+func FailExpiredInvestmentTransactions(db *gorm.DB, now time.Time) (int64, error) {
+	var failed int64
+	for {
+		var ids []uint
+		if err := db.Model(&Transaction{}).
+			Where("transaction_type = ? AND status = ? AND order_id IN (?)", "investment", "Pending", ExpiredPendingOrders(db, now)).
+			Order("id ASC").Limit(staleTransactionBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return failed, err
+		}
+		if len(ids) == 0 {
+			return failed, nil
+		}
+		res := db.Model(&Transaction{}).Where("id IN ? AND status = ?", ids, "Pending").Update("status", "Failed")
+		if res.Error != nil {
+			return failed, res.Error
+		}
+		failed += res.RowsAffected
+		if len(ids) < staleTransactionBatchSize {
+			return failed, nil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"project/money"
+
+	"gorm.io/gorm"
+)
+
+// PaymentChannel is the gateway fee of one payment method and channel: QRIS
+// uses the code "QRIS", virtual accounts the bank code. The fee is FeeFlat
+// rupiah plus FeePercent of the amount; with PassFee the buyer pays it on top
+// of the product price, otherwise the business absorbs it. A Disabled channel
+// is offered to no one, e.g. while the bank's virtual accounts are down.
+type PaymentChannel struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Method     string    `gorm:"type:enum('QRIS','BANK');not null;uniqueIndex:idx_payment_channels_method_code,priority:1" json:"method"`
+	Code       string    `gorm:"type:varchar(16);not null;uniqueIndex:idx_payment_channels_method_code,priority:2" json:"code"`
+	FeeFlat    int64     `gorm:"type:bigint;not null;default:0" json:"fee_flat"`
+	FeePercent float64   `gorm:"type:decimal(5,2);not null;default:0" json:"fee_percent"`
+	PassFee    bool      `gorm:"not null;default:false" json:"pass_fee"`
+	Disabled   bool      `gorm:"not null;default:false" json:"disabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (PaymentChannel) TableName() string {
+	return "payment_channels"
+}
+
+// BuyerFee is what the buyer pays on top of amount through this channel.
+func (c PaymentChannel) BuyerFee(amount int64) int64 {
+	if !c.PassFee {
+		return 0
+	}
+	return c.FeeFlat + money.Percent(amount, c.FeePercent)
+}
+
+// BuyerFee looks up the channel of method and code and returns its buyer fee
+// for amount. A channel without a row has no fee.
+func BuyerFee(db *gorm.DB, method, code string, amount int64) (int64, error) {
+	if method == "QRIS" {
+		code = "QRIS"
+	}
+	var c PaymentChannel
+	if err := db.Where("method = ? AND code = ?", method, code).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return c.BuyerFee(amount), nil
+}
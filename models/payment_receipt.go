@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// PaymentReceipt records a transfer a success callback reported for an
+// investment payment. A virtual account can be paid in several transfers,
+// each with its own callback, so AmountReceived on the payment is their sum;
+// one (payment_id, callback_key) is counted once, so a redelivered callback
+// cannot add its amount again. CallbackKey is the callback's callback_time,
+// or "amount:<amount>" when KytaPay sent none.
+type PaymentReceipt struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PaymentID   uint      `gorm:"not null;uniqueIndex:idx_payment_receipts_payment_callback,priority:1" json:"payment_id"`
+	CallbackKey string    `gorm:"type:varchar(191);not null;uniqueIndex:idx_payment_receipts_payment_callback,priority:2" json:"callback_key"`
+	Amount      int64     `gorm:"type:bigint;not null" json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (PaymentReceipt) TableName() string {
+	return "payment_receipts"
+}
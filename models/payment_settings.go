@@ -35,6 +35,31 @@ func (ps *PaymentSettings) IsUserInWishlist(userID uint) bool {
 	return false
 }
 
+// WishlistIDs parses the CSV wishlist into user IDs, skipping invalid entries.
+func (ps *PaymentSettings) WishlistIDs() []uint {
+	ids := []uint{}
+	if ps == nil {
+		return ids
+	}
+	for _, p := range strings.Split(ps.WishlistID, ",") {
+		v, err := strconv.ParseUint(strings.TrimSpace(p), 10, 64)
+		if err != nil || v == 0 {
+			continue
+		}
+		ids = append(ids, uint(v))
+	}
+	return ids
+}
+
+// SetWishlistIDs stores the given user IDs as CSV.
+func (ps *PaymentSettings) SetWishlistIDs(ids []uint) {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmtUint(id))
+	}
+	ps.WishlistID = strings.Join(parts, ",")
+}
+
 func fmtUint(v uint) string {
 	return strconv.FormatUint(uint64(v), 10)
 }
@@ -1,19 +1,46 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Product struct {
 	ID            uint      `gorm:"primaryKey" json:"id"`
 	CategoryID    uint      `gorm:"column:category_id;not null;index" json:"category_id"`
 	Name          string    `gorm:"column:name;size:100;not null" json:"name"`
-	Amount        float64   `gorm:"column:amount;type:decimal(15,2);not null" json:"amount"`
-	DailyProfit   float64   `gorm:"column:daily_profit;type:decimal(15,2);not null" json:"daily_profit"`
+	Amount        int64     `gorm:"column:amount;type:bigint;not null" json:"amount"`
+	DailyProfit   int64     `gorm:"column:daily_profit;type:bigint;not null" json:"daily_profit"`
 	Duration      int       `gorm:"column:duration;not null" json:"duration"`
 	RequiredVIP   int       `gorm:"column:required_vip;default:0" json:"required_vip"`
 	PurchaseLimit int       `gorm:"column:purchase_limit;default:0" json:"purchase_limit"` // 0 = unlimited
 	Status        string    `gorm:"column:status;type:enum('Active','Inactive');default:'Active'" json:"status"`
 	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at"`
+	// TopupMin and TopupMax bound one top-up of a Running investment; a zero
+	// TopupMax turns top-ups off
+	TopupMin int64 `gorm:"column:topup_min;type:bigint;not null;default:0" json:"topup_min"`
+	TopupMax int64 `gorm:"column:topup_max;type:bigint;not null;default:0" json:"topup_max"`
+	// PurchaseCooldownHours is how long after creating an investment in the
+	// product the user must wait to create another, paid or not; 0 = none
+	PurchaseCooldownHours int `gorm:"column:purchase_cooldown_hours;not null;default:0" json:"purchase_cooldown_hours"`
+	// Image is an object key in the upload bucket (or an absolute URL); the
+	// app loads ImageURL, which handlers resolve from it
+	Image       string            `gorm:"column:image;size:255;not null;default:''" json:"image"`
+	ImageURL    string            `gorm:"-" json:"image_url"`
+	Description *string           `gorm:"column:description;type:text" json:"description"`
+	Highlights  ProductHighlights `gorm:"column:highlights;type:text" json:"highlights"`
+	Badge       string            `gorm:"column:badge;size:32;not null;default:''" json:"badge"`
+	// Eligibility is the caller's standing for the product, set by the
+	// listing for signed-in users
+	Eligibility *ProductEligibility `gorm:"-" json:"eligibility,omitempty"`
+	// Boost sums the profit boosts running on the product now, set by the
+	// listing for the app's badge
+	Boost *ProductBoost `gorm:"-" json:"boost,omitempty"`
 	
 	// Relations
 	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
@@ -22,3 +49,86 @@ type Product struct {
 func (Product) TableName() string {
 	return "products"
 }
+
+// ProductEligibility tells a signed-in user whether they may buy a product
+// now. CoolingDown is set until NextPurchaseAt while its purchase cooldown
+// runs.
+type ProductEligibility struct {
+	CoolingDown    bool       `json:"cooling_down"`
+	NextPurchaseAt *time.Time `json:"next_purchase_at,omitempty"`
+}
+
+// NextPurchaseAt is when a user whose last investment in p was created at
+// last may create another, or nil when p has no cooldown.
+func (p *Product) NextPurchaseAt(last time.Time) *time.Time {
+	if p.PurchaseCooldownHours <= 0 || last.IsZero() {
+		return nil
+	}
+	next := last.Add(time.Duration(p.PurchaseCooldownHours) * time.Hour)
+	return &next
+}
+
+// LastPurchases returns when uid last created an investment, not cancelled,
+// in each of productIDs, archived ones included; products never bought are
+// left out. Creation time counts so unpaid attempts start the cooldown too.
+func LastPurchases(db *gorm.DB, uid uint, productIDs []uint) (map[uint]time.Time, error) {
+	last := map[uint]time.Time{}
+	if len(productIDs) == 0 {
+		return last, nil
+	}
+	var rows []struct {
+		ProductID uint
+		LastAt    time.Time
+	}
+	if err := db.Unscoped().Model(&Investment{}).
+		Select("product_id, MAX(created_at) AS last_at").
+		Where("user_id = ? AND product_id IN ? AND status <> ?", uid, productIDs, "Cancelled").
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		last[row.ProductID] = row.LastAt
+	}
+	return last, nil
+}
+
+// ProductHighlights are the bullet points shown under a product, stored as a
+// JSON array in a text column.
+type ProductHighlights []string
+
+func (h ProductHighlights) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal([]string(h))
+	return string(b), err
+}
+
+func (h *ProductHighlights) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("product highlights: unsupported column type")
+	}
+	if len(b) == 0 {
+		*h = nil
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(h))
+}
+
+// MarshalJSON writes no highlights as [], never null.
+func (h ProductHighlights) MarshalJSON() ([]byte, error) {
+	if h == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(h))
+}
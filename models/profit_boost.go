@@ -0,0 +1,94 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"project/money"
+
+	"gorm.io/gorm"
+)
+
+// ProfitBoost is a promotion paying ExtraPercent of the invested amount on
+// top of the daily profit, for payouts scheduled between StartsAt and EndsAt.
+// It covers either a whole category or a single product. The daily returns
+// cron checks each payout's own date, so a boost ending mid-term stops with
+// the first payout due after EndsAt, whenever the investment was bought.
+type ProfitBoost struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"size:100;not null" json:"name"`
+	CategoryID   *uint     `gorm:"index" json:"category_id"`
+	ProductID    *uint     `gorm:"index" json:"product_id"`
+	ExtraPercent float64   `gorm:"type:decimal(5,2);not null" json:"extra_percent"`
+	StartsAt     time.Time `gorm:"not null;index:idx_profit_boosts_status_window,priority:2" json:"starts_at"`
+	EndsAt       time.Time `gorm:"not null;index:idx_profit_boosts_status_window,priority:3" json:"ends_at"`
+	Status       string    `gorm:"type:enum('Active','Inactive');not null;default:'Active';index:idx_profit_boosts_status_window,priority:1" json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (ProfitBoost) TableName() string {
+	return "profit_boosts"
+}
+
+// ActiveAt reports whether the boost covers a payout scheduled at t.
+func (b *ProfitBoost) ActiveAt(t time.Time) bool {
+	return b.Status == "Active" && !t.Before(b.StartsAt) && t.Before(b.EndsAt)
+}
+
+// Covers reports whether the boost applies to the product.
+func (b *ProfitBoost) Covers(productID, categoryID uint) bool {
+	if b.ProductID != nil {
+		return *b.ProductID == productID
+	}
+	return b.CategoryID != nil && *b.CategoryID == categoryID
+}
+
+// ProfitBoostsBetween returns the active boosts running at some point in
+// [from, to].
+func ProfitBoostsBetween(db *gorm.DB, from, to time.Time) ([]ProfitBoost, error) {
+	var boosts []ProfitBoost
+	err := db.Where("status = ? AND starts_at <= ? AND ends_at > ?", "Active", to, from).Order("id ASC").Find(&boosts).Error
+	return boosts, err
+}
+
+// ProductBoost is what the product listing shows of the boosts running on a
+// product: their names, the summed extra percent and the earliest end.
+type ProductBoost struct {
+	Name         string    `json:"name"`
+	ExtraPercent float64   `json:"extra_percent"`
+	EndsAt       time.Time `json:"ends_at"`
+}
+
+// BoostFor sums the boosts covering the product at t; nil when none does.
+// Boosts stack.
+func BoostFor(boosts []ProfitBoost, productID, categoryID uint, t time.Time) *ProductBoost {
+	var out *ProductBoost
+	var names []string
+	for i := range boosts {
+		b := &boosts[i]
+		if !b.ActiveAt(t) || !b.Covers(productID, categoryID) {
+			continue
+		}
+		if out == nil {
+			out = &ProductBoost{EndsAt: b.EndsAt}
+		}
+		out.ExtraPercent += b.ExtraPercent
+		if b.EndsAt.Before(out.EndsAt) {
+			out.EndsAt = b.EndsAt
+		}
+		names = append(names, b.Name)
+	}
+	if out != nil {
+		out.Name = strings.Join(names, ", ")
+	}
+	return out
+}
+
+// Amount is the boost paid on one payout of an investment of amount.
+func (b *ProductBoost) Amount(amount int64) int64 {
+	if b == nil {
+		return 0
+	}
+	return money.Percent(amount, b.ExtraPercent)
+}
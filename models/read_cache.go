@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"project/cache"
+
+	"gorm.io/gorm"
+)
+
+// Shared data the app reads on every open, cached for READ_CACHE_TTL. The
+// admin handlers that write the underlying rows call the matching Invalidate
+// function. Per-user data, such as purchase eligibility or the banners a
+// level sees, is worked out on top of these on each request.
+var (
+	catalogCache  = cache.New[ProductCatalog]("product_catalog", cache.EnvTTL)
+	bannerCache   = cache.New[[]Banner]("banners", cache.EnvTTL)
+	vipLevelCache = cache.New[map[uint]VIPLevel]("vip_levels", cache.EnvTTL)
+)
+
+// ProductCatalog is what the product listing shows to everyone: the Active
+// categories in display order, their Active products with Category loaded,
+// and the boosts running at some point while the copy is cached.
+type ProductCatalog struct {
+	Categories []Category
+	Products   []Product
+	Boosts     []ProfitBoost
+}
+
+// CachedProductCatalog returns the shared catalog. Callers copy a Product
+// before setting its per-request fields.
+func CachedProductCatalog(db *gorm.DB) (ProductCatalog, cache.Outcome, error) {
+	return catalogCache.Load(db.Statement.Context, "", func() (ProductCatalog, error) {
+		var c ProductCatalog
+		if err := db.Where("status = ?", "Active").Order("sort_priority ASC, id ASC").Find(&c.Categories).Error; err != nil {
+			return c, err
+		}
+		if err := db.Preload("Category").Where("status = ?", "Active").Order("category_id ASC, id ASC").Find(&c.Products).Error; err != nil {
+			return c, err
+		}
+		// BoostFor checks each boost's window at request time, so one
+		// starting before the copy expires shows on time
+		now := time.Now()
+		var err error
+		c.Boosts, err = ProfitBoostsBetween(db, now, now.Add(cache.TTL()))
+		return c, err
+	})
+}
+
+// InvalidateProductCatalog drops the cached catalog after a product,
+// category or profit boost changes.
+func InvalidateProductCatalog() {
+	catalogCache.Invalidate()
+}
+
+// CachedBanners returns the Active banners with an image that have not
+// ended, in carousel order. Callers filter them with VisibleTo.
+func CachedBanners(db *gorm.DB) ([]Banner, cache.Outcome, error) {
+	return bannerCache.Load(db.Statement.Context, "", func() ([]Banner, error) {
+		var banners []Banner
+		err := db.
+			Where("status = ? AND image <> ''", "Active").
+			Where("ends_at IS NULL OR ends_at > ?", time.Now()).
+			Order("sort_order ASC, starts_at DESC, id ASC").
+			Find(&banners).Error
+		return banners, err
+	})
+}
+
+// InvalidateBanners drops the cached banners after one changes.
+func InvalidateBanners() {
+	bannerCache.Invalidate()
+}
+
+// vipLadder returns the vip_levels rows by level.
+func vipLadder(db *gorm.DB) (map[uint]VIPLevel, error) {
+	ladder, _, err := vipLevelCache.Load(db.Statement.Context, "", func() (map[uint]VIPLevel, error) {
+		var levels []VIPLevel
+		if err := db.Find(&levels).Error; err != nil {
+			return nil, err
+		}
+		ladder := make(map[uint]VIPLevel, len(levels))
+		for _, l := range levels {
+			ladder[l.Level] = l
+		}
+		return ladder, nil
+	})
+	return ladder, err
+}
+
+// InvalidateVIPLevels drops the cached ladder after a level's caps change.
+func InvalidateVIPLevels() {
+	vipLevelCache.Invalidate()
+}
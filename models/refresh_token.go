@@ -7,7 +7,7 @@ import (
 )
 
 type RefreshToken struct {
-	ID        string    `gorm:"primaryKey;type:char(36)" json:"id"`
+	ID        string    `gorm:"primaryKey;type:varchar(64)" json:"id"`
 	UserID    uint      `gorm:"index" json:"user_id"`
 	ExpiresAt time.Time `json:"expires_at"`
 	Revoked   bool      `json:"revoked"`
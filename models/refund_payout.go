@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefundPayout is the principal of a cancelled investment paid back to the
+// user's bank account through KytaPay. It is Pending from the moment the
+// payout is sent until the payout callback reports it Success or Failed; a
+// failed one is credited to the balance instead. OrderID is the payout's
+// reference and the order id of its refund transaction.
+type RefundPayout struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	InvestmentID  uint   `gorm:"not null;uniqueIndex" json:"investment_id"`
+	UserID        uint   `gorm:"not null;index" json:"user_id"`
+	BankAccountID uint   `gorm:"not null" json:"bank_account_id"`
+	Amount        int64  `gorm:"type:bigint;not null" json:"amount"`
+	OrderID       string `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
+	Status        string `gorm:"type:enum('Pending','Success','Failed');not null;default:'Pending';index" json:"status"`
+	// GatewayPayoutID is KytaPay's id of the payout
+	GatewayPayoutID *string `gorm:"type:varchar(191)" json:"gateway_payout_id,omitempty"`
+	// FailureReason is the gateway's message when the payout failed
+	FailureReason *string   `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+	AdminID       int64     `gorm:"not null" json:"admin_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (RefundPayout) TableName() string {
+	return "refund_payouts"
+}
+
+// VerifiedBankAccount returns the account of userID a refund can be paid out
+// to, or nil when there is none. An account is verified once a withdrawal to
+// it settled or its holder's name matched the bank's inquiry. With accountID
+// set only that account is considered; otherwise the one most recently
+// withdrawn to is the default.
+func VerifiedBankAccount(db *gorm.DB, userID, accountID uint) (*BankAccount, error) {
+	query := db.Preload("Bank").
+		Where("bank_accounts.user_id = ?", userID).
+		Where("EXISTS (SELECT 1 FROM withdrawals w WHERE w.bank_account_id = bank_accounts.id AND w.user_id = bank_accounts.user_id AND (w.status = ? OR w.inquiry_status = ?))", "Success", InquiryMatched)
+	if accountID != 0 {
+		query = query.Where("bank_accounts.id = ?", accountID)
+	} else {
+		query = query.Order("(SELECT MAX(w.id) FROM withdrawals w WHERE w.bank_account_id = bank_accounts.id) DESC")
+	}
+	var accounts []BankAccount
+	if err := query.Limit(1).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	return &accounts[0], nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import "gorm.io/gorm"
+
+// users.token_version is stamped into every access token as the "tv" claim;
+// tokens carrying an older version are rejected. It is read and written only
+// through these helpers and deliberately kept out of User, so a Save of a
+// stale User can never roll it back.
+
+// TokenVersion returns the current token version of userID (0 when the user
+// does not exist).
+func TokenVersion(db *gorm.DB, userID uint) (int, error) {
+	var version int
+	err := db.Table("users").Select("token_version").Where("id = ?", userID).Scan(&version).Error
+	return version, err
+}
+
+// RevokeSessions bumps the token version of userID and revokes all of its
+// refresh tokens, signing the user out on every device. Callers should also
+// drop the middleware's cached version once db commits.
+func RevokeSessions(db *gorm.DB, userID uint) error {
+	if err := db.Table("users").Where("id = ?", userID).UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+	return db.Table("refresh_tokens").Where("user_id = ? AND revoked = ?", userID, false).Update("revoked", true).Error
+}
@@ -1,26 +1,90 @@
 package models
 
-import "database/sql"
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"project/cache"
+
+	"gorm.io/gorm"
+)
 
 type Setting struct {
-	ID             int     `json:"id"`
-	Name           string  `json:"name"`
-	Company        string  `json:"company"`
-	Logo           string  `json:"logo"`
-	MinWithdraw    float64 `json:"min_withdraw"`
-	MaxWithdraw    float64 `json:"max_withdraw"`
-	WithdrawCharge float64 `json:"withdraw_charge"`
-	AutoWithdraw   bool    `json:"auto_withdraw"`
-	Maintenance    bool    `json:"maintenance"`
-	ClosedRegister bool    `json:"closed_register"`
-	LinkCS         string  `json:"link_cs"`
-	LinkGroup      string  `json:"link_group"`
-	LinkApp        string  `json:"link_app"`
+	ID                   int     `json:"id"`
+	Name                 string  `json:"name"`
+	Company              string  `json:"company"`
+	Logo                 string  `json:"logo"`
+	MinWithdraw          float64 `json:"min_withdraw"`
+	MaxWithdraw          float64 `json:"max_withdraw"`
+	MinDeposit           float64 `gorm:"default:10000" json:"min_deposit"`
+	MaxDeposit           float64 `gorm:"default:10000000" json:"max_deposit"`
+	WithdrawCharge       float64 `json:"withdraw_charge"`
+	WithdrawStartHour    int     `gorm:"default:9" json:"withdraw_start_hour"`
+	WithdrawEndHour      int     `gorm:"default:17" json:"withdraw_end_hour"`
+	ReferralBonusPercent float64 `gorm:"type:decimal(5,2);default:30" json:"referral_bonus_percent"`
+	AutoWithdraw         bool    `json:"auto_withdraw"`
+	Maintenance          bool    `json:"maintenance"`
+	// Express withdrawals pay ExpressWithdrawCharge percent on top of
+	// WithdrawCharge to skip the processing window
+	ExpressWithdraw       bool    `gorm:"default:false" json:"express_withdraw"`
+	ExpressWithdrawCharge float64 `gorm:"type:decimal(5,2);default:0" json:"express_withdraw_charge"`
+	// A withdrawal whose account holder, as the gateway's account inquiry
+	// names them, scores below this percent against the user's name is put
+	// On Hold; 0 turns the check off
+	WithdrawNameMatchMin int `gorm:"default:80" json:"withdraw_name_match_min"`
+	// ReferralBonusPercent applies to a downline's first purchase and this to
+	// later ones; ReferralBonusCap bounds what one downline earns its
+	// referrer in total, 0 meaning no cap
+	ReferralRepeatPercent float64 `gorm:"type:decimal(5,2);default:30" json:"referral_repeat_percent"`
+	ReferralBonusCap      int64   `gorm:"default:0" json:"referral_bonus_cap"`
+	// A direct referral's purchase of at least SpinTicketMinAmount earns the
+	// referrer SpinTicketsPerPurchase tickets, up to SpinTicketDailyCap a day
+	// (0 meaning no cap)
+	SpinTicketMinAmount    int64 `gorm:"default:100000" json:"spin_ticket_min_amount"`
+	SpinTicketsPerPurchase uint  `gorm:"default:1" json:"spin_tickets_per_purchase"`
+	SpinTicketDailyCap     uint  `gorm:"default:0" json:"spin_ticket_daily_cap"`
+	// Per-feature maintenance flags; the global Maintenance flag implies both
+	MaintenanceInvestment bool       `gorm:"default:false" json:"maintenance_investment"`
+	MaintenanceWithdrawal bool       `gorm:"default:false" json:"maintenance_withdrawal"`
+	MaintenanceMessage    string     `gorm:"size:255" json:"maintenance_message"`
+	MaintenanceUntil      *time.Time `json:"maintenance_until"`
+	ClosedRegister        bool       `json:"closed_register"`
+	LinkCS                string     `json:"link_cs"`
+	LinkGroup             string     `json:"link_group"`
+	LinkApp               string     `json:"link_app"`
+
+	// ManualPayment offers the MANUAL purchase method, a bank transfer to this
+	// account reviewed by an admin, for when the gateway is down
+	ManualPayment       bool   `gorm:"default:false" json:"manual_payment"`
+	ManualBankName      string `gorm:"size:100" json:"manual_bank_name"`
+	ManualAccountNumber string `gorm:"size:50" json:"manual_account_number"`
+	ManualAccountName   string `gorm:"size:100" json:"manual_account_name"`
+
+	// Countries, as comma-separated ISO codes, whose requests may register,
+	// buy and withdraw. A non-empty allow list admits only its countries;
+	// the deny list refuses its own
+	GeoAllowCountries string `gorm:"size:1000;not null;default:''" json:"geo_allow_countries"`
+	GeoDenyCountries  string `gorm:"size:1000;not null;default:''" json:"geo_deny_countries"`
+
+	// Per-minute limits of each signed-in user on the expensive route
+	// classes; 0 turns a class's limit off
+	RateLimitPurchase int `gorm:"default:5" json:"rate_limit_purchase"`
+	RateLimitExport   int `gorm:"default:1" json:"rate_limit_export"`
+	RateLimitRead     int `gorm:"default:30" json:"rate_limit_read"`
+
+	// Days the retention cron keeps rows of each log-like table; 0 keeps
+	// them forever. Money records are never purged whatever is set here
+	RetentionNotificationsDays     int `gorm:"default:90" json:"retention_notifications_days"`
+	RetentionUserSignalsDays       int `gorm:"default:180" json:"retention_user_signals_days"`
+	RetentionWebhookDeliveriesDays int `gorm:"default:30" json:"retention_webhook_deliveries_days"`
+	RetentionOutboxEventsDays      int `gorm:"default:30" json:"retention_outbox_events_days"`
+	RetentionCronRunsDays          int `gorm:"default:90" json:"retention_cron_runs_days"`
 }
 
 func GetSetting(db *sql.DB) (*Setting, error) {
 	setting := &Setting{}
-	row := db.QueryRow("SELECT id, name, company, logo, min_withdraw, max_withdraw, withdraw_charge, auto_withdraw, maintenance, closed_register, link_cs, link_group, link_app FROM settings LIMIT 1")
+	row := db.QueryRow("SELECT id, name, company, logo, min_withdraw, max_withdraw, withdraw_charge, withdraw_start_hour, withdraw_end_hour, referral_bonus_percent, auto_withdraw, maintenance, maintenance_investment, maintenance_withdrawal, maintenance_message, closed_register, link_cs, link_group, link_app FROM settings LIMIT 1")
 	err := row.Scan(
 		&setting.ID,
 		&setting.Name,
@@ -29,8 +93,14 @@ func GetSetting(db *sql.DB) (*Setting, error) {
 		&setting.MinWithdraw,
 		&setting.MaxWithdraw,
 		&setting.WithdrawCharge,
+		&setting.WithdrawStartHour,
+		&setting.WithdrawEndHour,
+		&setting.ReferralBonusPercent,
 		&setting.AutoWithdraw,
 		&setting.Maintenance,
+		&setting.MaintenanceInvestment,
+		&setting.MaintenanceWithdrawal,
+		&setting.MaintenanceMessage,
 		&setting.ClosedRegister,
 		&setting.LinkCS,
 		&setting.LinkGroup,
@@ -41,3 +111,98 @@ func GetSetting(db *sql.DB) (*Setting, error) {
 	}
 	return setting, nil
 }
+
+// settingCacheTTL bounds how stale another instance's copy can be after a write.
+const settingCacheTTL = 30 * time.Second
+
+var settingCache = cache.New[Setting]("settings", settingCacheTTL)
+
+// Features that can be put into maintenance individually.
+const (
+	FeatureInvestment = "investment"
+	FeatureWithdrawal = "withdrawal"
+)
+
+// InMaintenance reports whether the given feature is frozen. An empty feature
+// only checks the global flag.
+func (s *Setting) InMaintenance(feature string) bool {
+	if s.Maintenance {
+		return true
+	}
+	switch feature {
+	case FeatureInvestment:
+		return s.MaintenanceInvestment
+	case FeatureWithdrawal:
+		return s.MaintenanceWithdrawal
+	}
+	return false
+}
+
+// ManualPaymentAvailable reports whether MANUAL purchases are switched on
+// with a receiving account to show.
+func (s *Setting) ManualPaymentAvailable() bool {
+	return s.ManualPayment && s.ManualBankName != "" && s.ManualAccountNumber != "" && s.ManualAccountName != ""
+}
+
+// GeoBlocked reports whether requests from country are refused. An unknown
+// country, such as a private address or one the database lacks, is refused
+// while an allow list is set, since it cannot be shown to be on it, and
+// passes a deny list alone.
+func (s *Setting) GeoBlocked(country string) bool {
+	if s.GeoAllowCountries != "" && (country == "" || !countryListed(s.GeoAllowCountries, country)) {
+		return true
+	}
+	return country != "" && countryListed(s.GeoDenyCountries, country)
+}
+
+// countryListed reports whether the comma-separated list holds country.
+func countryListed(list, country string) bool {
+	for _, c := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(c), country) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithdrawalWindow reports whether regular withdrawals are taken at now:
+// from WithdrawStartHour to WithdrawEndHour in loc, Monday to Saturday.
+// change is when the window closes while open, or next opens while shut.
+// Express withdrawals ignore it.
+func (s *Setting) WithdrawalWindow(now time.Time, loc *time.Location) (open bool, change time.Time) {
+	local := now.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	start := day.Add(time.Duration(s.WithdrawStartHour) * time.Hour)
+	end := day.Add(time.Duration(s.WithdrawEndHour) * time.Hour)
+	if local.Weekday() != time.Sunday && !local.Before(start) && local.Before(end) {
+		return true, end
+	}
+	if local.Weekday() != time.Sunday && local.Before(start) {
+		return false, start
+	}
+	for {
+		day = day.AddDate(0, 0, 1)
+		if day.Weekday() != time.Sunday {
+			return false, day.Add(time.Duration(s.WithdrawStartHour) * time.Hour)
+		}
+	}
+}
+
+// GetCachedSetting returns a copy of the settings row, reloading it when the
+// cache is empty, invalidated, or older than settingCacheTTL.
+func GetCachedSetting(db *gorm.DB) (Setting, error) {
+	s, _, err := settingCache.Load(db.Statement.Context, "", func() (Setting, error) {
+		var s Setting
+		err := db.First(&s).Error
+		return s, err
+	})
+	if err != nil {
+		return Setting{}, err
+	}
+	return s, nil
+}
+
+// InvalidateSettingCache drops the cached settings so the next read hits the database.
+func InvalidateSettingCache() {
+	settingCache.Invalidate()
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Settlement file kinds: gateway payments received, payouts sent to banks and
+// profit credited to balances.
+const (
+	SettlementPayments = "payments"
+	SettlementPayouts  = "payouts"
+	SettlementProfits  = "profits"
+)
+
+// SettlementKinds lists the files exported for every day, in order.
+var SettlementKinds = []string{SettlementPayments, SettlementPayouts, SettlementProfits}
+
+// SettlementExport is one settlement file of one app-timezone day, uploaded
+// to object storage under ObjectKey. Re-exporting a day overwrites both the
+// object and this row; the same transactions give the same SHA256.
+type SettlementExport struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ExportDate string `gorm:"type:char(10);not null;uniqueIndex:idx_settlement_exports_date_kind,priority:1" json:"export_date"`
+	Kind       string `gorm:"type:enum('payments','payouts','profits');not null;uniqueIndex:idx_settlement_exports_date_kind,priority:2" json:"kind"`
+	ObjectKey  string `gorm:"type:varchar(255);not null" json:"object_key"`
+	// RowCount and TotalAmount are the file's transactions and their amounts
+	RowCount    int       `gorm:"not null" json:"row_count"`
+	TotalAmount int64     `gorm:"type:bigint;not null" json:"total_amount"`
+	Size        int64     `gorm:"type:bigint;not null" json:"size"`
+	SHA256      string    `gorm:"column:sha256;type:char(64);not null" json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+	// UpdatedAt is when the file was last written
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (SettlementExport) TableName() string {
+	return "settlement_exports"
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Outcomes of an SFXCR withdrawal callback.
+const (
+	// SFXCRSettled: the withdrawal went from Pending to Success
+	SFXCRSettled = "settled"
+	// SFXCRRefunded: the payout failed for good; the withdrawal is Failed and
+	// its amount is back in the balance
+	SFXCRRefunded = "refunded"
+	// SFXCRAcknowledged: a status that is not final; nothing changed
+	SFXCRAcknowledged = "acknowledged"
+	// SFXCRUnchanged: the withdrawal already had the reported status
+	SFXCRUnchanged = "unchanged"
+)
+
+// SFXCRCallback records a StoneForm withdrawal callback that was applied.
+// One (order_id, callback_id) is processed once, so a retried callback cannot
+// settle or refund again. CallbackID is "status:<status>" when StoneForm sent
+// none.
+type SFXCRCallback struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	OrderID    string    `gorm:"type:varchar(191);not null;uniqueIndex:idx_sfxcr_callbacks_order_callback,priority:1" json:"order_id"`
+	CallbackID string    `gorm:"type:varchar(191);not null;uniqueIndex:idx_sfxcr_callbacks_order_callback,priority:2" json:"callback_id"`
+	Status     string    `gorm:"type:varchar(32);not null" json:"status"` // as StoneForm sent it
+	Outcome    string    `gorm:"type:varchar(32);not null" json:"outcome"`
+	RemoteIP   string    `gorm:"type:varchar(64);not null;default:''" json:"remote_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (SFXCRCallback) TableName() string {
+	return "sfxcr_callbacks"
+}
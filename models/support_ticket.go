@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Ticket categories a user can pick when opening a support ticket.
+var TicketCategories = []string{"account", "deposit", "investment", "withdrawal", "other"}
+
+// SupportTicket is a user's support conversation. Status is Open while the
+// ticket waits for support, Answered after a support reply and Closed once
+// resolved; a user reply moves an Answered ticket back to Open.
+type SupportTicket struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index:idx_support_tickets_user_updated,priority:1" json:"user_id"`
+	Category      string     `gorm:"type:enum('account','deposit','investment','withdrawal','other');not null" json:"category"`
+	Subject       string     `gorm:"size:150;not null" json:"subject"`
+	Status        string     `gorm:"type:enum('Open','Answered','Closed');not null;default:'Open';index:idx_support_tickets_status_last,priority:1" json:"status"`
+	AssignedTo    *int64     `gorm:"index" json:"assigned_to"`
+	LastMessageAt time.Time  `gorm:"not null;index:idx_support_tickets_status_last,priority:2" json:"last_message_at"`
+	ClosedAt      *time.Time `json:"closed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"index:idx_support_tickets_user_updated,priority:2" json:"updated_at"`
+}
+
+func (SupportTicket) TableName() string {
+	return "support_tickets"
+}
+
+// TicketMessage is one message in a support ticket. SenderID is the user id
+// for "user" messages and the admin id for "admin" ones. Image is an object
+// key in the upload bucket.
+type TicketMessage struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TicketID   uint      `gorm:"not null;index" json:"ticket_id"`
+	SenderType string    `gorm:"type:enum('user','admin');not null" json:"sender_type"`
+	SenderID   int64     `gorm:"not null" json:"sender_id"`
+	Body       string    `gorm:"type:text;not null" json:"body"`
+	Image      *string   `gorm:"type:varchar(255)" json:"image"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (TicketMessage) TableName() string {
+	return "ticket_messages"
+}
+
+// CannedResponse is a reusable support reply admins can send instead of typing.
+type CannedResponse struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Title     string    `gorm:"size:100;not null" json:"title"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (CannedResponse) TableName() string {
+	return "canned_responses"
+}
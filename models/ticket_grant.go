@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Where spin tickets come from.
+const (
+	// TicketSourceReferral: a direct referral's purchase; the ref is its order id.
+	TicketSourceReferral = "referral"
+	// TicketSourceMission: a claimed spin_ticket mission; the ref is the user_missions id.
+	TicketSourceMission = "mission"
+	// TicketSourceAdmin: an admin grant batch; the ref is "grant-<batch id>-<user id>".
+	TicketSourceAdmin = "admin"
+)
+
+// TicketGrant records spin tickets added to a user's spin_ticket. One source
+// ref grants at most once, so replayed callbacks cannot grant again.
+type TicketGrant struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_ticket_grants_user_created,priority:1" json:"user_id"`
+	Source    string    `gorm:"type:enum('referral','mission','admin');not null;uniqueIndex:idx_ticket_grants_source_ref,priority:1" json:"source"`
+	SourceRef string    `gorm:"type:varchar(191);not null;uniqueIndex:idx_ticket_grants_source_ref,priority:2" json:"source_ref"`
+	Tickets   uint      `gorm:"not null" json:"tickets"`
+	CreatedAt time.Time `gorm:"index:idx_ticket_grants_user_created,priority:2" json:"created_at"`
+}
+
+func (TicketGrant) TableName() string {
+	return "ticket_grants"
+}
+
+// GrantSpinTickets adds tickets to userID's spin_ticket and records the grant.
+// It reports false without granting when source and ref were granted before.
+// Call it inside the transaction that earns the tickets.
+func GrantSpinTickets(tx *gorm.DB, userID uint, source, ref string, tickets uint) (bool, error) {
+	if tickets == 0 {
+		return false, nil
+	}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&TicketGrant{UserID: userID, Source: source, SourceRef: ref, Tickets: tickets})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	err := tx.Model(&User{}).Where("id = ?", userID).
+		UpdateColumn("spin_ticket", gorm.Expr("COALESCE(spin_ticket, 0) + ?", tickets)).Error
+	return err == nil, err
+}
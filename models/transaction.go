@@ -1,21 +1,58 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Transaction struct {
 	ID               uint      `gorm:"primaryKey" json:"id"`
-	UserID           uint      `gorm:"not null;index" json:"user_id"`
-	Amount           float64   `gorm:"type:decimal(15,2);not null" json:"amount"`
-	Charge           float64   `gorm:"type:decimal(15,2);not null;default:0.00" json:"charge"`
+	UserID           uint      `gorm:"not null;index;index:idx_transactions_user_created,priority:1;index:idx_transactions_user_flow_status_created,priority:1" json:"user_id"`
+	InvestmentID     *uint     `gorm:"index" json:"investment_id,omitempty"`
+	SourceOrderID    *string   `gorm:"type:varchar(191);index" json:"source_order_id,omitempty"` // order whose payment funded it, e.g. the investment behind a referral bonus
+	Amount           int64     `gorm:"type:bigint;not null" json:"amount"`
+	Charge           int64     `gorm:"type:bigint;not null;default:0" json:"charge"`
 	OrderID          string    `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
-	TransactionFlow  string    `gorm:"type:enum('debit','credit');not null" json:"transaction_flow"`
-	TransactionType  string    `gorm:"type:varchar(50);not null" json:"transaction_type"`
+	TransactionFlow  string    `gorm:"type:enum('debit','credit');not null;index:idx_transactions_user_flow_status_created,priority:2" json:"transaction_flow"`
+	TransactionType  string    `gorm:"type:varchar(50);not null;index:idx_transactions_type_created,priority:1" json:"transaction_type"`
 	Message          *string   `gorm:"type:text" json:"message,omitempty"`
-	Status           string    `gorm:"type:enum('Success','Pending','Failed');not null;default:'Pending'" json:"status"`
-	CreatedAt        time.Time `json:"-"`
+	Status           string    `gorm:"type:enum('Success','Pending','Failed','Held');not null;default:'Pending';index:idx_transactions_user_flow_status_created,priority:3" json:"status"` // Held: a referral bonus waiting for fraud review
+	CreatedAt        time.Time `gorm:"index:idx_transactions_type_created,priority:2;index:idx_transactions_user_created,priority:2;index:idx_transactions_user_flow_status_created,priority:4" json:"-"`
 	UpdatedAt        time.Time `json:"-"`
+	// SettledAt is when the transaction first became Success. Unlike
+	// UpdatedAt it never moves afterwards, so day-bound exports can rely on it
+	SettledAt *time.Time `gorm:"index" json:"-"`
 }
 
 func (Transaction) TableName() string {
 	return "transactions"
 }
+
+// BeforeCreate stamps SettledAt on a transaction created as Success.
+func (t *Transaction) BeforeCreate(*gorm.DB) error {
+	if t.Status == "Success" && t.SettledAt == nil {
+		now := time.Now()
+		t.SettledAt = &now
+	}
+	return nil
+}
+
+// BeforeUpdate stamps settled_at when an update sets the status to Success,
+// keeping the first settlement time of a transaction that already had one.
+// Updates by column name (Update, Updates with a map) and saves of a loaded
+// transaction are covered; UpdateColumn skips hooks and must not settle.
+func (t *Transaction) BeforeUpdate(tx *gorm.DB) error {
+	switch dest := tx.Statement.Dest.(type) {
+	case map[string]interface{}:
+		if dest["status"] == "Success" {
+			dest["settled_at"] = gorm.Expr("COALESCE(settled_at, ?)", time.Now())
+		}
+	case *Transaction:
+		if dest.Status == "Success" && dest.SettledAt == nil {
+			now := time.Now()
+			dest.SettledAt = &now
+		}
+	}
+	return nil
+}
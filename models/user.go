@@ -2,6 +2,27 @@ package models
 
 import "time"
 
+// MaxVIPLevel is the highest level on the VIP ladder.
+const MaxVIPLevel = 5
+
+// VIPLevelFor determines the VIP level from total locked category investments.
+// VIP1: 50k, VIP2: 1.2M, VIP3: 7M, VIP4: 30M, VIP5: 150M. The thresholds in
+// vip_levels take precedence; see package vip.
+func VIPLevelFor(totalInvestVIP int64) uint {
+	if totalInvestVIP >= 150000000 {
+		return 5
+	} else if totalInvestVIP >= 30000000 {
+		return 4
+	} else if totalInvestVIP >= 7000000 {
+		return 3
+	} else if totalInvestVIP >= 1200000 {
+		return 2
+	} else if totalInvestVIP >= 50000 {
+		return 1
+	}
+	return 0
+}
+
 type User struct {
 	ID               uint      `gorm:"primaryKey" json:"id"`
 	Name             string    `gorm:"size:100;not null" json:"name"`
@@ -9,13 +30,14 @@ type User struct {
 	Password         string    `gorm:"size:255;not null" json:"-"`
 	ReffCode         string    `gorm:"size:20;uniqueIndex;not null" json:"reff_code"`
 	ReffBy           *uint     `gorm:"column:reff_by" json:"reff_by"`
-	Balance          float64   `gorm:"type:decimal(15,2);default:0" json:"balance"`
+	Balance          int64     `gorm:"type:bigint;default:0" json:"balance"`
 	Level            *uint     `gorm:"column:level;default:0" json:"level"`
-	TotalInvest      float64   `gorm:"column:total_invest;type:decimal(15,2);default:0" json:"total_invest"`
-	TotalInvestVIP   float64   `gorm:"column:total_invest_vip;type:decimal(15,2);default:0" json:"total_invest_vip"`
+	TotalInvest      int64     `gorm:"column:total_invest;type:bigint;default:0" json:"total_invest"`
+	TotalInvestVIP   int64     `gorm:"column:total_invest_vip;type:bigint;default:0" json:"total_invest_vip"`
 	SpinTicket       *uint     `gorm:"column:spin_ticket;default:0" json:"spin_ticket"`
 	Status           string    `gorm:"type:enum('Active','Inactive','Suspend');default:'Active'" json:"status"`
 	InvestmentStatus string    `gorm:"type:enum('Active','Inactive');default:'Inactive'" json:"investment_status"`
+	Locale           *string   `gorm:"column:locale;size:5" json:"locale"` // response language preference; nil follows Accept-Language
 	CreatedAt        time.Time `json:"-"`
 	UpdatedAt        time.Time `json:"-"`
 }
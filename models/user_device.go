@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// UserDevice is a push notification token registered by the mobile app. A
+// token belongs to one user at a time; registering it again moves it.
+type UserDevice struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	Token      string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"token"`
+	Platform   string    `gorm:"type:enum('android','ios','web');not null" json:"platform"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (UserDevice) TableName() string {
+	return "user_devices"
+}
+
+// NotificationPreference holds a user's push opt-outs. Users without a row
+// receive every push.
+type NotificationPreference struct {
+	UserID     uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Payment    bool      `gorm:"not null;default:true" json:"payment"`
+	Profit     bool      `gorm:"not null;default:true" json:"profit"`
+	Withdrawal bool      `gorm:"not null;default:true" json:"withdrawal"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// DefaultNotificationPreference is the preference of a user without a row.
+func DefaultNotificationPreference(userID uint) NotificationPreference {
+	return NotificationPreference{UserID: userID, Payment: true, Profit: true, Withdrawal: true}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User signal events.
+const (
+	UserSignalRegister = "register"
+	UserSignalLogin    = "login"
+)
+
+// DeviceFingerprintHeader carries the app's device fingerprint.
+const DeviceFingerprintHeader = "X-Device-Fingerprint"
+
+// UserSignal is the IP, user agent and device fingerprint seen when a user
+// registered or logged in, kept to spot one person behind several accounts.
+// It doubles as the user's device history: user_devices holds push tokens.
+type UserSignal struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	UserID            uint      `gorm:"not null;index" json:"user_id"`
+	Event             string    `gorm:"type:enum('register','login');not null" json:"event"`
+	IP                string    `gorm:"type:varchar(45);not null;index:idx_user_signals_ip_created,priority:1" json:"ip"`
+	DeviceFingerprint *string   `gorm:"type:varchar(128);index" json:"device_fingerprint,omitempty"`
+	UserAgent         string    `gorm:"type:varchar(255);not null;default:''" json:"user_agent"`
+	CreatedAt         time.Time `gorm:"index:idx_user_signals_ip_created,priority:2" json:"created_at"`
+}
+
+func (UserSignal) TableName() string {
+	return "user_signals"
+}
+
+// RecordUserSignal stores the IP, fingerprint and user agent of a register or
+// login event. An empty fingerprint is stored as NULL so it never matches
+// another user.
+func RecordUserSignal(db *gorm.DB, userID uint, event, ip, fingerprint, userAgent string) error {
+	s := UserSignal{UserID: userID, Event: event, IP: ip}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	s.UserAgent = userAgent
+	if fp := strings.TrimSpace(fingerprint); fp != "" {
+		if len(fp) > 128 {
+			fp = fp[:128]
+		}
+		s.DeviceFingerprint = &fp
+	}
+	return db.Create(&s).Error
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VIPLevel holds the caps of one VIP level. A zero cap, or a level without a
+// row, means no limit.
+type VIPLevel struct {
+	Level uint `gorm:"primaryKey;autoIncrement:false" json:"level"`
+	// MinTotalInvest is the total_invest_vip a user needs for the level; it
+	// must be set for every level above 0
+	MinTotalInvest int64 `gorm:"type:bigint;not null;default:0" json:"min_total_invest"`
+	// MaxActiveInvestments caps Running, Suspended and awaiting-payment
+	// investments held at once
+	MaxActiveInvestments int `gorm:"not null;default:0" json:"max_active_investments"`
+	// MaxDailyWithdrawal caps the rupiah requested per day in APP_TIMEZONE,
+	// counting every withdrawal that has not failed
+	MaxDailyWithdrawal int64 `gorm:"type:bigint;not null;default:0" json:"max_daily_withdrawal"`
+	// MaxSingleWithdrawal caps one withdrawal request
+	MaxSingleWithdrawal int64     `gorm:"type:bigint;not null;default:0" json:"max_single_withdrawal"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+func (VIPLevel) TableName() string {
+	return "vip_levels"
+}
+
+// VIPLevelLimits returns the caps of level; a level without a row has none.
+// The ladder is read through the cache, which UpdateVIPLevelHandler empties.
+func VIPLevelLimits(db *gorm.DB, level uint) (VIPLevel, error) {
+	ladder, err := vipLadder(db)
+	if err != nil {
+		return VIPLevel{}, err
+	}
+	if l, ok := ladder[level]; ok {
+		return l, nil
+	}
+	return VIPLevel{Level: level}, nil
+}
+
+// VIPLevelChange records one VIP level recalculation that changed, or under
+// the flag policy would have lowered, a user's level.
+type VIPLevelChange struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	UserID         uint   `gorm:"not null;index:idx_vip_level_changes_user,priority:1" json:"user_id"`
+	FromLevel      uint   `gorm:"not null" json:"from_level"`
+	ToLevel        uint   `gorm:"not null" json:"to_level"`
+	TotalInvestVIP int64  `gorm:"column:total_invest_vip;type:bigint;not null" json:"total_invest_vip"`
+	Source         string `gorm:"type:varchar(16);not null" json:"source"` // payment, cancel, cron or admin
+	// Applied is false for a downgrade only flagged for support
+	Applied   bool      `gorm:"not null" json:"applied"`
+	CreatedAt time.Time `gorm:"index:idx_vip_level_changes_user,priority:2" json:"created_at"`
+}
+
+func (VIPLevelChange) TableName() string {
+	return "vip_level_changes"
+}
+
+// CurrentVIPLevel returns u's VIP level, 0 when unset.
+func (u *User) CurrentVIPLevel() uint {
+	if u.Level == nil {
+		return 0
+	}
+	return *u.Level
+}
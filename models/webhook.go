@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending = "Pending"
+	WebhookDeliverySuccess = "Success"
+	// WebhookDeliveryFailed is set once a delivery used up its attempts or its
+	// endpoint is gone; an admin can redeliver it
+	WebhookDeliveryFailed = "Failed"
+)
+
+// WebhookEndpoint is a downstream system notified when money moves. Events is
+// a comma-separated list of the event types it subscribes to. Deliveries are
+// signed with Secret, which is only shown when created or rotated.
+type WebhookEndpoint struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	Name   string `gorm:"type:varchar(100);not null" json:"name"`
+	URL    string `gorm:"column:url;type:varchar(500);not null" json:"url"`
+	Secret string `gorm:"type:varchar(128);not null" json:"-"`
+	Events string `gorm:"type:varchar(255);not null" json:"events"`
+	// IncludePII adds the user's name and phone number to payloads, which
+	// otherwise identify the user by id only
+	IncludePII bool      `gorm:"column:include_pii;not null;default:false" json:"include_pii"`
+	Active     bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// Subscribes reports whether e wants events of eventType.
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	for _, t := range strings.Split(e.Events, ",") {
+		if strings.TrimSpace(t) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to one endpoint, with the exact body
+// signed and the outcome of the last attempt. EventID is shared by the
+// deliveries of one event so receivers can drop duplicates.
+type WebhookDelivery struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	EndpointID uint   `gorm:"not null;index:idx_webhook_deliveries_endpoint,priority:1" json:"endpoint_id"`
+	EventID    string `gorm:"type:varchar(64);not null;index" json:"event_id"`
+	EventType  string `gorm:"type:varchar(64);not null;index:idx_webhook_deliveries_type" json:"event_type"`
+	Payload    string `gorm:"type:text;not null" json:"payload"`
+	Status     string `gorm:"type:enum('Pending','Success','Failed');not null;default:'Pending';index:idx_webhook_deliveries_status" json:"status"`
+	Attempts   int    `gorm:"not null;default:0" json:"attempts"`
+	// ResponseStatus is the HTTP status of the last attempt, 0 when none came back
+	ResponseStatus int        `gorm:"not null;default:0" json:"response_status"`
+	LastError      *string    `gorm:"type:text" json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"index:idx_webhook_deliveries_endpoint,priority:2" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
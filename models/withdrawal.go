@@ -2,18 +2,49 @@ package models
 
 import "time"
 
+// WithdrawalOnHold is the status of a withdrawal a risk rule held back from
+// review until an admin releases it to Pending.
+const WithdrawalOnHold = "On Hold"
+
+// Outcomes of the account inquiry run when a withdrawal is created, stored in
+// withdrawals.inquiry_status.
+const (
+	InquiryMatched     = "matched"
+	InquiryMismatch    = "mismatch"
+	InquiryUnsupported = "unsupported"
+	InquiryFailed      = "failed"
+)
+
 type Withdrawal struct {
 	ID            uint         `gorm:"primaryKey" json:"id"`
 	UserID        uint         `gorm:"not null;index" json:"user_id"`
 	BankAccountID uint         `gorm:"not null;index" json:"bank_account_id"`
-	Amount        float64      `gorm:"type:decimal(15,2);not null" json:"amount"`
-	Charge        float64      `gorm:"type:decimal(15,2);not null;default:0.00" json:"charge"`
-	FinalAmount   float64      `gorm:"type:decimal(15,2);not null" json:"final_amount"`
+	Amount        int64        `gorm:"type:bigint;not null" json:"amount"`
+	Charge        int64        `gorm:"type:bigint;not null;default:0" json:"charge"`
+	FinalAmount   int64        `gorm:"type:bigint;not null" json:"final_amount"`
 	OrderID       string       `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
-	Status        string       `gorm:"type:enum('Success','Pending','Failed');not null;default:'Pending'" json:"status"`
-	CreatedAt     time.Time    `json:"created_at"`
+	Status        string       `gorm:"type:enum('Success','Pending','On Hold','Failed');not null;default:'Pending';index:idx_withdrawals_status_created,priority:1" json:"status"`
+	ProcessedBy   *int64       `gorm:"column:processed_by;index" json:"processed_by,omitempty"` // admins.id that approved or rejected
+	ProcessedAt   *time.Time   `gorm:"column:processed_at" json:"processed_at,omitempty"`
+	RiskFlags     *string      `gorm:"type:varchar(255)" json:"risk_flags,omitempty"` // comma-separated risk rules that fired at request time
+	CreatedAt     time.Time    `gorm:"index:idx_withdrawals_status_created,priority:2" json:"created_at"`
 	UpdatedAt     time.Time    `json:"updated_at"`
 	BankAccount   *BankAccount `gorm:"foreignKey:BankAccountID" json:"bank_account,omitempty"`
+	// Express withdrawals skip the processing window and are paid out by the
+	// express cron; ExpressFee is the part of Charge paid for it
+	Express    bool  `gorm:"not null;default:false;index" json:"express"`
+	ExpressFee int64 `gorm:"type:bigint;not null;default:0" json:"express_fee"`
+
+	// GatewayPayoutID is KytaPay's id of the last payout sent for the
+	// withdrawal; the payout's reference is the OrderID
+	GatewayPayoutID *string `gorm:"type:varchar(191);index" json:"gateway_payout_id,omitempty"`
+
+	// InquiryStatus is the outcome of the gateway's account inquiry, nil when
+	// none was run; InquiryName is the holder the bank reported and
+	// NameMatchScore (0-100) how well it matched the user's name
+	InquiryStatus  *string `gorm:"type:varchar(16)" json:"inquiry_status,omitempty"`
+	InquiryName    *string `gorm:"type:varchar(191)" json:"inquiry_name,omitempty"`
+	NameMatchScore *int    `json:"name_match_score,omitempty"`
 }
 
 func (Withdrawal) TableName() string {
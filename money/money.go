@@ -0,0 +1,50 @@
+// Package money holds the helpers for amounts stored as whole rupiah in int64.
+// Balances, investment amounts, profits, and withdrawal figures never go
+// through float64 arithmetic; floats only appear at the edges (percent
+// settings and legacy decimal rewards), where they are converted once with
+// FromFloat or applied with Percent.
+package money
+
+import "math"
+
+// Rounding is half away from zero (math.Round semantics) everywhere. Banker's
+// rounding was considered and rejected: every credit is computed once from an
+// exact integer base, so there is no long chain of roundings for it to
+// de-bias, and half-up is what users and finance expect to see on a receipt.
+
+// FromFloat converts a float amount to whole rupiah, rounding half away from zero.
+// Unlike the old round3, which went through int and truncated toward zero,
+// negative values and values beyond 2^31 are handled correctly.
+func FromFloat(f float64) int64 {
+	return int64(math.Round(f))
+}
+
+// Percent returns pct percent of amount, rounded half away from zero to whole
+// rupiah. pct is applied with two decimal places of precision (basis points),
+// which matches the decimal(5,2) percent columns in settings. The intermediate
+// product amount*bps must fit in int64, i.e. |amount| up to ~9e14 rupiah.
+func Percent(amount int64, pct float64) int64 {
+	bps := int64(math.Round(pct * 100))
+	return divRound(amount*bps, 10000)
+}
+
+// Total returns the sum of daily payments over days, i.e. what a completed
+// investment pays out. Used to cross-check the per-day accumulation.
+func Total(daily int64, days int) int64 {
+	return daily * int64(days)
+}
+
+// Scale returns amount*num/den (den > 0), rounded half away from zero. Used
+// to keep a daily profit at the rate it was bought when the principal
+// changes. amount*num must fit in int64.
+func Scale(amount, num, den int64) int64 {
+	return divRound(amount*num, den)
+}
+
+// divRound divides a by b (b > 0), rounding half away from zero.
+func divRound(a, b int64) int64 {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
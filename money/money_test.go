@@ -0,0 +1,107 @@
+package money
+
+import "testing"
+
+func TestFromFloat_RoundsHalfAwayFromZero(t *testing.T) {
+	cases := []struct {
+		in   float64
+		want int64
+	}{
+		{0, 0},
+		{1499.49, 1499},
+		{1499.5, 1500},
+		{-1499.5, -1500},
+		{123456789.5, 123456790},
+		{-0.5, -1},
+		{-0.4, 0},
+		{-2500.75, -2501},
+		// above 2^31/100, where the old int-cast helper started to misbehave
+		{21474836.48, 21474836},
+		{21474836.5, 21474837},
+		{4294967296.5, 4294967297},
+		{-4294967296.5, -4294967297},
+	}
+	for _, c := range cases {
+		if got := FromFloat(c.in); got != c.want {
+			t.Fatalf("FromFloat(%v) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	cases := []struct {
+		amount int64
+		pct    float64
+		want   int64
+	}{
+		{100000, 30, 30000},
+		{100000, 10, 10000},
+		{55555, 10, 5556}, // 5555.5 rounds up
+		{55554, 10, 5555},
+		{33333, 2.5, 833}, // 833.325
+		{100000, 0, 0},
+		{-55555, 10, -5556},
+		{-1, 50, -1}, // -0.5 rounds away from zero
+		{30000000000, 30, 9000000000},
+		{100000, 12.345, 12350}, // pct applied at two decimals: 12.35%
+	}
+	for _, c := range cases {
+		if got := Percent(c.amount, c.pct); got != c.want {
+			t.Fatalf("Percent(%d, %v) = %d, want %d", c.amount, c.pct, got, c.want)
+		}
+	}
+}
+
+// Referral bonus scenario from the payment webhook: money.Percent(inv.Amount,
+// setting.ReferralBonusPercent). The old inv.Amount * 0.30 through round3 left
+// fractions (33333 * 0.30 = 9999.9) or lost a sen to float error.
+func TestReferralBonusScenario(t *testing.T) {
+	cases := []struct {
+		amount int64
+		pct    float64
+		want   int64
+	}{
+		{50000, 30, 15000},
+		{33333, 30, 10000}, // 9999.9
+		{1200000, 30, 360000},
+		{99999, 30, 30000}, // 29999.7
+		{150000000, 30, 45000000},
+		{77777, 12.5, 9722}, // 9722.125
+	}
+	for _, c := range cases {
+		if got := Percent(c.amount, c.pct); got != c.want {
+			t.Fatalf("referral bonus for %d at %v%% = %d, want %d", c.amount, c.pct, got, c.want)
+		}
+	}
+}
+
+// Withdrawal fee scenario: charge is a whole-rupiah percent and final amount
+// is the exact remainder, so charge + final always equals the request.
+func TestWithdrawalChargeScenario(t *testing.T) {
+	for _, amount := range []int64{50000, 55555, 1000001, 21474837} {
+		charge := Percent(amount, 10)
+		final := amount - charge
+		if charge+final != amount || charge < 0 || final <= 0 {
+			t.Fatalf("amount %d: charge %d, final %d", amount, charge, final)
+		}
+	}
+}
+
+// Top-up scenario: the daily profit follows the principal at the rate bought,
+// 5000/100000 a day here, without drifting over repeated top-ups.
+func TestScaleTopupScenario(t *testing.T) {
+	cases := []struct {
+		amount, rateDaily, rateAmount int64
+		want                          int64
+	}{
+		{150000, 5000, 100000, 7500},
+		{133333, 5000, 100000, 6667}, // 6666.65
+		{100000, 1333, 30000, 4443},  // 4443.33
+		{250000000, 1500000, 100000000, 3750000},
+	}
+	for _, c := range cases {
+		if got := Scale(c.rateDaily, c.amount, c.rateAmount); got != c.want {
+			t.Fatalf("daily profit of %d at %d/%d = %d, want %d", c.amount, c.rateDaily, c.rateAmount, got, c.want)
+		}
+	}
+}
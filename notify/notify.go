@@ -0,0 +1,405 @@
+// Package notify turns money and support events into push notifications for
+// the user's registered devices.
+//
+// Events are queued in memory and delivered by a background worker, so a slow
+// or failing push provider never holds a database transaction or delays a
+// response. Callers enqueue only after their transaction has committed; a nil
+// *Notifier accepts and drops everything, which is what tests use.
+package notify
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"project/i18n"
+	"project/models"
+	"project/push"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// QueueSize is how many events may wait for delivery before new ones are dropped.
+const QueueSize = 1024
+
+// sendTimeout bounds delivery of one event to all of a user's devices.
+const sendTimeout = 30 * time.Second
+
+// Kind groups events by the preference that controls them.
+type Kind string
+
+const (
+	KindPayment    Kind = "payment"
+	KindProfit     Kind = "profit"
+	KindWithdrawal Kind = "withdrawal"
+	// KindSupport covers replies to the user's own support tickets and has no opt-out.
+	KindSupport Kind = "support"
+	// KindAccount covers changes to the user's account, like the VIP level, and has no opt-out.
+	KindAccount Kind = "account"
+)
+
+// Event is one notification for one user. Title and body are catalog keys,
+// rendered in the user's locale at delivery time.
+type Event struct {
+	UserID   uint
+	Kind     Kind
+	TitleKey string
+	BodyKey  string
+	Args     []interface{}
+	Data     map[string]string
+}
+
+// PaymentSuccess is sent when an investment or deposit payment is confirmed.
+func PaymentSuccess(userID uint, orderID string, amount int64) Event {
+	return Event{
+		UserID: userID, Kind: KindPayment,
+		TitleKey: i18n.MsgPushPaymentSuccessTitle, BodyKey: i18n.MsgPushPaymentSuccessBody,
+		Args: []interface{}{orderID, amount},
+		Data: map[string]string{"type": "payment_success", "order_id": orderID},
+	}
+}
+
+// PaymentExpiring is sent by the expiry cron shortly before a pending payment
+// lapses. productName is empty for a deposit. The expiry time is shown as
+// HH:MM in the app timezone.
+func PaymentExpiring(userID uint, orderID, productName string, expiredAt time.Time) Event {
+	at := expiredAt.In(utils.AppLocation()).Format("15:04")
+	e := Event{
+		UserID: userID, Kind: KindPayment,
+		TitleKey: i18n.MsgPushPaymentExpiringTitle, BodyKey: i18n.MsgPushPaymentExpiringBody,
+		Args: []interface{}{orderID, productName, at},
+		Data: map[string]string{"type": "payment_expiring", "order_id": orderID},
+	}
+	if productName == "" {
+		e.BodyKey, e.Args = i18n.MsgPushDepositExpiringBody, []interface{}{orderID, at}
+	}
+	return e
+}
+
+// PaymentPartial is sent when an investment payment arrives short of what was
+// billed; the amount received is refunded by the partial refund cron.
+func PaymentPartial(userID uint, orderID string, received, remaining int64) Event {
+	return Event{
+		UserID: userID, Kind: KindPayment,
+		TitleKey: i18n.MsgPushPaymentPartialTitle, BodyKey: i18n.MsgPushPaymentPartialBody,
+		Args: []interface{}{orderID, remaining, received},
+		Data: map[string]string{"type": "payment_partial", "order_id": orderID},
+	}
+}
+
+// PaymentOverpaid is sent when the excess of an overpaid investment payment
+// has been held for an admin to release to the balance.
+func PaymentOverpaid(userID uint, orderID string, excess int64) Event {
+	return Event{
+		UserID: userID, Kind: KindPayment,
+		TitleKey: i18n.MsgPushPaymentOverpaidTitle, BodyKey: i18n.MsgPushPaymentOverpaidBody,
+		Args: []interface{}{orderID, excess},
+		Data: map[string]string{"type": "payment_overpaid", "order_id": orderID},
+	}
+}
+
+// PaymentRefunded is sent when the partial refund cron refunds a short payment.
+func PaymentRefunded(userID uint, orderID string, amount int64) Event {
+	return Event{
+		UserID: userID, Kind: KindPayment,
+		TitleKey: i18n.MsgPushPaymentRefundedTitle, BodyKey: i18n.MsgPushPaymentRefundedBody,
+		Args: []interface{}{amount, orderID},
+		Data: map[string]string{"type": "payment_refunded", "order_id": orderID},
+	}
+}
+
+// Refund states for InvestmentRefund.
+const (
+	RefundSent    = "sent"
+	RefundPaid    = "paid"
+	RefundBalance = "balance"
+)
+
+// InvestmentRefund is sent about the refund of a cancelled investment: when
+// the payout to the user's bank is sent (RefundSent) and confirmed
+// (RefundPaid), or when it went to the balance instead (RefundBalance), which
+// asks the user to withdraw it.
+func InvestmentRefund(userID uint, orderID, productName, state string, amount int64) Event {
+	e := Event{
+		UserID: userID, Kind: KindPayment,
+		Args: []interface{}{productName, amount},
+		Data: map[string]string{"type": "investment_refund", "order_id": orderID, "state": state},
+	}
+	switch state {
+	case RefundPaid:
+		e.TitleKey, e.BodyKey = i18n.MsgPushRefundPaidTitle, i18n.MsgPushRefundPaidBody
+	case RefundBalance:
+		e.TitleKey, e.BodyKey = i18n.MsgPushRefundBalanceTitle, i18n.MsgPushRefundBalanceBody
+	default:
+		e.TitleKey, e.BodyKey = i18n.MsgPushRefundSentTitle, i18n.MsgPushRefundSentBody
+	}
+	return e
+}
+
+// PaymentRejected is sent when an admin rejects the transfer proof of a
+// manual payment, cancelling the purchase.
+func PaymentRejected(userID uint, orderID, reason string) Event {
+	return Event{
+		UserID: userID, Kind: KindPayment,
+		TitleKey: i18n.MsgPushPaymentRejectedTitle, BodyKey: i18n.MsgPushPaymentRejectedBody,
+		Args: []interface{}{orderID, reason},
+		Data: map[string]string{"type": "payment_rejected", "order_id": orderID},
+	}
+}
+
+// ProfitCredited is sent when the daily returns cron credits an investment.
+func ProfitCredited(userID, investmentID uint, productName string, amount int64) Event {
+	return Event{
+		UserID: userID, Kind: KindProfit,
+		TitleKey: i18n.MsgPushProfitCreditedTitle, BodyKey: i18n.MsgPushProfitCreditedBody,
+		Args: []interface{}{amount, productName},
+		Data: map[string]string{"type": "profit_credited", "investment_id": strconv.FormatUint(uint64(investmentID), 10)},
+	}
+}
+
+// InvestmentCompleted is sent with the recap of an investment the daily
+// returns cron completed: the capital returned and everything earned on it.
+func InvestmentCompleted(userID, investmentID uint, productName string, principal, earned int64, roiPercent float64) Event {
+	return Event{
+		UserID: userID, Kind: KindProfit,
+		TitleKey: i18n.MsgPushInvestmentDoneTitle, BodyKey: i18n.MsgPushInvestmentDoneBody,
+		Args: []interface{}{productName, principal, earned, roiPercent},
+		Data: map[string]string{"type": "investment_completed", "investment_id": strconv.FormatUint(uint64(investmentID), 10)},
+	}
+}
+
+// WithdrawalStatus is sent when a withdrawal is paid out ("Success"), rejected
+// ("Failed") or sent back for another payout attempt ("Pending").
+func WithdrawalStatus(userID uint, orderID, status string, amount int64) Event {
+	e := Event{
+		UserID: userID, Kind: KindWithdrawal,
+		Data: map[string]string{"type": "withdrawal_status", "order_id": orderID, "status": status},
+	}
+	switch status {
+	case "Success":
+		e.TitleKey, e.BodyKey, e.Args = i18n.MsgPushWithdrawalSuccessTitle, i18n.MsgPushWithdrawalSuccessBody, []interface{}{orderID, amount}
+	case "Failed":
+		e.TitleKey, e.BodyKey, e.Args = i18n.MsgPushWithdrawalFailedTitle, i18n.MsgPushWithdrawalFailedBody, []interface{}{orderID, amount}
+	default:
+		e.TitleKey, e.BodyKey, e.Args = i18n.MsgPushWithdrawalRetryTitle, i18n.MsgPushWithdrawalRetryBody, []interface{}{orderID}
+	}
+	return e
+}
+
+// TicketStatus is sent when support answers ("Answered") or closes ("Closed")
+// one of the user's tickets.
+func TicketStatus(userID, ticketID uint, subject, status string) Event {
+	e := Event{
+		UserID: userID, Kind: KindSupport,
+		Args: []interface{}{ticketID, subject},
+		Data: map[string]string{"type": "ticket_status", "ticket_id": strconv.FormatUint(uint64(ticketID), 10), "status": status},
+	}
+	if status == "Closed" {
+		e.TitleKey, e.BodyKey = i18n.MsgPushTicketClosedTitle, i18n.MsgPushTicketClosedBody
+	} else {
+		e.TitleKey, e.BodyKey = i18n.MsgPushTicketAnsweredTitle, i18n.MsgPushTicketAnsweredBody
+	}
+	return e
+}
+
+// VIPLevelChanged is sent when a recalculation raises or lowers the user's VIP level.
+func VIPLevelChanged(userID, from, to uint) Event {
+	e := Event{
+		UserID: userID, Kind: KindAccount,
+		Args: []interface{}{to},
+		Data: map[string]string{"type": "vip_level", "level": strconv.FormatUint(uint64(to), 10)},
+	}
+	if to > from {
+		e.TitleKey, e.BodyKey = i18n.MsgPushVIPUpgradedTitle, i18n.MsgPushVIPUpgradedBody
+	} else {
+		e.TitleKey, e.BodyKey = i18n.MsgPushVIPDowngradedTitle, i18n.MsgPushVIPDowngradedBody
+	}
+	return e
+}
+
+// Allows reports whether pref lets events of kind k through.
+func Allows(pref models.NotificationPreference, k Kind) bool {
+	switch k {
+	case KindPayment:
+		return pref.Payment
+	case KindProfit:
+		return pref.Profit
+	case KindWithdrawal:
+		return pref.Withdrawal
+	case KindSupport, KindAccount:
+		return true
+	}
+	return false
+}
+
+// Inbox returns the in-app notification row for e, rendered in locale.
+func Inbox(e Event, locale i18n.Locale, notificationType string) models.Notification {
+	return models.Notification{
+		UserID: e.UserID,
+		Type:   notificationType,
+		Title:  i18n.T(locale, e.TitleKey),
+		Body:   i18n.T(locale, e.BodyKey, e.Args...),
+	}
+}
+
+// UserLocale returns the user's saved locale, or the default when unset.
+func UserLocale(db *gorm.DB, userID uint) (i18n.Locale, error) {
+	var user models.User
+	if err := db.Select("id", "locale").First(&user, userID).Error; err != nil {
+		return i18n.Default, err
+	}
+	if user.Locale != nil {
+		if l, ok := i18n.Parse(*user.Locale); ok {
+			return l, nil
+		}
+	}
+	return i18n.Default, nil
+}
+
+// Render returns the push messages for e, one per device token.
+func Render(e Event, locale i18n.Locale, tokens []string) []push.Message {
+	title := i18n.T(locale, e.TitleKey)
+	body := i18n.T(locale, e.BodyKey, e.Args...)
+	msgs := make([]push.Message, len(tokens))
+	for i, t := range tokens {
+		msgs[i] = push.Message{Token: t, Title: title, Body: body, Data: e.Data}
+	}
+	return msgs
+}
+
+// Notifier queues events and delivers them from a single background worker.
+type Notifier struct {
+	db     *gorm.DB
+	sender push.Sender
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan Event
+	done   chan struct{}
+}
+
+// running tracks started notifiers so shutdown can drain them all.
+var running struct {
+	mu        sync.Mutex
+	notifiers []*Notifier
+}
+
+// New starts a Notifier delivering through sender.
+func New(db *gorm.DB, sender push.Sender) *Notifier {
+	n := &Notifier{db: db, sender: sender, queue: make(chan Event, QueueSize), done: make(chan struct{})}
+	running.mu.Lock()
+	running.notifiers = append(running.notifiers, n)
+	running.mu.Unlock()
+	go n.run()
+	return n
+}
+
+// CloseAll closes every notifier started by New. Called on shutdown after the
+// HTTP server has stopped, so no handler can enqueue any more.
+func CloseAll(ctx context.Context) error {
+	running.mu.Lock()
+	ns := running.notifiers
+	running.notifiers = nil
+	running.mu.Unlock()
+	var firstErr error
+	for _, n := range ns {
+		if err := n.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Enqueue schedules e for delivery. It never blocks: when the queue is full the
+// event is dropped and logged, and after Close it is dropped silently.
+func (n *Notifier) Enqueue(e Event) {
+	if n == nil {
+		return
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- e:
+	default:
+		utils.Logger.Warn("push queue full, dropping notification", "user_id", e.UserID, "title_key", e.TitleKey)
+	}
+}
+
+// Close stops accepting events and waits until the queued ones are delivered
+// or ctx is done.
+func (n *Notifier) Close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *Notifier) run() {
+	defer close(n.done)
+	for e := range n.queue {
+		if err := n.deliver(e); err != nil {
+			utils.Logger.Error("push delivery failed", "user_id", e.UserID, "title_key", e.TitleKey, "error", err.Error())
+		}
+	}
+}
+
+// deliver sends e to every device of the user unless their preferences opt
+// out, then forgets tokens the provider reported as invalid.
+func (n *Notifier) deliver(e Event) error {
+	pref := models.DefaultNotificationPreference(e.UserID)
+	if err := n.db.Where("user_id = ?", e.UserID).First(&pref).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if !Allows(pref, e.Kind) {
+		return nil
+	}
+
+	var tokens []string
+	if err := n.db.Model(&models.UserDevice{}).Where("user_id = ?", e.UserID).Pluck("token", &tokens).Error; err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	locale, err := UserLocale(n.db, e.UserID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	res, err := n.sender.Send(ctx, Render(e, locale, tokens))
+	if errors.Is(err, push.ErrNotConfigured) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(res.InvalidTokens) > 0 {
+		if err := n.db.Where("token IN ?", res.InvalidTokens).Delete(&models.UserDevice{}).Error; err != nil {
+			return err
+		}
+	}
+	if res.Failed > len(res.InvalidTokens) {
+		utils.Logger.Warn("push partially failed", "user_id", e.UserID, "sent", res.Sent, "failed", res.Failed)
+	}
+	return nil
+}
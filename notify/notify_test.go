@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"project/i18n"
+	"project/models"
+)
+
+func TestAllowsFollowsPreferences(t *testing.T) {
+	pref := models.DefaultNotificationPreference(1)
+	for _, k := range []Kind{KindPayment, KindProfit, KindWithdrawal} {
+		if !Allows(pref, k) {
+			t.Fatalf("default preference should allow %s", k)
+		}
+	}
+	pref.Profit = false
+	if Allows(pref, KindProfit) || !Allows(pref, KindPayment) {
+		t.Fatalf("profit opt-out should only block profit pushes")
+	}
+}
+
+func TestRenderUsesLocale(t *testing.T) {
+	e := PaymentSuccess(7, "INV-1", 150000)
+	id := Render(e, i18n.ID, []string{"a", "b"})
+	en := Render(e, i18n.EN, []string{"a"})
+	if len(id) != 2 || id[1].Token != "b" || id[0].Data["order_id"] != "INV-1" {
+		t.Fatalf("unexpected messages %+v", id)
+	}
+	if !strings.Contains(id[0].Body, "Rp150000") || id[0].Title == en[0].Title {
+		t.Fatalf("expected localized text, got %q / %q", id[0].Title, en[0].Title)
+	}
+}
+
+func TestWithdrawalStatusPicksMessage(t *testing.T) {
+	cases := map[string]string{
+		"Success": i18n.MsgPushWithdrawalSuccessTitle,
+		"Failed":  i18n.MsgPushWithdrawalFailedTitle,
+		"Pending": i18n.MsgPushWithdrawalRetryTitle,
+	}
+	for status, want := range cases {
+		if got := WithdrawalStatus(1, "WD-1", status, 1000).TitleKey; got != want {
+			t.Fatalf("%s: expected %s, got %s", status, want, got)
+		}
+	}
+}
+
+func TestNilNotifierIsNoop(t *testing.T) {
+	var n *Notifier
+	n.Enqueue(PaymentSuccess(1, "INV-1", 1))
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTicketStatusPicksMessageAndIgnoresPreferences(t *testing.T) {
+	if got := TicketStatus(1, 9, "Deposit", "Closed").TitleKey; got != i18n.MsgPushTicketClosedTitle {
+		t.Fatalf("expected closed title, got %s", got)
+	}
+	e := TicketStatus(1, 9, "Deposit", "Answered")
+	if e.TitleKey != i18n.MsgPushTicketAnsweredTitle || e.Data["ticket_id"] != "9" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	pref := models.NotificationPreference{UserID: 1}
+	if !Allows(pref, KindSupport) {
+		t.Fatal("support replies should not be opt-out")
+	}
+	if n := Inbox(e, i18n.EN, "support_ticket"); n.UserID != 1 || !strings.Contains(n.Body, "#9") {
+		t.Fatalf("unexpected inbox row %+v", n)
+	}
+}
@@ -0,0 +1,276 @@
+// Package outbox carries out the side effects of a state change (bonuses,
+// pushes, alerts) outside the transaction that confirms it.
+//
+// The change records its effects as outbox_events rows in its own
+// transaction, so they exist exactly when it commits. A Dispatcher then runs
+// each event's handler, right after the commit for the events just recorded
+// and from the outbox cron for anything left over. A failing handler never
+// undoes the change: its event is retried with backoff and, after
+// MaxAttempts, marked Failed and alerted.
+//
+// A handler runs in one transaction with the locked event row and the update
+// marking it Done, so its database writes happen exactly once per event.
+// Handlers that hand work to something outside the database (pushes,
+// Telegram) do so without blocking; the hand-off can repeat only if the
+// commit after it fails.
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"project/alert"
+	"project/models"
+	"project/notify"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Built-in kinds, handled by every Dispatcher.
+const (
+	KindPush  = "push"
+	KindAlert = "alert"
+)
+
+// MaxAttempts is how many times a handler runs before its event is Failed.
+const MaxAttempts = 10
+
+// Handler carries out e inside tx. Returning an error rolls back its writes
+// and schedules a retry.
+type Handler func(tx *gorm.DB, e *models.OutboxEvent) error
+
+// Add records an event of kind for key with payload encoded as JSON. It must
+// run inside the transaction of the change causing it. It returns the new
+// event's id, or 0 when an event of the same kind and key already exists.
+func Add(tx *gorm.DB, kind, key string, payload interface{}) (uint, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	e := models.OutboxEvent{Kind: kind, Key: key, Payload: string(body), Status: models.OutboxPending, NextAttemptAt: time.Now()}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&e)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return 0, res.Error
+	}
+	return e.ID, nil
+}
+
+// Batch collects the events recorded in one transaction so the caller can
+// dispatch them once it commits.
+type Batch []uint
+
+// Add records an event like the package-level Add and keeps its id.
+func (b *Batch) Add(tx *gorm.DB, kind, key string, payload interface{}) error {
+	id, err := Add(tx, kind, key, payload)
+	if err == nil && id != 0 {
+		*b = append(*b, id)
+	}
+	return err
+}
+
+// Push records a push notification; key is usually the push type and order.
+func (b *Batch) Push(tx *gorm.DB, key string, e notify.Event) error {
+	return b.Add(tx, KindPush, key, pushPayload{UserID: e.UserID, Kind: e.Kind, TitleKey: e.TitleKey, BodyKey: e.BodyKey, Args: e.Args, Data: e.Data})
+}
+
+// Alert records an ops alert under alertKey, formatted now.
+func (b *Batch) Alert(tx *gorm.DB, key, alertKey, format string, args ...interface{}) error {
+	return b.Add(tx, KindAlert, key, alertPayload{Key: alertKey, Text: fmt.Sprintf(format, args...)})
+}
+
+type pushPayload struct {
+	UserID   uint              `json:"user_id"`
+	Kind     notify.Kind       `json:"kind"`
+	TitleKey string            `json:"title_key"`
+	BodyKey  string            `json:"body_key"`
+	Args     []interface{}     `json:"args,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+type alertPayload struct {
+	Key  string `json:"key"`
+	Text string `json:"text"`
+}
+
+// Decode unmarshals the payload of e into v.
+func Decode(e *models.OutboxEvent, v interface{}) error {
+	return json.Unmarshal([]byte(e.Payload), v)
+}
+
+// retryDelay is the wait before attempt n+1: 30s doubling up to an hour.
+func retryDelay(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// Dispatcher runs outbox events through the handler registered for their kind.
+type Dispatcher struct {
+	DB *gorm.DB
+	// Notifier receives KindPush events; nil drops them
+	Notifier *notify.Notifier
+	// Alerts receives KindAlert events and events that fail for good; nil only logs
+	Alerts *alert.Alerter
+
+	handlers map[string]Handler
+}
+
+// NewDispatcher returns a Dispatcher with the built-in kinds registered.
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	d := &Dispatcher{DB: db, handlers: map[string]Handler{}}
+	d.Handle(KindPush, d.push)
+	d.Handle(KindAlert, d.alert)
+	return d
+}
+
+// Handle registers h for kind, replacing any earlier handler.
+func (d *Dispatcher) Handle(kind string, h Handler) {
+	d.handlers[kind] = h
+}
+
+// Result counts the outcome of a Run.
+type Result struct {
+	Processed int `json:"processed"`
+	// Retrying failed this time and will run again
+	Retrying int `json:"retrying"`
+	// Failed used up its attempts
+	Failed int `json:"failed"`
+}
+
+// errNotPending marks an event another dispatcher finished first.
+var errNotPending = errors.New("outbox event no longer pending")
+
+// Dispatch runs the events of b now. Failures are recorded on the events for
+// the cron to retry, so callers have nothing to handle. A nil Dispatcher
+// leaves the events to the cron.
+func (d *Dispatcher) Dispatch(b Batch) {
+	if d == nil {
+		return
+	}
+	for _, id := range b {
+		d.process(id)
+	}
+}
+
+// Run processes up to limit due Pending events, oldest first, stopping early
+// when stop reports true.
+func (d *Dispatcher) Run(limit int, stop func() bool) (Result, error) {
+	var res Result
+	var ids []uint
+	if err := d.DB.Model(&models.OutboxEvent{}).
+		Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, time.Now()).
+		Order("id ASC").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return res, err
+	}
+	for _, id := range ids {
+		if stop != nil && stop() {
+			break
+		}
+		switch d.process(id) {
+		case models.OutboxDone:
+			res.Processed++
+		case models.OutboxPending:
+			res.Retrying++
+		case models.OutboxFailed:
+			res.Failed++
+		}
+	}
+	return res, nil
+}
+
+// process runs one event and returns its status afterwards, or "" when it
+// was not pending.
+func (d *Dispatcher) process(id uint) string {
+	var e models.OutboxEvent
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&e, id).Error; err != nil {
+			return err
+		}
+		if e.Status != models.OutboxPending {
+			return errNotPending
+		}
+		h, ok := d.handlers[e.Kind]
+		if !ok {
+			return fmt.Errorf("no handler for outbox kind %q", e.Kind)
+		}
+		if err := h(tx, &e); err != nil {
+			return err
+		}
+		return tx.Model(&e).Updates(map[string]interface{}{"status": models.OutboxDone, "attempts": e.Attempts + 1, "processed_at": time.Now(), "last_error": nil}).Error
+	})
+	if errors.Is(err, errNotPending) {
+		return ""
+	}
+	if err == nil {
+		return models.OutboxDone
+	}
+	return d.fail(id, err)
+}
+
+// fail records a failed attempt of event id and returns its new status.
+func (d *Dispatcher) fail(id uint, cause error) string {
+	var e models.OutboxEvent
+	if err := d.DB.First(&e, id).Error; err != nil {
+		utils.Logger.Error("outbox: load failed event", "event_id", id, "error", err.Error(), "cause", cause.Error())
+		return models.OutboxPending
+	}
+	if e.Status != models.OutboxPending {
+		return ""
+	}
+	attempts := e.Attempts + 1
+	msg := cause.Error()
+	updates := map[string]interface{}{"attempts": attempts, "last_error": msg, "next_attempt_at": time.Now().Add(retryDelay(attempts))}
+	status := models.OutboxPending
+	if attempts >= MaxAttempts {
+		status = models.OutboxFailed
+		updates["status"] = status
+	}
+	if err := d.DB.Model(&models.OutboxEvent{}).Where("id = ? AND status = ?", id, models.OutboxPending).Updates(updates).Error; err != nil {
+		utils.Logger.Error("outbox: record failure", "event_id", id, "error", err.Error())
+	}
+	utils.Logger.Error("outbox event failed", "event_id", id, "kind", e.Kind, "key", e.Key, "attempts", attempts, "error", msg)
+	if status == models.OutboxFailed {
+		d.Alerts.Notify(alert.KeyOutboxFailed, "Outbox %s (%s) gagal %d kali: %s", e.Kind, e.Key, attempts, msg)
+	}
+	return status
+}
+
+func (d *Dispatcher) push(_ *gorm.DB, e *models.OutboxEvent) error {
+	var p pushPayload
+	dec := json.NewDecoder(strings.NewReader(e.Payload))
+	dec.UseNumber()
+	if err := dec.Decode(&p); err != nil {
+		return err
+	}
+	// Amounts are formatted with %d, so numbers come back as int64
+	for i, a := range p.Args {
+		if n, ok := a.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				p.Args[i] = v
+			} else if f, err := n.Float64(); err == nil {
+				p.Args[i] = f
+			}
+		}
+	}
+	d.Notifier.Enqueue(notify.Event{UserID: p.UserID, Kind: p.Kind, TitleKey: p.TitleKey, BodyKey: p.BodyKey, Args: p.Args, Data: p.Data})
+	return nil
+}
+
+func (d *Dispatcher) alert(_ *gorm.DB, e *models.OutboxEvent) error {
+	var p alertPayload
+	if err := Decode(e, &p); err != nil {
+		return err
+	}
+	d.Alerts.Notify(p.Key, "%s", p.Text)
+	return nil
+}
@@ -0,0 +1,57 @@
+// Package pubsub wakes requests waiting on a key, such as a payment page
+// long-polling its order id, when another goroutine publishes to that key.
+// It is in-process only: a waiter on another instance is not woken and falls
+// back to reading the current state when its wait ends.
+package pubsub
+
+import "sync"
+
+// Hub is a set of waiters by key. The zero value is not usable; use New.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+func New() *Hub {
+	return &Hub{subs: map[string]map[chan struct{}]struct{}{}}
+}
+
+// Subscribe returns a channel that receives once key is published to after
+// this call, and a cancel func that must be called when done waiting.
+func (h *Hub) Subscribe(key string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.subs[key] == nil {
+		h.subs[key] = map[chan struct{}]struct{}{}
+	}
+	h.subs[key][ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[key], ch)
+		if len(h.subs[key]) == 0 {
+			delete(h.subs, key)
+		}
+	}
+}
+
+// Publish wakes every current subscriber of key. It never blocks; a waiter
+// already woken stays woken once.
+func (h *Hub) Publish(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Waiting returns how many subscribers key has.
+func (h *Hub) Waiting(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[key])
+}
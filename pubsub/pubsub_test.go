@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishWakesSubscribersOfTheKey(t *testing.T) {
+	h := New()
+	a, cancelA := h.Subscribe("INV-1")
+	b, cancelB := h.Subscribe("INV-1")
+	other, cancelOther := h.Subscribe("INV-2")
+	defer cancelOther()
+
+	h.Publish("INV-1")
+	h.Publish("INV-1") // never blocks on a waiter already woken
+	for name, ch := range map[string]<-chan struct{}{"a": a, "b": b} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("subscriber %s not woken", name)
+		}
+	}
+	select {
+	case <-other:
+		t.Fatal("subscriber of another key woken")
+	default:
+	}
+
+	cancelA()
+	cancelB()
+	if n := h.Waiting("INV-1"); n != 0 {
+		t.Fatalf("expected no waiters after cancel, got %d", n)
+	}
+	h.Publish("INV-1")
+}
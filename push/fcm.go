@@ -0,0 +1,305 @@
+// Package push delivers mobile push notifications through Firebase Cloud
+// Messaging (HTTP v1 API).
+//
+// Callers depend on the Sender interface so tests can swap in a stub or an
+// FCMClient pointed at an httptest server.
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultBaseURL is the FCM HTTP v1 endpoint root.
+const DefaultBaseURL = "https://fcm.googleapis.com/v1"
+
+// DefaultTokenURL is used when the service account file has no token_uri.
+const DefaultTokenURL = "https://oauth2.googleapis.com/token"
+
+// messagingScope is the OAuth2 scope required to send FCM messages.
+const messagingScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// MaxConcurrency is how many messages of one batch are sent in parallel.
+const MaxConcurrency = 8
+
+// ErrNotConfigured is returned when no service account is configured.
+var ErrNotConfigured = errors.New("push: FCM service account not configured")
+
+// Message is one notification for one device token.
+type Message struct {
+	Token string
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Result summarises a batch. InvalidTokens are tokens FCM reported as no
+// longer registered; callers should forget them.
+type Result struct {
+	Sent          int
+	Failed        int
+	InvalidTokens []string
+}
+
+// Sender delivers a batch of messages.
+type Sender interface {
+	Send(ctx context.Context, msgs []Message) (Result, error)
+}
+
+// FCMClient is the Sender backed by the FCM HTTP v1 API. It signs a service
+// account JWT to obtain an OAuth2 access token, which is cached until shortly
+// before it expires.
+type FCMClient struct {
+	BaseURL     string
+	TokenURL    string
+	ProjectID   string
+	ClientEmail string
+	PrivateKey  *rsa.PrivateKey
+
+	HTTP *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// serviceAccount is the subset of a Google service account key file we use.
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewFromEnv builds an FCMClient from the key file at FCM_SERVICE_ACCOUNT_FILE.
+// FCM_PROJECT_ID overrides the project in the file. When the file is not set
+// the client is returned unconfigured and Send returns ErrNotConfigured.
+func NewFromEnv() (*FCMClient, error) {
+	c := &FCMClient{
+		BaseURL:  DefaultBaseURL,
+		TokenURL: DefaultTokenURL,
+		HTTP:     &http.Client{Timeout: 15 * time.Second},
+	}
+	path := strings.TrimSpace(os.Getenv("FCM_SERVICE_ACCOUNT_FILE"))
+	if path == "" {
+		return c, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("push: read service account: %w", err)
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(raw, &sa); err != nil {
+		return c, fmt.Errorf("push: parse service account: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		return c, fmt.Errorf("push: parse service account key: %w", err)
+	}
+	c.ProjectID = sa.ProjectID
+	if p := strings.TrimSpace(os.Getenv("FCM_PROJECT_ID")); p != "" {
+		c.ProjectID = p
+	}
+	c.ClientEmail = sa.ClientEmail
+	c.PrivateKey = key
+	if sa.TokenURI != "" {
+		c.TokenURL = sa.TokenURI
+	}
+	return c, nil
+}
+
+// Configured reports whether the client has credentials to send with.
+func (c *FCMClient) Configured() bool {
+	return c != nil && c.ProjectID != "" && c.ClientEmail != "" && c.PrivateKey != nil
+}
+
+// Send delivers msgs with at most MaxConcurrency requests in flight. A failure
+// for one token does not stop the others; the returned error is only set when
+// no message could be attempted (for example, no access token).
+func (c *FCMClient) Send(ctx context.Context, msgs []Message) (Result, error) {
+	var res Result
+	if len(msgs) == 0 {
+		return res, nil
+	}
+	if !c.Configured() {
+		return res, ErrNotConfigured
+	}
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, MaxConcurrency)
+	)
+	for _, m := range msgs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(m Message) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := c.sendOne(ctx, token, m)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				res.Sent++
+			case errors.Is(err, errInvalidToken):
+				res.Failed++
+				res.InvalidTokens = append(res.InvalidTokens, m.Token)
+			default:
+				res.Failed++
+			}
+		}(m)
+	}
+	wg.Wait()
+	return res, nil
+}
+
+// errInvalidToken marks a token FCM will never deliver to again.
+var errInvalidToken = errors.New("push: invalid registration token")
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (c *FCMClient) sendOne(ctx context.Context, token string, m Message) error {
+	body, _ := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        m.Token,
+		Notification: fcmNotification{Title: m.Title, Body: m.Body},
+		Data:         m.Data,
+	}})
+	endpoint := strings.TrimRight(c.BaseURL, "/") + "/projects/" + url.PathEscape(c.ProjectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if tokenRejected(resp.StatusCode, raw) {
+		return errInvalidToken
+	}
+	return fmt.Errorf("push: fcm status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
+
+// tokenRejected reports whether an FCM error means the token itself is bad:
+// UNREGISTERED (app uninstalled, token rotated) or a malformed token.
+func tokenRejected(status int, raw []byte) bool {
+	if status == http.StatusNotFound {
+		return true
+	}
+	var e fcmError
+	if json.Unmarshal(raw, &e) != nil {
+		return false
+	}
+	for _, d := range e.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return true
+		}
+	}
+	return e.Error.Status == "INVALID_ARGUMENT" && strings.Contains(strings.ToLower(e.Error.Message), "registration token")
+}
+
+// accessToken returns the cached OAuth2 token, refreshing it when it is
+// missing or expires within a minute.
+func (c *FCMClient) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Until(c.tokenExpiry) > time.Minute {
+		return c.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   c.ClientEmail,
+		"scope": messagingScope,
+		"aud":   c.TokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(c.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("push: sign assertion: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("push: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("push: token request status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.AccessToken == "" {
+		return "", fmt.Errorf("push: token response invalid: %v", err)
+	}
+	c.token = out.AccessToken
+	c.tokenExpiry = now.Add(time.Duration(out.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+func (c *FCMClient) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,96 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// stubFCM answers the OAuth token endpoint and messages:send. Tokens starting
+// with "gone" are reported UNREGISTERED, "bad" fails with a server error.
+type stubFCM struct {
+	tokenCalls int32
+	sent       int32
+}
+
+func (s *stubFCM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		atomic.AddInt32(&s.tokenCalls, 1)
+		if err := r.ParseForm(); err != nil || r.Form.Get("assertion") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"ya29.test","expires_in":3600}`))
+		return
+	}
+	if r.URL.Path != "/projects/demo/messages:send" || r.Header.Get("Authorization") != "Bearer ya29.test" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var body fcmRequest
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	switch {
+	case strings.HasPrefix(body.Message.Token, "gone"):
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+	case strings.HasPrefix(body.Message.Token, "bad"):
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		atomic.AddInt32(&s.sent, 1)
+		_, _ = w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+	}
+}
+
+func newStubClient(t *testing.T, s *stubFCM) *FCMClient {
+	t.Helper()
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &FCMClient{BaseURL: srv.URL, TokenURL: srv.URL + "/token", ProjectID: "demo", ClientEmail: "push@demo.iam.gserviceaccount.com", PrivateKey: key, HTTP: srv.Client()}
+}
+
+func TestSendReportsInvalidTokens(t *testing.T) {
+	s := &stubFCM{}
+	c := newStubClient(t, s)
+
+	msgs := []Message{{Token: "ok-1"}, {Token: "gone-1"}, {Token: "ok-2"}, {Token: "bad-1"}}
+	res, err := c.Send(context.Background(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Sent != 2 || res.Failed != 2 {
+		t.Fatalf("expected 2 sent and 2 failed, got %+v", res)
+	}
+	if len(res.InvalidTokens) != 1 || res.InvalidTokens[0] != "gone-1" {
+		t.Fatalf("expected only gone-1 to be invalid, got %v", res.InvalidTokens)
+	}
+}
+
+func TestAccessTokenIsCached(t *testing.T) {
+	s := &stubFCM{}
+	c := newStubClient(t, s)
+	for i := 0; i < 3; i++ {
+		if _, err := c.Send(context.Background(), []Message{{Token: "ok"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.tokenCalls != 1 || s.sent != 3 {
+		t.Fatalf("expected 1 token call and 3 sends, got %d and %d", s.tokenCalls, s.sent)
+	}
+}
+
+func TestSendWithoutCredentials(t *testing.T) {
+	c := &FCMClient{}
+	if _, err := c.Send(context.Background(), []Message{{Token: "ok"}}); err != ErrNotConfigured {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package referral
+
+import (
+	"fmt"
+	"strconv"
+
+	"project/models"
+	"project/money"
+
+	"gorm.io/gorm"
+)
+
+// Bonus is the referral bonus an activated investment pays its direct
+// referrer, with the rate that priced it.
+type Bonus struct {
+	Amount  int64
+	Percent float64
+	// Repeat is set when the investor had a Success investment before.
+	Repeat bool
+	// Capped is set when ReferralBonusCap cut the amount.
+	Capped bool
+}
+
+// Message is the bonus transaction message, naming the rate that applied.
+func (b Bonus) Message() string {
+	kind := "pembelian pertama"
+	if b.Repeat {
+		kind = "pembelian ulang"
+	}
+	msg := fmt.Sprintf("Bonus rekomendasi investor (%s %s%%", kind, strconv.FormatFloat(b.Percent, 'f', -1, 64))
+	if b.Capped {
+		msg += ", batas per downline"
+	}
+	return msg + ")"
+}
+
+// ComputeBonus prices the bonus referrerID earns for inv: the first-purchase
+// percent when the investor has no other Success investment transaction, the
+// repeat percent otherwise, reduced to what is left of the per-downline cap.
+// Bonuses of the same downline that were held or paid and not clawed back
+// count towards the cap. It must run inside the activating transaction.
+func ComputeBonus(tx *gorm.DB, s models.Setting, referrerID uint, inv *models.Investment) (Bonus, error) {
+	var prior int64
+	if err := tx.Model(&models.Transaction{}).
+		Where("user_id = ? AND transaction_type = ? AND status = ? AND order_id <> ?", inv.UserID, "investment", "Success", inv.OrderID).
+		Count(&prior).Error; err != nil {
+		return Bonus{}, err
+	}
+	b := Bonus{Percent: s.ReferralBonusPercent, Repeat: prior > 0}
+	if b.Repeat {
+		b.Percent = s.ReferralRepeatPercent
+	}
+	b.Amount = money.Percent(inv.Amount, b.Percent)
+	if s.ReferralBonusCap <= 0 || b.Amount <= 0 {
+		return b, nil
+	}
+
+	var earned int64
+	if err := tx.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(transactions.amount), 0)").
+		Joins("JOIN investments i ON i.id = transactions.investment_id").
+		Where("transactions.user_id = ? AND transactions.transaction_type = ? AND transactions.status IN ? AND i.user_id = ?",
+			referrerID, "team", []string{"Success", "Held"}, inv.UserID).
+		Where("NOT EXISTS (SELECT 1 FROM transactions c WHERE c.source_order_id = transactions.order_id AND c.transaction_type = ?)", "referral_clawback").
+		Scan(&earned).Error; err != nil {
+		return Bonus{}, err
+	}
+	if left := max(s.ReferralBonusCap-earned, 0); b.Amount > left {
+		b.Amount, b.Capped = left, true
+	}
+	return b, nil
+}
@@ -0,0 +1,111 @@
+// Package referral prices and guards the referral bonus: it applies the
+// first- and repeat-purchase rates, rejects self-referrals and referral
+// cycles, flags bonuses between accounts that look like one person, and
+// reverses bonuses whose funding payment did not hold.
+package referral
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Clawback policies: what to do when the referrer's balance is short.
+const (
+	// PolicyNegative debits the whole bonus, even into a negative balance.
+	PolicyNegative = "negative"
+	// PolicyPartial debits at most the current balance and writes off the rest.
+	PolicyPartial = "partial"
+)
+
+// Policy reads REFERRAL_CLAWBACK_POLICY, defaulting to PolicyNegative.
+func Policy() string {
+	if strings.TrimSpace(os.Getenv("REFERRAL_CLAWBACK_POLICY")) == PolicyPartial {
+		return PolicyPartial
+	}
+	return PolicyNegative
+}
+
+// Result sums one Clawback call.
+type Result struct {
+	// Bonus is the referral bonus not reversed before this call.
+	Bonus int64 `json:"bonus"`
+	// Recovered is what was debited from referrers now.
+	Recovered int64 `json:"recovered"`
+}
+
+// Clawback reverses the Success referral bonuses funded by sourceOrderID,
+// debiting each referrer and recording a referral_clawback transaction whose
+// source_order_id is the bonus's order id. Held bonuses were never paid and
+// are just failed. Bonuses reversed before are skipped, so repeated calls are
+// harmless. It must run inside tx.
+func Clawback(tx *gorm.DB, sourceOrderID, policy, reason string) (Result, error) {
+	var res Result
+	if err := tx.Model(&models.Transaction{}).
+		Where("source_order_id = ? AND transaction_type = ? AND status = ?", sourceOrderID, "team", "Held").
+		Update("status", "Failed").Error; err != nil {
+		return res, err
+	}
+	var bonuses []models.Transaction
+	if err := tx.Where("source_order_id = ? AND transaction_type = ? AND status = ?", sourceOrderID, "team", "Success").
+		Find(&bonuses).Error; err != nil {
+		return res, err
+	}
+
+	for _, b := range bonuses {
+		var done int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("source_order_id = ? AND transaction_type = ?", b.OrderID, "referral_clawback").
+			Count(&done).Error; err != nil {
+			return res, err
+		}
+		if done > 0 {
+			continue
+		}
+
+		var referrer models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, balance").First(&referrer, b.UserID).Error; err != nil {
+			return res, err
+		}
+		amount := b.Amount
+		if policy == PolicyPartial {
+			amount = min(amount, max(referrer.Balance, 0))
+		}
+		if amount > 0 {
+			if err := tx.Model(&models.User{}).Where("id = ?", b.UserID).UpdateColumn("balance", gorm.Expr("balance - ?", amount)).Error; err != nil {
+				return res, err
+			}
+		}
+
+		// Recorded even when nothing was recovered, so the bonus is not
+		// reversed twice
+		msg := fmt.Sprintf("Pembatalan bonus rekomendasi %s: %s", sourceOrderID, reason)
+		if amount < b.Amount {
+			msg += fmt.Sprintf(" (tertagih Rp%d dari Rp%d)", amount, b.Amount)
+		}
+		bonusOrderID := b.OrderID
+		if err := tx.Create(&models.Transaction{
+			UserID:          b.UserID,
+			InvestmentID:    b.InvestmentID,
+			SourceOrderID:   &bonusOrderID,
+			Amount:          amount,
+			Charge:          0,
+			OrderID:         utils.GenerateOrderID(utils.OrderAdjustment, b.UserID),
+			TransactionFlow: "credit",
+			TransactionType: "referral_clawback",
+			Message:         &msg,
+			Status:          "Success",
+		}).Error; err != nil {
+			return res, err
+		}
+		res.Bonus += b.Amount
+		res.Recovered += amount
+	}
+	return res, nil
+}
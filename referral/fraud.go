@@ -0,0 +1,109 @@
+package referral
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+)
+
+// maxDepth stops the upline walk on chains longer than any real one.
+const maxDepth = 100
+
+var (
+	// ErrSelfReferral is returned when a user would refer themselves.
+	ErrSelfReferral = errors.New("user cannot refer themselves")
+	// ErrReferralCycle is returned when the referrer is the user's own
+	// descendant, or the referrer's upline already loops.
+	ErrReferralCycle = errors.New("referral chain would form a cycle")
+)
+
+// ValidateReferrer checks that referrerID may become the reff_by of userID by
+// walking the referrer's upline. Pass userID 0 for an account not created
+// yet, which only checks that the upline is sound.
+func ValidateReferrer(db *gorm.DB, userID, referrerID uint) error {
+	if userID != 0 && userID == referrerID {
+		return ErrSelfReferral
+	}
+	seen := map[uint]bool{}
+	id := referrerID
+	for depth := 0; depth < maxDepth; depth++ {
+		if id == userID || seen[id] {
+			return ErrReferralCycle
+		}
+		seen[id] = true
+		var u models.User
+		if err := db.Select("id, reff_by").First(&u, id).Error; err != nil {
+			return err
+		}
+		if u.ReffBy == nil {
+			return nil
+		}
+		id = *u.ReffBy
+	}
+	return ErrReferralCycle
+}
+
+// Fraud signals: why a referral bonus is held for review.
+const (
+	SignalSameDevice      = "same_device"
+	SignalSameBankAccount = "same_bank_account"
+	SignalSameIP          = "same_ip"
+)
+
+// DefaultIPWindow is used when REFERRAL_FRAUD_IP_WINDOW_HOURS is not set.
+const DefaultIPWindow = 24 * time.Hour
+
+// IPWindow reads REFERRAL_FRAUD_IP_WINDOW_HOURS: how close in time two
+// accounts must have used the same IP to count as one person.
+func IPWindow() time.Duration {
+	if h, err := strconv.Atoi(strings.TrimSpace(os.Getenv("REFERRAL_FRAUD_IP_WINDOW_HOURS"))); err == nil && h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return DefaultIPWindow
+}
+
+// Signals lists why referee and referrer look like the same person: a shared
+// device fingerprint, a shared bank account, or register/login events from
+// one IP within window of each other. An empty result means none was found.
+func Signals(db *gorm.DB, refereeID, referrerID uint, window time.Duration) ([]string, error) {
+	var signals []string
+
+	var n int64
+	if err := db.Table("user_signals AS a").
+		Joins("JOIN user_signals AS b ON b.device_fingerprint = a.device_fingerprint").
+		Where("a.user_id = ? AND b.user_id = ? AND a.device_fingerprint IS NOT NULL", refereeID, referrerID).
+		Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		signals = append(signals, SignalSameDevice)
+	}
+
+	if err := db.Table("bank_accounts AS a").
+		Joins("JOIN bank_accounts AS b ON b.bank_id = a.bank_id AND b.account_number = a.account_number").
+		Where("a.user_id = ? AND b.user_id = ?", refereeID, referrerID).
+		Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		signals = append(signals, SignalSameBankAccount)
+	}
+
+	if err := db.Table("user_signals AS a").
+		Joins("JOIN user_signals AS b ON b.ip = a.ip").
+		Where("a.user_id = ? AND b.user_id = ?", refereeID, referrerID).
+		Where("ABS(TIMESTAMPDIFF(SECOND, a.created_at, b.created_at)) <= ?", int64(window/time.Second)).
+		Count(&n).Error; err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		signals = append(signals, SignalSameIP)
+	}
+	return signals, nil
+}
@@ -0,0 +1,44 @@
+package referral
+
+import (
+	"time"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SpinTickets returns how many spin tickets referrerID earns for a direct
+// referral's purchase of amount: none below SpinTicketMinAmount, otherwise
+// SpinTicketsPerPurchase cut to what is left of SpinTicketDailyCap for today
+// in APP_TIMEZONE. With a cap it locks the referrer, so it must run inside
+// the transaction that grants the tickets.
+func SpinTickets(tx *gorm.DB, s models.Setting, referrerID uint, amount int64) (uint, error) {
+	if amount < s.SpinTicketMinAmount || s.SpinTicketsPerPurchase == 0 {
+		return 0, nil
+	}
+	if s.SpinTicketDailyCap == 0 {
+		return s.SpinTicketsPerPurchase, nil
+	}
+
+	// Concurrent purchases of one referrer's team must not both fit the cap
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, referrerID).Error; err != nil {
+		return 0, err
+	}
+	now := time.Now().In(utils.AppLocation())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var granted int64
+	if err := tx.Model(&models.TicketGrant{}).
+		Select("COALESCE(SUM(tickets), 0)").
+		Where("user_id = ? AND source = ? AND created_at >= ?", referrerID, models.TicketSourceReferral, today.UTC()).
+		Scan(&granted).Error; err != nil {
+		return 0, err
+	}
+	left := int64(s.SpinTicketDailyCap) - granted
+	if left <= 0 {
+		return 0, nil
+	}
+	return uint(min(int64(s.SpinTicketsPerPurchase), left)), nil
+}
@@ -0,0 +1,86 @@
+package risk
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// nameTitles are honorifics banks and users add to names; they are dropped
+// before comparing.
+var nameTitles = map[string]bool{
+	"BPK": true, "BAPAK": true, "IBU": true, "SDR": true, "SDRI": true,
+	"TN": true, "NY": true, "NN": true, "MR": true, "MRS": true, "MS": true,
+	"HJ": true,
+}
+
+// nameTokens upper-cases name, keeps letters only and drops titles.
+func nameTokens(name string) []string {
+	fields := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool { return !unicode.IsLetter(r) })
+	out := fields[:0]
+	for _, f := range fields {
+		if !nameTitles[f] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// NameMatchScore scores from 0 to 100 how alike two person names are, after
+// dropping case, punctuation, titles and word order. A name whose words, two
+// or more, all appear in the other scores 100, since banks often keep only
+// part of a long name. Otherwise the score is the edit distance similarity
+// of the sorted words.
+func NameMatchScore(a, b string) int {
+	ta, tb := nameTokens(a), nameTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	short, long := ta, tb
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	if len(short) >= 2 {
+		words := map[string]bool{}
+		for _, w := range long {
+			words[w] = true
+		}
+		all := true
+		for _, w := range short {
+			all = all && words[w]
+		}
+		if all {
+			return 100
+		}
+	}
+
+	sort.Strings(ta)
+	sort.Strings(tb)
+	sa, sb := []rune(strings.Join(ta, " ")), []rune(strings.Join(tb, " "))
+	longest := len(sa)
+	if len(sb) > longest {
+		longest = len(sb)
+	}
+	return 100 - levenshtein(sa, sb)*100/longest
+}
+
+// levenshtein is the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
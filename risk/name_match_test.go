@@ -0,0 +1,27 @@
+package risk
+
+import "testing"
+
+func TestNameMatchScore(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		min, max int
+	}{
+		{"Budi Santoso", "BUDI SANTOSO", 100, 100},
+		{"Budi Santoso", "SANTOSO, BUDI", 100, 100},
+		{"Ibu Siti Aminah", "SITI AMINAH", 100, 100},
+		// banks keep part of long names
+		{"Muhammad Rizky Pratama Putra", "MUHAMMAD RIZKY PRATAMA", 100, 100},
+		// a typo still matches closely
+		{"Dewi Lestari", "DEWI LESTARY", 90, 99},
+		{"Budi Santoso", "AGUS HARTONO", 0, 50},
+		// one shared first name is not enough
+		{"Budi", "BUDI HARTONO", 0, 60},
+		{"", "BUDI", 0, 0},
+	} {
+		got := NameMatchScore(tc.a, tc.b)
+		if got < tc.min || got > tc.max {
+			t.Errorf("NameMatchScore(%q, %q) = %d, want %d..%d", tc.a, tc.b, got, tc.min, tc.max)
+		}
+	}
+}
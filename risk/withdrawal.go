@@ -0,0 +1,101 @@
+// Package risk holds the rules that flag a withdrawal for closer review.
+package risk
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Withdrawal risk flags, stored comma-separated in withdrawals.risk_flags.
+const (
+	FlagSharedDevice      = "shared_device"
+	FlagSharedBankAccount = "shared_bank_account"
+	// FlagNameMismatch: the account holder the bank reports is not the user
+	FlagNameMismatch = "name_mismatch"
+)
+
+// holdFlags are the flags that put a withdrawal On Hold instead of Pending.
+var holdFlags = map[string]bool{
+	FlagSharedBankAccount: true,
+	FlagNameMismatch:      true,
+}
+
+// Holds reports whether any of flags puts the withdrawal On Hold.
+func Holds(flags []string) bool {
+	for _, f := range flags {
+		if holdFlags[f] {
+			return true
+		}
+	}
+	return false
+}
+
+// SharedDeviceThreshold reads WITHDRAWAL_RISK_SHARED_DEVICE_ACCOUNTS: a
+// withdrawal is flagged when one of the user's device fingerprints has been
+// seen on at least that many accounts, the user's own included. 0 or unset
+// disables the rule.
+func SharedDeviceThreshold() int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("WITHDRAWAL_RISK_SHARED_DEVICE_ACCOUNTS")), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// SharedDeviceAccounts returns the most accounts, userID's included, that
+// have registered or logged in from any one of userID's device fingerprints.
+// It is 0 when the user has never sent a fingerprint.
+func SharedDeviceAccounts(db *gorm.DB, userID uint) (int64, error) {
+	var counts []int64
+	if err := db.Table("user_signals AS a").
+		Joins("JOIN user_signals AS b ON b.device_fingerprint = a.device_fingerprint").
+		Where("a.user_id = ? AND a.device_fingerprint IS NOT NULL", userID).
+		Group("a.device_fingerprint").
+		Pluck("COUNT(DISTINCT b.user_id)", &counts).Error; err != nil {
+		return 0, err
+	}
+	var most int64
+	for _, n := range counts {
+		if n > most {
+			most = n
+		}
+	}
+	return most, nil
+}
+
+// SharedBankAccount reports whether another user has registered the bank
+// and normalized number of bank account accountID.
+func SharedBankAccount(db *gorm.DB, accountID uint) (bool, error) {
+	var n int64
+	err := db.Table("bank_accounts AS a").
+		Joins("JOIN bank_accounts AS b ON b.bank_id = a.bank_id AND b.account_number_normalized = a.account_number_normalized AND b.user_id <> a.user_id").
+		Where("a.id = ? AND a.account_number_normalized <> ''", accountID).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// WithdrawalFlags evaluates the withdrawal risk rules for userID paying out
+// to bank account accountID. An empty result means no rule fired.
+func WithdrawalFlags(db *gorm.DB, userID, accountID uint) ([]string, error) {
+	var flags []string
+	if threshold := SharedDeviceThreshold(); threshold > 0 {
+		n, err := SharedDeviceAccounts(db, userID)
+		if err != nil {
+			return nil, err
+		}
+		if n >= threshold {
+			flags = append(flags, FlagSharedDevice)
+		}
+	}
+	shared, err := SharedBankAccount(db, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		flags = append(flags, FlagSharedBankAccount)
+	}
+	return flags, nil
+}
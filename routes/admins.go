@@ -5,14 +5,18 @@ import (
 	"time"
 
 	"project/controllers/admins"
+	"project/controllers/users"
 	"project/middleware"
+	"project/models"
 
 	"github.com/gorilla/mux"
 )
 
-func SetAdminRoutes(api *mux.Router) {
+func SetAdminRoutes(api *mux.Router, investments *users.InvestmentHandler, withdrawals *admins.WithdrawalHandler, support *admins.SupportHandler, reports *admins.ReportHandler, webhooks *admins.WebhookHandler, monitor *admins.MonitorHandler, settlements *admins.SettlementExportHandler) {
 	// Rate limiter for admin login: 5 attempts per IP per minute
-	adminLoginLimiter := middleware.NewIPRateLimiter(5, time.Minute)
+	adminLoginLimiter := middleware.NewIPRateLimiter(5, time.Minute).Named("admin_login")
+	// Per-admin limit on CSV exports, adjustable in the settings
+	exportLimiter := middleware.NewRouteRateLimiter(middleware.SettingLimit(func(s models.Setting) int { return s.RateLimitExport }, 1), time.Minute).Named("export")
 
 	// Public admin routes
 	api.Handle("/admin/login", adminLoginLimiter.Middleware(http.HandlerFunc(admins.Login))).Methods(http.MethodPost)
@@ -22,7 +26,18 @@ func SetAdminRoutes(api *mux.Router) {
 	adminRouter.Use(middleware.AdminAuthMiddleware)
 
 	// Dashboard stats
-	adminRouter.Handle("/dashboard", http.HandlerFunc(admins.GetDashboardStats)).Methods(http.MethodGet)
+	adminRouter.Handle("/dashboard", http.HandlerFunc(reports.GetDashboardStats)).Methods(http.MethodGet)
+	// Stuck payments, withdrawals, investments and events, with their fixes
+	adminRouter.Handle("/monitor", http.HandlerFunc(monitor.Get)).Methods(http.MethodGet)
+
+	// Swagger UI for /v3/docs/openapi.json
+	adminRouter.Handle("/docs", http.HandlerFunc(admins.SwaggerUIHandler)).Methods(http.MethodGet)
+
+	// In-process metrics (rate limiter sizes, route timings)
+	adminRouter.Handle("/metrics", http.HandlerFunc(admins.GetMetrics)).Methods(http.MethodGet)
+
+	// Audit logs of admin changes (read-only; rows are append-only)
+	adminRouter.Handle("/audit-logs", http.HandlerFunc(admins.ListAuditLogs)).Methods(http.MethodGet)
 
 	// Admin info
 	adminRouter.Handle("/info", http.HandlerFunc(admins.GetAdminInfo)).Methods(http.MethodGet)
@@ -33,16 +48,54 @@ func SetAdminRoutes(api *mux.Router) {
 	adminRouter.Handle("/password", http.HandlerFunc(admins.UpdateAdminPassword)).Methods(http.MethodPut)
 
 	// User management
-	adminRouter.Handle("/users", http.HandlerFunc(admins.GetUsers)).Methods(http.MethodGet)
+	adminRouter.Handle("/users", http.HandlerFunc(reports.GetUsers)).Methods(http.MethodGet)
 	adminRouter.Handle("/users/{id:[0-9]+}", http.HandlerFunc(admins.GetUserDetail)).Methods(http.MethodGet)
 	adminRouter.Handle("/users/{id:[0-9]+}", http.HandlerFunc(admins.UpdateUser)).Methods(http.MethodPut)
 	adminRouter.Handle("/users/balance/{id:[0-9]+}", http.HandlerFunc(admins.UpdateUserBalance)).Methods(http.MethodPut)
 	adminRouter.Handle("/users/password/{id:[0-9]+}", http.HandlerFunc(admins.UpdateUserPassword)).Methods(http.MethodPut)
+	adminRouter.Handle("/users/{id:[0-9]+}/logout-all", http.HandlerFunc(admins.LogoutAllUser)).Methods(http.MethodPost)
+	adminRouter.Handle("/users/{id:[0-9]+}/referrer", http.HandlerFunc(admins.SetUserReferrer)).Methods(http.MethodPut)
+	adminRouter.Handle("/users/{id:[0-9]+}/devices", http.HandlerFunc(admins.GetUserDevices)).Methods(http.MethodGet)
+	adminRouter.Handle("/users/{id:[0-9]+}/linked-accounts", http.HandlerFunc(admins.GetLinkedAccounts)).Methods(http.MethodGet)
+	adminRouter.Handle("/users/{id:[0-9]+}/vip-history", http.HandlerFunc(admins.GetUserVIPHistory)).Methods(http.MethodGet)
+	adminRouter.Handle("/users/{id:[0-9]+}/vip-level/recalculate", http.HandlerFunc(admins.RecalculateUserVIPLevel)).Methods(http.MethodPost)
+	// Rebuilds total_invest(_vip) from the ledger; a dry run unless ?apply=true
+	adminRouter.Handle("/users/{id:[0-9]+}/recalculate", http.HandlerFunc(admins.RecalculateUserTotals)).Methods(http.MethodPost)
+
+	// Outbox events of payment side effects
+	adminRouter.Handle("/outbox-events", http.HandlerFunc(admins.ListOutboxEvents)).Methods(http.MethodGet)
+	adminRouter.Handle("/outbox-events/{id:[0-9]+}/retry", http.HandlerFunc(admins.RetryOutboxEvent)).Methods(http.MethodPost)
+	adminRouter.Handle("/cron-runs", http.HandlerFunc(admins.ListCronRuns)).Methods(http.MethodGet)
+	// Which integrations the environment configures, without their values
+	adminRouter.Handle("/config/check", http.HandlerFunc(admins.GetConfigCheck)).Methods(http.MethodGet)
+	// API keys for read-only integrations; see middleware.APIKeyRoutes
+	adminRouter.Handle("/api-keys", http.HandlerFunc(admins.ListAPIKeys)).Methods(http.MethodGet)
+	adminRouter.Handle("/api-keys", http.HandlerFunc(admins.CreateAPIKey)).Methods(http.MethodPost)
+	adminRouter.Handle("/api-keys/{id:[0-9]+}", http.HandlerFunc(admins.RevokeAPIKey)).Methods(http.MethodDelete)
+	// Outbound webhooks to downstream systems and their delivery log
+	adminRouter.Handle("/webhooks", http.HandlerFunc(admins.ListWebhookEndpoints)).Methods(http.MethodGet)
+	adminRouter.Handle("/webhooks", http.HandlerFunc(admins.CreateWebhookEndpoint)).Methods(http.MethodPost)
+	adminRouter.Handle("/webhooks/{id:[0-9]+}", http.HandlerFunc(admins.UpdateWebhookEndpoint)).Methods(http.MethodPut)
+	adminRouter.Handle("/webhooks/{id:[0-9]+}", http.HandlerFunc(admins.DeleteWebhookEndpoint)).Methods(http.MethodDelete)
+	adminRouter.Handle("/webhook-deliveries", http.HandlerFunc(admins.ListWebhookDeliveries)).Methods(http.MethodGet)
+	adminRouter.Handle("/webhook-deliveries/{id:[0-9]+}/redeliver", http.HandlerFunc(webhooks.Redeliver)).Methods(http.MethodPost)
+
+	// Referral bonuses held for fraud review
+	adminRouter.Handle("/referral-bonuses/held", http.HandlerFunc(admins.ListHeldReferralBonuses)).Methods(http.MethodGet)
+	adminRouter.Handle("/referral-bonuses/{id:[0-9]+}/release", http.HandlerFunc(admins.ReleaseReferralBonus)).Methods(http.MethodPost)
+	adminRouter.Handle("/referral-bonuses/{id:[0-9]+}/reject", http.HandlerFunc(admins.RejectReferralBonus)).Methods(http.MethodPost)
+
+	// Overpaid investment payments held until checked against the gateway
+	adminRouter.Handle("/overpayments/held", http.HandlerFunc(admins.ListHeldOverpayments)).Methods(http.MethodGet)
+	adminRouter.Handle("/overpayments/{id:[0-9]+}/release", http.HandlerFunc(admins.ReleaseOverpayment)).Methods(http.MethodPost)
+	adminRouter.Handle("/overpayments/{id:[0-9]+}/reject", http.HandlerFunc(admins.RejectOverpayment)).Methods(http.MethodPost)
 
 	// Investment management
-	adminRouter.Handle("/investments", http.HandlerFunc(admins.GetInvestments)).Methods(http.MethodGet)
+	adminRouter.Handle("/investments", http.HandlerFunc(reports.GetInvestments)).Methods(http.MethodGet)
+	adminRouter.Handle("/investments", http.HandlerFunc(investments.AdminCreate)).Methods(http.MethodPost)
 	adminRouter.Handle("/investments/{id:[0-9]+}", http.HandlerFunc(admins.GetInvestmentDetail)).Methods(http.MethodGet)
 	adminRouter.Handle("/investments/{id:[0-9]+}/status", http.HandlerFunc(admins.UpdateInvestmentStatus)).Methods(http.MethodPut)
+	adminRouter.Handle("/investments/{id:[0-9]+}/cancel", http.HandlerFunc(withdrawals.CancelInvestment)).Methods(http.MethodPost)
 
 	// Category management
 	adminRouter.Handle("/categories", http.HandlerFunc(admins.ListCategoriesHandler)).Methods(http.MethodGet)
@@ -56,31 +109,97 @@ func SetAdminRoutes(api *mux.Router) {
 	adminRouter.Handle("/products", http.HandlerFunc(admins.CreateProductHandler)).Methods(http.MethodPost)
 	adminRouter.Handle("/products/{id:[0-9]+}", http.HandlerFunc(admins.GetProductHandler)).Methods(http.MethodGet)
 	adminRouter.Handle("/products/{id:[0-9]+}", http.HandlerFunc(admins.UpdateProductHandler)).Methods(http.MethodPut)
-	adminRouter.Handle("/products/{id:[0-9]+}", http.HandlerFunc(admins.DeleteProductHandler)).Methods(http.MethodDelete)
+	adminRouter.Handle("/products/{id:[0-9]+}", http.HandlerFunc(admins.ArchiveProductHandler)).Methods(http.MethodDelete)
+	adminRouter.Handle("/products/{id:[0-9]+}/image", http.HandlerFunc(admins.UploadProductImageHandler)).Methods(http.MethodPost)
 
 	//Withdrawal management
-	adminRouter.Handle("/withdrawals", http.HandlerFunc(admins.GetWithdrawals)).Methods(http.MethodGet)
-	adminRouter.Handle("/withdrawals/{id:[0-9]+}/approve", http.HandlerFunc(admins.ApproveWithdrawal)).Methods(http.MethodPut)
-	adminRouter.Handle("/withdrawals/{id:[0-9]+}/reject", http.HandlerFunc(admins.RejectWithdrawal)).Methods(http.MethodPut)
+	adminRouter.Handle("/withdrawals", http.HandlerFunc(withdrawals.List)).Methods(http.MethodGet)
+	adminRouter.Handle("/withdrawals/export", exportLimiter.Middleware(http.HandlerFunc(withdrawals.Export))).Methods(http.MethodGet)
+	adminRouter.Handle("/withdrawals/{id:[0-9]+}/approve", http.HandlerFunc(withdrawals.Approve)).Methods(http.MethodPut)
+	adminRouter.Handle("/withdrawals/{id:[0-9]+}/reject", http.HandlerFunc(withdrawals.Reject)).Methods(http.MethodPut)
+	adminRouter.Handle("/withdrawals/{id:[0-9]+}/release", http.HandlerFunc(withdrawals.Release)).Methods(http.MethodPut)
+
+	// Bank accounts registered by more than one user
+	adminRouter.Handle("/bank-accounts/shared", http.HandlerFunc(admins.ListSharedBankAccounts)).Methods(http.MethodGet)
 
 	// Bank management
 	adminRouter.Handle("/banks", http.HandlerFunc(admins.GetBanks)).Methods(http.MethodGet)
 	adminRouter.Handle("/banks", http.HandlerFunc(admins.CreateBank)).Methods(http.MethodPost)
 	adminRouter.Handle("/banks/{id:[0-9]+}", http.HandlerFunc(admins.UpdateBank)).Methods(http.MethodPut)
+	adminRouter.Handle("/banks/{id:[0-9]+}/status", http.HandlerFunc(admins.UpdateBankStatus)).Methods(http.MethodPut)
 
 	// Bank accounts management
 	adminRouter.Handle("/bank-accounts", http.HandlerFunc(admins.GetBankAccounts)).Methods(http.MethodGet)
 
 	// Transaction management
-	adminRouter.Handle("/transactions", http.HandlerFunc(admins.GetTransactions)).Methods(http.MethodGet)
+	adminRouter.Handle("/transactions", http.HandlerFunc(reports.GetTransactions)).Methods(http.MethodGet)
+	adminRouter.Handle("/transactions/export", exportLimiter.Middleware(http.HandlerFunc(reports.ExportTransactions))).Methods(http.MethodGet)
+	// Which payment, purchase, withdrawal or ledger row an order id belongs to
+	adminRouter.Handle("/search", http.HandlerFunc(reports.Search)).Methods(http.MethodGet)
+	adminRouter.Handle("/transactions/fail-stale", http.HandlerFunc(admins.FailStaleTransactions)).Methods(http.MethodPost)
 
 	// Payment management
-	adminRouter.Handle("/payments", http.HandlerFunc(admins.GetPayments)).Methods(http.MethodGet)
+	adminRouter.Handle("/payments", http.HandlerFunc(reports.GetPayments)).Methods(http.MethodGet)
+	// Manual bank-transfer review queue; approval settles like a gateway callback
+	adminRouter.Handle("/payments/manual", http.HandlerFunc(investments.AdminManualPayments)).Methods(http.MethodGet)
+	adminRouter.Handle("/payments/{id:[0-9]+}/approve", http.HandlerFunc(investments.AdminApproveManualPayment)).Methods(http.MethodPost)
+	adminRouter.Handle("/payments/{id:[0-9]+}/reject", http.HandlerFunc(investments.AdminRejectManualPayment)).Methods(http.MethodPost)
 
 	// Spin prize management
 	adminRouter.Handle("/spin-prizes", http.HandlerFunc(admins.GetSpinPrizes)).Methods(http.MethodGet)
 	adminRouter.Handle("/spin-prizes/{id:[0-9]+}", http.HandlerFunc(admins.UpdateSpinPrize)).Methods(http.MethodPut)
 
+	// Announcements
+	adminRouter.Handle("/announcements", http.HandlerFunc(admins.ListAnnouncementsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/announcements", http.HandlerFunc(admins.CreateAnnouncementHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/announcements/{id:[0-9]+}", http.HandlerFunc(admins.UpdateAnnouncementHandler)).Methods(http.MethodPut)
+	adminRouter.Handle("/announcements/{id:[0-9]+}", http.HandlerFunc(admins.DeleteAnnouncementHandler)).Methods(http.MethodDelete)
+
+	// Deposit bonus campaigns
+	adminRouter.Handle("/deposit-campaigns", http.HandlerFunc(admins.ListDepositCampaignsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/deposit-campaigns", http.HandlerFunc(admins.CreateDepositCampaignHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/deposit-campaigns/{id:[0-9]+}", http.HandlerFunc(admins.UpdateDepositCampaignHandler)).Methods(http.MethodPut)
+	adminRouter.Handle("/deposit-campaigns/{id:[0-9]+}", http.HandlerFunc(admins.DeleteDepositCampaignHandler)).Methods(http.MethodDelete)
+
+	// Promo campaign landing pages
+	adminRouter.Handle("/campaigns", http.HandlerFunc(admins.ListCampaignsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/campaigns", http.HandlerFunc(admins.CreateCampaignHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/campaigns/{id:[0-9]+}", http.HandlerFunc(admins.UpdateCampaignHandler)).Methods(http.MethodPut)
+	adminRouter.Handle("/campaigns/{id:[0-9]+}", http.HandlerFunc(admins.DeleteCampaignHandler)).Methods(http.MethodDelete)
+	adminRouter.Handle("/profit-boosts", http.HandlerFunc(admins.ListProfitBoostsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/profit-boosts", http.HandlerFunc(admins.CreateProfitBoostHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/profit-boosts/{id:[0-9]+}", http.HandlerFunc(admins.UpdateProfitBoostHandler)).Methods(http.MethodPut)
+	adminRouter.Handle("/profit-boosts/{id:[0-9]+}", http.HandlerFunc(admins.DeleteProfitBoostHandler)).Methods(http.MethodDelete)
+	// Promo grants of spin tickets or balance; resubmitting a batch_id resumes it
+	adminRouter.Handle("/grants", http.HandlerFunc(admins.ListGrantsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/grants", http.HandlerFunc(admins.CreateGrantHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/grants/{id:[0-9]+}", http.HandlerFunc(admins.GetGrantHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/grants/{id:[0-9]+}/items", http.HandlerFunc(admins.ListGrantItemsHandler)).Methods(http.MethodGet)
+
+	// Home screen banners
+	adminRouter.Handle("/banners", http.HandlerFunc(admins.ListBannersHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/banners", http.HandlerFunc(admins.CreateBannerHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/banners/{id:[0-9]+}", http.HandlerFunc(admins.UpdateBannerHandler)).Methods(http.MethodPut)
+	adminRouter.Handle("/banners/{id:[0-9]+}", http.HandlerFunc(admins.DeleteBannerHandler)).Methods(http.MethodDelete)
+	adminRouter.Handle("/banners/{id:[0-9]+}/image", http.HandlerFunc(admins.UploadBannerImageHandler)).Methods(http.MethodPost)
+
+	// Missions
+	adminRouter.Handle("/missions", http.HandlerFunc(admins.ListMissionsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/missions", http.HandlerFunc(admins.CreateMissionHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/missions/{id:[0-9]+}", http.HandlerFunc(admins.UpdateMissionHandler)).Methods(http.MethodPut)
+	adminRouter.Handle("/missions/{id:[0-9]+}", http.HandlerFunc(admins.DeleteMissionHandler)).Methods(http.MethodDelete)
+
+	// Support tickets
+	adminRouter.Handle("/tickets", http.HandlerFunc(support.List)).Methods(http.MethodGet)
+	adminRouter.Handle("/tickets/{id:[0-9]+}", http.HandlerFunc(support.Get)).Methods(http.MethodGet)
+	adminRouter.Handle("/tickets/{id:[0-9]+}/assign", http.HandlerFunc(support.Assign)).Methods(http.MethodPut)
+	adminRouter.Handle("/tickets/{id:[0-9]+}/messages", http.HandlerFunc(support.Reply)).Methods(http.MethodPost)
+	adminRouter.Handle("/tickets/{id:[0-9]+}/close", http.HandlerFunc(support.Close)).Methods(http.MethodPut)
+	adminRouter.Handle("/canned-responses", http.HandlerFunc(support.ListCannedResponses)).Methods(http.MethodGet)
+	adminRouter.Handle("/canned-responses", http.HandlerFunc(support.CreateCannedResponse)).Methods(http.MethodPost)
+	adminRouter.Handle("/canned-responses/{id:[0-9]+}", http.HandlerFunc(support.UpdateCannedResponse)).Methods(http.MethodPut)
+	adminRouter.Handle("/canned-responses/{id:[0-9]+}", http.HandlerFunc(support.DeleteCannedResponse)).Methods(http.MethodDelete)
+
 	// Task management
 	adminRouter.Handle("/tasks", http.HandlerFunc(admins.TaskListHandler)).Methods(http.MethodGet)
 	adminRouter.Handle("/tasks", http.HandlerFunc(admins.CreateTaskHandler)).Methods(http.MethodPost)
@@ -94,7 +213,47 @@ func SetAdminRoutes(api *mux.Router) {
 	adminRouter.Handle("/forums/{id:[0-9]+}/approve", http.HandlerFunc(admins.ApproveForumHandler)).Methods(http.MethodPut)
 	adminRouter.Handle("/forums/{id:[0-9]+}/reject", http.HandlerFunc(admins.RejectForumHandler)).Methods(http.MethodPut)
 
+	// Payment settings: wishlist and masking configuration
+	adminRouter.Handle("/payment-settings/wishlist", http.HandlerFunc(admins.ListWishlistHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/payment-settings/wishlist", http.HandlerFunc(admins.AddWishlistHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/payment-settings/wishlist/{id:[0-9]+}", http.HandlerFunc(admins.RemoveWishlistHandler)).Methods(http.MethodDelete)
+	adminRouter.Handle("/payment-settings/masking", http.HandlerFunc(admins.GetMaskingConfigHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/payment-settings/masking", http.HandlerFunc(admins.UpdateMaskingConfigHandler)).Methods(http.MethodPut)
+
+	// Per-channel gateway fees, optionally passed through to the buyer
+	adminRouter.Handle("/payment-channels", http.HandlerFunc(admins.ListPaymentChannelsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/payment-channels", http.HandlerFunc(admins.UpdatePaymentChannelHandler)).Methods(http.MethodPut)
+
+	// Investment and withdrawal caps per VIP level
+	adminRouter.Handle("/vip-levels", http.HandlerFunc(admins.ListVIPLevelsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/vip-levels", http.HandlerFunc(admins.UpdateVIPLevelHandler)).Methods(http.MethodPut)
+
+	// Finance reports
+	adminRouter.Handle("/reports/daily", http.HandlerFunc(reports.GetDailyReportsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/reports/daily/export", exportLimiter.Middleware(http.HandlerFunc(reports.ExportDailyReportsHandler))).Methods(http.MethodGet)
+	adminRouter.Handle("/reports/products", http.HandlerFunc(reports.GetProductReportHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/reports/products/export", exportLimiter.Middleware(http.HandlerFunc(reports.ExportProductReportHandler))).Methods(http.MethodGet)
+	adminRouter.Handle("/reports/cohorts", http.HandlerFunc(reports.GetCohortReportHandler)).Methods(http.MethodGet)
+	// Settlement CSVs written by the settlement export cron, with download links
+	adminRouter.Handle("/reports/settlements", http.HandlerFunc(settlements.List)).Methods(http.MethodGet)
+	adminRouter.Handle("/reports/settlements/{date}", http.HandlerFunc(settlements.Download)).Methods(http.MethodGet)
+
+	// Balance vs. ledger mismatches found by the balance audit cron
+	adminRouter.Handle("/balance-audits", http.HandlerFunc(admins.ListBalanceAudits)).Methods(http.MethodGet)
+	adminRouter.Handle("/balance-audits/{id:[0-9]+}", http.HandlerFunc(admins.GetBalanceAudit)).Methods(http.MethodGet)
+	adminRouter.Handle("/balance-audits/{id:[0-9]+}/repair", http.HandlerFunc(admins.RepairBalanceAudit)).Methods(http.MethodPost)
+
+	// Team leaderboard
+	adminRouter.Handle("/leaderboard/{period}", http.HandlerFunc(admins.GetLeaderboardHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/leaderboard/{period}/close", http.HandlerFunc(admins.CloseLeaderboardPeriodHandler)).Methods(http.MethodPost)
+
 	// Settings management
 	adminRouter.Handle("/settings", http.HandlerFunc(admins.GetSettingsHandler)).Methods(http.MethodGet)
 	adminRouter.Handle("/settings", http.HandlerFunc(admins.UpdateSettingsHandler)).Methods(http.MethodPut)
+
+	// Geoblocking: per-user overrides for support cases, and what an IP resolves to
+	adminRouter.Handle("/geo-overrides", http.HandlerFunc(admins.ListGeoOverridesHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/geo-overrides", http.HandlerFunc(admins.CreateGeoOverrideHandler)).Methods(http.MethodPost)
+	adminRouter.Handle("/geo-overrides/{id:[0-9]+}", http.HandlerFunc(admins.DeleteGeoOverrideHandler)).Methods(http.MethodDelete)
+	adminRouter.Handle("/geoip", http.HandlerFunc(admins.GeoIPLookupHandler)).Methods(http.MethodGet)
 }
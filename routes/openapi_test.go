@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"project/docs"
+
+	"github.com/gorilla/mux"
+)
+
+// pathVarPattern strips mux regexps: {id:[0-9]+} -> {id}
+var pathVarPattern = regexp.MustCompile(`\{(\w+):[^}]*\}`)
+
+type openAPIDoc struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(docs.OpenAPI, &doc); err != nil {
+		t.Fatalf("docs/openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+// registeredOperations returns "METHOD /path" for every route under /v3,
+// with the /v3 prefix removed to match the spec's server URL.
+func registeredOperations(t *testing.T) []string {
+	t.Helper()
+	var ops []string
+	err := InitRouter().Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // subrouter or prefix without its own handler
+		}
+		path := strings.TrimPrefix(pathVarPattern.ReplaceAllString(tpl, "{$1}"), "/v3")
+		for _, m := range methods {
+			if m == http.MethodOptions {
+				continue // CORS preflight catch-all
+			}
+			ops = append(ops, m+" "+path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	doc := loadOpenAPI(t)
+	registered := map[string]bool{}
+	var missing []string
+	for _, op := range registeredOperations(t) {
+		registered[op] = true
+		parts := strings.SplitN(op, " ", 2)
+		if _, ok := doc.Paths[parts[1]][strings.ToLower(parts[0])]; !ok {
+			missing = append(missing, op)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		t.Errorf("routes missing from docs/openapi.json:\n  %s", strings.Join(missing, "\n  "))
+	}
+
+	var stale []string
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if op := strings.ToUpper(method) + " " + path; !registered[op] {
+				stale = append(stale, op)
+			}
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		t.Errorf("docs/openapi.json documents routes that are not registered:\n  %s", strings.Join(stale, "\n  "))
+	}
+}
+
+func TestOpenAPIServedAndSwaggerUIRequiresAdmin(t *testing.T) {
+	router := InitRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v3/docs/openapi.json", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("expected JSON document, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Fatal("served document is not valid JSON")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v3/admin/docs", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an admin token, got %d", rec.Code)
+	}
+}
+
+var refPattern = regexp.MustCompile(`"\$ref":\s*"#/([^"]+)"`)
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(docs.OpenAPI, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range refPattern.FindAllStringSubmatch(string(docs.OpenAPI), -1) {
+		var cur interface{} = doc
+		for _, part := range strings.Split(m[1], "/") {
+			obj, ok := cur.(map[string]interface{})
+			if !ok || obj[part] == nil {
+				t.Errorf("unresolved $ref #/%s", m[1])
+				break
+			}
+			cur = obj[part]
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/middleware"
+	"project/utils"
+)
+
+func TestRouterRecoversPanicsAsJSON(t *testing.T) {
+	var logs bytes.Buffer
+	prevLogger := utils.Logger
+	utils.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	t.Cleanup(func() { utils.Logger = prevLogger })
+
+	router := InitRouter()
+	router.HandleFunc("/v3/test/panic", func(w http.ResponseWriter, r *http.Request) {
+		var name *string
+		_ = *name // nil pointer dereference
+	})
+	handler := middleware.RequestIDMiddleware(router)
+
+	before := middleware.PanicCount()
+	req := httptest.NewRequest(http.MethodGet, "/v3/test/panic", nil)
+	req.Header.Set("X-Request-ID", "panic-test-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type %q, want application/json", ct)
+	}
+	var resp utils.APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("body is not JSON: %q", rec.Body.String())
+	}
+	if resp.Success || resp.Code != utils.CodeInternalError || resp.Message == "" {
+		t.Fatalf("unexpected body %s", rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "nil pointer") {
+		t.Fatal("panic details leaked to the client")
+	}
+	if got := middleware.PanicCount() - before; got != 1 {
+		t.Fatalf("panic counter moved by %d, want 1", got)
+	}
+
+	out := logs.String()
+	for _, want := range []string{`"request_id":"panic-test-1"`, `"path":"/v3/test/panic"`, "nil pointer dereference", `"stack":"goroutine`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %s:\n%s", want, out)
+		}
+	}
+}
@@ -2,16 +2,22 @@ package routes
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"project/database"
 	"time"
 
+	"project/alert"
+	"project/config"
 	"project/controllers"
 	"project/controllers/admins"
 	"project/controllers/users"
+	"project/kyta"
 	"project/middleware"
+	"project/notify"
+	"project/push"
+	"project/webhook"
 
-	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 )
 
@@ -22,15 +28,17 @@ func optionsHandler(w http.ResponseWriter, r *http.Request) {
 func InitRouter() *mux.Router {
 	r := mux.NewRouter()
 
-	// Add CORS middleware
-	r.Use(func(next http.Handler) http.Handler {
-		return handlers.CORS(
-			handlers.AllowedOrigins([]string{"https://ciroos.ca", "https://stoneform.co.id", "https://api.stoneform.co.id", "http://localhost:3000"}),
-			handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-			handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-VLA-KEY", "X-CRON-KEY"}),
-			handlers.AllowCredentials(),
-		)(next)
-	})
+	// Panics in any handler become a JSON 500; registered first so it wraps everything below
+	r.Use(middleware.RecoveryMiddleware)
+
+	// CORS policy from CORS_* env vars; invalid entries abort startup
+	corsConfig, err := middleware.LoadCORSConfig()
+	if err != nil {
+		log.Fatalf("invalid CORS configuration: %v", err)
+	}
+	r.Use(middleware.CORSMiddleware(corsConfig))
+	// Response language from Accept-Language (user preference applied in AuthMiddleware)
+	r.Use(middleware.LocaleMiddleware)
 
 	api := r.PathPrefix("/v3").Subrouter()
 
@@ -38,24 +46,100 @@ func InitRouter() *mux.Router {
 	api.PathPrefix("/").HandlerFunc(optionsHandler).Methods(http.MethodOptions)
 
 	// Rate limiter untuk cron: 1000/jam
-	cronLimiter := middleware.NewIPRateLimiter(1000, time.Hour)
+	cronLimiter := middleware.NewIPRateLimiter(1000, time.Hour).Named("cron")
+	// Rate limiter untuk verifikasi sertifikat publik: 30/jam per IP, agar nomor tidak bisa ditelusuri
+	verifyLimiter := middleware.NewIPRateLimiter(30, time.Hour).Named("verify")
 	// Rate limiter untuk webhook: 500/ip, whitelist, sliding window
-	webhookLimiter := middleware.NewWebhookLimiter(500, time.Hour, []string{"127.0.0.1" /* tambahkan IP whitelist di sini */})
+	webhookLimiter := middleware.NewWebhookLimiter(500, time.Hour, []string{"127.0.0.1" /* tambahkan IP whitelist di sini */}).Named("webhook")
+
+	// Push notifications are a no-op until FCM_SERVICE_ACCOUNT_FILE is set
+	fcmClient, err := push.NewFromEnv()
+	if err != nil {
+		log.Printf("push notifications disabled: %v", err)
+	}
+	notifier := notify.New(database.DB, fcmClient)
+
+	// Ops alerts go to Telegram when TELEGRAM_* is set; the gateway wrapper
+	// watches KytaPay's error rate
+	alerter := alert.NewFromEnv()
+	gatewayMonitor := alert.MonitorGateway(kyta.New(config.Get().Kyta), alerter)
 
 	sfxcrController := controllers.NewSFXCRController(database.DB)
+	sfxcrController.Notifier = notifier
+	sfxcrController.Alerts = alerter
+	var kytaClient kyta.Client = gatewayMonitor
+	// Side effects of payments run right after the commit and again from the
+	// outbox cron when that fails
+	outboxDispatcher := users.NewOutboxDispatcher(database.DB)
+	outboxDispatcher.Notifier = notifier
+	outboxDispatcher.Alerts = alerter
+	// Signed deliveries to downstream systems when money moves
+	webhook.Register(outboxDispatcher)
+	outboxHandler := admins.NewOutboxHandler(outboxDispatcher)
+	investmentHandler := users.NewInvestmentHandler(database.DB, kytaClient)
+	investmentHandler.Notifier = notifier
+	investmentHandler.Alerts = alerter
+	investmentHandler.Outbox = outboxDispatcher
+	withdrawalHandler := users.NewWithdrawalHandler(database.DB)
+	withdrawalHandler.Kyta = kytaClient
+	depositHandler := users.NewDepositHandler(database.DB, kytaClient)
+	adminWithdrawalHandler := admins.NewWithdrawalHandler(database.DB, kytaClient)
+	adminWithdrawalHandler.Notifier = notifier
+	adminWithdrawalHandler.Alerts = alerter
+	supportHandler := users.NewSupportHandler(database.DB)
+	missionHandler := users.NewMissionHandler(database.DB)
+	adminSupportHandler := admins.NewSupportHandler(database.DB)
+	adminSupportHandler.Notifier = notifier
+	alertCheckHandler := admins.NewAlertCheckHandler(database.DB, alerter, gatewayMonitor)
+	monitorHandler := admins.NewMonitorHandler(database.DB, alerter)
+	balanceAuditHandler := admins.NewBalanceAuditHandler(database.DB, alerter)
+	retentionHandler := admins.NewRetentionHandler(database.DB)
+	settlementHandler := admins.NewSettlementExportHandler(database.DB)
+	vipLevelHandler := admins.NewVIPLevelHandler(database.DB)
+	vipLevelHandler.Notifier = notifier
+	// Admin lists and reports read from the replica while it is healthy
+	reportHandler := admins.NewReportHandler(database.Reads)
 
 	api.Handle("/sfxcr/withdrawals/pending", http.HandlerFunc(sfxcrController.GetPendingWithdrawals)).Methods(http.MethodGet)
 	api.Handle("/sfxcr/withdrawals/pending/{order_id}", http.HandlerFunc(sfxcrController.GetPendingWithdrawalByOrderID)).Methods(http.MethodGet)
 	api.Handle("/sfxcr/withdrawals/callback", http.HandlerFunc(sfxcrController.WithdrawalCallback)).Methods(http.MethodPost)
 
 	// Cron endpoint for daily returns (protected via X-CRON-KEY header)
-	api.Handle("/cron/daily-returns", cronLimiter.Middleware(http.HandlerFunc(users.CronDailyReturnsHandler))).Methods(http.MethodPost)
+	api.Handle("/cron/daily-returns", cronLimiter.Middleware(http.HandlerFunc(investmentHandler.CronDailyReturns))).Methods(http.MethodPost)
+	// Threshold checks for ops alerts (stale withdrawals, gateway error rate)
+	api.Handle("/cron/alert-check", cronLimiter.Middleware(http.HandlerFunc(alertCheckHandler.Run))).Methods(http.MethodPost)
+	// Alerts on stuck entities above MONITOR_THRESHOLDS; every 5 to 15 minutes
+	api.Handle("/cron/monitor", cronLimiter.Middleware(http.HandlerFunc(monitorHandler.Cron))).Methods(http.MethodPost)
+	// Reminds users of pending payments about to expire; run every minute or so
+	api.Handle("/cron/payment-expiry", cronLimiter.Middleware(http.HandlerFunc(investmentHandler.CronPaymentExpiry))).Methods(http.MethodPost)
+	// Daily finance snapshot, scheduled after daily-returns
+	api.Handle("/cron/daily-report", cronLimiter.Middleware(http.HandlerFunc(admins.CronDailyReportHandler))).Methods(http.MethodPost)
+	// Refreshes the current month's team leaderboard; hourly is plenty
+	api.Handle("/cron/leaderboard-snapshot", cronLimiter.Middleware(http.HandlerFunc(admins.CronLeaderboardSnapshotHandler))).Methods(http.MethodPost)
+	// Soft-deletes old cancelled and expired investments; daily is plenty
+	api.Handle("/cron/archive-investments", cronLimiter.Middleware(http.HandlerFunc(investmentHandler.CronArchiveInvestments))).Methods(http.MethodPost)
+	// Refunds investment payments left short (Partial); every 10 minutes is plenty
+	api.Handle("/cron/partial-refunds", cronLimiter.Middleware(http.HandlerFunc(investmentHandler.CronPartialRefunds))).Methods(http.MethodPost)
+	// Compares every balance with the transaction ledger; run nightly
+	api.Handle("/cron/balance-audit", cronLimiter.Middleware(http.HandlerFunc(balanceAuditHandler.Cron))).Methods(http.MethodPost)
+	// Pays out Pending express withdrawals without an admin; every minute or so
+	api.Handle("/cron/express-withdrawals", cronLimiter.Middleware(http.HandlerFunc(adminWithdrawalHandler.CronExpress))).Methods(http.MethodPost)
+	// Recomputes VIP levels from total_invest_vip; daily is plenty
+	api.Handle("/cron/vip-levels", cronLimiter.Middleware(http.HandlerFunc(vipLevelHandler.Cron))).Methods(http.MethodPost)
+	// Reports (with ?apply=true fixes) total_invest drift from the ledger; weekly
+	api.Handle("/cron/user-totals", cronLimiter.Middleware(http.HandlerFunc(vipLevelHandler.CronTotals))).Methods(http.MethodPost)
+	// Retries outbox events (payment rewards, pushes, alerts); every minute or so
+	api.Handle("/cron/outbox", cronLimiter.Middleware(http.HandlerFunc(outboxHandler.Cron))).Methods(http.MethodPost)
+	// Purges old notifications, device signals, webhook and outbox logs; daily
+	api.Handle("/cron/retention", cronLimiter.Middleware(http.HandlerFunc(retentionHandler.Cron))).Methods(http.MethodPost)
+	// Yesterday's settlement CSVs to SETTLEMENT_S3_BUCKET; run daily
+	api.Handle("/cron/settlement-export", cronLimiter.Middleware(http.HandlerFunc(settlementHandler.Cron))).Methods(http.MethodPost)
 
 	// Kytapay webhook (no auth, whitelist, sliding window)
-	api.Handle("/callback/payments", webhookLimiter.Middleware(http.HandlerFunc(users.KytaWebhookHandler))).Methods(http.MethodPost)
+	api.Handle("/callback/payments", webhookLimiter.Middleware(http.HandlerFunc(investmentHandler.KytaWebhook))).Methods(http.MethodPost)
 
 	// Kytapay payout callback
-	api.Handle("/callback/payouts", webhookLimiter.Middleware(http.HandlerFunc(admins.KytaPayoutCallbackHandler))).Methods(http.MethodPost)
+	api.Handle("/callback/payouts", webhookLimiter.Middleware(http.HandlerFunc(adminWithdrawalHandler.KytaPayoutCallback))).Methods(http.MethodPost)
 
 	// Example protected endpoint using JWT middleware
 	api.Handle("/ping", middleware.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -69,26 +153,25 @@ func InitRouter() *mux.Router {
 	// Public application info
 	api.Handle("/info", http.HandlerFunc(controllers.InfoPublicHandler)).Methods(http.MethodGet)
 
-	// Health check endpoint for Docker health checks
-	api.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":    "healthy",
-			"timestamp": time.Now().Unix(),
-			"service":   "stoneform-api",
-		})
-	})).Methods(http.MethodGet)
+	// Public investment certificate verification
+	api.Handle("/verify/{certificate_no}", verifyLimiter.Middleware(http.HandlerFunc(controllers.VerifyCertificateHandler))).Methods(http.MethodGet)
+
+	// Health checks: readiness verifies dependencies, liveness stays dependency-free
+	api.Handle("/health", http.HandlerFunc(controllers.HealthHandler)).Methods(http.MethodGet)
+	api.Handle("/health/live", http.HandlerFunc(controllers.LivenessHandler)).Methods(http.MethodGet)
+
+	// OpenAPI document (docs/openapi.json); swagger-ui is served under /admin/docs
+	api.Handle("/docs/openapi.json", http.HandlerFunc(controllers.OpenAPIHandler)).Methods(http.MethodGet)
 
 	// Payment settings endpoints (protected by static header)
 	api.Handle("/payment_info", http.HandlerFunc(controllers.GetPaymentInfo)).Methods(http.MethodGet)
 	api.Handle("/payment_info", http.HandlerFunc(controllers.PutPaymentInfo)).Methods(http.MethodPut)
 
 	// Delegasi semua route users ke file users.go
-	UsersRoutes(api)
+	UsersRoutes(api, investmentHandler, withdrawalHandler, depositHandler, supportHandler, missionHandler)
 
 	// Setup admin routes
-	SetAdminRoutes(api)
+	SetAdminRoutes(api, investmentHandler, adminWithdrawalHandler, adminSupportHandler, reportHandler, admins.NewWebhookHandler(outboxDispatcher), monitorHandler, settlementHandler)
 
 	return r
 }
@@ -6,21 +6,27 @@ import (
 	"project/controllers/auth"
 	"project/controllers/users"
 	"project/middleware"
+	"project/models"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
 // UsersRoutes mendaftarkan semua route terkait user ke subrouter yang diberikan
-func UsersRoutes(api *mux.Router) {
+func UsersRoutes(api *mux.Router, investments *users.InvestmentHandler, withdrawals *users.WithdrawalHandler, deposits *users.DepositHandler, support *users.SupportHandler, missions *users.MissionHandler) {
+	// Write endpoints below are wrapped in MaintenanceMiddleware; reads stay available during maintenance
+	// Registration, purchases and withdrawals are also wrapped in GeoBlockMiddleware
 	// Active investments by product
 	// Rate limiter login/register: 10 per IP per menit
-	loginLimiter := middleware.NewIPRateLimiter(10, time.Minute)
+	loginLimiter := middleware.NewIPRateLimiter(10, time.Minute).Named("login")
 	// Rate limiter session: 120 per user per menit (GET), 60 per user per menit (POST/PUT/DELETE)
-	userLimiter := middleware.NewUserRateLimiter(120, 60, 60) // 120 read, 60 write, window 60 detik
+	userLimiter := middleware.NewUserRateLimiter(120, 60, 60).Named("user") // 120 read, 60 write, window 60 detik
+	// Per-user limits on expensive routes, adjustable in the settings; placed inside AuthMiddleware
+	purchaseLimiter := middleware.NewRouteRateLimiter(middleware.SettingLimit(func(s models.Setting) int { return s.RateLimitPurchase }, 5), time.Minute).Named("purchase")
+	readLimiter := middleware.NewRouteRateLimiter(middleware.SettingLimit(func(s models.Setting) int { return s.RateLimitRead }, 30), time.Minute).Named("heavy_read")
 
 	// Register & Login
-	api.Handle("/register", loginLimiter.Middleware(http.HandlerFunc(auth.RegisterHandler))).Methods(http.MethodPost)
+	api.Handle("/register", loginLimiter.Middleware(middleware.GeoBlockMiddleware(middleware.GeoFeatureRegister)(http.HandlerFunc(auth.RegisterHandler)))).Methods(http.MethodPost)
 	api.Handle("/login", loginLimiter.Middleware(http.HandlerFunc(auth.LoginHandler))).Methods(http.MethodPost)
 	api.Handle("/refresh", loginLimiter.Middleware(http.HandlerFunc(auth.RefreshHandler))).Methods(http.MethodPost)
 	api.Handle("/logout", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(auth.LogoutHandler)))).Methods(http.MethodPost)
@@ -31,47 +37,93 @@ func UsersRoutes(api *mux.Router) {
 
 	// User info (read)
 	api.Handle("/users/info", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.InfoHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/locale", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.UpdateLocaleHandler)))).Methods(http.MethodPut)
+
+	// Push notifications
+	api.Handle("/users/devices", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.RegisterDeviceHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/notification-preferences", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetNotificationPreferencesHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/notification-preferences", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.UpdateNotificationPreferencesHandler)))).Methods(http.MethodPut)
 
 	// Get Bank List, Add, Edit, Delete
 	api.Handle("/bank", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(controllers.BankListHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.AddBankAccountHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/banks", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ListBanksHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(http.HandlerFunc(users.AddBankAccountHandler))))).Methods(http.MethodPost)
 	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetBankAccountHandler)))).Methods(http.MethodGet)
 	api.Handle("/users/bank/{id}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetBankAccountHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.EditBankAccountHandler)))).Methods(http.MethodPut)
-	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.DeleteBankAccountHandler)))).Methods(http.MethodDelete)
+	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(http.HandlerFunc(users.EditBankAccountHandler))))).Methods(http.MethodPut)
+	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(http.HandlerFunc(users.DeleteBankAccountHandler))))).Methods(http.MethodDelete)
 
 	// Public: list products
-	api.Handle("/products", userLimiter.Middleware(http.HandlerFunc(controllers.ProductListHandler))).Methods(http.MethodGet)
+	api.Handle("/products", userLimiter.Middleware(middleware.OptionalAuthMiddleware(http.HandlerFunc(controllers.ProductListHandler)))).Methods(http.MethodGet)
+	// What an amount invested in a product pays, rounded as the cron credits it
+	api.Handle("/users/products/{id:[0-9]+}/projection", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(investments.Projection)))).Methods(http.MethodGet)
+
+	// Public: home screen banners, filtered by VIP level when signed in
+	api.Handle("/banners", userLimiter.Middleware(middleware.OptionalAuthMiddleware(http.HandlerFunc(users.BannerListHandler)))).Methods(http.MethodGet)
+	// Promo tab: a campaign's banners, products and bonus terms in one call
+	api.Handle("/users/campaigns/{slug:[a-z0-9-]+}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CampaignHandler)))).Methods(http.MethodGet)
 
 	// Investment endpoints (replace deposit flow)
-	api.Handle("/users/investments", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CreateInvestmentHandler)))).Methods(http.MethodPost)
-	api.Handle("/users/investments", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ListInvestmentsHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/investments/active", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetActiveInvestmentsHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/investments/{id:[0-9]+}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetInvestmentHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/investments", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware(models.FeatureInvestment)(middleware.GeoBlockMiddleware(middleware.GeoFeaturePurchase)(purchaseLimiter.Middleware(http.HandlerFunc(investments.Create))))))).Methods(http.MethodPost)
+	api.Handle("/users/investments", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(investments.List)))).Methods(http.MethodGet)
+	api.Handle("/users/investments/active", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(investments.GetActive)))).Methods(http.MethodGet)
+	api.Handle("/users/investments/{id:[0-9]+}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(investments.Get)))).Methods(http.MethodGet)
+	api.Handle("/users/investments/{id:[0-9]+}/recap", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(investments.Recap)))).Methods(http.MethodGet)
+	api.Handle("/users/investments/{id:[0-9]+}/topup", userLimiter.Middleware(middleware.AuthMiddleware(middleware.GeoBlockMiddleware(middleware.GeoFeaturePurchase)(purchaseLimiter.Middleware(http.HandlerFunc(investments.Topup)))))).Methods(http.MethodPost)
+
+	// Which payment methods can take an amount, checked as a purchase would
+	api.Handle("/users/payment/methods", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.PaymentMethodsHandler)))).Methods(http.MethodGet)
 
 	// Handle Payments get
-	api.Handle("/users/payments/{order_id}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetPaymentDetailsHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/payments/{order_id}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(investments.PaymentDetails)))).Methods(http.MethodGet)
+	// Long-poll of the payment page, answering when the payment changes
+	api.Handle("/users/payments/{order_id}/events", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(investments.PaymentEvents)))).Methods(http.MethodGet)
+	// Transfer proof of a MANUAL payment, reviewed under /admin/payments/manual
+	api.Handle("/users/payments/{order_id}/proof", userLimiter.Middleware(middleware.AuthMiddleware(purchaseLimiter.Middleware(http.HandlerFunc(investments.UploadPaymentProof))))).Methods(http.MethodPost)
+
+	// Wallet top-ups; paid through the same gateway webhook as investments
+	api.Handle("/users/deposits", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(purchaseLimiter.Middleware(http.HandlerFunc(deposits.Create)))))).Methods(http.MethodPost)
+	api.Handle("/users/deposits", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(deposits.List)))).Methods(http.MethodGet)
 
 	// Protected endpoint: withdrawal request
-	api.Handle("/users/withdrawal", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.WithdrawalHandler)))).Methods(http.MethodPost)
-	api.Handle("/users/withdrawal", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ListWithdrawalHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/withdrawal", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware(models.FeatureWithdrawal)(middleware.GeoBlockMiddleware(middleware.GeoFeatureWithdrawal)(purchaseLimiter.Middleware(http.HandlerFunc(withdrawals.Create))))))).Methods(http.MethodPost)
+	api.Handle("/users/withdrawal", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(withdrawals.List)))).Methods(http.MethodGet)
+	// Charge, final amount, refusals and SLA of a withdrawal before it is confirmed
+	api.Handle("/users/withdrawals/quote", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(withdrawals.Quote)))).Methods(http.MethodGet)
 
 	// Spin endpoints
 	api.Handle("/spin-prize-list", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.SpinPrizeListHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/spin", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.UserSpinHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/spin-tickets", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.SpinTicketHistoryHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/spin", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(http.HandlerFunc(users.UserSpinHandler))))).Methods(http.MethodPost)
 	//api.Handle("/users/spin-v2", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.UserSpinHandler)))).Methods(http.MethodGet)
 
-	api.Handle("/users/transaction", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetTransactionHistory)))).Methods(http.MethodGet)
-	api.Handle("/users/transaction/{type}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetTransactionHistory)))).Methods(http.MethodGet)
+	api.Handle("/users/transaction", userLimiter.Middleware(middleware.AuthMiddleware(readLimiter.Middleware(http.HandlerFunc(users.GetTransactionHistory))))).Methods(http.MethodGet)
+	api.Handle("/users/transaction/{type}", userLimiter.Middleware(middleware.AuthMiddleware(readLimiter.Middleware(http.HandlerFunc(users.GetTransactionHistory))))).Methods(http.MethodGet)
+	api.Handle("/users/transactions/{id:[0-9]+}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetTransactionDetail)))).Methods(http.MethodGet)
+	api.Handle("/users/income/summary", userLimiter.Middleware(middleware.AuthMiddleware(readLimiter.Middleware(http.HandlerFunc(users.GetIncomeSummary))))).Methods(http.MethodGet)
 
-	api.Handle("/users/team-invited", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TeamInvitedHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/team-invited/{level}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TeamInvitedHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/team-data/{level}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TeamDataHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/team-invited", userLimiter.Middleware(middleware.AuthMiddleware(readLimiter.Middleware(http.HandlerFunc(users.TeamInvitedHandler))))).Methods(http.MethodGet)
+	api.Handle("/users/team-invited/{level}", userLimiter.Middleware(middleware.AuthMiddleware(readLimiter.Middleware(http.HandlerFunc(users.TeamInvitedHandler))))).Methods(http.MethodGet)
+	api.Handle("/users/team-data/{level}", userLimiter.Middleware(middleware.AuthMiddleware(readLimiter.Middleware(http.HandlerFunc(users.TeamDataHandler))))).Methods(http.MethodGet)
+	api.Handle("/users/leaderboard", userLimiter.Middleware(middleware.AuthMiddleware(readLimiter.Middleware(http.HandlerFunc(users.LeaderboardHandler))))).Methods(http.MethodGet)
 
 	api.Handle("/users/forum", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ForumListHandler)))).Methods(http.MethodGet)
 	api.Handle("/users/check-forum", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CheckWithdrawalForumHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/forum/submit", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ForumSubmitHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/forum/submit", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(http.HandlerFunc(users.ForumSubmitHandler))))).Methods(http.MethodPost)
+
+	api.Handle("/users/announcements", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.AnnouncementListHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/announcements/{id:[0-9]+}/read", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.AnnouncementReadHandler)))).Methods(http.MethodPost)
+
+	// Support tickets
+	api.Handle("/users/tickets", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(support.Create)))).Methods(http.MethodPost)
+	api.Handle("/users/tickets", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(support.List)))).Methods(http.MethodGet)
+	api.Handle("/users/tickets/{id:[0-9]+}", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(support.Get)))).Methods(http.MethodGet)
+	api.Handle("/users/tickets/{id:[0-9]+}/messages", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(support.Reply)))).Methods(http.MethodPost)
+
+	// Missions
+	api.Handle("/users/missions", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(missions.List)))).Methods(http.MethodGet)
+	api.Handle("/users/missions/{id:[0-9]+}/claim", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(http.HandlerFunc(missions.Claim))))).Methods(http.MethodPost)
 
 	api.Handle("/users/task", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TaskListHandler)))).Methods(http.MethodGet)
-	api.Handle("/users/task/submit", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TaskSubmitHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/task/submit", userLimiter.Middleware(middleware.AuthMiddleware(middleware.MaintenanceMiddleware("")(http.HandlerFunc(users.TaskSubmitHandler))))).Methods(http.MethodPost)
 }
@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"project/utils"
+)
+
+func userRequest(t *testing.T, method, path string, userID uint) *http.Request {
+	t.Helper()
+	token, err := utils.GenerateAccessToken(userID, "user")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func hasArg(q recordedQuery, want interface{}) bool {
+	for _, a := range q.Args {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
+// The handlers read their ids from mux path variables, so the full /v3 path
+// through InitRouter must reach the database with the id from the URL.
+func TestUserRoutesReadPathVariables(t *testing.T) {
+	t.Setenv("JWT_SECRET", "routing-test-secret")
+	t.Setenv("JWT_AUD", "")
+	t.Setenv("JWT_ISS", "")
+	db := useRecordingDB(t)
+	router := InitRouter()
+
+	cases := []struct {
+		name   string
+		path   string
+		table  string
+		arg    interface{}
+		status int
+		code   utils.ErrorCode
+	}{
+		{"investment detail", "/v3/users/investments/123", "`investments`", int64(123), http.StatusNotFound, utils.CodeNotFound},
+		{"payment detail", "/v3/users/payments/INV-20250101-7", "`payments`", "INV-20250101-7", http.StatusNotFound, utils.CodePaymentNotFound},
+		{"bank account detail", "/v3/users/bank/55", "`bank_accounts`", "55", http.StatusNotFound, utils.CodeBankAccountNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, userRequest(t, http.MethodGet, c.path, 7))
+			if rec.Code != c.status {
+				t.Fatalf("status %d, want %d (body %s)", rec.Code, c.status, rec.Body.String())
+			}
+			var resp utils.APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Code != c.code {
+				t.Fatalf("body %s, want code %s", rec.Body.String(), c.code)
+			}
+			found := false
+			for _, q := range db.find(c.table) {
+				found = found || hasArg(q, c.arg)
+			}
+			if !found {
+				t.Fatalf("no query on %s with argument %v; recorded: %+v", c.table, c.arg, db.find(c.table))
+			}
+		})
+	}
+}
+
+func TestUserRoutesRejectInvalidPathVariables(t *testing.T) {
+	t.Setenv("JWT_SECRET", "routing-test-secret")
+	t.Setenv("JWT_AUD", "")
+	t.Setenv("JWT_ISS", "")
+	useRecordingDB(t)
+	router := InitRouter()
+
+	cases := []struct {
+		path   string
+		status int
+	}{
+		// non-numeric ids do not match the route at all
+		{"/v3/users/investments/abc", http.StatusNotFound},
+		// level outside 1..3 is rejected by the handler
+		{"/v3/users/team-data/4", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, userRequest(t, http.MethodGet, c.path, 7))
+		if rec.Code != c.status {
+			t.Errorf("%s: status %d, want %d (body %s)", c.path, rec.Code, c.status, rec.Body.String())
+		}
+	}
+}
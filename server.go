@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"project/utils"
+)
+
+// defaultDrainTimeout is how long in-flight requests get to finish on shutdown
+// when SHUTDOWN_TIMEOUT_SEC is not set.
+const defaultDrainTimeout = 30 * time.Second
+
+// serve runs srv on ln until stop is closed, then stops accepting connections
+// and waits up to drain for in-flight requests. Handlers can observe the
+// shutdown through utils.ShuttingDown; their request contexts stay live so
+// DB commits after an external call (e.g. a payout) still go through.
+func serve(srv *http.Server, ln net.Listener, stop <-chan struct{}, drain time.Duration) error {
+	shuttingDown := make(chan struct{})
+	srv.BaseContext = func(net.Listener) context.Context {
+		return utils.WithShutdown(context.Background(), shuttingDown)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-stop:
+	}
+
+	close(shuttingDown)
+	ctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package testutil
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// ErrInjected is the error FailWrites makes updates fail with.
+var ErrInjected = errors.New("injected failure")
+
+// FailWrites makes every update of table through db fail, or panic, until
+// the test ends.
+func FailWrites(t *testing.T, db *gorm.DB, table string, panics bool) {
+	t.Helper()
+	name := "test:fail_" + table
+	err := db.Callback().Update().Before("gorm:update").Register(name, func(d *gorm.DB) {
+		if d.Statement.Table != table {
+			return
+		}
+		if panics {
+			panic(ErrInjected)
+		}
+		d.AddError(ErrInjected)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Callback().Update().Remove(name) })
+}
@@ -0,0 +1,81 @@
+package testutil
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+)
+
+// seq numbers the fixtures of one test binary. It starts from the clock so
+// runs against the same schema do not collide on unique columns.
+var seq = time.Now().UnixNano() % 1000000000 * 10
+
+func nextSeq() int64 { return atomic.AddInt64(&seq, 1) }
+
+// NewUser creates the user u describes. An empty Number, ReffCode or
+// Password gets a unique placeholder and an empty Name "User".
+func NewUser(tb testing.TB, db *gorm.DB, u models.User) models.User {
+	tb.Helper()
+	n := nextSeq()
+	if u.Name == "" {
+		u.Name = "User"
+	}
+	if u.Number == "" {
+		u.Number = fmt.Sprintf("08%010d", n)
+	}
+	if u.ReffCode == "" {
+		u.ReffCode = fmt.Sprintf("T%d", n)
+	}
+	if u.Password == "" {
+		u.Password = "x"
+	}
+	if err := db.Create(&u).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return u
+}
+
+// NewCategory creates an active category paying profit the profitType way,
+// "locked" or "unlocked".
+func NewCategory(tb testing.TB, db *gorm.DB, profitType string) models.Category {
+	tb.Helper()
+	c := models.Category{Name: fmt.Sprintf("Category %d", nextSeq()), ProfitType: profitType, Status: "Active"}
+	if err := db.Create(&c).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// NewProduct creates the product p describes. Without a CategoryID it goes
+// in a new unlocked category; an empty Name, Amount, DailyProfit, Duration or
+// Status defaults to an active Rp100.000 product paying Rp5.000 for 2 days.
+func NewProduct(tb testing.TB, db *gorm.DB, p models.Product) models.Product {
+	tb.Helper()
+	if p.CategoryID == 0 {
+		p.CategoryID = NewCategory(tb, db, "unlocked").ID
+	}
+	if p.Name == "" {
+		p.Name = fmt.Sprintf("Product %d", nextSeq())
+	}
+	if p.Amount == 0 {
+		p.Amount = 100000
+	}
+	if p.DailyProfit == 0 {
+		p.DailyProfit = 5000
+	}
+	if p.Duration == 0 {
+		p.Duration = 2
+	}
+	if p.Status == "" {
+		p.Status = "Active"
+	}
+	if err := db.Create(&p).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return p
+}
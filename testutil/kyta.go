@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"project/kyta"
+)
+
+// Kyta is a KytaPay client that records the payments and payouts it was asked
+// to create. With Err set every payment fails with it, and payouts are
+// recorded but answer it. Statuses is what CheckPayment reports for each
+// reference; one missing from it is unknown to the gateway.
+type Kyta struct {
+	mu       sync.Mutex
+	Payments []kyta.PaymentRequest
+	Payouts  []kyta.PayoutRequest
+	Statuses map[string]string
+	Err      error
+}
+
+func (s *Kyta) CreateQRIS(_ context.Context, p kyta.PaymentRequest) (*kyta.PaymentResponse, error) {
+	return s.payment(p)
+}
+
+func (s *Kyta) CreateVA(_ context.Context, p kyta.PaymentRequest) (*kyta.PaymentResponse, error) {
+	return s.payment(p)
+}
+
+func (s *Kyta) CreatePayout(_ context.Context, p kyta.PayoutRequest) (*kyta.PayoutResponse, error) {
+	s.mu.Lock()
+	s.Payouts = append(s.Payouts, p)
+	s.mu.Unlock()
+	return &kyta.PayoutResponse{ResponseCode: "2001000"}, s.Err
+}
+
+func (s *Kyta) CheckPayment(_ context.Context, referenceID string) (*kyta.PaymentStatusResponse, error) {
+	s.mu.Lock()
+	status, ok := s.Statuses[referenceID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, &kyta.Error{Message: "payment not found", Status: http.StatusNotFound, Err: errors.New("unknown reference")}
+	}
+	resp := &kyta.PaymentStatusResponse{ResponseCode: "2001200"}
+	resp.ResponseData.ReferenceID = referenceID
+	resp.ResponseData.Status = status
+	return resp, nil
+}
+
+func (s *Kyta) payment(p kyta.PaymentRequest) (*kyta.PaymentResponse, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.mu.Lock()
+	s.Payments = append(s.Payments, p)
+	s.mu.Unlock()
+	resp := &kyta.PaymentResponse{ResponseCode: "2001100"}
+	resp.ResponseData.ID = "pay-" + p.ReferenceID
+	resp.ResponseData.ReferenceID = p.ReferenceID
+	resp.ResponseData.Amount = p.Amount
+	resp.ResponseData.PaymentData.QRString = "000201"
+	resp.ResponseData.ExpiresAt = time.Now().Add(15 * time.Minute).UTC().Format(time.RFC3339)
+	return resp, nil
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"project/database"
+)
+
+// WriteDBTimeout writes 503 DATABASE_TIMEOUT when err is a query deadline set
+// by database.WithTimeout and reports whether it did. Handlers call it in
+// front of their 500 so clients and the payment gateway know to retry;
+// Retry-After is one query timeout.
+func WriteDBTimeout(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !database.IsTimeout(err) {
+		return false
+	}
+	retry := int(math.Ceil(database.QueryTimeout().Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	WriteError(w, r, http.StatusServiceUnavailable, CodeDatabaseTimeout)
+	return true
+}
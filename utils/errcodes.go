@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//go:generate go run gen_error_codes.go -o ../ERROR_CODES.md
+
+// ErrorCode is the machine-readable reason carried in APIResponse.Code on
+// failed responses. Messages are for display only; clients should branch on
+// the code. Codes are never renamed once shipped.
+type ErrorCode string
+
+// Generic codes, also used as the default for a status when a handler does not
+// set a more specific one.
+const (
+	CodeBadRequest         ErrorCode = "BAD_REQUEST"
+	CodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	CodeForbidden          ErrorCode = "FORBIDDEN"
+	CodeNotFound           ErrorCode = "NOT_FOUND"
+	CodeMethodNotAllowed   ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeConflict           ErrorCode = "CONFLICT"
+	CodePayloadTooLarge    ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMedia   ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	CodeRateLimited        ErrorCode = "RATE_LIMITED"
+	CodeInternalError      ErrorCode = "INTERNAL_ERROR"
+	CodeBadGateway         ErrorCode = "BAD_GATEWAY"
+	CodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeDatabaseTimeout    ErrorCode = "DATABASE_TIMEOUT"
+)
+
+// Request and auth codes.
+const (
+	CodeInvalidJSON         ErrorCode = "INVALID_JSON"
+	CodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
+	CodeInvalidCredentials  ErrorCode = "INVALID_CREDENTIALS"
+	CodeInvalidRefreshToken ErrorCode = "INVALID_REFRESH_TOKEN"
+	CodeSessionRevoked      ErrorCode = "SESSION_REVOKED"
+	CodePhoneRegistered     ErrorCode = "PHONE_ALREADY_REGISTERED"
+	CodeInvalidReferralCode ErrorCode = "INVALID_REFERRAL_CODE"
+	CodePasswordMismatch    ErrorCode = "PASSWORD_MISMATCH"
+	CodeWrongPassword       ErrorCode = "WRONG_CURRENT_PASSWORD"
+	CodeMaintenance         ErrorCode = "MAINTENANCE"
+	CodeRegionBlocked       ErrorCode = "REGION_BLOCKED"
+)
+
+// Domain codes.
+const (
+	CodeUserNotFound             ErrorCode = "USER_NOT_FOUND"
+	CodeProductNotFound          ErrorCode = "PRODUCT_NOT_FOUND"
+	CodeCategoryNotFound         ErrorCode = "CATEGORY_NOT_FOUND"
+	CodeCategoryInUse            ErrorCode = "CATEGORY_IN_USE"
+	CodeVIPRequired              ErrorCode = "VIP_REQUIRED"
+	CodePurchaseLimitReached     ErrorCode = "PURCHASE_LIMIT_REACHED"
+	CodePurchaseCooldown         ErrorCode = "PURCHASE_COOLDOWN"
+	CodeVIPActiveInvestmentLimit ErrorCode = "VIP_ACTIVE_INVESTMENT_LIMIT"
+	CodeInvestmentNotFound       ErrorCode = "INVESTMENT_NOT_FOUND"
+	CodeRecapNotFound            ErrorCode = "INVESTMENT_RECAP_NOT_FOUND"
+	CodePaymentNotFound          ErrorCode = "PAYMENT_NOT_FOUND"
+	CodePaymentAmountOutOfRange  ErrorCode = "PAYMENT_AMOUNT_OUT_OF_RANGE"
+	CodePaymentGatewayError      ErrorCode = "PAYMENT_GATEWAY_ERROR"
+	CodePaymentChannelDisabled   ErrorCode = "PAYMENT_CHANNEL_DISABLED"
+	CodePaymentGatewayDegraded   ErrorCode = "PAYMENT_GATEWAY_DEGRADED"
+	CodeManualPaymentUnavailable ErrorCode = "MANUAL_PAYMENT_UNAVAILABLE"
+	CodePaymentClosed            ErrorCode = "PAYMENT_CLOSED"
+	CodeDepositAmountRange       ErrorCode = "DEPOSIT_AMOUNT_OUT_OF_RANGE"
+	CodeTopupUnavailable         ErrorCode = "TOPUP_UNAVAILABLE"
+	CodeTopupAmountRange         ErrorCode = "TOPUP_AMOUNT_OUT_OF_RANGE"
+	CodeTopupPending             ErrorCode = "TOPUP_PENDING"
+	CodeInsufficientBalance      ErrorCode = "INSUFFICIENT_BALANCE"
+	CodeWithdrawalNotFound       ErrorCode = "WITHDRAWAL_NOT_FOUND"
+	CodeWithdrawalAmountRange    ErrorCode = "WITHDRAWAL_AMOUNT_OUT_OF_RANGE"
+	CodeWithdrawalOutsideHours   ErrorCode = "WITHDRAWAL_OUTSIDE_HOURS"
+	CodeWithdrawalDailyLimit     ErrorCode = "WITHDRAWAL_DAILY_LIMIT"
+	CodeVIPDailyWithdrawalLimit  ErrorCode = "VIP_DAILY_WITHDRAWAL_LIMIT"
+	CodeVIPSingleWithdrawalLimit ErrorCode = "VIP_SINGLE_WITHDRAWAL_LIMIT"
+	CodeExpressUnavailable       ErrorCode = "EXPRESS_WITHDRAWAL_UNAVAILABLE"
+	CodeBankAccountNotFound      ErrorCode = "BANK_ACCOUNT_NOT_FOUND"
+	CodeBankAccountLimitReached  ErrorCode = "BANK_ACCOUNT_LIMIT_REACHED"
+	CodeBankAccountDuplicate     ErrorCode = "BANK_ACCOUNT_DUPLICATE"
+	CodeBankUnavailable          ErrorCode = "BANK_UNAVAILABLE"
+	CodeBankDisabled             ErrorCode = "BANK_DISABLED"
+	CodeNoSpinTicket             ErrorCode = "NO_SPIN_TICKET"
+	CodeSpinPrizeUnavailable     ErrorCode = "SPIN_PRIZE_UNAVAILABLE"
+	CodeTaskAlreadyClaimed       ErrorCode = "TASK_ALREADY_CLAIMED"
+	CodeTaskRequirementsNotMet   ErrorCode = "TASK_REQUIREMENTS_NOT_MET"
+	CodeForumWithdrawalRequired  ErrorCode = "FORUM_WITHDRAWAL_REQUIRED"
+	CodeInvalidImage             ErrorCode = "INVALID_IMAGE"
+	CodeTicketNotFound           ErrorCode = "TICKET_NOT_FOUND"
+	CodeTicketClosed             ErrorCode = "TICKET_CLOSED"
+	CodeMissionNotFound          ErrorCode = "MISSION_NOT_FOUND"
+	CodeMissionNotCompleted      ErrorCode = "MISSION_NOT_COMPLETED"
+	CodeMissionAlreadyClaimed    ErrorCode = "MISSION_ALREADY_CLAIMED"
+	CodeMissionExpired           ErrorCode = "MISSION_EXPIRED"
+	CodeTransactionNotFound      ErrorCode = "TRANSACTION_NOT_FOUND"
+	CodeCampaignNotFound         ErrorCode = "CAMPAIGN_NOT_FOUND"
+)
+
+// ErrorCodeInfo documents one code for ERROR_CODES.md.
+type ErrorCodeInfo struct {
+	Code        ErrorCode
+	Status      int
+	Description string
+}
+
+// ErrorCodes is the published list of codes, in documentation order.
+var ErrorCodes = []ErrorCodeInfo{
+	{CodeBadRequest, http.StatusBadRequest, "Request rejected; no more specific code applies"},
+	{CodeInvalidJSON, http.StatusBadRequest, "Body is not a single valid JSON object"},
+	{CodeValidationFailed, http.StatusBadRequest, "One or more fields are invalid; see `errors` for per-field messages"},
+	{CodeUnauthorized, http.StatusUnauthorized, "Missing, invalid or expired access token"},
+	{CodeInvalidCredentials, http.StatusUnauthorized, "Phone/username or password is wrong"},
+	{CodeInvalidRefreshToken, http.StatusUnauthorized, "Refresh token is invalid, expired or revoked"},
+	{CodeSessionRevoked, http.StatusUnauthorized, "All sessions were signed out (password or phone changed, or forced logout); log in again"},
+	{CodeForbidden, http.StatusForbidden, "Authenticated but not allowed to perform this action"},
+	{CodeNotFound, http.StatusNotFound, "Resource does not exist"},
+	{CodeMethodNotAllowed, http.StatusMethodNotAllowed, "HTTP method not supported on this path"},
+	{CodeConflict, http.StatusConflict, "Request conflicts with the current state"},
+	{CodePayloadTooLarge, http.StatusRequestEntityTooLarge, "Request body exceeds the size limit"},
+	{CodeUnsupportedMedia, http.StatusUnsupportedMediaType, "Content-Type not accepted"},
+	{CodeRateLimited, http.StatusTooManyRequests, "Too many requests; retry later"},
+	{CodeInternalError, http.StatusInternalServerError, "Unexpected server error"},
+	{CodeBadGateway, http.StatusBadGateway, "Upstream service returned an invalid response"},
+	{CodeServiceUnavailable, http.StatusServiceUnavailable, "Service temporarily unavailable"},
+	{CodeMaintenance, http.StatusServiceUnavailable, "Feature is under maintenance; see data.maintenance_until"},
+	{CodeRegionBlocked, http.StatusUnavailableForLegalReasons, "Registration, purchases and withdrawals are not offered in the caller's country; see details.country"},
+	{CodeDatabaseTimeout, http.StatusServiceUnavailable, "Database did not answer in time; safe to retry after Retry-After"},
+
+	{CodePhoneRegistered, http.StatusConflict, "Phone number is already registered"},
+	{CodeInvalidReferralCode, http.StatusBadRequest, "Referral code does not exist"},
+	{CodePasswordMismatch, http.StatusBadRequest, "Password confirmation does not match"},
+	{CodeWrongPassword, http.StatusBadRequest, "Current password is wrong"},
+	{CodeUserNotFound, http.StatusNotFound, "User does not exist"},
+
+	{CodeProductNotFound, http.StatusBadRequest, "Product does not exist or is inactive"},
+	{CodeCategoryNotFound, http.StatusNotFound, "Category does not exist"},
+	{CodeCategoryInUse, http.StatusConflict, "Category still has products or running investments"},
+	{CodeVIPRequired, http.StatusBadRequest, "User VIP level is below the product requirement; see `details` for the required and current level"},
+	{CodePurchaseLimitReached, http.StatusBadRequest, "User reached the purchase limit for this product; see `details` for the limit and purchases used"},
+	{CodePurchaseCooldown, http.StatusBadRequest, "User bought this product within its purchase cooldown; see `details` for when the next purchase is allowed"},
+	{CodeVIPActiveInvestmentLimit, http.StatusBadRequest, "User holds the most active investments their VIP level allows; see `data` for the cap and usage"},
+	{CodeInvestmentNotFound, http.StatusNotFound, "Investment does not exist or belongs to another user"},
+	{CodeRecapNotFound, http.StatusNotFound, "Investment has not completed yet, or completed before recaps were kept"},
+	{CodePaymentNotFound, http.StatusNotFound, "Payment does not exist"},
+	{CodePaymentAmountOutOfRange, http.StatusBadRequest, "Amount is outside the limits of the chosen payment method; see `details` for the method and bounds"},
+	{CodePaymentGatewayError, http.StatusBadGateway, "Payment gateway call failed; safe to retry"},
+	{CodePaymentChannelDisabled, http.StatusBadRequest, "Payment channel was switched off by an admin; pick another from GET /users/payment/methods"},
+	{CodePaymentGatewayDegraded, http.StatusServiceUnavailable, "Payment gateway is unreachable; pay with MANUAL if offered or retry later"},
+	{CodeManualPaymentUnavailable, http.StatusBadRequest, "Manual bank transfer is switched off or has no receiving account configured"},
+	{CodePaymentClosed, http.StatusConflict, "Payment is not a manual transfer still awaiting review, or has expired"},
+	{CodeDepositAmountRange, http.StatusBadRequest, "Deposit amount is below the minimum or above the maximum"},
+	{CodeTopupUnavailable, http.StatusBadRequest, "Investment is not Running or its product does not take top-ups"},
+	{CodeTopupAmountRange, http.StatusBadRequest, "Top-up amount is outside the product's top-up bounds"},
+	{CodeTopupPending, http.StatusConflict, "A top-up of this investment is still awaiting payment"},
+
+	{CodeInsufficientBalance, http.StatusBadRequest, "Balance is lower than the requested amount"},
+	{CodeWithdrawalNotFound, http.StatusNotFound, "Withdrawal does not exist"},
+	{CodeWithdrawalAmountRange, http.StatusBadRequest, "Withdrawal amount is below the minimum or above the maximum"},
+	{CodeWithdrawalOutsideHours, http.StatusBadRequest, "Withdrawals are closed at this time or day"},
+	{CodeWithdrawalDailyLimit, http.StatusBadRequest, "User already withdrew today"},
+	{CodeVIPDailyWithdrawalLimit, http.StatusBadRequest, "Withdrawal would exceed the daily amount of the user's VIP level; see `data` for the cap and usage"},
+	{CodeVIPSingleWithdrawalLimit, http.StatusBadRequest, "Withdrawal exceeds the single-withdrawal cap of the user's VIP level; see `data`"},
+	{CodeExpressUnavailable, http.StatusBadRequest, "Express withdrawals are turned off"},
+	{CodeBankAccountNotFound, http.StatusNotFound, "Bank account does not exist or belongs to another user"},
+	{CodeBankAccountLimitReached, http.StatusBadRequest, "User already has the maximum number of bank accounts"},
+	{CodeBankAccountDuplicate, http.StatusBadRequest, "Bank account number is already registered"},
+	{CodeBankUnavailable, http.StatusBadRequest, "Bank is invalid, inactive or under maintenance"},
+	{CodeBankDisabled, http.StatusBadRequest, "Bank was disabled by an admin; pick one from GET /users/banks"},
+
+	{CodeNoSpinTicket, http.StatusBadRequest, "User has no spin tickets left"},
+	{CodeSpinPrizeUnavailable, http.StatusBadRequest, "No spin prize is currently available"},
+	{CodeTaskAlreadyClaimed, http.StatusBadRequest, "Task reward was already claimed"},
+	{CodeTaskRequirementsNotMet, http.StatusBadRequest, "User does not meet the task requirements yet"},
+	{CodeForumWithdrawalRequired, http.StatusBadRequest, "Forum posts need a withdrawal in the last 3 days"},
+	{CodeInvalidImage, http.StatusBadRequest, "Uploaded image is missing, too large or not JPG/PNG"},
+
+	{CodeTicketNotFound, http.StatusNotFound, "Support ticket does not exist or belongs to another user"},
+	{CodeTicketClosed, http.StatusConflict, "Support ticket is closed and cannot be replied to"},
+
+	{CodeMissionNotFound, http.StatusNotFound, "Mission does not exist"},
+	{CodeMissionNotCompleted, http.StatusBadRequest, "User has not reached the mission target yet"},
+	{CodeMissionAlreadyClaimed, http.StatusConflict, "Mission reward was already claimed"},
+	{CodeMissionExpired, http.StatusBadRequest, "Mission has ended or was deactivated"},
+	{CodeTransactionNotFound, http.StatusNotFound, "Transaction does not exist or belongs to another user"},
+	{CodeCampaignNotFound, http.StatusNotFound, "Campaign does not exist, is inactive or has ended"},
+}
+
+// DefaultErrorCode is the code WriteJSON uses for a failed response that does
+// not set one.
+func DefaultErrorCode(status int) ErrorCode {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	}
+	if status >= 500 {
+		return CodeInternalError
+	}
+	return CodeBadRequest
+}
+
+// ErrorCodesMarkdown renders ErrorCodes as the ERROR_CODES.md table.
+func ErrorCodesMarkdown() string {
+	var b strings.Builder
+	b.WriteString("# API error codes\n\n")
+	b.WriteString("<!-- Generated by `go generate ./utils`; do not edit. -->\n\n")
+	b.WriteString("Failed responses carry a stable `code` next to the display `message`:\n\n")
+	b.WriteString("```json\n{\"success\": false, \"message\": \"Produk tidak ditemukan\", \"code\": \"PRODUCT_NOT_FOUND\"}\n```\n\n")
+	b.WriteString("Clients should branch on `code`; messages may change at any time. ")
+	b.WriteString("When a handler sets no specific code, the generic code for the HTTP status is used. ")
+	b.WriteString("Some refusals add a `details` object whose shape depends on the code, so clients can render the numbers without parsing `message`.\n\n")
+	b.WriteString("| Code | Typical status | Meaning |\n|---|---|---|\n")
+	for _, c := range ErrorCodes {
+		fmt.Fprintf(&b, "| `%s` | %d | %s |\n", c.Code, c.Status, c.Description)
+	}
+	return b.String()
+}
@@ -0,0 +1,21 @@
+//go:build ignore
+
+// gen_error_codes writes ERROR_CODES.md from utils.ErrorCodes.
+// Run with `go generate ./utils`.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"project/utils"
+)
+
+func main() {
+	out := flag.String("o", "ERROR_CODES.md", "output file")
+	flag.Parse()
+	if err := os.WriteFile(*out, []byte(utils.ErrorCodesMarkdown()), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"net/http"
+
+	"project/i18n"
+)
+
+// T translates key into the locale chosen for r by the locale middleware.
+func T(r *http.Request, key string, args ...interface{}) string {
+	return i18n.T(i18n.FromContext(r.Context()), key, args...)
+}
+
+// WriteError writes a failed response whose message is the localized default
+// text for code, formatted with args.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, args ...interface{}) {
+	WriteJSON(w, status, APIResponse{Success: false, Message: T(r, string(code), args...), Code: code})
+}
+
+// WriteErrorDetails is WriteError with a details object for clients that
+// render the reason themselves.
+func WriteErrorDetails(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, details interface{}, args ...interface{}) {
+	WriteJSON(w, status, APIResponse{Success: false, Message: T(r, string(code), args...), Code: code, Details: details})
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImageError is an upload rejected for its content. Message is user-facing.
+type ImageError struct {
+	Message string
+}
+
+func (e *ImageError) Error() string { return e.Message }
+
+// SanitizeImage reads an uploaded JPG/PNG of at most maxBytes, checks its
+// extension and magic bytes, and re-encodes it to strip metadata and anything
+// appended to the image data. It returns the new bytes and the extension
+// matching their format. Rejections are *ImageError; other errors come from
+// re-encoding.
+func SanitizeImage(file io.Reader, filename string, size, maxBytes int64) ([]byte, string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return nil, "", &ImageError{Message: "Gambar harus JPG/PNG"}
+	}
+	if size > maxBytes {
+		return nil, "", &ImageError{Message: "Gambar maksimal " + humanBytes(maxBytes)}
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return nil, "", &ImageError{Message: "Gagal membaca gambar"}
+	}
+	if int64(len(raw)) > maxBytes {
+		return nil, "", &ImageError{Message: "Gambar maksimal " + humanBytes(maxBytes)}
+	}
+	head := raw
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if detected := http.DetectContentType(head); detected != "image/jpeg" && detected != "image/png" {
+		return nil, "", &ImageError{Message: "Gambar harus JPG/PNG"}
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", &ImageError{Message: "Invalid image format"}
+	}
+	var out bytes.Buffer
+	switch format {
+	case "jpeg":
+		if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return out.Bytes(), ".jpg", nil
+	case "png":
+		if err := png.Encode(&out, img); err != nil {
+			return nil, "", err
+		}
+		return out.Bytes(), ".png", nil
+	}
+	return nil, "", &ImageError{Message: "Gambar harus JPG/PNG"}
+}
+
+// humanBytes formats a whole number of megabytes, or kilobytes below that.
+func humanBytes(n int64) string {
+	if n >= 1<<20 {
+		return strconv.FormatInt(n>>20, 10) + "MB"
+	}
+	return strconv.FormatInt(n>>10, 10) + "KB"
+}
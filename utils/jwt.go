@@ -61,6 +61,11 @@ type contextKey string
 const UserIDKey = contextKey("userID")
 const UserRoleKey = contextKey("userRole")
 const RequestIDKey = contextKey("requestID")
+const AdminIDKey = contextKey("adminID")
+
+// APIKeyIDKey is set, next to AdminIDKey, when an admin route is called with an
+// API key rather than the admin's token.
+const APIKeyIDKey = contextKey("apiKeyID")
 
 // ValidateToken validates a JWT token and returns the parsed token if valid
 func ValidateToken(tokenString string) (*jwt.Token, error) {
@@ -149,10 +154,12 @@ func GenerateAccessToken(userID uint, role string) (string, error) {
 		Issuer:    os.Getenv("JWT_ISS"),
 	}
 
-	// Custom claims wrapper
+	// Custom claims wrapper; "tv" is checked against users.token_version by
+	// AuthMiddleware
 	claims := jwt.MapClaims{
 		"id":   userID,
 		"role": role,
+		"tv":   currentTokenVersion(userID),
 		"exp":  rc.ExpiresAt.Unix(),
 		"iat":  rc.IssuedAt.Unix(),
 		"nbf":  rc.NotBefore.Unix(),
@@ -165,6 +172,19 @@ func GenerateAccessToken(userID uint, role string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
+// currentTokenVersion reads the user's token version for a new access token,
+// straight from the DB so a token issued right after a bump is never stale.
+func currentTokenVersion(userID uint) int {
+	if database.DB == nil {
+		return 0
+	}
+	v, err := models.TokenVersion(database.DB, userID)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
 // GenerateRefreshToken creates a refresh token, stores it in DB and returns the token string (contains jti)
 func GenerateRefreshToken(userID uint) (string, string, error) {
 	// We store the refresh token ID in DB and return opaque token = jti
@@ -421,3 +441,17 @@ func GetUserID(r *http.Request) (uint, bool) {
 	id, ok := v.(uint)
 	return id, ok
 }
+
+// Get adminID from context (set by AdminAuthMiddleware)
+func GetAdminID(r *http.Request) (int64, bool) {
+	v := r.Context().Value(AdminIDKey)
+	id, ok := v.(int64)
+	return id, ok
+}
+
+// GetAPIKeyID returns the API key the admin request was made with, if any.
+func GetAPIKeyID(r *http.Request) (uint, bool) {
+	v := r.Context().Value(APIKeyIDKey)
+	id, ok := v.(uint)
+	return id, ok
+}
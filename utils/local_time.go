@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LocalTimeLayout renders the *_local strings: APP_TIMEZONE wall time with
+// the zone's abbreviation, ready to display.
+const LocalTimeLayout = "2006-01-02 15:04:05 MST"
+
+// WantsLocalTimes reports whether the client asked for *_local strings, with
+// ?time_format=local or an X-Time-Format: local header.
+func WantsLocalTimes(r *http.Request) bool {
+	if strings.EqualFold(r.URL.Query().Get("time_format"), "local") {
+		return true
+	}
+	return strings.EqualFold(strings.TrimSpace(r.Header.Get("X-Time-Format")), "local")
+}
+
+// FormatLocalTime renders t in APP_TIMEZONE with LocalTimeLayout.
+func FormatLocalTime(t time.Time) string {
+	return t.In(AppLocation()).Format(LocalTimeLayout)
+}
+
+// AddLocalTimes sets "<key>_local" in data for each of times when the client
+// asked for local times; a nil time gives null. The RFC 3339 fields are left
+// as they are.
+func AddLocalTimes(r *http.Request, data map[string]interface{}, times map[string]*time.Time) {
+	if !WantsLocalTimes(r) {
+		return
+	}
+	for key, t := range times {
+		if t == nil {
+			data[key+"_local"] = nil
+			continue
+		}
+		data[key+"_local"] = FormatLocalTime(*t)
+	}
+}
+
+// AddServerTime sets server_time in data, and server_time_local when asked,
+// so clients running countdowns can correct their clock's skew.
+func AddServerTime(r *http.Request, data map[string]interface{}) {
+	now := time.Now()
+	data["server_time"] = FormatTime(now)
+	AddLocalTimes(r, data, map[string]*time.Time{"server_time": &now})
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Logger writes structured JSON logs to stdout.
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+const requestMetaKey = contextKey("requestMeta")
+
+// RequestMeta is the per-request state shared between the outer request
+// logging middleware and the auth middlewares further in, which only see a
+// derived context. The caller ids are filled in once authentication succeeds.
+type RequestMeta struct {
+	ID string
+
+	mu      sync.Mutex
+	userID  uint
+	adminID int64
+}
+
+// WithRequestMeta stores a new RequestMeta for id in ctx, along with the plain
+// request id under RequestIDKey for existing readers.
+func WithRequestMeta(ctx context.Context, id string) (context.Context, *RequestMeta) {
+	meta := &RequestMeta{ID: id}
+	ctx = context.WithValue(ctx, RequestIDKey, id)
+	return context.WithValue(ctx, requestMetaKey, meta), meta
+}
+
+// Caller returns the authenticated user and admin ids recorded so far (0 if none).
+func (m *RequestMeta) Caller() (uint, int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.userID, m.adminID
+}
+
+func requestMeta(r *http.Request) *RequestMeta {
+	meta, _ := r.Context().Value(requestMetaKey).(*RequestMeta)
+	return meta
+}
+
+// SetRequestUser records the authenticated user for the request log.
+func SetRequestUser(r *http.Request, userID uint) {
+	if meta := requestMeta(r); meta != nil {
+		meta.mu.Lock()
+		meta.userID = userID
+		meta.mu.Unlock()
+	}
+}
+
+// SetRequestAdmin records the authenticated admin for the request log.
+func SetRequestAdmin(r *http.Request, adminID int64) {
+	if meta := requestMeta(r); meta != nil {
+		meta.mu.Lock()
+		meta.adminID = adminID
+		meta.mu.Unlock()
+	}
+}
+
+// GetRequestID returns the request id set by RequestIDMiddleware, or "".
+func GetRequestID(r *http.Request) string {
+	rid, _ := r.Context().Value(RequestIDKey).(string)
+	return rid
+}
+
+// LogError logs err with the request id, route and caller so that a failed
+// response can be traced back from the X-Request-ID the client received.
+// Extra key/value pairs are passed through to slog.
+func LogError(r *http.Request, msg string, err error, args ...any) {
+	attrs := []any{
+		"request_id", GetRequestID(r),
+		"method", r.Method,
+		"path", r.URL.Path,
+	}
+	if meta := requestMeta(r); meta != nil {
+		if uid, aid := meta.Caller(); uid != 0 {
+			attrs = append(attrs, "user_id", uid)
+		} else if aid != 0 {
+			attrs = append(attrs, "admin_id", aid)
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err.Error())
+	}
+	Logger.Error(msg, append(attrs, args...)...)
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -14,7 +16,33 @@ func init() {
 	seededRand = rand.New(rand.NewSource(time.Now().UnixNano()))
 }
 
-func GenerateOrderID(userID uint) string {
+// OrderType says what an order id is for. It is the id's prefix, so support
+// can tell a deposit from a withdrawal at a glance and the payment webhook
+// can route a callback by its reference.
+type OrderType string
+
+const (
+	OrderInvestment   OrderType = "INV"
+	OrderWithdrawal   OrderType = "WD"
+	OrderDeposit      OrderType = "DEP"
+	OrderReturn       OrderType = "RTN" // daily profit, completion payouts and capital returns
+	OrderBonus        OrderType = "BNS" // referral, mission, task, spin and admin bonuses
+	OrderTopup        OrderType = "TUP"
+	OrderRefund       OrderType = "RFD" // refunds and overpayment credits
+	OrderRefundPayout OrderType = "RPO" // refunds paid out to a bank account
+	OrderAdjustment   OrderType = "ADJ" // clawbacks and admin deductions
+	// OrderLegacy is every id issued before order types existed
+	OrderLegacy OrderType = "XIN"
+)
+
+var orderTypes = map[OrderType]bool{
+	OrderInvestment: true, OrderWithdrawal: true, OrderDeposit: true, OrderReturn: true,
+	OrderBonus: true, OrderTopup: true, OrderRefund: true, OrderRefundPayout: true, OrderAdjustment: true, OrderLegacy: true,
+}
+
+// GenerateOrderID returns a new order id of type t for userID:
+// <type>-<6 digits of time><3 random digits><user id>.
+func GenerateOrderID(t OrderType, userID uint) string {
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -23,7 +51,7 @@ func GenerateOrderID(userID uint) string {
 
 	randPart := seededRand.Intn(900) + 100
 
-	return fmt.Sprintf("XIN-%06d%03d%d", nanoPart, randPart, userID)
+	return fmt.Sprintf("%s-%06d%03d%d", t, nanoPart, randPart, userID)
 }
 
 func GenerateReferenceID(userID uint) string {
@@ -37,3 +65,41 @@ func GenerateReferenceID(userID uint) string {
 
 	return fmt.Sprintf("XIN-%06d%03d%d", nanoPart, randPart, userID)
 }
+
+// DepositOrderPrefix starts every deposit order id, so gateway callbacks for
+// top-ups can be told apart from investment payments.
+const DepositOrderPrefix = string(OrderDeposit) + "-"
+
+// TopupOrderPrefix starts every investment top-up order id, so gateway
+// callbacks for top-ups reach settleTopup.
+const TopupOrderPrefix = string(OrderTopup) + "-"
+
+// RefundPayoutOrderPrefix starts every refund payout order id, so payout
+// callbacks for refunds reach the refund instead of a withdrawal.
+const RefundPayoutOrderPrefix = string(OrderRefundPayout) + "-"
+
+// OrderID is what an order id says about itself.
+type OrderID struct {
+	Type OrderType
+	// UserID is the user the id was generated for. It is a hint: the order
+	// row remains the authority on who owns it.
+	UserID uint
+}
+
+// ParseOrderID reads the type and user of an id made by GenerateOrderID,
+// including legacy XIN- ids. ok is false for anything else.
+func ParseOrderID(id string) (o OrderID, ok bool) {
+	prefix, digits, found := strings.Cut(strings.TrimSpace(id), "-")
+	if !found || !orderTypes[OrderType(prefix)] {
+		return OrderID{}, false
+	}
+	// 6 digits of time and 3 random ones come before the user id
+	if len(digits) < 10 {
+		return OrderID{}, false
+	}
+	uid, err := strconv.ParseUint(digits[9:], 10, 64)
+	if err != nil || uid == 0 || strings.Trim(digits[:9], "0123456789") != "" {
+		return OrderID{}, false
+	}
+	return OrderID{Type: OrderType(prefix), UserID: uint(uid)}, true
+}
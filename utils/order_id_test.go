@@ -0,0 +1,22 @@
+package utils
+
+import "testing"
+
+func TestParseOrderID(t *testing.T) {
+	for _, typ := range []OrderType{OrderInvestment, OrderWithdrawal, OrderDeposit, OrderReturn, OrderBonus} {
+		id := GenerateOrderID(typ, 4021)
+		o, ok := ParseOrderID(id)
+		if !ok || o.Type != typ || o.UserID != 4021 {
+			t.Errorf("ParseOrderID(%q) = %+v, %v", id, o, ok)
+		}
+	}
+	// Ids issued before order types keep parsing
+	if o, ok := ParseOrderID("XIN-0123457897"); !ok || o.Type != OrderLegacy || o.UserID != 7 {
+		t.Errorf("legacy id parsed as %+v, %v", o, ok)
+	}
+	for _, bad := range []string{"", "INV", "INV-123456789", "FOO-1234567891", "INV-12345678x1", "INV-1234567890", "DEP-test-1"} {
+		if o, ok := ParseOrderID(bad); ok {
+			t.Errorf("ParseOrderID(%q) = %+v, want not ok", bad, o)
+		}
+	}
+}
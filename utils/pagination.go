@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// DefaultPageLimit is used when ?limit= is missing or not a positive number.
+const DefaultPageLimit = 20
+
+// MaxPageLimit caps ?limit= for every list endpoint. Override with
+// PAGINATION_MAX_LIMIT.
+var MaxPageLimit = func() int {
+	if n, err := strconv.Atoi(os.Getenv("PAGINATION_MAX_LIMIT")); err == nil && n > 0 {
+		return n
+	}
+	return 100
+}()
+
+// Pagination is the parsed ?page=&limit= of a list request.
+type Pagination struct {
+	Page   int
+	Limit  int
+	Offset int
+}
+
+// PaginationMeta is the "pagination" object of a list response.
+type PaginationMeta struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	TotalRows  int64 `json:"total_rows"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// Paginated is the {data, pagination} envelope returned by list endpoints.
+type Paginated[T any] struct {
+	Data       []T            `json:"data"`
+	Pagination PaginationMeta `json:"pagination"`
+}
+
+// ParsePagination reads ?page= and ?limit= using DefaultPageLimit and
+// MaxPageLimit. Missing or non-positive values fall back to the defaults; a
+// limit above the cap is rejected with a message that states the maximum.
+func ParsePagination(r *http.Request) (Pagination, error) {
+	return ParsePaginationMax(r, MaxPageLimit)
+}
+
+// ParsePaginationMax is ParsePagination with a caller-supplied cap.
+func ParsePaginationMax(r *http.Request, max int) (Pagination, error) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 {
+		limit = DefaultPageLimit
+	}
+	if limit > max {
+		return Pagination{}, fmt.Errorf("Limit maksimal %d data per halaman", max)
+	}
+	return Pagination{Page: page, Limit: limit, Offset: (page - 1) * limit}, nil
+}
+
+// Meta builds the pagination object for totalRows matching rows.
+func (p Pagination) Meta(totalRows int64) PaginationMeta {
+	pages := 0
+	if p.Limit > 0 {
+		pages = int((totalRows + int64(p.Limit) - 1) / int64(p.Limit))
+	}
+	return PaginationMeta{Page: p.Page, Limit: p.Limit, TotalRows: totalRows, TotalPages: pages}
+}
+
+// NewPaginated wraps one page of rows; a nil slice is encoded as [].
+func NewPaginated[T any](data []T, p Pagination, totalRows int64) Paginated[T] {
+	if data == nil {
+		data = []T{}
+	}
+	return Paginated[T]{Data: data, Pagination: p.Meta(totalRows)}
+}
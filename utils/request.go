@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// MaxJSONBodyBytes caps request bodies read by DecodeAndValidate, and is the
+// usual limit passed to DecodeJSON.
+const MaxJSONBodyBytes int64 = 64 << 10
+
+// Normalizer is implemented by request structs that need trimming or case
+// folding before their validate tags are checked.
+type Normalizer interface {
+	Normalize()
+}
+
+// requestError is a decode or validation failure ready to be written as a response.
+type requestError struct {
+	status  int
+	code    ErrorCode
+	message string
+	fields  map[string]string
+}
+
+func (e *requestError) Error() string { return e.message }
+
+var requestValidator = newRequestValidator()
+
+func newRequestValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by their JSON name so the errors map matches the payload
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	// Same rules as the internal ValidateStruct tags
+	_ = v.RegisterValidation("phone8", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() == "" || rePhone8.MatchString(fl.Field().String())
+	})
+	_ = v.RegisterValidation("nameok", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() == "" || reNameOK.MatchString(fl.Field().String())
+	})
+	return v
+}
+
+// DecodeAndValidate reads a single JSON object from the body into dst, rejecting
+// unknown fields, wrong types and bodies over MaxJSONBodyBytes, then checks the
+// `validate` tags on dst. On failure it writes the 400/413 response, with an
+// `errors` map of field -> message where applicable, and returns false.
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := decodeStrict(w, r, dst, MaxJSONBodyBytes)
+	if err == nil {
+		if n, ok := dst.(Normalizer); ok {
+			n.Normalize()
+		}
+		if fields := ValidateRequest(dst); len(fields) > 0 {
+			err = &requestError{status: http.StatusBadRequest, code: CodeValidationFailed, message: T(r, string(CodeValidationFailed)), fields: fields}
+		}
+	}
+	if err != nil {
+		WriteJSON(w, err.status, APIResponse{Success: false, Message: err.message, Code: err.code, Errors: err.fields})
+		return false
+	}
+	return true
+}
+
+// DecodeJSON reads a single JSON object from the body into dst, rejecting
+// unknown fields, wrong types and bodies over maxBytes. On failure it writes
+// the 400/413 response and returns the error so callers can log it.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	if err := decodeStrict(w, r, dst, maxBytes); err != nil {
+		WriteJSON(w, err.status, APIResponse{Success: false, Message: err.message, Code: err.code, Errors: err.fields})
+		return err
+	}
+	return nil
+}
+
+// ValidateRequest checks the `validate` tags on v and returns a message per
+// invalid field, or nil when v is valid.
+func ValidateRequest(v interface{}) map[string]string {
+	err := requestValidator.Struct(v)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return map[string]string{"_": err.Error()}
+	}
+	fields := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		// Namespace is "Struct.field.sub"; drop the struct name
+		name := fe.Namespace()
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+		if _, seen := fields[name]; !seen {
+			fields[name] = fieldMessage(fe)
+		}
+	}
+	return fields
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "wajib diisi"
+	case "oneof":
+		return "harus salah satu dari: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min", "gte":
+		if isString {
+			return fmt.Sprintf("minimal %s karakter", fe.Param())
+		}
+		return "minimal " + fe.Param()
+	case "max", "lte":
+		if isString {
+			return fmt.Sprintf("maksimal %s karakter", fe.Param())
+		}
+		return "maksimal " + fe.Param()
+	case "gt":
+		return "harus lebih dari " + fe.Param()
+	case "lt":
+		return "harus kurang dari " + fe.Param()
+	case "phone8":
+		return "harus nomor HP Indonesia diawali 8"
+	case "nameok":
+		return "mengandung karakter yang tidak diizinkan"
+	}
+	return "tidak valid"
+}
+
+// decodeStrict decodes exactly one JSON value into dst with a size cap and
+// DisallowUnknownFields, translating decoder errors into client-facing ones.
+func decodeStrict(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) *requestError {
+	if r.Body == nil {
+		return &requestError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "Request body kosong"}
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		// Anything but whitespace after the first value is rejected
+		if _, err := dec.Token(); err != io.EOF {
+			return &requestError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "Request body harus berisi satu objek JSON"}
+		}
+		return nil
+	}
+
+	var maxErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &maxErr):
+		return &requestError{status: http.StatusRequestEntityTooLarge, code: CodePayloadTooLarge, message: fmt.Sprintf("Request body melebihi batas %d byte", maxErr.Limit)}
+	case errors.Is(err, io.EOF):
+		return &requestError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "Request body kosong"}
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			return &requestError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "Request body harus berupa objek JSON"}
+		}
+		return &requestError{status: http.StatusBadRequest, code: CodeValidationFailed, message: "Data tidak valid", fields: map[string]string{field: "harus berupa " + jsonTypeName(typeErr.Type)}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &requestError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "Format JSON tidak valid"}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &requestError{status: http.StatusBadRequest, code: CodeValidationFailed, message: "Data tidak valid", fields: map[string]string{field: "field tidak dikenal"}}
+	}
+	return &requestError{status: http.StatusBadRequest, code: CodeInvalidJSON, message: "Format JSON tidak valid"}
+}
+
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "teks"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "bilangan bulat"
+	case reflect.Float32, reflect.Float64:
+		return "angka"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "objek"
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type sampleRequest struct {
+	Name   string `json:"name" validate:"required,max=10"`
+	Amount int64  `json:"amount" validate:"gt=0"`
+	Method string `json:"method" validate:"required,oneof=QRIS BANK"`
+}
+
+func (s *sampleRequest) Normalize() {
+	s.Method = strings.ToUpper(strings.TrimSpace(s.Method))
+}
+
+func decodeSample(t *testing.T, body string) (*httptest.ResponseRecorder, APIResponse, bool) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v3/x", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	var dst sampleRequest
+	ok := DecodeAndValidate(rec, req, &dst)
+	var resp APIResponse
+	if !ok {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("response is not JSON: %v", err)
+		}
+	}
+	return rec, resp, ok
+}
+
+func TestDecodeAndValidate_Valid(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v3/x", strings.NewReader(`{"name":"a","amount":5,"method":" qris "}`))
+	var dst sampleRequest
+	if !DecodeAndValidate(httptest.NewRecorder(), req, &dst) {
+		t.Fatal("expected valid request")
+	}
+	if dst.Method != "QRIS" {
+		t.Fatalf("Normalize not applied before validation, got %q", dst.Method)
+	}
+}
+
+func TestDecodeAndValidate_UnknownField(t *testing.T) {
+	rec, resp, ok := decodeSample(t, `{"name":"a","amount":5,"method":"QRIS","payment_methd":"x"}`)
+	if ok || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if resp.Errors["payment_methd"] == "" {
+		t.Fatalf("expected error for unknown field, got %v", resp.Errors)
+	}
+}
+
+func TestDecodeAndValidate_WrongType(t *testing.T) {
+	rec, resp, ok := decodeSample(t, `{"name":"a","amount":"lima","method":"QRIS"}`)
+	if ok || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if resp.Errors["amount"] != "harus berupa bilangan bulat" {
+		t.Fatalf("unexpected errors %v", resp.Errors)
+	}
+}
+
+func TestDecodeAndValidate_MissingRequired(t *testing.T) {
+	rec, resp, ok := decodeSample(t, `{"amount":0}`)
+	if ok || rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	want := map[string]string{"name": "wajib diisi", "amount": "harus lebih dari 0", "method": "wajib diisi"}
+	for k, v := range want {
+		if resp.Errors[k] != v {
+			t.Errorf("errors[%s] = %q, want %q", k, resp.Errors[k], v)
+		}
+	}
+}
+
+func TestDecodeAndValidate_OneOf(t *testing.T) {
+	_, resp, ok := decodeSample(t, `{"name":"a","amount":1,"method":"CASH"}`)
+	if ok || resp.Errors["method"] != "harus salah satu dari: QRIS, BANK" {
+		t.Fatalf("unexpected errors %v", resp.Errors)
+	}
+}
+
+func TestDecodeAndValidate_MalformedAndTrailing(t *testing.T) {
+	for _, body := range []string{``, `{"name":`, `{"name":"a","amount":1,"method":"QRIS"} {}`, `[1,2]`} {
+		rec, _, ok := decodeSample(t, body)
+		if ok || rec.Code != http.StatusBadRequest {
+			t.Errorf("body %q: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestDecodeAndValidate_BodyTooLarge(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", int(MaxJSONBodyBytes)) + `"}`
+	rec, _, ok := decodeSample(t, body)
+	if ok || rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", rec.Code)
+	}
+}
+
+func TestDecodeJSON_LimitAndUnknownFields(t *testing.T) {
+	var dst struct {
+		ID string `json:"id"`
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(`{"id":"`+strings.Repeat("x", 100)+`"}`))
+	if err := DecodeJSON(rec, req, &dst, 32); err == nil || rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body: err=%v status=%d, want 413", err, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(`{"id":"a","payment_methd":"QRIS"}`))
+	if err := DecodeJSON(rec, req, &dst, MaxJSONBodyBytes); err == nil || rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown field: err=%v status=%d, want 400", err, rec.Code)
+	}
+	var resp APIResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Errors["payment_methd"] == "" {
+		t.Fatalf("errors = %v, want payment_methd entry", resp.Errors)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/v3/callback/payments", strings.NewReader(`{"id":"a"}`))
+	if err := DecodeJSON(rec, req, &dst, MaxJSONBodyBytes); err != nil || dst.ID != "a" {
+		t.Fatalf("valid body: err=%v id=%q", err, dst.ID)
+	}
+}
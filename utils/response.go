@@ -1,22 +1,56 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	Code    ErrorCode         `json:"code,omitempty"` // set on failures; see ERROR_CODES.md
+	Data    interface{}       `json:"data,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`  // per-field validation messages, keyed by JSON field name
+	Details interface{}       `json:"details,omitempty"` // structured reasons for a refusal, shaped by Code
 }
 
+// WriteJSON encodes resp with the given status. Failed responses without a
+// Code get the generic code for the status.
 func WriteJSON(w http.ResponseWriter, status int, resp APIResponse) {
+	if !resp.Success && resp.Code == "" && status >= 400 {
+		resp.Code = DefaultErrorCode(status)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// ETag returns a strong entity tag for v: the start of the SHA-256 of its
+// JSON, quoted.
+func ETag(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// NotModified reports whether the request's If-None-Match lists etag, in
+// which case the caller answers 304 without a body.
+func NotModified(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 // GetStringValue returns the value of a nullable string pointer or empty string if nil
 func GetStringValue(s *string) string {
 	if s == nil {
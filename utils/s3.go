@@ -7,6 +7,7 @@ import (
 	"mime"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -42,6 +43,22 @@ func getS3Config() (aws.Config, error) {
 	return cfg, nil
 }
 
+// newS3Client returns an S3 client. With S3_ENDPOINT set it talks to that
+// S3-compatible service (MinIO, R2, ...) with path-style addressing.
+func newS3Client() (*s3.Client, error) {
+	cfg, err := getS3Config()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := strings.TrimSpace(os.Getenv("S3_ENDPOINT"))
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
+
 // UploadToS3 uploads a file to AWS S3
 func UploadToS3(objectName string, file io.Reader, fileSize int64) error {
 	bucket := os.Getenv("S3_BUCKET")
@@ -49,28 +66,28 @@ func UploadToS3(objectName string, file io.Reader, fileSize int64) error {
 		return fmt.Errorf("S3_BUCKET not set in environment")
 	}
 
-	cfg, err := getS3Config()
-	if err != nil {
-		return err
-	}
-
-	client := s3.NewFromConfig(cfg)
-
 	contentType := mime.TypeByExtension(path.Ext(objectName))
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
+	return PutObject(context.TODO(), bucket, objectName, file, contentType)
+}
 
-	_, err = client.PutObject(context.TODO(), &s3.PutObjectInput{
+// PutObject stores body under key in bucket, replacing what was there.
+func PutObject(ctx context.Context, bucket, key string, body io.Reader, contentType string) error {
+	client, err := newS3Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(bucket),
-		Key:         aws.String(objectName),
-		Body:        file,
+		Key:         aws.String(key),
+		Body:        body,
 		ContentType: aws.String(contentType),
 	})
 	if err != nil {
 		return fmt.Errorf("S3 upload failed: %w", err)
 	}
-
 	return nil
 }
 
@@ -80,22 +97,25 @@ func GenerateSignedURL(objectName string, expirySeconds int64) (string, error) {
 	if bucket == "" {
 		return "", fmt.Errorf("S3_BUCKET not set in environment")
 	}
+	return PresignGetObject(bucket, objectName, time.Duration(expirySeconds)*time.Second)
+}
 
-	cfg, err := getS3Config()
+// PresignGetObject returns a URL that downloads key from bucket until expiry
+// has passed.
+func PresignGetObject(bucket, key string, expiry time.Duration) (string, error) {
+	client, err := newS3Client()
 	if err != nil {
 		return "", err
 	}
-
-	client := s3.NewFromConfig(cfg)
 	presigner := s3.NewPresignClient(client)
 
 	presigned, err := presigner.PresignGetObject(context.TODO(),
 		&s3.GetObjectInput{
 			Bucket: aws.String(bucket),
-			Key:    aws.String(objectName),
+			Key:    aws.String(key),
 		},
 		func(po *s3.PresignOptions) {
-			po.Expires = time.Duration(expirySeconds) * time.Second
+			po.Expires = expiry
 		},
 	)
 	if err != nil {
@@ -119,4 +139,30 @@ func UploadToS3AndPresign(objectName string, file io.ReadSeeker, fileSize int64,
 	}
 
 	return url, nil
-}
\ No newline at end of file
+}
+
+// ObjectURL returns a URL clients can load objectName from: under
+// S3_PUBLIC_BASE_URL when the bucket (or a CDN in front of it) is public,
+// otherwise a presigned URL valid for expirySeconds.
+func ObjectURL(objectName string, expirySeconds int64) (string, error) {
+	if base := strings.TrimRight(os.Getenv("S3_PUBLIC_BASE_URL"), "/"); base != "" {
+		return base + "/" + strings.TrimLeft(objectName, "/"), nil
+	}
+	return GenerateSignedURL(objectName, expirySeconds)
+}
+
+// ImageURLExpiry is how long presigned image URLs in API responses stay
+// valid, in seconds; the app refetches far more often than this.
+const ImageURLExpiry = 24 * 60 * 60
+
+// StoredImageURL resolves a stored image for clients: absolute URLs are used
+// as is, anything else is an object key (see ObjectURL), and no image gives "".
+func StoredImageURL(image string, expirySeconds int64) (string, error) {
+	switch {
+	case image == "":
+		return "", nil
+	case strings.HasPrefix(image, "https://") || strings.HasPrefix(image, "http://"):
+		return image, nil
+	}
+	return ObjectURL(image, expirySeconds)
+}
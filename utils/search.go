@@ -0,0 +1,11 @@
+package utils
+
+import "strings"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// PrefixLike turns user input into a LIKE pattern matching values that start
+// with it. Unlike a "%x%" search it can use an index on the column.
+func PrefixLike(s string) string {
+	return likeEscaper.Replace(s) + "%"
+}
@@ -0,0 +1,17 @@
+package utils
+
+import "testing"
+
+func TestPrefixLike(t *testing.T) {
+	cases := map[string]string{
+		"XIN-12":  "XIN-12%",
+		"50%_off": `50\%\_off%`,
+		`a\b`:     `a\\b%`,
+		"":        "%",
+	}
+	for in, want := range cases {
+		if got := PrefixLike(in); got != want {
+			t.Errorf("PrefixLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+)
+
+const shutdownKey = contextKey("shutdown")
+
+// WithShutdown attaches a channel that is closed when the server starts a
+// graceful shutdown. It is installed as the server's base context so every
+// request can see it without the request context itself being cancelled.
+func WithShutdown(ctx context.Context, done <-chan struct{}) context.Context {
+	return context.WithValue(ctx, shutdownKey, done)
+}
+
+// ShuttingDown reports whether the server handling r has begun shutting down.
+// Long-running handlers (cron loops) check it between units of work so they
+// stop at a clean boundary instead of being cut off mid-transaction.
+func ShuttingDown(r *http.Request) bool {
+	done, ok := r.Context().Value(shutdownKey).(<-chan struct{})
+	if !ok {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ShutdownSignal returns the channel closed when the server handling r begins
+// shutting down, for handlers that wait, such as long-polls, to select on. It
+// is nil, and so never ready, outside a server.
+func ShutdownSignal(r *http.Request) <-chan struct{} {
+	done, _ := r.Context().Value(shutdownKey).(<-chan struct{})
+	return done
+}
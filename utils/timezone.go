@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimezone is the app timezone when APP_TIMEZONE is unset.
+const DefaultTimezone = "Asia/Jakarta"
+
+var (
+	appLocMu   sync.Mutex
+	appLocName string
+	appLoc     *time.Location
+)
+
+// AppLocation returns the timezone user-facing times are shown in, from
+// APP_TIMEZONE (an IANA name such as "Asia/Jakarta"). Timestamps are stored
+// in UTC; this only affects how they are presented and how "today" is cut.
+func AppLocation() *time.Location {
+	name := strings.TrimSpace(os.Getenv("APP_TIMEZONE"))
+	if name == "" {
+		name = DefaultTimezone
+	}
+	appLocMu.Lock()
+	defer appLocMu.Unlock()
+	if appLoc != nil && appLocName == name {
+		return appLoc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// No tzdata on the host: fall back to WIB, which has no DST
+		loc = time.FixedZone("WIB", 7*60*60)
+	}
+	appLocName, appLoc = name, loc
+	return loc
+}
+
+// FormatTime formats t as RFC3339 with the app timezone's offset,
+// e.g. 2024-05-01T14:30:00+07:00.
+func FormatTime(t time.Time) string {
+	return t.In(AppLocation()).Format(time.RFC3339)
+}
+
+// FormatTimePtr is FormatTime for optional timestamps; nil stays nil.
+func FormatTimePtr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := FormatTime(*t)
+	return &s
+}
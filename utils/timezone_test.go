@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFormatTimeUsesAppTimezone(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 7, 30, 0, 0, time.UTC)
+
+	t.Setenv("APP_TIMEZONE", "")
+	if got, want := FormatTime(ts), "2024-05-01T14:30:00+07:00"; got != want {
+		t.Fatalf("default timezone: got %s, want %s", got, want)
+	}
+
+	t.Setenv("APP_TIMEZONE", "UTC")
+	if got, want := FormatTime(ts), "2024-05-01T07:30:00Z"; got != want {
+		t.Fatalf("UTC: got %s, want %s", got, want)
+	}
+
+	// The same instant formats the same whatever zone it was loaded in
+	t.Setenv("APP_TIMEZONE", "Asia/Jakarta")
+	if got, want := FormatTime(ts.In(time.FixedZone("X", -5*3600))), "2024-05-01T14:30:00+07:00"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if FormatTimePtr(nil) != nil {
+		t.Fatal("nil time should stay nil")
+	}
+	if got := FormatTimePtr(&ts); got == nil || *got != "2024-05-01T14:30:00+07:00" {
+		t.Fatalf("unexpected pointer result %v", got)
+	}
+}
+
+func TestLocalTimesOnRequest(t *testing.T) {
+	t.Setenv("APP_TIMEZONE", "Asia/Jakarta")
+	ts := time.Date(2024, 5, 1, 7, 30, 0, 0, time.UTC)
+	times := map[string]*time.Time{"expired_at": &ts, "opens_at": nil}
+
+	plain := map[string]interface{}{}
+	AddLocalTimes(httptest.NewRequest("GET", "/v3/info", nil), plain, times)
+	if len(plain) != 0 {
+		t.Fatalf("local times added unasked: %v", plain)
+	}
+
+	for _, req := range []string{"query", "header"} {
+		r := httptest.NewRequest("GET", "/v3/info?time_format=local", nil)
+		if req == "header" {
+			r = httptest.NewRequest("GET", "/v3/info", nil)
+			r.Header.Set("X-Time-Format", "Local")
+		}
+		data := map[string]interface{}{}
+		AddLocalTimes(r, data, times)
+		if data["expired_at_local"] != "2024-05-01 14:30:00 WIB" {
+			t.Fatalf("%s: unexpected expired_at_local %v", req, data["expired_at_local"])
+		}
+		if v, ok := data["opens_at_local"]; !ok || v != nil {
+			t.Fatalf("%s: nil time should give null, got %v", req, data)
+		}
+		AddServerTime(r, data)
+		if _, ok := data["server_time_local"]; !ok || data["server_time"] == nil {
+			t.Fatalf("%s: missing server time: %v", req, data)
+		}
+	}
+}
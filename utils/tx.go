@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"project/database"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// ErrTxPanic is wrapped by the error WithTx returns when fn panicked. The
+// transaction was rolled back and the stack logged.
+var ErrTxPanic = errors.New("panic inside transaction")
+
+// txRetryBackoff is the wait before the first retry of a deadlocked
+// transaction; each later retry waits one more step.
+const txRetryBackoff = 50 * time.Millisecond
+
+// TxOptions tunes WithTxOptions.
+type TxOptions struct {
+	// Timeout bounds the whole transaction, retries included: 0 means
+	// database.QueryTimeout, negative leaves only ctx's own deadline
+	Timeout time.Duration
+	// Retries is how many more times fn runs after MySQL rolls the
+	// transaction back as a deadlock victim or on a lock wait timeout. fn
+	// must then set everything it reports afresh on each run.
+	Retries int
+}
+
+// WithTx runs fn in one transaction bounded by database.QueryTimeout. It
+// commits when fn returns nil and rolls back otherwise, a panic in fn
+// included, which comes back as an error wrapping ErrTxPanic. Every write of a
+// request belongs in fn, so a failure at any step leaves nothing behind. On a
+// db already inside a transaction fn runs in a savepoint.
+func WithTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return WithTxOptions(ctx, db, TxOptions{}, fn)
+}
+
+// WithTxOptions is WithTx with a timeout and deadlock retries of its own.
+func WithTxOptions(ctx context.Context, db *gorm.DB, opts TxOptions, fn func(tx *gorm.DB) error) error {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = database.QueryTimeout()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	db = db.WithContext(ctx)
+	// A deadlock rolls back the outer transaction too, so only it may retry
+	_, nested := db.Statement.ConnPool.(gorm.TxCommitter)
+
+	for attempt := 0; ; attempt++ {
+		err := db.Transaction(func(tx *gorm.DB) (err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					Logger.Error("panic inside transaction", "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+					err = fmt.Errorf("%w: %v", ErrTxPanic, p)
+				}
+			}()
+			return fn(tx)
+		})
+		if err == nil || nested || attempt >= opts.Retries || !IsDeadlock(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt+1) * txRetryBackoff):
+		}
+	}
+}
+
+// IsDeadlock reports whether err is MySQL rolling a transaction back as a
+// deadlock victim (1213) or after a lock wait timeout (1205).
+func IsDeadlock(err error) bool {
+	var merr *mysql.MySQLError
+	return errors.As(err, &merr) && (merr.Number == 1213 || merr.Number == 1205)
+}
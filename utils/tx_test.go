@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsDeadlock(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, true},
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, true},
+		{fmt.Errorf("settle: %w", &mysql.MySQLError{Number: 1213}), true},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, false},
+		{errors.New("deadlock"), false},
+		{nil, false},
+	} {
+		if got := IsDeadlock(tc.err); got != tc.want {
+			t.Errorf("IsDeadlock(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package vip recalculates VIP levels from total_invest_vip and the
+// thresholds in vip_levels, records every change for support and leaves the
+// user an inbox notification.
+package vip
+
+import (
+	"os"
+	"strings"
+
+	"project/models"
+	"project/notify"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Downgrade policies: what to do when a user's total no longer reaches their level.
+const (
+	// PolicyFlag keeps the level and records the downgrade for support.
+	PolicyFlag = "flag"
+	// PolicyApply lowers the level.
+	PolicyApply = "apply"
+)
+
+// Sources of a recalculation, as VIPLevelChange.Source.
+const (
+	SourcePayment = "payment"
+	SourceCancel  = "cancel"
+	SourceCron    = "cron"
+	SourceAdmin   = "admin"
+)
+
+// Policy reads VIP_DOWNGRADE_POLICY, defaulting to PolicyFlag.
+func Policy() string {
+	if strings.TrimSpace(os.Getenv("VIP_DOWNGRADE_POLICY")) == PolicyApply {
+		return PolicyApply
+	}
+	return PolicyFlag
+}
+
+// Levels loads the levels above 0 that have a threshold.
+func Levels(db *gorm.DB) ([]models.VIPLevel, error) {
+	var levels []models.VIPLevel
+	err := db.Where("level > 0 AND min_total_invest > 0").Order("level ASC").Find(&levels).Error
+	return levels, err
+}
+
+// LevelFor returns the highest of levels whose threshold total reaches, or 0.
+// Without levels it falls back to the built-in ladder of models.VIPLevelFor.
+func LevelFor(levels []models.VIPLevel, total int64) uint {
+	if len(levels) == 0 {
+		return models.VIPLevelFor(total)
+	}
+	var best uint
+	for _, l := range levels {
+		if total >= l.MinTotalInvest && l.Level > best {
+			best = l.Level
+		}
+	}
+	return best
+}
+
+// Recalculate sets userID's level from their total_invest_vip. A lower level
+// is only applied under PolicyApply; under PolicyFlag it is recorded as not
+// applied, once until the target changes. It returns the change, or nil when
+// there is none, and must run inside tx; nil levels are loaded.
+func Recalculate(tx *gorm.DB, userID uint, levels []models.VIPLevel, source, policy string) (*models.VIPLevelChange, error) {
+	if levels == nil {
+		var err error
+		if levels, err = Levels(tx); err != nil {
+			return nil, err
+		}
+	}
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, level, total_invest_vip").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	from := user.CurrentVIPLevel()
+	to := LevelFor(levels, user.TotalInvestVIP)
+	if to == from {
+		return nil, nil
+	}
+
+	change := models.VIPLevelChange{UserID: userID, FromLevel: from, ToLevel: to, TotalInvestVIP: user.TotalInvestVIP, Source: source, Applied: to > from || policy == PolicyApply}
+	if !change.Applied {
+		var last models.VIPLevelChange
+		err := tx.Where("user_id = ?", userID).Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return nil, err
+		}
+		if last.ID != 0 && !last.Applied && last.FromLevel == from && last.ToLevel == to {
+			return nil, nil
+		}
+	}
+	if err := tx.Create(&change).Error; err != nil {
+		return nil, err
+	}
+	if !change.Applied {
+		return &change, nil
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("level", to).Error; err != nil {
+		return nil, err
+	}
+	locale, err := notify.UserLocale(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	inbox := notify.Inbox(notify.VIPLevelChanged(userID, from, to), locale, "vip_level")
+	if err := tx.Create(&inbox).Error; err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
@@ -0,0 +1,102 @@
+package vip
+
+import (
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Totals are a user's invested totals and the VIP level they give.
+type Totals struct {
+	TotalInvest    int64 `json:"total_invest"`
+	TotalInvestVIP int64 `json:"total_invest_vip"`
+	Level          uint  `json:"level"`
+}
+
+// Drift compares a user's stored totals with those rebuilt from the ledger.
+// Level in After is the level the rebuilt total reaches, whether or not the
+// downgrade policy lets it apply.
+type Drift struct {
+	UserID  uint                   `json:"user_id"`
+	Before  Totals                 `json:"before"`
+	After   Totals                 `json:"after"`
+	Drifted bool                   `json:"drifted"`
+	Applied bool                   `json:"applied"`
+	Change  *models.VIPLevelChange `json:"level_change,omitempty"`
+}
+
+// LedgerTotals rebuilds total_invest and total_invest_vip of userIDs from
+// the ledger: the Success investment and investment_topup transactions of
+// investments that were not cancelled, the VIP total counting locked
+// categories only. Users without any are left out of the map.
+func LedgerTotals(db *gorm.DB, userIDs []uint) (map[uint]Totals, error) {
+	var rows []struct {
+		UserID         uint
+		TotalInvest    int64
+		TotalInvestVIP int64
+	}
+	err := db.Table("transactions").
+		Select("transactions.user_id, SUM(transactions.amount) AS total_invest, "+
+			"SUM(CASE WHEN categories.profit_type = ? THEN transactions.amount ELSE 0 END) AS total_invest_vip", "locked").
+		Joins("JOIN investments ON investments.id = transactions.investment_id").
+		Joins("LEFT JOIN categories ON categories.id = investments.category_id").
+		Where("transactions.user_id IN ? AND transactions.transaction_type IN ? AND transactions.status = ?", userIDs, []string{"investment", "investment_topup"}, "Success").
+		Where("investments.status <> ?", "Cancelled").
+		Group("transactions.user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[uint]Totals, len(rows))
+	for _, r := range rows {
+		totals[r.UserID] = Totals{TotalInvest: r.TotalInvest, TotalInvestVIP: r.TotalInvestVIP}
+	}
+	return totals, nil
+}
+
+// Rebuild compares userID's stored totals with LedgerTotals under a lock on
+// the user's row. With apply it writes drifted totals and recalculates the
+// level following policy, recording source. It must run inside tx; nil
+// levels are loaded.
+func Rebuild(tx *gorm.DB, userID uint, levels []models.VIPLevel, apply bool, source, policy string) (*Drift, error) {
+	if levels == nil {
+		var err error
+		if levels, err = Levels(tx); err != nil {
+			return nil, err
+		}
+	}
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, level, total_invest, total_invest_vip").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	ledger, err := LedgerTotals(tx, []uint{userID})
+	if err != nil {
+		return nil, err
+	}
+	after := ledger[userID]
+	after.Level = LevelFor(levels, after.TotalInvestVIP)
+	d := &Drift{
+		UserID: userID,
+		Before: Totals{TotalInvest: user.TotalInvest, TotalInvestVIP: user.TotalInvestVIP, Level: user.CurrentVIPLevel()},
+		After:  after,
+	}
+	d.Drifted = d.Before.TotalInvest != after.TotalInvest || d.Before.TotalInvestVIP != after.TotalInvestVIP
+	if !apply {
+		return d, nil
+	}
+
+	if d.Drifted {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"total_invest":     after.TotalInvest,
+			"total_invest_vip": after.TotalInvestVIP,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if d.Change, err = Recalculate(tx, userID, levels, source, policy); err != nil {
+		return nil, err
+	}
+	d.Applied = d.Drifted || (d.Change != nil && d.Change.Applied)
+	return d, nil
+}
@@ -0,0 +1,302 @@
+// Package webhook notifies downstream systems (accounting, analytics) when
+// money moves.
+//
+// Record runs inside the transaction that moves the money and stores one
+// WebhookDelivery per active endpoint subscribed to the event, each with an
+// outbox event of KindDelivery. The outbox cron then POSTs the JSON body,
+// signed with the endpoint's secret, and retries a failed attempt with the
+// outbox backoff until MaxAttempts. Deliveries are never sent from the
+// request that recorded them, so a slow endpoint cannot hold up a payment.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/models"
+	"project/outbox"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// Event types an endpoint can subscribe to.
+const (
+	// EventDepositConfirmed: a wallet top-up was paid and credited
+	EventDepositConfirmed = "deposit.confirmed"
+	// EventInvestmentPaid: an investment purchase was paid and started running
+	EventInvestmentPaid = "investment.paid"
+	// EventInvestmentCompleted: an investment ended and its capital was returned
+	EventInvestmentCompleted = "investment.completed"
+	// EventWithdrawalSettled: a withdrawal was paid out
+	EventWithdrawalSettled = "withdrawal.settled"
+	// EventWithdrawalReversed: a settled withdrawal's payout failed at the
+	// gateway and it went back to Pending
+	EventWithdrawalReversed = "withdrawal.reversed"
+)
+
+// Events lists every event type, in the order shown to admins.
+var Events = []string{EventDepositConfirmed, EventInvestmentPaid, EventInvestmentCompleted, EventWithdrawalSettled, EventWithdrawalReversed}
+
+// KindDelivery is the outbox kind sending one WebhookDelivery, keyed by its id.
+const KindDelivery = "webhook.delivery"
+
+// Request headers of a delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// sendTimeout bounds one delivery attempt, which holds its outbox row.
+const sendTimeout = 10 * time.Second
+
+// errorBodyLimit is how much of a failed response is kept in last_error.
+const errorBodyLimit = 200
+
+// ErrNotFailed is returned by Redeliver for a delivery that has not failed.
+var ErrNotFailed = errors.New("webhook delivery has not failed")
+
+// Valid reports whether eventType is one of Events.
+func Valid(eventType string) bool {
+	for _, t := range Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type envelope struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt string                 `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+type deliveryPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+type userPII struct {
+	Name   string `json:"name"`
+	Number string `json:"number"`
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "evt_" + hex.EncodeToString(b), nil
+}
+
+// Record stores a delivery of eventType about userID to every active
+// endpoint subscribed to it. data holds the event's fields; user_id is added,
+// and the user's name and number only for endpoints with IncludePII. It must
+// run inside the transaction moving the money, so the deliveries exist
+// exactly when it commits.
+func Record(tx *gorm.DB, eventType string, userID uint, data map[string]interface{}) error {
+	var endpoints []models.WebhookEndpoint
+	if err := tx.Where("active = ?", true).Order("id ASC").Find(&endpoints).Error; err != nil {
+		return err
+	}
+	var subscribed []models.WebhookEndpoint
+	for _, ep := range endpoints {
+		if ep.Subscribes(eventType) {
+			subscribed = append(subscribed, ep)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	id, err := newEventID()
+	if err != nil {
+		return err
+	}
+	base := map[string]interface{}{"user_id": userID}
+	for k, v := range data {
+		base[k] = v
+	}
+	var pii *userPII
+	for _, ep := range subscribed {
+		fields := base
+		if ep.IncludePII {
+			if pii == nil {
+				pii = &userPII{}
+				if err := tx.Model(&models.User{}).Select("name, number").Where("id = ?", userID).Scan(pii).Error; err != nil {
+					return err
+				}
+			}
+			fields = make(map[string]interface{}, len(base)+1)
+			for k, v := range base {
+				fields[k] = v
+			}
+			fields["user"] = pii
+		}
+		body, err := json.Marshal(envelope{ID: id, Type: eventType, CreatedAt: utils.FormatTime(time.Now()), Data: fields})
+		if err != nil {
+			return err
+		}
+		d := models.WebhookDelivery{EndpointID: ep.ID, EventID: id, EventType: eventType, Payload: string(body), Status: models.WebhookDeliveryPending}
+		if err := tx.Create(&d).Error; err != nil {
+			return err
+		}
+		if _, err := outbox.Add(tx, KindDelivery, strconv.FormatUint(uint64(d.ID), 10), deliveryPayload{DeliveryID: d.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sign returns the signature of body sent at timestamp (unix seconds): the
+// hex HMAC-SHA256 of "timestamp.body" under secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret for an endpoint.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sender carries out KindDelivery events.
+type Sender struct {
+	DB   *gorm.DB
+	HTTP *http.Client
+}
+
+// Register adds a Sender for KindDelivery to d.
+func Register(d *outbox.Dispatcher) *Sender {
+	s := &Sender{
+		DB: d.DB,
+		HTTP: &http.Client{
+			Timeout: sendTimeout,
+			// A redirect is answered as a failure rather than followed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+	d.Handle(KindDelivery, s.deliver)
+	return s
+}
+
+// deliver sends the delivery of e. Each attempt is recorded on the delivery
+// with s.DB, outside tx, so a failure is logged although tx rolls back for
+// the outbox retry; the last attempt marks it Failed.
+func (s *Sender) deliver(tx *gorm.DB, e *models.OutboxEvent) error {
+	var p deliveryPayload
+	if err := outbox.Decode(e, &p); err != nil {
+		return err
+	}
+	var d models.WebhookDelivery
+	if err := tx.First(&d, p.DeliveryID).Error; err != nil {
+		return err
+	}
+	if d.Status == models.WebhookDeliverySuccess {
+		return nil
+	}
+	var ep models.WebhookEndpoint
+	err := tx.First(&ep, d.EndpointID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !ep.Active) {
+		// Retrying cannot help; an admin can redeliver once it is back
+		return s.record(&d, 0, errors.New("endpoint removed or disabled"), true)
+	}
+	if err != nil {
+		return err
+	}
+
+	status, sendErr := s.Post(context.Background(), &ep, &d)
+	if err := s.record(&d, status, sendErr, e.Attempts+1 >= outbox.MaxAttempts); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// record stores the outcome of an attempt at d; final marks a failure Failed.
+func (s *Sender) record(d *models.WebhookDelivery, status int, sendErr error, final bool) error {
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "response_status": status, "last_attempt_at": now}
+	switch {
+	case sendErr == nil:
+		updates["status"] = models.WebhookDeliverySuccess
+		updates["delivered_at"] = now
+		updates["last_error"] = nil
+	case final:
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["last_error"] = sendErr.Error()
+	default:
+		updates["last_error"] = sendErr.Error()
+	}
+	return s.DB.Model(&models.WebhookDelivery{}).Where("id = ?", d.ID).Updates(updates).Error
+}
+
+// Post sends d to ep and returns the response status, 0 when none came
+// back. Any status outside 2xx is an error.
+func (s *Sender) Post(ctx context.Context, ep *models.WebhookEndpoint, d *models.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.EventID)
+	req.Header.Set(HeaderSignature, fmt.Sprintf("t=%d,v1=%s", ts, Sign(ep.Secret, ts, body)))
+	client := s.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook: request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+		return resp.StatusCode, fmt.Errorf("webhook: status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, nil
+}
+
+// Redeliver puts a Failed delivery and its outbox event back to Pending with
+// fresh attempts and returns the event's id for dispatch.
+func Redeliver(db *gorm.DB, deliveryID uint) (uint, error) {
+	var eventID uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.WebhookDelivery{}).Where("id = ? AND status = ?", deliveryID, models.WebhookDeliveryFailed).
+			Updates(map[string]interface{}{"status": models.WebhookDeliveryPending, "attempts": 0})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFailed
+		}
+		var e models.OutboxEvent
+		if err := tx.Where("kind = ? AND event_key = ?", KindDelivery, strconv.FormatUint(uint64(deliveryID), 10)).First(&e).Error; err != nil {
+			return err
+		}
+		eventID = e.ID
+		return tx.Model(&e).Updates(map[string]interface{}{"status": models.OutboxPending, "attempts": 0, "next_attempt_at": time.Now()}).Error
+	})
+	return eventID, err
+}
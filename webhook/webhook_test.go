@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/models"
+)
+
+func TestPostSignsBody(t *testing.T) {
+	var gotSig, gotEvent, gotDelivery, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get(HeaderSignature)
+		gotEvent = r.Header.Get(HeaderEvent)
+		gotDelivery = r.Header.Get(HeaderDelivery)
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ep := &models.WebhookEndpoint{URL: srv.URL, Secret: "whsec_test"}
+	d := &models.WebhookDelivery{EventID: "evt_1", EventType: EventDepositConfirmed, Payload: `{"id":"evt_1"}`}
+	status, err := (&Sender{}).Post(context.Background(), ep, d)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Post = %d, %v; want 204, nil", status, err)
+	}
+	if gotEvent != EventDepositConfirmed || gotDelivery != "evt_1" || gotBody != d.Payload {
+		t.Fatalf("headers %q %q body %q", gotEvent, gotDelivery, gotBody)
+	}
+	var ts int64
+	var sig string
+	if _, err := fmt.Sscanf(strings.Replace(gotSig, ",v1=", " ", 1), "t=%d %s", &ts, &sig); err != nil {
+		t.Fatalf("signature header %q: %v", gotSig, err)
+	}
+	if sig != Sign("whsec_test", ts, []byte(d.Payload)) {
+		t.Fatalf("signature %q does not verify", gotSig)
+	}
+	// Another secret or body gives another signature
+	if Sign("whsec_other", ts, []byte(d.Payload)) == sig || Sign("whsec_test", ts, []byte(`{}`)) == sig {
+		t.Fatal("signature does not depend on secret and body")
+	}
+}
+
+func TestPostFailsOutside2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	status, err := (&Sender{}).Post(context.Background(), &models.WebhookEndpoint{URL: srv.URL}, &models.WebhookDelivery{Payload: "{}"})
+	if status != http.StatusServiceUnavailable || err == nil || !strings.Contains(err.Error(), "maintenance") {
+		t.Fatalf("Post = %d, %v; want 503 with the body in the error", status, err)
+	}
+}
+
+func TestSubscribes(t *testing.T) {
+	ep := models.WebhookEndpoint{Events: "deposit.confirmed, withdrawal.settled"}
+	if !ep.Subscribes(EventDepositConfirmed) || !ep.Subscribes(EventWithdrawalSettled) {
+		t.Fatal("subscribed events not matched")
+	}
+	if ep.Subscribes(EventInvestmentPaid) || ep.Subscribes("deposit") {
+		t.Fatal("unsubscribed event matched")
+	}
+}